@@ -0,0 +1,389 @@
+// Package apiauth guards the HTTP and gRPC APIs with API keys and HS256
+// JWTs, each granting a role: readers read the results and jobs, producers
+// also submit jobs and change results, and admins also reach the admin
+// server. Each key or token is bound to an organization, whose data only
+// it reaches, or to all of them.
+package apiauth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/gosom/google-maps-scraper/httpjson"
+)
+
+// Role is what a key or token may do. Each role may do what the lower ones
+// do.
+type Role string
+
+const (
+	Reader   Role = "reader"
+	Producer Role = "producer"
+	Admin    Role = "admin"
+)
+
+var ranks = map[Role]int{Reader: 1, Producer: 2, Admin: 3}
+
+// ParseRole parses a role name.
+func ParseRole(s string) (Role, error) {
+	r := Role(strings.TrimSpace(s))
+	if _, ok := ranks[r]; !ok {
+		return "", fmt.Errorf("unknown role %q: must be reader, producer or admin", s)
+	}
+
+	return r, nil
+}
+
+// Allows tells whether r may do what need may.
+func (r Role) Allows(need Role) bool {
+	return ranks[r] >= ranks[need]
+}
+
+// AllOrganizations is the organization of the keys and tokens reaching the
+// data of every organization.
+const AllOrganizations = "*"
+
+var (
+	ErrMissing = errors.New("missing API key or token")
+	ErrInvalid = errors.New("invalid API key or token")
+)
+
+// Identity is what a key or token grants: a role, over the data of an
+// organization. OrganizationID is empty for the keys of all organizations.
+type Identity struct {
+	Role           Role
+	OrganizationID string
+}
+
+// parseOrganization parses the organization of a key or token, empty for
+// AllOrganizations.
+func parseOrganization(s string) (string, error) {
+	switch s = strings.TrimSpace(s); s {
+	case "":
+		return "", errors.New("missing organization: use '*' for all of them")
+	case AllOrganizations:
+		return "", nil
+	default:
+		return s, nil
+	}
+}
+
+type identityKey struct{}
+
+// GetOrganizationFromContext returns the organization the key or token of
+// the request of ctx is bound to, or an empty string when it reaches all of
+// them or the APIs are open.
+func GetOrganizationFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(identityKey{}).(Identity)
+
+	return id.OrganizationID
+}
+
+// ErrOtherOrganization is returned when a key bound to an organization asks
+// for the data of another one.
+var ErrOtherOrganization = errors.New("the key is bound to another organization")
+
+// RestrictOrganization returns the organization whose data the request of
+// ctx reads when it asks for that of requested, empty for all of them: the
+// organization of its key when it is bound to one, which fails with
+// ErrOtherOrganization when another one is requested.
+func RestrictOrganization(ctx context.Context, requested string) (string, error) {
+	organization := GetOrganizationFromContext(ctx)
+
+	switch {
+	case organization == "":
+		return requested, nil
+	case requested != "" && requested != organization:
+		return "", ErrOtherOrganization
+	default:
+		return organization, nil
+	}
+}
+
+// Authenticator checks the API keys and tokens of the requests.
+type Authenticator struct {
+	// keys holds the identities by SHA-256 of the key, so that looking a
+	// key up takes the same time whatever it shares with the valid ones.
+	keys      map[[sha256.Size]byte]Identity
+	jwtSecret []byte
+}
+
+// New creates an Authenticator accepting the keys, given as
+// role:organization:key entries with '*' as the organization of the keys of
+// all organizations, and the HS256 tokens signed with jwtSecret when it is
+// set.
+func New(keys []string, jwtSecret []byte) (*Authenticator, error) {
+	a := &Authenticator{
+		keys:      make(map[[sha256.Size]byte]Identity, len(keys)),
+		jwtSecret: jwtSecret,
+	}
+
+	for _, entry := range keys {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 3)
+		if len(parts) != 3 || parts[2] == "" {
+			return nil, fmt.Errorf("invalid API key entry (expected 'role:organization:key')")
+		}
+
+		role, err := ParseRole(parts[0])
+		if err != nil {
+			return nil, err
+		}
+
+		organization, err := parseOrganization(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid API key entry: %w", err)
+		}
+
+		a.keys[sha256.Sum256([]byte(parts[2]))] = Identity{Role: role, OrganizationID: organization}
+	}
+
+	return a, nil
+}
+
+// FromEnv creates the Authenticator of the API_KEYS (comma separated
+// role:organization:key entries) and API_JWT_SECRET variables, or returns
+// nil, leaving the APIs open, when neither is set.
+func FromEnv() (*Authenticator, error) {
+	keys := os.Getenv("API_KEYS")
+	secret := os.Getenv("API_JWT_SECRET")
+
+	if keys == "" && secret == "" {
+		return nil, nil
+	}
+
+	var entries []string
+
+	for _, entry := range strings.Split(keys, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	return New(entries, []byte(secret))
+}
+
+// Authenticate returns the identity of an API key or token.
+func (a *Authenticator) Authenticate(credential string) (Identity, error) {
+	if credential == "" {
+		return Identity{}, ErrMissing
+	}
+
+	if id, ok := a.keys[sha256.Sum256([]byte(credential))]; ok {
+		return id, nil
+	}
+
+	if len(a.jwtSecret) > 0 && strings.Count(credential, ".") == 2 {
+		return a.verifyJWT(credential)
+	}
+
+	return Identity{}, ErrInvalid
+}
+
+// claims are the claims of the tokens read: the role and organization, and
+// the expiry and start of validity when they are set.
+type claims struct {
+	Role         string `json:"role"`
+	Organization string `json:"org"`
+	ExpiresAt    int64  `json:"exp"`
+	NotBefore    int64  `json:"nbf"`
+}
+
+func (a *Authenticator) verifyJWT(token string) (Identity, error) {
+	parts := strings.Split(token, ".")
+
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return Identity{}, ErrInvalid
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+
+	if err := json.Unmarshal(rawHeader, &header); err != nil || header.Alg != "HS256" {
+		return Identity{}, ErrInvalid
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Identity{}, ErrInvalid
+	}
+
+	mac := hmac.New(sha256.New, a.jwtSecret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return Identity{}, ErrInvalid
+	}
+
+	rawClaims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return Identity{}, ErrInvalid
+	}
+
+	var c claims
+	if err := json.Unmarshal(rawClaims, &c); err != nil {
+		return Identity{}, ErrInvalid
+	}
+
+	now := time.Now().Unix()
+
+	if (c.ExpiresAt != 0 && now >= c.ExpiresAt) || (c.NotBefore != 0 && now < c.NotBefore) {
+		return Identity{}, fmt.Errorf("%w: expired or not valid yet", ErrInvalid)
+	}
+
+	role, err := ParseRole(c.Role)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	organization, err := parseOrganization(c.Organization)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	return Identity{Role: role, OrganizationID: organization}, nil
+}
+
+// Require serves h to the requests whose key or token grants role, given as
+// a bearer token or in X-API-Key, and answers 401 or 403 to the others. The
+// organization of the key is set in the context of the requests, see
+// GetOrganizationFromContext. A nil Authenticator serves every request.
+func (a *Authenticator) Require(role Role, h http.Handler) http.Handler {
+	return a.require(role, false, h)
+}
+
+// RequireGlobal is Require for the data of no organization in particular:
+// keys bound to an organization are answered 403.
+func (a *Authenticator) RequireGlobal(role Role, h http.Handler) http.Handler {
+	return a.require(role, true, h)
+}
+
+func (a *Authenticator) require(role Role, global bool, h http.Handler) http.Handler {
+	if a == nil {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		credential := r.Header.Get("X-API-Key")
+		if v, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			credential = strings.TrimSpace(v)
+		}
+
+		got, err := a.Authenticate(credential)
+
+		switch {
+		case err != nil:
+			w.Header().Set("WWW-Authenticate", `Bearer realm="google-maps-scraper"`)
+			httpjson.Write(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		case !got.Role.Allows(role):
+			httpjson.Write(w, http.StatusForbidden, map[string]string{"error": fmt.Sprintf("the %s role is required", role)})
+		case global && got.OrganizationID != "":
+			httpjson.Write(w, http.StatusForbidden, map[string]string{"error": "a key of all organizations is required"})
+		default:
+			h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, got)))
+		}
+	})
+}
+
+// ByMethod serves h to readers for the GET and HEAD requests, and to
+// producers for the requests changing something.
+func (a *Authenticator) ByMethod(h http.Handler) http.Handler {
+	if a == nil {
+		return h
+	}
+
+	read, write := a.Require(Reader, h), a.Require(Producer, h)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			read.ServeHTTP(w, r)
+			return
+		}
+
+		write.ServeHTTP(w, r)
+	})
+}
+
+// ServerOptions returns the interceptors requiring, for each gRPC method
+// of roles, its role, read from the authorization metadata as a bearer
+// token. Methods missing from roles need the admin role. The organization
+// of the token is set in the context of the calls. A nil Authenticator
+// returns none.
+func (a *Authenticator) ServerOptions(roles map[string]Role) []grpc.ServerOption {
+	if a == nil {
+		return nil
+	}
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			ctx, err := a.authorize(ctx, roles, info.FullMethod)
+			if err != nil {
+				return nil, err
+			}
+
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := a.authorize(ss.Context(), roles, info.FullMethod)
+			if err != nil {
+				return err
+			}
+
+			return handler(srv, &authorizedStream{ServerStream: ss, ctx: ctx})
+		}),
+	}
+}
+
+// authorizedStream is a server stream whose context holds the identity of
+// its caller.
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+// authorize checks the role of the caller of method, and returns ctx with
+// its identity.
+func (a *Authenticator) authorize(ctx context.Context, roles map[string]Role, method string) (context.Context, error) {
+	need, ok := roles[method]
+	if !ok {
+		need = Admin
+	}
+
+	var credential string
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("authorization"); len(v) > 0 {
+			credential = strings.TrimSpace(strings.TrimPrefix(v[0], "Bearer "))
+		}
+	}
+
+	got, err := a.Authenticate(credential)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	if !got.Role.Allows(need) {
+		return nil, status.Errorf(codes.PermissionDenied, "the %s role is required", need)
+	}
+
+	return context.WithValue(ctx, identityKey{}, got), nil
+}
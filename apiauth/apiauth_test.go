@@ -0,0 +1,138 @@
+package apiauth_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/apiauth"
+)
+
+func token(secret, claims string) string {
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + enc.EncodeToString([]byte(claims))
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+
+	return unsigned + "." + enc.EncodeToString(mac.Sum(nil))
+}
+
+func Test_Authenticate(t *testing.T) {
+	a, err := apiauth.New([]string{"reader:org-1:r-key", "admin:*:a-key"}, []byte("secret"))
+	require.NoError(t, err)
+
+	id, err := a.Authenticate("r-key")
+	require.NoError(t, err)
+	require.Equal(t, apiauth.Identity{Role: apiauth.Reader, OrganizationID: "org-1"}, id)
+
+	id, err = a.Authenticate("a-key")
+	require.NoError(t, err)
+	require.Equal(t, apiauth.Identity{Role: apiauth.Admin}, id)
+
+	_, err = a.Authenticate("other")
+	require.ErrorIs(t, err, apiauth.ErrInvalid)
+
+	_, err = a.Authenticate("")
+	require.ErrorIs(t, err, apiauth.ErrMissing)
+
+	id, err = a.Authenticate(token("secret", `{"role":"producer","org":"org-2","exp":4102444800}`))
+	require.NoError(t, err)
+	require.Equal(t, apiauth.Identity{Role: apiauth.Producer, OrganizationID: "org-2"}, id)
+
+	for _, bad := range []string{
+		token("other", `{"role":"producer","org":"*"}`),
+		token("secret", `{"role":"root","org":"*"}`),
+		token("secret", `{"role":"reader"}`),
+		token("secret", `{"role":"admin","org":"*","exp":1}`),
+		token("secret", `{"role":"admin","org":"*","nbf":`+strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)+`}`),
+	} {
+		_, err := a.Authenticate(bad)
+		require.ErrorIs(t, err, apiauth.ErrInvalid, bad)
+	}
+
+	// keys are bound to an organization, or to all of them with '*'
+	for _, bad := range []string{"owner:*:k", "reader:k", "reader::k"} {
+		_, err = apiauth.New([]string{bad}, nil)
+		require.Error(t, err, bad)
+	}
+}
+
+func Test_RequireBindsTheOrganization(t *testing.T) {
+	a, err := apiauth.New([]string{"reader:org-1:r-key", "admin:org-1:o-key", "admin:*:a-key"}, nil)
+	require.NoError(t, err)
+
+	var got string
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = apiauth.GetOrganizationFromContext(r.Context())
+
+		if _, err := apiauth.RestrictOrganization(r.Context(), r.URL.Query().Get("organization_id")); err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	for _, tc := range []struct {
+		handler  http.Handler
+		key, url string
+		want     int
+		org      string
+	}{
+		{a.Require(apiauth.Reader, h), "r-key", "/", http.StatusNoContent, "org-1"},
+		{a.Require(apiauth.Reader, h), "r-key", "/?organization_id=org-1", http.StatusNoContent, "org-1"},
+		{a.Require(apiauth.Reader, h), "r-key", "/?organization_id=org-2", http.StatusForbidden, "org-1"},
+		{a.Require(apiauth.Reader, h), "a-key", "/?organization_id=org-2", http.StatusNoContent, ""},
+		{a.RequireGlobal(apiauth.Admin, h), "o-key", "/", http.StatusForbidden, ""},
+		{a.RequireGlobal(apiauth.Admin, h), "a-key", "/", http.StatusNoContent, ""},
+	} {
+		got = ""
+
+		req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+		req.Header.Set("X-API-Key", tc.key)
+
+		rec := httptest.NewRecorder()
+		tc.handler.ServeHTTP(rec, req)
+
+		require.Equal(t, tc.want, rec.Code, tc.key+" "+tc.url)
+		require.Equal(t, tc.org, got, tc.key+" "+tc.url)
+	}
+}
+
+func Test_ByMethod(t *testing.T) {
+	a, err := apiauth.New([]string{"reader:org-1:r-key", "producer:org-1:p-key"}, nil)
+	require.NoError(t, err)
+
+	h := a.ByMethod(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for _, tc := range []struct {
+		method, key string
+		want        int
+	}{
+		{http.MethodGet, "", http.StatusUnauthorized},
+		{http.MethodGet, "r-key", http.StatusNoContent},
+		{http.MethodPatch, "r-key", http.StatusForbidden},
+		{http.MethodPatch, "p-key", http.StatusNoContent},
+	} {
+		req := httptest.NewRequest(tc.method, "/results/1", nil)
+		if tc.key != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.key)
+		}
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		require.Equal(t, tc.want, rec.Code, tc.method+" "+tc.key)
+	}
+}
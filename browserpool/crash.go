@@ -0,0 +1,212 @@
+package browserpool
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// CrashKind describes how the browser a job ran in broke.
+type CrashKind string
+
+const (
+	// CrashBrowser is a browser process that died or disconnected.
+	CrashBrowser CrashKind = "browser"
+	// CrashPage is a page or context closed or crashed under the job.
+	CrashPage CrashKind = "page"
+	// CrashZombie is a context that is still open but stopped answering.
+	CrashZombie CrashKind = "zombie"
+)
+
+// livenessTimeout is how long a context has to evaluate a trivial script
+// before it is considered a zombie.
+const livenessTimeout = 10 * time.Second
+
+// crashMarkers are the playwright errors of a crashed or closed target.
+var crashMarkers = []string{
+	"target crashed",
+	"page crashed",
+	"target closed",
+	"has been closed",
+	"browser has disconnected",
+	"connection closed",
+}
+
+var crashStats = struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}{
+	counts: make(map[string]int64),
+}
+
+func countCrash(key string) {
+	crashStats.mu.Lock()
+	defer crashStats.mu.Unlock()
+
+	crashStats.counts[key]++
+}
+
+// CrashCounts returns how many crashes of each kind were detected and how
+// many browsers were restarted since startup.
+func CrashCounts() map[string]int64 {
+	crashStats.mu.Lock()
+	defer crashStats.mu.Unlock()
+
+	ans := make(map[string]int64, len(crashStats.counts))
+	for k, v := range crashStats.counts {
+		ans[k] = v
+	}
+
+	return ans
+}
+
+// RequeueLimiter caps how many times a job whose browser crashed is put
+// back in the queue. The crashes of a job are forgotten once it ran without
+// crashing, or was refused a requeue.
+type RequeueLimiter struct {
+	mu    sync.Mutex
+	limit int
+	jobs  map[string]int
+}
+
+// NewRequeueLimiter returns a limiter allowing limit requeues per job.
+func NewRequeueLimiter(limit int) *RequeueLimiter {
+	return &RequeueLimiter{
+		limit: limit,
+		jobs:  make(map[string]int),
+	}
+}
+
+// Allow counts a crash of the job id and tells whether it may be requeued
+// once more.
+func (l *RequeueLimiter) Allow(id string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.jobs[id]++
+
+	if l.jobs[id] > l.limit {
+		delete(l.jobs, id)
+
+		return false
+	}
+
+	return true
+}
+
+// Done forgets the crashes of the job id, which ran without crashing.
+func (l *RequeueLimiter) Done(id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.jobs, id)
+}
+
+// Len returns how many jobs have crashes counted.
+func (l *RequeueLimiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.jobs)
+}
+
+// Crashed tells whether the job that failed with err in page did so because
+// the browser broke rather than because of Google or the network. Pages of
+// jobs that succeeded are not checked.
+func Crashed(page playwright.Page, err error) (CrashKind, bool) {
+	if err == nil || page == nil {
+		return "", false
+	}
+
+	if browser := page.Context().Browser(); browser != nil && !browser.IsConnected() {
+		return CrashBrowser, true
+	}
+
+	if page.IsClosed() || isCrashError(err) {
+		return CrashPage, true
+	}
+
+	if !alive(page) {
+		return CrashZombie, true
+	}
+
+	return "", false
+}
+
+func isCrashError(err error) bool {
+	msg := strings.ToLower(err.Error())
+
+	for _, marker := range crashMarkers {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// alive evaluates a trivial script in page, giving up after livenessTimeout.
+func alive(page playwright.Page) bool {
+	done := make(chan error, 1)
+
+	go func() {
+		_, err := page.Evaluate("1")
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err == nil || !isCrashError(err)
+	case <-time.After(livenessTimeout):
+		return false
+	}
+}
+
+// Restart closes the browser of page after a crash of kind and forgets its
+// contexts. scrapemate drops disconnected browsers when a job hands them
+// back, so the next job gets a freshly launched and warmed up browser
+// instead of the broken one.
+func (p *Pool) Restart(page playwright.Page, kind CrashKind) error {
+	countCrash(string(kind))
+
+	bctx := page.Context()
+	browser := bctx.Browser()
+
+	p.mu.Lock()
+
+	if browser != nil {
+		p.dropBrowser(browser)
+	}
+
+	delete(p.contexts, bctx)
+
+	p.mu.Unlock()
+
+	countCrash("restarts")
+
+	// a zombie browser may never answer the close either
+	done := make(chan error, 1)
+
+	go func() {
+		if browser == nil {
+			done <- bctx.Close()
+			return
+		}
+
+		done <- browser.Close()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil && !isCrashError(err) {
+			return err
+		}
+
+		return nil
+	case <-time.After(livenessTimeout):
+		return errors.New("browser did not close in time")
+	}
+}
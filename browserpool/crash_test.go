@@ -0,0 +1,34 @@
+package browserpool_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/browserpool"
+)
+
+func Test_RequeueLimiter(t *testing.T) {
+	l := browserpool.NewRequeueLimiter(2)
+
+	// a job crashing too often is refused and forgotten
+	require.True(t, l.Allow("job-1"))
+	require.True(t, l.Allow("job-1"))
+	require.False(t, l.Allow("job-1"))
+	require.Equal(t, 0, l.Len())
+
+	// a job that ran without crashing starts over
+	require.True(t, l.Allow("job-2"))
+	require.True(t, l.Allow("job-3"))
+	require.Equal(t, 2, l.Len())
+
+	l.Done("job-2")
+	require.Equal(t, 1, l.Len())
+
+	require.True(t, l.Allow("job-2"))
+	require.True(t, l.Allow("job-2"))
+
+	// jobs that never crashed are no-ops
+	l.Done("job-4")
+	require.Equal(t, 2, l.Len())
+}
@@ -0,0 +1,158 @@
+package browserpool
+
+import (
+	"math/rand/v2"
+	"strings"
+)
+
+// Fingerprint is the identity a browser context presents to Google.
+type Fingerprint struct {
+	UserAgent      string `json:"user_agent"`
+	Platform       string `json:"platform"`
+	Locale         string `json:"locale"`
+	AcceptLanguage string `json:"accept_language"`
+	TimezoneID     string `json:"timezone_id"`
+	Width          int    `json:"width"`
+	Height         int    `json:"height"`
+}
+
+type userAgent struct {
+	value    string
+	platform string
+}
+
+// The scraper drives chromium, so only Chrome user agents are used: a
+// firefox user agent on a chromium engine is an easy tell.
+var userAgents = []userAgent{
+	{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/140.0.0.0 Safari/537.36", "Win32"},
+	{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/139.0.0.0 Safari/537.36", "Win32"},
+	{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Safari/537.36", "Win32"},
+	{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/140.0.0.0 Safari/537.36", "MacIntel"},
+	{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/139.0.0.0 Safari/537.36", "MacIntel"},
+	{"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/140.0.0.0 Safari/537.36", "Linux x86_64"},
+}
+
+// Target is the language (hl) and country (gl) a job asks Google for. The
+// context the job runs in speaks its language from its country, so the
+// locale of the browser never contradicts the targeting of the job.
+type Target struct {
+	Lang    string
+	Country string
+}
+
+// countryTimezones are the timezones contexts claim in each country. The
+// timezone of a country missing here is the one of the language.
+var countryTimezones = map[string][]string{
+	"at": {"Europe/Vienna"},
+	"be": {"Europe/Brussels"},
+	"ca": {"America/Toronto", "America/Vancouver"},
+	"ch": {"Europe/Zurich"},
+	"de": {"Europe/Berlin"},
+	"es": {"Europe/Madrid"},
+	"fr": {"Europe/Paris"},
+	"gb": {"Europe/London"},
+	"ie": {"Europe/Dublin"},
+	"it": {"Europe/Rome"},
+	"lu": {"Europe/Luxembourg"},
+	"mc": {"Europe/Monaco"},
+	"nl": {"Europe/Amsterdam"},
+	"pt": {"Europe/Lisbon"},
+	"us": {"America/New_York", "America/Chicago", "America/Los_Angeles"},
+}
+
+// languageCountries is the country a language is spoken from when the job
+// sets none.
+var languageCountries = map[string]string{
+	"de": "de",
+	"en": "us",
+	"es": "es",
+	"fr": "fr",
+	"it": "it",
+	"nl": "nl",
+	"pt": "pt",
+}
+
+var viewports = [][2]int{
+	{1920, 1080},
+	{1680, 1050},
+	{1600, 900},
+	{1536, 864},
+	{1440, 900},
+	{1366, 768},
+}
+
+// Locale returns the locale of the contexts of t, like de-CH. Jobs without a
+// language get the English one.
+func (t Target) Locale() string {
+	lang := strings.ToLower(t.Lang)
+	if lang == "" {
+		lang = "en"
+	}
+
+	country := strings.ToLower(t.Country)
+	if country == "" {
+		country = languageCountries[lang]
+	}
+
+	if country == "" {
+		return lang
+	}
+
+	return lang + "-" + strings.ToUpper(country)
+}
+
+// acceptLanguage returns the Accept-Language header of the contexts of t,
+// with English as the fallback.
+func (t Target) acceptLanguage() string {
+	locale := t.Locale()
+	lang, _, _ := strings.Cut(locale, "-")
+
+	ans := locale
+	if locale != lang {
+		ans += "," + lang + ";q=0.9"
+	}
+
+	if lang != "en" {
+		ans += ",en;q=0.8"
+	}
+
+	return ans
+}
+
+// timezone returns a timezone of the country of t.
+//
+//nolint:gosec // fingerprints do not need a cryptographic source
+func (t Target) timezone() string {
+	_, country, _ := strings.Cut(t.Locale(), "-")
+
+	zones, ok := countryTimezones[strings.ToLower(country)]
+	if !ok {
+		zones = countryTimezones[languageCountries[strings.ToLower(t.Lang)]]
+	}
+
+	if len(zones) == 0 {
+		return "UTC"
+	}
+
+	return zones[rand.IntN(len(zones))]
+}
+
+// NewFingerprint returns a consistent fingerprint for the jobs of t: the
+// user agent and viewport are picked at random, the locale, Accept-Language
+// and timezone follow the language and country of t.
+//
+//nolint:gosec // fingerprints do not need a cryptographic source
+func NewFingerprint(t Target) Fingerprint {
+	ua := userAgents[rand.IntN(len(userAgents))]
+	vp := viewports[rand.IntN(len(viewports))]
+
+	return Fingerprint{
+		UserAgent:      ua.value,
+		Platform:       ua.platform,
+		Locale:         t.Locale(),
+		AcceptLanguage: t.acceptLanguage(),
+		TimezoneID:     t.timezone(),
+		Width:          vp[0],
+		Height:         vp[1],
+	}
+}
@@ -0,0 +1,27 @@
+package browserpool_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/browserpool"
+)
+
+func Test_NewFingerprintFollowsTheTargetOfTheJob(t *testing.T) {
+	fp := browserpool.NewFingerprint(browserpool.Target{Lang: "de", Country: "CH"})
+	require.Equal(t, "de-CH", fp.Locale)
+	require.Equal(t, "de-CH,de;q=0.9,en;q=0.8", fp.AcceptLanguage)
+	require.Equal(t, "Europe/Zurich", fp.TimezoneID)
+
+	// the country defaults to the one of the language
+	fp = browserpool.NewFingerprint(browserpool.Target{Lang: "fr"})
+	require.Equal(t, "fr-FR", fp.Locale)
+	require.Equal(t, "Europe/Paris", fp.TimezoneID)
+
+	// and jobs without a language speak English
+	fp = browserpool.NewFingerprint(browserpool.Target{})
+	require.Equal(t, "en-US", fp.Locale)
+	require.Equal(t, "en-US,en;q=0.9", fp.AcceptLanguage)
+	require.Contains(t, []string{"America/New_York", "America/Chicago", "America/Los_Angeles"}, fp.TimezoneID)
+}
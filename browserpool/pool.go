@@ -0,0 +1,337 @@
+package browserpool
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+const (
+	// ContextsPerBrowser is how many contexts of the pool a browser holds at
+	// most: the one its job runs in and the idle ones kept for the next jobs.
+	ContextsPerBrowser = 4
+	// contextReuseLimit is how many jobs run in a context before it is
+	// closed, like scrapemate closes its pages.
+	contextReuseLimit = 200
+)
+
+// Option configures a Pool.
+type Option func(*Pool)
+
+// WithReuse controls whether a browser context is kept for the next jobs.
+// It follows -disable-page-reuse: when pages are not reused every job runs
+// in a new context, with a new fingerprint.
+func WithReuse(reuse bool) Option {
+	return func(p *Pool) {
+		p.reuse = reuse
+	}
+}
+
+// WithFingerprints controls whether contexts get a randomized fingerprint.
+// Session warm-up happens either way.
+func WithFingerprints(enabled bool) Option {
+	return func(p *Pool) {
+		p.fingerprints = enabled
+	}
+}
+
+// WithProxies gives every context the pool opens the next of urls as its
+// proxy, credentials included.
+func WithProxies(urls []string) Option {
+	return func(p *Pool) {
+		p.proxies = urls
+	}
+}
+
+// pooledContext is a context of the pool and the page its jobs run in.
+type pooledContext struct {
+	browser playwright.Browser
+	bctx    playwright.BrowserContext
+	page    playwright.Page
+	locale  string
+	jobs    int
+}
+
+// Pool is a managed pool of browser contexts. A job asks it for a context of
+// the browser scrapemate handed it with Acquire, and gives it back with
+// Release. Contexts are opened on demand with a randomized fingerprint
+// (user agent and viewport, and the locale and timezone of the target of
+// the job) and warmed up with the shared consent answer, then kept and
+// reused by the next jobs with the same locale, so neither the context nor
+// the identity Google gives it is set up again. The identity Google gives a
+// context is only ever seen with its fingerprint. Contexts are forgotten
+// when they or their browser close.
+type Pool struct {
+	mu           sync.Mutex
+	reuse        bool
+	fingerprints bool
+	proxies      []string
+	next         int
+	contexts     map[playwright.BrowserContext]*pooledContext
+	idle         map[playwright.Browser][]*pooledContext
+	session      sessionStore
+}
+
+// New creates an empty pool. By default contexts are fingerprinted and
+// reused across jobs.
+func New(opts ...Option) *Pool {
+	p := &Pool{
+		reuse:        true,
+		fingerprints: true,
+		contexts:     make(map[playwright.BrowserContext]*pooledContext),
+		idle:         make(map[playwright.Browser][]*pooledContext),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Len returns the number of live browser contexts of the pool, idle or not.
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.contexts)
+}
+
+// Acquire returns the page a job targeting t runs in: the page of an idle
+// context of the browser of page speaking the locale of t, or of a new one.
+// The page of a context of the pool is given back with Release. The page
+// itself is returned when its browser cannot open contexts.
+func (p *Pool) Acquire(page playwright.Page, t Target) (playwright.Page, error) {
+	browser := page.Context().Browser()
+	if browser == nil {
+		return page, nil
+	}
+
+	if pc := p.take(browser, t.Locale()); pc != nil {
+		return pc.page, nil
+	}
+
+	pc, err := p.open(browser, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return pc.page, nil
+}
+
+// take removes an idle context of browser with locale from the pool.
+func (p *Pool) take(browser playwright.Browser, locale string) *pooledContext {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	idle := p.idle[browser]
+
+	for i, pc := range idle {
+		if pc.locale != locale || pc.page.IsClosed() {
+			continue
+		}
+
+		p.idle[browser] = append(idle[:i:i], idle[i+1:]...)
+
+		return pc
+	}
+
+	return nil
+}
+
+// open opens a context of browser for the jobs of t, makes room for it
+// among the idle contexts of browser and warms it up.
+func (p *Pool) open(browser playwright.Browser, t Target) (*pooledContext, error) {
+	fp := NewFingerprint(t)
+
+	var opts playwright.BrowserNewContextOptions
+
+	if p.fingerprints {
+		opts.UserAgent = playwright.String(fp.UserAgent)
+		opts.Locale = playwright.String(fp.Locale)
+		opts.TimezoneId = playwright.String(fp.TimezoneID)
+		opts.Viewport = &playwright.Size{Width: fp.Width, Height: fp.Height}
+		opts.ExtraHttpHeaders = map[string]string{"Accept-Language": fp.AcceptLanguage}
+	}
+
+	proxy, err := p.nextProxy()
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Proxy = proxy
+
+	p.evict(browser)
+
+	bctx, err := browser.NewContext(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open context: %w", err)
+	}
+
+	pc := &pooledContext{browser: browser, bctx: bctx, locale: t.Locale()}
+
+	if err := p.prepare(pc, fp); err != nil {
+		_ = bctx.Close()
+
+		return nil, err
+	}
+
+	p.mu.Lock()
+
+	if _, seen := p.idle[browser]; !seen {
+		p.idle[browser] = nil
+
+		browser.OnDisconnected(p.forgetBrowser)
+	}
+
+	p.contexts[bctx] = pc
+
+	p.mu.Unlock()
+
+	bctx.OnClose(p.forget)
+
+	return pc, nil
+}
+
+// prepare presents the platform of fp, warms the context of pc up and opens
+// its page.
+func (p *Pool) prepare(pc *pooledContext, fp Fingerprint) error {
+	if p.fingerprints {
+		script := fmt.Sprintf("Object.defineProperty(navigator, 'platform', {get: () => %q})", fp.Platform)
+
+		if err := pc.bctx.AddInitScript(playwright.Script{Content: playwright.String(script)}); err != nil {
+			return fmt.Errorf("failed to set platform: %w", err)
+		}
+	}
+
+	if err := p.warmUp(pc.bctx); err != nil {
+		return fmt.Errorf("failed to warm up context: %w", err)
+	}
+
+	page, err := pc.bctx.NewPage()
+	if err != nil {
+		return fmt.Errorf("failed to open page: %w", err)
+	}
+
+	pc.page = page
+
+	return nil
+}
+
+// evict closes the oldest idle contexts of browser until a new one fits.
+func (p *Pool) evict(browser playwright.Browser) {
+	p.mu.Lock()
+
+	var evicted []*pooledContext
+
+	for len(p.idle[browser]) >= ContextsPerBrowser-1 {
+		evicted = append(evicted, p.idle[browser][0])
+		p.idle[browser] = p.idle[browser][1:]
+	}
+
+	p.mu.Unlock()
+
+	for _, pc := range evicted {
+		_ = pc.bctx.Close()
+	}
+}
+
+// nextProxy returns the proxy of the next context, or nil without proxies.
+func (p *Pool) nextProxy() (*playwright.Proxy, error) {
+	p.mu.Lock()
+
+	if len(p.proxies) == 0 {
+		p.mu.Unlock()
+
+		return nil, nil
+	}
+
+	raw := p.proxies[p.next%len(p.proxies)]
+	p.next++
+
+	p.mu.Unlock()
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url: %w", err)
+	}
+
+	proxy := &playwright.Proxy{Server: u.Scheme + "://" + u.Host}
+
+	if u.User != nil {
+		proxy.Username = playwright.String(u.User.Username())
+
+		if password, ok := u.User.Password(); ok {
+			proxy.Password = playwright.String(password)
+		}
+	}
+
+	return proxy, nil
+}
+
+// Release gives back the page a job ran in. Its context is kept for the
+// next jobs when the job succeeded and contexts are reused, until
+// contextReuseLimit jobs ran in it, and closed otherwise. Pages Acquire did
+// not open are left alone.
+func (p *Pool) Release(page playwright.Page, succeeded bool) {
+	p.mu.Lock()
+
+	pc, ok := p.contexts[page.Context()]
+	if ok {
+		pc.jobs++
+	}
+
+	keep := ok && succeeded && p.reuse && pc.jobs < contextReuseLimit && !page.IsClosed()
+
+	if keep {
+		p.idle[pc.browser] = append(p.idle[pc.browser], pc)
+	}
+
+	p.mu.Unlock()
+
+	if ok && !keep {
+		_ = pc.bctx.Close()
+	}
+}
+
+func (p *Pool) forget(bctx playwright.BrowserContext) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pc, ok := p.contexts[bctx]
+	if !ok {
+		return
+	}
+
+	delete(p.contexts, bctx)
+
+	idle := p.idle[pc.browser]
+	for i, c := range idle {
+		if c == pc {
+			p.idle[pc.browser] = append(idle[:i:i], idle[i+1:]...)
+
+			break
+		}
+	}
+}
+
+// forgetBrowser forgets the contexts of a browser that closed or crashed.
+func (p *Pool) forgetBrowser(browser playwright.Browser) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.dropBrowser(browser)
+}
+
+// dropBrowser forgets the contexts of browser. p.mu must be held.
+func (p *Pool) dropBrowser(browser playwright.Browser) {
+	for bctx, pc := range p.contexts {
+		if pc.browser == browser {
+			delete(p.contexts, bctx)
+		}
+	}
+
+	delete(p.idle, browser)
+}
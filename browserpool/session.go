@@ -0,0 +1,221 @@
+package browserpool
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+const googleURL = "https://www.google.com"
+
+// sessionCookies are the Google cookies that carry the consent answer, which
+// every context starts from. Everything else, like the identity Google gives
+// a context, stays in the context.
+var sessionCookies = []string{gmaps.ConsentCookie, "CONSENT"}
+
+// defaultConsentCookie records a "reject all" answer to the EU consent form.
+// It is used until a real session has been captured.
+var defaultConsentCookie = storedCookie{
+	Name:   gmaps.ConsentCookie,
+	Value:  gmaps.ConsentCookieValue,
+	Domain: ".google.com",
+	Path:   "/",
+	Secure: true,
+}
+
+type storedCookie struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain"`
+	Path     string  `json:"path"`
+	Expires  float64 `json:"expires"`
+	HTTPOnly bool    `json:"http_only"`
+	Secure   bool    `json:"secure"`
+}
+
+// WithSessionFile persists the Google consent cookies to path, so restarts
+// do not have to go through the consent form again.
+func WithSessionFile(path string) Option {
+	return func(p *Pool) {
+		p.session.path = path
+	}
+}
+
+type sessionStore struct {
+	mu      sync.Mutex
+	path    string
+	cookies []storedCookie
+	loaded  bool
+}
+
+func (s *sessionStore) get() []storedCookie {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.loaded {
+		s.loaded = true
+		s.load()
+	}
+
+	if len(s.cookies) == 0 {
+		return []storedCookie{defaultConsentCookie}
+	}
+
+	return slices.Clone(s.cookies)
+}
+
+func (s *sessionStore) load() {
+	if s.path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("browserpool: cannot read session file: %v", err)
+		}
+
+		return
+	}
+
+	var cookies []storedCookie
+	if err := json.Unmarshal(data, &cookies); err != nil {
+		log.Printf("browserpool: invalid session file %s: %v", s.path, err)
+		return
+	}
+
+	now := float64(time.Now().Unix())
+
+	for _, c := range cookies {
+		// session files written before the identity was kept out hold it
+		if !slices.Contains(sessionCookies, c.Name) {
+			continue
+		}
+
+		if c.Expires <= 0 || c.Expires > now {
+			s.cookies = append(s.cookies, c)
+		}
+	}
+}
+
+// set stores cookies, writing them to the session file when they changed.
+func (s *sessionStore) set(cookies []storedCookie) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slices.Equal(s.cookies, cookies) {
+		return
+	}
+
+	s.cookies = cookies
+
+	if s.path != "" {
+		if err := s.save(); err != nil {
+			log.Printf("browserpool: cannot write session file: %v", err)
+		}
+	}
+}
+
+func (s *sessionStore) save() error {
+	data, err := json.Marshal(s.cookies)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".session-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// warmUp seeds a new context with the stored session so the first page it
+// loads is not a consent wall.
+func (p *Pool) warmUp(bctx playwright.BrowserContext) error {
+	stored := p.session.get()
+	cookies := make([]playwright.OptionalCookie, 0, len(stored))
+
+	for _, c := range stored {
+		oc := playwright.OptionalCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   playwright.String(c.Domain),
+			Path:     playwright.String(c.Path),
+			HttpOnly: playwright.Bool(c.HTTPOnly),
+			Secure:   playwright.Bool(c.Secure),
+		}
+
+		if c.Expires > 0 {
+			oc.Expires = playwright.Float(c.Expires)
+		}
+
+		cookies = append(cookies, oc)
+	}
+
+	return bctx.AddCookies(cookies)
+}
+
+// Persist captures the consent cookies of the page's context, after a job ran
+// in it, so new contexts start from the same answer.
+func (p *Pool) Persist(page playwright.Page) error {
+	cookies, err := page.Context().Cookies(googleURL)
+	if err != nil {
+		return err
+	}
+
+	var stored []storedCookie
+
+	for _, c := range cookies {
+		if !slices.Contains(sessionCookies, c.Name) {
+			continue
+		}
+
+		stored = append(stored, storedCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  c.Expires,
+			HTTPOnly: c.HttpOnly,
+			Secure:   c.Secure,
+		})
+	}
+
+	// a context that never saw the consent answer has nothing worth keeping
+	if !slices.ContainsFunc(stored, func(c storedCookie) bool {
+		return c.Name == gmaps.ConsentCookie || c.Name == "CONSENT"
+	}) {
+		return nil
+	}
+
+	slices.SortFunc(stored, func(a, b storedCookie) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	p.session.set(stored)
+
+	return nil
+}
@@ -0,0 +1,300 @@
+package categories
+
+// defaultLabels are the labels Google gives the most common business
+// categories, by category id and language.
+var defaultLabels = map[string]map[string][]string{
+	"plumber": {
+		"en": {"Plumber"},
+		"fr": {"Plombier", "Plombier chauffagiste"},
+		"de": {"Klempner", "Installateur", "Sanitärinstallateur"},
+		"es": {"Fontanero", "Fontanería"},
+		"it": {"Idraulico"},
+	},
+	"electrician": {
+		"en": {"Electrician"},
+		"fr": {"Électricien"},
+		"de": {"Elektriker", "Elektroinstallateur"},
+		"es": {"Electricista"},
+		"it": {"Elettricista"},
+	},
+	"locksmith": {
+		"en": {"Locksmith"},
+		"fr": {"Serrurier"},
+		"de": {"Schlüsseldienst", "Schlosser"},
+		"es": {"Cerrajero", "Cerrajería"},
+		"it": {"Fabbro"},
+	},
+	"painter": {
+		"en": {"Painter", "Painting contractor"},
+		"fr": {"Peintre", "Peintre en bâtiment"},
+		"de": {"Maler", "Malerbetrieb"},
+		"es": {"Pintor"},
+		"it": {"Imbianchino"},
+	},
+	"roofer": {
+		"en": {"Roofing contractor"},
+		"fr": {"Couvreur"},
+		"de": {"Dachdecker"},
+		"es": {"Empresa de tejados"},
+		"it": {"Impresa di coperture"},
+	},
+	"carpenter": {
+		"en": {"Carpenter"},
+		"fr": {"Menuisier", "Charpentier"},
+		"de": {"Tischler", "Schreiner", "Zimmerei"},
+		"es": {"Carpintero", "Carpintería"},
+		"it": {"Falegname", "Falegnameria"},
+	},
+	"mason": {
+		"en": {"Mason", "Masonry contractor"},
+		"fr": {"Maçon", "Entreprise de maçonnerie"},
+		"de": {"Maurer"},
+		"es": {"Albañil"},
+		"it": {"Muratore"},
+	},
+	"general_contractor": {
+		"en": {"General contractor", "Construction company"},
+		"fr": {"Entreprise générale du bâtiment", "Entreprise de construction"},
+		"de": {"Bauunternehmen", "Generalunternehmer"},
+		"es": {"Contratista general", "Empresa constructora"},
+		"it": {"Impresa edile", "Impresa di costruzioni"},
+	},
+	"hvac_contractor": {
+		"en": {"HVAC contractor", "Heating contractor"},
+		"fr": {"Chauffagiste", "Entreprise de climatisation"},
+		"de": {"Heizungsbauer", "Klimaanlagenfirma"},
+		"es": {"Empresa de climatización", "Instalador de calefacción"},
+		"it": {"Impresa di riscaldamento", "Impianti di climatizzazione"},
+	},
+	"landscaper": {
+		"en": {"Landscaper", "Gardener"},
+		"fr": {"Paysagiste", "Jardinier"},
+		"de": {"Garten- und Landschaftsbau", "Gärtner"},
+		"es": {"Paisajista", "Jardinero"},
+		"it": {"Paesaggista", "Giardiniere"},
+	},
+	"restaurant": {
+		"en": {"Restaurant"},
+		"fr": {"Restaurant"},
+		"de": {"Restaurant"},
+		"es": {"Restaurante"},
+		"it": {"Ristorante"},
+	},
+	"pizza_restaurant": {
+		"en": {"Pizza restaurant"},
+		"fr": {"Pizzeria"},
+		"de": {"Pizzeria"},
+		"es": {"Pizzería"},
+		"it": {"Pizzeria"},
+	},
+	"fast_food_restaurant": {
+		"en": {"Fast food restaurant"},
+		"fr": {"Restauration rapide"},
+		"de": {"Fast-Food-Restaurant", "Schnellrestaurant"},
+		"es": {"Restaurante de comida rápida"},
+		"it": {"Fast food"},
+	},
+	"cafe": {
+		"en": {"Cafe", "Coffee shop"},
+		"fr": {"Café", "Salon de thé"},
+		"de": {"Café"},
+		"es": {"Cafetería"},
+		"it": {"Caffetteria"},
+	},
+	"bar": {
+		"en": {"Bar", "Pub"},
+		"fr": {"Bar", "Pub"},
+		"de": {"Bar", "Kneipe"},
+		"es": {"Bar"},
+		"it": {"Bar", "Pub"},
+	},
+	"bakery": {
+		"en": {"Bakery"},
+		"fr": {"Boulangerie", "Boulangerie pâtisserie"},
+		"de": {"Bäckerei"},
+		"es": {"Panadería"},
+		"it": {"Panificio", "Panetteria"},
+	},
+	"butcher": {
+		"en": {"Butcher shop"},
+		"fr": {"Boucherie", "Boucherie charcuterie"},
+		"de": {"Metzgerei", "Fleischerei"},
+		"es": {"Carnicería"},
+		"it": {"Macelleria"},
+	},
+	"supermarket": {
+		"en": {"Supermarket", "Grocery store"},
+		"fr": {"Supermarché", "Épicerie"},
+		"de": {"Supermarkt", "Lebensmittelgeschäft"},
+		"es": {"Supermercado", "Tienda de comestibles"},
+		"it": {"Supermercato", "Alimentari"},
+	},
+	"hotel": {
+		"en": {"Hotel"},
+		"fr": {"Hôtel"},
+		"de": {"Hotel"},
+		"es": {"Hotel"},
+		"it": {"Hotel", "Albergo"},
+	},
+	"hair_salon": {
+		"en": {"Hair salon", "Hairdresser"},
+		"fr": {"Salon de coiffure", "Coiffeur"},
+		"de": {"Friseursalon", "Friseur"},
+		"es": {"Peluquería"},
+		"it": {"Parrucchiere"},
+	},
+	"barber": {
+		"en": {"Barber shop"},
+		"fr": {"Barbier"},
+		"de": {"Barbier"},
+		"es": {"Barbería"},
+		"it": {"Barbiere"},
+	},
+	"beauty_salon": {
+		"en": {"Beauty salon"},
+		"fr": {"Institut de beauté"},
+		"de": {"Kosmetikstudio", "Schönheitssalon"},
+		"es": {"Salón de belleza", "Centro de estética"},
+		"it": {"Centro estetico", "Istituto di bellezza"},
+	},
+	"pharmacy": {
+		"en": {"Pharmacy", "Drugstore"},
+		"fr": {"Pharmacie"},
+		"de": {"Apotheke"},
+		"es": {"Farmacia"},
+		"it": {"Farmacia"},
+	},
+	"dentist": {
+		"en": {"Dentist", "Dental clinic"},
+		"fr": {"Dentiste", "Chirurgien-dentiste", "Cabinet dentaire"},
+		"de": {"Zahnarzt", "Zahnarztpraxis"},
+		"es": {"Dentista", "Clínica dental"},
+		"it": {"Dentista", "Studio dentistico"},
+	},
+	"doctor": {
+		"en": {"Doctor", "General practitioner"},
+		"fr": {"Médecin", "Médecin généraliste"},
+		"de": {"Arzt", "Allgemeinmediziner", "Hausarzt"},
+		"es": {"Médico", "Médico de cabecera"},
+		"it": {"Medico", "Medico di base"},
+	},
+	"physiotherapist": {
+		"en": {"Physiotherapist", "Physical therapist"},
+		"fr": {"Kinésithérapeute", "Masseur kinésithérapeute"},
+		"de": {"Physiotherapeut", "Physiotherapiepraxis"},
+		"es": {"Fisioterapeuta"},
+		"it": {"Fisioterapista"},
+	},
+	"veterinarian": {
+		"en": {"Veterinarian", "Animal hospital"},
+		"fr": {"Vétérinaire", "Clinique vétérinaire"},
+		"de": {"Tierarzt", "Tierarztpraxis", "Tierklinik"},
+		"es": {"Veterinario", "Clínica veterinaria"},
+		"it": {"Veterinario", "Clinica veterinaria"},
+	},
+	"lawyer": {
+		"en": {"Lawyer", "Law firm", "Attorney"},
+		"fr": {"Avocat", "Cabinet d'avocats"},
+		"de": {"Rechtsanwalt", "Anwaltskanzlei"},
+		"es": {"Abogado", "Bufete de abogados"},
+		"it": {"Avvocato", "Studio legale"},
+	},
+	"accountant": {
+		"en": {"Accountant", "Accounting firm"},
+		"fr": {"Expert-comptable", "Cabinet d'expertise comptable", "Comptable"},
+		"de": {"Steuerberater", "Buchhalter", "Wirtschaftsprüfer"},
+		"es": {"Contable", "Asesoría contable", "Gestoría"},
+		"it": {"Commercialista", "Studio commercialista"},
+	},
+	"real_estate_agency": {
+		"en": {"Real estate agency", "Real estate agent"},
+		"fr": {"Agence immobilière"},
+		"de": {"Immobilienmakler", "Immobilienagentur"},
+		"es": {"Inmobiliaria", "Agencia inmobiliaria"},
+		"it": {"Agenzia immobiliare"},
+	},
+	"insurance_agency": {
+		"en": {"Insurance agency"},
+		"fr": {"Agence d'assurance", "Assurance"},
+		"de": {"Versicherungsagentur", "Versicherungsmakler"},
+		"es": {"Agencia de seguros", "Correduría de seguros"},
+		"it": {"Agenzia assicurativa"},
+	},
+	"bank": {
+		"en": {"Bank"},
+		"fr": {"Banque"},
+		"de": {"Bank"},
+		"es": {"Banco"},
+		"it": {"Banca"},
+	},
+	"car_repair": {
+		"en": {"Auto repair shop", "Mechanic"},
+		"fr": {"Garage automobile", "Garagiste", "Mécanicien"},
+		"de": {"Autowerkstatt", "Kfz-Werkstatt"},
+		"es": {"Taller mecánico", "Taller de reparación de automóviles"},
+		"it": {"Autofficina", "Officina meccanica"},
+	},
+	"car_dealer": {
+		"en": {"Car dealer", "Used car dealer"},
+		"fr": {"Concessionnaire automobile", "Vendeur de voitures d'occasion"},
+		"de": {"Autohaus", "Gebrauchtwagenhändler"},
+		"es": {"Concesionario de automóviles", "Concesionario de coches usados"},
+		"it": {"Concessionaria auto", "Rivenditore di auto usate"},
+	},
+	"gym": {
+		"en": {"Gym", "Fitness center"},
+		"fr": {"Salle de sport", "Centre de fitness"},
+		"de": {"Fitnessstudio"},
+		"es": {"Gimnasio"},
+		"it": {"Palestra"},
+	},
+	"florist": {
+		"en": {"Florist"},
+		"fr": {"Fleuriste"},
+		"de": {"Blumengeschäft", "Florist"},
+		"es": {"Floristería"},
+		"it": {"Fioraio", "Fiorista"},
+	},
+	"clothing_store": {
+		"en": {"Clothing store"},
+		"fr": {"Magasin de vêtements", "Boutique de vêtements"},
+		"de": {"Bekleidungsgeschäft", "Modegeschäft"},
+		"es": {"Tienda de ropa"},
+		"it": {"Negozio di abbigliamento"},
+	},
+	"optician": {
+		"en": {"Optician"},
+		"fr": {"Opticien"},
+		"de": {"Optiker"},
+		"es": {"Óptica"},
+		"it": {"Ottico"},
+	},
+	"driving_school": {
+		"en": {"Driving school"},
+		"fr": {"Auto-école"},
+		"de": {"Fahrschule"},
+		"es": {"Autoescuela"},
+		"it": {"Autoscuola"},
+	},
+	"cleaning_service": {
+		"en": {"Cleaning service", "Commercial cleaning service"},
+		"fr": {"Entreprise de nettoyage", "Service de nettoyage"},
+		"de": {"Reinigungsdienst", "Gebäudereinigung"},
+		"es": {"Servicio de limpieza", "Empresa de limpieza"},
+		"it": {"Impresa di pulizie"},
+	},
+	"moving_company": {
+		"en": {"Moving company", "Mover"},
+		"fr": {"Déménageur", "Entreprise de déménagement"},
+		"de": {"Umzugsunternehmen"},
+		"es": {"Empresa de mudanzas"},
+		"it": {"Ditta di traslochi"},
+	},
+	"architect": {
+		"en": {"Architect"},
+		"fr": {"Architecte", "Cabinet d'architecte"},
+		"de": {"Architekt", "Architekturbüro"},
+		"es": {"Arquitecto", "Estudio de arquitectura"},
+		"it": {"Architetto", "Studio di architettura"},
+	},
+}
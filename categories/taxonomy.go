@@ -0,0 +1,116 @@
+// Package categories maps the categories Google gives places, which are
+// localized in the language of the search, to canonical category ids.
+package categories
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	"gopkg.in/yaml.v3"
+)
+
+// Taxonomy maps localized category labels to category ids.
+type Taxonomy struct {
+	// labels maps a language, then a normalized label, to a category id.
+	labels map[string]map[string]string
+}
+
+// New creates a Taxonomy from the labels of each category id, keyed by
+// language, e.g. {"plumber": {"fr": ["Plombier"], "de": ["Klempner"]}}.
+func New(labels map[string]map[string][]string) *Taxonomy {
+	t := &Taxonomy{labels: map[string]map[string]string{}}
+
+	t.add(labels)
+
+	return t
+}
+
+// Default returns the built-in taxonomy of common business categories in
+// English, French, German, Spanish and Italian.
+func Default() *Taxonomy {
+	return New(defaultLabels)
+}
+
+// Load returns the built-in taxonomy extended with the YAML file path, which
+// has the layout New takes:
+//
+//	plumber:
+//	  fr: [Plombier, Plombier chauffagiste]
+//	  nl: [Loodgieter]
+//
+// A label of the file takes over the same label of the built-in taxonomy.
+func Load(path string) (*Taxonomy, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var labels map[string]map[string][]string
+	if err := yaml.Unmarshal(raw, &labels); err != nil {
+		return nil, fmt.Errorf("invalid category taxonomy %s: %w", path, err)
+	}
+
+	t := Default()
+	t.add(labels)
+
+	return t, nil
+}
+
+func (t *Taxonomy) add(labels map[string]map[string][]string) {
+	for id, langs := range labels {
+		for lang, names := range langs {
+			lang = strings.ToLower(lang)
+
+			if t.labels[lang] == nil {
+				t.labels[lang] = map[string]string{}
+			}
+
+			for _, name := range names {
+				t.labels[lang][normalize(name)] = id
+			}
+		}
+	}
+}
+
+// Map returns the category id of label, in the language lang, or "" when the
+// taxonomy does not know it. Google sometimes answers in English whatever
+// the language asked, so labels unknown in lang are looked up in English.
+func (t *Taxonomy) Map(lang, label string) string {
+	key := normalize(label)
+	if key == "" {
+		return ""
+	}
+
+	lang = strings.ToLower(lang)
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		// fr-CA uses the fr labels
+		lang = lang[:i]
+	}
+
+	if id, ok := t.labels[lang][key]; ok {
+		return id
+	}
+
+	return t.labels["en"][key]
+}
+
+// normalize lowers label and strips its accents and punctuation, so
+// "Électricien" and "electricien" match.
+func normalize(label string) string {
+	var b strings.Builder
+
+	for _, r := range norm.NFD.String(strings.ToLower(label)) {
+		switch {
+		case unicode.IsMark(r):
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
@@ -0,0 +1,43 @@
+package categories_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/categories"
+)
+
+func Test_TaxonomyMap(t *testing.T) {
+	taxonomy := categories.Default()
+
+	require.Equal(t, "plumber", taxonomy.Map("fr", "Plombier"))
+	require.Equal(t, "plumber", taxonomy.Map("de", "Klempner"))
+	require.Equal(t, "plumber", taxonomy.Map("en", "plumber"))
+	require.Equal(t, "electrician", taxonomy.Map("fr-CA", "electricien"))
+	// Google answered in English
+	require.Equal(t, "bakery", taxonomy.Map("fr", "Bakery"))
+	require.Equal(t, "", taxonomy.Map("fr", "Klempner"))
+	require.Equal(t, "", taxonomy.Map("fr", "Astrologue"))
+	require.Equal(t, "", taxonomy.Map("fr", ""))
+}
+
+func Test_TaxonomyLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "taxonomy.yaml")
+	err := os.WriteFile(path, []byte("plumber:\n  nl: [Loodgieter]\nastrologer:\n  fr: [Astrologue]\n"), 0o600)
+	require.NoError(t, err)
+
+	taxonomy, err := categories.Load(path)
+	require.NoError(t, err)
+
+	require.Equal(t, "plumber", taxonomy.Map("nl", "Loodgieter"))
+	require.Equal(t, "astrologer", taxonomy.Map("fr", "Astrologue"))
+	require.Equal(t, "plumber", taxonomy.Map("fr", "Plombier"))
+
+	require.NoError(t, os.WriteFile(path, []byte("plumber: [Loodgieter]\n"), 0o600))
+
+	_, err = categories.Load(path)
+	require.Error(t, err)
+}
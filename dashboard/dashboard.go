@@ -0,0 +1,151 @@
+// Package dashboard serves a single-page dashboard of the scrapes, so users
+// without the frontend can follow their jobs: the root jobs with their
+// progress, the tree of the jobs they spawned, why jobs failed and a preview
+// of the results. The page is in index.html.
+package dashboard
+
+import (
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gosom/google-maps-scraper/apiauth"
+	"github.com/gosom/google-maps-scraper/httpjson"
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+const (
+	// defaultLimit and maxLimit bound the jobs of a list.
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+//go:embed index.html
+var page []byte
+
+// Page serves the dashboard page. It holds no data: the page asks API for it
+// with the key its user enters.
+func Page() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/dashboard/" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(page)
+	})
+}
+
+// API serves the data of the dashboard page:
+//
+//	GET /dashboard/api/jobs                 root jobs, the newest first
+//	GET /dashboard/api/jobs/{id}            progress, summary, failures and top results of a job
+//	GET /dashboard/api/jobs/{id}/children   jobs the job spawned
+//
+// The lists take the status, owner_id, organization_id and limit query
+// parameters. The top results are decrypted with c. A key bound to an
+// organization only sees the jobs of its organization.
+func API(db *sql.DB, c *postgres.FieldCipher) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /dashboard/api/jobs", func(w http.ResponseWriter, r *http.Request) {
+		f, err := listFilter(r)
+		if err != nil {
+			writeFilterError(w, err)
+			return
+		}
+
+		writeJobs(w, r, db, f)
+	})
+
+	mux.HandleFunc("GET /dashboard/api/jobs/{id}/children", func(w http.ResponseWriter, r *http.Request) {
+		f, err := listFilter(r)
+		if err != nil {
+			writeFilterError(w, err)
+			return
+		}
+
+		f.ParentID = r.PathValue("id")
+
+		writeJobs(w, r, db, f)
+	})
+
+	mux.HandleFunc("GET /dashboard/api/jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		var report *postgres.Report
+
+		err := postgres.CheckJobOrganization(r.Context(), db, id, apiauth.GetOrganizationFromContext(r.Context()))
+		if err == nil {
+			report, err = postgres.BuildReport(r.Context(), db, c, id)
+		}
+
+		switch {
+		case errors.Is(err, postgres.ErrJobNotFound):
+			httpjson.Write(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		case err != nil:
+			httpjson.Write(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		default:
+			httpjson.Write(w, http.StatusOK, report)
+		}
+	})
+
+	return mux
+}
+
+// listFilter reads the filter of a job list from the query of r.
+func listFilter(r *http.Request) (postgres.JobListFilter, error) {
+	q := r.URL.Query()
+
+	organization, err := apiauth.RestrictOrganization(r.Context(), q.Get("organization_id"))
+	if err != nil {
+		return postgres.JobListFilter{}, err
+	}
+
+	f := postgres.JobListFilter{
+		Status:         q.Get("status"),
+		OwnerID:        q.Get("owner_id"),
+		OrganizationID: organization,
+		Limit:          defaultLimit,
+	}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxLimit {
+			return f, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+
+		f.Limit = n
+	}
+
+	return f, nil
+}
+
+// writeFilterError answers the error of listFilter: 403 when the key is
+// bound to another organization, 400 otherwise.
+func writeFilterError(w http.ResponseWriter, err error) {
+	code := http.StatusBadRequest
+	if errors.Is(err, apiauth.ErrOtherOrganization) {
+		code = http.StatusForbidden
+	}
+
+	httpjson.Write(w, code, map[string]string{"error": err.Error()})
+}
+
+func writeJobs(w http.ResponseWriter, r *http.Request, db *sql.DB, f postgres.JobListFilter) {
+	jobs, err := postgres.ListJobs(r.Context(), db, f)
+	if err != nil {
+		httpjson.Write(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	if jobs == nil {
+		jobs = []postgres.JobInfo{}
+	}
+
+	httpjson.Write(w, http.StatusOK, jobs)
+}
@@ -0,0 +1,40 @@
+package dashboard_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/dashboard"
+)
+
+func Test_Page(t *testing.T) {
+	h := dashboard.Page()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dashboard/", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+	require.Contains(t, rec.Body.String(), `fetch("api/"`)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dashboard/index.html", nil))
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func Test_API_InvalidLimit(t *testing.T) {
+	// the limit is checked before the database is read
+	h := dashboard.API(nil, nil)
+
+	for _, path := range []string{"/dashboard/api/jobs?limit=0", "/dashboard/api/jobs/abc/children?limit=1000"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		require.Equal(t, http.StatusBadRequest, rec.Code, path)
+		require.Contains(t, rec.Body.String(), "limit must be between 1 and 500", path)
+	}
+}
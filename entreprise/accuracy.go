@@ -0,0 +1,122 @@
+package entreprise
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// MatchCase is a place whose company is known: the SIREN Enrich should find
+// for Name at Address, empty when it should find none.
+type MatchCase struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	Siren   string `json:"siren"`
+}
+
+// MatchMiss is a MatchCase Enrich got wrong, with the SIREN it found, or
+// the error it returned.
+type MatchMiss struct {
+	Case  MatchCase
+	Siren string
+	Err   error
+}
+
+func (m MatchMiss) String() string {
+	got := m.Siren
+	if m.Err != nil {
+		got = m.Err.Error()
+	}
+
+	return fmt.Sprintf("%s, %s: want %q, got %s", m.Case.Name, m.Case.Address, m.Case.Siren, got)
+}
+
+// MatchReport counts how EvaluateMatching did. A case is a true positive
+// when the expected SIREN was found, a false positive when another one was,
+// a false negative when an expected company was missed, and a true negative
+// when no company was expected nor found. Expected counts the cases
+// expecting a company. Lookups that failed are counted in Errors only.
+type MatchReport struct {
+	Expected       int
+	TruePositives  int
+	FalsePositives int
+	FalseNegatives int
+	TrueNegatives  int
+	Errors         int
+	Misses         []MatchMiss
+}
+
+// Precision is the share of the companies found that are the right ones, 1
+// when none was found.
+func (r MatchReport) Precision() float64 {
+	found := r.TruePositives + r.FalsePositives
+	if found == 0 {
+		return 1
+	}
+
+	return float64(r.TruePositives) / float64(found)
+}
+
+// Recall is the share of the expected companies that were found, 1 when
+// none was expected.
+func (r MatchReport) Recall() float64 {
+	if r.Expected == 0 {
+		return 1
+	}
+
+	return float64(r.TruePositives) / float64(r.Expected)
+}
+
+func (r MatchReport) String() string {
+	return fmt.Sprintf("precision %.3f, recall %.3f (tp %d, fp %d, fn %d, tn %d, errors %d)",
+		r.Precision(), r.Recall(), r.TruePositives, r.FalsePositives, r.FalseNegatives, r.TrueNegatives, r.Errors)
+}
+
+// EvaluateMatching looks up each case with Enrich, directors skipped, and
+// reports how many it got right, so that changes of the scoring can be
+// measured against known companies.
+func EvaluateMatching(ctx context.Context, cases []MatchCase, opts ...EnrichOption) MatchReport {
+	var ans MatchReport
+
+	opts = append([]EnrichOption{WithoutDirectors()}, opts...)
+
+	for _, c := range cases {
+		want := NormalizeSiren(c.Siren)
+
+		company, err := Enrich(ctx, c.Name, c.Address, opts...)
+
+		var got string
+		if company != nil {
+			got = NormalizeSiren(company.SocieteSiren)
+		}
+
+		switch {
+		case err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrLowConfidence):
+			ans.Errors++
+			ans.Misses = append(ans.Misses, MatchMiss{Case: c, Err: err})
+
+			continue
+		case want != "":
+			ans.Expected++
+		}
+
+		switch {
+		case got != "" && got == want:
+			ans.TruePositives++
+
+			continue
+		case got == "" && want == "":
+			ans.TrueNegatives++
+
+			continue
+		case got != "":
+			ans.FalsePositives++
+		default:
+			ans.FalseNegatives++
+		}
+
+		ans.Misses = append(ans.Misses, MatchMiss{Case: c, Siren: got})
+	}
+
+	return ans
+}
@@ -0,0 +1,121 @@
+package entreprise_test
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/entreprise"
+)
+
+// the accuracy the matching must keep on testdata/matching.json, raise them
+// when it improves
+const (
+	minPrecision = 1.0
+	minRecall    = 0.9
+)
+
+var recordMatching = flag.Bool("matching.record", false, "query the live GOUV API and store its answers in testdata/matching.json")
+
+type matchingCase struct {
+	entreprise.MatchCase
+	// Response is the answer of the GOUV search API to the case.
+	Response json.RawMessage `json:"response"`
+}
+
+var postalCodeRe = regexp.MustCompile(`\b\d{5}\b`)
+
+// matchingKey is the key of the GOUV search of name at address.
+func matchingKey(name, postalCode string) string {
+	return entreprise.ProcessForSearch(name) + "|" + postalCode
+}
+
+func Test_MatchingAccuracy(t *testing.T) {
+	const path = "testdata/matching.json"
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var cases []matchingCase
+	require.NoError(t, json.Unmarshal(raw, &cases))
+
+	var (
+		mu      sync.Mutex
+		byKey   = map[string]int{}
+		unknown []string
+		matches = make([]entreprise.MatchCase, 0, len(cases))
+	)
+
+	for i, c := range cases {
+		byKey[matchingKey(c.Name, postalCodeRe.FindString(c.Address))] = i
+		matches = append(matches, c.MatchCase)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := matchingKey(r.URL.Query().Get("q"), r.URL.Query().Get("code_postal"))
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		i, ok := byKey[key]
+		if !ok {
+			unknown = append(unknown, key)
+			_, _ = io.WriteString(w, `{"results": []}`)
+
+			return
+		}
+
+		if *recordMatching {
+			resp, err := http.Get("https://recherche-entreprises.api.gouv.fr" + r.URL.RequestURI())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil || resp.StatusCode != http.StatusOK {
+				http.Error(w, string(body), http.StatusBadGateway)
+				return
+			}
+
+			cases[i].Response = body
+		}
+
+		_, _ = w.Write(cases[i].Response)
+	}))
+	defer srv.Close()
+
+	// GOUV only: the other registers need accounts
+	service := entreprise.NewServiceWithCredentials(entreprise.Credentials{},
+		entreprise.WithEndpoints(entreprise.Endpoints{GOUV: srv.URL}))
+
+	report := entreprise.EvaluateMatching(context.Background(), matches,
+		entreprise.WithService(service), entreprise.WithReconciliation(false))
+
+	t.Log(report)
+
+	for _, m := range report.Misses {
+		t.Log(m)
+	}
+
+	require.Empty(t, unknown, "searches without a recorded response")
+	require.Equal(t, 0, report.Errors)
+	require.GreaterOrEqual(t, report.Precision(), minPrecision)
+	require.GreaterOrEqual(t, report.Recall(), minRecall)
+
+	if *recordMatching {
+		raw, err := json.MarshalIndent(cases, "", "  ")
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, append(raw, '\n'), 0o600))
+	}
+}
@@ -0,0 +1,309 @@
+package entreprise
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AddressParser splits the address of a place into the fields the company
+// registers index.
+type AddressParser interface {
+	Parse(address string) ParsedAddress
+}
+
+// WithAddressParser makes the service match places with companies on the
+// addresses p parses, instead of those of LocalAddressParser.
+func WithAddressParser(p AddressParser) ServiceOption {
+	return func(o *serviceOptions) {
+		o.parser = p
+	}
+}
+
+var (
+	postalCodeRe  = regexp.MustCompile(`\b\d{5}\b`)
+	cedexRe       = regexp.MustCompile(`\bCEDEX\b.*$`)
+	houseNumberRe = regexp.MustCompile(`^(\d+)([A-Z]*)$`)
+	numberRangeRe = regexp.MustCompile(`^\d+$`)
+)
+
+// complementsNumeroVoie maps the suffixes of a house number to the words the
+// registers use.
+var complementsNumeroVoie = map[string]string{
+	"B":         "BIS",
+	"BIS":       "BIS",
+	"T":         "TER",
+	"TER":       "TER",
+	"Q":         "QUATER",
+	"QUATER":    "QUATER",
+	"QUINQUIES": "QUINQUIES",
+}
+
+// LocalAddressParser parses addresses as Google Maps writes them for French
+// places, e.g. "Centre Commercial Les Halles, 12 bis Av. de la République,
+// 75011 Paris, France", without calling any service.
+//
+// The last part holding a 5 digit postal code gives the postal code and the
+// commune, without its CEDEX. Of the parts before it, the one that looks most
+// like a street, a house number followed by a street type, is the street; the
+// others become AdresseBis. Addresses without a postal code parse to an empty
+// ParsedAddress.
+type LocalAddressParser struct{}
+
+func (LocalAddressParser) Parse(address string) ParsedAddress {
+	var result ParsedAddress
+
+	var parts []string
+
+	for _, part := range strings.FieldsFunc(address, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if part = normalizeCompanyName(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	if n := len(parts); n > 0 && parts[n-1] == "FRANCE" {
+		parts = parts[:n-1]
+	}
+
+	city := -1
+
+	for i := len(parts) - 1; i >= 0; i-- {
+		if postalCodeRe.MatchString(parts[i]) {
+			city = i
+			break
+		}
+	}
+
+	if city < 0 {
+		return result
+	}
+
+	loc := postalCodeRe.FindStringIndex(parts[city])
+	result.PostalCode = parts[city][loc[0]:loc[1]]
+	result.LibelleCommune = strings.TrimSpace(cedexRe.ReplaceAllString(parts[city][loc[1]:], ""))
+
+	rest := parts[:city:city]
+	if before := strings.TrimSpace(parts[city][:loc[0]]); before != "" {
+		rest = append(rest, before)
+	}
+
+	street, best := -1, 0
+
+	for i, part := range rest {
+		// on a tie the later part wins, Google puts the building before
+		// the street
+		if score := streetScore(part); score > 0 && score >= best {
+			street, best = i, score
+		}
+	}
+
+	var bis []string
+
+	for i, part := range rest {
+		if i != street {
+			bis = append(bis, part)
+			continue
+		}
+
+		if prefix := parseStreet(part, &result); prefix != "" {
+			bis = append(bis, prefix)
+		}
+	}
+
+	result.AdresseBis = strings.Join(bis, " ")
+
+	return result
+}
+
+// streetScore tells how much part looks like a street: 2 for a house number
+// followed by a street type, 1 for either of them and 0 otherwise.
+func streetScore(part string) int {
+	tokens := strings.Fields(part)
+
+	if n := houseNumberAt(tokens); n >= 0 {
+		if _, ok := streetTypeAt(tokens, n); ok {
+			return 2
+		}
+
+		return 1
+	}
+
+	if _, ok := streetTypeAt(tokens, 0); ok {
+		return 1
+	}
+
+	return 0
+}
+
+// houseNumberAt returns the index of the first token after the house number
+// of tokens and its complement, or -1 when there is no house number. A number
+// counts as the house number when it starts the part or comes before a street
+// type, so "RUE DU 8 MAI 1945" has none.
+func houseNumberAt(tokens []string) int {
+	for i, token := range tokens {
+		if !houseNumberRe.MatchString(token) {
+			continue
+		}
+
+		next := i + 1
+
+		// a range such as "5-7", normalized to "5 7", keeps its first number
+		if next+1 < len(tokens) && numberRangeRe.MatchString(tokens[next]) {
+			if _, ok := streetTypeAt(tokens, next+1); ok {
+				next++
+			}
+		}
+
+		if next < len(tokens) {
+			if _, ok := complementsNumeroVoie[tokens[next]]; ok {
+				if _, typed := streetTypeAt(tokens, next+1); typed || len(tokens[next]) > 1 {
+					next++
+				}
+			}
+		}
+
+		if i == 0 {
+			return next
+		}
+
+		if _, ok := streetTypeAt(tokens, next); ok {
+			return next
+		}
+	}
+
+	return -1
+}
+
+// streetTypeAt returns the street type starting at tokens[i], trying two word
+// types first, and the number of tokens it spans.
+func streetTypeAt(tokens []string, i int) (int, bool) {
+	if i+1 < len(tokens) {
+		if _, ok := typeVoieAbbreviations[tokens[i]+" "+tokens[i+1]]; ok {
+			return 2, true
+		}
+	}
+
+	if i < len(tokens) {
+		if _, ok := typeVoieAbbreviations[tokens[i]]; ok {
+			return 1, true
+		}
+	}
+
+	return 0, false
+}
+
+// parseStreet fills the street fields of result from part and returns the
+// text before the house number, e.g. a building.
+func parseStreet(part string, result *ParsedAddress) string {
+	tokens := strings.Fields(part)
+
+	var prefix string
+
+	start := 0
+
+	if next := houseNumberAt(tokens); next >= 0 {
+		for i, token := range tokens[:next] {
+			m := houseNumberRe.FindStringSubmatch(token)
+			if m == nil {
+				continue
+			}
+
+			prefix = strings.Join(tokens[:i], " ")
+			result.NumVoie = m[1]
+
+			if c, ok := complementsNumeroVoie[m[2]]; ok {
+				result.ComplementNumeroVoie = c
+			} else if c, ok := complementsNumeroVoie[tokens[next-1]]; ok && next-1 > i {
+				result.ComplementNumeroVoie = c
+			}
+
+			break
+		}
+
+		start = next
+	}
+
+	if n, ok := streetTypeAt(tokens, start); ok {
+		result.TypeVoie = normalizeTypeVoie(strings.Join(tokens[start:start+n], " "))
+		start += n
+	}
+
+	result.LibelleVoie = strings.Join(tokens[start:], " ")
+
+	return prefix
+}
+
+// banMinScore is the score under which a BAN match is ignored.
+const banMinScore = 0.6
+
+// banCacheSize bounds the addresses BANAddressParser remembers; the same
+// place is parsed several times while looking for its company.
+const banCacheSize = 10000
+
+// BANAddressParser parses addresses with the geocoder of the Base Adresse
+// Nationale, which knows the official street and commune names. It keeps the
+// AdresseBis of its fallback, and falls back entirely when the BAN has no
+// confident match or cannot be reached.
+type BANAddressParser struct {
+	ban      *BANService
+	fallback AddressParser
+	timeout  time.Duration
+
+	mu    sync.Mutex
+	cache map[string]ParsedAddress
+}
+
+func NewBANAddressParser(opts ...ServiceOption) *BANAddressParser {
+	return &BANAddressParser{
+		ban:      NewBANService(opts...),
+		fallback: LocalAddressParser{},
+		timeout:  5 * time.Second,
+		cache:    map[string]ParsedAddress{},
+	}
+}
+
+func (p *BANAddressParser) Parse(address string) ParsedAddress {
+	p.mu.Lock()
+	cached, ok := p.cache[address]
+	p.mu.Unlock()
+
+	if ok {
+		return cached
+	}
+
+	result := p.fallback.Parse(address)
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	loc, err := p.ban.Search(ctx, address)
+	if err != nil {
+		// not cached, the BAN may be back for the next place
+		return result
+	}
+
+	if loc != nil && loc.Score >= banMinScore && (loc.Type == "housenumber" || loc.Type == "street") {
+		result.PostalCode = loc.PostCode
+		result.LibelleCommune = normalizeCompanyName(loc.City)
+
+		var street ParsedAddress
+
+		parseStreet(normalizeCompanyName(loc.HouseNumber+" "+loc.Street), &street)
+
+		result.NumVoie = street.NumVoie
+		result.ComplementNumeroVoie = street.ComplementNumeroVoie
+		result.TypeVoie = street.TypeVoie
+		result.LibelleVoie = street.LibelleVoie
+	}
+
+	p.mu.Lock()
+	if len(p.cache) >= banCacheSize {
+		p.cache = map[string]ParsedAddress{}
+	}
+	p.cache[address] = result
+	p.mu.Unlock()
+
+	return result
+}
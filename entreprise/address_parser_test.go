@@ -0,0 +1,175 @@
+package entreprise_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/entreprise"
+)
+
+func Test_LocalAddressParser(t *testing.T) {
+	tests := []struct {
+		address string
+		want    entreprise.ParsedAddress
+	}{
+		{
+			address: "12 Rue de la Paix, 75002 Paris, France",
+			want:    entreprise.ParsedAddress{PostalCode: "75002", NumVoie: "12", TypeVoie: "RUE", LibelleVoie: "DE LA PAIX", LibelleCommune: "PARIS"},
+		},
+		{
+			address: "12 Rue de la Paix, 75002 Paris",
+			want:    entreprise.ParsedAddress{PostalCode: "75002", NumVoie: "12", TypeVoie: "RUE", LibelleVoie: "DE LA PAIX", LibelleCommune: "PARIS"},
+		},
+		{
+			address: "5 Av. Anatole France, 75007 Paris, France",
+			want:    entreprise.ParsedAddress{PostalCode: "75007", NumVoie: "5", TypeVoie: "AVENUE", LibelleVoie: "ANATOLE FRANCE", LibelleCommune: "PARIS"},
+		},
+		{
+			address: "8 Bd de la Croisette, 06400 Cannes",
+			want:    entreprise.ParsedAddress{PostalCode: "06400", NumVoie: "8", TypeVoie: "BOULEVARD", LibelleVoie: "DE LA CROISETTE", LibelleCommune: "CANNES"},
+		},
+		{
+			address: "14 bis Rue des Lilas, 69003 Lyon",
+			want:    entreprise.ParsedAddress{PostalCode: "69003", NumVoie: "14", ComplementNumeroVoie: "BIS", TypeVoie: "RUE", LibelleVoie: "DES LILAS", LibelleCommune: "LYON"},
+		},
+		{
+			address: "14bis Rue des Lilas, 69003 Lyon",
+			want:    entreprise.ParsedAddress{PostalCode: "69003", NumVoie: "14", ComplementNumeroVoie: "BIS", TypeVoie: "RUE", LibelleVoie: "DES LILAS", LibelleCommune: "LYON"},
+		},
+		{
+			address: "3 ter Pl. du Marché, 33000 Bordeaux",
+			want:    entreprise.ParsedAddress{PostalCode: "33000", NumVoie: "3", ComplementNumeroVoie: "TER", TypeVoie: "PLACE", LibelleVoie: "DU MARCHE", LibelleCommune: "BORDEAUX"},
+		},
+		{
+			address: "7B Chem. des Vignes, 84000 Avignon",
+			want:    entreprise.ParsedAddress{PostalCode: "84000", NumVoie: "7", ComplementNumeroVoie: "BIS", TypeVoie: "CHEMIN", LibelleVoie: "DES VIGNES", LibelleCommune: "AVIGNON"},
+		},
+		{
+			address: "5-7 Rue Victor Hugo, 59000 Lille",
+			want:    entreprise.ParsedAddress{PostalCode: "59000", NumVoie: "5", TypeVoie: "RUE", LibelleVoie: "VICTOR HUGO", LibelleCommune: "LILLE"},
+		},
+		{
+			address: "Centre Commercial Les Halles, 1 Rue Pierre Lescot, 75001 Paris",
+			want:    entreprise.ParsedAddress{PostalCode: "75001", NumVoie: "1", TypeVoie: "RUE", LibelleVoie: "PIERRE LESCOT", LibelleCommune: "PARIS", AdresseBis: "CENTRE COMMERCIAL LES HALLES"},
+		},
+		{
+			address: "2 Allée des Roses, Bâtiment B, 44000 Nantes",
+			want:    entreprise.ParsedAddress{PostalCode: "44000", NumVoie: "2", TypeVoie: "ALLEE", LibelleVoie: "DES ROSES", LibelleCommune: "NANTES", AdresseBis: "BATIMENT B"},
+		},
+		{
+			address: "ZA du Moulin, 44120 Vertou",
+			want:    entreprise.ParsedAddress{PostalCode: "44120", TypeVoie: "ZONE", LibelleVoie: "DU MOULIN", LibelleCommune: "VERTOU"},
+		},
+		{
+			address: "ZI des Paluds, 13400 Aubagne",
+			want:    entreprise.ParsedAddress{PostalCode: "13400", TypeVoie: "ZONE INDUSTRIELLE", LibelleVoie: "DES PALUDS", LibelleCommune: "AUBAGNE"},
+		},
+		{
+			address: "Zone Industrielle Nord, 21000 Dijon",
+			want:    entreprise.ParsedAddress{PostalCode: "21000", TypeVoie: "ZONE INDUSTRIELLE", LibelleVoie: "NORD", LibelleCommune: "DIJON"},
+		},
+		{
+			address: "Lieu-dit Les Granges, 24200 Sarlat-la-Canéda",
+			want:    entreprise.ParsedAddress{PostalCode: "24200", TypeVoie: "LIEU DIT", LibelleVoie: "LES GRANGES", LibelleCommune: "SARLAT LA CANEDA"},
+		},
+		{
+			address: "10 Rue du 8 Mai 1945, 38000 Grenoble",
+			want:    entreprise.ParsedAddress{PostalCode: "38000", NumVoie: "10", TypeVoie: "RUE", LibelleVoie: "DU 8 MAI 1945", LibelleCommune: "GRENOBLE"},
+		},
+		{
+			address: "Rue du 8 Mai 1945, 38000 Grenoble",
+			want:    entreprise.ParsedAddress{PostalCode: "38000", TypeVoie: "RUE", LibelleVoie: "DU 8 MAI 1945", LibelleCommune: "GRENOBLE"},
+		},
+		{
+			address: "45 Quai des Chartrons, 33300 Bordeaux Cedex",
+			want:    entreprise.ParsedAddress{PostalCode: "33300", NumVoie: "45", TypeVoie: "QUAI", LibelleVoie: "DES CHARTRONS", LibelleCommune: "BORDEAUX"},
+		},
+		{
+			address: "1 Esplanade de la Défense, 92400 Courbevoie Cedex 12, France",
+			want:    entreprise.ParsedAddress{PostalCode: "92400", NumVoie: "1", TypeVoie: "ESPLANADE", LibelleVoie: "DE LA DEFENSE", LibelleCommune: "COURBEVOIE"},
+		},
+		{
+			address: "23 Rue d'Alsace-Lorraine, 31000 Toulouse",
+			want:    entreprise.ParsedAddress{PostalCode: "31000", NumVoie: "23", TypeVoie: "RUE", LibelleVoie: "D ALSACE LORRAINE", LibelleCommune: "TOULOUSE"},
+		},
+		{
+			address: "18 Rte de Lyon, 38080 L'Isle-d'Abeau",
+			want:    entreprise.ParsedAddress{PostalCode: "38080", NumVoie: "18", TypeVoie: "ROUTE", LibelleVoie: "DE LYON", LibelleCommune: "L ISLE D ABEAU"},
+		},
+		{
+			address: "32 Fbg Saint-Antoine, 75012 Paris",
+			want:    entreprise.ParsedAddress{PostalCode: "75012", NumVoie: "32", TypeVoie: "FAUBOURG", LibelleVoie: "SAINT ANTOINE", LibelleCommune: "PARIS"},
+		},
+		{
+			address: "100 Prom. des Anglais, 06000 Nice",
+			want:    entreprise.ParsedAddress{PostalCode: "06000", NumVoie: "100", TypeVoie: "PROMENADE", LibelleVoie: "DES ANGLAIS", LibelleCommune: "NICE"},
+		},
+		{
+			address: "Rond-Point des Champs-Élysées, 75008 Paris",
+			want:    entreprise.ParsedAddress{PostalCode: "75008", TypeVoie: "ROND POINT", LibelleVoie: "DES CHAMPS ELYSEES", LibelleCommune: "PARIS"},
+		},
+		{
+			address: "Résidence les Pins, Impasse des Mimosas, 83000 Toulon",
+			want:    entreprise.ParsedAddress{PostalCode: "83000", TypeVoie: "IMPASSE", LibelleVoie: "DES MIMOSAS", LibelleCommune: "TOULON", AdresseBis: "RESIDENCE LES PINS"},
+		},
+		{
+			address: "Bât. A, 12 Rue Jean Jaurès, 29200 Brest",
+			want:    entreprise.ParsedAddress{PostalCode: "29200", NumVoie: "12", TypeVoie: "RUE", LibelleVoie: "JEAN JAURES", LibelleCommune: "BREST", AdresseBis: "BAT A"},
+		},
+		{
+			address: "Bâtiment A 12 Rue Jean Jaurès, 29200 Brest",
+			want:    entreprise.ParsedAddress{PostalCode: "29200", NumVoie: "12", TypeVoie: "RUE", LibelleVoie: "JEAN JAURES", LibelleCommune: "BREST", AdresseBis: "BATIMENT A"},
+		},
+		{
+			address: "Galerie Marchande, Centre Commercial Carrefour, 1 Av. de l'Europe, 67300 Schiltigheim",
+			want:    entreprise.ParsedAddress{PostalCode: "67300", NumVoie: "1", TypeVoie: "AVENUE", LibelleVoie: "DE L EUROPE", LibelleCommune: "SCHILTIGHEIM", AdresseBis: "GALERIE MARCHANDE CENTRE COMMERCIAL CARREFOUR"},
+		},
+		{
+			address: "15 Cours Mirabeau, 13100 Aix-en-Provence",
+			want:    entreprise.ParsedAddress{PostalCode: "13100", NumVoie: "15", TypeVoie: "COURS", LibelleVoie: "MIRABEAU", LibelleCommune: "AIX EN PROVENCE"},
+		},
+		{
+			address: "4 Imp. des Lilas, 35000 Rennes",
+			want:    entreprise.ParsedAddress{PostalCode: "35000", NumVoie: "4", TypeVoie: "IMPASSE", LibelleVoie: "DES LILAS", LibelleCommune: "RENNES"},
+		},
+		{
+			address: "Le Bourg, 71250 Cluny",
+			want:    entreprise.ParsedAddress{PostalCode: "71250", LibelleCommune: "CLUNY", AdresseBis: "LE BOURG"},
+		},
+		{
+			address: "Hameau de la Forêt, 74400 Chamonix-Mont-Blanc",
+			want:    entreprise.ParsedAddress{PostalCode: "74400", TypeVoie: "HAMEAU", LibelleVoie: "DE LA FORET", LibelleCommune: "CHAMONIX MONT BLANC"},
+		},
+		{
+			address: "12 Les Grands Champs, 49000 Angers",
+			want:    entreprise.ParsedAddress{PostalCode: "49000", NumVoie: "12", LibelleVoie: "LES GRANDS CHAMPS", LibelleCommune: "ANGERS"},
+		},
+		{
+			address: "75002 Paris",
+			want:    entreprise.ParsedAddress{PostalCode: "75002", LibelleCommune: "PARIS"},
+		},
+		{
+			address: "12 Rue de la Paix 75002 Paris",
+			want:    entreprise.ParsedAddress{PostalCode: "75002", NumVoie: "12", TypeVoie: "RUE", LibelleVoie: "DE LA PAIX", LibelleCommune: "PARIS"},
+		},
+		{
+			address: "9 Rue Victor Schœlcher, 97200 Fort-de-France, Martinique",
+			want:    entreprise.ParsedAddress{PostalCode: "97200", NumVoie: "9", TypeVoie: "RUE", LibelleVoie: "VICTOR SCH LCHER", LibelleCommune: "FORT DE FRANCE"},
+		},
+		{
+			address: "Rue de la Paix, Paris",
+			want:    entreprise.ParsedAddress{},
+		},
+		{
+			address: "",
+			want:    entreprise.ParsedAddress{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.address, func(t *testing.T) {
+			require.Equal(t, tc.want, entreprise.LocalAddressParser{}.Parse(tc.address))
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package entreprise
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	banBaseURL         = "https://api-adresse.data.gouv.fr"
+	banReverseEndpoint = "/reverse/"
+	banSearchEndpoint  = "/search/"
+)
+
+// BANLocation is an address of the Base Adresse Nationale.
+type BANLocation struct {
+	// CityCode is the INSEE code of the commune, e.g. 75102.
+	CityCode string
+	PostCode string
+	City     string
+	Label    string
+	Score    float64
+	// Type is how precise the address is: housenumber, street, locality or
+	// municipality.
+	Type        string
+	HouseNumber string
+	Street      string
+}
+
+type banResponse struct {
+	Features []struct {
+		Properties struct {
+			CityCode string  `json:"citycode"`
+			PostCode string  `json:"postcode"`
+			City     string  `json:"city"`
+			Label    string  `json:"label"`
+			Score    float64 `json:"score"`
+			Type     string  `json:"type"`
+			// HouseNumber keeps its suffix, e.g. 12bis
+			HouseNumber string `json:"housenumber"`
+			Street      string `json:"street"`
+		} `json:"properties"`
+	} `json:"features"`
+}
+
+// BANService looks up French addresses in the Base Adresse Nationale.
+type BANService struct {
+	client  *http.Client
+	baseURL string
+}
+
+func NewBANService(opts ...ServiceOption) *BANService {
+	o := newServiceOptions(opts)
+
+	return &BANService{
+		client:  newHTTPClient(10*time.Second, o),
+		baseURL: endpointOr(o.endpoints.BAN, banBaseURL),
+	}
+}
+
+// Reverse returns the address closest to lat, lon, or nil when there is none,
+// e.g. outside France.
+func (s *BANService) Reverse(ctx context.Context, lat, lon float64) (*BANLocation, error) {
+	params := url.Values{}
+	params.Set("lat", strconv.FormatFloat(lat, 'f', -1, 64))
+	params.Set("lon", strconv.FormatFloat(lon, 'f', -1, 64))
+	params.Set("limit", "1")
+
+	return s.first(ctx, banReverseEndpoint, params)
+}
+
+// Search returns the address best matching the free text q, or nil when
+// there is none.
+func (s *BANService) Search(ctx context.Context, q string) (*BANLocation, error) {
+	params := url.Values{}
+	params.Set("q", q)
+	params.Set("limit", "1")
+
+	return s.first(ctx, banSearchEndpoint, params)
+}
+
+// first queries endpoint and returns its first address.
+func (s *BANService) first(ctx context.Context, endpoint string, params url.Values) (*BANLocation, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("BAN request failed: status %d", resp.StatusCode)
+	}
+
+	var body banResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("error decoding BAN response: %w", err)
+	}
+
+	if len(body.Features) == 0 {
+		return nil, nil
+	}
+
+	p := body.Features[0].Properties
+
+	return &BANLocation{
+		CityCode:    p.CityCode,
+		PostCode:    p.PostCode,
+		City:        p.City,
+		Label:       p.Label,
+		Score:       p.Score,
+		Type:        p.Type,
+		HouseNumber: p.HouseNumber,
+		Street:      p.Street,
+	}, nil
+}
@@ -31,3 +31,10 @@ func CreateLikeConditions(companyName string) string {
 
 	return strings.Join(conditions, " OR ")
 }
+
+// NormalizeCompanyName upper cases name and strips its accents and
+// punctuation, so the spellings of a name found on Google and in the company
+// registers compare equal.
+func NormalizeCompanyName(name string) string {
+	return normalizeCompanyName(name)
+}
@@ -0,0 +1,40 @@
+package entreprise_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/entreprise"
+)
+
+func Test_DepartmentFromPostalCode(t *testing.T) {
+	tests := []struct {
+		code   string
+		want   string
+		region string
+	}{
+		{"75002", "75", "Île-de-France"},
+		{"01000", "01", "Auvergne-Rhône-Alpes"},
+		{"20000", "2A", "Corse"},
+		{"20200", "2B", "Corse"},
+		{"97400", "974", "La Réunion"},
+		{"97600", "976", "Mayotte"},
+	}
+
+	for _, tc := range tests {
+		d, ok := entreprise.DepartmentFromPostalCode(tc.code)
+		require.True(t, ok, tc.code)
+		require.Equal(t, tc.want, d.Code)
+		require.Equal(t, tc.region, d.Region)
+	}
+
+	for _, code := range []string{"", "7500", "98000", "97500", "ABCDE"} {
+		_, ok := entreprise.DepartmentFromPostalCode(code)
+		require.False(t, ok, code)
+	}
+
+	d, ok := entreprise.DepartmentFromCommune("2a004")
+	require.True(t, ok)
+	require.Equal(t, "Corse-du-Sud", d.Name)
+}
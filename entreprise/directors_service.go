@@ -2,13 +2,12 @@ package entreprise
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"regexp"
 	"strconv"
 	"strings"
@@ -18,61 +17,70 @@ import (
 type DirectorInfo struct {
 	Nom    string
 	Prenom string
+	// Source is the register the director was found in.
+	Source string
 }
 
 type DirectorsService struct {
-	client *http.Client
+	client    *http.Client
+	endpoints Endpoints
+	// inpi logs in to INPI for the lookups by SIRET, which are skipped
+	// when it is nil.
+	inpi *INPIService
+	// pappersAPIKey makes the Pappers lookups go through its API instead of
+	// its web pages.
+	pappersAPIKey string
 }
 
-func NewDirectorsService() *DirectorsService {
+func NewDirectorsService(opts ...ServiceOption) *DirectorsService {
+	o := newServiceOptions(opts)
+
 	return &DirectorsService{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:        10,
-				IdleConnTimeout:     30 * time.Second,
-				DisableKeepAlives:   false,
-				MaxIdleConnsPerHost: 2,
-			},
-		},
+		client:    newHTTPClient(30*time.Second, o),
+		endpoints: o.endpoints,
 	}
 }
 
-func (s *DirectorsService) GetDirectors(siren string, siret string) *DirectorInfo {
+func (s *DirectorsService) GetDirectors(ctx context.Context, siren string, siret string) *DirectorInfo {
 	if siret != "" {
-		directors := s.getDirectorsFromInpiBySiret(siret)
+		directors := s.getDirectorsFromInpiBySiret(ctx, siret)
 		if directors != nil && directors.Nom != "" && directors.Prenom != "" {
+			directors.Source = "INPI"
 			return directors
 		}
 	}
 
-	directors := s.getDirectorsFromAnnuaireEntreprises(siren)
+	directors := s.getDirectorsFromAnnuaireEntreprises(ctx, siren)
 	if directors != nil && directors.Nom != "" && directors.Prenom != "" {
+		directors.Source = "GOUV"
 		return directors
 	}
 
-	directors = s.getDirectorsFromInpiSearch(siren)
+	directors = s.getDirectorsFromInpiSearch(ctx, siren)
 	if directors != nil && directors.Nom != "" && directors.Prenom != "" {
+		directors.Source = "INPI"
 		return directors
 	}
 
-	directors = s.getDirectorsFromBodacc(siren)
+	directors = s.getDirectorsFromBodacc(ctx, siren)
 	if directors != nil && directors.Nom != "" && directors.Prenom != "" {
+		directors.Source = "BODACC"
 		return directors
 	}
 
-	directors = s.getDirectorsFromPappers(siren)
+	directors = s.getDirectorsFromPappers(ctx, siren)
 	if directors != nil && directors.Nom != "" && directors.Prenom != "" {
+		directors.Source = "Pappers"
 		return directors
 	}
 
 	return nil
 }
 
-func (s *DirectorsService) getDirectorsFromAnnuaireEntreprises(siren string) *DirectorInfo {
-	url := fmt.Sprintf("https://recherche-entreprises.api.gouv.fr/entreprises/%s", siren)
+func (s *DirectorsService) getDirectorsFromAnnuaireEntreprises(ctx context.Context, siren string) *DirectorInfo {
+	url := fmt.Sprintf("%s/entreprises/%s", s.endpoints.gouvURL(), siren)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil
 	}
@@ -129,8 +137,8 @@ func (s *DirectorsService) getDirectorsFromAnnuaireEntreprises(siren string) *Di
 	return nil
 }
 
-func (s *DirectorsService) getDirectorsFromBodacc(siren string) *DirectorInfo {
-	baseURL := "https://bodacc-datadila.opendatasoft.com/api/explore/v2.1"
+func (s *DirectorsService) getDirectorsFromBodacc(ctx context.Context, siren string) *DirectorInfo {
+	baseURL := endpointOr(s.endpoints.BODACC, bodaccBaseURL)
 	dataset := "annonces-commerciales"
 
 	searchQuery := fmt.Sprintf(`registre:"%s"`, siren)
@@ -141,7 +149,7 @@ func (s *DirectorsService) getDirectorsFromBodacc(siren string) *DirectorInfo {
 
 	searchURL := fmt.Sprintf("%s/catalog/datasets/%s/records?%s", baseURL, dataset, params.Encode())
 
-	req, err := http.NewRequest("GET", searchURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL, nil)
 	if err != nil {
 		return nil
 	}
@@ -225,16 +233,19 @@ func (s *DirectorsService) getDirectorsFromBodacc(siren string) *DirectorInfo {
 	return nil
 }
 
-func (s *DirectorsService) getDirectorsFromInpiBySiret(siret string) *DirectorInfo {
+func (s *DirectorsService) getDirectorsFromInpiBySiret(ctx context.Context, siret string) *DirectorInfo {
 	const retries = 3
-	const inpiRNEBaseURL = "https://registre-national-entreprises.inpi.fr/api"
+
+	if s.inpi == nil {
+		return nil
+	}
 
 	var jwt string
 	var err error
 
 	for attempt := 0; attempt < retries; attempt++ {
 		if jwt == "" {
-			jwt, err = getINPIJWTToken()
+			jwt, err = s.inpi.getAuthToken(ctx)
 			if err != nil {
 				log.Printf("getDirectorsFromInpiBySiret: Failed to get INPI JWT token: %v", err)
 				if attempt < retries-1 {
@@ -245,9 +256,9 @@ func (s *DirectorsService) getDirectorsFromInpiBySiret(siret string) *DirectorIn
 			}
 		}
 
-		url := fmt.Sprintf("%s/companies?siret=%s", inpiRNEBaseURL, siret)
+		url := fmt.Sprintf("%s%s?siret=%s", s.inpi.baseURL, inpiCompaniesEndpoint, siret)
 
-		req, err := http.NewRequest("GET", url, nil)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 		if err != nil {
 			log.Printf("getDirectorsFromInpiBySiret: Error creating request: %v", err)
 			if attempt < retries-1 {
@@ -271,6 +282,16 @@ func (s *DirectorsService) getDirectorsFromInpiBySiret(siret string) *DirectorIn
 		}
 		defer resp.Body.Close()
 
+		if resp.StatusCode == http.StatusUnauthorized {
+			s.inpi.invalidateToken(jwt)
+			jwt = ""
+
+			if attempt < retries-1 {
+				continue
+			}
+			return nil
+		}
+
 		if resp.StatusCode == http.StatusTooManyRequests {
 			retryAfter := resp.Header.Get("Retry-After")
 			waitTime := time.Duration(1<<uint(attempt)) * time.Second
@@ -313,66 +334,6 @@ func (s *DirectorsService) getDirectorsFromInpiBySiret(siret string) *DirectorIn
 	return nil
 }
 
-func getINPIJWTToken() (string, error) {
-	username := os.Getenv("INPI_USERNAME")
-	password := os.Getenv("INPI_PASSWORD")
-	useDemoEnv := os.Getenv("INPI_USE_DEMO") == "true"
-
-	if username == "" || password == "" {
-		return "", fmt.Errorf("INPI_USERNAME and INPI_PASSWORD environment variables are required")
-	}
-
-	authURL := "https://registre-national-entreprises.inpi.fr/api/sso/login"
-	if useDemoEnv {
-		authURL = "https://registre-national-entreprises-pprod.inpi.fr/api/sso/login"
-	}
-
-	authReq := map[string]string{
-		"username": username,
-		"password": password,
-	}
-
-	jsonData, err := json.Marshal(authReq)
-	if err != nil {
-		return "", fmt.Errorf("error marshaling auth request: %w", err)
-	}
-
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-
-	req, err := http.NewRequest("POST", authURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("error creating auth request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("error executing auth request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("authentication failed: status %d, body: %s", resp.StatusCode, string(body))
-	}
-
-	var authResp map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&authResp); err != nil {
-		return "", fmt.Errorf("error decoding auth response: %w", err)
-	}
-
-	token, ok := authResp["token"].(string)
-	if !ok || token == "" {
-		return "", fmt.Errorf("no token received in auth response")
-	}
-
-	return token, nil
-}
-
 func extractDirectorsFromInpiData(inpiData []map[string]interface{}) *DirectorInfo {
 	if len(inpiData) == 0 {
 		return nil
@@ -467,7 +428,7 @@ func extractDirectorsFromInpiData(inpiData []map[string]interface{}) *DirectorIn
 	return nil
 }
 
-func (s *DirectorsService) getDirectorsFromInpiSearch(siren string) *DirectorInfo {
+func (s *DirectorsService) getDirectorsFromInpiSearch(ctx context.Context, siren string) *DirectorInfo {
 	requestBody := map[string]interface{}{
 		"query": map[string]interface{}{
 			"type":             "companies",
@@ -493,7 +454,7 @@ func (s *DirectorsService) getDirectorsFromInpiSearch(siren string) *DirectorInf
 		return nil
 	}
 
-	req, err := http.NewRequest("POST", "https://data.inpi.fr/search", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpointOr(s.endpoints.DataINPI, dataINPIBaseURL)+"/search", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil
 	}
@@ -604,10 +565,14 @@ func (s *DirectorsService) getDirectorsFromInpiSearch(siren string) *DirectorInf
 	return nil
 }
 
-func (s *DirectorsService) getDirectorsFromPappers(siren string) *DirectorInfo {
-	url := fmt.Sprintf("https://www.pappers.fr/entreprise/%s", siren)
+func (s *DirectorsService) getDirectorsFromPappers(ctx context.Context, siren string) *DirectorInfo {
+	if s.pappersAPIKey != "" {
+		return s.getDirectorsFromPappersAPI(ctx, siren)
+	}
+
+	url := fmt.Sprintf("%s/entreprise/%s", endpointOr(s.endpoints.PappersWeb, pappersWebBaseURL), siren)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil
 	}
@@ -623,7 +588,12 @@ func (s *DirectorsService) getDirectorsFromPappers(siren string) *DirectorInfo {
 		return nil
 	}
 
-	bodyBytes, _ := io.ReadAll(resp.Body)
+	bodyBytes, err := readBody(resp.Body)
+	if err != nil {
+		log.Printf("getDirectorsFromPappers: error reading page of SIREN %s: %v", siren, err)
+		return nil
+	}
+
 	body := string(bodyBytes)
 
 	re := regexp.MustCompile(`(?i)Dirigeant[^<]*<[^>]*>([^<]+)</[^>]*>`)
@@ -641,3 +611,48 @@ func (s *DirectorsService) getDirectorsFromPappers(siren string) *DirectorInfo {
 
 	return nil
 }
+
+// getDirectorsFromPappersAPI returns the first natural person among the
+// representatives Pappers gives for siren.
+func (s *DirectorsService) getDirectorsFromPappersAPI(ctx context.Context, siren string) *DirectorInfo {
+	params := url.Values{}
+	params.Set("api_token", s.pappersAPIKey)
+	params.Set("siren", siren)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpointOr(s.endpoints.Pappers, pappersAPIBaseURL)+"/entreprise?"+params.Encode(), nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("getDirectorsFromPappersAPI: status %d for SIREN %s", resp.StatusCode, siren)
+		return nil
+	}
+
+	var data struct {
+		Representants []struct {
+			Nom            string `json:"nom"`
+			Prenom         string `json:"prenom"`
+			PersonneMorale bool   `json:"personne_morale"`
+		} `json:"representants"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil
+	}
+
+	for _, r := range data.Representants {
+		if !r.PersonneMorale && r.Nom != "" && r.Prenom != "" {
+			return &DirectorInfo{Nom: r.Nom, Prenom: r.Prenom}
+		}
+	}
+
+	return nil
+}
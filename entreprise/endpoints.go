@@ -0,0 +1,78 @@
+package entreprise
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	bodaccBaseURL     = "https://bodacc-datadila.opendatasoft.com/api/explore/v2.1"
+	dataINPIBaseURL   = "https://data.inpi.fr"
+	pappersAPIBaseURL = "https://api.pappers.fr/v2"
+	pappersWebBaseURL = "https://www.pappers.fr"
+)
+
+// Endpoints are the base URLs of the APIs the services query, so that tests
+// and mirrors can stand in for them. An empty field keeps the public API,
+// see the entreprisetest package for fakes of all of them.
+type Endpoints struct {
+	// INSEE is the Sirene API, e.g. https://api.insee.fr/api-sirene/3.11,
+	// and INSEEToken the URL its OAuth tokens are requested from.
+	INSEE      string
+	INSEEToken string
+	// INPI replaces the RNE API, production or demo.
+	INPI string
+	// GOUV is the recherche-entreprises API, GOUV_BASE_URL or
+	// https://recherche-entreprises.api.gouv.fr by default.
+	GOUV       string
+	BODACC     string
+	BAN        string
+	DataINPI   string
+	Pappers    string
+	PappersWeb string
+	// Transport sends the requests instead of the pooled transport of each
+	// service. Retries, timeouts and size limits still apply.
+	Transport http.RoundTripper
+}
+
+// ServiceOption configures the services of the registers.
+type ServiceOption func(*serviceOptions)
+
+type serviceOptions struct {
+	endpoints Endpoints
+	retry     RetryPolicy
+	reconcile bool
+	parser    AddressParser
+}
+
+// WithEndpoints makes the service query the APIs of e.
+func WithEndpoints(e Endpoints) ServiceOption {
+	return func(o *serviceOptions) {
+		o.endpoints = e
+	}
+}
+
+func newServiceOptions(opts []ServiceOption) serviceOptions {
+	o := serviceOptions{retry: DefaultRetryPolicy, parser: LocalAddressParser{}}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// endpointOr returns the base URL v without its trailing slash, def when
+// it is empty.
+func endpointOr(v, def string) string {
+	if v == "" {
+		return def
+	}
+
+	return strings.TrimSuffix(v, "/")
+}
+
+// gouvURL returns the base URL of the GOUV API.
+func (e Endpoints) gouvURL() string {
+	return endpointOr(e.GOUV, strings.TrimSuffix(getEnvOrDefault("GOUV_BASE_URL", gouvBaseURL), "/"))
+}
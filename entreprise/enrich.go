@@ -0,0 +1,128 @@
+package entreprise
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+)
+
+type enrichOptions struct {
+	service *Service
+	// reconcile overrides the reconciliation of service when set.
+	reconcile *bool
+	directors bool
+}
+
+// EnrichOption configures Enrich.
+type EnrichOption func(*enrichOptions)
+
+// WithService makes Enrich query the registers of s instead of those of
+// NewService.
+func WithService(s *Service) EnrichOption {
+	return func(o *enrichOptions) {
+		o.service = s
+	}
+}
+
+// WithCredentials makes Enrich query the registers with the accounts of c,
+// through the Service SharedService keeps for them.
+func WithCredentials(c Credentials) EnrichOption {
+	return func(o *enrichOptions) {
+		o.service = SharedService(c)
+	}
+}
+
+// WithReconciliation makes Enrich query every register and settle their
+// disagreements on the SIREN, or stop at the first match when reconcile is
+// false, whatever the service was created with.
+func WithReconciliation(reconcile bool) EnrichOption {
+	return func(o *enrichOptions) {
+		o.reconcile = &reconcile
+	}
+}
+
+// WithoutDirectors skips the lookup of the director of companies the
+// registers give none for.
+func WithoutDirectors() EnrichOption {
+	return func(o *enrichOptions) {
+		o.directors = false
+	}
+}
+
+// Enrich finds the company called name at address in the INSEE, INPI and
+// GOUV registers, in that order of trust, and when they give no director
+// looks one up by SIREN in INPI, GOUV, BODACC and Pappers. When no register
+// gives the company it returns ErrNotFound, ErrLowConfidence when they only
+// know poor matches, as a LowConfidenceError holding the best of them, or
+// the RegisterError failures of the registers that
+// could not answer, which Retryable tells apart.
+//
+// The registers are configured from the environment like for the scraper
+// (INSEE_API_KEY, INPI_USERNAME, INPI_PASSWORD) unless WithService or
+// WithCredentials give others, and addresses are parsed by the parser of
+// the service. ctx bounds the requests to the registers.
+func Enrich(ctx context.Context, name, address string, opts ...EnrichOption) (*CompanyInfo, error) {
+	o := enrichOptions{
+		directors: true,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.service == nil {
+		o.service = NewService()
+	}
+
+	reconcile := o.service.reconcile
+	if o.reconcile != nil {
+		reconcile = *o.reconcile
+	}
+
+	result, err := o.service.search(ctx, name, address, reconcile)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if result == nil || len(result.Data) == 0 {
+		if result != nil && result.Error != nil {
+			return nil, result.Error
+		}
+
+		return nil, ErrNotFound
+	}
+
+	company := result.Data[0]
+	company.DirectorsSource = company.Source
+
+	if !o.directors || len(company.SocieteDirigeants) > 0 || company.SocieteSiren == "" {
+		return &company, nil
+	}
+
+	if d := o.service.GetDirectors(ctx, company.SocieteSiren, ""); d != nil && d.Nom != "" && d.Prenom != "" {
+		company.SocieteDirigeants = []string{d.FullName()}
+		company.DirectorsSource = d.Source
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return &company, nil
+}
+
+// FullName returns the name of the director followed by its capitalized
+// first name, e.g. DUPONT Jean, or the name alone without a first name.
+func (d *DirectorInfo) FullName() string {
+	if d.Prenom == "" {
+		return d.Nom
+	}
+
+	first, size := utf8.DecodeRuneInString(d.Prenom)
+
+	return d.Nom + " " + strings.ToUpper(string(first)) + strings.ToLower(d.Prenom[size:])
+}
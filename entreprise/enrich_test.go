@@ -0,0 +1,103 @@
+package entreprise_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/entreprise"
+)
+
+func Test_Enrich(t *testing.T) {
+	// a service without registers knows no company
+	noRegisters := entreprise.WithService(&entreprise.Service{})
+
+	_, err := entreprise.Enrich(context.Background(), "Boulangerie Martin", "12 Rue de la Paix, 75002 Paris", noRegisters)
+	require.ErrorIs(t, err, entreprise.ErrNotFound)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = entreprise.Enrich(ctx, "Boulangerie Martin", "12 Rue de la Paix, 75002 Paris", noRegisters)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func Test_DirectorFullName(t *testing.T) {
+	d := entreprise.DirectorInfo{Nom: "DUPONT", Prenom: "jEAN"}
+	require.Equal(t, "DUPONT Jean", d.FullName())
+
+	// the initial is a whole rune
+	d = entreprise.DirectorInfo{Nom: "MARTIN", Prenom: "élodie"}
+	require.Equal(t, "MARTIN Élodie", d.FullName())
+
+	d = entreprise.DirectorInfo{Nom: "MARTIN"}
+	require.Equal(t, "MARTIN", d.FullName())
+}
+
+func Test_SharedService(t *testing.T) {
+	a := entreprise.Credentials{INPIUsername: "a@example.com", INPIPassword: "secret"}
+	b := entreprise.Credentials{INPIUsername: "b@example.com", INPIPassword: "secret"}
+
+	require.Same(t, entreprise.SharedService(a), entreprise.SharedService(a))
+	require.NotSame(t, entreprise.SharedService(a), entreprise.SharedService(b))
+	require.NotSame(t, entreprise.SharedService(a), entreprise.NewServiceWithCredentials(a))
+}
+
+func Test_CredentialsMerge(t *testing.T) {
+	defaults := entreprise.Credentials{
+		INSEEAPIKey:   "global-insee",
+		INPIUsername:  "global@example.com",
+		INPIPassword:  "global",
+		PappersAPIKey: "global-pappers",
+	}
+
+	org := entreprise.Credentials{
+		INPIUsername: "org@example.com",
+		INPIPassword: "org",
+	}
+
+	require.Equal(t, entreprise.Credentials{
+		INSEEAPIKey:   "global-insee",
+		INPIUsername:  "org@example.com",
+		INPIPassword:  "org",
+		PappersAPIKey: "global-pappers",
+	}, org.Merge(defaults))
+
+	// An incomplete account is replaced as a whole.
+	org = entreprise.Credentials{INSEEConsumerKey: "key", INPIUsername: "org@example.com"}
+
+	require.Equal(t, defaults, org.Merge(defaults))
+}
+
+func Test_Retryable(t *testing.T) {
+	rateLimited := &entreprise.RegisterError{Source: "INSEE", StatusCode: 429, Err: entreprise.ErrRateLimited}
+	auth := &entreprise.RegisterError{Source: "INPI", StatusCode: 401, Err: entreprise.ErrAuth}
+
+	require.False(t, entreprise.Retryable(nil))
+	require.False(t, entreprise.Retryable(entreprise.ErrNotFound))
+	require.False(t, entreprise.Retryable(entreprise.ErrLowConfidence))
+	require.False(t, entreprise.Retryable(auth))
+	require.True(t, entreprise.Retryable(rateLimited))
+	require.True(t, entreprise.Retryable(&entreprise.RegisterError{Source: "GOUV", Err: errors.New("connection reset")}))
+
+	// one register that may answer later is enough to try again
+	require.True(t, entreprise.Retryable(errors.Join(auth, rateLimited)))
+	require.ErrorIs(t, errors.Join(auth, rateLimited), entreprise.ErrRateLimited)
+}
+
+func Test_LowConfidenceError(t *testing.T) {
+	err := error(&entreprise.RegisterError{
+		Source: "INSEE",
+		Err:    &entreprise.LowConfidenceError{Candidate: entreprise.CompanyInfo{SocieteSiren: "552100554", MatchScore: 145}},
+	})
+
+	require.ErrorIs(t, err, entreprise.ErrLowConfidence)
+	require.False(t, entreprise.Retryable(err))
+
+	var lce *entreprise.LowConfidenceError
+	require.ErrorAs(t, err, &lce)
+	require.Equal(t, "552100554", lce.Candidate.SocieteSiren)
+	require.Equal(t, 145.0, lce.Candidate.MatchScore)
+}
@@ -0,0 +1,229 @@
+// Package entreprisetest fakes the APIs the entreprise package queries, so
+// that its services can be tested without reaching the registers.
+//
+// A Server answers with the exchanges of testdata, one file for each API,
+// written in the formats of the real APIs, and with those added by the tests,
+// e.g. a 429 or a 401:
+//
+//	srv := entreprisetest.NewServer(t)
+//	srv.Use(t)
+//	srv.Add(entreprisetest.GOUV, entreprisetest.Exchange{Method: "GET", Path: "/search", Status: 429})
+package entreprisetest
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/entreprise"
+)
+
+// API is a fake API of a Server, served under /<API>.
+type API string
+
+const (
+	INSEE      API = "insee"
+	INSEEToken API = "insee-token"
+	INPI       API = "inpi"
+	GOUV       API = "gouv"
+	BODACC     API = "bodacc"
+	BAN        API = "ban"
+	DataINPI   API = "data-inpi"
+	Pappers    API = "pappers"
+	PappersWeb API = "pappers-web"
+)
+
+var apis = []API{INSEE, INSEEToken, INPI, GOUV, BODACC, BAN, DataINPI, Pappers, PappersWeb}
+
+// The company of the fixtures, known to every register.
+const (
+	CompanyName    = "Boulangerie Dupont"
+	CompanyAddress = "14 Rue des Lilas, 69003 Lyon"
+	CompanySiren   = "812345676"
+	CompanySiret   = "81234567600017"
+	DirectorNom    = "DUPONT"
+	DirectorPrenom = "Jean"
+)
+
+//go:embed testdata/*.json
+var fixtures embed.FS
+
+// Exchange is a request of an API and the answer of the Server to it.
+type Exchange struct {
+	// Method and Path, relative to the API, must equal those of the request,
+	// an empty Method matches any.
+	Method string `json:"method,omitempty"`
+	Path   string `json:"path"`
+	// Query holds parameters whose values must be contained in those of the
+	// request.
+	Query map[string]string `json:"query,omitempty"`
+
+	Status int               `json:"status,omitempty"`
+	Header map[string]string `json:"header,omitempty"`
+	// Body is the JSON answered, Text the answer of the APIs that are not
+	// JSON.
+	Body json.RawMessage `json:"body,omitempty"`
+	Text string          `json:"text,omitempty"`
+}
+
+func (e *Exchange) matches(r *http.Request, path string) bool {
+	if e.Method != "" && e.Method != r.Method {
+		return false
+	}
+
+	if e.Path != path {
+		return false
+	}
+
+	for k, v := range e.Query {
+		if !strings.Contains(r.URL.Query().Get(k), v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Server is an httptest.Server faking every API.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	exchanges map[API][]Exchange
+	requests  map[API]int
+}
+
+// NewServer starts a Server with the exchanges of testdata, closed when the
+// test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{
+		exchanges: map[API][]Exchange{},
+		requests:  map[API]int{},
+	}
+
+	for _, api := range apis {
+		raw, err := fixtures.ReadFile("testdata/" + string(api) + ".json")
+		if err != nil {
+			continue
+		}
+
+		var exchanges []Exchange
+		if err := json.Unmarshal(raw, &exchanges); err != nil {
+			t.Fatalf("entreprisetest: testdata/%s.json: %v", api, err)
+		}
+
+		s.exchanges[api] = exchanges
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// Add makes the Server answer e to the requests of api it matches, before
+// the exchanges of testdata and those added earlier.
+func (s *Server) Add(api API, e Exchange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.exchanges[api] = append([]Exchange{e}, s.exchanges[api]...)
+}
+
+// Requests returns how many requests api received.
+func (s *Server) Requests(api API) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[api]
+}
+
+// Endpoints returns the Endpoints of the APIs of s.
+func (s *Server) Endpoints() entreprise.Endpoints {
+	return entreprise.Endpoints{
+		INSEE:      s.URL + "/" + string(INSEE),
+		INSEEToken: s.URL + "/" + string(INSEEToken),
+		INPI:       s.URL + "/" + string(INPI),
+		GOUV:       s.URL + "/" + string(GOUV),
+		BODACC:     s.URL + "/" + string(BODACC),
+		BAN:        s.URL + "/" + string(BAN),
+		DataINPI:   s.URL + "/" + string(DataINPI),
+		Pappers:    s.URL + "/" + string(Pappers),
+		PappersWeb: s.URL + "/" + string(PappersWeb),
+	}
+}
+
+// Options returns the options of the services of the entreprise package
+// querying s, retrying without waiting.
+func (s *Server) Options() []entreprise.ServiceOption {
+	return []entreprise.ServiceOption{
+		entreprise.WithEndpoints(s.Endpoints()),
+		entreprise.WithRetryPolicy(entreprise.RetryPolicy{
+			Retries:   entreprise.DefaultRetryPolicy.Retries,
+			BaseDelay: time.Millisecond,
+			MaxDelay:  time.Millisecond,
+		}),
+	}
+}
+
+// serve answers with the first exchange of the API matching r, 404 when
+// none does.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	_, _ = io.Copy(io.Discard, r.Body)
+
+	name, path, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	api := API(name)
+	path = "/" + path
+
+	s.mu.Lock()
+
+	s.requests[api]++
+
+	var found *Exchange
+
+	for i := range s.exchanges[api] {
+		if s.exchanges[api][i].matches(r, path) {
+			e := s.exchanges[api][i]
+			found = &e
+
+			break
+		}
+	}
+
+	s.mu.Unlock()
+
+	if found == nil {
+		http.Error(w, fmt.Sprintf("entreprisetest: no %s exchange for %s %s", api, r.Method, r.URL.RequestURI()), http.StatusNotFound)
+		return
+	}
+
+	for k, v := range found.Header {
+		w.Header().Set(k, v)
+	}
+
+	if found.Body != nil && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+
+	status := found.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	w.WriteHeader(status)
+
+	if found.Body != nil {
+		_, _ = w.Write(found.Body)
+	} else {
+		_, _ = io.WriteString(w, found.Text)
+	}
+}
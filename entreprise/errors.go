@@ -0,0 +1,155 @@
+package entreprise
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrNotFound is returned by Enrich when no register knows the company.
+	ErrNotFound = errors.New("company not found")
+	// ErrLowConfidence is returned when the registers only know companies
+	// matching the place too poorly to be kept.
+	ErrLowConfidence = errors.New("no company matches closely enough")
+	// ErrRateLimited is returned when a register refused a query because
+	// of its quota, retries included.
+	ErrRateLimited = errors.New("register rate limit reached")
+	// ErrAuth is returned when a register rejected the credentials.
+	ErrAuth = errors.New("register authentication failed")
+)
+
+// RegisterError is the failure of a query to a register. It wraps one of the
+// errors above when the failure is one of them, the underlying error
+// otherwise.
+type RegisterError struct {
+	Source string
+	// StatusCode is the HTTP status of the response, 0 when there was none.
+	StatusCode int
+	Err        error
+}
+
+func (e *RegisterError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s: status %d: %v", e.Source, e.StatusCode, e.Err)
+	}
+
+	return fmt.Sprintf("%s: %v", e.Source, e.Err)
+}
+
+func (e *RegisterError) Unwrap() error {
+	return e.Err
+}
+
+// LowConfidenceError is the ErrLowConfidence of a register, holding the
+// company it matched best, with its MatchScore.
+type LowConfidenceError struct {
+	Candidate CompanyInfo
+}
+
+func (e *LowConfidenceError) Error() string {
+	return fmt.Sprintf("%v: best candidate %s (%s) scored %.0f",
+		ErrLowConfidence, e.Candidate.SocieteNom, e.Candidate.SocieteSiren, e.Candidate.MatchScore)
+}
+
+func (e *LowConfidenceError) Is(target error) bool {
+	return target == ErrLowConfidence
+}
+
+// statusError returns the RegisterError of a response of source with the
+// unexpected status, whose body starts with body.
+func statusError(source string, status int, body string) error {
+	var err error
+
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		err = ErrAuth
+	case http.StatusTooManyRequests:
+		err = ErrRateLimited
+	default:
+		err = fmt.Errorf("unexpected response %q", body)
+	}
+
+	return &RegisterError{Source: source, StatusCode: status, Err: err}
+}
+
+// registerError returns err as a failure of source.
+func registerError(source string, err error) error {
+	var re *RegisterError
+	if errors.As(err, &re) {
+		return err
+	}
+
+	return &RegisterError{Source: source, Err: err}
+}
+
+// Retryable tells whether the lookup that failed with err may succeed when
+// tried again later: a register was rate limited, unreachable or failing.
+// Misses, ErrNotFound and ErrLowConfidence, and ErrAuth are not.
+func Retryable(err error) bool {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			if Retryable(e) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrLowConfidence), errors.Is(err, ErrAuth):
+		return false
+	default:
+		return true
+	}
+}
+
+// missError returns why no register gave a company out of the failures of
+// each of them: the failures that may not last when there are some, then
+// ErrLowConfidence when a register only knew poor matches, as the
+// LowConfidenceError of the best of them when known, ErrNotFound otherwise.
+func missError(errs []error) error {
+	var (
+		failures      []error
+		lowConfidence bool
+		best          *LowConfidenceError
+	)
+
+	for _, err := range errs {
+		switch {
+		case errors.Is(err, ErrLowConfidence):
+			lowConfidence = true
+
+			var lce *LowConfidenceError
+			if !errors.As(err, &lce) {
+				continue
+			}
+
+			if best == nil || lce.Candidate.MatchScore > best.Candidate.MatchScore {
+				best = &LowConfidenceError{Candidate: lce.Candidate}
+
+				var re *RegisterError
+				if best.Candidate.Source == "" && errors.As(err, &re) {
+					best.Candidate.Source = re.Source
+				}
+			}
+		case errors.Is(err, ErrNotFound):
+		default:
+			failures = append(failures, err)
+		}
+	}
+
+	switch {
+	case len(failures) > 0:
+		return errors.Join(failures...)
+	case best != nil:
+		return best
+	case lowConfidence:
+		return ErrLowConfidence
+	default:
+		return ErrNotFound
+	}
+}
@@ -1,9 +1,10 @@
 package entreprise
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"math"
 	"net/http"
@@ -22,7 +23,9 @@ const (
 )
 
 type GOUVService struct {
-	client *http.Client
+	client  *http.Client
+	baseURL string
+	parser  AddressParser
 }
 
 type GOUVEntrepriseResult struct {
@@ -93,22 +96,20 @@ type GOUVSearchResponse struct {
 	TotalPages   int                    `json:"total_pages"`
 }
 
-func NewGOUVService() *GOUVService {
+// NewGOUVService creates a GOUVService of the public API, or of the
+// instance at GOUV_BASE_URL when it is set, e.g. a self-hosted one.
+func NewGOUVService(opts ...ServiceOption) *GOUVService {
+	o := newServiceOptions(opts)
+
 	return &GOUVService{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:        10,
-				IdleConnTimeout:     30 * time.Second,
-				DisableKeepAlives:   false,
-				MaxIdleConnsPerHost: 2,
-			},
-		},
+		client:  newHTTPClient(30*time.Second, o),
+		baseURL: o.endpoints.gouvURL(),
+		parser:  o.parser,
 	}
 }
 
-func (s *GOUVService) SearchCompany(companyName, address string) (*SearchResult, error) {
-	parsedAddress := parseAddress(address)
+func (s *GOUVService) SearchCompany(ctx context.Context, companyName, address string) (*SearchResult, error) {
+	parsedAddress := s.parser.Parse(address)
 
 	var searchURL string
 	params := url.Values{}
@@ -117,19 +118,19 @@ func (s *GOUVService) SearchCompany(companyName, address string) (*SearchResult,
 		params.Set("q", ProcessForSearch(companyName))
 		params.Set("code_postal", parsedAddress.PostalCode)
 		params.Set("per_page", "20")
-		searchURL = fmt.Sprintf("%s%s?%s", gouvBaseURL, gouvSearchEndpoint, params.Encode())
+		searchURL = fmt.Sprintf("%s%s?%s", s.baseURL, gouvSearchEndpoint, params.Encode())
 	} else {
 		return &SearchResult{
 			Success: false,
-			Error:   "Code postal requis pour la recherche GOUV",
+			Error:   registerError("GOUV", fmt.Errorf("%w: code postal requis pour la recherche GOUV", ErrNotFound)),
 		}, nil
 	}
 
-	req, err := http.NewRequest("GET", searchURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL, nil)
 	if err != nil {
 		return &SearchResult{
 			Success: false,
-			Error:   fmt.Sprintf("Error creating request: %v", err),
+			Error:   registerError("GOUV", fmt.Errorf("error creating request: %w", err)),
 		}, nil
 	}
 
@@ -139,18 +140,25 @@ func (s *GOUVService) SearchCompany(companyName, address string) (*SearchResult,
 	if err != nil {
 		return &SearchResult{
 			Success: false,
-			Error:   fmt.Sprintf("Error executing request: %v", err),
+			Error:   registerError("GOUV", fmt.Errorf("error executing request: %w", err)),
 		}, nil
 	}
 	defer resp.Body.Close()
 
-	bodyBytes, _ := io.ReadAll(resp.Body)
-
 	if resp.StatusCode != http.StatusOK {
-		log.Printf("GOUV search failed: status %d, body: %s", resp.StatusCode, string(bodyBytes))
+		body := errorBody(resp.Body)
+		log.Printf("GOUV search failed: status %d, body: %s", resp.StatusCode, body)
+		return &SearchResult{
+			Success: false,
+			Error:   statusError("GOUV", resp.StatusCode, body),
+		}, nil
+	}
+
+	bodyBytes, err := readBody(resp.Body)
+	if err != nil {
 		return &SearchResult{
 			Success: false,
-			Error:   fmt.Sprintf("Search failed: status %d", resp.StatusCode),
+			Error:   registerError("GOUV", fmt.Errorf("error reading response: %w", err)),
 		}, nil
 	}
 
@@ -159,7 +167,7 @@ func (s *GOUVService) SearchCompany(companyName, address string) (*SearchResult,
 		log.Printf("GOUV JSON decode error: %v, response body: %s", err, string(bodyBytes[:min(1000, len(bodyBytes))]))
 		return &SearchResult{
 			Success: false,
-			Error:   fmt.Sprintf("Error decoding response: %v", err),
+			Error:   registerError("GOUV", fmt.Errorf("error decoding response: %w", err)),
 		}, nil
 	}
 
@@ -182,13 +190,14 @@ func (s *GOUVService) SearchCompany(companyName, address string) (*SearchResult,
 	}
 
 	if len(results) > 0 {
-		s.sortResultsByMatchScore(results)
+		SortByMatchScore(results)
 
 		if results[0].MatchScore < gouvMinScoreThreshold {
 			return &SearchResult{
 				Success:      true,
 				Data:         []CompanyInfo{},
 				TotalResults: 0,
+				Error:        &LowConfidenceError{Candidate: results[0]},
 			}, nil
 		}
 	}
@@ -450,12 +459,14 @@ func (s *GOUVService) transformGOUVToCompanyInfo(result *GOUVEntrepriseResult, o
 		}
 	}
 
-	city := ""
+	city, postalCode, address := "", "", ""
 	if result.Siege != nil {
 		city = result.Siege.LibelleCommune
+		postalCode = result.Siege.CodePostal
+		address = result.Siege.Adresse
 	}
 	if city == "" && originalAddress != "" {
-		parsed := parseAddress(originalAddress)
+		parsed := s.parser.Parse(originalAddress)
 		city = parsed.LibelleCommune
 	}
 
@@ -478,20 +489,56 @@ func (s *GOUVService) transformGOUVToCompanyInfo(result *GOUVEntrepriseResult, o
 		SocieteCloture:    result.DateFermeture,
 		SocieteDirigeants: directors,
 		City:              city,
+		PostalCode:        postalCode,
+		Address:           address,
 		PappersURL:        pappersURL,
 		SocieteLink:       fmt.Sprintf("https://recherche-entreprises.api.gouv.fr/search?q=%s", url.QueryEscape(result.Siren)),
 		SocieteDiffusion:  societeDiffusion,
+		NafCode:           result.ActivitePrincipale,
 	}
 }
 
-func (s *GOUVService) sortResultsByMatchScore(results []CompanyInfo) {
-	for i := 0; i < len(results)-1; i++ {
-		for j := i + 1; j < len(results); j++ {
-			if results[j].MatchScore > results[i].MatchScore {
-				results[i], results[j] = results[j], results[i]
-			}
+// GetBySiren returns the company siren, or nil when the register does not
+// know it.
+func (s *GOUVService) GetBySiren(ctx context.Context, siren string) (*CompanyInfo, error) {
+	params := url.Values{}
+	params.Set("q", siren)
+	params.Set("per_page", "1")
+
+	searchURL := fmt.Sprintf("%s%s?%s", s.baseURL, gouvSearchEndpoint, params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GOUV search failed: status %d", resp.StatusCode)
+	}
+
+	var searchResponse GOUVSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&searchResponse); err != nil {
+		return nil, err
+	}
+
+	for i := range searchResponse.Results {
+		if searchResponse.Results[i].Siren == siren {
+			info := s.transformGOUVToCompanyInfo(&searchResponse.Results[i], "")
+			info.Source = "GOUV"
+
+			return &info, nil
 		}
 	}
+
+	return nil, nil
 }
 
 func calculateDistance(lat1, lon1, lat2, lon2 float64) float64 {
@@ -510,7 +557,7 @@ func calculateDistance(lat1, lon1, lat2, lon2 float64) float64 {
 	return earthRadiusKm * c
 }
 
-func scoreEntrepriseResult(result *GOUVEntrepriseResult, query string, address string) float64 {
+func (s *GOUVService) scoreEntrepriseResult(result *GOUVEntrepriseResult, query string, address string) float64 {
 	score := 0.0
 
 	if query == "" && address == "" {
@@ -519,7 +566,7 @@ func scoreEntrepriseResult(result *GOUVEntrepriseResult, query string, address s
 
 	var parsedAddress *ParsedAddress
 	if address != "" {
-		parsed := parseAddress(address)
+		parsed := s.parser.Parse(address)
 		parsedAddress = &parsed
 	}
 
@@ -752,14 +799,14 @@ type GeographicSearchParams struct {
 	SortBySize                   *bool
 }
 
-func (s *GOUVService) SearchByGeographicLocation(params GeographicSearchParams) (*SearchResult, error) {
+func (s *GOUVService) SearchByGeographicLocation(ctx context.Context, params GeographicSearchParams) (*SearchResult, error) {
 	hasTextSearch := params.Query != ""
 	hasGeographicFilters := params.CodePostal != "" || params.CodeCommune != "" || (params.Lat != nil && params.Long != nil)
 
 	if !hasTextSearch && !hasGeographicFilters {
 		return &SearchResult{
 			Success: false,
-			Error:   "Au moins un paramètre de recherche (query, lat/long, ou code_postal) est requis",
+			Error:   registerError("GOUV", errors.New("au moins un paramètre de recherche (query, lat/long, ou code_postal) est requis")),
 		}, nil
 	}
 
@@ -828,7 +875,7 @@ func (s *GOUVService) SearchByGeographicLocation(params GeographicSearchParams)
 			urlParams.Set("sort_by_size", strconv.FormatBool(*params.SortBySize))
 		}
 
-		searchURL = fmt.Sprintf("%s%s?%s", gouvBaseURL, gouvNearPointEndpoint, urlParams.Encode())
+		searchURL = fmt.Sprintf("%s%s?%s", s.baseURL, gouvNearPointEndpoint, urlParams.Encode())
 	} else {
 		searchParams := url.Values{}
 
@@ -838,7 +885,7 @@ func (s *GOUVService) SearchByGeographicLocation(params GeographicSearchParams)
 
 		postalCode := params.CodePostal
 		if postalCode == "" && params.Address != "" {
-			parsed := parseAddress(params.Address)
+			parsed := s.parser.Parse(params.Address)
 			postalCode = parsed.PostalCode
 		}
 
@@ -897,14 +944,14 @@ func (s *GOUVService) SearchByGeographicLocation(params GeographicSearchParams)
 			searchParams.Set("sort_by_size", strconv.FormatBool(*params.SortBySize))
 		}
 
-		searchURL = fmt.Sprintf("%s%s?%s", gouvBaseURL, gouvSearchEndpoint, searchParams.Encode())
+		searchURL = fmt.Sprintf("%s%s?%s", s.baseURL, gouvSearchEndpoint, searchParams.Encode())
 	}
 
-	req, err := http.NewRequest("GET", searchURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL, nil)
 	if err != nil {
 		return &SearchResult{
 			Success: false,
-			Error:   fmt.Sprintf("Error creating request: %v", err),
+			Error:   registerError("GOUV", fmt.Errorf("error creating request: %w", err)),
 		}, nil
 	}
 
@@ -916,19 +963,26 @@ func (s *GOUVService) SearchByGeographicLocation(params GeographicSearchParams)
 			err, searchURL, params.Query, params.Address, params.Lat, params.Long, radius)
 		return &SearchResult{
 			Success: false,
-			Error:   fmt.Sprintf("Error executing request: %v", err),
+			Error:   registerError("GOUV", fmt.Errorf("error executing request: %w", err)),
 		}, nil
 	}
 	defer resp.Body.Close()
 
-	bodyBytes, _ := io.ReadAll(resp.Body)
-
 	if resp.StatusCode != http.StatusOK {
+		body := errorBody(resp.Body)
 		log.Printf("GOUV geographic search failed: status %d, statusText: %s, url: %s, query: %s, address: %s, lat: %v, long: %v, radius: %f, body: %s",
-			resp.StatusCode, resp.Status, searchURL, params.Query, params.Address, params.Lat, params.Long, radius, string(bodyBytes))
+			resp.StatusCode, resp.Status, searchURL, params.Query, params.Address, params.Lat, params.Long, radius, body)
+		return &SearchResult{
+			Success: false,
+			Error:   statusError("GOUV", resp.StatusCode, body),
+		}, nil
+	}
+
+	bodyBytes, err := readBody(resp.Body)
+	if err != nil {
 		return &SearchResult{
 			Success: false,
-			Error:   fmt.Sprintf("Erreur HTTP %d: %s", resp.StatusCode, resp.Status),
+			Error:   registerError("GOUV", fmt.Errorf("error reading response: %w", err)),
 		}, nil
 	}
 
@@ -937,7 +991,7 @@ func (s *GOUVService) SearchByGeographicLocation(params GeographicSearchParams)
 		log.Printf("GOUV geographic search JSON decode error: %v, response body: %s", err, string(bodyBytes[:min(1000, len(bodyBytes))]))
 		return &SearchResult{
 			Success: false,
-			Error:   fmt.Sprintf("Error decoding response: %v", err),
+			Error:   registerError("GOUV", fmt.Errorf("error decoding response: %w", err)),
 		}, nil
 	}
 
@@ -981,7 +1035,7 @@ func (s *GOUVService) SearchByGeographicLocation(params GeographicSearchParams)
 
 	if params.Query != "" || params.Address != "" {
 		for _, result := range results {
-			score := scoreEntrepriseResult(&result, params.Query, params.Address)
+			score := s.scoreEntrepriseResult(&result, params.Query, params.Address)
 			scoredResults = append(scoredResults, ScoredResult{
 				Result: result,
 				Score:  score,
@@ -0,0 +1,105 @@
+package entreprise
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// maxResponseBytes caps the responses of the registers, far above the
+	// largest legitimate one, so that a runaway one cannot exhaust the memory
+	// of a worker.
+	maxResponseBytes = 10 << 20
+	// errorBodyBytes is how much of the body of a failed response is kept
+	// for the logs.
+	errorBodyBytes = 1024
+)
+
+// errResponseTooLarge is returned when reading past maxResponseBytes.
+var errResponseTooLarge = fmt.Errorf("response larger than %d bytes", maxResponseBytes)
+
+// newHTTPClient returns the client the register services query with: pooled
+// connections, or the Transport of the Endpoints of o, the retries of the
+// RetryPolicy of o, and timeout for each attempt and the reading of its
+// body.
+func newHTTPClient(timeout time.Duration, o serviceOptions) *http.Client {
+	base := o.endpoints.Transport
+	if base == nil {
+		base = &http.Transport{
+			MaxIdleConns:        10,
+			IdleConnTimeout:     30 * time.Second,
+			DisableKeepAlives:   false,
+			MaxIdleConnsPerHost: 2,
+		}
+	}
+
+	return &http.Client{
+		Transport: &retryTransport{
+			base:    base,
+			timeout: timeout,
+			policy:  o.retry,
+		},
+	}
+}
+
+// limitedBody fails the reads past remaining bytes and ends the context of
+// its request once closed.
+type limitedBody struct {
+	body      io.ReadCloser
+	remaining int64
+	cancel    context.CancelFunc
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// one more byte tells a body of exactly the limit from a larger one
+		var one [1]byte
+
+		n, err := b.body.Read(one[:])
+		if n > 0 {
+			return 0, errResponseTooLarge
+		}
+
+		return 0, err
+	}
+
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	err := b.body.Close()
+	b.cancel()
+
+	return err
+}
+
+// readBody reads a response body of at most maxResponseBytes.
+func readBody(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxResponseBytes+1))
+	if err != nil {
+		return data, err
+	}
+
+	if len(data) > maxResponseBytes {
+		return data[:maxResponseBytes], errResponseTooLarge
+	}
+
+	return data, nil
+}
+
+// errorBody returns the start of the body of a failed response, for the
+// logs.
+func errorBody(r io.Reader) string {
+	data, _ := io.ReadAll(io.LimitReader(r, errorBodyBytes))
+	return string(data)
+}
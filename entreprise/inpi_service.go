@@ -2,9 +2,9 @@ package entreprise
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"net/url"
@@ -22,7 +22,6 @@ const (
 
 type INPIService struct {
 	baseURL     string
-	authURL     string
 	username    string
 	password    string
 	token       string
@@ -30,13 +29,9 @@ type INPIService struct {
 	client      *http.Client
 	tokenMutex  sync.RWMutex
 	useDemoEnv  bool
+	parser      AddressParser
 }
 
-var (
-	inpiServiceInstance *INPIService
-	inpiServiceOnce     sync.Once
-)
-
 type INPIAuthRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
@@ -146,37 +141,26 @@ type INPICompanyResponse struct {
 	Enseignes    []string
 }
 
-func NewINPIService(username, password string, useDemoEnv bool) *INPIService {
-	inpiServiceOnce.Do(func() {
-		baseURL := "https://registre-national-entreprises.inpi.fr"
-		authURL := "https://registre-national-entreprises.inpi.fr/api/sso/login"
-
-		if useDemoEnv {
-			baseURL = "https://registre-national-entreprises-pprod.inpi.fr"
-			authURL = "https://registre-national-entreprises-pprod.inpi.fr/api/sso/login"
-		}
-
-		inpiServiceInstance = &INPIService{
-			baseURL:    baseURL,
-			authURL:    authURL,
-			username:   username,
-			password:   password,
-			useDemoEnv: useDemoEnv,
-			client: &http.Client{
-				Timeout: 30 * time.Second,
-				Transport: &http.Transport{
-					MaxIdleConns:        10,
-					IdleConnTimeout:     30 * time.Second,
-					DisableKeepAlives:   false,
-					MaxIdleConnsPerHost: 2,
-				},
-			},
-		}
-	})
-	return inpiServiceInstance
+func NewINPIService(username, password string, useDemoEnv bool, opts ...ServiceOption) *INPIService {
+	o := newServiceOptions(opts)
+
+	baseURL := "https://registre-national-entreprises.inpi.fr"
+
+	if useDemoEnv {
+		baseURL = "https://registre-national-entreprises-pprod.inpi.fr"
+	}
+
+	return &INPIService{
+		baseURL:    endpointOr(o.endpoints.INPI, baseURL),
+		username:   username,
+		password:   password,
+		useDemoEnv: useDemoEnv,
+		client:     newHTTPClient(30*time.Second, o),
+		parser:     o.parser,
+	}
 }
 
-func (s *INPIService) authenticate() error {
+func (s *INPIService) authenticate(ctx context.Context) error {
 	s.tokenMutex.Lock()
 	defer s.tokenMutex.Unlock()
 
@@ -194,7 +178,7 @@ func (s *INPIService) authenticate() error {
 		return fmt.Errorf("error marshaling auth request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", s.authURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+inpiSSOLoginEndpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("error creating auth request: %w", err)
 	}
@@ -209,8 +193,7 @@ func (s *INPIService) authenticate() error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("authentication failed: status %d, body: %s", resp.StatusCode, string(body))
+		return statusError("INPI", resp.StatusCode, errorBody(resp.Body))
 	}
 
 	var authResp INPIAuthResponse
@@ -228,7 +211,7 @@ func (s *INPIService) authenticate() error {
 	return nil
 }
 
-func (s *INPIService) getAuthToken() (string, error) {
+func (s *INPIService) getAuthToken(ctx context.Context) (string, error) {
 	s.tokenMutex.RLock()
 	if s.token != "" && time.Now().Before(s.tokenExpiry) {
 		token := s.token
@@ -237,7 +220,7 @@ func (s *INPIService) getAuthToken() (string, error) {
 	}
 	s.tokenMutex.RUnlock()
 
-	if err := s.authenticate(); err != nil {
+	if err := s.authenticate(ctx); err != nil {
 		return "", err
 	}
 
@@ -246,28 +229,60 @@ func (s *INPIService) getAuthToken() (string, error) {
 	return s.token, nil
 }
 
-func (s *INPIService) SearchCompany(companyName, address string) (*SearchResult, error) {
-	if err := s.authenticate(); err != nil {
-		return &SearchResult{
-			Success: false,
-			Error:   fmt.Sprintf("Authentication failed: %v", err),
-		}, nil
+// invalidateToken drops token after the API rejected it, unless another
+// request already logged in again.
+func (s *INPIService) invalidateToken(token string) {
+	s.tokenMutex.Lock()
+	defer s.tokenMutex.Unlock()
+
+	if s.token == token {
+		s.token = ""
 	}
+}
 
-	token, err := s.getAuthToken()
-	if err != nil {
+// do sends the request newReq builds with the current token. When the API
+// rejects the token it logs in again and sends it once more; concurrent
+// requests wait for that single login instead of each logging in.
+func (s *INPIService) do(ctx context.Context, newReq func(token string) (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		token, err := s.getAuthToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := newReq(token)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return resp, nil
+		}
+
+		resp.Body.Close()
+		s.invalidateToken(token)
+	}
+}
+
+func (s *INPIService) SearchCompany(ctx context.Context, companyName, address string) (*SearchResult, error) {
+	if err := s.authenticate(ctx); err != nil {
 		return &SearchResult{
 			Success: false,
-			Error:   fmt.Sprintf("Failed to get auth token: %v", err),
+			Error:   registerError("INPI", fmt.Errorf("authentication: %w", err)),
 		}, nil
 	}
 
-	formalities, err := s.searchByCompanyNameAndAddress(companyName, address, token)
+	formalities, err := s.searchByCompanyNameAndAddress(ctx, companyName, address)
 	if err != nil {
 		log.Printf("INPI search by name/address failed: %v", err)
 		return &SearchResult{
 			Success: false,
-			Error:   fmt.Sprintf("Search failed: %v", err),
+			Error:   registerError("INPI", err),
 		}, nil
 	}
 
@@ -283,7 +298,7 @@ func (s *INPIService) SearchCompany(companyName, address string) (*SearchResult,
 	processedName := ProcessForSearch(companyName)
 	normalizedSearch := normalizeCompanyName(processedName)
 	searchNameLower := strings.ToLower(normalizedSearch)
-	parsedAddress := parseAddress(address)
+	parsedAddress := s.parser.Parse(address)
 
 	for _, formality := range formalities {
 		inpiCompany := s.parseFormalityToCompanyResponse(&formality)
@@ -294,13 +309,14 @@ func (s *INPIService) SearchCompany(companyName, address string) (*SearchResult,
 	}
 
 	if len(results) > 0 {
-		s.sortResultsByMatchScore(results)
+		SortByMatchScore(results)
 
 		if results[0].MatchScore < inpiMinScoreThreshold {
 			return &SearchResult{
 				Success:      true,
 				Data:         []CompanyInfo{},
 				TotalResults: 0,
+				Error:        &LowConfidenceError{Candidate: results[0]},
 			}, nil
 		}
 	}
@@ -312,7 +328,7 @@ func (s *INPIService) SearchCompany(companyName, address string) (*SearchResult,
 	}, nil
 }
 
-func (s *INPIService) searchByCompanyNameAndAddress(companyName, address, token string) ([]INPIFormality, error) {
+func (s *INPIService) searchByCompanyNameAndAddress(ctx context.Context, companyName, address string) ([]INPIFormality, error) {
 	searchURL := fmt.Sprintf("%s%s", s.baseURL, inpiCompaniesEndpoint)
 
 	params := url.Values{}
@@ -328,29 +344,35 @@ func (s *INPIService) searchByCompanyNameAndAddress(companyName, address, token
 
 	fullURL := fmt.Sprintf("%s?%s", searchURL, params.Encode())
 
-	req, err := http.NewRequest("GET", fullURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating search request: %w", err)
-	}
+	resp, err := s.do(ctx, func(token string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating search request: %w", err)
+		}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
-	req.Header.Set("Accept", "application/json")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		req.Header.Set("Accept", "application/json")
 
-	resp, err := s.client.Do(req)
+		return req, nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error executing search request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	bodyBytes, _ := io.ReadAll(resp.Body)
-
 	if resp.StatusCode == http.StatusNotFound {
 		return []INPIFormality{}, nil
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		log.Printf("INPI search failed: status %d, URL: %s, body: %s", resp.StatusCode, fullURL, string(bodyBytes))
-		return nil, fmt.Errorf("search failed: status %d, body: %s", resp.StatusCode, string(bodyBytes))
+		body := errorBody(resp.Body)
+		log.Printf("INPI search failed: status %d, URL: %s, body: %s", resp.StatusCode, fullURL, body)
+		return nil, statusError("INPI", resp.StatusCode, body)
+	}
+
+	bodyBytes, err := readBody(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading search response: %w", err)
 	}
 
 	var searchResults []INPIFormality
@@ -369,20 +391,22 @@ func min(a, b int) int {
 	return b
 }
 
-func (s *INPIService) getCompanyBySIREN(siren, token string) (*INPICompanyResponse, error) {
+func (s *INPIService) getCompanyBySIREN(ctx context.Context, siren string) (*INPICompanyResponse, error) {
 	params := url.Values{}
 	params.Set("siren", siren)
 	companyURL := fmt.Sprintf("%s%s?%s", s.baseURL, inpiCompaniesEndpoint, params.Encode())
 
-	req, err := http.NewRequest("GET", companyURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating company request: %w", err)
-	}
+	resp, err := s.do(ctx, func(token string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, companyURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating company request: %w", err)
+		}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
-	req.Header.Set("Accept", "application/json")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		req.Header.Set("Accept", "application/json")
 
-	resp, err := s.client.Do(req)
+		return req, nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error executing company request: %w", err)
 	}
@@ -393,8 +417,7 @@ func (s *INPIService) getCompanyBySIREN(siren, token string) (*INPICompanyRespon
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("get company failed: status %d, body: %s", resp.StatusCode, string(body))
+		return nil, statusError("INPI", resp.StatusCode, errorBody(resp.Body))
 	}
 
 	var formalities []INPIFormality
@@ -686,20 +709,10 @@ func (s *INPIService) calculateMatchScore(searchNameLower string, company *INPIC
 	return score
 }
 
-func (s *INPIService) sortResultsByMatchScore(results []CompanyInfo) {
-	for i := 0; i < len(results)-1; i++ {
-		for j := i + 1; j < len(results); j++ {
-			if results[j].MatchScore > results[i].MatchScore {
-				results[i], results[j] = results[j], results[i]
-			}
-		}
-	}
-}
-
 func (s *INPIService) transformINPIResponseToCompanyInfo(inpiCompany *INPICompanyResponse, originalAddress string) CompanyInfo {
 	city := inpiCompany.City
 	if city == "" && originalAddress != "" {
-		parsed := parseAddress(originalAddress)
+		parsed := s.parser.Parse(originalAddress)
 		city = parsed.LibelleCommune
 	}
 
@@ -716,6 +729,7 @@ func (s *INPIService) transformINPIResponseToCompanyInfo(inpiCompany *INPICompan
 		SocieteCloture:    inpiCompany.ClosureDate,
 		SocieteDirigeants: inpiCompany.Directors,
 		City:              city,
+		PostalCode:        inpiCompany.PostalCode,
 		PappersURL:        pappersURL,
 		SocieteLink:       fmt.Sprintf("https://www.inpi.fr/recherche-entreprise/entreprise/%s", inpiCompany.SIREN),
 	}
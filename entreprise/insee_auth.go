@@ -0,0 +1,95 @@
+package entreprise
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	inseeTokenURL = "https://api.insee.fr/token"
+	// inseeTokenMargin is how long before its expiry a token is renewed, so
+	// that requests in flight do not carry an expired one.
+	inseeTokenMargin = time.Minute
+)
+
+// inseeOAuth gets the access tokens of INSEE accounts that only have a
+// consumer key and secret, with the OAuth client credentials flow, and
+// renews them before they expire.
+type inseeOAuth struct {
+	key      string
+	secret   string
+	client   *http.Client
+	tokenURL string
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+type inseeTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// accessToken returns the current token, or a new one when it is about to
+// expire. Concurrent callers wait for a single renewal.
+func (o *inseeOAuth) accessToken(ctx context.Context) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.token != "" && time.Now().Before(o.expiry) {
+		return o.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("error creating token request: %w", err)
+	}
+
+	req.SetBasicAuth(o.key, o.secret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error executing token request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", statusError("INSEE", resp.StatusCode, errorBody(resp.Body))
+	}
+
+	var tokenResp inseeTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", fmt.Errorf("error decoding token response: %w", err)
+	}
+
+	if tokenResp.AccessToken == "" {
+		return "", fmt.Errorf("no token received in token response")
+	}
+
+	o.token = tokenResp.AccessToken
+	o.expiry = time.Now().Add(time.Duration(tokenResp.ExpiresIn)*time.Second - inseeTokenMargin)
+
+	return o.token, nil
+}
+
+// invalidate drops token after the API rejected it, unless another caller
+// already renewed it.
+func (o *inseeOAuth) invalidate(token string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.token == token {
+		o.token = ""
+	}
+}
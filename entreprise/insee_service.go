@@ -1,14 +1,13 @@
 package entreprise
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"strings"
-	"sync"
 	"time"
 )
 
@@ -20,14 +19,13 @@ const (
 
 type INSEEService struct {
 	apiKey string
-	client *http.Client
+	// oauth authenticates the requests instead of apiKey when it is set.
+	oauth   *inseeOAuth
+	client  *http.Client
+	baseURL string
+	parser  AddressParser
 }
 
-var (
-	inseeServiceInstance *INSEEService
-	inseeServiceOnce     sync.Once
-)
-
 type INSEEResponse struct {
 	Etablissements []map[string]interface{} `json:"etablissements,omitempty"`
 }
@@ -38,36 +36,51 @@ type ScoredResult struct {
 	Source        string
 }
 
-func NewINSEEService(apiKey string) *INSEEService {
-	inseeServiceOnce.Do(func() {
-		inseeServiceInstance = &INSEEService{
-			apiKey: apiKey,
-			client: &http.Client{
-				Timeout: 30 * time.Second,
-				Transport: &http.Transport{
-					MaxIdleConns:        10,
-					IdleConnTimeout:     30 * time.Second,
-					DisableKeepAlives:   false,
-					MaxIdleConnsPerHost: 2,
-				},
-			},
-		}
-	})
-	return inseeServiceInstance
+func NewINSEEService(apiKey string, opts ...ServiceOption) *INSEEService {
+	o := newServiceOptions(opts)
+
+	return &INSEEService{
+		apiKey:  apiKey,
+		client:  newHTTPClient(30*time.Second, o),
+		baseURL: endpointOr(o.endpoints.INSEE, inseeBaseURL),
+		parser:  o.parser,
+	}
 }
 
-func (s *INSEEService) SearchCompany(companyName, address string) (*SearchResult, error) {
+// NewINSEEOAuthService creates the INSEE service of accounts that have a
+// consumer key and secret instead of an API key. Their access tokens are
+// renewed before they expire, and when the API rejects them.
+func NewINSEEOAuthService(consumerKey, consumerSecret string, opts ...ServiceOption) *INSEEService {
+	o := newServiceOptions(opts)
+	client := newHTTPClient(30*time.Second, o)
+
+	return &INSEEService{
+		oauth: &inseeOAuth{
+			key:      consumerKey,
+			secret:   consumerSecret,
+			client:   client,
+			tokenURL: endpointOr(o.endpoints.INSEEToken, inseeTokenURL),
+		},
+		client:  client,
+		baseURL: endpointOr(o.endpoints.INSEE, inseeBaseURL),
+		parser:  o.parser,
+	}
+}
+
+func (s *INSEEService) SearchCompany(ctx context.Context, companyName, address string) (*SearchResult, error) {
 	var addressUpper string
 	if address != "" {
 		addressUpper = strings.ToUpper(address)
 	}
-	query := generateSearchQuery(companyName, addressUpper)
+	query := s.generateSearchQuery(companyName, addressUpper)
 
-	result, err := s.searchSiret(query)
+	result, err := s.searchSiret(ctx, query)
 	if err != nil {
+		err = registerError("INSEE", err)
+
 		return &SearchResult{
 			Success: false,
-			Error:   err.Error(),
+			Error:   err,
 		}, err
 	}
 
@@ -94,7 +107,7 @@ func (s *INSEEService) SearchCompany(companyName, address string) (*SearchResult
 			source = "adresse"
 		}
 
-		score := scoreResult(etab, companyName, address)
+		score := s.scoreResult(etab, companyName, address)
 		allResults = append(allResults, ScoredResult{
 			Etablissement: etab,
 			Score:         score,
@@ -110,19 +123,19 @@ func (s *INSEEService) SearchCompany(companyName, address string) (*SearchResult
 		}, nil
 	}
 
-	for i := 0; i < len(allResults)-1; i++ {
-		for j := i + 1; j < len(allResults); j++ {
-			if allResults[j].Score > allResults[i].Score {
-				allResults[i], allResults[j] = allResults[j], allResults[i]
-			}
-		}
-	}
+	sortByRank(allResults, func(r ScoredResult) rank {
+		return etablissementRank(r.Etablissement, r.Score)
+	})
+
+	if allResults[0].Score < MIN_SCORE_THRESHOLD {
+		candidate := s.transformEtablissementToCompanyInfo(allResults[0].Etablissement)
+		candidate.MatchScore = allResults[0].Score
 
-	if len(allResults) == 0 || allResults[0].Score < MIN_SCORE_THRESHOLD {
 		return &SearchResult{
 			Success:      true,
 			Data:         []CompanyInfo{},
 			TotalResults: 0,
+			Error:        &LowConfidenceError{Candidate: candidate},
 		}, nil
 	}
 
@@ -140,29 +153,21 @@ func (s *INSEEService) SearchCompany(companyName, address string) (*SearchResult
 	}, nil
 }
 
-func (s *INSEEService) searchSiret(query string) (*INSEEResponse, error) {
+func (s *INSEEService) searchSiret(ctx context.Context, query string) (*INSEEResponse, error) {
 	encodedQuery := url.QueryEscape(query)
 	searchURL := fmt.Sprintf("%s%s?q=%s&nombre=200",
-		inseeBaseURL, inseeSiretEndpoint, encodedQuery)
+		s.baseURL, inseeSiretEndpoint, encodedQuery)
 
-	req, err := http.NewRequest("GET", searchURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating search request: %w", err)
-	}
-
-	req.Header.Set("X-INSEE-Api-Key-Integration", s.apiKey)
-	req.Header.Set("Accept", "application/json;charset=utf-8")
-
-	resp, err := s.client.Do(req)
+	resp, err := s.get(ctx, searchURL)
 	if err != nil {
 		return nil, fmt.Errorf("error executing search request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		log.Printf("INSEE search failed: status %d, body: %s", resp.StatusCode, string(bodyBytes))
-		return nil, fmt.Errorf("search failed: status %d", resp.StatusCode)
+		body := errorBody(resp.Body)
+		log.Printf("INSEE search failed: status %d, body: %s", resp.StatusCode, body)
+		return nil, statusError("INSEE", resp.StatusCode, body)
 	}
 
 	var data map[string]interface{}
@@ -189,6 +194,60 @@ func (s *INSEEService) searchSiret(query string) (*INSEEResponse, error) {
 	}, nil
 }
 
+// get requests u with the API key, or with the access token which is
+// renewed once when the API rejects it.
+func (s *INSEEService) get(ctx context.Context, u string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Accept", "application/json;charset=utf-8")
+
+		var token string
+
+		if s.oauth == nil {
+			req.Header.Set("X-INSEE-Api-Key-Integration", s.apiKey)
+		} else {
+			if token, err = s.oauth.accessToken(ctx); err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusUnauthorized || s.oauth == nil || attempt > 0 {
+			return resp, nil
+		}
+
+		resp.Body.Close()
+		s.oauth.invalidate(token)
+	}
+}
+
+// etablissementRank returns the rank of the establishment etab scoring
+// score: active when its current period is, and its creation date.
+func etablissementRank(etab map[string]interface{}, score float64) rank {
+	r := rank{score: score}
+
+	r.siege, _ = etab["etablissementSiege"].(bool)
+	r.creation, _ = etab["dateCreationEtablissement"].(string)
+
+	if periodes, ok := etab["periodesEtablissement"].([]interface{}); ok && len(periodes) > 0 {
+		if current, ok := periodes[0].(map[string]interface{}); ok {
+			r.active = current["etatAdministratifEtablissement"] == "A"
+		}
+	}
+
+	return r
+}
+
 func (s *INSEEService) transformEtablissementToCompanyInfo(etab map[string]interface{}) CompanyInfo {
 	result := CompanyInfo{
 		SocieteDirigeants: []string{},
@@ -201,6 +260,14 @@ func (s *INSEEService) transformEtablissementToCompanyInfo(etab map[string]inter
 	}
 	result.SocieteSiren = siren
 
+	if valid := NormalizeSiret(siret); valid != "" && NormalizeSiren(siren) != valid[:9] {
+		FlagSirenMismatch("SIRET "+siret, siren, valid[:9], "INSEE siren and siret")
+	}
+
+	if adresse, ok := etab["adresseEtablissement"].(map[string]interface{}); ok {
+		result.PostalCode, _ = adresse["codePostalEtablissement"].(string)
+	}
+
 	ul, ok := etab["uniteLegale"].(map[string]interface{})
 	if ok {
 		denomination, _ := ul["denominationUniteLegale"].(string)
@@ -209,6 +276,7 @@ func (s *INSEEService) transformEtablissementToCompanyInfo(etab map[string]inter
 		result.SocieteForme, _ = ul["categorieJuridiqueUniteLegale"].(string)
 		result.SocieteCreation, _ = ul["dateCreationUniteLegale"].(string)
 		result.SocieteCloture, _ = ul["dateDernierTraitementUniteLegale"].(string)
+		result.NafCode, _ = ul["activitePrincipaleUniteLegale"].(string)
 
 		nomUsage, _ := ul["nomUsageUniteLegale"].(string)
 		nom, _ := ul["nomUniteLegale"].(string)
@@ -11,40 +11,67 @@ import (
 const MIN_SCORE_THRESHOLD = 200.0
 
 var typeVoieAbbreviations = map[string]string{
-	"RUE":         "RUE",
-	"AV":          "AVENUE",
-	"AVENUE":      "AVENUE",
-	"BD":          "BOULEVARD",
-	"BOULEVARD":   "BOULEVARD",
-	"BLVD":        "BOULEVARD",
-	"PL":          "PLACE",
-	"PLACE":       "PLACE",
-	"CH":          "CHEMIN",
-	"CHEMIN":      "CHEMIN",
-	"IMP":         "IMPASSE",
-	"IMPASSE":     "IMPASSE",
-	"AL":          "ALLEE",
-	"ALLEE":       "ALLEE",
-	"CRS":         "COURS",
-	"COURS":       "COURS",
-	"PASS":        "PASSAGE",
-	"PASSAGE":     "PASSAGE",
-	"SQ":          "SQUARE",
-	"SQUARE":      "SQUARE",
-	"QT":          "QUAI",
-	"QUAI":        "QUAI",
-	"RTE":         "ROUTE",
-	"ROUTE":       "ROUTE",
-	"VOIE":        "VOIE",
-	"VILLA":       "VILLA",
-	"RES":         "RESIDENCE",
-	"RESIDENCE":   "RESIDENCE",
-	"DOM":         "DOMAINE",
-	"DOMAINE":     "DOMAINE",
-	"LOT":         "LOTISSEMENT",
-	"LOTISSEMENT": "LOTISSEMENT",
-	"ZA":          "ZONE",
-	"ZONE":        "ZONE",
+	"RUE":               "RUE",
+	"AV":                "AVENUE",
+	"AVENUE":            "AVENUE",
+	"BD":                "BOULEVARD",
+	"BOULEVARD":         "BOULEVARD",
+	"BLVD":              "BOULEVARD",
+	"PL":                "PLACE",
+	"PLACE":             "PLACE",
+	"CH":                "CHEMIN",
+	"CHEMIN":            "CHEMIN",
+	"IMP":               "IMPASSE",
+	"IMPASSE":           "IMPASSE",
+	"AL":                "ALLEE",
+	"ALLEE":             "ALLEE",
+	"CRS":               "COURS",
+	"COURS":             "COURS",
+	"PASS":              "PASSAGE",
+	"PASSAGE":           "PASSAGE",
+	"SQ":                "SQUARE",
+	"SQUARE":            "SQUARE",
+	"QT":                "QUAI",
+	"QUAI":              "QUAI",
+	"RTE":               "ROUTE",
+	"ROUTE":             "ROUTE",
+	"VOIE":              "VOIE",
+	"VILLA":             "VILLA",
+	"RES":               "RESIDENCE",
+	"RESIDENCE":         "RESIDENCE",
+	"DOM":               "DOMAINE",
+	"DOMAINE":           "DOMAINE",
+	"LOT":               "LOTISSEMENT",
+	"LOTISSEMENT":       "LOTISSEMENT",
+	"ZA":                "ZONE",
+	"ZONE":              "ZONE",
+	"ALL":               "ALLEE",
+	"AVE":               "AVENUE",
+	"CHEM":              "CHEMIN",
+	"CHE":               "CHEMIN",
+	"FG":                "FAUBOURG",
+	"FBG":               "FAUBOURG",
+	"FAUBOURG":          "FAUBOURG",
+	"PROM":              "PROMENADE",
+	"PROMENADE":         "PROMENADE",
+	"ESP":               "ESPLANADE",
+	"ESPLANADE":         "ESPLANADE",
+	"HAM":               "HAMEAU",
+	"HAMEAU":            "HAMEAU",
+	"QUA":               "QUARTIER",
+	"QUARTIER":          "QUARTIER",
+	"CITE":              "CITE",
+	"SENTIER":           "SENTIER",
+	"TRAVERSE":          "TRAVERSE",
+	"MONTEE":            "MONTEE",
+	"PARVIS":            "PARVIS",
+	"LD":                "LIEU DIT",
+	"LIEU DIT":          "LIEU DIT",
+	"RPT":               "ROND POINT",
+	"ROND POINT":        "ROND POINT",
+	"ZI":                "ZONE INDUSTRIELLE",
+	"ZONE INDUSTRIELLE": "ZONE INDUSTRIELLE",
+	"ZAC":               "ZAC",
 }
 
 var legalForms = []string{
@@ -52,6 +79,9 @@ var legalForms = []string{
 	"SELARL", "SELAS", "SELAFA", "SELCA", "EURL", "EIRL", "SCI", "SCM", "SEL",
 }
 
+// ParsedAddress is a French postal address split into the fields the company
+// registers index. Values are normalized like company names: upper case,
+// without accents or punctuation.
 type ParsedAddress struct {
 	PostalCode           string
 	NumVoie              string
@@ -108,164 +138,14 @@ func normalizeTypeVoie(abbrev string) string {
 	return cleaned
 }
 
-func parseAddress(address string) ParsedAddress {
-	result := ParsedAddress{}
-	cleaned := normalizeCompanyName(address)
-
-	postalCodeRe := regexp.MustCompile(`(\d{5})`)
-	postalCodeMatch := postalCodeRe.FindStringSubmatch(cleaned)
-	if len(postalCodeMatch) > 1 {
-		result.PostalCode = postalCodeMatch[1]
-	}
-
-	parts := regexp.MustCompile(`[, ]+`).Split(cleaned, -1)
-	var filteredParts []string
-	for _, p := range parts {
-		if len(p) > 0 {
-			filteredParts = append(filteredParts, p)
-		}
-	}
-
-	postalCodeIndex := -1
-	for i, p := range filteredParts {
-		if regexp.MustCompile(`^\d{5}$`).MatchString(p) {
-			postalCodeIndex = i
-			break
-		}
-	}
-
-	if postalCodeIndex > 0 {
-		result.LibelleCommune = strings.Join(filteredParts[postalCodeIndex+1:], " ")
-
-		addressPart := strings.Join(filteredParts[:postalCodeIndex], " ")
-
-		typeVoiePatterns := []*regexp.Regexp{
-			regexp.MustCompile(`(?i)\b(RUE|AVENUE|BOULEVARD|PLACE|CHEMIN|IMPASSE|ALLEE|COURS|PASSAGE|SQUARE|QUAI|VOIE|ROUTE|VILLA|RESIDENCE|DOMAINE|LOTISSEMENT|ZONE)\s+`),
-			regexp.MustCompile(`(?i)\b(PL|AV|BD|BLVD|CH|IMP|AL|CRS|PASS|SQ|QT|RTE|RES|DOM|LOT|ZA)\s+`),
-		}
-
-		typeVoieIndex := -1
-		for _, pattern := range typeVoiePatterns {
-			match := pattern.FindStringSubmatch(addressPart)
-			if len(match) > 1 {
-				abbrev := strings.ToUpper(match[1])
-				result.TypeVoie = normalizeTypeVoie(abbrev)
-				typeVoieIndex = pattern.FindStringIndex(addressPart)[0]
-				afterTypeVoie := addressPart[pattern.FindStringIndex(addressPart)[1]:]
-				result.LibelleVoie = strings.TrimSpace(afterTypeVoie)
-				break
-			}
-		}
-
-		if typeVoieIndex >= 0 {
-			beforeTypeVoie := strings.TrimSpace(addressPart[:typeVoieIndex])
-			numVoieRe := regexp.MustCompile(`(?i)\b(\d+)(BIS|TER|QUATER|QUINQUIES)?\s*$`)
-			numVoieMatch := numVoieRe.FindStringSubmatch(beforeTypeVoie)
-			if len(numVoieMatch) > 1 {
-				result.NumVoie = numVoieMatch[1]
-				if len(numVoieMatch) > 2 && numVoieMatch[2] != "" {
-					result.ComplementNumeroVoie = strings.ToUpper(numVoieMatch[2])
-				}
-				numIndex := numVoieRe.FindStringIndex(beforeTypeVoie)
-				if numIndex != nil {
-					beforeNum := strings.TrimSpace(beforeTypeVoie[:numIndex[0]])
-					if beforeNum != "" {
-						result.AdresseBis = beforeNum
-					}
-				}
-			} else {
-				numVoieWithComplementRe := regexp.MustCompile(`(?i)\b(\d+)\s+(BIS|TER|QUATER|QUINQUIES)\s*$`)
-				numVoieWithComplementMatch := numVoieWithComplementRe.FindStringSubmatch(beforeTypeVoie)
-				if len(numVoieWithComplementMatch) > 1 {
-					result.NumVoie = numVoieWithComplementMatch[1]
-					result.ComplementNumeroVoie = strings.ToUpper(numVoieWithComplementMatch[2])
-					numIndex := numVoieWithComplementRe.FindStringIndex(beforeTypeVoie)
-					if numIndex != nil {
-						beforeNum := strings.TrimSpace(beforeTypeVoie[:numIndex[0]])
-						if beforeNum != "" {
-							result.AdresseBis = beforeNum
-						}
-					}
-				} else {
-					if beforeTypeVoie != "" && !regexp.MustCompile(`^\d`).MatchString(beforeTypeVoie) {
-						result.AdresseBis = beforeTypeVoie
-					}
-					if result.LibelleVoie != "" {
-						afterTypeVoieRe := regexp.MustCompile(`(?i)^(\d+)(BIS|TER|QUATER|QUINQUIES)?\s+`)
-						afterTypeVoieMatch := afterTypeVoieRe.FindStringSubmatch(result.LibelleVoie)
-						if len(afterTypeVoieMatch) > 1 {
-							result.NumVoie = afterTypeVoieMatch[1]
-							if len(afterTypeVoieMatch) > 2 && afterTypeVoieMatch[2] != "" {
-								result.ComplementNumeroVoie = strings.ToUpper(afterTypeVoieMatch[2])
-							}
-							afterIndex := afterTypeVoieRe.FindStringIndex(result.LibelleVoie)
-							if afterIndex != nil {
-								result.LibelleVoie = strings.TrimSpace(result.LibelleVoie[afterIndex[1]:])
-							}
-						}
-					}
-				}
-			}
-		} else {
-			numVoieRe := regexp.MustCompile(`(?i)\b(\d+)(BIS|TER|QUATER|QUINQUIES)?\b`)
-			numVoieMatch := numVoieRe.FindStringSubmatch(addressPart)
-			if len(numVoieMatch) > 1 {
-				result.NumVoie = numVoieMatch[1]
-				if len(numVoieMatch) > 2 && numVoieMatch[2] != "" {
-					result.ComplementNumeroVoie = strings.ToUpper(numVoieMatch[2])
-				}
-				numIndex := numVoieRe.FindStringIndex(addressPart)
-				if numIndex != nil {
-					beforeNum := strings.TrimSpace(addressPart[:numIndex[0]])
-					if beforeNum != "" {
-						result.AdresseBis = beforeNum
-					}
-					afterNum := strings.TrimSpace(addressPart[numIndex[1]:])
-					typeMatch := regexp.MustCompile(`(?i)^([A-Z]{2,})\s+(.+)$`).FindStringSubmatch(afterNum)
-					if len(typeMatch) > 2 {
-						abbrev := strings.ToUpper(typeMatch[1])
-						result.TypeVoie = normalizeTypeVoie(abbrev)
-						result.LibelleVoie = typeMatch[2]
-					}
-				}
-			} else {
-				numVoieWithComplementRe := regexp.MustCompile(`(?i)\b(\d+)\s+(BIS|TER|QUATER|QUINQUIES)\b`)
-				numVoieWithComplementMatch := numVoieWithComplementRe.FindStringSubmatch(addressPart)
-				if len(numVoieWithComplementMatch) > 1 {
-					result.NumVoie = numVoieWithComplementMatch[1]
-					result.ComplementNumeroVoie = strings.ToUpper(numVoieWithComplementMatch[2])
-					numIndex := numVoieWithComplementRe.FindStringIndex(addressPart)
-					if numIndex != nil {
-						beforeNum := strings.TrimSpace(addressPart[:numIndex[0]])
-						if beforeNum != "" {
-							result.AdresseBis = beforeNum
-						}
-						afterNum := strings.TrimSpace(addressPart[numIndex[1]:])
-						typeMatch := regexp.MustCompile(`(?i)^([A-Z]{2,})\s+(.+)$`).FindStringSubmatch(afterNum)
-						if len(typeMatch) > 2 {
-							abbrev := strings.ToUpper(typeMatch[1])
-							result.TypeVoie = normalizeTypeVoie(abbrev)
-							result.LibelleVoie = typeMatch[2]
-						}
-					}
-				} else {
-					result.AdresseBis = addressPart
-				}
-			}
-		}
-	}
-
-	return result
-}
-
-func generateSearchQuery(name string, address string) string {
+func (s *INSEEService) generateSearchQuery(name string, address string) string {
 	normalized := normalizeCompanyName(name)
 	nameQuery := `denominationUniteLegale:"` + normalized + `"`
 	var addressQuery string
 	var adresseBisQuery string
 
 	if address != "" {
-		parsed := parseAddress(address)
+		parsed := s.parser.Parse(address)
 
 		if parsed.PostalCode != "" {
 			postalCodePrefix := parsed.PostalCode[:2]
@@ -408,7 +288,7 @@ func matchesByName(etab map[string]interface{}, searchName string) bool {
 	return false
 }
 
-func scoreResult(etab map[string]interface{}, searchName string, searchAddress string) float64 {
+func (s *INSEEService) scoreResult(etab map[string]interface{}, searchName string, searchAddress string) float64 {
 	score := 0.0
 	normalizedSearch := normalizeCompanyName(searchName)
 
@@ -496,7 +376,7 @@ func scoreResult(etab map[string]interface{}, searchName string, searchAddress s
 	}
 
 	if searchAddress != "" {
-		parsed := parseAddress(searchAddress)
+		parsed := s.parser.Parse(searchAddress)
 		adresse, _ := etab["adresseEtablissement"].(map[string]interface{})
 
 		if parsed.PostalCode != "" {
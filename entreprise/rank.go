@@ -0,0 +1,51 @@
+package entreprise
+
+import "sort"
+
+// rank is what the candidates of a register are ordered by: their match
+// score, then between equal scores active companies before closed ones,
+// head offices before secondary establishments, and older companies before
+// younger ones, whose creation date is known before the others.
+type rank struct {
+	score  float64
+	active bool
+	siege  bool
+	// creation is a date as the registers give it, YYYY-MM-DD
+	creation string
+}
+
+func (a rank) before(b rank) bool {
+	switch {
+	case a.score != b.score:
+		return a.score > b.score
+	case a.active != b.active:
+		return a.active
+	case a.siege != b.siege:
+		return a.siege
+	case a.creation == "" || b.creation == "":
+		return a.creation != ""
+	default:
+		return a.creation < b.creation
+	}
+}
+
+// sortByRank sorts items best first. Items of the same rank keep the order
+// of the register.
+func sortByRank[T any](items []T, rankOf func(T) rank) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return rankOf(items[i]).before(rankOf(items[j]))
+	})
+}
+
+// SortByMatchScore sorts companies by MatchScore, best first, breaking the
+// ties with their closure and creation dates as rank says, so that the
+// company kept does not depend on the order of the register.
+func SortByMatchScore(companies []CompanyInfo) {
+	sortByRank(companies, func(c CompanyInfo) rank {
+		return rank{
+			score:    c.MatchScore,
+			active:   c.SocieteCloture == "",
+			creation: c.SocieteCreation,
+		}
+	})
+}
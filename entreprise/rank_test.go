@@ -0,0 +1,29 @@
+package entreprise_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/entreprise"
+)
+
+func Test_SortByMatchScore(t *testing.T) {
+	companies := []entreprise.CompanyInfo{
+		{SocieteSiren: "closed", MatchScore: 250, SocieteCreation: "1990-01-01", SocieteCloture: "2020-01-01"},
+		{SocieteSiren: "young", MatchScore: 250, SocieteCreation: "2019-05-01"},
+		{SocieteSiren: "low", MatchScore: 210},
+		{SocieteSiren: "undated", MatchScore: 250},
+		{SocieteSiren: "old", MatchScore: 250, SocieteCreation: "2001-03-15"},
+		{SocieteSiren: "best", MatchScore: 300},
+	}
+
+	entreprise.SortByMatchScore(companies)
+
+	var got []string
+	for _, c := range companies {
+		got = append(got, c.SocieteSiren)
+	}
+
+	require.Equal(t, []string{"best", "old", "young", "undated", "closed", "low"}, got)
+}
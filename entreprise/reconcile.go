@@ -0,0 +1,196 @@
+package entreprise
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// WithSourceReconciliation makes the SearchCompany of the Service query
+// every register instead of stopping at the first with a match, and settle
+// their disagreements on the SIREN of the place.
+func WithSourceReconciliation(enabled bool) ServiceOption {
+	return func(o *serviceOptions) {
+		o.reconcile = enabled
+	}
+}
+
+// candidate is the best match of a register for a place.
+type candidate struct {
+	source  string
+	company CompanyInfo
+	// votes is how many registers gave the same SIREN
+	votes int
+	// name and address rate from 0 to 1 how well the company matches the
+	// place
+	name    float64
+	address float64
+}
+
+func (c candidate) score() float64 {
+	if c.company.SocieteSiren == "" {
+		return -1
+	}
+
+	return float64(c.votes) + c.name + c.address
+}
+
+// searchReconciled asks every register for the company of the place and
+// reconciles their answers.
+func (s *Service) searchReconciled(ctx context.Context, companyName, address string) *SearchResult {
+	var (
+		candidates []candidate
+		errs       []error
+	)
+
+	for _, src := range s.sources() {
+		result, err := src.search(ctx, companyName, address)
+		if err != nil {
+			log.Printf("Service: %s error for '%s': %v", src.name, companyName, err)
+			errs = append(errs, registerError(src.name, err))
+
+			continue
+		}
+
+		if result == nil || !result.Success || len(result.Data) == 0 {
+			if result != nil && result.Error != nil {
+				errs = append(errs, registerError(src.name, result.Error))
+			}
+
+			continue
+		}
+
+		checkSirens(src.name, result.Data)
+		setSource(src.name, result.Data)
+		candidates = append(candidates, candidate{source: src.name, company: result.Data[0]})
+	}
+
+	if len(candidates) == 0 {
+		return &SearchResult{
+			Success:      true,
+			Data:         []CompanyInfo{},
+			TotalResults: 0,
+			Error:        missError(errs),
+		}
+	}
+
+	return &SearchResult{
+		Success:      true,
+		Data:         []CompanyInfo{s.reconcileCandidates(companyName, address, candidates)},
+		TotalResults: 1,
+	}
+}
+
+// reconcileCandidates returns the company to keep out of candidates, given
+// in order of trust. When they disagree on the SIREN, each one is scored by
+// how many registers agree with it and how well its name and postal code
+// match the place; the best score wins, the most trusted register on a tie.
+// The kept company explains the choice in its Resolution.
+func (s *Service) reconcileCandidates(companyName, address string, candidates []candidate) CompanyInfo {
+	votes := map[string]int{}
+
+	for _, c := range candidates {
+		if c.company.SocieteSiren != "" {
+			votes[c.company.SocieteSiren]++
+		}
+	}
+
+	if len(votes) < 2 {
+		for _, c := range candidates {
+			if c.company.SocieteSiren != "" {
+				return c.company
+			}
+		}
+
+		return candidates[0].company
+	}
+
+	name := normalizeCompanyName(removeLegalForm(companyName))
+	postalCode := s.parser.Parse(address).PostalCode
+
+	best := 0
+
+	for i := range candidates {
+		c := &candidates[i]
+		c.votes = votes[c.company.SocieteSiren]
+		c.name = nameSimilarity(name, normalizeCompanyName(removeLegalForm(c.company.SocieteNom)))
+		c.address = postalCodeSimilarity(postalCode, c.company.PostalCode)
+
+		if c.score() > candidates[best].score() {
+			best = i
+		}
+	}
+
+	kept := candidates[best].company
+	kept.Resolution = explainResolution(candidates, best)
+
+	countSiren("conflicts")
+	log.Printf("Service: registers disagree on '%s': %s", companyName, kept.Resolution)
+
+	return kept
+}
+
+// explainResolution describes the candidates and why the one at best was
+// kept.
+func explainResolution(candidates []candidate, best int) string {
+	parts := make([]string, 0, len(candidates))
+
+	for _, c := range candidates {
+		siren := c.company.SocieteSiren
+		if siren == "" {
+			siren = "no SIREN"
+		}
+
+		parts = append(parts, fmt.Sprintf("%s %s (%d source(s), name %.2f, address %.2f)",
+			c.source, siren, c.votes, c.name, c.address))
+	}
+
+	kept := candidates[best]
+
+	return fmt.Sprintf("registers disagree: %s; kept %s from %s with the best score %.2f",
+		strings.Join(parts, ", "), kept.company.SocieteSiren, kept.source, kept.score())
+}
+
+// nameSimilarity returns the share of the words of a and b they have in
+// common.
+func nameSimilarity(a, b string) float64 {
+	wa, wb := wordSet(a), wordSet(b)
+	if len(wa) == 0 || len(wb) == 0 {
+		return 0
+	}
+
+	common := 0
+
+	for w := range wb {
+		if wa[w] {
+			common++
+		}
+	}
+
+	return float64(common) / float64(len(wa)+len(wb)-common)
+}
+
+func wordSet(s string) map[string]bool {
+	words := map[string]bool{}
+	for _, w := range strings.Fields(s) {
+		words[w] = true
+	}
+
+	return words
+}
+
+// postalCodeSimilarity returns 1 for the same postal code, 0.5 for the same
+// department and 0 otherwise.
+func postalCodeSimilarity(a, b string) float64 {
+	switch {
+	case a == "" || b == "":
+		return 0
+	case a == b:
+		return 1
+	case len(a) >= 2 && len(b) >= 2 && a[:2] == b[:2]:
+		return 0.5
+	default:
+		return 0
+	}
+}
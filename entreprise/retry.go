@@ -0,0 +1,167 @@
+package entreprise
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy is how the clients of the registers retry the requests that
+// failed for a reason that may not last: a transport error, a 429 or a 5xx.
+type RetryPolicy struct {
+	// Retries is how many times a request is sent again, 0 disables the
+	// retries.
+	Retries int
+	// BaseDelay is the wait before the first retry, doubled for each next
+	// one, with jitter.
+	BaseDelay time.Duration
+	// MaxDelay caps the waits. A response asking with Retry-After to wait
+	// longer is returned as is.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is the RetryPolicy of the services created without
+// WithRetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{
+	Retries:   2,
+	BaseDelay: time.Second,
+	MaxDelay:  30 * time.Second,
+}
+
+// WithRetryPolicy makes the service retry its failed requests as p says.
+func WithRetryPolicy(p RetryPolicy) ServiceOption {
+	return func(o *serviceOptions) {
+		o.retry = p
+	}
+}
+
+// retryTransport sends the requests again as the RetryPolicy says. Requests
+// whose body cannot be read again are sent once. Each attempt has its own
+// timeout, reading the body included, and a body larger than
+// maxResponseBytes fails.
+type retryTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+	policy  RetryPolicy
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	policy := t.policy
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.send(req)
+
+		if attempt >= policy.Retries || !retryable(resp, err) || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		if req.Body != nil && req.GetBody == nil {
+			return resp, err
+		}
+
+		wait := backoff(policy, attempt)
+
+		if resp != nil {
+			if after, ok := retryAfter(resp); ok {
+				if after > policy.MaxDelay {
+					return resp, err
+				}
+
+				wait = after
+			}
+
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// send sends req once, bounded by the timeout of t until its body is closed.
+func (t *retryTransport) send(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp.Body = &limitedBody{
+		body:      resp.Body,
+		remaining: maxResponseBytes,
+		cancel:    cancel,
+	}
+
+	return resp, nil
+}
+
+// retryable tells whether a request that got resp or err may succeed if
+// sent again.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// backoff returns the wait before retry attempt+1: BaseDelay doubled attempt
+// times, drawn between its half and itself, at most MaxDelay.
+func backoff(p RetryPolicy, attempt int) time.Duration {
+	d := p.BaseDelay << attempt
+	if p.MaxDelay > 0 && (d > p.MaxDelay || d < 0) {
+		d = p.MaxDelay
+	}
+
+	if d <= 0 {
+		return 0
+	}
+
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryAfter reads the Retry-After header of resp, in seconds or as a date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(v); err == nil {
+		return max(time.Until(at), 0), true
+	}
+
+	return 0, false
+}
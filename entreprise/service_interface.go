@@ -1,5 +1,7 @@
 package entreprise
 
+import "context"
+
 type CompanySearchService interface {
-	SearchCompany(companyName, address string) (*SearchResult, error)
+	SearchCompany(ctx context.Context, companyName, address string) (*SearchResult, error)
 }
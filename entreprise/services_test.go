@@ -0,0 +1,153 @@
+package entreprise_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/entreprise"
+	"github.com/gosom/google-maps-scraper/entreprise/entreprisetest"
+)
+
+func Test_ServiceSearchCompany(t *testing.T) {
+	srv := entreprisetest.NewServer(t)
+
+	tests := []struct {
+		name        string
+		credentials entreprise.Credentials
+		source      string
+	}{
+		{"insee api key", entreprise.Credentials{INSEEAPIKey: "key"}, "INSEE"},
+		{"insee oauth", entreprise.Credentials{INSEEConsumerKey: "key", INSEEConsumerSecret: "secret"}, "INSEE"},
+		{"inpi", entreprise.Credentials{INPIUsername: "user", INPIPassword: "secret"}, "INPI"},
+		{"gouv", entreprise.Credentials{}, "GOUV"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := entreprise.NewServiceWithCredentials(tc.credentials, srv.Options()...)
+
+			result, err := service.SearchCompany(context.Background(), entreprisetest.CompanyName, entreprisetest.CompanyAddress)
+			require.NoError(t, err)
+			require.NotEmpty(t, result.Data)
+			require.Equal(t, entreprisetest.CompanySiren, result.Data[0].SocieteSiren)
+			require.Equal(t, tc.source, result.Data[0].Source)
+		})
+	}
+
+	require.Equal(t, 1, srv.Requests(entreprisetest.INSEEToken))
+}
+
+func Test_ServiceSearchCompanyFailures(t *testing.T) {
+	srv := entreprisetest.NewServer(t)
+
+	srv.Add(entreprisetest.GOUV, entreprisetest.Exchange{Path: "/search", Status: http.StatusTooManyRequests})
+	srv.Add(entreprisetest.INSEE, entreprisetest.Exchange{Path: "/siret", Status: http.StatusUnauthorized})
+
+	_, err := entreprise.Enrich(context.Background(), entreprisetest.CompanyName, entreprisetest.CompanyAddress,
+		entreprise.WithService(entreprise.NewServiceWithCredentials(entreprise.Credentials{}, srv.Options()...)),
+		entreprise.WithoutDirectors())
+	require.ErrorIs(t, err, entreprise.ErrRateLimited)
+	require.True(t, entreprise.Retryable(err))
+	require.Equal(t, entreprise.DefaultRetryPolicy.Retries+1, srv.Requests(entreprisetest.GOUV))
+
+	// the rejected token is renewed once
+	srv.Add(entreprisetest.GOUV, entreprisetest.Exchange{Path: "/search", Body: []byte(`{"results": []}`)})
+
+	service := entreprise.NewServiceWithCredentials(entreprise.Credentials{INSEEConsumerKey: "key", INSEEConsumerSecret: "secret"},
+		srv.Options()...)
+
+	result, err := service.SearchCompany(context.Background(), entreprisetest.CompanyName, entreprisetest.CompanyAddress)
+	require.NoError(t, err)
+	require.ErrorIs(t, result.Error, entreprise.ErrAuth)
+	require.False(t, entreprise.Retryable(result.Error))
+	require.Equal(t, 2, srv.Requests(entreprisetest.INSEEToken))
+}
+
+func Test_EnrichCancelled(t *testing.T) {
+	srv := entreprisetest.NewServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// the requests to the registers are bound to ctx
+	_, err := entreprise.Enrich(ctx, entreprisetest.CompanyName, entreprisetest.CompanyAddress,
+		entreprise.WithService(entreprise.NewServiceWithCredentials(entreprise.Credentials{INSEEAPIKey: "key"}, srv.Options()...)))
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 0, srv.Requests(entreprisetest.INSEE))
+	require.Equal(t, 0, srv.Requests(entreprisetest.GOUV))
+}
+
+func Test_ServiceGetDirectors(t *testing.T) {
+	srv := entreprisetest.NewServer(t)
+
+	service := entreprise.NewServiceWithCredentials(entreprise.Credentials{INPIUsername: "user", INPIPassword: "secret"}, srv.Options()...)
+
+	director := service.GetDirectors(context.Background(), entreprisetest.CompanySiren, entreprisetest.CompanySiret)
+	require.NotNil(t, director)
+	require.Equal(t, "INPI", director.Source)
+	require.Equal(t, entreprisetest.DirectorNom, director.Nom)
+	require.Equal(t, entreprisetest.DirectorPrenom, director.Prenom)
+
+	director = service.GetDirectors(context.Background(), entreprisetest.CompanySiren, "")
+	require.NotNil(t, director)
+	require.Equal(t, "GOUV", director.Source)
+
+	// the later registers are asked when the earlier ones do not know
+	srv.Add(entreprisetest.GOUV, entreprisetest.Exchange{Path: "/entreprises/" + entreprisetest.CompanySiren, Status: http.StatusNotFound})
+
+	director = service.GetDirectors(context.Background(), entreprisetest.CompanySiren, "")
+	require.NotNil(t, director)
+	require.Equal(t, "BODACC", director.Source)
+	require.Equal(t, entreprisetest.DirectorNom, director.Nom)
+	require.Equal(t, entreprisetest.DirectorPrenom, director.Prenom)
+
+	srv.Add(entreprisetest.BODACC, entreprisetest.Exchange{Path: "/catalog/datasets/annonces-commerciales/records", Body: []byte(`{"results": []}`)})
+
+	director = entreprise.NewServiceWithCredentials(entreprise.Credentials{PappersAPIKey: "key"}, srv.Options()...).
+		GetDirectors(context.Background(), entreprisetest.CompanySiren, "")
+	require.NotNil(t, director)
+	require.Equal(t, "Pappers", director.Source)
+}
+
+func Test_BANService(t *testing.T) {
+	srv := entreprisetest.NewServer(t)
+
+	ban := entreprise.NewBANService(srv.Options()...)
+
+	location, err := ban.Search(context.Background(), entreprisetest.CompanyAddress)
+	require.NoError(t, err)
+	require.NotNil(t, location)
+	require.Equal(t, "69383", location.CityCode)
+	require.Equal(t, "14", location.HouseNumber)
+
+	location, err = ban.Reverse(context.Background(), 45.7597, 4.8512)
+	require.NoError(t, err)
+	require.NotNil(t, location)
+	require.Equal(t, "69003", location.PostCode)
+
+	location, err = ban.Search(context.Background(), "nowhere")
+	require.NoError(t, err)
+	require.Nil(t, location)
+}
+
+func Test_ServiceSourceReconciliation(t *testing.T) {
+	srv := entreprisetest.NewServer(t)
+	creds := entreprise.Credentials{INSEEAPIKey: "key"}
+
+	// the first register with a match answers
+	_, err := entreprise.NewServiceWithCredentials(creds, srv.Options()...).
+		SearchCompany(context.Background(), entreprisetest.CompanyName, entreprisetest.CompanyAddress)
+	require.NoError(t, err)
+	require.Equal(t, 0, srv.Requests(entreprisetest.GOUV))
+
+	opts := append(srv.Options(), entreprise.WithSourceReconciliation(true))
+
+	result, err := entreprise.NewServiceWithCredentials(creds, opts...).
+		SearchCompany(context.Background(), entreprisetest.CompanyName, entreprisetest.CompanyAddress)
+	require.NoError(t, err)
+	require.Equal(t, entreprisetest.CompanySiren, result.Data[0].SocieteSiren)
+	require.Equal(t, 1, srv.Requests(entreprisetest.GOUV))
+}
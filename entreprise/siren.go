@@ -0,0 +1,149 @@
+package entreprise
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// laPosteSiren is the SIREN of La Poste, whose thousands of SIRET do not
+// all pass the Luhn check: the digits of the others add up to a multiple of
+// 5 instead.
+const laPosteSiren = "356000000"
+
+// NormalizeSiren returns the 9 digits of the SIREN raw, which may hold
+// spaces or dots ("123 456 789"), or "" when it is not a valid SIREN.
+func NormalizeSiren(raw string) string {
+	digits := sirenDigits(raw)
+	if len(digits) != 9 || !luhnValid(digits) {
+		return ""
+	}
+
+	return digits
+}
+
+// NormalizeSiret returns the 14 digits of the SIRET raw, or "" when it is not
+// a valid SIRET.
+func NormalizeSiret(raw string) string {
+	digits := sirenDigits(raw)
+	if len(digits) != 14 {
+		return ""
+	}
+
+	if digits[:9] == laPosteSiren {
+		sum := 0
+		for _, r := range digits {
+			sum += int(r - '0')
+		}
+
+		if sum%5 != 0 && !luhnValid(digits) {
+			return ""
+		}
+
+		return digits
+	}
+
+	if NormalizeSiren(digits[:9]) == "" || !luhnValid(digits) {
+		return ""
+	}
+
+	return digits
+}
+
+// sirenDigits removes the separators of raw. It returns "" when raw holds
+// anything else than digits and separators.
+func sirenDigits(raw string) string {
+	var b strings.Builder
+
+	for _, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || r == '.' || r == '-':
+		default:
+			return ""
+		}
+	}
+
+	return b.String()
+}
+
+// luhnValid tells whether digits pass the Luhn check.
+func luhnValid(digits string) bool {
+	sum := 0
+
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+
+		if (len(digits)-1-i)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+
+		sum += d
+	}
+
+	return sum%10 == 0
+}
+
+var sirenStats = struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}{
+	counts: make(map[string]int64),
+}
+
+// SirenStats returns how many invalid SIRENs were discarded, how many
+// mismatches between sources were flagged and how many register
+// disagreements were reconciled since startup.
+func SirenStats() map[string]int64 {
+	sirenStats.mu.Lock()
+	defer sirenStats.mu.Unlock()
+
+	ans := map[string]int64{"invalid": 0, "mismatches": 0, "conflicts": 0}
+	for k, v := range sirenStats.counts {
+		ans[k] = v
+	}
+
+	return ans
+}
+
+func countSiren(kind string) {
+	sirenStats.mu.Lock()
+	sirenStats.counts[kind]++
+	sirenStats.mu.Unlock()
+}
+
+// FlagSirenMismatch records that two sources gave different SIRENs for the
+// same company, e.g. the stored result and a new registry lookup.
+func FlagSirenMismatch(subject, kept, other, sources string) {
+	countSiren("mismatches")
+	log.Printf("SIREN mismatch for %s between %s: kept %s, got %s", subject, sources, kept, other)
+}
+
+// checkSirens normalizes the SIRENs of the companies source returned and
+// discards the invalid ones along with the links built from them.
+func checkSirens(source string, companies []CompanyInfo) {
+	for i := range companies {
+		c := &companies[i]
+		if c.SocieteSiren == "" {
+			continue
+		}
+
+		siren := NormalizeSiren(c.SocieteSiren)
+		if siren != "" {
+			c.SocieteSiren = siren
+			continue
+		}
+
+		countSiren("invalid")
+		log.Printf("%s: discarding invalid SIREN %q of '%s'", source, c.SocieteSiren, c.SocieteNom)
+
+		c.SocieteSiren = ""
+		c.SocieteLink = ""
+		c.PappersURL = ""
+	}
+}
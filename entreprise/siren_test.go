@@ -0,0 +1,30 @@
+package entreprise_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/entreprise"
+)
+
+func Test_NormalizeSiren(t *testing.T) {
+	require.Equal(t, "732829320", entreprise.NormalizeSiren("732829320"))
+	require.Equal(t, "732829320", entreprise.NormalizeSiren(" 732 829 320 "))
+	require.Equal(t, "732829320", entreprise.NormalizeSiren("732.829.320"))
+	require.Equal(t, "732829320", entreprise.NormalizeSiren("732 829 320"))
+
+	require.Equal(t, "", entreprise.NormalizeSiren("732829321"))
+	require.Equal(t, "", entreprise.NormalizeSiren("73282932"))
+	require.Equal(t, "", entreprise.NormalizeSiren("FR732829320"))
+	require.Equal(t, "", entreprise.NormalizeSiren(""))
+}
+
+func Test_NormalizeSiret(t *testing.T) {
+	require.Equal(t, "73282932000074", entreprise.NormalizeSiret("732 829 320 00074"))
+	require.Equal(t, "", entreprise.NormalizeSiret("73282932000075"))
+	// most La Poste establishments only add up to a multiple of 5
+	require.Equal(t, "35600000000048", entreprise.NormalizeSiret("35600000000048"))
+	require.Equal(t, "35600000049837", entreprise.NormalizeSiret("35600000049837"))
+	require.Equal(t, "", entreprise.NormalizeSiret("35600000049838"))
+}
@@ -10,13 +10,29 @@ type CompanyInfo struct {
 	SocieteLink       string   `json:"societeLink"`
 	PappersURL        string   `json:"pappersURL"`
 	City              string   `json:"city"`
-	MatchScore        float64  `json:"matchScore,omitempty"`
-	SocieteDiffusion  *bool    `json:"societeDiffusion"`
+	PostalCode        string   `json:"postalCode,omitempty"`
+	// Address is the address of the head office, as the register writes it.
+	Address          string  `json:"address,omitempty"`
+	MatchScore       float64 `json:"matchScore,omitempty"`
+	SocieteDiffusion *bool   `json:"societeDiffusion"`
+	// NafCode is the APE/NAF code of the main activity, e.g. 43.22A.
+	NafCode string `json:"nafCode,omitempty"`
+	// Resolution explains why this company was kept when the registers
+	// disagreed on the SIREN of the place.
+	Resolution string `json:"resolution,omitempty"`
+	// Source is the register the company was found in.
+	Source string `json:"source,omitempty"`
+	// DirectorsSource is the register SocieteDirigeants come from, set by
+	// Enrich.
+	DirectorsSource string `json:"directorsSource,omitempty"`
 }
 
 type SearchResult struct {
 	Success      bool          `json:"success"`
 	Data         []CompanyInfo `json:"data,omitempty"`
-	Error        string        `json:"error,omitempty"`
 	TotalResults int           `json:"totalResults,omitempty"`
+	// Error tells why Data is empty: the failure of the query, a
+	// RegisterError, when Success is false, ErrLowConfidence when the
+	// register only knew companies matching too poorly.
+	Error error `json:"-"`
 }
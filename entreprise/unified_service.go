@@ -1,8 +1,10 @@
 package entreprise
 
 import (
+	"context"
 	"log"
 	"os"
+	"strings"
 	"sync"
 )
 
@@ -13,63 +15,224 @@ type Service struct {
 	inpiService      *INPIService
 	gouvService      *GOUVService
 	directorsService *DirectorsService
+	// reconcile makes SearchCompany query every register, see
+	// WithSourceReconciliation.
+	reconcile bool
+	parser    AddressParser
 }
 
-var (
-	serviceInstance *Service
-	serviceOnce     sync.Once
-)
+// Credentials are the accounts a Service queries the registers with.
+// Registers without credentials are skipped, except GOUV which needs none.
+type Credentials struct {
+	INSEEAPIKey string `json:"insee_api_key,omitempty"`
+	// INSEEConsumerKey and INSEEConsumerSecret are used instead of
+	// INSEEAPIKey when it is empty.
+	INSEEConsumerKey    string `json:"insee_consumer_key,omitempty"`
+	INSEEConsumerSecret string `json:"insee_consumer_secret,omitempty"`
+	INPIUsername        string `json:"inpi_username,omitempty"`
+	INPIPassword        string `json:"inpi_password,omitempty"`
+	INPIUseDemo         bool   `json:"inpi_use_demo,omitempty"`
+	// PappersAPIKey makes the directors lookups query the Pappers API
+	// instead of its web pages.
+	PappersAPIKey string `json:"pappers_api_key,omitempty"`
+}
+
+// CredentialsFromEnv reads the credentials of INSEE_API_KEY,
+// INSEE_CONSUMER_KEY, INSEE_CONSUMER_SECRET, INPI_USERNAME, INPI_PASSWORD,
+// INPI_USE_DEMO and PAPPERS_API_KEY.
+func CredentialsFromEnv() Credentials {
+	return Credentials{
+		INSEEAPIKey:         getEnvOrDefault("INSEE_API_KEY", ""),
+		INSEEConsumerKey:    getEnvOrDefault("INSEE_CONSUMER_KEY", ""),
+		INSEEConsumerSecret: getEnvOrDefault("INSEE_CONSUMER_SECRET", ""),
+		INPIUsername:        getEnvOrDefault("INPI_USERNAME", ""),
+		INPIPassword:        getEnvOrDefault("INPI_PASSWORD", ""),
+		INPIUseDemo:         getEnvOrDefault("INPI_USE_DEMO", "false") == "true",
+		PappersAPIKey:       getEnvOrDefault("PAPPERS_API_KEY", ""),
+	}
+}
+
+// Merge returns c with the accounts of each register it lacks taken from
+// defaults, so that an organization bringing only its INPI account still
+// queries INSEE with the global one.
+func (c Credentials) Merge(defaults Credentials) Credentials {
+	ans := c
+
+	if c.INSEEAPIKey == "" && (c.INSEEConsumerKey == "" || c.INSEEConsumerSecret == "") {
+		ans.INSEEAPIKey = defaults.INSEEAPIKey
+		ans.INSEEConsumerKey = defaults.INSEEConsumerKey
+		ans.INSEEConsumerSecret = defaults.INSEEConsumerSecret
+	}
+
+	if c.INPIUsername == "" || c.INPIPassword == "" {
+		ans.INPIUsername = defaults.INPIUsername
+		ans.INPIPassword = defaults.INPIPassword
+		ans.INPIUseDemo = defaults.INPIUseDemo
+	}
 
+	if c.PappersAPIKey == "" {
+		ans.PappersAPIKey = defaults.PappersAPIKey
+	}
+
+	return ans
+}
+
+// Services shares the Service of each set of credentials, all configured
+// with the same options, so that the lookups with the same accounts reuse
+// its connections and tokens.
+type Services struct {
+	opts []ServiceOption
+
+	mu     sync.Mutex
+	shared map[Credentials]*Service
+}
+
+// NewServices creates the Services configured by opts.
+func NewServices(opts ...ServiceOption) *Services {
+	return &Services{
+		opts:   opts,
+		shared: map[Credentials]*Service{},
+	}
+}
+
+// defaultServices are the Services of NewService and SharedService.
+var defaultServices = NewServices()
+
+// DefaultServices returns the Services created without options, those of
+// NewService and SharedService.
+func DefaultServices() *Services {
+	return defaultServices
+}
+
+// Default returns the Service of the credentials of the environment.
+func (s *Services) Default() *Service {
+	return s.Shared(CredentialsFromEnv())
+}
+
+// Shared returns the Service of c, created on the first call and shared
+// with the later ones.
+func (s *Services) Shared(c Credentials) *Service {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if service, ok := s.shared[c]; ok {
+		return service
+	}
+
+	service := NewServiceWithCredentials(c, s.opts...)
+	s.shared[c] = service
+
+	log.Printf("Service: enterprise services initialized (%s)", service.describe())
+
+	return service
+}
+
+// NewService returns the shared Service of the credentials of the
+// environment, of the Services created without options.
 func NewService() *Service {
-	serviceOnce.Do(func() {
-		serviceInstance = &Service{}
+	return defaultServices.Default()
+}
 
-		inseeApiKey := getEnvOrDefault("INSEE_API_KEY", "")
-		if inseeApiKey != "" {
-			serviceInstance.inseeService = NewINSEEService(inseeApiKey)
-		}
+// SharedService returns the Service of c, created on the first call and
+// shared with the later ones, so that they reuse its connections and
+// tokens, of the Services created without options.
+func SharedService(c Credentials) *Service {
+	return defaultServices.Shared(c)
+}
 
-		inpiUsername := getEnvOrDefault("INPI_USERNAME", "")
-		inpiPassword := getEnvOrDefault("INPI_PASSWORD", "")
-		useDemoEnv := getEnvOrDefault("INPI_USE_DEMO", "false") == "true"
-		if inpiUsername != "" && inpiPassword != "" {
-			serviceInstance.inpiService = NewINPIService(inpiUsername, inpiPassword, useDemoEnv)
-		}
+// NewServiceWithCredentials creates a Service of its own querying the
+// registers with c, configured by opts.
+func NewServiceWithCredentials(c Credentials, opts ...ServiceOption) *Service {
+	o := newServiceOptions(opts)
 
-		serviceInstance.gouvService = NewGOUVService()
-		serviceInstance.directorsService = NewDirectorsService()
+	s := &Service{
+		gouvService:      NewGOUVService(opts...),
+		directorsService: NewDirectorsService(opts...),
+		reconcile:        o.reconcile,
+		parser:           o.parser,
+	}
 
-		log.Println("Service: all enterprise services initialized")
-	})
+	if c.INSEEAPIKey != "" {
+		s.inseeService = NewINSEEService(c.INSEEAPIKey, opts...)
+	} else if c.INSEEConsumerKey != "" && c.INSEEConsumerSecret != "" {
+		s.inseeService = NewINSEEOAuthService(c.INSEEConsumerKey, c.INSEEConsumerSecret, opts...)
+	}
+
+	if c.INPIUsername != "" && c.INPIPassword != "" {
+		s.inpiService = NewINPIService(c.INPIUsername, c.INPIPassword, c.INPIUseDemo, opts...)
+		s.directorsService.inpi = s.inpiService
+	}
+
+	s.directorsService.pappersAPIKey = c.PappersAPIKey
+
+	return s
+}
+
+// describe lists the registers of s.
+func (s *Service) describe() string {
+	var names []string
+
+	for _, src := range s.sources() {
+		names = append(names, src.name)
+	}
 
-	return serviceInstance
+	return strings.Join(names, ", ")
 }
 
-func (s *Service) SearchCompany(companyName, address string) (*SearchResult, error) {
+// source is a company register SearchCompany queries.
+type source struct {
+	name   string
+	search func(ctx context.Context, companyName, address string) (*SearchResult, error)
+}
+
+// sources returns the configured registers, in order of trust.
+func (s *Service) sources() []source {
+	var ans []source
+
 	if s.inseeService != nil {
-		result, err := s.inseeService.SearchCompany(companyName, address)
-		if err != nil {
-			log.Printf("Service: INSEE error for '%s': %v", companyName, err)
-		} else if result != nil && result.Success && len(result.Data) > 0 {
-			return result, nil
-		}
+		ans = append(ans, source{"INSEE", s.inseeService.SearchCompany})
 	}
 
 	if s.inpiService != nil {
-		result, err := s.inpiService.SearchCompany(companyName, address)
-		if err != nil {
-			log.Printf("Service: INPI error for '%s': %v", companyName, err)
-		} else if result != nil && result.Success && len(result.Data) > 0 {
-			return result, nil
-		}
+		ans = append(ans, source{"INPI", s.inpiService.SearchCompany})
 	}
 
 	if s.gouvService != nil {
-		result, err := s.gouvService.SearchCompany(companyName, address)
-		if err != nil {
-			log.Printf("Service: GOUV error for '%s': %v", companyName, err)
-		} else if result != nil && result.Success && len(result.Data) > 0 {
+		ans = append(ans, source{"GOUV", s.gouvService.SearchCompany})
+	}
+
+	return ans
+}
+
+func (s *Service) SearchCompany(ctx context.Context, companyName, address string) (*SearchResult, error) {
+	return s.search(ctx, companyName, address, s.reconcile)
+}
+
+// search returns the company of the first register that knows it, or of all
+// of them reconciled when reconcile is set. When none does, the Error of the
+// result tells why, see missError.
+func (s *Service) search(ctx context.Context, companyName, address string, reconcile bool) (*SearchResult, error) {
+	if reconcile {
+		return s.searchReconciled(ctx, companyName, address), nil
+	}
+
+	var errs []error
+
+	for _, src := range s.sources() {
+		result, err := src.search(ctx, companyName, address)
+
+		switch {
+		case err != nil:
+			log.Printf("Service: %s error for '%s': %v", src.name, companyName, err)
+			errs = append(errs, registerError(src.name, err))
+		case result == nil:
+		case result.Success && len(result.Data) > 0:
+			checkSirens(src.name, result.Data)
+			setSource(src.name, result.Data)
+
 			return result, nil
+		case result.Error != nil:
+			errs = append(errs, registerError(src.name, result.Error))
 		}
 	}
 
@@ -77,12 +240,34 @@ func (s *Service) SearchCompany(companyName, address string) (*SearchResult, err
 		Success:      true,
 		Data:         []CompanyInfo{},
 		TotalResults: 0,
+		Error:        missError(errs),
 	}, nil
 }
 
-func (s *Service) GetDirectors(siren string, siret string) *DirectorInfo {
+func setSource(source string, companies []CompanyInfo) {
+	for i := range companies {
+		companies[i].Source = source
+	}
+}
+
+// ParseAddress parses address as s does to match places with companies.
+func (s *Service) ParseAddress(address string) ParsedAddress {
+	return s.parser.Parse(address)
+}
+
+// GetBySiren returns the company siren from the GOUV register, or nil when it
+// is not found.
+func (s *Service) GetBySiren(ctx context.Context, siren string) (*CompanyInfo, error) {
+	if s.gouvService == nil {
+		return nil, nil
+	}
+
+	return s.gouvService.GetBySiren(ctx, siren)
+}
+
+func (s *Service) GetDirectors(ctx context.Context, siren string, siret string) *DirectorInfo {
 	if s.directorsService != nil {
-		return s.directorsService.GetDirectors(siren, siret)
+		return s.directorsService.GetDirectors(ctx, siren, siret)
 	}
 	return nil
 }
@@ -0,0 +1,91 @@
+package exiter
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// CompletionMonitor calls a function once the jobs a one-shot run waits for
+// are complete, typically every queued job or a given root job.
+type CompletionMonitor struct {
+	pending  func(context.Context) (int, error)
+	interval time.Duration
+	check    chan struct{}
+}
+
+// NewCompletionMonitor creates a monitor done when pending reports no job
+// left. pending is called every interval and each time a root job is done.
+func NewCompletionMonitor(pending func(context.Context) (int, error), interval time.Duration) *CompletionMonitor {
+	return &CompletionMonitor{
+		pending:  pending,
+		interval: interval,
+		check:    make(chan struct{}, 1),
+	}
+}
+
+// RootDone reports that the root job id is done, so the monitor checks the
+// pending jobs without waiting for the next interval.
+func (m *CompletionMonitor) RootDone(_ string) {
+	select {
+	case m.check <- struct{}{}:
+	default:
+	}
+}
+
+// Run calls onComplete once no job is pending, then returns. It returns
+// without calling it when ctx is done, and with the error when pending fails
+// with an error wrapped by Permanent. Other errors are logged and the jobs
+// counted again.
+func (m *CompletionMonitor) Run(ctx context.Context, onComplete func()) error {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-m.check:
+		case <-ticker.C:
+		}
+
+		n, err := m.pending(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+
+		if err != nil {
+			log.Printf("failed to count pending jobs: %v", err)
+			continue
+		}
+
+		if n == 0 {
+			onComplete()
+			return nil
+		}
+	}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent wraps an error of pending that counting again cannot fix, such
+// as a root job that does not exist, so that Run returns it.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
@@ -0,0 +1,61 @@
+package exiter_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/exiter"
+)
+
+func Test_CompletionMonitorWaitsForThePendingJobs(t *testing.T) {
+	var left atomic.Int64
+
+	left.Store(3)
+
+	m := exiter.NewCompletionMonitor(func(context.Context) (int, error) {
+		n := left.Add(-1)
+		if n == 1 {
+			return 0, errors.New("connection reset")
+		}
+
+		return int(n), nil
+	}, time.Millisecond)
+
+	var completed bool
+
+	err := m.Run(context.Background(), func() { completed = true })
+	require.NoError(t, err)
+	require.True(t, completed)
+	require.Equal(t, int64(0), left.Load())
+}
+
+func Test_CompletionMonitorStopsOnPermanentErrors(t *testing.T) {
+	notFound := errors.New("job not found")
+
+	var calls atomic.Int64
+
+	m := exiter.NewCompletionMonitor(func(context.Context) (int, error) {
+		calls.Add(1)
+		return 0, exiter.Permanent(notFound)
+	}, time.Millisecond)
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- m.Run(context.Background(), func() { t.Error("completed without jobs") })
+	}()
+
+	select {
+	case err := <-done:
+		require.ErrorIs(t, err, notFound)
+	case <-time.After(time.Second):
+		t.Fatal("the monitor kept counting")
+	}
+
+	require.Equal(t, int64(1), calls.Load())
+}
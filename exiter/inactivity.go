@@ -0,0 +1,81 @@
+package exiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// InactivityMonitor calls a function once nothing happened for a given
+// duration: no job fetched, running or finished and no result written.
+type InactivityMonitor struct {
+	mu     sync.Mutex
+	idle   time.Duration
+	last   time.Time
+	active int
+}
+
+func NewInactivityMonitor(idle time.Duration) *InactivityMonitor {
+	return &InactivityMonitor{
+		idle: idle,
+		last: time.Now(),
+	}
+}
+
+// Touch records activity.
+func (m *InactivityMonitor) Touch() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.last = time.Now()
+}
+
+// Begin marks the start of a job. The monitor never fires while a job runs,
+// however long it takes.
+func (m *InactivityMonitor) Begin() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.active++
+	m.last = time.Now()
+}
+
+// End marks the end of a job started with Begin.
+func (m *InactivityMonitor) End() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.active--
+	m.last = time.Now()
+}
+
+func (m *InactivityMonitor) isIdle(now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.active == 0 && now.Sub(m.last) >= m.idle
+}
+
+// Run calls onIdle once the monitor has been idle for the configured
+// duration, then returns. It returns without calling it when ctx is done.
+func (m *InactivityMonitor) Run(ctx context.Context, onIdle func()) {
+	interval := min(m.idle/4, 5*time.Second)
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if m.isIdle(now) {
+				onIdle()
+				return
+			}
+		}
+	}
+}
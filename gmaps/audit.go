@@ -0,0 +1,138 @@
+package gmaps
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gosom/scrapemate"
+)
+
+// Audit is a company whose Google Maps listing is checked, as its register
+// knows it.
+type Audit struct {
+	Siren   string `json:"siren"`
+	Name    string `json:"name"`
+	Address string `json:"address,omitempty"`
+}
+
+// AuditResult tells whether a company is listed on Google Maps and, when it
+// is, how complete its listing is: Completeness is the share of
+// listingChecks it passes, from 0 to 100, and Missing names the others.
+type AuditResult struct {
+	Audit
+	SearchID       string
+	OwnerID        string
+	OrganizationID string
+	Listed         bool
+	Title          string
+	Link           string
+	Rating         float64
+	ReviewCount    int
+	Status         string
+	Completeness   int
+	Missing        []string
+}
+
+// listingChecks are what a complete listing shows.
+var listingChecks = []struct {
+	name string
+	ok   func(*Entry) bool
+}{
+	{"phone", func(e *Entry) bool { return e.Phone != "" }},
+	{"website", func(e *Entry) bool { return e.WebSite != "" }},
+	{"hours", func(e *Entry) bool { return len(e.OpenHours) > 0 }},
+	{"description", func(e *Entry) bool { return e.Description != "" }},
+	{"photos", func(e *Entry) bool { return len(e.Images) > 0 }},
+	{"category", func(e *Entry) bool { return e.Category != "" }},
+	{"reviews", func(e *Entry) bool { return e.ReviewCount >= 10 }},
+}
+
+// WithAudit turns the search into the audit of the listing of a company: it
+// finds the company among the places listed and scrapes only its place,
+// which yields an *AuditResult instead of an entry.
+func WithAudit(a Audit) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.Audit = &a
+	}
+}
+
+// WithPlaceJobAudit makes the place job yield the *AuditResult of its place
+// for the company a instead of an entry.
+func WithPlaceJobAudit(a *Audit) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.Audit = a
+	}
+}
+
+// AuditQuery is the search finding the listing of a company: its name and
+// address.
+func AuditQuery(a Audit) string {
+	return strings.TrimSpace(a.Name + " " + a.Address)
+}
+
+// audit queues the place of the company when the search page lists it, and
+// returns the result of a company that is not listed otherwise. A page Google
+// opened on a single place is taken as the company whatever its name.
+func (j *GmapJob) audit(doc *goquery.Document, pageURL string) (any, []scrapemate.IJob) {
+	listings := feedListings(doc, pageURL)
+
+	i := FindRank(j.Audit.Name, listings)
+	if i < 0 && strings.Contains(pageURL, "/maps/place/") {
+		i = 0
+	}
+
+	if i < 0 {
+		return &AuditResult{
+			Audit:          *j.Audit,
+			SearchID:       j.GetID(),
+			OwnerID:        j.OwnerID,
+			OrganizationID: j.OrganizationID,
+			Missing:        checkNames(),
+		}, nil
+	}
+
+	opts := []PlaceJobOptions{WithPlaceJobAudit(j.Audit), WithPlaceJobCountry(j.Country)}
+	if j.ExitMonitor != nil {
+		opts = append(opts, WithPlaceJobExitMonitor(j.ExitMonitor))
+	}
+
+	return nil, []scrapemate.IJob{
+		NewPlaceJob(j.ID, j.LangCode, listings[i].Link, j.OwnerID, j.OrganizationID, false, false, opts...),
+	}
+}
+
+// auditResult checks the listing of the company of the job.
+func (j *PlaceJob) auditResult(entry *Entry) *AuditResult {
+	ans := &AuditResult{
+		Audit:          *j.Audit,
+		SearchID:       j.ParentID,
+		OwnerID:        j.OwnerID,
+		OrganizationID: j.OrganizationID,
+		Listed:         true,
+		Title:          entry.Title,
+		Link:           entry.Link,
+		Rating:         entry.ReviewRating,
+		ReviewCount:    entry.ReviewCount,
+		Status:         entry.Status,
+		Missing:        []string{},
+	}
+
+	for _, c := range listingChecks {
+		if !c.ok(entry) {
+			ans.Missing = append(ans.Missing, c.name)
+		}
+	}
+
+	ans.Completeness = 100 * (len(listingChecks) - len(ans.Missing)) / len(listingChecks)
+
+	return ans
+}
+
+func checkNames() []string {
+	ans := make([]string, len(listingChecks))
+	for i, c := range listingChecks {
+		ans[i] = c.name
+	}
+
+	return ans
+}
@@ -0,0 +1,104 @@
+package gmaps
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gosom/scrapemate"
+	"github.com/playwright-community/playwright-go"
+)
+
+// DefaultBackfillBatchSize is how many results a BackfillJob reads when its
+// BatchSize is not set.
+const DefaultBackfillBatchSize = 200
+
+// Backfiller fills the columns added since older results were saved.
+type Backfiller interface {
+	// Backfill fills the results with an id greater than afterID, up to
+	// limit of them, and returns the last id it read, 0 once none is left.
+	Backfill(ctx context.Context, afterID int64, limit int) (int64, error)
+}
+
+type BackfillerKey struct{}
+
+func GetBackfillerFromContext(ctx context.Context) Backfiller {
+	if b, ok := ctx.Value(BackfillerKey{}).(Backfiller); ok {
+		return b
+	}
+
+	return nil
+}
+
+// BackfillJob is a maintenance job filling the columns of historical results
+// a batch at a time. Each job queues the one for the next batch, so a
+// backfill stopped midway resumes where it was.
+type BackfillJob struct {
+	scrapemate.Job
+	AfterID   int64
+	BatchSize int
+}
+
+func NewBackfillJob(afterID int64, batchSize int) *BackfillJob {
+	if batchSize <= 0 {
+		batchSize = DefaultBackfillBatchSize
+	}
+
+	return &BackfillJob{
+		Job: scrapemate.Job{
+			ID:         uuid.New().String(),
+			Method:     http.MethodGet,
+			URL:        "backfill://results",
+			MaxRetries: 2,
+			Priority:   scrapemate.PriorityLow,
+		},
+		AfterID:   afterID,
+		BatchSize: batchSize,
+	}
+}
+
+func (j *BackfillJob) Process(ctx context.Context, resp *scrapemate.Response) (any, []scrapemate.IJob, error) {
+	defer func() {
+		resp.Document = nil
+		resp.Body = nil
+		resp.Meta = nil
+	}()
+
+	b := GetBackfillerFromContext(ctx)
+	if b == nil {
+		return nil, nil, fmt.Errorf("backfill job %s: no backfiller", j.ID)
+	}
+
+	last, err := b.Backfill(ctx, j.AfterID, j.BatchSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if last == 0 {
+		logr := scrapemate.GetLoggerFromContext(ctx)
+		logr.Info(fmt.Sprintf("backfill done after result %d", j.AfterID))
+
+		return nil, nil, nil
+	}
+
+	return nil, []scrapemate.IJob{NewBackfillJob(last, j.BatchSize)}, nil
+}
+
+func (j *BackfillJob) UseInResults() bool {
+	return false
+}
+
+func (j *BackfillJob) BrowserActions(ctx context.Context, _ playwright.Page) scrapemate.Response {
+	return j.FetchWithoutBrowser(ctx)
+}
+
+// FetchWithoutBrowser returns the response the backfill runs on: it reads
+// the results table when the job is processed.
+func (j *BackfillJob) FetchWithoutBrowser(context.Context) scrapemate.Response {
+	var resp scrapemate.Response
+	resp.URL = j.URL
+	resp.StatusCode = 200
+
+	return resp
+}
@@ -0,0 +1,215 @@
+package gmaps
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosom/scrapemate"
+	"github.com/playwright-community/playwright-go"
+)
+
+// BlockKind describes why Google refused to serve a page.
+type BlockKind string
+
+const (
+	BlockSorry       BlockKind = "sorry"
+	BlockCaptcha     BlockKind = "captcha"
+	BlockConsentWall BlockKind = "consent"
+)
+
+// BlockedError is set as the response error when Google served an anti-bot
+// page instead of the requested content. It is a fetch failure, not a parse
+// failure, so scrapemate retries the job.
+type BlockedError struct {
+	Kind BlockKind
+	URL  string
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("blocked by google (%s) at %s", e.Kind, e.URL)
+}
+
+// BlockReporter is notified when a page is blocked so the caller can rotate
+// the identity (proxy) that served it.
+type BlockReporter interface {
+	ReportBlocked(page playwright.Page)
+}
+
+type BlockReporterKey struct{}
+
+func GetBlockReporterFromContext(ctx context.Context) BlockReporter {
+	if r, ok := ctx.Value(BlockReporterKey{}).(BlockReporter); ok {
+		return r
+	}
+
+	return nil
+}
+
+const (
+	minBlockBackoff = 2 * time.Second
+	maxBlockBackoff = 2 * time.Minute
+)
+
+var blockStats = struct {
+	mu     sync.Mutex
+	counts map[BlockKind]int64
+	scopes map[any]*blockState
+}{
+	counts: make(map[BlockKind]int64),
+	scopes: make(map[any]*blockState),
+}
+
+// blockState is the backoff of a scope.
+type blockState struct {
+	// consecutive counts the pages blocked in a row.
+	consecutive int
+	// until is when the backoff after the last blocked page ends.
+	until time.Time
+}
+
+type blockScopeKey struct{}
+
+// WithBlockScope returns a context running the jobs in scope: the upstream
+// Google sees their pages come from, like the browser context of a proxy
+// session. Pages blocked in a scope only hold back the jobs of that scope;
+// the jobs of a context without one share the scope nil, the address of the
+// worker. scope must be comparable.
+func WithBlockScope(ctx context.Context, scope any) context.Context {
+	return context.WithValue(ctx, blockScopeKey{}, scope)
+}
+
+// BlockScopeFromContext returns the scope set with WithBlockScope, or nil.
+func BlockScopeFromContext(ctx context.Context) any {
+	return ctx.Value(blockScopeKey{})
+}
+
+// BlockedCounts returns how many pages were blocked per kind since startup.
+func BlockedCounts() map[string]int64 {
+	blockStats.mu.Lock()
+	defer blockStats.mu.Unlock()
+
+	ans := make(map[string]int64, len(blockStats.counts))
+	for k, v := range blockStats.counts {
+		ans[string(k)] = v
+	}
+
+	return ans
+}
+
+// BlockBackoff returns how long the jobs of scope should leave Google alone
+// after the last page blocked in it. The backoff doubles with every page
+// blocked in a row, from minBlockBackoff up to maxBlockBackoff.
+func BlockBackoff(scope any) time.Duration {
+	blockStats.mu.Lock()
+	defer blockStats.mu.Unlock()
+
+	st, ok := blockStats.scopes[scope]
+	if !ok {
+		return 0
+	}
+
+	return max(0, time.Until(st.until))
+}
+
+// detectBlock inspects the current page for the sorry interstitial,
+// reCAPTCHA challenges and consent walls that survived the cookie click.
+func detectBlock(page playwright.Page) BlockKind {
+	u := page.URL()
+
+	switch {
+	case strings.Contains(u, "/sorry/"):
+		return BlockSorry
+	case strings.Contains(u, "consent.google."):
+		return BlockConsentWall
+	}
+
+	count, err := page.Locator(`iframe[src*="recaptcha"], #captcha-form, div.g-recaptcha`).Count()
+	if err == nil && count > 0 {
+		return BlockCaptcha
+	}
+
+	return ""
+}
+
+// handleBlock records a blocked page, notifies the reporter and extends the
+// backoff of the scope of ctx returned by BlockBackoff. It does not wait: the
+// job fails right away and the caller holds the search and place jobs of the
+// scope back until the backoff is over.
+func handleBlock(ctx context.Context, page playwright.Page, kind BlockKind) error {
+	recordBlock(BlockScopeFromContext(ctx), kind)
+
+	if reporter := GetBlockReporterFromContext(ctx); reporter != nil {
+		reporter.ReportBlocked(page)
+	}
+
+	return &BlockedError{Kind: kind, URL: page.URL()}
+}
+
+// recordBlock counts a blocked page and extends the backoff of scope.
+func recordBlock(scope any, kind BlockKind) {
+	blockStats.mu.Lock()
+	defer blockStats.mu.Unlock()
+
+	blockStats.counts[kind]++
+
+	// scopes idle for a whole backoff, like closed browser contexts, are
+	// forgotten
+	for k, st := range blockStats.scopes {
+		if time.Since(st.until) > maxBlockBackoff {
+			delete(blockStats.scopes, k)
+		}
+	}
+
+	st, ok := blockStats.scopes[scope]
+	if !ok {
+		st = &blockState{}
+		blockStats.scopes[scope] = st
+	}
+
+	st.consecutive++
+
+	delay := minBlockBackoff << min(st.consecutive-1, 6)
+	if delay > maxBlockBackoff {
+		delay = maxBlockBackoff
+	}
+
+	st.until = time.Now().Add(delay)
+}
+
+// resetBlocks ends the run of blocked pages of scope once one is served, and
+// forgets the scope when its backoff is over.
+func resetBlocks(scope any) {
+	blockStats.mu.Lock()
+	defer blockStats.mu.Unlock()
+
+	st, ok := blockStats.scopes[scope]
+	if !ok {
+		return
+	}
+
+	st.consecutive = 0
+
+	if time.Now().After(st.until) {
+		delete(blockStats.scopes, scope)
+	}
+}
+
+// markBlocked reports whether the page is blocked. Blocked responses carry a
+// *BlockedError and a "blocked" meta entry so they are not mistaken for
+// parse failures.
+func markBlocked(ctx context.Context, page playwright.Page, resp *scrapemate.Response) bool {
+	kind := detectBlock(page)
+	if kind == "" {
+		resetBlocks(BlockScopeFromContext(ctx))
+
+		return false
+	}
+
+	resp.Error = handleBlock(ctx, page, kind)
+	resp.Meta = map[string]any{"blocked": string(kind)}
+
+	return true
+}
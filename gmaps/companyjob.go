@@ -2,9 +2,10 @@ package gmaps
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gosom/google-maps-scraper/entreprise"
@@ -17,6 +18,12 @@ type CompanyDataChecker interface {
 	CheckCompanyDataExists(ctx context.Context, title, address, ownerID, organizationID string) (*entreprise.CompanyInfo, bool, error)
 }
 
+// CredentialResolver returns the register credentials an organization
+// brought, ok false when it has none.
+type CredentialResolver interface {
+	ResolveCredentials(ctx context.Context, organizationID string) (entreprise.Credentials, bool, error)
+}
+
 type CompanyEnrichmentResult struct {
 	PlaceLink         string
 	OwnerID           string
@@ -29,8 +36,37 @@ type CompanyEnrichmentResult struct {
 	SocieteLink       string
 	SocieteDiffusion  *bool
 	PappersURL        string
+	NafCode           string
+	// SirenResolution explains the choice of the SIREN when the registers
+	// disagreed.
+	SirenResolution string
+	// SocieteNom is the name of the company in the register, and MatchScore
+	// how closely it matches the place.
+	SocieteNom string
+	MatchScore float64
+	// Source is the register that supplied the company and DirectorsSource
+	// the one that supplied SocieteDirigeants.
+	Source          string
+	DirectorsSource string
+	FetchedAt       time.Time
+	// Existing is set when the data came from the CompanyDataChecker.
+	Existing bool
+	// Outcome is MatchOutcomeMatched or MatchOutcomeNoMatch, empty when the
+	// lookup could not tell, e.g. the registers rejected the credentials.
+	Outcome string
+	// Rejected is the company the registers matched best on a no_match,
+	// with its MatchScore, nil when they knew none.
+	Rejected *entreprise.CompanyInfo
 }
 
+const (
+	// MatchOutcomeMatched is the Outcome of a lookup that found the company.
+	MatchOutcomeMatched = "matched"
+	// MatchOutcomeNoMatch is the Outcome of a lookup that no register
+	// answered with a close enough company.
+	MatchOutcomeNoMatch = "no_match"
+)
+
 type CompanyJobOptions func(*CompanyJob)
 
 type CompanyJob struct {
@@ -111,6 +147,13 @@ func (j *CompanyJob) Process(ctx context.Context, resp *scrapemate.Response) (an
 		if err != nil {
 			logr.Info(fmt.Sprintf("CheckCompanyDataExists error for %s: %v", j.CompanyName, err))
 		} else if exists && existingData != nil {
+			enrichResult.Existing = true
+			enrichResult.Source = existingData.Source
+			if enrichResult.Source == "" {
+				enrichResult.Source = SourceStoredResult
+			}
+			enrichResult.DirectorsSource = enrichResult.Source
+			enrichResult.FetchedAt = time.Now().UTC()
 			enrichResult.SocieteDirigeants = existingData.SocieteDirigeants
 			enrichResult.SocieteForme = existingData.SocieteForme
 			enrichResult.SocieteCreation = existingData.SocieteCreation
@@ -118,14 +161,14 @@ func (j *CompanyJob) Process(ctx context.Context, resp *scrapemate.Response) (an
 			enrichResult.SocieteSiren = existingData.SocieteSiren
 			enrichResult.SocieteLink = existingData.SocieteLink
 			enrichResult.SocieteDiffusion = existingData.SocieteDiffusion
+			enrichResult.NafCode = existingData.NafCode
 
 			if len(enrichResult.SocieteDirigeants) == 0 && enrichResult.SocieteSiren != "" {
-				service := entreprise.NewService()
-				directorInfo := service.GetDirectors(enrichResult.SocieteSiren, "")
+				service := j.service(ctx)
+				directorInfo := service.GetDirectors(ctx, enrichResult.SocieteSiren, "")
 				if directorInfo != nil && directorInfo.Nom != "" && directorInfo.Prenom != "" {
-					prenomFormatted := strings.ToUpper(string(directorInfo.Prenom[0])) + strings.ToLower(directorInfo.Prenom[1:])
-					directorName := directorInfo.Nom + " " + prenomFormatted
-					enrichResult.SocieteDirigeants = []string{directorName}
+					enrichResult.SocieteDirigeants = []string{directorInfo.FullName()}
+					enrichResult.DirectorsSource = directorInfo.Source
 				}
 			}
 
@@ -133,18 +176,32 @@ func (j *CompanyJob) Process(ctx context.Context, resp *scrapemate.Response) (an
 		}
 	}
 
-	service := entreprise.NewService()
-	result, err := service.SearchCompany(j.CompanyName, j.Address)
+	company, err := entreprise.Enrich(ctx, j.CompanyName, j.Address, entreprise.WithService(j.service(ctx)))
 
-	if err != nil {
+	switch {
+	case err == nil:
+	case entreprise.Retryable(err):
+		// the job fails and the result keeps an empty SIREN, which
+		// -reenrich looks up again
+		return nil, nil, fmt.Errorf("company lookup of %s: %w", j.CompanyName, err)
+	case errors.Is(err, entreprise.ErrAuth):
+		logr.Error(fmt.Sprintf("company lookup of %s: %v", j.CompanyName, err))
 		return enrichResult, nil, nil
-	}
+	default:
+		// a permanent miss: no register knows the company well enough
+		logr.Info(fmt.Sprintf("company lookup of %s: %v", j.CompanyName, err))
+
+		enrichResult.Outcome = MatchOutcomeNoMatch
+		enrichResult.FetchedAt = time.Now().UTC()
+
+		var lce *entreprise.LowConfidenceError
+		if errors.As(err, &lce) {
+			enrichResult.Rejected = &lce.Candidate
+		}
 
-	if !result.Success || len(result.Data) == 0 {
 		return enrichResult, nil, nil
 	}
 
-	company := result.Data[0]
 	enrichResult.SocieteDirigeants = company.SocieteDirigeants
 	enrichResult.SocieteForme = company.SocieteForme
 	enrichResult.SocieteCreation = company.SocieteCreation
@@ -153,15 +210,14 @@ func (j *CompanyJob) Process(ctx context.Context, resp *scrapemate.Response) (an
 	enrichResult.SocieteLink = company.SocieteLink
 	enrichResult.SocieteDiffusion = company.SocieteDiffusion
 	enrichResult.PappersURL = company.PappersURL
-
-	if len(company.SocieteDirigeants) == 0 && company.SocieteSiren != "" {
-		directorInfo := service.GetDirectors(company.SocieteSiren, "")
-		if directorInfo != nil && directorInfo.Nom != "" && directorInfo.Prenom != "" {
-			prenomFormatted := strings.ToUpper(string(directorInfo.Prenom[0])) + strings.ToLower(directorInfo.Prenom[1:])
-			directorName := directorInfo.Nom + " " + prenomFormatted
-			enrichResult.SocieteDirigeants = []string{directorName}
-		}
-	}
+	enrichResult.NafCode = company.NafCode
+	enrichResult.SirenResolution = company.Resolution
+	enrichResult.SocieteNom = company.SocieteNom
+	enrichResult.MatchScore = company.MatchScore
+	enrichResult.Source = company.Source
+	enrichResult.DirectorsSource = company.DirectorsSource
+	enrichResult.FetchedAt = time.Now().UTC()
+	enrichResult.Outcome = MatchOutcomeMatched
 
 	// If PappersURL is available, create a PappersJob for director scraping
 	if enrichResult.PappersURL != "" {
@@ -174,6 +230,54 @@ func (j *CompanyJob) Process(ctx context.Context, resp *scrapemate.Response) (an
 	return enrichResult, nil, nil
 }
 
+// service returns the Service querying the registers with the credentials
+// of the organization of the job, completed with the global ones, or the
+// global Service when it has none. Both are those of the Services of ctx.
+func (j *CompanyJob) service(ctx context.Context) *entreprise.Service {
+	services := GetRegisterServicesFromContext(ctx)
+
+	resolver := GetCredentialResolverFromContext(ctx)
+	if resolver == nil || j.OrganizationID == "" {
+		return services.Default()
+	}
+
+	creds, ok, err := resolver.ResolveCredentials(ctx, j.OrganizationID)
+	if err != nil {
+		logr := scrapemate.GetLoggerFromContext(ctx)
+		logr.Info(fmt.Sprintf("ResolveCredentials error for organization %s: %v", j.OrganizationID, err))
+
+		return services.Default()
+	}
+
+	if !ok {
+		return services.Default()
+	}
+
+	return services.Shared(creds.Merge(entreprise.CredentialsFromEnv()))
+}
+
+type RegisterServicesKey struct{}
+
+// GetRegisterServicesFromContext returns the Services the company lookups
+// query the registers with, those created without options when ctx has
+// none.
+func GetRegisterServicesFromContext(ctx context.Context) *entreprise.Services {
+	if s, ok := ctx.Value(RegisterServicesKey{}).(*entreprise.Services); ok {
+		return s
+	}
+
+	return entreprise.DefaultServices()
+}
+
+type CredentialResolverKey struct{}
+
+func GetCredentialResolverFromContext(ctx context.Context) CredentialResolver {
+	if r, ok := ctx.Value(CredentialResolverKey{}).(CredentialResolver); ok {
+		return r
+	}
+	return nil
+}
+
 type CompanyDataCheckerKey struct{}
 
 func GetCompanyDataCheckerFromContext(ctx context.Context) CompanyDataChecker {
@@ -187,7 +291,13 @@ func (j *CompanyJob) UseInResults() bool {
 	return false
 }
 
-func (j *CompanyJob) BrowserActions(ctx context.Context, page playwright.Page) scrapemate.Response {
+func (j *CompanyJob) BrowserActions(ctx context.Context, _ playwright.Page) scrapemate.Response {
+	return j.FetchWithoutBrowser(ctx)
+}
+
+// FetchWithoutBrowser returns the response the lookup runs on: the company
+// registers are queried when the job is processed.
+func (j *CompanyJob) FetchWithoutBrowser(context.Context) scrapemate.Response {
 	var resp scrapemate.Response
 	resp.URL = "entreprise://api"
 	resp.StatusCode = 200
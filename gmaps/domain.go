@@ -0,0 +1,91 @@
+package gmaps
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// DefaultDomain is the Google domain of the searches that do not set one.
+const DefaultDomain = "google.com"
+
+// ConsentCookie is the Google cookie carrying the answer to the EU consent
+// form, and ConsentCookieValue a "reject all" answer to it.
+const (
+	ConsentCookie      = "SOCS"
+	ConsentCookieValue = "CAESHAgBEhJnd3NfMjAyMzA4MTAtMF9SQzIaAmVuIAEaBgiAo_CmBg"
+)
+
+// domainRe matches the regional Google domains: google.de, google.co.uk,
+// google.com.au and the like.
+var domainRe = regexp.MustCompile(`^google\.(com|(co\.|com\.)?[a-z]{2})$`)
+
+// ParseDomain normalizes a Google domain given as "google.de",
+// "www.google.co.uk" or "https://www.google.fr/", and rejects the hosts that
+// are not Google domains. An empty domain stays empty.
+func ParseDomain(s string) (string, error) {
+	d := strings.ToLower(strings.TrimSpace(s))
+	d = strings.TrimPrefix(strings.TrimPrefix(d, "https://"), "http://")
+	d = strings.TrimPrefix(strings.TrimSuffix(d, "/"), "www.")
+
+	if d == "" {
+		return "", nil
+	}
+
+	if !domainRe.MatchString(d) {
+		return "", fmt.Errorf("invalid Google domain %q (expected e.g. 'google.de' or 'google.co.uk')", s)
+	}
+
+	return d, nil
+}
+
+// WithDomain runs the search on a regional Google domain, e.g. "google.de"
+// or "google.co.uk", instead of google.com. The place jobs found by the
+// search inherit it through the URLs of the feed. Domains ParseDomain
+// rejects are ignored.
+func WithDomain(domain string) GmapJobOptions {
+	return func(j *GmapJob) {
+		d, err := ParseDomain(domain)
+		if err != nil || d == "" {
+			return
+		}
+
+		u, err := url.Parse(j.URL)
+		if err != nil {
+			return
+		}
+
+		u.Host = "www." + d
+		j.URL = u.String()
+
+		if d != DefaultDomain {
+			j.Domain = d
+		}
+	}
+}
+
+// acceptConsent seeds the context of the page with the consent answer for
+// the domain of rawURL, the stored session only holding the google.com one,
+// so that regional domains do not open on their consent form.
+func acceptConsent(page playwright.Page, rawURL string) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+
+	d, err := ParseDomain(u.Host)
+	if err != nil || d == "" || d == DefaultDomain {
+		return
+	}
+
+	_ = page.Context().AddCookies([]playwright.OptionalCookie{{
+		Name:   ConsentCookie,
+		Value:  ConsentCookieValue,
+		Domain: playwright.String("." + d),
+		Path:   playwright.String("/"),
+		Secure: playwright.Bool(true),
+	}})
+}
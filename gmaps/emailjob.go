@@ -24,6 +24,15 @@ type EmailEnrichmentResult struct {
 	OwnerID        string
 	OrganizationID string
 	Emails         []string
+	// Technologies are the products the website runs on, see
+	// DetectTechnologies.
+	Technologies []string
+	// WebsiteHealth is how the website fared when visited, see
+	// CheckWebsite.
+	WebsiteHealth *WebsiteHealth
+	// WhatsApp is the number of the first click-to-chat link of the
+	// website.
+	WhatsApp string
 }
 
 type EmailExtractJobOptions func(*EmailExtractJob)
@@ -35,6 +44,8 @@ type EmailExtractJob struct {
 	OrganizationID string
 	PlaceLink      string
 	ExitMonitor    exiter.Exiter
+	// EnrichmentJobs fetch the social pages the website links to.
+	EnrichmentJobs []scrapemate.IJob
 }
 
 func NewEmailJob(parentID string, placeLink, websiteURL, ownerID, organizationID string, opts ...EmailExtractJobOptions) *EmailExtractJob {
@@ -80,6 +91,8 @@ func (j *EmailExtractJob) Process(ctx context.Context, resp *scrapemate.Response
 		PlaceLink:      j.PlaceLink,
 		OwnerID:        j.OwnerID,
 		OrganizationID: j.OrganizationID,
+		// a site that cannot be fetched is what its health is about
+		WebsiteHealth: CheckWebsite(ctx, j.URL),
 	}
 
 	// if html fetch failed just return
@@ -108,6 +121,17 @@ func (j *EmailExtractJob) Process(ctx context.Context, resp *scrapemate.Response
 	}
 
 	result.Emails = emails
+	result.Technologies = DetectTechnologies(doc, resp.Body, resp.Headers)
+
+	var links []string
+
+	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		links = append(links, s.AttrOr("href", ""))
+	})
+
+	result.WhatsApp = findWhatsApp(links)
+
+	j.EnrichmentJobs = socialJobs(links, j.GetID(), j.PlaceLink, j.OwnerID, j.OrganizationID, j.ExitMonitor)
 
 	return result, nil, nil
 }
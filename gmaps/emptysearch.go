@@ -0,0 +1,185 @@
+package gmaps
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// EmptySearchKind tells why a search listed no places.
+type EmptySearchKind string
+
+const (
+	// EmptyNoResults is Google answering that nothing matches the query.
+	EmptyNoResults EmptySearchKind = "no_results"
+	// EmptyBlocked is an anti-bot page that was not caught while browsing.
+	EmptyBlocked EmptySearchKind = "blocked"
+	// EmptyUnknown is a page without results nor an answer, usually one
+	// that did not finish loading.
+	EmptyUnknown EmptySearchKind = "unknown"
+)
+
+// Relaxation strategies, in the order they are tried.
+const (
+	// RelaxDropModifiers keeps the first and last words of the query,
+	// usually the activity and the place, and drops the search operators.
+	RelaxDropModifiers = "drop_modifiers"
+	// RelaxWidenGeo zooms the search out.
+	RelaxWidenGeo = "widen_geo"
+)
+
+const (
+	// widenZoomSteps is how many zoom levels RelaxWidenGeo zooms out.
+	widenZoomSteps = 3
+	// minRelaxedZoom is the widest zoom RelaxWidenGeo goes to.
+	minRelaxedZoom = 8
+)
+
+// noResultsMarkers are the messages of the Google Maps "no results" panel in
+// the languages campaigns run in.
+var noResultsMarkers = []string{
+	"google maps can't find",
+	"google maps ne trouve pas",
+	"google maps kann",
+	"google maps no encuentra",
+	"google maps non riesce a trovare",
+	"google maps não encontra",
+}
+
+var emptySearchStats = struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}{
+	counts: make(map[string]int64),
+}
+
+func countEmptySearch(key string) {
+	emptySearchStats.mu.Lock()
+	defer emptySearchStats.mu.Unlock()
+
+	emptySearchStats.counts[key]++
+}
+
+// EmptySearchCounts returns how many searches listed no places per kind, and
+// how many relaxed searches were queued per strategy ("relaxed.widen_geo"),
+// since startup.
+func EmptySearchCounts() map[string]int64 {
+	emptySearchStats.mu.Lock()
+	defer emptySearchStats.mu.Unlock()
+
+	ans := make(map[string]int64, len(emptySearchStats.counts))
+	for k, v := range emptySearchStats.counts {
+		ans[k] = v
+	}
+
+	return ans
+}
+
+// ClassifyEmptySearch tells why the search page at pageURL lists no places.
+func ClassifyEmptySearch(doc *goquery.Document, pageURL string) EmptySearchKind {
+	if strings.Contains(pageURL, "/sorry/") ||
+		doc.Find(`iframe[src*="recaptcha"], #captcha-form, div.g-recaptcha`).Length() > 0 {
+		return EmptyBlocked
+	}
+
+	text := strings.ToLower(doc.Text())
+
+	for _, marker := range noResultsMarkers {
+		if strings.Contains(text, marker) {
+			return EmptyNoResults
+		}
+	}
+
+	return EmptyUnknown
+}
+
+// RelaxQuery drops the modifiers of query: quotes and operators go, and of
+// three words or more only the first and the last are kept. It returns false
+// when that leaves the query unchanged.
+func RelaxQuery(query string) (string, bool) {
+	var words []string
+
+	for _, w := range strings.Fields(query) {
+		w = strings.Trim(w, `"'()+`)
+
+		if w == "" || w == "OR" || w == "AND" || strings.HasPrefix(w, "-") {
+			continue
+		}
+
+		words = append(words, w)
+	}
+
+	if len(words) > 2 {
+		words = []string{words[0], words[len(words)-1]}
+	}
+
+	relaxed := strings.Join(words, " ")
+	if relaxed == "" || relaxed == strings.Join(strings.Fields(query), " ") {
+		return query, false
+	}
+
+	return relaxed, true
+}
+
+// relax returns the search to run instead of j, which listed no places, with
+// the next strategy that changes it, or nil when none is left.
+func (j *GmapJob) relax() *GmapJob {
+	query := SearchQuery(j.GetURL())
+	geo, zoom := j.GeoCoordinates, j.Zoom
+
+	var strategy string
+
+	for _, s := range []string{RelaxDropModifiers, RelaxWidenGeo} {
+		if slices.Contains(j.Relaxations, s) {
+			continue
+		}
+
+		switch s {
+		case RelaxDropModifiers:
+			if relaxed, ok := RelaxQuery(query); ok {
+				query, strategy = relaxed, s
+			}
+		case RelaxWidenGeo:
+			if geo != "" && zoom > minRelaxedZoom {
+				zoom, strategy = max(minRelaxedZoom, zoom-widenZoomSteps), s
+			}
+		}
+
+		if strategy != "" {
+			break
+		}
+	}
+
+	if strategy == "" {
+		return nil
+	}
+
+	opts := []GmapJobOptions{
+		WithCountry(j.Country),
+		WithDomain(j.Domain),
+		WithMaxResults(j.MaxResults),
+		WithRelaxEmpty(),
+	}
+
+	if j.Deduper != nil {
+		opts = append(opts, WithDeduper(j.Deduper))
+	}
+
+	if j.ExitMonitor != nil {
+		opts = append(opts, WithExitMonitor(j.ExitMonitor))
+	}
+
+	if j.ExtractExtraReviews {
+		opts = append(opts, WithExtraReviews())
+	}
+
+	next := NewGmapJob("", j.LangCode, query, j.OwnerID, j.OrganizationID, j.MaxDepth,
+		j.ExtractEmail, j.ExtractBodacc, geo, zoom, opts...)
+	next.Relaxations = append(append([]string(nil), j.Relaxations...), strategy)
+
+	countEmptySearch("relaxed." + strategy)
+
+	return next
+}
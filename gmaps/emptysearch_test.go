@@ -0,0 +1,75 @@
+package gmaps_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gosom/scrapemate"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_RelaxQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+		ok    bool
+	}{
+		{query: "plombier urgence 24h paris", want: "plombier paris", ok: true},
+		{query: `"boulangerie bio" -chaine lyon`, want: "boulangerie lyon", ok: true},
+		{query: "plombier paris", want: "plombier paris", ok: false},
+		{query: "pizza", want: "pizza", ok: false},
+	}
+
+	for _, tc := range tests {
+		got, ok := gmaps.RelaxQuery(tc.query)
+		require.Equal(t, tc.ok, ok, tc.query)
+		require.Equal(t, tc.want, got, tc.query)
+	}
+}
+
+func Test_EmptySearchRelaxation(t *testing.T) {
+	page := `<html><body><div>Google Maps can't find plombier urgence paris</div></body></html>`
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	require.NoError(t, err)
+
+	require.Equal(t, gmaps.EmptyNoResults, gmaps.ClassifyEmptySearch(doc, "https://www.google.com/maps/search/x"))
+
+	job := gmaps.NewGmapJob("", "fr", "plombier urgence paris", "owner-1", "", 10, false, false, "48.8566,2.3522", 14,
+		gmaps.WithRelaxEmpty())
+
+	search := func(j *gmaps.GmapJob) *gmaps.GmapJob {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+		require.NoError(t, err)
+
+		_, next, err := j.Process(context.Background(), &scrapemate.Response{URL: j.GetURL(), Document: doc})
+		require.NoError(t, err)
+
+		if len(next) == 0 {
+			return nil
+		}
+
+		require.Len(t, next, 1)
+
+		relaxed, ok := next[0].(*gmaps.GmapJob)
+		require.True(t, ok)
+
+		return relaxed
+	}
+
+	first := search(job)
+	require.NotNil(t, first)
+	require.Equal(t, "plombier paris", gmaps.SearchQuery(first.GetURL()))
+	require.Equal(t, []string{gmaps.RelaxDropModifiers}, first.Relaxations)
+
+	second := search(first)
+	require.NotNil(t, second)
+	require.Equal(t, 11, second.Zoom)
+	require.Equal(t, []string{gmaps.RelaxDropModifiers, gmaps.RelaxWidenGeo}, second.Relaxations)
+
+	require.Nil(t, search(second))
+}
@@ -10,6 +10,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Image struct {
@@ -55,15 +56,23 @@ type Review struct {
 	Description    string
 	Images         []string
 	When           string
+	// OwnerResponse is the answer of the owner, empty when there is none.
+	OwnerResponse string
+
+	published   time.Time
+	respondedAt time.Time
 }
 
 type Entry struct {
-	ID         string              `json:"input_id"`
-	Link       string              `json:"link"`
-	Cid        string              `json:"cid"`
-	Title      string              `json:"title"`
-	Categories []string            `json:"categories"`
-	Category   string              `json:"category"`
+	ID         string   `json:"input_id"`
+	Link       string   `json:"link"`
+	Cid        string   `json:"cid"`
+	Title      string   `json:"title"`
+	Categories []string `json:"categories"`
+	Category   string   `json:"category"`
+	// CategoryID is the canonical id of the localized Category, set by the
+	// CategoryMapper.
+	CategoryID string              `json:"category_id"`
 	Address    string              `json:"address"`
 	OpenHours  map[string][]string `json:"open_hours"`
 	// PopularTImes is a map with keys the days of the week
@@ -102,6 +111,26 @@ type Entry struct {
 	SocieteLink         string                 `json:"societe_link"`
 	SocieteDiffusion    *bool                  `json:"societe_diffusion"`
 	PappersURL          string                 `json:"pappers_url"`
+	// CodeCommune and CodePostal are the INSEE commune code and postal code
+	// of the coordinates, set for French places by the ReverseGeocoder.
+	CodeCommune string `json:"code_commune"`
+	CodePostal  string `json:"code_postal"`
+	// DepartmentCode, DepartmentName and Region locate French places, e.g.
+	// 69, Rhône, Auvergne-Rhône-Alpes.
+	DepartmentCode string `json:"department_code"`
+	DepartmentName string `json:"department_name"`
+	Region         string `json:"region"`
+	// Provenance records the source of the enriched fields.
+	Provenance Provenance `json:"provenance,omitempty"`
+	// ServiceAreaBusiness is set for listings with a hidden address, whose
+	// ServiceArea is the locality Google shows instead, e.g. Lyon, France.
+	ServiceAreaBusiness bool   `json:"service_area_business"`
+	ServiceArea         string `json:"service_area,omitempty"`
+	// ReviewStats sums up the extra reviews, set when they are fetched.
+	ReviewStats *ReviewStats `json:"review_stats,omitempty"`
+	// WhatsApp is the number a click-to-chat link of the place opens a chat
+	// with, e.g. +33612345678.
+	WhatsApp string `json:"whatsapp,omitempty"`
 }
 
 func (e *Entry) haversineDistance(lat, lon float64) float64 {
@@ -433,6 +462,8 @@ func EntryFromJSON(raw []byte, reviewCountOnly ...bool) (entry Entry, err error)
 	reviewsI := getNthElementAndCast[[]any](darray, 175, 9, 0, 0)
 	entry.UserReviews = make([]Review, 0, len(reviewsI))
 
+	entry.setWhatsApp()
+
 	return entry, nil
 }
 
@@ -467,6 +498,13 @@ func parseReviews(reviewsI []any) []Review {
 			continue
 		}
 
+		review.published = microsToTime(getNthElementAndCast[float64](el, 1, 2))
+
+		review.OwnerResponse = getNthElementAndCast[string](el, 3, 14, 0, 0)
+		if review.OwnerResponse != "" {
+			review.respondedAt = microsToTime(getNthElementAndCast[float64](el, 3, 1))
+		}
+
 		optsI := getNthElementAndCast[[]any](el, 2, 2, 0, 1, 21, 7)
 
 		for j := range optsI {
@@ -1,6 +1,7 @@
 package gmaps_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"testing"
@@ -216,3 +217,36 @@ func Test_EntryFromJsonC(t *testing.T) {
 		fmt.Printf("%+v\n", entry)
 	}
 }
+
+func Test_ReviewStats(t *testing.T) {
+	raw, err := os.ReadFile("../testdata/raw2.json")
+	require.NoError(t, err)
+
+	// a page of extra reviews holds them like the place data does
+	var jd []any
+	require.NoError(t, json.Unmarshal(raw, &jd))
+
+	reviews := jd[6].([]any)[175].([]any)[9].([]any)[0].([]any)[0]
+
+	page, err := json.Marshal([]any{nil, nil, reviews})
+	require.NoError(t, err)
+
+	var entry gmaps.Entry
+
+	entry.AddExtraReviews([][]byte{page})
+	require.Len(t, entry.UserReviewsExtended, 8)
+	require.NotEmpty(t, entry.UserReviewsExtended[2].OwnerResponse)
+
+	stats := gmaps.NewReviewStats(entry.UserReviewsExtended)
+	require.NotNil(t, stats)
+	require.Equal(t, 8, stats.Reviews)
+	require.Equal(t, 2, stats.Responses)
+	require.Equal(t, 0.25, stats.ResponseRate)
+	require.Equal(t, 6, stats.Positive)
+	require.Equal(t, 0, stats.Neutral)
+	require.Equal(t, 2, stats.Negative)
+	// answered 17 hours and 132 days after the reviews
+	require.InDelta(t, 1588.48, stats.ResponseDelayHours, 0.01)
+
+	require.Nil(t, gmaps.NewReviewStats(nil))
+}
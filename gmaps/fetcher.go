@@ -0,0 +1,147 @@
+package gmaps
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gosom/scrapemate"
+)
+
+// BrowserlessJob is implemented by the jobs that load their data themselves
+// when no browser runs, instead of having their page downloaded.
+type BrowserlessJob interface {
+	FetchWithoutBrowser(ctx context.Context) scrapemate.Response
+}
+
+var errNoHTTPClient = errors.New("no http client to fetch the page with")
+
+// FetchWithoutBrowser loads the data of job without a browser: a
+// BrowserlessJob loads it itself, the page of any other job is downloaded
+// with the client of ctx, see GetPlaceHTTPClientFromContext.
+func FetchWithoutBrowser(ctx context.Context, job scrapemate.IJob) scrapemate.Response {
+	if j, ok := job.(BrowserlessJob); ok {
+		return j.FetchWithoutBrowser(ctx)
+	}
+
+	client := GetPlaceHTTPClientFromContext(ctx)
+	if client == nil {
+		return scrapemate.Response{Error: errNoHTTPClient}
+	}
+
+	body, finalURL, err := fetchPage(withProxySession(ctx, job.GetID()), client, job.GetFullURL())
+	if err != nil {
+		return scrapemate.Response{URL: finalURL, Error: err}
+	}
+
+	return scrapemate.Response{URL: finalURL, StatusCode: http.StatusOK, Body: body}
+}
+
+// httpScope is the scope of the pages loaded over HTTP without a proxy
+// session. Google tells the HTTP client apart from the browsers on the same
+// address, so its blocks do not hold back the browser.
+type httpScope struct{}
+
+// httpFetcher is the scrapemate fetcher of fast mode.
+type httpFetcher struct {
+	client   *http.Client
+	sessions bool
+	browser  scrapemate.HTTPFetcher
+}
+
+// HTTPFetcherOption configures the fetcher of NewHTTPFetcher.
+type HTTPFetcherOption func(*httpFetcher)
+
+// WithProxySessions tells the fetcher its client reaches Google through a
+// proxy session per job (see NewPlaceHTTPClient), so a blocked page only holds
+// back the job it was loaded for.
+func WithProxySessions() HTTPFetcherOption {
+	return func(f *httpFetcher) {
+		f.sessions = true
+	}
+}
+
+// WithBrowserFallback gives the fetcher a browser fetcher for the jobs the
+// HTTP path cannot load: the searches scrolling their results, the places
+// collecting their extra reviews, and the places whose HTTP load fails or is
+// blocked.
+func WithBrowserFallback(browser scrapemate.HTTPFetcher) HTTPFetcherOption {
+	return func(f *httpFetcher) {
+		f.browser = browser
+	}
+}
+
+// NewHTTPFetcher returns a scrapemate fetcher running no browser of its own:
+// it loads the data of the jobs with FetchWithoutBrowser and client. Blocked
+// pages extend the backoff of BlockBackoff like they do in the browser.
+func NewHTTPFetcher(client *http.Client, opts ...HTTPFetcherOption) scrapemate.HTTPFetcher {
+	f := &httpFetcher{client: client}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	return f
+}
+
+func (f *httpFetcher) Fetch(ctx context.Context, job scrapemate.IJob) scrapemate.Response {
+	inner := job
+	if w, ok := job.(interface{ Unwrap() scrapemate.IJob }); ok {
+		inner = w.Unwrap()
+	}
+
+	if f.browser != nil && needsBrowser(inner) {
+		return f.browser.Fetch(ctx, job)
+	}
+
+	var scope any = httpScope{}
+	if f.sessions {
+		scope = proxySession(job.GetID())
+	}
+
+	_, isPlace := inner.(*PlaceJob)
+	fallback := f.browser != nil && isPlace
+
+	// the browser loads the places while the HTTP path backs off
+	if fallback && BlockBackoff(scope) > 0 {
+		return f.browser.Fetch(ctx, job)
+	}
+
+	resp := FetchWithoutBrowser(WithBlockScope(context.WithValue(ctx, PlaceHTTPClientKey{}, f.client), scope), job)
+
+	var blocked *BlockedError
+
+	switch {
+	case errors.As(resp.Error, &blocked):
+		recordBlock(scope, blocked.Kind)
+	case resp.Error == nil:
+		resetBlocks(scope)
+	}
+
+	if resp.Error != nil && fallback {
+		return f.browser.Fetch(ctx, job)
+	}
+
+	return resp
+}
+
+// needsBrowser tells whether job loads data only a browser gets: search
+// results as the page scrolls, reviews paginated through the page.
+func needsBrowser(job scrapemate.IJob) bool {
+	switch j := job.(type) {
+	case *GmapJob:
+		return true
+	case *PlaceJob:
+		return j.ExtractExtraReviews
+	}
+
+	return false
+}
+
+func (f *httpFetcher) Close() error {
+	if f.browser != nil {
+		return f.browser.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package gmaps_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gosom/scrapemate"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_HTTPFetcherLoadsJobsWithoutBrowser(t *testing.T) {
+	var cookies []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookies = append(cookies, r.Header.Get("Cookie"))
+		_, _ = w.Write([]byte(`<a href="mailto:hello@example.com">mail</a>`))
+	}))
+	defer srv.Close()
+
+	client, err := gmaps.NewPlaceHTTPClient("")
+	require.NoError(t, err)
+
+	f := gmaps.NewHTTPFetcher(client)
+	ctx := context.Background()
+
+	// the page of a job without a browserless path is downloaded, without
+	// the consent answer of google
+	resp := f.Fetch(ctx, gmaps.NewEmailJob("", "https://www.google.com/maps/place/acme", srv.URL, "owner-1", ""))
+	require.NoError(t, resp.Error)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, string(resp.Body), "hello@example.com")
+	require.Equal(t, []string{""}, cookies)
+
+	// the lookups load nothing
+	resp = f.Fetch(ctx, gmaps.NewCompanyJob("Acme", "1 rue de la Paix, Paris", "owner-1", "", ""))
+	require.NoError(t, resp.Error)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// searches scroll their results in a browser
+	resp = f.Fetch(ctx, gmaps.NewGmapJob("", "en", "acme", "owner-1", "", 1, false, false, "", 0))
+	require.Error(t, resp.Error)
+	require.Len(t, cookies, 1)
+}
+
+func Test_SearchJobQueuesPlaceJobs(t *testing.T) {
+	raw, err := os.ReadFile("../testdata/output.json")
+	require.NoError(t, err)
+
+	job := gmaps.NewSearchJob(&gmaps.MapSearchParams{
+		Location: gmaps.MapLocation{Lat: 37.7749, Lon: -122.4194, ZoomLvl: 13, Radius: 1e8},
+		Query:    "coffee",
+		Hl:       "fr",
+	}, gmaps.WithSearchJobOwner("owner-1", "org-1"), gmaps.WithSearchJobEnrichment(true, false))
+
+	// the endpoint prefixes its payload with a line
+	resp := scrapemate.Response{Body: append([]byte(")]}'\n"), raw...)}
+
+	data, next, err := job.Process(context.Background(), &resp)
+	require.NoError(t, err)
+	require.Nil(t, data)
+	require.NotEmpty(t, next)
+
+	for _, n := range next {
+		place, ok := n.(*gmaps.PlaceJob)
+		require.True(t, ok)
+		require.Equal(t, job.ID, place.ParentID)
+		require.Equal(t, "owner-1", place.OwnerID)
+		require.Equal(t, "org-1", place.OrganizationID)
+		require.True(t, place.ExtractEmail)
+		require.Equal(t, "fr", place.URLParams["hl"])
+		require.True(t, strings.HasPrefix(place.URL, "https://www.google.com/maps/place/data=!4m2!3m1!1s0x"), place.URL)
+	}
+}
+
+// roundTripFunc answers the requests of a client.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func Test_HTTPFetcherBacksOffPerProxySession(t *testing.T) {
+	// google answers every request with a 429
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusTooManyRequests, Body: http.NoBody, Request: r}, nil
+	})}
+
+	f := gmaps.NewHTTPFetcher(client, gmaps.WithProxySessions())
+
+	job := gmaps.NewEmailJob("", "https://www.google.com/maps/place/acme", "https://www.google.com/acme", "owner-1", "")
+
+	var blocked *gmaps.BlockedError
+
+	resp := f.Fetch(context.Background(), job)
+	require.ErrorAs(t, resp.Error, &blocked)
+
+	// only the jobs of the session of the job are held back
+	require.Greater(t, gmaps.BlockBackoff("job-"+job.GetID()), time.Duration(0))
+	require.Equal(t, time.Duration(0), gmaps.BlockBackoff(nil))
+}
+
+// fakeFetcher records the jobs it fetches.
+type fakeFetcher struct {
+	jobs []scrapemate.IJob
+}
+
+func (f *fakeFetcher) Fetch(_ context.Context, job scrapemate.IJob) scrapemate.Response {
+	f.jobs = append(f.jobs, job)
+
+	return scrapemate.Response{StatusCode: http.StatusOK}
+}
+
+func (f *fakeFetcher) Close() error {
+	return nil
+}
+
+func Test_HTTPFetcherFallsBackToBrowser(t *testing.T) {
+	var requests int
+
+	// google answers every request with a 429
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requests++
+
+		return &http.Response{StatusCode: http.StatusTooManyRequests, Body: http.NoBody, Request: r}, nil
+	})}
+
+	browser := &fakeFetcher{}
+	f := gmaps.NewHTTPFetcher(client, gmaps.WithBrowserFallback(browser))
+	ctx := context.Background()
+
+	// searches and extra reviews go to the browser right away
+	search := gmaps.NewGmapJob("", "en", "acme", "owner-1", "", 1, false, false, "", 0)
+	require.NoError(t, f.Fetch(ctx, search).Error)
+
+	reviews := gmaps.NewPlaceJob("", "en", "https://www.google.com/maps/place/acme", "owner-1", "", false, true)
+	require.NoError(t, f.Fetch(ctx, reviews).Error)
+	require.Equal(t, 0, requests)
+
+	// a place blocked over HTTP is loaded in the browser
+	place := gmaps.NewPlaceJob("", "en", "https://www.google.com/maps/place/acme", "owner-1", "", false, false)
+	require.NoError(t, f.Fetch(ctx, place).Error)
+	require.Equal(t, 1, requests)
+
+	// and so are the next places while the HTTP path backs off
+	next := gmaps.NewPlaceJob("", "en", "https://www.google.com/maps/place/other", "owner-1", "", false, false)
+	require.NoError(t, f.Fetch(ctx, next).Error)
+	require.Equal(t, 1, requests)
+
+	require.Equal(t, []scrapemate.IJob{search, reviews, place, next}, browser.jobs)
+
+	// the other jobs fail as blocked
+	email := gmaps.NewEmailJob("", "https://www.google.com/maps/place/acme", "https://www.google.com/acme", "owner-1", "")
+	require.Error(t, f.Fetch(ctx, email).Error)
+	require.Len(t, browser.jobs, 4)
+}
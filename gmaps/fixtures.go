@@ -0,0 +1,157 @@
+package gmaps
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gosom/scrapemate"
+)
+
+// ErrFixtureNotFound is the error of the jobs replaying a page that was
+// never recorded.
+var ErrFixtureNotFound = errors.New("no recorded fixture")
+
+// Fixtures keeps the pages search and place jobs load in Dir, one JSON file
+// for each URL, so that they can be replayed without a browser: parser
+// changes are then checked against the same pages every time. When Record
+// is set the jobs browse and save what they loaded, otherwise they only
+// replay, and fail with ErrFixtureNotFound on the pages never recorded.
+type Fixtures struct {
+	Dir    string
+	Record bool
+}
+
+type FixturesKey struct{}
+
+func GetFixturesFromContext(ctx context.Context) *Fixtures {
+	if f, ok := ctx.Value(FixturesKey{}).(*Fixtures); ok {
+		return f
+	}
+
+	return nil
+}
+
+// fixture is what a job loaded from a page: the HTML of search pages, the
+// place data and the pages of extra reviews of place pages.
+type fixture struct {
+	URL         string   `json:"url"`
+	FinalURL    string   `json:"final_url"`
+	StatusCode  int      `json:"status_code"`
+	HTML        string   `json:"html,omitempty"`
+	Data        string   `json:"data,omitempty"`
+	ReviewPages []string `json:"review_pages,omitempty"`
+}
+
+// Path returns the file of the fixture of u.
+func (f *Fixtures) Path(u string) string {
+	sum := sha256.Sum256([]byte(u))
+
+	return filepath.Join(f.Dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// Save stores resp as the fixture of u.
+func (f *Fixtures) Save(u string, resp *scrapemate.Response) error {
+	fx := fixture{
+		URL:        u,
+		FinalURL:   resp.URL,
+		StatusCode: resp.StatusCode,
+		HTML:       string(resp.Body),
+	}
+
+	if raw, ok := resp.Meta["json"].([]byte); ok {
+		fx.Data = string(raw)
+	}
+
+	if reviews, ok := resp.Meta["reviews_raw"].(fetchReviewsResponse); ok {
+		for _, page := range reviews.pages {
+			fx.ReviewPages = append(fx.ReviewPages, string(page))
+		}
+	}
+
+	data, err := json.MarshalIndent(fx, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(f.Dir, 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(f.Path(u), data, 0o600)
+}
+
+// Load returns the response recorded for u.
+func (f *Fixtures) Load(u string) (scrapemate.Response, error) {
+	var resp scrapemate.Response
+
+	data, err := os.ReadFile(f.Path(u))
+	if errors.Is(err, os.ErrNotExist) {
+		return resp, fmt.Errorf("%w for %s", ErrFixtureNotFound, u)
+	}
+
+	if err != nil {
+		return resp, err
+	}
+
+	var fx fixture
+	if err := json.Unmarshal(data, &fx); err != nil {
+		return resp, fmt.Errorf("fixture of %s: %w", u, err)
+	}
+
+	resp.URL = fx.FinalURL
+	resp.StatusCode = fx.StatusCode
+
+	if resp.StatusCode == 0 {
+		resp.StatusCode = http.StatusOK
+	}
+
+	if fx.HTML != "" {
+		resp.Body = []byte(fx.HTML)
+	}
+
+	if fx.Data != "" {
+		resp.Meta = map[string]any{"json": []byte(fx.Data)}
+
+		if len(fx.ReviewPages) > 0 {
+			var reviews fetchReviewsResponse
+			for _, page := range fx.ReviewPages {
+				reviews.pages = append(reviews.pages, []byte(page))
+			}
+
+			resp.Meta["reviews_raw"] = reviews
+		}
+	}
+
+	return resp, nil
+}
+
+// browse replays the page of u, or when recording loads it with
+// browserActions and saves it.
+func (f *Fixtures) browse(ctx context.Context, u string, browserActions func() scrapemate.Response) scrapemate.Response {
+	if !f.Record {
+		resp, err := f.Load(u)
+		if err != nil {
+			resp.Error = err
+		}
+
+		return resp
+	}
+
+	resp := browserActions()
+
+	if resp.Error == nil {
+		if err := f.Save(u, &resp); err != nil {
+			log := scrapemate.GetLoggerFromContext(ctx)
+			log.Error(fmt.Sprintf("could not record fixture of %s: %v", u, err))
+		}
+	}
+
+	return resp
+}
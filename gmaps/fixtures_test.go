@@ -0,0 +1,43 @@
+package gmaps_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/gosom/scrapemate"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_PlaceJobFixtures(t *testing.T) {
+	raw, err := os.ReadFile("../testdata/raw.json")
+	require.NoError(t, err)
+
+	fixtures := &gmaps.Fixtures{Dir: t.TempDir()}
+	ctx := context.WithValue(context.Background(), gmaps.FixturesKey{}, fixtures)
+
+	job := gmaps.NewPlaceJob("parent", "en", "https://www.google.com/maps/place/Kipriakon", "owner-1", "org-1", false, false)
+
+	// a page never recorded is not browsed
+	resp := job.BrowserActions(ctx, nil)
+	require.ErrorIs(t, resp.Error, gmaps.ErrFixtureNotFound)
+
+	require.NoError(t, fixtures.Save(job.GetFullURL(), &scrapemate.Response{
+		URL:        job.GetURL(),
+		StatusCode: 200,
+		Meta:       map[string]any{"json": raw},
+	}))
+
+	resp = job.BrowserActions(ctx, nil)
+	require.NoError(t, resp.Error)
+
+	data, _, err := job.Process(ctx, &resp)
+	require.NoError(t, err)
+
+	entry, ok := data.(*gmaps.Entry)
+	require.True(t, ok)
+	require.Equal(t, "parent", entry.ID)
+	require.NotEmpty(t, entry.Title)
+}
@@ -2,6 +2,7 @@ package gmaps
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -17,20 +18,41 @@ import (
 	"github.com/gosom/google-maps-scraper/exiter"
 )
 
+var errSearchNeedsBrowser = errors.New("search jobs need a browser to scroll their results")
+
 type GmapJobOptions func(*GmapJob)
 
 type GmapJob struct {
 	scrapemate.Job
 
-	OwnerID             string
-	OrganizationID      string
-	MaxDepth            int
-	LangCode            string
+	OwnerID        string
+	OrganizationID string
+	MaxDepth       int
+	LangCode       string
+	Country        string
+	// Domain is the regional Google domain of the search, e.g. "google.de";
+	// empty for google.com.
+	Domain              string
+	GeoCoordinates      string
+	Zoom                int
 	ExtractEmail        bool
 	ExtractBodacc       bool
 	Deduper             deduper.Deduper
 	ExitMonitor         exiter.Exiter
 	ExtractExtraReviews bool
+	// MaxResults stops the search once it collected this many unique
+	// places, before MaxDepth scrolls when it comes first; 0 means no limit.
+	MaxResults int
+	// RelaxEmpty queues a less specific search when this one lists no
+	// places; Relaxations are the strategies that made this search.
+	RelaxEmpty  bool
+	Relaxations []string
+	// Track is the business whose rank the search checks instead of
+	// scraping the places it lists, see WithTrack.
+	Track string
+	// Audit is the company whose listing the search looks for, see
+	// WithAudit.
+	Audit *Audit
 }
 
 func NewGmapJob(
@@ -53,13 +75,7 @@ func NewGmapJob(
 		id = uuid.New().String()
 	}
 
-	mapURL := ""
-	if geoCoordinates != "" && zoom > 0 {
-		mapURL = fmt.Sprintf("https://www.google.com/maps/search/%s/@%s,%dz", query, strings.ReplaceAll(geoCoordinates, " ", ""), zoom)
-	} else {
-		// Warning: geo and zoom MUST be both set or not
-		mapURL = fmt.Sprintf("https://www.google.com/maps/search/%s", query)
-	}
+	mapURL := SearchURLWithGeo(fmt.Sprintf("https://www.google.com/maps/search/%s", query), geoCoordinates, zoom)
 
 	job := GmapJob{
 		Job: scrapemate.Job{
@@ -78,6 +94,11 @@ func NewGmapJob(
 		OrganizationID: organizationID,
 	}
 
+	if geoCoordinates != "" && zoom > 0 {
+		job.GeoCoordinates = strings.ReplaceAll(geoCoordinates, " ", "")
+		job.Zoom = zoom
+	}
+
 	for _, opt := range opts {
 		opt(&job)
 	}
@@ -85,6 +106,56 @@ func NewGmapJob(
 	return &job
 }
 
+// SearchURLWithGeo centers a search URL on geoCoordinates ("lat,lon") at the
+// given zoom, replacing any position already in the URL. Geo and zoom must be
+// both set, otherwise the URL is returned without a position.
+func SearchURLWithGeo(mapURL, geoCoordinates string, zoom int) string {
+	if i := strings.Index(mapURL, "/@"); i != -1 {
+		mapURL = mapURL[:i]
+	}
+
+	if geoCoordinates == "" || zoom <= 0 {
+		return mapURL
+	}
+
+	return fmt.Sprintf("%s/@%s,%dz", strings.TrimSuffix(mapURL, "/"), strings.ReplaceAll(geoCoordinates, " ", ""), zoom)
+}
+
+// SearchQuery recovers the query from a search URL built by NewGmapJob. Other
+// URLs are returned unchanged.
+func SearchQuery(mapURL string) string {
+	const prefix = "/maps/search/"
+
+	i := strings.Index(mapURL, prefix)
+	if i == -1 {
+		return mapURL
+	}
+
+	q := mapURL[i+len(prefix):]
+	if j := strings.Index(q, "/"); j != -1 {
+		q = q[:j]
+	}
+
+	if unescaped, err := url.QueryUnescape(q); err == nil {
+		return unescaped
+	}
+
+	return q
+}
+
+// WithCountry sets the country (gl parameter) Google uses to rank results,
+// e.g. "fr", "be" or "ch". Place jobs found by the search inherit it.
+func WithCountry(gl string) GmapJobOptions {
+	return func(j *GmapJob) {
+		if gl == "" {
+			return
+		}
+
+		j.Country = strings.ToLower(gl)
+		j.URLParams["gl"] = j.Country
+	}
+}
+
 func WithDeduper(d deduper.Deduper) GmapJobOptions {
 	return func(j *GmapJob) {
 		j.Deduper = d
@@ -103,6 +174,22 @@ func WithExtraReviews() GmapJobOptions {
 	}
 }
 
+// WithRelaxEmpty makes a search listing no places queue a relaxed one, see
+// RelaxQuery and RelaxWidenGeo.
+func WithRelaxEmpty() GmapJobOptions {
+	return func(j *GmapJob) {
+		j.RelaxEmpty = true
+	}
+}
+
+// WithMaxResults stops the search once n unique places are collected; 0
+// keeps every place found within MaxDepth scrolls.
+func WithMaxResults(n int) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.MaxResults = n
+	}
+}
+
 func (j *GmapJob) UseInResults() bool {
 	return false
 }
@@ -120,7 +207,27 @@ func (j *GmapJob) Process(ctx context.Context, resp *scrapemate.Response) (any,
 		return nil, nil, fmt.Errorf("could not convert to goquery document")
 	}
 
-	var next []scrapemate.IJob
+	if j.Audit != nil {
+		data, next := j.audit(doc, resp.URL)
+		if len(next) == 0 {
+			log.Info(fmt.Sprintf("%s (%s) is not listed", j.Audit.Name, j.Audit.Siren))
+		}
+
+		return data, next, nil
+	}
+
+	if j.Track != "" {
+		ranking := j.rank(doc, resp.URL)
+
+		log.Info(fmt.Sprintf("%q ranked %d of %d places", j.Track, ranking.Position, ranking.Listed))
+
+		return ranking, nil, nil
+	}
+
+	var (
+		next  []scrapemate.IJob
+		found int
+	)
 
 	if strings.Contains(resp.URL, "/maps/place/") {
 		jopts := []PlaceJobOptions{}
@@ -130,13 +237,22 @@ func (j *GmapJob) Process(ctx context.Context, resp *scrapemate.Response) (any,
 		if j.ExtractBodacc {
 			jopts = append(jopts, WithBodaccExtraction())
 		}
+		if j.Country != "" {
+			jopts = append(jopts, WithPlaceJobCountry(j.Country))
+		}
 
 		placeJob := NewPlaceJob(j.ID, j.LangCode, resp.URL, j.OwnerID, j.OrganizationID, j.ExtractEmail, j.ExtractExtraReviews, jopts...)
 
 		next = append(next, placeJob)
 	} else {
-		doc.Find(`div[role=feed] div[jsaction]>a`).Each(func(_ int, s *goquery.Selection) {
+		doc.Find(feedResultSelector).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			if j.MaxResults > 0 && len(next) >= j.MaxResults {
+				return false
+			}
+
 			if href := s.AttrOr("href", ""); href != "" {
+				found++
+
 				jopts := []PlaceJobOptions{}
 				if j.ExitMonitor != nil {
 					jopts = append(jopts, WithPlaceJobExitMonitor(j.ExitMonitor))
@@ -144,6 +260,9 @@ func (j *GmapJob) Process(ctx context.Context, resp *scrapemate.Response) (any,
 				if j.ExtractBodacc {
 					jopts = append(jopts, WithBodaccExtraction())
 				}
+				if j.Country != "" {
+					jopts = append(jopts, WithPlaceJobCountry(j.Country))
+				}
 
 				nextJob := NewPlaceJob(j.ID, j.LangCode, href, j.OwnerID, j.OrganizationID, j.ExtractEmail, j.ExtractExtraReviews, jopts...)
 
@@ -151,9 +270,27 @@ func (j *GmapJob) Process(ctx context.Context, resp *scrapemate.Response) (any,
 					next = append(next, nextJob)
 				}
 			}
+
+			return true
 		})
 	}
 
+	if found == 0 && !strings.Contains(resp.URL, "/maps/place/") {
+		kind := ClassifyEmptySearch(doc, resp.URL)
+		countEmptySearch(string(kind))
+
+		log.Info(fmt.Sprintf("no places found: %s", kind))
+
+		if kind != EmptyBlocked && j.RelaxEmpty {
+			if relaxed := j.relax(); relaxed != nil {
+				log.Info(fmt.Sprintf("retrying as %q with %s", SearchQuery(relaxed.GetURL()), strings.Join(relaxed.Relaxations, ",")))
+
+				// the relaxed search completes the seed
+				return nil, []scrapemate.IJob{relaxed}, nil
+			}
+		}
+	}
+
 	if j.ExitMonitor != nil {
 		j.ExitMonitor.IncrPlacesFound(len(next))
 		j.ExitMonitor.IncrSeedCompleted(1)
@@ -165,8 +302,29 @@ func (j *GmapJob) Process(ctx context.Context, resp *scrapemate.Response) (any,
 }
 
 func (j *GmapJob) BrowserActions(ctx context.Context, page playwright.Page) scrapemate.Response {
+	if f := GetFixturesFromContext(ctx); f != nil {
+		return f.browse(ctx, j.GetFullURL(), func() scrapemate.Response {
+			return j.browserActions(ctx, page)
+		})
+	}
+
+	return j.browserActions(ctx, page)
+}
+
+// FetchWithoutBrowser fails: the search lists its places as the page
+// scrolls. Fast mode seeds a SearchJob instead, and hands the searches queued
+// by a worker with a browser to its browser fallback.
+func (j *GmapJob) FetchWithoutBrowser(context.Context) scrapemate.Response {
+	return scrapemate.Response{Error: errSearchNeedsBrowser}
+}
+
+func (j *GmapJob) browserActions(ctx context.Context, page playwright.Page) scrapemate.Response {
 	var resp scrapemate.Response
 
+	defer blockResources(ctx, page)()
+
+	acceptConsent(page, j.GetURL())
+
 	pageResponse, err := page.Goto(j.GetFullURL(), playwright.PageGotoOptions{
 		WaitUntil: playwright.WaitUntilStateDomcontentloaded,
 	})
@@ -178,6 +336,10 @@ func (j *GmapJob) BrowserActions(ctx context.Context, page playwright.Page) scra
 
 	clickRejectCookiesIfRequired(page)
 
+	if markBlocked(ctx, page, &resp) {
+		return resp
+	}
+
 	const defaultTimeout = 5000
 
 	err = page.WaitForURL(page.URL(), playwright.PageWaitForURLOptions{
@@ -236,7 +398,21 @@ func (j *GmapJob) BrowserActions(ctx context.Context, page playwright.Page) scra
 
 	scrollSelector := `div[role='feed']`
 
-	_, err = scroll(ctx, page, j.MaxDepth, scrollSelector)
+	// without a feed Google found nothing; Process tells why from the page
+	if count, err := page.Locator(scrollSelector).Count(); err == nil && count == 0 {
+		body, err := page.Content()
+		if err != nil {
+			resp.Error = err
+			return resp
+		}
+
+		resp.URL = page.URL()
+		resp.Body = []byte(body)
+
+		return resp
+	}
+
+	_, err = scroll(ctx, page, j.MaxDepth, j.MaxResults, scrollSelector)
 	if err != nil {
 		resp.Error = err
 
@@ -270,8 +446,11 @@ func waitUntilURLContains(ctx context.Context, page playwright.Page, s string) b
 	}
 }
 
+// clickRejectCookiesIfRequired answers "reject all" to the consent form,
+// whichever consent.google.<tld> domain serves it. The form has an input or a
+// button depending on the region, the rejection coming first.
 func clickRejectCookiesIfRequired(page playwright.Page) {
-	sel := `form[action="https://consent.google.com/save"] input[type="submit"]`
+	sel := `form[action^="https://consent.google."] :is(input[type="submit"], button)`
 
 	locator := page.Locator(sel)
 
@@ -289,9 +468,30 @@ func clickRejectCookiesIfRequired(page playwright.Page) {
 	})
 }
 
+// feedResultSelector matches the links to the places of a search feed.
+const feedResultSelector = `div[role=feed] div[jsaction]>a`
+
+// countFeedResults counts the distinct places listed in the search feed.
+func countFeedResults(page playwright.Page) (int, error) {
+	v, err := page.Evaluate(`() => new Set(Array.from(document.querySelectorAll("` + feedResultSelector + `")).map(a => a.href)).size`)
+	if err != nil {
+		return 0, err
+	}
+
+	n, ok := v.(int)
+	if !ok {
+		return 0, fmt.Errorf("result count is not an int")
+	}
+
+	return n, nil
+}
+
+// scroll scrolls the feed at most maxDepth times, stopping early when it
+// stops growing or, with maxResults set, lists that many places.
 func scroll(ctx context.Context,
 	page playwright.Page,
 	maxDepth int,
+	maxResults int,
 	scrollSelector string,
 ) (int, error) {
 	expr := `async () => {
@@ -338,6 +538,12 @@ func scroll(ctx context.Context,
 			break
 		}
 
+		if maxResults > 0 {
+			if n, err := countFeedResults(page); err == nil && n >= maxResults {
+				break
+			}
+		}
+
 		currentScrollHeight = height
 
 		select {
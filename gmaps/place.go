@@ -11,20 +11,70 @@ import (
 	"github.com/gosom/scrapemate"
 	"github.com/playwright-community/playwright-go"
 
+	"github.com/gosom/google-maps-scraper/entreprise"
 	"github.com/gosom/google-maps-scraper/exiter"
 )
 
+// ReverseGeocoder resolves coordinates to the French commune they are in.
+type ReverseGeocoder interface {
+	Reverse(ctx context.Context, lat, lon float64) (*entreprise.BANLocation, error)
+}
+
+type ReverseGeocoderKey struct{}
+
+func GetReverseGeocoderFromContext(ctx context.Context) ReverseGeocoder {
+	if g, ok := ctx.Value(ReverseGeocoderKey{}).(ReverseGeocoder); ok {
+		return g
+	}
+	return nil
+}
+
+// CategoryMapper maps the localized categories of places to canonical
+// category ids, "" when it does not know the label.
+type CategoryMapper interface {
+	Map(lang, label string) string
+}
+
+type CategoryMapperKey struct{}
+
+func GetCategoryMapperFromContext(ctx context.Context) CategoryMapper {
+	if m, ok := ctx.Value(CategoryMapperKey{}).(CategoryMapper); ok {
+		return m
+	}
+	return nil
+}
+
+// frenchCountries are the country codes Google gives places covered by the
+// Base Adresse Nationale: France and its overseas departments.
+var frenchCountries = map[string]bool{
+	"FR": true, "GP": true, "MQ": true, "GF": true, "RE": true, "YT": true,
+}
+
+// CachedMetaKey is set in the response meta of place jobs whose data came
+// from UseCachedData.
+const CachedMetaKey = "cached"
+
 type PlaceJobOptions func(*PlaceJob)
 
 type PlaceJob struct {
 	scrapemate.Job
 	OwnerID             string
 	OrganizationID      string
+	Country             string
 	ExtractEmail        bool
 	ExtractBodacc       bool
 	ExitMonitor         exiter.Exiter
 	ExtractExtraReviews bool
-	EnrichmentJobs      []scrapemate.IJob `json:"-"`
+	// Refresh updates the results the owner has of the place, see
+	// WithRefresh.
+	Refresh bool
+	// Audit is the company whose listing the place is, see
+	// WithPlaceJobAudit.
+	Audit          *Audit
+	EnrichmentJobs []scrapemate.IJob `json:"-"`
+
+	// cached is the data of the place scraped recently, see UseCachedData.
+	cached []byte
 }
 
 func NewPlaceJob(parentID, langCode, u, ownerID, organizationID string, extractEmail, extraExtraReviews bool, opts ...PlaceJobOptions) *PlaceJob {
@@ -62,13 +112,25 @@ func WithPlaceJobExitMonitor(exitMonitor exiter.Exiter) PlaceJobOptions {
 	}
 }
 
+// WithPlaceJobCountry sets the country (gl parameter) of the place page.
+func WithPlaceJobCountry(gl string) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		if gl == "" {
+			return
+		}
+
+		j.Country = strings.ToLower(gl)
+		j.URLParams["gl"] = j.Country
+	}
+}
+
 func WithBodaccExtraction() PlaceJobOptions {
 	return func(j *PlaceJob) {
 		j.ExtractBodacc = true
 	}
 }
 
-func (j *PlaceJob) Process(_ context.Context, resp *scrapemate.Response) (any, []scrapemate.IJob, error) {
+func (j *PlaceJob) Process(ctx context.Context, resp *scrapemate.Response) (any, []scrapemate.IJob, error) {
 	defer func() {
 		resp.Document = nil
 		resp.Body = nil
@@ -91,9 +153,23 @@ func (j *PlaceJob) Process(_ context.Context, resp *scrapemate.Response) (any, [
 		entry.Link = j.GetURL()
 	}
 
+	markServiceArea(&entry)
+
+	// the coordinates and locality of service-area businesses are not
+	// their address, enriching from them would only yield mismatches
+	skipAddress := entry.ServiceAreaBusiness && skipServiceAreaEnrichment(ctx)
+
+	if !skipAddress {
+		j.locate(ctx, &entry)
+	}
+
+	setDepartment(&entry)
+	j.categorize(ctx, &entry)
+
 	allReviewsRaw, ok := resp.Meta["reviews_raw"].(fetchReviewsResponse)
 	if ok && len(allReviewsRaw.pages) > 0 {
 		entry.AddExtraReviews(allReviewsRaw.pages)
+		entry.ReviewStats = NewReviewStats(entry.UserReviewsExtended)
 	}
 
 	var childJobs []scrapemate.IJob
@@ -109,8 +185,12 @@ func (j *PlaceJob) Process(_ context.Context, resp *scrapemate.Response) (any, [
 		childJobs = append(childJobs, emailJob)
 	}
 
+	// places whose website is their social page get its data, if the worker
+	// fetches social profiles
+	childJobs = append(childJobs, socialJobs([]string{entry.WebSite}, j.ID, entry.Link, j.OwnerID, j.OrganizationID, j.ExitMonitor)...)
+
 	// Create BODACC job if enabled and we have company information
-	if j.ExtractBodacc && entry.Title != "" && entry.Address != "" {
+	if j.ExtractBodacc && !skipAddress && entry.Title != "" && entry.Address != "" {
 		CompanyJob := NewCompanyJob(
 			entry.Title,
 			entry.Address,
@@ -131,13 +211,35 @@ func (j *PlaceJob) Process(_ context.Context, resp *scrapemate.Response) (any, [
 		j.ExitMonitor.IncrPlacesCompleted(1)
 	}
 
+	if j.Audit != nil {
+		return j.auditResult(&entry), nil, nil
+	}
+
 	return &entry, nil, nil
 }
 
 func (j *PlaceJob) BrowserActions(ctx context.Context, page playwright.Page) scrapemate.Response {
+	if f := GetFixturesFromContext(ctx); f != nil {
+		return f.browse(ctx, j.GetFullURL(), func() scrapemate.Response {
+			return j.browserActions(ctx, page)
+		})
+	}
+
+	return j.browserActions(ctx, page)
+}
+
+func (j *PlaceJob) browserActions(ctx context.Context, page playwright.Page) scrapemate.Response {
+	if resp, ok := j.CachedResponse(); ok {
+		return resp
+	}
+
 	var resp scrapemate.Response
 
-	pageResponse, err := page.Goto(j.GetURL(), playwright.PageGotoOptions{
+	defer blockResources(ctx, page)()
+
+	acceptConsent(page, j.GetURL())
+
+	pageResponse, err := page.Goto(j.GetFullURL(), playwright.PageGotoOptions{
 		WaitUntil: playwright.WaitUntilStateDomcontentloaded,
 	})
 	if err != nil {
@@ -148,6 +250,10 @@ func (j *PlaceJob) BrowserActions(ctx context.Context, page playwright.Page) scr
 
 	clickRejectCookiesIfRequired(page)
 
+	if markBlocked(ctx, page, &resp) {
+		return resp
+	}
+
 	const defaultTimeout = 5000
 
 	err = page.WaitForURL(page.URL(), playwright.PageWaitForURLOptions{
@@ -204,6 +310,136 @@ func (j *PlaceJob) BrowserActions(ctx context.Context, page playwright.Page) scr
 	return resp
 }
 
+// locate sets the commune codes of French entries with the ReverseGeocoder
+// of ctx. They stay empty when it fails.
+func (j *PlaceJob) locate(ctx context.Context, entry *Entry) {
+	g := GetReverseGeocoderFromContext(ctx)
+	if g == nil || !frenchCountries[strings.ToUpper(entry.CompleteAddress.Country)] {
+		return
+	}
+
+	if entry.Latitude == 0 && entry.Longtitude == 0 {
+		return
+	}
+
+	loc, err := g.Reverse(ctx, entry.Latitude, entry.Longtitude)
+	if err != nil {
+		log := scrapemate.GetLoggerFromContext(ctx)
+		log.Info(fmt.Sprintf("reverse geocoding failed for %s: %v", entry.Link, err))
+
+		return
+	}
+
+	if loc != nil {
+		entry.CodeCommune = loc.CityCode
+		entry.CodePostal = loc.PostCode
+	}
+}
+
+// categorize sets the category id of entry from its main category, or the
+// first of its other categories the CategoryMapper of ctx knows.
+func (j *PlaceJob) categorize(ctx context.Context, entry *Entry) {
+	m := GetCategoryMapperFromContext(ctx)
+	if m == nil {
+		return
+	}
+
+	lang := j.URLParams["hl"]
+
+	for _, label := range append([]string{entry.Category}, entry.Categories...) {
+		if id := m.Map(lang, label); id != "" {
+			entry.CategoryID = id
+			return
+		}
+	}
+}
+
+// setDepartment sets the department and region of French entries from their
+// commune code, or their postal code when the commune is unknown.
+func setDepartment(entry *Entry) {
+	d, ok := entreprise.DepartmentFromCommune(entry.CodeCommune)
+	if !ok {
+		if !frenchCountries[strings.ToUpper(entry.CompleteAddress.Country)] {
+			return
+		}
+
+		postalCode := entry.CodePostal
+		if postalCode == "" {
+			postalCode = entry.CompleteAddress.PostalCode
+		}
+
+		if d, ok = entreprise.DepartmentFromPostalCode(postalCode); !ok {
+			return
+		}
+	}
+
+	entry.DepartmentCode = d.Code
+	entry.DepartmentName = d.Name
+	entry.Region = d.Region
+}
+
+// UseCachedData makes the job use raw, the data of its place scraped
+// recently, instead of loading the place page. Extra reviews are not kept
+// with the data, so jobs collecting them ignore it.
+func (j *PlaceJob) UseCachedData(raw []byte) {
+	if !j.ExtractExtraReviews {
+		j.cached = raw
+	}
+}
+
+// CachedResponse returns the response of a job given cached data, which
+// needs no page.
+func (j *PlaceJob) CachedResponse() (scrapemate.Response, bool) {
+	if j.cached == nil {
+		return scrapemate.Response{}, false
+	}
+
+	return scrapemate.Response{
+		URL:        j.GetURL(),
+		StatusCode: http.StatusOK,
+		Meta:       map[string]any{"json": j.cached, CachedMetaKey: true},
+	}, true
+}
+
+// FetchWithoutBrowser loads the data of the place over HTTP, in fast mode.
+// Extra reviews are paginated through the page and are not collected: the
+// fetcher of fast mode hands those jobs to its browser fallback.
+func (j *PlaceJob) FetchWithoutBrowser(ctx context.Context) scrapemate.Response {
+	if resp, ok := j.CachedResponse(); ok {
+		return resp
+	}
+
+	if f := GetFixturesFromContext(ctx); f != nil {
+		return f.browse(ctx, j.GetFullURL(), func() scrapemate.Response {
+			return j.fetchWithoutBrowser(ctx)
+		})
+	}
+
+	return j.fetchWithoutBrowser(ctx)
+}
+
+func (j *PlaceJob) fetchWithoutBrowser(ctx context.Context) scrapemate.Response {
+	client := GetPlaceHTTPClientFromContext(ctx)
+	if client == nil {
+		return scrapemate.Response{Error: errNoHTTPClient}
+	}
+
+	raw, err := fetchPlaceJSON(withProxySession(ctx, j.GetID()), client, j.GetFullURL())
+	if err == nil {
+		_, err = EntryFromJSON(raw, true)
+	}
+
+	if err != nil {
+		return scrapemate.Response{Error: err}
+	}
+
+	return scrapemate.Response{
+		URL:        j.GetURL(),
+		StatusCode: http.StatusOK,
+		Meta:       map[string]any{"json": raw},
+	}
+}
+
 func (j *PlaceJob) extractJSON(page playwright.Page) ([]byte, error) {
 	var (
 		rawI any
@@ -0,0 +1,35 @@
+package gmaps_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_PlaceJobCachedData(t *testing.T) {
+	raw, err := os.ReadFile("../testdata/raw.json")
+	require.NoError(t, err)
+
+	job := gmaps.NewPlaceJob("parent", "en", "https://www.google.com/maps/place/Kipriakon", "owner-2", "org-2", false, false)
+
+	job.UseCachedData(raw)
+
+	ctx := context.Background()
+
+	// a cached place never touches the page
+	resp := job.BrowserActions(ctx, nil)
+	require.NoError(t, resp.Error)
+	require.Equal(t, true, resp.Meta[gmaps.CachedMetaKey])
+
+	data, _, err := job.Process(ctx, &resp)
+	require.NoError(t, err)
+
+	entry, ok := data.(*gmaps.Entry)
+	require.True(t, ok)
+	require.Equal(t, "parent", entry.ID)
+	require.NotEmpty(t, entry.Title)
+}
@@ -0,0 +1,177 @@
+package gmaps
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	placeHTTPTimeout = 20 * time.Second
+	maxPlaceBodySize = 8 << 20
+
+	placeUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/140.0.0.0 Safari/537.36"
+)
+
+// The place page preloads its data from the preview endpoint. The response
+// of that endpoint is what the browser exposes as APP_INITIALIZATION_STATE.
+var previewPlaceRe = regexp.MustCompile(`href="(/maps/preview/place\?[^"]+)"`)
+
+type PlaceHTTPClientKey struct{}
+
+// GetPlaceHTTPClientFromContext returns the client the jobs are loaded with
+// when no browser runs, or nil.
+func GetPlaceHTTPClientFromContext(ctx context.Context) *http.Client {
+	if c, ok := ctx.Value(PlaceHTTPClientKey{}).(*http.Client); ok {
+		return c
+	}
+
+	return nil
+}
+
+// proxySessionKey carries the proxy session of the requests of a job.
+type proxySessionKey struct{}
+
+// withProxySession makes the requests of the fast path made with ctx use the
+// proxy session of the job id.
+func withProxySession(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, proxySessionKey{}, proxySession(id))
+}
+
+// proxySession returns the proxy session of the requests of the job id.
+func proxySession(id string) string {
+	return "job-" + id
+}
+
+// NewPlaceHTTPClient returns a client for the jobs of fast mode. When proxyURL is
+// not empty every request goes through it. A proxy URL without credentials
+// gets the proxy session of the job as its username, so that a sticky proxy
+// pool keeps the requests of a job on one upstream.
+func NewPlaceHTTPClient(proxyURL string) (*http.Client, error) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+
+	if proxyURL != "" {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %w", err)
+		}
+
+		tr.Proxy = func(req *http.Request) (*url.URL, error) {
+			session, _ := req.Context().Value(proxySessionKey{}).(string)
+			if session == "" || u.User != nil {
+				return u, nil
+			}
+
+			c := *u
+			c.User = url.User(session)
+
+			return &c, nil
+		}
+	}
+
+	return &http.Client{
+		Transport: tr,
+		Timeout:   placeHTTPTimeout,
+	}, nil
+}
+
+// fetchPlaceJSON loads the place data without a browser: it downloads the
+// place page, finds the preview URL it preloads and returns that payload.
+func fetchPlaceJSON(ctx context.Context, client *http.Client, placeURL string) ([]byte, error) {
+	page, _, err := fetchPage(ctx, client, placeURL)
+	if err != nil {
+		return nil, err
+	}
+
+	m := previewPlaceRe.FindSubmatch(page)
+	if m == nil {
+		return nil, fmt.Errorf("preview url not found in place page")
+	}
+
+	// the preview is served by the domain of the place, google.de for the
+	// places of a search on google.de
+	host := "www.google.com"
+	if u, err := url.Parse(placeURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	previewURL := "https://" + host + html.UnescapeString(string(m[1]))
+
+	body, _, err := fetchPage(ctx, client, previewURL)
+	if err != nil {
+		return nil, err
+	}
+
+	const prefix = `)]}'`
+
+	body = bytes.TrimSpace(bytes.TrimPrefix(bytes.TrimSpace(body), []byte(prefix)))
+
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("preview response is not valid json")
+	}
+
+	return body, nil
+}
+
+// fetchPage downloads u and returns its body and the URL it was redirected
+// to. Google pages are requested with the consent answer, so that they are
+// not redirected to consent.google.<tld>, and the anti-bot pages they may
+// redirect to fail with a *BlockedError.
+func fetchPage(ctx context.Context, client *http.Client, u string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return nil, "", err
+	}
+
+	req.Header.Set("User-Agent", placeUserAgent)
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+
+	google := isGoogleHost(req.URL.Hostname())
+	if google {
+		req.AddCookie(&http.Cookie{Name: ConsentCookie, Value: ConsentCookieValue})
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	finalURL := resp.Request.URL.String()
+
+	if google {
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests, strings.Contains(finalURL, "/sorry/"):
+			return nil, finalURL, &BlockedError{Kind: BlockSorry, URL: finalURL}
+		case strings.Contains(finalURL, "consent.google."):
+			return nil, finalURL, &BlockedError{Kind: BlockConsentWall, URL: finalURL}
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, finalURL, fmt.Errorf("unexpected status code %d for %s", resp.StatusCode, finalURL)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPlaceBodySize))
+	if err != nil {
+		return nil, finalURL, err
+	}
+
+	return body, finalURL, nil
+}
+
+// isGoogleHost tells whether host is a Google domain, maps.google.com
+// included.
+func isGoogleHost(host string) bool {
+	d, err := ParseDomain(strings.TrimPrefix(host, "maps."))
+
+	return err == nil && d != ""
+}
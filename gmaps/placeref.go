@@ -0,0 +1,64 @@
+package gmaps
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	featureIDOnlyRe = regexp.MustCompile(`^0x[0-9a-fA-F]+:0x[0-9a-fA-F]+$`)
+	placeIDOnlyRe   = regexp.MustCompile(`^ChIJ[A-Za-z0-9_-]+$`)
+	cidRe           = regexp.MustCompile(`^(?:cid:)?([0-9]{10,20})$`)
+)
+
+// PlaceURL returns the URL of the place page ref points to, ref being a
+// Google Maps place URL, a feature id ("0x...:0x..."), a place id
+// ("ChIJ..."), or a CID, bare or as "cid:123...". Ids are looked up on
+// domain, google.com when empty. It returns false for anything else, e.g. a
+// search query.
+func PlaceURL(ref, domain string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+
+	host := "www." + DefaultDomain
+	if d, err := ParseDomain(domain); err == nil && d != "" {
+		host = "www." + d
+	}
+
+	switch {
+	case featureIDOnlyRe.MatchString(ref):
+		return "https://" + host + "/maps/place/data=!4m2!3m1!1s" + ref, true
+	case placeIDOnlyRe.MatchString(ref):
+		return "https://" + host + "/maps/place/?q=place_id:" + ref, true
+	}
+
+	if m := cidRe.FindStringSubmatch(ref); m != nil {
+		return "https://" + host + "/maps?cid=" + m[1], true
+	}
+
+	u, err := url.Parse(ref)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return "", false
+	}
+
+	if d, err := ParseDomain(u.Host); err != nil || d == "" {
+		// maps.google.com/?cid=... links of older exports
+		if u.Host != "maps.google.com" || u.Query().Get("cid") == "" {
+			return "", false
+		}
+	}
+
+	if strings.Contains(u.Path, "/maps/place/") || u.Query().Get("cid") != "" {
+		return ref, true
+	}
+
+	return "", false
+}
+
+// WithRefresh makes the place job update the results the owner already has
+// of the place, instead of skipping a place it saved before.
+func WithRefresh() PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.Refresh = true
+	}
+}
@@ -0,0 +1,19 @@
+package gmaps
+
+import "time"
+
+// SourceStoredResult is the source of the fields copied from a result saved
+// earlier for the same place.
+const SourceStoredResult = "stored result"
+
+// FieldSource tells which source supplied an enriched field and when.
+type FieldSource struct {
+	// Source is INSEE, INPI, GOUV, BODACC, Pappers, website, company cache,
+	// review or SourceStoredResult.
+	Source    string    `json:"source"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// Provenance maps the enriched columns of a result, e.g. societe_siren, to
+// the source that supplied them.
+type Provenance map[string]FieldSource
@@ -0,0 +1,175 @@
+package gmaps
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	// featureIDRe matches the feature id of a place ("0x...:0x..."), in the
+	// data part of its URL or on its own.
+	featureIDRe = regexp.MustCompile(`(?:^|!1s)(0x[0-9a-fA-F]+:0x[0-9a-fA-F]+)`)
+	// googlePlaceIDRe matches the place id of the Places API ("ChIJ...") in
+	// the data part of a place URL.
+	googlePlaceIDRe = regexp.MustCompile(`!19s([A-Za-z0-9_-]+)`)
+)
+
+// Listing is a place as a search lists it.
+type Listing struct {
+	Title string
+	Link  string
+}
+
+// Ranking is where a search listed the business it tracks. Position is 1
+// for the first place of the results and 0 when the business was not listed
+// among the Listed places the search scrolled through.
+type Ranking struct {
+	SearchID       string
+	ParentID       string
+	OwnerID        string
+	OrganizationID string
+	Query          string
+	Target         string
+	Geo            string
+	Zoom           int
+	Domain         string
+	Position       int
+	Title          string
+	Link           string
+	Listed         int
+}
+
+// WithTrack turns the search into a rank check of the business target,
+// given as a feature id ("0x...:0x..."), a place id ("ChIJ..."), a place
+// URL or a name: the search records the position of the business in its
+// results, a *Ranking, instead of scraping the places it lists.
+func WithTrack(target string) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.Track = strings.TrimSpace(target)
+	}
+}
+
+// FindRank returns the index of the first of listings that is the business
+// target, or -1. Ids match the ids in the links; names match the titles
+// that contain them, ignoring case, accents and punctuation, so that
+// "Boulangerie Martin" matches "BOULANGERIE MARTIN - Paris 11e".
+func FindRank(target string, listings []Listing) int {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return -1
+	}
+
+	if m := featureIDRe.FindStringSubmatch(target); m != nil {
+		return slices.IndexFunc(listings, func(l Listing) bool {
+			found := featureIDRe.FindStringSubmatch(l.Link)
+			return found != nil && strings.EqualFold(found[1], m[1])
+		})
+	}
+
+	if strings.HasPrefix(target, "ChIJ") {
+		return slices.IndexFunc(listings, func(l Listing) bool {
+			found := googlePlaceIDRe.FindStringSubmatch(l.Link)
+			return found != nil && found[1] == target
+		})
+	}
+
+	name := normalizeName(target)
+	if name == "" {
+		return -1
+	}
+
+	return slices.IndexFunc(listings, func(l Listing) bool {
+		return strings.Contains(" "+normalizeName(l.Title)+" ", " "+name+" ")
+	})
+}
+
+// feedListings returns the places the search page lists, in order.
+func feedListings(doc *goquery.Document, pageURL string) []Listing {
+	if strings.Contains(pageURL, "/maps/place/") {
+		// a search matching a single place opens it
+		return []Listing{{Title: titleFromPlaceURL(pageURL), Link: pageURL}}
+	}
+
+	var ans []Listing
+
+	seen := map[string]bool{}
+
+	doc.Find(feedResultSelector).Each(func(_ int, s *goquery.Selection) {
+		href := s.AttrOr("href", "")
+		if href == "" || seen[href] {
+			return
+		}
+
+		seen[href] = true
+
+		ans = append(ans, Listing{Title: s.AttrOr("aria-label", ""), Link: href})
+	})
+
+	return ans
+}
+
+// rank returns where the results of the search page list the business it
+// tracks.
+func (j *GmapJob) rank(doc *goquery.Document, pageURL string) *Ranking {
+	listings := feedListings(doc, pageURL)
+
+	ans := &Ranking{
+		SearchID:       j.GetID(),
+		ParentID:       j.ParentID,
+		OwnerID:        j.OwnerID,
+		OrganizationID: j.OrganizationID,
+		Query:          SearchQuery(j.GetURL()),
+		Target:         j.Track,
+		Geo:            j.GeoCoordinates,
+		Zoom:           j.Zoom,
+		Domain:         j.Domain,
+		Listed:         len(listings),
+	}
+
+	if i := FindRank(j.Track, listings); i >= 0 {
+		ans.Position = i + 1
+		ans.Title = listings[i].Title
+		ans.Link = listings[i].Link
+	}
+
+	return ans
+}
+
+// titleFromPlaceURL returns the name a place URL carries after /maps/place/.
+func titleFromPlaceURL(u string) string {
+	_, rest, ok := strings.Cut(u, "/maps/place/")
+	if !ok {
+		return ""
+	}
+
+	name, _, _ := strings.Cut(rest, "/")
+
+	if unescaped, err := url.QueryUnescape(name); err == nil {
+		return unescaped
+	}
+
+	return name
+}
+
+// normalizeName lowers name and strips its accents and punctuation.
+func normalizeName(name string) string {
+	var b strings.Builder
+
+	for _, r := range norm.NFD.String(strings.ToLower(name)) {
+		switch {
+		case unicode.IsMark(r):
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
@@ -0,0 +1,36 @@
+package gmaps_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_FindRank(t *testing.T) {
+	listings := []gmaps.Listing{
+		{Title: "Plomberie Dupont", Link: "https://www.google.com/maps/place/Plomberie+Dupont/data=!4m7!3m6!1s0x47e66e1f06e2b70f:0x40b82c3688c9460!8m2!3d48.8!4d2.3!19sChIJD7fiBh9u5kcRYJSMaMOCCwQ?hl=fr"},
+		{Title: "BOULANGERIE MARTIN - Paris 11e", Link: "https://www.google.com/maps/place/Boulangerie+Martin/data=!4m7!3m6!1s0x47e671f8f1a7b2a3:0x5bd4a1c5e6f7a8b9!8m2!3d48.8!4d2.3!19sChIJo7Kn8fhx5kcRuaj35sWh1Fs?hl=fr"},
+		{Title: "Martinez Électricité", Link: "https://www.google.com/maps/place/Martinez/data=!4m7!3m6!1s0x47e66d0000000001:0x1!8m2!3d48.8!4d2.3?hl=fr"},
+	}
+
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{target: "0x47e671f8f1a7b2a3:0x5bd4a1c5e6f7a8b9", want: 1},
+		{target: "ChIJD7fiBh9u5kcRYJSMaMOCCwQ", want: 0},
+		{target: listings[2].Link, want: 2},
+		{target: "boulangerie martin", want: 1},
+		{target: "martinez electricite", want: 2},
+		{target: "Martin", want: 1},
+		{target: "Serrurerie Durand", want: -1},
+		{target: "ChIJunknown", want: -1},
+		{target: "  ", want: -1},
+	}
+
+	for _, tc := range tests {
+		require.Equal(t, tc.want, gmaps.FindRank(tc.target, listings), tc.target)
+	}
+}
@@ -0,0 +1,102 @@
+package gmaps
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// DefaultBlockedResourceTypes are never needed to extract place data.
+var DefaultBlockedResourceTypes = []string{"image", "media", "font"}
+
+// DefaultBlockedDomains are analytics and ad hosts loaded by Google Maps.
+var DefaultBlockedDomains = []string{
+	"google-analytics.com",
+	"googletagmanager.com",
+	"doubleclick.net",
+	"googlesyndication.com",
+	"googleadservices.com",
+	"adservice.google.com",
+}
+
+// ResourceBlocking lists the requests search and place jobs abort while the
+// page is loading. Resource types are playwright's (image, font, media, ...).
+type ResourceBlocking struct {
+	ResourceTypes []string
+	Domains       []string
+}
+
+type ResourceBlockingKey struct{}
+
+func GetResourceBlockingFromContext(ctx context.Context) *ResourceBlocking {
+	if rb, ok := ctx.Value(ResourceBlockingKey{}).(*ResourceBlocking); ok {
+		return rb
+	}
+
+	return nil
+}
+
+var abortedRequests atomic.Int64
+
+// AbortedRequests returns how many requests were blocked since startup.
+func AbortedRequests() int64 {
+	return abortedRequests.Load()
+}
+
+func (rb *ResourceBlocking) blocks(req playwright.Request) bool {
+	if slices.Contains(rb.ResourceTypes, req.ResourceType()) {
+		return true
+	}
+
+	if len(rb.Domains) == 0 {
+		return false
+	}
+
+	u, err := url.Parse(req.URL())
+	if err != nil {
+		return false
+	}
+
+	host := u.Hostname()
+
+	for _, d := range rb.Domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// blockResources intercepts the page requests according to the resource
+// blocking configured in ctx. The returned function removes the interception,
+// since pages are reused by other jobs.
+func blockResources(ctx context.Context, page playwright.Page) func() {
+	rb := GetResourceBlockingFromContext(ctx)
+	if rb == nil || (len(rb.ResourceTypes) == 0 && len(rb.Domains) == 0) {
+		return func() {}
+	}
+
+	handler := func(route playwright.Route) {
+		if rb.blocks(route.Request()) {
+			abortedRequests.Add(1)
+			_ = route.Abort("blockedbyclient")
+
+			return
+		}
+
+		_ = route.Continue()
+	}
+
+	if err := page.Route("**/*", handler); err != nil {
+		return func() {}
+	}
+
+	return func() {
+		_ = page.Unroute("**/*", handler)
+	}
+}
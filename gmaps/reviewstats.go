@@ -0,0 +1,84 @@
+package gmaps
+
+import (
+	"math"
+	"time"
+)
+
+// ReviewStats sums up the reviews of a place and how its owner answers
+// them. The sentiment of a review comes from its rating: 4 and 5 stars are
+// positive, 3 neutral, 1 and 2 negative.
+type ReviewStats struct {
+	Reviews   int `json:"reviews"`
+	Responses int `json:"responses"`
+	// ResponseRate is the share of the reviews the owner answered, from 0
+	// to 1.
+	ResponseRate float64 `json:"response_rate"`
+	// ResponseDelayHours is the average time the owner took to answer, 0
+	// when the dates are unknown.
+	ResponseDelayHours float64 `json:"response_delay_hours"`
+	Positive           int     `json:"positive"`
+	Neutral            int     `json:"neutral"`
+	Negative           int     `json:"negative"`
+}
+
+// NewReviewStats computes the stats of reviews, nil when there are none.
+func NewReviewStats(reviews []Review) *ReviewStats {
+	if len(reviews) == 0 {
+		return nil
+	}
+
+	var (
+		stats  ReviewStats
+		delays time.Duration
+		timed  int
+	)
+
+	for i := range reviews {
+		r := &reviews[i]
+
+		stats.Reviews++
+
+		switch {
+		case r.Rating >= 4:
+			stats.Positive++
+		case r.Rating == 3:
+			stats.Neutral++
+		case r.Rating > 0:
+			stats.Negative++
+		}
+
+		if r.OwnerResponse == "" {
+			continue
+		}
+
+		stats.Responses++
+
+		if !r.published.IsZero() && r.respondedAt.After(r.published) {
+			delays += r.respondedAt.Sub(r.published)
+			timed++
+		}
+	}
+
+	stats.ResponseRate = round2(float64(stats.Responses) / float64(stats.Reviews))
+
+	if timed > 0 {
+		stats.ResponseDelayHours = round2((delays / time.Duration(timed)).Hours())
+	}
+
+	return &stats
+}
+
+// microsToTime converts the microsecond timestamps of the review data, the
+// zero time when ts is not set.
+func microsToTime(ts float64) time.Time {
+	if ts <= 0 {
+		return time.Time{}
+	}
+
+	return time.UnixMicro(int64(ts)).UTC()
+}
+
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
@@ -28,9 +28,18 @@ type MapSearchParams struct {
 	Hl        string
 }
 
+// SearchJob is the search of fast mode: it lists the places around a point
+// from the search endpoint, without a browser, and queues a place job for
+// each of them.
 type SearchJob struct {
 	scrapemate.Job
 
+	OwnerID        string
+	OrganizationID string
+	Country        string
+	ExtractEmail   bool
+	ExtractBodacc  bool
+
 	params      *MapSearchParams
 	ExitMonitor exiter.Exiter
 }
@@ -68,7 +77,36 @@ func WithSearchJobExitMonitor(exitMonitor exiter.Exiter) SearchJobOptions {
 	}
 }
 
-func (j *SearchJob) Process(_ context.Context, resp *scrapemate.Response) (any, []scrapemate.IJob, error) {
+// WithSearchJobOwner sets the owner and organization of the places found.
+func WithSearchJobOwner(ownerID, organizationID string) SearchJobOptions {
+	return func(j *SearchJob) {
+		j.OwnerID = ownerID
+		j.OrganizationID = organizationID
+	}
+}
+
+// WithSearchJobCountry sets the country (gl parameter) of the place pages.
+func WithSearchJobCountry(gl string) SearchJobOptions {
+	return func(j *SearchJob) {
+		j.Country = gl
+	}
+}
+
+// WithSearchJobEnrichment makes the places found extract the emails of their
+// website and the company data from BODACC.
+func WithSearchJobEnrichment(email, bodacc bool) SearchJobOptions {
+	return func(j *SearchJob) {
+		j.ExtractEmail = email
+		j.ExtractBodacc = bodacc
+	}
+}
+
+// Params returns the parameters of the search.
+func (j *SearchJob) Params() MapSearchParams {
+	return *j.params
+}
+
+func (j *SearchJob) Process(ctx context.Context, resp *scrapemate.Response) (any, []scrapemate.IJob, error) {
 	defer func() {
 		resp.Document = nil
 		resp.Body = nil
@@ -91,13 +129,35 @@ func (j *SearchJob) Process(_ context.Context, resp *scrapemate.Response) (any,
 		j.params.Location.Radius,
 	)
 
+	jopts := []PlaceJobOptions{WithPlaceJobCountry(j.Country)}
+	if j.ExitMonitor != nil {
+		jopts = append(jopts, WithPlaceJobExitMonitor(j.ExitMonitor))
+	}
+
+	if j.ExtractBodacc {
+		jopts = append(jopts, WithBodaccExtraction())
+	}
+
+	var next []scrapemate.IJob
+
+	for _, entry := range entries {
+		u, ok := PlaceURL(entry.DataID, "")
+		if !ok {
+			continue
+		}
+
+		next = append(next, NewPlaceJob(j.ID, j.params.Hl, u, j.OwnerID, j.OrganizationID, j.ExtractEmail, false, jopts...))
+	}
+
 	if j.ExitMonitor != nil {
+		j.ExitMonitor.IncrPlacesFound(len(next))
 		j.ExitMonitor.IncrSeedCompleted(1)
-		j.ExitMonitor.IncrPlacesFound(len(entries))
-		j.ExitMonitor.IncrPlacesCompleted(len(entries))
 	}
 
-	return entries, nil, nil
+	log := scrapemate.GetLoggerFromContext(ctx)
+	log.Info(fmt.Sprintf("%d places found", len(next)))
+
+	return nil, next, nil
 }
 
 func removeFirstLine(data []byte) []byte {
@@ -0,0 +1,64 @@
+package gmaps
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+// SkipServiceAreaEnrichmentKey holds true in the context of the place jobs
+// that skip the address based enrichment of service-area businesses: the
+// reverse geocoding of their coordinates and the company lookup, which can
+// only match the locality Google shows instead of their hidden address.
+type SkipServiceAreaEnrichmentKey struct{}
+
+func skipServiceAreaEnrichment(ctx context.Context) bool {
+	skip, _ := ctx.Value(SkipServiceAreaEnrichmentKey{}).(bool)
+	return skip
+}
+
+// IsServiceAreaBusiness tells whether e is a service-area business, a
+// listing whose owner hid its address: Google gives it no street and its
+// address holds nothing but the locality it serves.
+func IsServiceAreaBusiness(e *Entry) bool {
+	a := e.CompleteAddress
+	if a.Street != "" {
+		return false
+	}
+
+	rest := e.Address
+	for _, part := range []string{a.Borough, a.PostalCode, a.City, a.State, a.Country} {
+		if part != "" {
+			rest = strings.ReplaceAll(rest, part, "")
+		}
+	}
+
+	return strings.IndexFunc(rest, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}) == -1
+}
+
+// markServiceArea flags the service-area businesses and records the area
+// Google shows for them, e.g. Lyon, France.
+func markServiceArea(e *Entry) {
+	if !IsServiceAreaBusiness(e) {
+		return
+	}
+
+	e.ServiceAreaBusiness = true
+
+	if e.Address != "" {
+		e.ServiceArea = e.Address
+		return
+	}
+
+	var parts []string
+
+	for _, part := range []string{e.CompleteAddress.City, e.CompleteAddress.State, e.CompleteAddress.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	e.ServiceArea = strings.Join(parts, ", ")
+}
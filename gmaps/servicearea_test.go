@@ -0,0 +1,53 @@
+package gmaps_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_IsServiceAreaBusiness(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry gmaps.Entry
+		want  bool
+	}{
+		{
+			name: "street address",
+			entry: gmaps.Entry{
+				Address:         "12 Rue de la République, 69002 Lyon, France",
+				CompleteAddress: gmaps.Address{Street: "12 Rue de la République", City: "Lyon", PostalCode: "69002", Country: "FR"},
+			},
+		},
+		{
+			name: "locality only",
+			entry: gmaps.Entry{
+				Address:         "Lyon, France",
+				CompleteAddress: gmaps.Address{City: "Lyon", Country: "France"},
+			},
+			want: true,
+		},
+		{
+			name: "no address",
+			entry: gmaps.Entry{
+				CompleteAddress: gmaps.Address{City: "Lyon"},
+			},
+			want: true,
+		},
+		{
+			name: "place name without street",
+			entry: gmaps.Entry{
+				Address:         "Centre Commercial Part-Dieu, 69003 Lyon",
+				CompleteAddress: gmaps.Address{City: "Lyon", PostalCode: "69003"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, gmaps.IsServiceAreaBusiness(&tc.entry))
+		})
+	}
+}
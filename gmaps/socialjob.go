@@ -0,0 +1,279 @@
+package gmaps
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/google/uuid"
+	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/scrapemate"
+)
+
+// Social networks SocialJob fetches the pages of.
+const (
+	SocialFacebook  = "facebook"
+	SocialInstagram = "instagram"
+)
+
+var (
+	// followersRe finds the audience of a page in its description, e.g.
+	// "1,234 likes", "12K Followers" or "1 234 abonnés".
+	followersRe     = regexp.MustCompile(`(?i)\b(\d{1,3}(?:[.,\s\x{a0}\x{202f}]\d{3})*(?:[.,]\d+)?[km]?)\s+(?:followers|likes|abonnés|mentions j’aime|j’aime)`)
+	categoryNameRe  = regexp.MustCompile(`"category_name"\s*:\s*"([^"]+)"`)
+	reservedFbPaths = []string{"sharer", "sharer.php", "share", "share.php", "plugins", "tr", "dialog", "login", "login.php", "policies", "help", "events", "watch", "hashtag"}
+	reservedIgPaths = []string{"p", "reel", "reels", "explore", "accounts", "stories", "tv", "about", "legal"}
+)
+
+// SocialProfile is the public data of the page of a place on a social
+// network.
+type SocialProfile struct {
+	Network string `json:"network"`
+	URL     string `json:"url"`
+	Name    string `json:"name,omitempty"`
+	// Followers are the followers of the page, or its likes on Facebook
+	// pages showing only these; 0 when unknown.
+	Followers int       `json:"followers"`
+	Category  string    `json:"category,omitempty"`
+	Email     string    `json:"email,omitempty"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+type SocialEnrichmentResult struct {
+	PlaceLink      string
+	OwnerID        string
+	OrganizationID string
+	// Profile is nil when the page could not be fetched.
+	Profile *SocialProfile
+}
+
+type SocialJobOptions func(*SocialJob)
+
+// SocialJob fetches the public data of the page of a place on a social
+// network.
+type SocialJob struct {
+	scrapemate.Job
+	OwnerID        string
+	OrganizationID string
+	PlaceLink      string
+	Network        string
+	ExitMonitor    exiter.Exiter
+}
+
+func NewSocialJob(profileURL, network, placeLink, ownerID, organizationID string, opts ...SocialJobOptions) *SocialJob {
+	const (
+		defaultPrio       = scrapemate.PriorityLow
+		defaultMaxRetries = 1
+	)
+
+	job := SocialJob{
+		Job: scrapemate.Job{
+			ID:         uuid.New().String(),
+			Method:     http.MethodGet,
+			URL:        profileURL,
+			MaxRetries: defaultMaxRetries,
+			Priority:   defaultPrio,
+		},
+		Network:        network,
+		PlaceLink:      placeLink,
+		OwnerID:        ownerID,
+		OrganizationID: organizationID,
+	}
+
+	for _, opt := range opts {
+		opt(&job)
+	}
+
+	return &job
+}
+
+func WithSocialJobParentID(parentID string) SocialJobOptions {
+	return func(j *SocialJob) {
+		j.ParentID = parentID
+	}
+}
+
+func WithSocialJobExitMonitor(exitMonitor exiter.Exiter) SocialJobOptions {
+	return func(j *SocialJob) {
+		j.ExitMonitor = exitMonitor
+	}
+}
+
+// SocialProfileURL returns the network and the canonical URL of the page
+// raw links to, and false for links to anything else, e.g. a post or a share
+// button.
+func SocialProfileURL(raw string) (network, profileURL string, ok bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return "", "", false
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	segment, _, _ := strings.Cut(strings.Trim(u.Path, "/"), "/")
+
+	switch {
+	case host == "facebook.com" || strings.HasSuffix(host, ".facebook.com") || host == "fb.com":
+		if segment == "profile.php" {
+			id := u.Query().Get("id")
+			if id == "" {
+				return "", "", false
+			}
+
+			return SocialFacebook, "https://www.facebook.com/profile.php?id=" + url.QueryEscape(id), true
+		}
+
+		if segment == "" || slices.Contains(reservedFbPaths, segment) {
+			return "", "", false
+		}
+
+		return SocialFacebook, "https://www.facebook.com/" + segment, true
+	case host == "instagram.com":
+		if segment == "" || slices.Contains(reservedIgPaths, segment) {
+			return "", "", false
+		}
+
+		return SocialInstagram, "https://www.instagram.com/" + segment + "/", true
+	}
+
+	return "", "", false
+}
+
+// socialJobs returns a job per network for the profiles links point to, the
+// first link of each network winning.
+func socialJobs(links []string, parentID, placeLink, ownerID, organizationID string, exitMonitor exiter.Exiter) []scrapemate.IJob {
+	var (
+		ans  []scrapemate.IJob
+		seen = map[string]bool{}
+	)
+
+	for _, link := range links {
+		network, profileURL, ok := SocialProfileURL(link)
+		if !ok || seen[network] {
+			continue
+		}
+
+		seen[network] = true
+
+		opts := []SocialJobOptions{WithSocialJobParentID(parentID)}
+		if exitMonitor != nil {
+			opts = append(opts, WithSocialJobExitMonitor(exitMonitor))
+		}
+
+		ans = append(ans, NewSocialJob(profileURL, network, placeLink, ownerID, organizationID, opts...))
+	}
+
+	return ans
+}
+
+func (j *SocialJob) Process(_ context.Context, resp *scrapemate.Response) (any, []scrapemate.IJob, error) {
+	defer func() {
+		resp.Document = nil
+		resp.Body = nil
+		resp.Meta = nil
+	}()
+
+	result := &SocialEnrichmentResult{
+		PlaceLink:      j.PlaceLink,
+		OwnerID:        j.OwnerID,
+		OrganizationID: j.OrganizationID,
+	}
+
+	if resp.Error != nil || resp.Document == nil {
+		return result, nil, nil
+	}
+
+	doc, ok := resp.Document.(*goquery.Document)
+	if !ok {
+		return result, nil, nil
+	}
+
+	result.Profile = ParseSocialProfile(j.Network, j.GetURL(), doc, resp.Body)
+
+	return result, nil, nil
+}
+
+// ParseSocialProfile reads the public data of a page of network from its
+// Open Graph tags and the data embedded in it.
+func ParseSocialProfile(network, profileURL string, doc *goquery.Document, body []byte) *SocialProfile {
+	p := &SocialProfile{
+		Network:   network,
+		URL:       profileURL,
+		FetchedAt: time.Now().UTC(),
+	}
+
+	p.Name = strings.TrimSpace(doc.Find(`meta[property="og:title"]`).AttrOr("content", ""))
+
+	description := doc.Find(`meta[property="og:description"]`).AttrOr("content", "")
+	if description == "" {
+		description = doc.Find(`meta[name="description"]`).AttrOr("content", "")
+	}
+
+	if m := followersRe.FindStringSubmatch(description); m != nil {
+		p.Followers = parseCount(m[1])
+	}
+
+	if m := categoryNameRe.FindSubmatch(body); m != nil {
+		p.Category = strings.TrimSpace(string(m[1]))
+	}
+
+	if emails := regexEmailExtractor([]byte(description)); len(emails) > 0 {
+		p.Email = emails[0]
+	} else if emails := docEmailExtractor(doc); len(emails) > 0 {
+		p.Email = emails[0]
+	}
+
+	return p
+}
+
+// parseCount parses the counts social networks show, e.g. "1,234", "1 234",
+// "12.5K" or "3M".
+func parseCount(s string) int {
+	s = strings.ToLower(strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\u00a0', '\u202f':
+			return -1
+		}
+
+		return r
+	}, s))
+
+	mult := 1.0
+
+	switch {
+	case strings.HasSuffix(s, "k"):
+		mult, s = 1e3, strings.TrimSuffix(s, "k")
+	case strings.HasSuffix(s, "m"):
+		mult, s = 1e6, strings.TrimSuffix(s, "m")
+	}
+
+	if mult > 1 {
+		// a decimal separator: 12.5K, 12,5K
+		v, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+		if err != nil {
+			return 0
+		}
+
+		return int(v * mult)
+	}
+
+	v, err := strconv.Atoi(strings.NewReplacer(",", "", ".", "").Replace(s))
+	if err != nil {
+		return 0
+	}
+
+	return v
+}
+
+func (j *SocialJob) UseInResults() bool {
+	return false
+}
+
+func (j *SocialJob) ProcessOnFetchError() bool {
+	return true
+}
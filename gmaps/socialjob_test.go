@@ -0,0 +1,58 @@
+package gmaps_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_SocialProfileURL(t *testing.T) {
+	tests := []struct {
+		raw     string
+		network string
+		want    string
+	}{
+		{"https://www.facebook.com/boulangerie.martin/?ref=page", gmaps.SocialFacebook, "https://www.facebook.com/boulangerie.martin"},
+		{"https://m.facebook.com/profile.php?id=100063", gmaps.SocialFacebook, "https://www.facebook.com/profile.php?id=100063"},
+		{"http://instagram.com/boulangerie.martin", gmaps.SocialInstagram, "https://www.instagram.com/boulangerie.martin/"},
+		{"https://www.facebook.com/sharer/sharer.php?u=https://example.com", "", ""},
+		{"https://www.instagram.com/p/C1x2y3z/", "", ""},
+		{"https://www.example.com/facebook", "", ""},
+	}
+
+	for _, tt := range tests {
+		network, got, ok := gmaps.SocialProfileURL(tt.raw)
+		require.Equal(t, tt.want != "", ok, tt.raw)
+		require.Equal(t, tt.network, network, tt.raw)
+		require.Equal(t, tt.want, got, tt.raw)
+	}
+}
+
+func Test_ParseSocialProfile(t *testing.T) {
+	page := `<html><head>
+		<meta property="og:title" content="Boulangerie Martin (@boulangerie.martin)">
+		<meta property="og:description" content="12,5K Followers, 310 Following, 842 Posts - Pain au levain, Paris 11">
+		</head><body><script>{"category_name":"Bakery"}</script></body></html>`
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	require.NoError(t, err)
+
+	p := gmaps.ParseSocialProfile(gmaps.SocialInstagram, "https://www.instagram.com/boulangerie.martin/", doc, []byte(page))
+	require.Equal(t, "Boulangerie Martin (@boulangerie.martin)", p.Name)
+	require.Equal(t, 12500, p.Followers)
+	require.Equal(t, "Bakery", p.Category)
+
+	page = `<html><head><meta property="og:description" content="Boulangerie Martin, Paris 75011. 1 234 J’aime · 56 en parlent"></head>
+		<body><a href="mailto:contact@boulangerie-martin.fr">Envoyer un message</a></body></html>`
+
+	doc, err = goquery.NewDocumentFromReader(strings.NewReader(page))
+	require.NoError(t, err)
+
+	p = gmaps.ParseSocialProfile(gmaps.SocialFacebook, "https://www.facebook.com/boulangerie.martin", doc, []byte(page))
+	require.Equal(t, 1234, p.Followers)
+	require.Equal(t, "contact@boulangerie-martin.fr", p.Email)
+}
@@ -0,0 +1,107 @@
+package gmaps
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// technology is how a product shows on the websites using it: the prefix of
+// their <meta name="generator">, strings of their HTML, or response headers
+// containing a value ("" for any). All are lower case.
+type technology struct {
+	name      string
+	generator string
+	html      []string
+	headers   map[string]string
+}
+
+// technologies are the products DetectTechnologies recognizes, in the order
+// it lists them.
+var technologies = []technology{
+	// CMS
+	{name: "WordPress", generator: "wordpress", html: []string{"/wp-content/", "/wp-includes/"}},
+	{name: "Wix", generator: "wix.com", html: []string{"static.wixstatic.com", "static.parastorage.com"}},
+	{name: "Squarespace", generator: "squarespace", html: []string{"static1.squarespace.com"}},
+	{name: "Webflow", generator: "webflow", html: []string{"assets.website-files.com"}},
+	{name: "Joomla", generator: "joomla"},
+	{name: "Drupal", generator: "drupal", html: []string{"/sites/default/files/"}, headers: map[string]string{"X-Generator": "drupal"}},
+	{name: "Jimdo", html: []string{"assets.jimstatic.com"}},
+
+	// e-commerce
+	{name: "Shopify", html: []string{"cdn.shopify.com"}, headers: map[string]string{"X-Shopid": ""}},
+	{name: "WooCommerce", generator: "woocommerce", html: []string{"/plugins/woocommerce/"}},
+	{name: "PrestaShop", generator: "prestashop", html: []string{"var prestashop"}, headers: map[string]string{"Powered-By": "prestashop"}},
+	{name: "Magento", html: []string{"mage/cookies", "text/x-magento-init"}},
+
+	// analytics
+	{name: "Google Analytics", html: []string{"google-analytics.com/analytics.js", "googletagmanager.com/gtag/js"}},
+	{name: "Google Tag Manager", html: []string{"googletagmanager.com/gtm.js"}},
+	{name: "Meta Pixel", html: []string{"/fbevents.js", "fbq('init'"}},
+	{name: "Hotjar", html: []string{"static.hotjar.com"}},
+	{name: "Matomo", html: []string{"matomo.js", "piwik.js"}},
+
+	// chat widgets
+	{name: "Intercom", html: []string{"widget.intercom.io"}},
+	{name: "Crisp", html: []string{"client.crisp.chat"}},
+	{name: "Zendesk Chat", html: []string{"static.zdassets.com"}},
+	{name: "Tawk.to", html: []string{"embed.tawk.to"}},
+	{name: "Tidio", html: []string{"code.tidio.co"}},
+	{name: "HubSpot", html: []string{"js.hs-scripts.com"}},
+	{name: "Drift", html: []string{"js.driftt.com"}},
+}
+
+// DetectTechnologies returns the names of the products the page of a website
+// runs on, from its document, raw body and response headers, any of which
+// may be nil.
+func DetectTechnologies(doc *goquery.Document, body []byte, headers http.Header) []string {
+	body = bytes.ToLower(body)
+
+	var generators []string
+
+	if doc != nil {
+		doc.Find("meta[name]").Each(func(_ int, s *goquery.Selection) {
+			if strings.EqualFold(s.AttrOr("name", ""), "generator") {
+				generators = append(generators, strings.ToLower(s.AttrOr("content", "")))
+			}
+		})
+	}
+
+	var ans []string
+
+	for i := range technologies {
+		if technologies[i].detect(generators, body, headers) {
+			ans = append(ans, technologies[i].name)
+		}
+	}
+
+	return ans
+}
+
+func (t *technology) detect(generators []string, body []byte, headers http.Header) bool {
+	if t.generator != "" {
+		for _, g := range generators {
+			if strings.HasPrefix(g, t.generator) {
+				return true
+			}
+		}
+	}
+
+	for _, s := range t.html {
+		if bytes.Contains(body, []byte(s)) {
+			return true
+		}
+	}
+
+	for name, value := range t.headers {
+		for _, v := range headers.Values(name) {
+			if strings.Contains(strings.ToLower(v), value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
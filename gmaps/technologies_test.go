@@ -0,0 +1,34 @@
+package gmaps_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_DetectTechnologies(t *testing.T) {
+	page := `<html><head>
+		<meta name="Generator" content="WordPress 6.4.2">
+		<link rel="stylesheet" href="/wp-content/plugins/woocommerce/assets/css/woocommerce.css">
+		<script async src="https://www.googletagmanager.com/gtag/js?id=G-ABC123"></script>
+		</head><body><script src="https://embed.tawk.to/123/default"></script></body></html>`
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	require.NoError(t, err)
+
+	got := gmaps.DetectTechnologies(doc, []byte(page), nil)
+	require.Equal(t, []string{"WordPress", "WooCommerce", "Google Analytics", "Tawk.to"}, got)
+
+	headers := http.Header{}
+	headers.Set("X-ShopId", "12345")
+
+	got = gmaps.DetectTechnologies(nil, []byte(`<script src="https://static.hotjar.com/c/hotjar-1.js"></script>`), headers)
+	require.Equal(t, []string{"Shopify", "Hotjar"}, got)
+
+	require.Empty(t, gmaps.DetectTechnologies(nil, []byte("<html><body>Boulangerie</body></html>"), nil))
+}
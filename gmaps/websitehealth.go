@@ -0,0 +1,116 @@
+package gmaps
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// maxWebsiteBody bounds how much of a page CheckWebsite downloads.
+const maxWebsiteBody = 2 << 20
+
+var viewportRe = regexp.MustCompile(`(?i)<meta[^>]*name\s*=\s*["']?viewport\b`)
+
+// websiteClient fetches the websites CheckWebsite visits.
+var websiteClient = &http.Client{Timeout: 30 * time.Second}
+
+// WebsiteHealth is how the website of a place fares when visited: what web
+// agencies pitch against.
+type WebsiteHealth struct {
+	// StatusCode is the HTTP status of the page reached, 0 when the site did
+	// not answer.
+	StatusCode int `json:"status_code"`
+	// TLSValid tells whether the certificate of the site is trusted, nil for
+	// sites without https.
+	TLSValid     *bool      `json:"tls_valid,omitempty"`
+	TLSExpiresAt *time.Time `json:"tls_expires_at,omitempty"`
+	// RedirectURL is the page the site redirected to, empty when it did not.
+	RedirectURL string `json:"redirect_url,omitempty"`
+	// MobileViewport tells whether the page sets a viewport meta tag, which
+	// sites made for mobile phones do.
+	MobileViewport bool `json:"mobile_viewport"`
+	// LoadTimeMs is how long the page took to download, in milliseconds.
+	LoadTimeMs int64 `json:"load_time_ms"`
+	// Error is why the site could not be reached.
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// CheckWebsite visits the website at rawURL and returns its health. A site
+// whose certificate is not trusted is visited again without checking it, so
+// that the rest of its health is known.
+func CheckWebsite(ctx context.Context, rawURL string) *WebsiteHealth {
+	return checkWebsite(ctx, websiteClient, rawURL)
+}
+
+func checkWebsite(ctx context.Context, client *http.Client, rawURL string) *WebsiteHealth {
+	h := &WebsiteHealth{CheckedAt: time.Now().UTC()}
+
+	start := time.Now()
+
+	resp, err := getWebsite(ctx, client, rawURL)
+
+	var certErr *tls.CertificateVerificationError
+
+	trusted := true
+
+	if errors.As(err, &certErr) {
+		trusted = false
+
+		insecure := *client
+		insecure.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // only to report on the site
+		}
+
+		start = time.Now()
+		resp, err = getWebsite(ctx, &insecure, rawURL)
+	}
+
+	if err != nil {
+		h.Error = err.Error()
+
+		if !trusted {
+			h.TLSValid = &trusted
+		}
+
+		return h
+	}
+
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebsiteBody))
+
+	h.LoadTimeMs = time.Since(start).Milliseconds()
+	h.StatusCode = resp.StatusCode
+	h.MobileViewport = viewportRe.Match(body)
+
+	if final := resp.Request.URL.String(); final != rawURL {
+		h.RedirectURL = final
+	}
+
+	if resp.TLS != nil {
+		h.TLSValid = &trusted
+
+		if len(resp.TLS.PeerCertificates) > 0 {
+			expires := resp.TLS.PeerCertificates[0].NotAfter.UTC()
+			h.TLSExpiresAt = &expires
+		}
+	}
+
+	return h
+}
+
+func getWebsite(ctx context.Context, client *http.Client, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", placeUserAgent)
+
+	return client.Do(req)
+}
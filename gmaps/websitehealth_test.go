@@ -0,0 +1,50 @@
+package gmaps_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_CheckWebsite(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/accueil", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/accueil", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`<html><head><meta name="viewport" content="width=device-width"></head></html>`))
+	})
+
+	// a self-signed certificate is not trusted
+	srv := httptest.NewTLSServer(mux)
+	defer srv.Close()
+
+	h := gmaps.CheckWebsite(context.Background(), srv.URL)
+	require.Empty(t, h.Error)
+	require.Equal(t, http.StatusOK, h.StatusCode)
+	require.Equal(t, srv.URL+"/accueil", h.RedirectURL)
+	require.True(t, h.MobileViewport)
+	require.NotNil(t, h.TLSValid)
+	require.False(t, *h.TLSValid)
+	require.NotNil(t, h.TLSExpiresAt)
+
+	plain := httptest.NewServer(http.NotFoundHandler())
+	defer plain.Close()
+
+	h = gmaps.CheckWebsite(context.Background(), plain.URL)
+	require.Equal(t, http.StatusNotFound, h.StatusCode)
+	require.Nil(t, h.TLSValid)
+	require.Empty(t, h.RedirectURL)
+	require.False(t, h.MobileViewport)
+
+	plain.Close()
+
+	h = gmaps.CheckWebsite(context.Background(), plain.URL)
+	require.Equal(t, 0, h.StatusCode)
+	require.NotEmpty(t, h.Error)
+}
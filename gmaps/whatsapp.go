@@ -0,0 +1,113 @@
+package gmaps
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	// waLinkRe finds click-to-chat links in text, e.g. a description.
+	waLinkRe = regexp.MustCompile(`(?i)(?:https?://)?(?:wa\.me|(?:api|web)\.whatsapp\.com)/[^\s"'<>]+`)
+	waPathRe = regexp.MustCompile(`^/\+?([0-9]+)/?$`)
+)
+
+// WhatsAppNumber returns the phone number a WhatsApp click-to-chat link
+// opens a chat with, in international format ("+33612345678"), or "" when
+// raw is not such a link. wa.me, api.whatsapp.com/send, web.whatsapp.com/send
+// and whatsapp://send links are recognized, also behind a Google redirect;
+// group invites and catalog links carry no number.
+func WhatsAppNumber(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") && strings.HasPrefix(strings.ToLower(raw), "wa.me/") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+
+	// links of Google Maps go through /url?q=
+	if u.Path == "/url" && u.Query().Get("q") != "" {
+		return WhatsAppNumber(u.Query().Get("q"))
+	}
+
+	var number string
+
+	switch host := strings.TrimPrefix(strings.ToLower(u.Host), "www."); {
+	case u.Scheme == "whatsapp" && host == "send":
+		number = u.Query().Get("phone")
+	case host == "wa.me":
+		m := waPathRe.FindStringSubmatch(u.Path)
+		if m == nil {
+			return ""
+		}
+
+		number = m[1]
+	case (host == "api.whatsapp.com" || host == "web.whatsapp.com" || host == "whatsapp.com") &&
+		strings.TrimSuffix(u.Path, "/") == "/send":
+		number = u.Query().Get("phone")
+	default:
+		return ""
+	}
+
+	return normalizeWhatsApp(number)
+}
+
+// normalizeWhatsApp keeps the digits of an international number, or returns
+// "" when they cannot be one.
+func normalizeWhatsApp(number string) string {
+	var b strings.Builder
+
+	for _, r := range number {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+
+	digits := strings.TrimPrefix(b.String(), "00")
+
+	// E.164 numbers have at most 15 digits, country code included
+	if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return ""
+	}
+
+	return "+" + digits
+}
+
+// findWhatsApp returns the number of the first click-to-chat link among
+// links, or found in text.
+func findWhatsApp(links []string, text ...string) string {
+	for _, link := range links {
+		if n := WhatsAppNumber(link); n != "" {
+			return n
+		}
+	}
+
+	for _, t := range text {
+		for _, link := range waLinkRe.FindAllString(t, -1) {
+			if n := WhatsAppNumber(link); n != "" {
+				return n
+			}
+		}
+	}
+
+	return ""
+}
+
+// setWhatsApp sets the WhatsApp number of the entry from the links and
+// description of the place.
+func (e *Entry) setWhatsApp() {
+	links := []string{e.WebSite, e.Menu.Link}
+
+	for _, l := range e.Reservations {
+		links = append(links, l.Link)
+	}
+
+	for _, l := range e.OrderOnline {
+		links = append(links, l.Link)
+	}
+
+	e.WhatsApp = findWhatsApp(links, e.Description)
+}
@@ -0,0 +1,31 @@
+package gmaps_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+func Test_WhatsAppNumber(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"https://wa.me/33612345678", "+33612345678"},
+		{"wa.me/+33612345678?text=Bonjour", "+33612345678"},
+		{"https://api.whatsapp.com/send?phone=33%206%2012%2034%2056%2078", "+33612345678"},
+		{"https://web.whatsapp.com/send/?phone=0033612345678&text=hi", "+33612345678"},
+		{"whatsapp://send?phone=+33612345678", "+33612345678"},
+		{"https://www.google.com/url?q=https://wa.me/33612345678&opi=1", "+33612345678"},
+		{"https://chat.whatsapp.com/AbCdEf123", ""},
+		{"https://wa.me/c/33612345678", ""},
+		{"https://wa.me/0612345678", ""},
+		{"https://www.boulangerie-martin.fr", ""},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, gmaps.WhatsAppNumber(tt.raw), tt.raw)
+	}
+}
@@ -0,0 +1,396 @@
+// Package graphqlapi serves a read-only GraphQL API over the results table,
+// so lead explorers can filter results without writing SQL. The schema is
+// in schema.graphql.
+package graphqlapi
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+
+	"github.com/gosom/google-maps-scraper/apiauth"
+	"github.com/gosom/google-maps-scraper/entreprise"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+const (
+	maxLimit = 500
+	maxDepth = 5
+)
+
+//go:embed schema.graphql
+var schema string
+
+// Handler returns an http.Handler answering GraphQL queries sent as JSON
+// POST bodies. The results are decrypted with c.
+func Handler(db *sql.DB, c *postgres.FieldCipher) http.Handler {
+	s := graphql.MustParseSchema(schema, &resolver{db: db, cipher: c}, graphql.MaxDepth(maxDepth))
+
+	return &relay.Handler{Schema: s}
+}
+
+type resolver struct {
+	db     *sql.DB
+	cipher *postgres.FieldCipher
+}
+
+type resultFilterInput struct {
+	OwnerID        *string
+	OrganizationID *string
+	Category       *string
+	CategoryID     *string
+	Department     *string
+	Region         *string
+	HasEmail       *bool
+	HasSiren       *bool
+	MinRating      *float64
+	MaxRating      *float64
+	MinReviews     *int32
+	MaxReviews     *int32
+	MinQuality     *int32
+	City           *string
+	SocieteForme   *string
+	CreatedAfter   *graphql.Time
+	CreatedBefore  *graphql.Time
+	Tag            *string
+	Technology     *string
+	LeadStatus     *string
+}
+
+// sorts maps the ResultSort enum values to their columns.
+var sorts = map[string]postgres.ResultSort{
+	"ID":           postgres.SortByID,
+	"CREATED_AT":   postgres.SortByCreatedAt,
+	"TITLE":        postgres.SortByTitle,
+	"CATEGORY":     postgres.SortByCategory,
+	"RATING":       postgres.SortByRating,
+	"REVIEW_COUNT": postgres.SortByReviewCount,
+	"QUALITY":      postgres.SortByQuality,
+}
+
+func (in *resultFilterInput) filter() postgres.ResultFilter {
+	var f postgres.ResultFilter
+
+	if in == nil {
+		return f
+	}
+
+	f.OwnerID = deref(in.OwnerID)
+	f.OrganizationID = deref(in.OrganizationID)
+	f.Category = deref(in.Category)
+	f.CategoryID = deref(in.CategoryID)
+	f.Department = deref(in.Department)
+	f.Region = deref(in.Region)
+	f.HasEmail = in.HasEmail
+	f.HasSiren = in.HasSiren
+	f.MinRating = in.MinRating
+	f.MaxRating = in.MaxRating
+	f.MinReviews = toInt(in.MinReviews)
+	f.MaxReviews = toInt(in.MaxReviews)
+	f.MinQuality = toInt(in.MinQuality)
+	f.City = deref(in.City)
+	f.SocieteForme = deref(in.SocieteForme)
+
+	f.Tag = deref(in.Tag)
+	f.Technology = deref(in.Technology)
+
+	// the LeadStatus enum values are the upper case statuses
+	if in.LeadStatus != nil {
+		f.LeadStatus = postgres.LeadStatus(strings.ToLower(*in.LeadStatus))
+	}
+
+	if in.CreatedAfter != nil {
+		f.CreatedAfter = &in.CreatedAfter.Time
+	}
+
+	if in.CreatedBefore != nil {
+		f.CreatedBefore = &in.CreatedBefore.Time
+	}
+
+	return f
+}
+
+// Results selects the results of args. A key bound to an organization only
+// reads the results of its organization.
+func (r *resolver) Results(ctx context.Context, args struct {
+	Filter *resultFilterInput
+	Sort   string
+	Desc   bool
+	Limit  int32
+	Offset int32
+}) (*resultPage, error) {
+	if args.Limit < 0 || args.Limit > maxLimit {
+		return nil, fmt.Errorf("limit must be between 0 and %d", maxLimit)
+	}
+
+	if args.Offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative")
+	}
+
+	f := args.Filter.filter()
+	f.ScopeOrganizationID = apiauth.GetOrganizationFromContext(ctx)
+
+	q := postgres.NewResultsQuery(f).
+		OrderBy(sorts[args.Sort], args.Desc).
+		Page(int(args.Limit), int(args.Offset))
+
+	return &resultPage{db: r.db, cipher: r.cipher, query: q, limit: int(args.Limit)}, nil
+}
+
+// resultPage only runs the queries for the fields that were asked for.
+type resultPage struct {
+	db     *sql.DB
+	cipher *postgres.FieldCipher
+	query  *postgres.ResultsQuery
+	limit  int
+}
+
+func (p *resultPage) Total(ctx context.Context) (int32, error) {
+	n, err := postgres.CountResults(ctx, p.db, p.query)
+
+	return int32(n), err
+}
+
+func (p *resultPage) Items(ctx context.Context) ([]*resultResolver, error) {
+	// a zero limit only asks for the total
+	if p.limit == 0 {
+		return []*resultResolver{}, nil
+	}
+
+	results, err := postgres.ListResults(ctx, p.db, p.cipher, p.query)
+	if err != nil {
+		return nil, err
+	}
+
+	ans := make([]*resultResolver, len(results))
+	for i := range results {
+		ans[i] = &resultResolver{r: &results[i]}
+	}
+
+	return ans, nil
+}
+
+type resultResolver struct {
+	r *postgres.Result
+}
+
+func (r *resultResolver) ID() graphql.ID {
+	return graphql.ID(strconv.FormatInt(r.r.ID, 10))
+}
+
+func (r *resultResolver) JobID() string               { return r.r.ParentID }
+func (r *resultResolver) OwnerID() string             { return r.r.UserID }
+func (r *resultResolver) OrganizationID() string      { return r.r.OrganizationID }
+func (r *resultResolver) Link() string                { return r.r.Link }
+func (r *resultResolver) PlaceID() string             { return r.r.PlaceID }
+func (r *resultResolver) Title() string               { return r.r.Title }
+func (r *resultResolver) Category() string            { return r.r.Category }
+func (r *resultResolver) CategoryID() string          { return r.r.CategoryID }
+func (r *resultResolver) Address() string             { return r.r.Address }
+func (r *resultResolver) DepartmentName() string      { return r.r.DepartmentName }
+func (r *resultResolver) Region() string              { return r.r.Region }
+func (r *resultResolver) CodeCommune() string         { return r.r.CodeCommune }
+func (r *resultResolver) CodePostal() string          { return r.r.CodePostal }
+func (r *resultResolver) Website() string             { return r.r.Website }
+func (r *resultResolver) Phones() []string            { return nonNil(r.r.Phones) }
+func (r *resultResolver) Emails() []string            { return nonNil(r.r.Emails) }
+func (r *resultResolver) WhatsApp() string            { return r.r.WhatsApp }
+func (r *resultResolver) Latitude() float64           { return r.r.Latitude }
+func (r *resultResolver) Longitude() float64          { return r.r.Longitude }
+func (r *resultResolver) Rating() float64             { return r.r.ReviewRating }
+func (r *resultResolver) ReviewCount() int32          { return int32(r.r.ReviewCount) }
+func (r *resultResolver) QualityScore() int32         { return int32(r.r.QualityScore) }
+func (r *resultResolver) SocieteDirigeants() []string { return nonNil(r.r.SocieteDirigeants) }
+func (r *resultResolver) SocieteSiren() string        { return r.r.SocieteSiren }
+func (r *resultResolver) SocieteForme() string        { return r.r.SocieteForme }
+func (r *resultResolver) SocieteEffectif() string     { return r.r.SocieteEffectif }
+func (r *resultResolver) SocieteCreation() string     { return r.r.SocieteCreation }
+func (r *resultResolver) SocieteCloture() string      { return r.r.SocieteCloture }
+func (r *resultResolver) SocieteLink() string         { return r.r.SocieteLink }
+func (r *resultResolver) SocieteDiffusion() *bool     { return r.r.SocieteDiffusion }
+func (r *resultResolver) NafCode() string             { return r.r.NafCode }
+func (r *resultResolver) SirenResolution() string     { return r.r.SirenResolution }
+func (r *resultResolver) ServiceAreaBusiness() bool   { return r.r.ServiceAreaBusiness }
+func (r *resultResolver) ServiceArea() string         { return r.r.ServiceArea }
+func (r *resultResolver) Tags() []string              { return nonNil(r.r.Tags) }
+func (r *resultResolver) Technologies() []string      { return nonNil(r.r.Technologies) }
+func (r *resultResolver) LeadStatus() string          { return strings.ToUpper(string(r.r.LeadStatus)) }
+
+func (r *resultResolver) CompanyMatch() string { return r.r.CompanyMatch }
+
+func (r *resultResolver) CompanyCandidate() *companyCandidateResolver {
+	if r.r.CompanyCandidate == nil {
+		return nil
+	}
+
+	return &companyCandidateResolver{c: r.r.CompanyCandidate}
+}
+
+type companyCandidateResolver struct {
+	c *postgres.CompanyCandidate
+}
+
+func (r *companyCandidateResolver) Siren() string  { return r.c.Siren }
+func (r *companyCandidateResolver) Name() string   { return r.c.Name }
+func (r *companyCandidateResolver) Source() string { return r.c.Source }
+func (r *companyCandidateResolver) Score() float64 { return r.c.Score }
+
+// SocialProfiles lists the social pages of the place in network order.
+func (r *resultResolver) SocialProfiles() []*socialProfileResolver {
+	ans := make([]*socialProfileResolver, 0, len(r.r.SocialProfiles))
+
+	for _, network := range slices.Sorted(maps.Keys(r.r.SocialProfiles)) {
+		p := r.r.SocialProfiles[network]
+		ans = append(ans, &socialProfileResolver{p: &p})
+	}
+
+	return ans
+}
+
+func (r *resultResolver) WebsiteHealth() *websiteHealthResolver {
+	if r.r.WebsiteHealth == nil {
+		return nil
+	}
+
+	return &websiteHealthResolver{h: r.r.WebsiteHealth}
+}
+
+func (r *resultResolver) ReviewStats() *reviewStatsResolver {
+	if r.r.ReviewStats == nil {
+		return nil
+	}
+
+	return &reviewStatsResolver{s: r.r.ReviewStats}
+}
+
+func (r *resultResolver) CreatedAt() *graphql.Time {
+	if r.r.CreatedAt.IsZero() {
+		return nil
+	}
+
+	return &graphql.Time{Time: r.r.CreatedAt}
+}
+
+// Provenance returns the sources of the enriched columns in column order.
+func (r *resultResolver) Provenance() []*fieldProvenanceResolver {
+	fields := make([]string, 0, len(r.r.Provenance))
+	for field := range r.r.Provenance {
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields)
+
+	ans := make([]*fieldProvenanceResolver, 0, len(fields))
+	for _, field := range fields {
+		ans = append(ans, &fieldProvenanceResolver{field: field, src: r.r.Provenance[field]})
+	}
+
+	return ans
+}
+
+type fieldProvenanceResolver struct {
+	field string
+	src   gmaps.FieldSource
+}
+
+func (r *fieldProvenanceResolver) Field() string  { return r.field }
+func (r *fieldProvenanceResolver) Source() string { return r.src.Source }
+
+func (r *fieldProvenanceResolver) FetchedAt() *graphql.Time {
+	if r.src.FetchedAt.IsZero() {
+		return nil
+	}
+
+	return &graphql.Time{Time: r.src.FetchedAt}
+}
+
+type socialProfileResolver struct {
+	p *gmaps.SocialProfile
+}
+
+func (r *socialProfileResolver) Network() string          { return r.p.Network }
+func (r *socialProfileResolver) URL() string              { return r.p.URL }
+func (r *socialProfileResolver) Name() string             { return r.p.Name }
+func (r *socialProfileResolver) Followers() int32         { return int32(r.p.Followers) }
+func (r *socialProfileResolver) Category() string         { return r.p.Category }
+func (r *socialProfileResolver) Email() string            { return r.p.Email }
+func (r *socialProfileResolver) FetchedAt() *graphql.Time { return &graphql.Time{Time: r.p.FetchedAt} }
+
+type websiteHealthResolver struct {
+	h *gmaps.WebsiteHealth
+}
+
+func (r *websiteHealthResolver) StatusCode() int32        { return int32(r.h.StatusCode) }
+func (r *websiteHealthResolver) TLSValid() *bool          { return r.h.TLSValid }
+func (r *websiteHealthResolver) RedirectURL() string      { return r.h.RedirectURL }
+func (r *websiteHealthResolver) MobileViewport() bool     { return r.h.MobileViewport }
+func (r *websiteHealthResolver) LoadTimeMs() int32        { return int32(r.h.LoadTimeMs) }
+func (r *websiteHealthResolver) Error() string            { return r.h.Error }
+func (r *websiteHealthResolver) CheckedAt() *graphql.Time { return &graphql.Time{Time: r.h.CheckedAt} }
+
+func (r *websiteHealthResolver) TLSExpiresAt() *graphql.Time {
+	if r.h.TLSExpiresAt == nil {
+		return nil
+	}
+
+	return &graphql.Time{Time: *r.h.TLSExpiresAt}
+}
+
+type reviewStatsResolver struct {
+	s *gmaps.ReviewStats
+}
+
+func (r *reviewStatsResolver) Reviews() int32              { return int32(r.s.Reviews) }
+func (r *reviewStatsResolver) Responses() int32            { return int32(r.s.Responses) }
+func (r *reviewStatsResolver) ResponseRate() float64       { return r.s.ResponseRate }
+func (r *reviewStatsResolver) ResponseDelayHours() float64 { return r.s.ResponseDelayHours }
+func (r *reviewStatsResolver) Positive() int32             { return int32(r.s.Positive) }
+func (r *reviewStatsResolver) Neutral() int32              { return int32(r.s.Neutral) }
+func (r *reviewStatsResolver) Negative() int32             { return int32(r.s.Negative) }
+
+// Department falls back to the postal code of the address for results saved
+// before departments were.
+func (r *resultResolver) Department() string {
+	if r.r.DepartmentCode != "" {
+		return r.r.DepartmentCode
+	}
+
+	return entreprise.ExtractDepartmentNumber(r.r.Address)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
+
+func toInt(v *int32) *int {
+	if v == nil {
+		return nil
+	}
+
+	n := int(*v)
+
+	return &n
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+
+	return s
+}
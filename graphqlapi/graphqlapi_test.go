@@ -0,0 +1,55 @@
+package graphqlapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/graphqlapi"
+)
+
+func Test_Handler(t *testing.T) {
+	// the schema is bound to the resolvers when the handler is created
+	h := graphqlapi.Handler(nil, nil)
+
+	tests := []struct {
+		name  string
+		query string
+		err   string
+	}{
+		{
+			name:  "limit too large",
+			query: `{ results(limit: 1000) { total } }`,
+			err:   "limit must be between 0 and 500",
+		},
+		{
+			name:  "unknown filter",
+			query: `{ results(filter: {town: "Paris"}) { total } }`,
+			err:   `"town"`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			body, err := json.Marshal(map[string]string{"query": tc.query})
+			require.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+
+			var resp struct {
+				Errors []struct {
+					Message string `json:"message"`
+				} `json:"errors"`
+			}
+
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			require.NotEmpty(t, resp.Errors)
+			require.Contains(t, resp.Errors[0].Message, tc.err)
+		})
+	}
+}
@@ -0,0 +1,909 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: scraper.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SubmitJobRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Query          string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Lang           string                 `protobuf:"bytes,2,opt,name=lang,proto3" json:"lang,omitempty"`
+	Country        string                 `protobuf:"bytes,3,opt,name=country,proto3" json:"country,omitempty"`
+	Geo            string                 `protobuf:"bytes,4,opt,name=geo,proto3" json:"geo,omitempty"`
+	Zoom           int32                  `protobuf:"varint,5,opt,name=zoom,proto3" json:"zoom,omitempty"`
+	Depth          int32                  `protobuf:"varint,6,opt,name=depth,proto3" json:"depth,omitempty"`
+	Email          bool                   `protobuf:"varint,7,opt,name=email,proto3" json:"email,omitempty"`
+	Bodacc         bool                   `protobuf:"varint,8,opt,name=bodacc,proto3" json:"bodacc,omitempty"`
+	ExtraReviews   bool                   `protobuf:"varint,9,opt,name=extra_reviews,json=extraReviews,proto3" json:"extra_reviews,omitempty"`
+	OwnerId        string                 `protobuf:"bytes,10,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"`
+	OrganizationId string                 `protobuf:"bytes,11,opt,name=organization_id,json=organizationId,proto3" json:"organization_id,omitempty"`
+	Priority       int32                  `protobuf:"varint,12,opt,name=priority,proto3" json:"priority,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *SubmitJobRequest) Reset() {
+	*x = SubmitJobRequest{}
+	mi := &file_scraper_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitJobRequest) ProtoMessage() {}
+
+func (x *SubmitJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_scraper_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitJobRequest.ProtoReflect.Descriptor instead.
+func (*SubmitJobRequest) Descriptor() ([]byte, []int) {
+	return file_scraper_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SubmitJobRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SubmitJobRequest) GetLang() string {
+	if x != nil {
+		return x.Lang
+	}
+	return ""
+}
+
+func (x *SubmitJobRequest) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *SubmitJobRequest) GetGeo() string {
+	if x != nil {
+		return x.Geo
+	}
+	return ""
+}
+
+func (x *SubmitJobRequest) GetZoom() int32 {
+	if x != nil {
+		return x.Zoom
+	}
+	return 0
+}
+
+func (x *SubmitJobRequest) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+func (x *SubmitJobRequest) GetEmail() bool {
+	if x != nil {
+		return x.Email
+	}
+	return false
+}
+
+func (x *SubmitJobRequest) GetBodacc() bool {
+	if x != nil {
+		return x.Bodacc
+	}
+	return false
+}
+
+func (x *SubmitJobRequest) GetExtraReviews() bool {
+	if x != nil {
+		return x.ExtraReviews
+	}
+	return false
+}
+
+func (x *SubmitJobRequest) GetOwnerId() string {
+	if x != nil {
+		return x.OwnerId
+	}
+	return ""
+}
+
+func (x *SubmitJobRequest) GetOrganizationId() string {
+	if x != nil {
+		return x.OrganizationId
+	}
+	return ""
+}
+
+func (x *SubmitJobRequest) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+type GetJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetJobRequest) Reset() {
+	*x = GetJobRequest{}
+	mi := &file_scraper_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetJobRequest) ProtoMessage() {}
+
+func (x *GetJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_scraper_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetJobRequest.ProtoReflect.Descriptor instead.
+func (*GetJobRequest) Descriptor() ([]byte, []int) {
+	return file_scraper_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetJobRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type Job struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Progress      *Progress              `protobuf:"bytes,2,opt,name=progress,proto3" json:"progress,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Job) Reset() {
+	*x = Job{}
+	mi := &file_scraper_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Job) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Job) ProtoMessage() {}
+
+func (x *Job) ProtoReflect() protoreflect.Message {
+	mi := &file_scraper_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Job.ProtoReflect.Descriptor instead.
+func (*Job) Descriptor() ([]byte, []int) {
+	return file_scraper_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Job) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Job) GetProgress() *Progress {
+	if x != nil {
+		return x.Progress
+	}
+	return nil
+}
+
+type Progress struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Status             string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	ChildJobsCount     int32                  `protobuf:"varint,2,opt,name=child_jobs_count,json=childJobsCount,proto3" json:"child_jobs_count,omitempty"`
+	ChildJobsCompleted int32                  `protobuf:"varint,3,opt,name=child_jobs_completed,json=childJobsCompleted,proto3" json:"child_jobs_completed,omitempty"`
+	ChildJobsFailed    int32                  `protobuf:"varint,4,opt,name=child_jobs_failed,json=childJobsFailed,proto3" json:"child_jobs_failed,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *Progress) Reset() {
+	*x = Progress{}
+	mi := &file_scraper_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Progress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Progress) ProtoMessage() {}
+
+func (x *Progress) ProtoReflect() protoreflect.Message {
+	mi := &file_scraper_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Progress.ProtoReflect.Descriptor instead.
+func (*Progress) Descriptor() ([]byte, []int) {
+	return file_scraper_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Progress) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Progress) GetChildJobsCount() int32 {
+	if x != nil {
+		return x.ChildJobsCount
+	}
+	return 0
+}
+
+func (x *Progress) GetChildJobsCompleted() int32 {
+	if x != nil {
+		return x.ChildJobsCompleted
+	}
+	return 0
+}
+
+func (x *Progress) GetChildJobsFailed() int32 {
+	if x != nil {
+		return x.ChildJobsFailed
+	}
+	return 0
+}
+
+type StreamResultsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	AfterId       int64                  `protobuf:"varint,2,opt,name=after_id,json=afterId,proto3" json:"after_id,omitempty"`
+	Follow        bool                   `protobuf:"varint,3,opt,name=follow,proto3" json:"follow,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamResultsRequest) Reset() {
+	*x = StreamResultsRequest{}
+	mi := &file_scraper_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamResultsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamResultsRequest) ProtoMessage() {}
+
+func (x *StreamResultsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_scraper_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamResultsRequest.ProtoReflect.Descriptor instead.
+func (*StreamResultsRequest) Descriptor() ([]byte, []int) {
+	return file_scraper_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StreamResultsRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *StreamResultsRequest) GetAfterId() int64 {
+	if x != nil {
+		return x.AfterId
+	}
+	return 0
+}
+
+func (x *StreamResultsRequest) GetFollow() bool {
+	if x != nil {
+		return x.Follow
+	}
+	return false
+}
+
+type ResultEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*ResultEvent_Entry
+	//	*ResultEvent_Progress
+	Event         isResultEvent_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResultEvent) Reset() {
+	*x = ResultEvent{}
+	mi := &file_scraper_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResultEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResultEvent) ProtoMessage() {}
+
+func (x *ResultEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_scraper_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResultEvent.ProtoReflect.Descriptor instead.
+func (*ResultEvent) Descriptor() ([]byte, []int) {
+	return file_scraper_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ResultEvent) GetEvent() isResultEvent_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *ResultEvent) GetEntry() *Entry {
+	if x != nil {
+		if x, ok := x.Event.(*ResultEvent_Entry); ok {
+			return x.Entry
+		}
+	}
+	return nil
+}
+
+func (x *ResultEvent) GetProgress() *Progress {
+	if x != nil {
+		if x, ok := x.Event.(*ResultEvent_Progress); ok {
+			return x.Progress
+		}
+	}
+	return nil
+}
+
+type isResultEvent_Event interface {
+	isResultEvent_Event()
+}
+
+type ResultEvent_Entry struct {
+	Entry *Entry `protobuf:"bytes,1,opt,name=entry,proto3,oneof"`
+}
+
+type ResultEvent_Progress struct {
+	Progress *Progress `protobuf:"bytes,2,opt,name=progress,proto3,oneof"`
+}
+
+func (*ResultEvent_Entry) isResultEvent_Event() {}
+
+func (*ResultEvent_Progress) isResultEvent_Event() {}
+
+type Entry struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Id                int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Link              string                 `protobuf:"bytes,2,opt,name=link,proto3" json:"link,omitempty"`
+	Title             string                 `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
+	Category          string                 `protobuf:"bytes,4,opt,name=category,proto3" json:"category,omitempty"`
+	Address           string                 `protobuf:"bytes,5,opt,name=address,proto3" json:"address,omitempty"`
+	Website           string                 `protobuf:"bytes,6,opt,name=website,proto3" json:"website,omitempty"`
+	Phones            []string               `protobuf:"bytes,7,rep,name=phones,proto3" json:"phones,omitempty"`
+	Emails            []string               `protobuf:"bytes,8,rep,name=emails,proto3" json:"emails,omitempty"`
+	Latitude          float64                `protobuf:"fixed64,9,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude         float64                `protobuf:"fixed64,10,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	SocieteDirigeants []string               `protobuf:"bytes,11,rep,name=societe_dirigeants,json=societeDirigeants,proto3" json:"societe_dirigeants,omitempty"`
+	SocieteSiren      string                 `protobuf:"bytes,12,opt,name=societe_siren,json=societeSiren,proto3" json:"societe_siren,omitempty"`
+	SocieteForme      string                 `protobuf:"bytes,13,opt,name=societe_forme,json=societeForme,proto3" json:"societe_forme,omitempty"`
+	SocieteEffectif   string                 `protobuf:"bytes,14,opt,name=societe_effectif,json=societeEffectif,proto3" json:"societe_effectif,omitempty"`
+	SocieteCreation   string                 `protobuf:"bytes,15,opt,name=societe_creation,json=societeCreation,proto3" json:"societe_creation,omitempty"`
+	SocieteCloture    string                 `protobuf:"bytes,16,opt,name=societe_cloture,json=societeCloture,proto3" json:"societe_cloture,omitempty"`
+	SocieteLink       string                 `protobuf:"bytes,17,opt,name=societe_link,json=societeLink,proto3" json:"societe_link,omitempty"`
+	SocieteDiffusion  *bool                  `protobuf:"varint,18,opt,name=societe_diffusion,json=societeDiffusion,proto3,oneof" json:"societe_diffusion,omitempty"`
+	Tags              []string               `protobuf:"bytes,19,rep,name=tags,proto3" json:"tags,omitempty"`
+	LeadStatus        string                 `protobuf:"bytes,20,opt,name=lead_status,json=leadStatus,proto3" json:"lead_status,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Entry) Reset() {
+	*x = Entry{}
+	mi := &file_scraper_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Entry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Entry) ProtoMessage() {}
+
+func (x *Entry) ProtoReflect() protoreflect.Message {
+	mi := &file_scraper_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Entry.ProtoReflect.Descriptor instead.
+func (*Entry) Descriptor() ([]byte, []int) {
+	return file_scraper_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Entry) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Entry) GetLink() string {
+	if x != nil {
+		return x.Link
+	}
+	return ""
+}
+
+func (x *Entry) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Entry) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+func (x *Entry) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Entry) GetWebsite() string {
+	if x != nil {
+		return x.Website
+	}
+	return ""
+}
+
+func (x *Entry) GetPhones() []string {
+	if x != nil {
+		return x.Phones
+	}
+	return nil
+}
+
+func (x *Entry) GetEmails() []string {
+	if x != nil {
+		return x.Emails
+	}
+	return nil
+}
+
+func (x *Entry) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *Entry) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *Entry) GetSocieteDirigeants() []string {
+	if x != nil {
+		return x.SocieteDirigeants
+	}
+	return nil
+}
+
+func (x *Entry) GetSocieteSiren() string {
+	if x != nil {
+		return x.SocieteSiren
+	}
+	return ""
+}
+
+func (x *Entry) GetSocieteForme() string {
+	if x != nil {
+		return x.SocieteForme
+	}
+	return ""
+}
+
+func (x *Entry) GetSocieteEffectif() string {
+	if x != nil {
+		return x.SocieteEffectif
+	}
+	return ""
+}
+
+func (x *Entry) GetSocieteCreation() string {
+	if x != nil {
+		return x.SocieteCreation
+	}
+	return ""
+}
+
+func (x *Entry) GetSocieteCloture() string {
+	if x != nil {
+		return x.SocieteCloture
+	}
+	return ""
+}
+
+func (x *Entry) GetSocieteLink() string {
+	if x != nil {
+		return x.SocieteLink
+	}
+	return ""
+}
+
+func (x *Entry) GetSocieteDiffusion() bool {
+	if x != nil && x.SocieteDiffusion != nil {
+		return *x.SocieteDiffusion
+	}
+	return false
+}
+
+func (x *Entry) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Entry) GetLeadStatus() string {
+	if x != nil {
+		return x.LeadStatus
+	}
+	return ""
+}
+
+type UpdateResultRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Tags          *Tags                  `protobuf:"bytes,2,opt,name=tags,proto3" json:"tags,omitempty"`
+	LeadStatus    *string                `protobuf:"bytes,3,opt,name=lead_status,json=leadStatus,proto3,oneof" json:"lead_status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateResultRequest) Reset() {
+	*x = UpdateResultRequest{}
+	mi := &file_scraper_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateResultRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateResultRequest) ProtoMessage() {}
+
+func (x *UpdateResultRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_scraper_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateResultRequest.ProtoReflect.Descriptor instead.
+func (*UpdateResultRequest) Descriptor() ([]byte, []int) {
+	return file_scraper_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *UpdateResultRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdateResultRequest) GetTags() *Tags {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *UpdateResultRequest) GetLeadStatus() string {
+	if x != nil && x.LeadStatus != nil {
+		return *x.LeadStatus
+	}
+	return ""
+}
+
+type Tags struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        []string               `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Tags) Reset() {
+	*x = Tags{}
+	mi := &file_scraper_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Tags) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Tags) ProtoMessage() {}
+
+func (x *Tags) ProtoReflect() protoreflect.Message {
+	mi := &file_scraper_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Tags.ProtoReflect.Descriptor instead.
+func (*Tags) Descriptor() ([]byte, []int) {
+	return file_scraper_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Tags) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+var File_scraper_proto protoreflect.FileDescriptor
+
+const file_scraper_proto_rawDesc = "" +
+	"\n" +
+	"\rscraper.proto\x12\bgmaps.v1\"\xc5\x02\n" +
+	"\x10SubmitJobRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x12\n" +
+	"\x04lang\x18\x02 \x01(\tR\x04lang\x12\x18\n" +
+	"\acountry\x18\x03 \x01(\tR\acountry\x12\x10\n" +
+	"\x03geo\x18\x04 \x01(\tR\x03geo\x12\x12\n" +
+	"\x04zoom\x18\x05 \x01(\x05R\x04zoom\x12\x14\n" +
+	"\x05depth\x18\x06 \x01(\x05R\x05depth\x12\x14\n" +
+	"\x05email\x18\a \x01(\bR\x05email\x12\x16\n" +
+	"\x06bodacc\x18\b \x01(\bR\x06bodacc\x12#\n" +
+	"\rextra_reviews\x18\t \x01(\bR\fextraReviews\x12\x19\n" +
+	"\bowner_id\x18\n" +
+	" \x01(\tR\aownerId\x12'\n" +
+	"\x0forganization_id\x18\v \x01(\tR\x0eorganizationId\x12\x1a\n" +
+	"\bpriority\x18\f \x01(\x05R\bpriority\"\x1f\n" +
+	"\rGetJobRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"E\n" +
+	"\x03Job\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12.\n" +
+	"\bprogress\x18\x02 \x01(\v2\x12.gmaps.v1.ProgressR\bprogress\"\xaa\x01\n" +
+	"\bProgress\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12(\n" +
+	"\x10child_jobs_count\x18\x02 \x01(\x05R\x0echildJobsCount\x120\n" +
+	"\x14child_jobs_completed\x18\x03 \x01(\x05R\x12childJobsCompleted\x12*\n" +
+	"\x11child_jobs_failed\x18\x04 \x01(\x05R\x0fchildJobsFailed\"`\n" +
+	"\x14StreamResultsRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x19\n" +
+	"\bafter_id\x18\x02 \x01(\x03R\aafterId\x12\x16\n" +
+	"\x06follow\x18\x03 \x01(\bR\x06follow\"q\n" +
+	"\vResultEvent\x12'\n" +
+	"\x05entry\x18\x01 \x01(\v2\x0f.gmaps.v1.EntryH\x00R\x05entry\x120\n" +
+	"\bprogress\x18\x02 \x01(\v2\x12.gmaps.v1.ProgressH\x00R\bprogressB\a\n" +
+	"\x05event\"\x93\x05\n" +
+	"\x05Entry\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04link\x18\x02 \x01(\tR\x04link\x12\x14\n" +
+	"\x05title\x18\x03 \x01(\tR\x05title\x12\x1a\n" +
+	"\bcategory\x18\x04 \x01(\tR\bcategory\x12\x18\n" +
+	"\aaddress\x18\x05 \x01(\tR\aaddress\x12\x18\n" +
+	"\awebsite\x18\x06 \x01(\tR\awebsite\x12\x16\n" +
+	"\x06phones\x18\a \x03(\tR\x06phones\x12\x16\n" +
+	"\x06emails\x18\b \x03(\tR\x06emails\x12\x1a\n" +
+	"\blatitude\x18\t \x01(\x01R\blatitude\x12\x1c\n" +
+	"\tlongitude\x18\n" +
+	" \x01(\x01R\tlongitude\x12-\n" +
+	"\x12societe_dirigeants\x18\v \x03(\tR\x11societeDirigeants\x12#\n" +
+	"\rsociete_siren\x18\f \x01(\tR\fsocieteSiren\x12#\n" +
+	"\rsociete_forme\x18\r \x01(\tR\fsocieteForme\x12)\n" +
+	"\x10societe_effectif\x18\x0e \x01(\tR\x0fsocieteEffectif\x12)\n" +
+	"\x10societe_creation\x18\x0f \x01(\tR\x0fsocieteCreation\x12'\n" +
+	"\x0fsociete_cloture\x18\x10 \x01(\tR\x0esocieteCloture\x12!\n" +
+	"\fsociete_link\x18\x11 \x01(\tR\vsocieteLink\x120\n" +
+	"\x11societe_diffusion\x18\x12 \x01(\bH\x00R\x10societeDiffusion\x88\x01\x01\x12\x12\n" +
+	"\x04tags\x18\x13 \x03(\tR\x04tags\x12\x1f\n" +
+	"\vlead_status\x18\x14 \x01(\tR\n" +
+	"leadStatusB\x14\n" +
+	"\x12_societe_diffusion\"\x7f\n" +
+	"\x13UpdateResultRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\"\n" +
+	"\x04tags\x18\x02 \x01(\v2\x0e.gmaps.v1.TagsR\x04tags\x12$\n" +
+	"\vlead_status\x18\x03 \x01(\tH\x00R\n" +
+	"leadStatus\x88\x01\x01B\x0e\n" +
+	"\f_lead_status\"\x1e\n" +
+	"\x04Tags\x12\x16\n" +
+	"\x06values\x18\x01 \x03(\tR\x06values2\xfd\x01\n" +
+	"\aScraper\x126\n" +
+	"\tSubmitJob\x12\x1a.gmaps.v1.SubmitJobRequest\x1a\r.gmaps.v1.Job\x120\n" +
+	"\x06GetJob\x12\x17.gmaps.v1.GetJobRequest\x1a\r.gmaps.v1.Job\x12H\n" +
+	"\rStreamResults\x12\x1e.gmaps.v1.StreamResultsRequest\x1a\x15.gmaps.v1.ResultEvent0\x01\x12>\n" +
+	"\fUpdateResult\x12\x1d.gmaps.v1.UpdateResultRequest\x1a\x0f.gmaps.v1.EntryB1Z/github.com/gosom/google-maps-scraper/grpcapi/pbb\x06proto3"
+
+var (
+	file_scraper_proto_rawDescOnce sync.Once
+	file_scraper_proto_rawDescData []byte
+)
+
+func file_scraper_proto_rawDescGZIP() []byte {
+	file_scraper_proto_rawDescOnce.Do(func() {
+		file_scraper_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_scraper_proto_rawDesc), len(file_scraper_proto_rawDesc)))
+	})
+	return file_scraper_proto_rawDescData
+}
+
+var file_scraper_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_scraper_proto_goTypes = []any{
+	(*SubmitJobRequest)(nil),     // 0: gmaps.v1.SubmitJobRequest
+	(*GetJobRequest)(nil),        // 1: gmaps.v1.GetJobRequest
+	(*Job)(nil),                  // 2: gmaps.v1.Job
+	(*Progress)(nil),             // 3: gmaps.v1.Progress
+	(*StreamResultsRequest)(nil), // 4: gmaps.v1.StreamResultsRequest
+	(*ResultEvent)(nil),          // 5: gmaps.v1.ResultEvent
+	(*Entry)(nil),                // 6: gmaps.v1.Entry
+	(*UpdateResultRequest)(nil),  // 7: gmaps.v1.UpdateResultRequest
+	(*Tags)(nil),                 // 8: gmaps.v1.Tags
+}
+var file_scraper_proto_depIdxs = []int32{
+	3, // 0: gmaps.v1.Job.progress:type_name -> gmaps.v1.Progress
+	6, // 1: gmaps.v1.ResultEvent.entry:type_name -> gmaps.v1.Entry
+	3, // 2: gmaps.v1.ResultEvent.progress:type_name -> gmaps.v1.Progress
+	8, // 3: gmaps.v1.UpdateResultRequest.tags:type_name -> gmaps.v1.Tags
+	0, // 4: gmaps.v1.Scraper.SubmitJob:input_type -> gmaps.v1.SubmitJobRequest
+	1, // 5: gmaps.v1.Scraper.GetJob:input_type -> gmaps.v1.GetJobRequest
+	4, // 6: gmaps.v1.Scraper.StreamResults:input_type -> gmaps.v1.StreamResultsRequest
+	7, // 7: gmaps.v1.Scraper.UpdateResult:input_type -> gmaps.v1.UpdateResultRequest
+	2, // 8: gmaps.v1.Scraper.SubmitJob:output_type -> gmaps.v1.Job
+	2, // 9: gmaps.v1.Scraper.GetJob:output_type -> gmaps.v1.Job
+	5, // 10: gmaps.v1.Scraper.StreamResults:output_type -> gmaps.v1.ResultEvent
+	6, // 11: gmaps.v1.Scraper.UpdateResult:output_type -> gmaps.v1.Entry
+	8, // [8:12] is the sub-list for method output_type
+	4, // [4:8] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_scraper_proto_init() }
+func file_scraper_proto_init() {
+	if File_scraper_proto != nil {
+		return
+	}
+	file_scraper_proto_msgTypes[5].OneofWrappers = []any{
+		(*ResultEvent_Entry)(nil),
+		(*ResultEvent_Progress)(nil),
+	}
+	file_scraper_proto_msgTypes[6].OneofWrappers = []any{}
+	file_scraper_proto_msgTypes[7].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_scraper_proto_rawDesc), len(file_scraper_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_scraper_proto_goTypes,
+		DependencyIndexes: file_scraper_proto_depIdxs,
+		MessageInfos:      file_scraper_proto_msgTypes,
+	}.Build()
+	File_scraper_proto = out.File
+	file_scraper_proto_goTypes = nil
+	file_scraper_proto_depIdxs = nil
+}
@@ -0,0 +1,239 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: scraper.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Scraper_SubmitJob_FullMethodName     = "/gmaps.v1.Scraper/SubmitJob"
+	Scraper_GetJob_FullMethodName        = "/gmaps.v1.Scraper/GetJob"
+	Scraper_StreamResults_FullMethodName = "/gmaps.v1.Scraper/StreamResults"
+	Scraper_UpdateResult_FullMethodName  = "/gmaps.v1.Scraper/UpdateResult"
+)
+
+// ScraperClient is the client API for Scraper service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ScraperClient interface {
+	SubmitJob(ctx context.Context, in *SubmitJobRequest, opts ...grpc.CallOption) (*Job, error)
+	GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error)
+	StreamResults(ctx context.Context, in *StreamResultsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ResultEvent], error)
+	UpdateResult(ctx context.Context, in *UpdateResultRequest, opts ...grpc.CallOption) (*Entry, error)
+}
+
+type scraperClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewScraperClient(cc grpc.ClientConnInterface) ScraperClient {
+	return &scraperClient{cc}
+}
+
+func (c *scraperClient) SubmitJob(ctx context.Context, in *SubmitJobRequest, opts ...grpc.CallOption) (*Job, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Job)
+	err := c.cc.Invoke(ctx, Scraper_SubmitJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *scraperClient) GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Job)
+	err := c.cc.Invoke(ctx, Scraper_GetJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *scraperClient) StreamResults(ctx context.Context, in *StreamResultsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ResultEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Scraper_ServiceDesc.Streams[0], Scraper_StreamResults_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamResultsRequest, ResultEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Scraper_StreamResultsClient = grpc.ServerStreamingClient[ResultEvent]
+
+func (c *scraperClient) UpdateResult(ctx context.Context, in *UpdateResultRequest, opts ...grpc.CallOption) (*Entry, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Entry)
+	err := c.cc.Invoke(ctx, Scraper_UpdateResult_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ScraperServer is the server API for Scraper service.
+// All implementations must embed UnimplementedScraperServer
+// for forward compatibility.
+type ScraperServer interface {
+	SubmitJob(context.Context, *SubmitJobRequest) (*Job, error)
+	GetJob(context.Context, *GetJobRequest) (*Job, error)
+	StreamResults(*StreamResultsRequest, grpc.ServerStreamingServer[ResultEvent]) error
+	UpdateResult(context.Context, *UpdateResultRequest) (*Entry, error)
+	mustEmbedUnimplementedScraperServer()
+}
+
+// UnimplementedScraperServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedScraperServer struct{}
+
+func (UnimplementedScraperServer) SubmitJob(context.Context, *SubmitJobRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitJob not implemented")
+}
+func (UnimplementedScraperServer) GetJob(context.Context, *GetJobRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJob not implemented")
+}
+func (UnimplementedScraperServer) StreamResults(*StreamResultsRequest, grpc.ServerStreamingServer[ResultEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamResults not implemented")
+}
+func (UnimplementedScraperServer) UpdateResult(context.Context, *UpdateResultRequest) (*Entry, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateResult not implemented")
+}
+func (UnimplementedScraperServer) mustEmbedUnimplementedScraperServer() {}
+func (UnimplementedScraperServer) testEmbeddedByValue()                 {}
+
+// UnsafeScraperServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ScraperServer will
+// result in compilation errors.
+type UnsafeScraperServer interface {
+	mustEmbedUnimplementedScraperServer()
+}
+
+func RegisterScraperServer(s grpc.ServiceRegistrar, srv ScraperServer) {
+	// If the following call pancis, it indicates UnimplementedScraperServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Scraper_ServiceDesc, srv)
+}
+
+func _Scraper_SubmitJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ScraperServer).SubmitJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Scraper_SubmitJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ScraperServer).SubmitJob(ctx, req.(*SubmitJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Scraper_GetJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ScraperServer).GetJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Scraper_GetJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ScraperServer).GetJob(ctx, req.(*GetJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Scraper_StreamResults_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamResultsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ScraperServer).StreamResults(m, &grpc.GenericServerStream[StreamResultsRequest, ResultEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Scraper_StreamResultsServer = grpc.ServerStreamingServer[ResultEvent]
+
+func _Scraper_UpdateResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ScraperServer).UpdateResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Scraper_UpdateResult_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ScraperServer).UpdateResult(ctx, req.(*UpdateResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Scraper_ServiceDesc is the grpc.ServiceDesc for Scraper service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Scraper_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gmaps.v1.Scraper",
+	HandlerType: (*ScraperServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SubmitJob",
+			Handler:    _Scraper_SubmitJob_Handler,
+		},
+		{
+			MethodName: "GetJob",
+			Handler:    _Scraper_GetJob_Handler,
+		},
+		{
+			MethodName: "UpdateResult",
+			Handler:    _Scraper_UpdateResult_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamResults",
+			Handler:       _Scraper_StreamResults_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "scraper.proto",
+}
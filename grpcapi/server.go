@@ -0,0 +1,335 @@
+// Package grpcapi serves the Scraper gRPC service defined in
+// proto/scraper.proto. The generated code lives in the pb package; run
+// `make proto` after changing the definitions.
+package grpcapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gosom/scrapemate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/gosom/google-maps-scraper/apiauth"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/grpcapi/pb"
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+const (
+	defaultDepth      = 10
+	defaultLang       = "en"
+	resultsBatchSize  = 100
+	defaultPollPeriod = 2 * time.Second
+	// defaultSettle is how long a followed stream keeps reading results
+	// after its job is finished: the result writer marks the jobs done
+	// before saving their last results, which it buffers up to 10 seconds.
+	defaultSettle = 15 * time.Second
+)
+
+// Roles are the roles the methods need when the API requires keys.
+var Roles = map[string]apiauth.Role{
+	pb.Scraper_SubmitJob_FullMethodName:     apiauth.Producer,
+	pb.Scraper_GetJob_FullMethodName:        apiauth.Reader,
+	pb.Scraper_StreamResults_FullMethodName: apiauth.Reader,
+	pb.Scraper_UpdateResult_FullMethodName:  apiauth.Producer,
+}
+
+// Store reads the progress and the results of jobs.
+type Store interface {
+	JobProgress(ctx context.Context, id string) (postgres.JobProgress, error)
+	ResultsAfter(ctx context.Context, jobID string, afterID int64, limit int) ([]postgres.Result, error)
+}
+
+// dbStore is the Store of the database.
+type dbStore struct {
+	db     *sql.DB
+	cipher *postgres.FieldCipher
+}
+
+func (s dbStore) JobProgress(ctx context.Context, id string) (postgres.JobProgress, error) {
+	return postgres.GetJobProgress(ctx, s.db, id)
+}
+
+func (s dbStore) ResultsAfter(ctx context.Context, jobID string, afterID int64, limit int) ([]postgres.Result, error) {
+	return postgres.ResultsAfter(ctx, s.db, s.cipher, jobID, afterID, limit)
+}
+
+type Server struct {
+	pb.UnimplementedScraperServer
+
+	db         *sql.DB
+	cipher     *postgres.FieldCipher
+	store      Store
+	provider   scrapemate.JobProvider
+	pollPeriod time.Duration
+	settle     time.Duration
+}
+
+// ServerOption configures a Server.
+type ServerOption func(*Server)
+
+// WithStore makes the server read progress and results from store instead
+// of the database.
+func WithStore(store Store) ServerOption {
+	return func(s *Server) {
+		s.store = store
+	}
+}
+
+// WithFieldCipher makes the server decrypt the results it reads with c.
+func WithFieldCipher(c *postgres.FieldCipher) ServerOption {
+	return func(s *Server) {
+		s.cipher = c
+	}
+}
+
+// WithPollPeriod sets how often followed streams look for new results, and
+// how long they keep doing so once their job is finished.
+func WithPollPeriod(poll, settle time.Duration) ServerOption {
+	return func(s *Server) {
+		s.pollPeriod = poll
+		s.settle = settle
+	}
+}
+
+// NewServer creates a server pushing jobs to provider and reading progress
+// and results from db.
+func NewServer(db *sql.DB, provider scrapemate.JobProvider, opts ...ServerOption) *Server {
+	s := &Server{
+		db:         db,
+		provider:   provider,
+		pollPeriod: defaultPollPeriod,
+		settle:     defaultSettle,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.store == nil {
+		s.store = dbStore{db: db, cipher: s.cipher}
+	}
+
+	return s
+}
+
+// Register creates a gRPC server with the Scraper service registered.
+func (s *Server) Register(opts ...grpc.ServerOption) *grpc.Server {
+	gs := grpc.NewServer(opts...)
+	pb.RegisterScraperServer(gs, s)
+
+	return gs
+}
+
+func (s *Server) SubmitJob(ctx context.Context, req *pb.SubmitJobRequest) (*pb.Job, error) {
+	query := strings.TrimSpace(req.GetQuery())
+	if query == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+
+	if req.GetZoom() < 0 || req.GetZoom() > 21 {
+		return nil, status.Errorf(codes.InvalidArgument, "zoom must be between 0 and 21, got %d", req.GetZoom())
+	}
+
+	// a key bound to an organization submits the jobs of its organization
+	organization, err := apiauth.RestrictOrganization(ctx, req.GetOrganizationId())
+	if err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	depth := int(req.GetDepth())
+	if depth <= 0 {
+		depth = defaultDepth
+	}
+
+	lang := req.GetLang()
+	if lang == "" {
+		lang = defaultLang
+	}
+
+	opts := []gmaps.GmapJobOptions{gmaps.WithCountry(req.GetCountry())}
+
+	if req.GetExtraReviews() {
+		opts = append(opts, gmaps.WithExtraReviews())
+	}
+
+	job := gmaps.NewGmapJob("", lang, query, req.GetOwnerId(), organization, depth,
+		req.GetEmail(), req.GetBodacc(), req.GetGeo(), int(req.GetZoom()), opts...)
+
+	if req.GetPriority() != 0 {
+		job.Priority = int(req.GetPriority())
+	}
+
+	if err := s.provider.Push(ctx, job); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to queue job: %v", err)
+	}
+
+	return s.GetJob(ctx, &pb.GetJobRequest{Id: job.ID})
+}
+
+func (s *Server) GetJob(ctx context.Context, req *pb.GetJobRequest) (*pb.Job, error) {
+	progress, err := s.progress(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.Job{Id: req.GetId(), Progress: toProgress(progress)}, nil
+}
+
+func (s *Server) StreamResults(req *pb.StreamResultsRequest, stream grpc.ServerStreamingServer[pb.ResultEvent]) error {
+	ctx := stream.Context()
+	afterID := req.GetAfterId()
+
+	var (
+		last postgres.JobProgress
+		// quietSince is when a finished job last had new results.
+		quietSince time.Time
+	)
+
+	for first := true; ; first = false {
+		progress, err := s.progress(ctx, req.GetJobId())
+		if err != nil {
+			return err
+		}
+
+		results, err := s.store.ResultsAfter(ctx, req.GetJobId(), afterID, resultsBatchSize)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to read results: %v", err)
+		}
+
+		for i := range results {
+			// Send blocks while the client does not keep up.
+			if err := stream.Send(&pb.ResultEvent{Event: &pb.ResultEvent_Entry{Entry: toEntry(&results[i])}}); err != nil {
+				return err
+			}
+
+			afterID = results[i].ID
+		}
+
+		if first || progress != last {
+			if err := stream.Send(&pb.ResultEvent{Event: &pb.ResultEvent_Progress{Progress: toProgress(progress)}}); err != nil {
+				return err
+			}
+
+			last = progress
+		}
+
+		if len(results) == resultsBatchSize {
+			continue
+		}
+
+		if !req.GetFollow() {
+			return nil
+		}
+
+		// A finished job may still have results on their way to the
+		// database: stop once none came for the settle period.
+		if progress.Finished() {
+			if quietSince.IsZero() || len(results) > 0 {
+				quietSince = time.Now()
+			}
+
+			if time.Since(quietSince) >= s.settle {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.pollPeriod):
+		}
+	}
+}
+
+func (s *Server) UpdateResult(ctx context.Context, req *pb.UpdateResultRequest) (*pb.Entry, error) {
+	var u postgres.ResultUpdate
+
+	if req.GetTags() != nil {
+		tags := req.GetTags().GetValues()
+		u.Tags = &tags
+	}
+
+	if req.LeadStatus != nil {
+		lead := postgres.LeadStatus(req.GetLeadStatus())
+		u.LeadStatus = &lead
+	}
+
+	if err := u.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	result, err := postgres.UpdateResult(ctx, s.db, s.cipher, req.GetId(), apiauth.GetOrganizationFromContext(ctx), u)
+	if errors.Is(err, postgres.ErrResultNotFound) {
+		return nil, status.Errorf(codes.NotFound, "result %d not found", req.GetId())
+	}
+
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to update result: %v", err)
+	}
+
+	return toEntry(&result), nil
+}
+
+func (s *Server) progress(ctx context.Context, id string) (postgres.JobProgress, error) {
+	if id == "" {
+		return postgres.JobProgress{}, status.Error(codes.InvalidArgument, "job id is required")
+	}
+
+	progress, err := s.store.JobProgress(ctx, id)
+
+	// the jobs of other organizations are not found with a key bound to one
+	if organization := apiauth.GetOrganizationFromContext(ctx); err == nil && organization != "" && progress.OrganizationID != organization {
+		err = postgres.ErrJobNotFound
+	}
+
+	if errors.Is(err, postgres.ErrJobNotFound) {
+		return postgres.JobProgress{}, status.Errorf(codes.NotFound, "job %s not found", id)
+	}
+
+	if err != nil {
+		return progress, status.Errorf(codes.Internal, "failed to read job: %v", err)
+	}
+
+	return progress, nil
+}
+
+func toProgress(p postgres.JobProgress) *pb.Progress {
+	return &pb.Progress{
+		Status:             p.Status,
+		ChildJobsCount:     int32(p.ChildJobsCount),
+		ChildJobsCompleted: int32(p.ChildJobsCompleted),
+		ChildJobsFailed:    int32(p.ChildJobsFailed),
+	}
+}
+
+func toEntry(r *postgres.Result) *pb.Entry {
+	return &pb.Entry{
+		Id:                r.ID,
+		Link:              r.Link,
+		Title:             r.Title,
+		Category:          r.Category,
+		Address:           r.Address,
+		Website:           r.Website,
+		Phones:            r.Phones,
+		Emails:            r.Emails,
+		Latitude:          r.Latitude,
+		Longitude:         r.Longitude,
+		SocieteDirigeants: r.SocieteDirigeants,
+		SocieteSiren:      r.SocieteSiren,
+		SocieteForme:      r.SocieteForme,
+		SocieteEffectif:   r.SocieteEffectif,
+		SocieteCreation:   r.SocieteCreation,
+		SocieteCloture:    r.SocieteCloture,
+		SocieteLink:       r.SocieteLink,
+		SocieteDiffusion:  r.SocieteDiffusion,
+		Tags:              r.Tags,
+		LeadStatus:        string(r.LeadStatus),
+	}
+}
@@ -0,0 +1,73 @@
+package grpcapi_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/gosom/google-maps-scraper/grpcapi"
+	"github.com/gosom/google-maps-scraper/grpcapi/pb"
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+// lateStore has a finished job whose last result is saved a few reads
+// after, as the result writer does.
+type lateStore struct {
+	mu    sync.Mutex
+	reads int
+}
+
+func (s *lateStore) JobProgress(context.Context, string) (postgres.JobProgress, error) {
+	return postgres.JobProgress{Status: "done", ChildJobsCount: 1, ChildJobsCompleted: 1}, nil
+}
+
+func (s *lateStore) ResultsAfter(_ context.Context, _ string, afterID int64, _ int) ([]postgres.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reads++
+
+	if s.reads < 3 || afterID >= 1 {
+		return nil, nil
+	}
+
+	return []postgres.Result{{ID: 1, Title: "late"}}, nil
+}
+
+type stream struct {
+	grpc.ServerStream
+	events []*pb.ResultEvent
+}
+
+func (s *stream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *stream) Send(e *pb.ResultEvent) error {
+	s.events = append(s.events, e)
+	return nil
+}
+
+func Test_StreamResultsFollowWaitsForLateResults(t *testing.T) {
+	srv := grpcapi.NewServer(nil, nil,
+		grpcapi.WithStore(&lateStore{}),
+		grpcapi.WithPollPeriod(10*time.Millisecond, 100*time.Millisecond),
+	)
+
+	out := &stream{}
+	require.NoError(t, srv.StreamResults(&pb.StreamResultsRequest{JobId: "job-1", Follow: true}, out))
+
+	var titles []string
+
+	for _, e := range out.events {
+		if entry := e.GetEntry(); entry != nil {
+			titles = append(titles, entry.GetTitle())
+		}
+	}
+
+	require.Equal(t, []string{"late"}, titles)
+}
@@ -0,0 +1,14 @@
+// Package httpjson writes the JSON responses of the HTTP endpoints.
+package httpjson
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Write sends v encoded as JSON with status.
+func Write(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -11,6 +11,8 @@ import (
 
 	"github.com/gosom/google-maps-scraper/runner"
 	"github.com/gosom/google-maps-scraper/runner/databaserunner"
+	"github.com/gosom/google-maps-scraper/runner/dryrunner"
+	"github.com/gosom/google-maps-scraper/runner/enrichrunner"
 	"github.com/joho/godotenv"
 )
 
@@ -36,7 +38,15 @@ func main() {
 		cancel()
 	}()
 
-	cfg := runner.ParseConfig()
+	cfg, err := runner.ParseConfig()
+	if err != nil {
+		cancel()
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%s\n\nrun with -h to see the available options\n", err)
+
+		os.Exit(2)
+	}
+
+	go cfg.WatchSecrets(ctx)
 
 	runnerInstance, err := runnerFactory(cfg)
 	if err != nil {
@@ -65,8 +75,13 @@ func main() {
 
 func runnerFactory(cfg *runner.Config) (runner.Runner, error) {
 	switch cfg.RunMode {
-	case runner.RunModeDatabase, runner.RunModeDatabaseProduce:
+	case runner.RunModeDatabase, runner.RunModeDatabaseProduce, runner.RunModeDatabaseReenrich,
+		runner.RunModeDatabaseCredentials, runner.RunModeDatabaseForget, runner.RunModeDatabaseAdmin:
 		return databaserunner.New(cfg)
+	case runner.RunModeDryRun:
+		return dryrunner.New(cfg)
+	case runner.RunModeEnrich:
+		return enrichrunner.New(cfg)
 	default:
 		return nil, fmt.Errorf("%w: %d", runner.ErrInvalidRunMode, cfg.RunMode)
 	}
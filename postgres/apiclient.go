@@ -3,118 +3,283 @@ package postgres
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"os"
+	"strconv"
 	"sync"
 	"time"
+
+	"github.com/gosom/scrapemate"
+)
+
+const (
+	// WebhookSignatureHeader carries the hex-encoded HMAC-SHA256 of the request.
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	// WebhookTimestampHeader carries the unix timestamp used in the signature.
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+)
+
+const (
+	// DefaultRevalidationInterval is the least time between two revalidation
+	// calls for the same user.
+	DefaultRevalidationInterval = 30 * time.Second
+	// revalidationTimeout bounds a revalidation call, retries included.
+	revalidationTimeout = 30 * time.Second
 )
 
 // APIClient handles HTTP API calls for revalidation and job completion.
 type APIClient struct {
-	revalidationURL  string
-	jobCompletionURL string
-	httpClient       *http.Client
-	revalidationMu   sync.Mutex
-	lastRevalidation map[string]time.Time
+	revalidationURL      string
+	jobCompletionURL     string
+	webhookSecret        string
+	httpClient           *http.Client
+	revalidationInterval time.Duration
+	revalidationMu       sync.Mutex
+	revalidations        map[string]*revalidation
+	// dropped counts the revalidation calls of each user dropped since the
+	// last root job of the user completed.
+	dropped map[string]int
+	// pending counts the revalidation calls made or waiting.
+	pending sync.WaitGroup
+}
+
+// revalidation is the state of the revalidation calls of a user.
+type revalidation struct {
+	last time.Time
+	// timer makes the call coalescing those asked for since last, nil when
+	// none was.
+	timer *time.Timer
+}
+
+// APIClientOption configures an APIClient.
+type APIClientOption func(*APIClient)
+
+// WithRevalidationInterval calls the revalidation API at most once per d for
+// each user.
+func WithRevalidationInterval(d time.Duration) APIClientOption {
+	return func(c *APIClient) {
+		c.revalidationInterval = d
+	}
 }
 
 // NewAPIClient creates a new APIClient with the given URLs.
-func NewAPIClient(revalidationURL, jobCompletionURL string) *APIClient {
-	return &APIClient{
-		revalidationURL:  revalidationURL,
-		jobCompletionURL: jobCompletionURL,
-		httpClient:       &http.Client{Timeout: 10 * time.Second},
-		lastRevalidation: make(map[string]time.Time),
+// Requests are signed when the WEBHOOK_SECRET environment variable is set.
+func NewAPIClient(revalidationURL, jobCompletionURL string, opts ...APIClientOption) *APIClient {
+	c := &APIClient{
+		revalidationURL:      revalidationURL,
+		jobCompletionURL:     jobCompletionURL,
+		webhookSecret:        os.Getenv("WEBHOOK_SECRET"),
+		httpClient:           &http.Client{Timeout: 10 * time.Second},
+		revalidationInterval: DefaultRevalidationInterval,
+		revalidations:        make(map[string]*revalidation),
+		dropped:              make(map[string]int),
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// SignWebhookPayload returns the signature for the given timestamp and body.
+// The signed message is "<timestamp>.<body>" so receivers can reject replays.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
 }
 
-// CallRevalidationAPI calls the revalidation API for the given userID.
-// Debounces calls: skips if called within 5 seconds for the same user.
-func (c *APIClient) CallRevalidationAPI(ctx context.Context, userID string) {
+// newWebhookRequest builds a JSON POST request, signing it if a secret is configured.
+func (c *APIClient) newWebhookRequest(ctx context.Context, url string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if c.webhookSecret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(WebhookTimestampHeader, timestamp)
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(c.webhookSecret, timestamp, body))
+	}
+
+	return req, nil
+}
+
+// CallRevalidationAPI asks the revalidation API to refresh the cache of
+// userID, at most once per revalidation interval: the first call goes out
+// right away, and those made until the interval elapses are coalesced into
+// one call at its end, so the last results are not missed. Calls run in the
+// background and are retried when the API cannot be reached or answers a
+// server error.
+func (c *APIClient) CallRevalidationAPI(_ context.Context, userID string) {
 	if c.revalidationURL == "" || userID == "" {
 		return
 	}
 
-	// Debounce: skip if called within 5 seconds for the same user
 	c.revalidationMu.Lock()
-	if last, ok := c.lastRevalidation[userID]; ok && time.Since(last) < 5*time.Second {
-		c.revalidationMu.Unlock()
-		return
+	defer c.revalidationMu.Unlock()
+
+	r := c.revalidations[userID]
+	if r == nil {
+		r = &revalidation{}
+		c.revalidations[userID] = r
 	}
-	c.lastRevalidation[userID] = time.Now()
-	c.revalidationMu.Unlock()
 
-	payload := map[string]string{"userId": userID}
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
+	// the coming call covers this one
+	if r.timer != nil {
 		return
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", c.revalidationURL, bytes.NewBuffer(jsonData))
-	if err != nil {
+	wait := c.revalidationInterval - time.Since(r.last)
+	if wait <= 0 {
+		r.last = time.Now()
+		c.pending.Add(1)
+
+		go c.revalidate(userID)
+
 		return
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	c.pending.Add(1)
+
+	r.timer = time.AfterFunc(wait, func() {
+		c.revalidationMu.Lock()
+		r.timer = nil
+		r.last = time.Now()
+		c.revalidationMu.Unlock()
+
+		c.revalidate(userID)
+	})
+}
+
+// FlushRevalidations makes the coalesced revalidation calls right away and
+// waits for all the calls to finish, or ctx to be done.
+func (c *APIClient) FlushRevalidations(ctx context.Context) error {
+	c.revalidationMu.Lock()
+
+	for userID, r := range c.revalidations {
+		if r.timer != nil && r.timer.Stop() {
+			r.timer = nil
+			r.last = time.Now()
+
+			go c.revalidate(userID)
+		}
+	}
 
-	resp, err := c.httpClient.Do(req)
+	c.revalidationMu.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		c.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// revalidate calls the revalidation API for userID, retrying failures.
+func (c *APIClient) revalidate(userID string) {
+	defer c.pending.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), revalidationTimeout)
+	defer cancel()
+
+	jsonData, err := json.Marshal(map[string]string{"userId": userID})
 	if err != nil {
 		return
 	}
-	defer resp.Body.Close()
+
+	if err := c.deliver(ctx, EndpointRevalidation, c.revalidationURL, jsonData); err != nil {
+		c.revalidationMu.Lock()
+		c.dropped[userID]++
+		c.revalidationMu.Unlock()
+
+		log := scrapemate.GetLoggerFromContext(ctx)
+		log.Error(fmt.Sprintf("revalidation of user %s: %v", userID, err))
+	}
 }
 
-// CallJobCompletionAPIAsync calls the job completion API asynchronously.
-func (c *APIClient) CallJobCompletionAPIAsync(ctx context.Context, jobID string, payload []byte) {
-	if c.jobCompletionURL == "" {
-		return
+// takeDropped returns how many revalidation calls of userID were dropped
+// since it was last asked.
+func (c *APIClient) takeDropped(userID string) int {
+	c.revalidationMu.Lock()
+	defer c.revalidationMu.Unlock()
+
+	n := c.dropped[userID]
+	delete(c.dropped, userID)
+
+	return n
+}
+
+// rootOwner returns the owner and organization of the root job of payload.
+func rootOwner(payload []byte) (ownerID, organizationID string, err error) {
+	var rawJSON string
+	if err := json.Unmarshal(payload, &rawJSON); err == nil {
+		payload = []byte(rawJSON)
 	}
 
-	go func() {
-		var rawJSON string
-		if err := json.Unmarshal(payload, &rawJSON); err == nil {
-			payload = []byte(rawJSON)
-		}
+	var jsonJob JSONJob
+	if err := json.Unmarshal(payload, &jsonJob); err != nil {
+		return "", "", fmt.Errorf("failed to unmarshal root job: %w", err)
+	}
 
-		var jsonJob JSONJob
-		if err := json.Unmarshal(payload, &jsonJob); err != nil {
-			return
-		}
+	ownerID, _ = jsonJob.Metadata["owner_id"].(string)
+	organizationID, _ = jsonJob.Metadata["organization_id"].(string)
 
-		var ownerID, organizationID string
-		if jsonJob.Metadata != nil {
-			if id, ok := jsonJob.Metadata["owner_id"].(string); ok {
-				ownerID = id
-			}
-			if id, ok := jsonJob.Metadata["organization_id"].(string); ok {
-				organizationID = id
-			}
-		}
+	return ownerID, organizationID, nil
+}
 
-		apiPayload := map[string]interface{}{
-			"jobId":          jobID,
-			"userId":         ownerID,
-			"organizationId": organizationID,
-		}
+// CallJobCompletionAPI calls the job completion API, with the results
+// summary and the changes since the previous run of the job when they are
+// not nil. Calls the API does not take are retried like the revalidation
+// ones, and a *DeliveryError is returned when they are dropped.
+func (c *APIClient) CallJobCompletionAPI(ctx context.Context, jobID string, payload []byte, summary *ResultsSummary, changes *ChangeSet) error {
+	if c.jobCompletionURL == "" {
+		return nil
+	}
 
-		jsonData, err := json.Marshal(apiPayload)
-		if err != nil {
-			return
-		}
+	ownerID, organizationID, err := rootOwner(payload)
+	if err != nil {
+		return err
+	}
 
-		req, err := http.NewRequestWithContext(context.Background(), "POST", c.jobCompletionURL, bytes.NewBuffer(jsonData))
-		if err != nil {
-			return
-		}
+	apiPayload := map[string]interface{}{
+		"jobId":          jobID,
+		"userId":         ownerID,
+		"organizationId": organizationID,
+	}
 
-		req.Header.Set("Content-Type", "application/json")
+	if summary != nil {
+		apiPayload["summary"] = summary
+	}
 
-		resp, err := c.httpClient.Do(req)
-		if err != nil {
-			return
-		}
-		defer resp.Body.Close()
-	}()
+	if changes != nil {
+		apiPayload["changes"] = changes
+	}
+
+	jsonData, err := json.Marshal(apiPayload)
+	if err != nil {
+		return err
+	}
+
+	return c.deliver(ctx, EndpointJobCompletion, c.jobCompletionURL, jsonData)
 }
 
 // GetRevalidationURL returns the revalidation URL.
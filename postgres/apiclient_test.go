@@ -0,0 +1,84 @@
+package postgres_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+func Test_CallRevalidationAPI_Coalesces(t *testing.T) {
+	var calls atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	c := postgres.NewAPIClient(srv.URL, "", postgres.WithRevalidationInterval(time.Hour))
+
+	for range 10 {
+		c.CallRevalidationAPI(context.Background(), "user-1")
+	}
+
+	c.CallRevalidationAPI(context.Background(), "user-2")
+
+	// the first call of each user went out, the others wait for the interval
+	require.Eventually(t, func() bool { return calls.Load() == 2 }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, c.FlushRevalidations(context.Background()))
+	require.Equal(t, int32(3), calls.Load())
+}
+
+func Test_CallRevalidationAPI_Retries(t *testing.T) {
+	var calls atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	before := postgres.WebhookDeliveries()[postgres.EndpointRevalidation]
+
+	c := postgres.NewAPIClient(srv.URL, "")
+	c.CallRevalidationAPI(context.Background(), "user-1")
+
+	require.NoError(t, c.FlushRevalidations(context.Background()))
+	require.Equal(t, int32(2), calls.Load())
+
+	after := postgres.WebhookDeliveries()[postgres.EndpointRevalidation]
+	require.Equal(t, before.Retried+1, after.Retried)
+}
+
+func Test_CallJobCompletionAPI_Dropped(t *testing.T) {
+	var calls atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	before := postgres.WebhookDeliveries()[postgres.EndpointJobCompletion]
+
+	c := postgres.NewAPIClient("", srv.URL)
+	err := c.CallJobCompletionAPI(context.Background(), "job-1", []byte(`{"metadata": {"owner_id": "user-1"}}`), nil, nil)
+
+	// refused calls are not tried again
+	var derr *postgres.DeliveryError
+	require.ErrorAs(t, err, &derr)
+	require.Equal(t, http.StatusUnauthorized, derr.StatusCode)
+	require.Equal(t, 1, derr.Attempts)
+	require.Equal(t, int32(1), calls.Load())
+
+	after := postgres.WebhookDeliveries()[postgres.EndpointJobCompletion]
+	require.Equal(t, before.Dropped+1, after.Dropped)
+}
@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gosom/scrapemate"
+)
+
+const (
+	archiveInterval = time.Hour
+	// archiveBatchSize is how many job trees a statement moves, so that the
+	// locks it takes stay short.
+	archiveBatchSize = 500
+)
+
+// archiveQuery moves the job trees whose root was created before $3 and
+// whose jobs are all done or failed, at most $4 of them, from gmaps_jobs to
+// gmaps_jobs_archive. Trees are moved whole: the status updates of a job
+// read its parent, and the reports and summaries walk the tree from its
+// root. Enrichment jobs have no parent and are trees of their own.
+const archiveQuery = `
+	WITH RECURSIVE tree AS (
+		SELECT id, id AS root FROM gmaps_jobs
+		WHERE parent_id IS NULL AND status IN ($1, $2) AND created_at < $3
+		UNION ALL
+		SELECT j.id, t.root FROM gmaps_jobs j JOIN tree t ON j.parent_id = t.id
+	), roots AS (
+		SELECT t.root FROM tree t JOIN gmaps_jobs j ON j.id = t.id
+		GROUP BY t.root
+		HAVING bool_and(j.status IN ($1, $2))
+		LIMIT $4
+	), moved AS (
+		DELETE FROM gmaps_jobs
+		WHERE id IN (SELECT id FROM tree WHERE root IN (SELECT root FROM roots))
+		RETURNING *
+	)
+	INSERT INTO gmaps_jobs_archive SELECT * FROM moved`
+
+// Archiver keeps gmaps_jobs small by moving the finished jobs older than a
+// retention to gmaps_jobs_archive, so that the SKIP LOCKED scans fetching
+// jobs do not slow down with millions of rows that will never run again.
+type Archiver struct {
+	db    *sql.DB
+	after time.Duration
+}
+
+// NewArchiver creates an Archiver of the jobs created more than after ago.
+// It does nothing until Run or Archive is called.
+func NewArchiver(db *sql.DB, after time.Duration) *Archiver {
+	return &Archiver{db: db, after: after}
+}
+
+// Run archives once an hour until ctx is done.
+func (a *Archiver) Run(ctx context.Context) {
+	ticker := time.NewTicker(archiveInterval)
+	defer ticker.Stop()
+
+	log := scrapemate.GetLoggerFromContext(ctx)
+
+	for {
+		n, err := a.Archive(ctx)
+
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Error(fmt.Sprintf("archiver: %v", err))
+		case n > 0:
+			log.Info(fmt.Sprintf("archiver: moved %d jobs to gmaps_jobs_archive", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Archive moves the jobs due to the archive, a batch of trees at a time,
+// and returns how many it moved.
+func (a *Archiver) Archive(ctx context.Context) (int64, error) {
+	cutoff := time.Now().UTC().Add(-a.after)
+
+	var total int64
+
+	for {
+		res, err := a.db.ExecContext(ctx, archiveQuery, statusDone, statusFailed, cutoff, archiveBatchSize)
+		if err != nil {
+			return total, err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+
+		total += n
+
+		if n == 0 {
+			return total, nil
+		}
+	}
+}
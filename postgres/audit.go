@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// saveAudit stores the audit of the listing of a company. A retried audit
+// replaces the result of its earlier attempt.
+func (p *provider) saveAudit(ctx context.Context, r *gmaps.AuditResult) error {
+	const q = `INSERT INTO gmb_audits
+		(search_id, owner_id, organization_id, siren, name, address, listed, title, link,
+		 rating, review_count, status, completeness, missing, audited_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, NULLIF($8, ''), NULLIF($9, ''),
+		 $10, $11, NULLIF($12, ''), $13, $14, $15)
+		ON CONFLICT (search_id) DO UPDATE SET
+			listed = EXCLUDED.listed, title = EXCLUDED.title, link = EXCLUDED.link,
+			rating = EXCLUDED.rating, review_count = EXCLUDED.review_count, status = EXCLUDED.status,
+			completeness = EXCLUDED.completeness, missing = EXCLUDED.missing, audited_at = EXCLUDED.audited_at`
+
+	_, err := p.db.ExecContext(ctx, q, r.SearchID, r.OwnerID, r.OrganizationID, r.Siren, r.Name, r.Address,
+		r.Listed, r.Title, r.Link, r.Rating, r.ReviewCount, r.Status, r.Completeness, r.Missing, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save the audit of %s: %w", r.Siren, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/entreprise"
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+var _ gmaps.Backfiller = (*provider)(nil)
+
+// Backfill fills the place_id and naf_code columns of the results after
+// afterID saved before they existed. The place id comes from the link of the
+// result, or from the raw data the place cache kept of it; the NAF code is
+// fetched from the GOUV register by SIREN.
+func (p *provider) Backfill(ctx context.Context, afterID int64, limit int) (int64, error) {
+	const q = `SELECT id, link, COALESCE(place_id, ''), COALESCE(naf_code, ''), COALESCE(societe_siren, '')
+		FROM results
+		WHERE id > $1
+		AND (place_id IS NULL OR (naf_code IS NULL AND COALESCE(societe_siren, '') <> ''))
+		ORDER BY id
+		LIMIT $2`
+
+	rows, err := p.db.QueryContext(ctx, q, afterID, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select results to backfill: %w", err)
+	}
+
+	type row struct {
+		id                        int64
+		link, placeID, naf, siren string
+	}
+
+	var batch []row
+
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.link, &r.placeID, &r.naf, &r.siren); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan result to backfill: %w", err)
+		}
+
+		batch = append(batch, r)
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(batch) == 0 {
+		return 0, nil
+	}
+
+	log := scrapemate.GetLoggerFromContext(ctx)
+	registers := p.registers
+	if registers == nil {
+		registers = entreprise.DefaultServices()
+	}
+
+	service := registers.Default()
+	filled := 0
+
+	for _, r := range batch {
+		placeID := ""
+		if r.placeID == "" {
+			placeID = p.backfillPlaceID(ctx, r.link)
+		}
+
+		var naf string
+		if r.naf == "" && r.siren != "" {
+			info, err := service.GetBySiren(ctx, r.siren)
+			if err != nil {
+				log.Error(fmt.Sprintf("backfill: NAF code of %s: %v", r.siren, err))
+			} else if info != nil {
+				naf = info.NafCode
+			}
+		}
+
+		if placeID == "" && naf == "" {
+			continue
+		}
+
+		q := fmt.Sprintf(`UPDATE results SET
+			place_id = COALESCE(place_id, NULLIF($2, '')),
+			naf_code = COALESCE(naf_code, NULLIF($3, '')),
+			%s
+			WHERE id = $1`,
+			setProvenance([]provenanceField{{column: "naf_code", cond: "naf_code IS NULL AND $3 <> ''", source: 4}}),
+		)
+
+		if _, err := p.db.ExecContext(ctx, q, r.id, placeID, naf, fieldSource("GOUV", time.Time{})); err != nil {
+			return 0, fmt.Errorf("failed to backfill result %d: %w", r.id, err)
+		}
+
+		filled++
+	}
+
+	last := batch[len(batch)-1].id
+
+	log.Info(fmt.Sprintf("backfill: %d of %d results filled up to id %d", filled, len(batch), last))
+
+	return last, nil
+}
+
+// backfillPlaceID returns the place id of the result at link: the one in the
+// link, or the one of the raw place data the place cache kept.
+func (p *provider) backfillPlaceID(ctx context.Context, link string) string {
+	if m := placeIDRe.FindStringSubmatch(link); m != nil {
+		return m[1]
+	}
+
+	const q = `SELECT data FROM place_cache WHERE key = $1`
+
+	var raw []byte
+
+	if err := p.db.QueryRowContext(ctx, q, PlaceKey(link)).Scan(&raw); err != nil {
+		// no copy, or no place cache table without -place-cache-ttl
+		return ""
+	}
+
+	entry, err := gmaps.EntryFromJSON(raw)
+	if err != nil {
+		return ""
+	}
+
+	return entry.DataID
+}
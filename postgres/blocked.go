@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// blockedFilter leaves out the search and place jobs while the backoff after
+// a page blocked by Google runs for the address of the worker; the
+// enrichment jobs do not load its pages. Jobs behind proxies are held back
+// by holdBlocked only when they land on a blocked upstream.
+const blockedFilter = `
+			AND payload_type NOT IN ('` + jobTypeSearch + `', '` + jobTypeFastSearch + `', '` + jobTypePlace + `')`
+
+// holdBlocked puts a search or place job back in the queue instead of
+// loading its page while gmaps.BlockBackoff runs for the scope of ctx, the
+// upstream it would go through, and tells whether it did.
+// A blocked job fails right away and comes back here when scrapemate retries
+// it, so no worker sleeps through the backoff.
+func (w *jobWrapper) holdBlocked(ctx context.Context) bool {
+	switch w.IJob.(type) {
+	case *gmaps.GmapJob, *gmaps.SearchJob, *gmaps.PlaceJob:
+	default:
+		return false
+	}
+
+	if gmaps.BlockBackoff(gmaps.BlockScopeFromContext(ctx)) <= 0 {
+		return false
+	}
+
+	q := `UPDATE gmaps_jobs SET status = $1 WHERE id = $2`
+
+	if err := execWithReason(context.Background(), w.provider.db, "requeued while google blocks pages", q, statusNew, w.GetID()); err != nil {
+		log := scrapemate.GetLoggerFromContext(ctx)
+		log.Error(fmt.Sprintf("jobWrapper.holdBlocked: failed to requeue job %s: %v", w.GetID(), err))
+
+		return false
+	}
+
+	w.requeued = true
+
+	return true
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ChatNotifier posts the start, completion and failure of root jobs to a
+// Slack or Discord incoming webhook: the one the job metadata gives in
+// notify_webhook, or else the global one. Jobs without either post nothing.
+type ChatNotifier struct {
+	webhookURL string
+	httpClient *http.Client
+}
+
+// NewChatNotifier creates a ChatNotifier posting to webhookURL the jobs whose
+// metadata gives no webhook; webhookURL may be empty.
+func NewChatNotifier(webhookURL string) *ChatNotifier {
+	return &ChatNotifier{
+		webhookURL: webhookURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NotifyJob posts n to the webhook of its job.
+func (c *ChatNotifier) NotifyJob(ctx context.Context, n *JobNotification) error {
+	target := n.ChatWebhook
+	if target == "" {
+		target = c.webhookURL
+	}
+
+	if target == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(chatPayload(target, ChatMessage(n)))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to chat webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("chat webhook answered %s", resp.Status)
+	}
+
+	return nil
+}
+
+// chatPayload is the body posting text to the webhook: Discord reads content
+// and Slack text.
+func chatPayload(webhookURL, text string) map[string]string {
+	if u, err := url.Parse(webhookURL); err == nil {
+		host := strings.ToLower(u.Hostname())
+		if host == "discord.com" || host == "discordapp.com" || strings.HasSuffix(host, ".discord.com") {
+			return map[string]string{"content": text}
+		}
+	}
+
+	return map[string]string{"text": text}
+}
+
+// ChatMessage is the text posted about n: the queries of the job and, once it
+// is done, its results summary, or why it failed.
+func ChatMessage(n *JobNotification) string {
+	switch n.Status {
+	case JobStarted:
+		return fmt.Sprintf("Scrape started: %s (job %s)", n.Name(), n.JobID)
+	case statusFailed:
+		return fmt.Sprintf("Scrape failed: %s (job %s): %s", n.Name(), n.JobID, n.Reason)
+	}
+
+	msg := fmt.Sprintf("Scrape completed: %s (job %s)", n.Name(), n.JobID)
+
+	if s := n.Summary; s != nil {
+		msg += fmt.Sprintf("\n%d places, %.1f%% with a website, %.1f%% with an email, %.1f%% with a SIREN, average rating %.2f",
+			s.Places, s.WithWebsitePct, s.WithEmailPct, s.WithSirenPct, s.AverageRating)
+	}
+
+	return msg
+}
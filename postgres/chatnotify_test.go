@@ -0,0 +1,48 @@
+package postgres_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+func Test_ChatNotifier(t *testing.T) {
+	var got []map[string]string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		got = append(got, body)
+	}))
+	defer srv.Close()
+
+	notifier := postgres.NewChatNotifier("")
+
+	n := &postgres.JobNotification{JobID: "job-1", Status: postgres.JobStarted, Queries: []string{"plombier paris"}}
+
+	// neither a global webhook nor one in the job metadata
+	require.NoError(t, notifier.NotifyJob(context.Background(), n))
+	require.Empty(t, got)
+
+	n.ChatWebhook = srv.URL
+	require.NoError(t, notifier.NotifyJob(context.Background(), n))
+
+	n.Status, n.Summary = "done", &postgres.ResultsSummary{Places: 12, WithWebsitePct: 75, AverageRating: 4.4}
+	require.NoError(t, postgres.NewChatNotifier(srv.URL).NotifyJob(context.Background(), &postgres.JobNotification{
+		JobID: "job-2", Status: "failed", Reason: "search batch has no queries",
+	}))
+	require.NoError(t, notifier.NotifyJob(context.Background(), n))
+
+	require.Equal(t, []map[string]string{
+		{"text": "Scrape started: plombier paris (job job-1)"},
+		{"text": "Scrape failed: job-2 (job job-2): search batch has no queries"},
+		{"text": "Scrape completed: plombier paris (job job-1)\n12 places, 75.0% with a website, 0.0% with an email, 0.0% with a SIREN, average rating 4.40"},
+	}, got)
+}
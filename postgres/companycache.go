@@ -0,0 +1,225 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/entreprise"
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+var (
+	postalCodeRe = regexp.MustCompile(`\b\d{5}\b`)
+	// pappersSirenRe matches the SIREN ending the Pappers company URLs.
+	pappersSirenRe = regexp.MustCompile(`-(\d{9})$`)
+)
+
+// CompanyCache keeps the company found for each business name, whoever looked
+// it up, so the SIREN and director lookups of a business are done once for
+// all owners. Rows are keyed by SIREN and found by normalized name; an
+// expired row is looked up again and refreshed.
+type CompanyCache struct {
+	db     *sql.DB
+	ttl    time.Duration
+	cipher *FieldCipher
+}
+
+// NewCompanyCache creates a CompanyCache serving companies fetched less than
+// ttl ago. The directors of the cached companies are encrypted with cipher,
+// and stored in clear when it is nil.
+func NewCompanyCache(db *sql.DB, ttl time.Duration, cipher *FieldCipher) *CompanyCache {
+	return &CompanyCache{
+		db:     db,
+		ttl:    ttl,
+		cipher: cipher,
+	}
+}
+
+// WithCompanyCache makes company jobs check c before the results of their
+// owner, and fills c with the companies they find.
+func WithCompanyCache(c *CompanyCache) ProviderOption {
+	return func(p *provider) {
+		p.companyCache = c
+	}
+}
+
+// CompanyKey returns the key a business is found under: its normalized name
+// and the postal code of its address, which tells apart businesses with the
+// same name in different towns.
+func CompanyKey(name, address string) string {
+	key := entreprise.NormalizeCompanyName(name)
+	if key == "" {
+		return ""
+	}
+
+	return key + "|" + postalCodeRe.FindString(address)
+}
+
+// Get returns the company of the business name at address if it was fetched
+// less than the TTL ago.
+func (c *CompanyCache) Get(ctx context.Context, name, address string) (*entreprise.CompanyInfo, bool, error) {
+	key := CompanyKey(name, address)
+	if key == "" {
+		return nil, false, nil
+	}
+
+	const q = `SELECT siren, dirigeants, forme, creation, cloture, link, diffusion
+		FROM company_cache
+		WHERE name_key = $1 AND fetched_at > $2
+		ORDER BY fetched_at DESC
+		LIMIT 1`
+
+	var (
+		info       entreprise.CompanyInfo
+		dirigeants string
+		diffusion  sql.NullBool
+	)
+
+	err := c.db.QueryRowContext(ctx, q, key, time.Now().UTC().Add(-c.ttl)).Scan(
+		&info.SocieteSiren, &dirigeants, &info.SocieteForme,
+		&info.SocieteCreation, &info.SocieteCloture, &info.SocieteLink, &diffusion,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read company cache: %w", err)
+	}
+
+	dirigeants, err = c.cipher.open(dirigeants)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read cached directors: %w", err)
+	}
+
+	if dirigeants != "" {
+		for _, d := range strings.Split(dirigeants, ",") {
+			info.SocieteDirigeants = append(info.SocieteDirigeants, strings.TrimSpace(d))
+		}
+	}
+
+	if diffusion.Valid {
+		v := diffusion.Bool
+		info.SocieteDiffusion = &v
+	}
+
+	return &info, true, nil
+}
+
+// Put stores the company found for the business name at address. Companies
+// without a SIREN are not stored.
+func (c *CompanyCache) Put(ctx context.Context, name, address string, info *entreprise.CompanyInfo) error {
+	key := CompanyKey(name, address)
+	if key == "" || info.SocieteSiren == "" {
+		return nil
+	}
+
+	const q = `INSERT INTO company_cache
+		(siren, name_key, dirigeants, forme, creation, cloture, link, diffusion, fetched_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (siren) DO UPDATE SET
+			name_key = EXCLUDED.name_key,
+			dirigeants = EXCLUDED.dirigeants,
+			forme = EXCLUDED.forme,
+			creation = EXCLUDED.creation,
+			cloture = EXCLUDED.cloture,
+			link = EXCLUDED.link,
+			diffusion = EXCLUDED.diffusion,
+			fetched_at = EXCLUDED.fetched_at`
+
+	dirigeants, err := c.sealDirectors(info.SocieteDirigeants)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.ExecContext(ctx, q,
+		info.SocieteSiren, key, dirigeants,
+		info.SocieteForme, info.SocieteCreation, info.SocieteCloture, info.SocieteLink,
+		info.SocieteDiffusion, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write company cache: %w", err)
+	}
+
+	return nil
+}
+
+// SetDirectors replaces the directors of the company siren.
+func (c *CompanyCache) SetDirectors(ctx context.Context, siren string, directors []string) error {
+	const q = `UPDATE company_cache SET dirigeants = $2 WHERE siren = $1`
+
+	dirigeants, err := c.sealDirectors(directors)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.db.ExecContext(ctx, q, siren, dirigeants); err != nil {
+		return fmt.Errorf("failed to update company cache: %w", err)
+	}
+
+	return nil
+}
+
+// sealDirectors encrypts the directors of a cached company. The cache is
+// shared by the organizations, so they are sealed with the key of none.
+func (c *CompanyCache) sealDirectors(directors []string) (string, error) {
+	sealed, err := c.cipher.seal("", strings.Join(directors, ","))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt cached directors: %w", err)
+	}
+
+	return sealed, nil
+}
+
+// cacheCompany stores the company found by the wrapped company job, unless it
+// came from a cache or the results already.
+func (w *jobWrapper) cacheCompany(result *gmaps.CompanyEnrichmentResult) {
+	job, ok := w.IJob.(*gmaps.CompanyJob)
+	if !ok || w.provider.companyCache == nil || result.Existing || result.SocieteSiren == "" {
+		return
+	}
+
+	info := &entreprise.CompanyInfo{
+		SocieteDirigeants: result.SocieteDirigeants,
+		SocieteForme:      result.SocieteForme,
+		SocieteCreation:   result.SocieteCreation,
+		SocieteCloture:    result.SocieteCloture,
+		SocieteSiren:      result.SocieteSiren,
+		SocieteLink:       result.SocieteLink,
+		SocieteDiffusion:  result.SocieteDiffusion,
+	}
+
+	w.provider.goBackground(func() {
+		if err := w.provider.companyCache.Put(context.Background(), job.CompanyName, job.Address, info); err != nil {
+			log := scrapemate.GetLoggerFromContext(context.Background())
+			log.Error(fmt.Sprintf("jobWrapper.cacheCompany: %v", err))
+		}
+	})
+}
+
+// cacheDirectors stores the directors found by the wrapped Pappers job with
+// the company of its URL.
+func (w *jobWrapper) cacheDirectors(result *gmaps.PappersEnrichmentResult) {
+	if w.provider.companyCache == nil || len(result.SocieteDirigeants) == 0 {
+		return
+	}
+
+	m := pappersSirenRe.FindStringSubmatch(w.GetURL())
+	if m == nil {
+		return
+	}
+
+	w.provider.goBackground(func() {
+		if err := w.provider.companyCache.SetDirectors(context.Background(), m[1], result.SocieteDirigeants); err != nil {
+			log := scrapemate.GetLoggerFromContext(context.Background())
+			log.Error(fmt.Sprintf("jobWrapper.cacheDirectors: %v", err))
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package postgres_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/entreprise"
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+func Test_CompanyKey(t *testing.T) {
+	a := postgres.CompanyKey("Boulangerie  Dupré & Fils", "12 Rue de la Paix, 75002 Paris")
+	b := postgres.CompanyKey("BOULANGERIE DUPRE ET FILS", "75002 Paris, France")
+
+	require.Equal(t, "BOULANGERIE DUPRE ET FILS|75002", a)
+	require.Equal(t, a, b)
+
+	// same name, another town
+	require.NotEqual(t, a, postgres.CompanyKey("Boulangerie Dupré & Fils", "3 Place du Marché, 69001 Lyon"))
+
+	require.Empty(t, postgres.CompanyKey("  ", "75002 Paris"))
+}
+
+func Test_CompanyCacheSealsDirectors(t *testing.T) {
+	c, err := postgres.NewFieldCipher(bytes.Repeat([]byte{7}, 32))
+	require.NoError(t, err)
+
+	fake, db := newFakeDB(t)
+	cache := postgres.NewCompanyCache(db, time.Hour, c)
+
+	info := &entreprise.CompanyInfo{SocieteSiren: "123456789", SocieteDirigeants: []string{"Jean Dupont", "Marie Curie"}}
+	require.NoError(t, cache.Put(context.Background(), "Boulangerie Dupont", "75002 Paris", info))
+
+	stored := fake.ran("INSERT INTO company_cache")
+	require.Len(t, stored, 1)
+
+	sealed, ok := stored[0][2].(string)
+	require.True(t, ok)
+	require.NotContains(t, sealed, "Dupont")
+
+	fake.on("FROM company_cache", func([]driver.Value) answer {
+		return answer{
+			columns: []string{"siren", "dirigeants", "forme", "creation", "cloture", "link", "diffusion"},
+			rows:    [][]driver.Value{{"123456789", sealed, "", "", "", "", nil}},
+		}
+	})
+
+	got, ok, err := cache.Get(context.Background(), "Boulangerie Dupont", "75002 Paris")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, info.SocieteDirigeants, got.SocieteDirigeants)
+
+	// without the key the sealed directors are not read as plaintext
+	_, _, err = postgres.NewCompanyCache(db, time.Hour, nil).Get(context.Background(), "Boulangerie Dupont", "75002 Paris")
+	require.ErrorIs(t, err, postgres.ErrNoFieldCipher)
+}
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gosom/scrapemate"
+	"github.com/playwright-community/playwright-go"
+
+	"github.com/gosom/google-maps-scraper/browserpool"
+)
+
+// maxCrashRequeues is how many times a job is put back in the queue after
+// the browser crashed under it, before it fails like any other job.
+const maxCrashRequeues = 2
+
+var crashRequeues = struct {
+	mu      sync.Mutex
+	counts  map[string]int64
+	limiter *browserpool.RequeueLimiter
+}{
+	counts:  make(map[string]int64),
+	limiter: browserpool.NewRequeueLimiter(maxCrashRequeues),
+}
+
+// CrashRequeues returns how many jobs were put back in the queue after a
+// browser crash ("requeued") and how many crashed too often and were left
+// to fail ("failed") since startup.
+func CrashRequeues() map[string]int64 {
+	crashRequeues.mu.Lock()
+	defer crashRequeues.mu.Unlock()
+
+	ans := make(map[string]int64, len(crashRequeues.counts))
+	for k, v := range crashRequeues.counts {
+		ans[k] = v
+	}
+
+	return ans
+}
+
+// allowCrashRequeue counts a crash of the job id and tells whether it may be
+// requeued once more.
+func allowCrashRequeue(id string) bool {
+	crashRequeues.mu.Lock()
+	defer crashRequeues.mu.Unlock()
+
+	if !crashRequeues.limiter.Allow(id) {
+		crashRequeues.counts["failed"]++
+
+		return false
+	}
+
+	crashRequeues.counts["requeued"]++
+
+	return true
+}
+
+// recoverCrash restarts the browser that crashed of kind while the job ran
+// in page and puts the job back in the queue, so it runs again in a fresh
+// browser instead of being marked failed. The returned response replaces the
+// one of the job: it is empty when the job was requeued, which Process then
+// skips.
+func (w *jobWrapper) recoverCrash(ctx context.Context, page playwright.Page, kind browserpool.CrashKind, resp scrapemate.Response) scrapemate.Response {
+	log := scrapemate.GetLoggerFromContext(ctx)
+	log.Error(fmt.Sprintf("jobWrapper.BrowserActions: %s crash running job %s: %v", kind, w.GetID(), resp.Error))
+
+	if err := w.provider.browserPool.Restart(page, kind); err != nil {
+		log.Error(fmt.Sprintf("jobWrapper.BrowserActions: failed to restart browser: %v", err))
+	}
+
+	if !allowCrashRequeue(w.GetID()) {
+		return resp
+	}
+
+	q := `UPDATE gmaps_jobs SET status = $1 WHERE id = $2`
+	reason := fmt.Sprintf("requeued after a %s crash", kind)
+
+	if err := execWithReason(context.Background(), w.provider.db, reason, q, statusNew, w.GetID()); err != nil {
+		log.Error(fmt.Sprintf("jobWrapper.BrowserActions: failed to requeue job %s: %v", w.GetID(), err))
+		return resp
+	}
+
+	w.requeued = true
+
+	return scrapemate.Response{}
+}
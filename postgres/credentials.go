@@ -0,0 +1,213 @@
+package postgres
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/entreprise"
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// credentialsCacheTTL is how long the credentials of an organization are
+// kept in memory before being read again, so that an update is picked up by
+// the running workers.
+const credentialsCacheTTL = 5 * time.Minute
+
+var _ gmaps.CredentialResolver = (*CredentialStore)(nil)
+
+// CredentialStore keeps the register credentials each organization brings in
+// the organization_credentials table, encrypted with AES-256-GCM.
+type CredentialStore struct {
+	db   *sql.DB
+	aead cipher.AEAD
+
+	mu    sync.Mutex
+	cache map[string]cachedCredentials
+}
+
+type cachedCredentials struct {
+	creds   entreprise.Credentials
+	ok      bool
+	expires time.Time
+}
+
+// ParseCredentialsKey decodes the base64 encoded 32 bytes key of a
+// CredentialStore.
+func ParseCredentialsKey(s string) ([]byte, error) {
+	return parseKey("credentials", s)
+}
+
+// parseKey decodes a base64 encoded 32 bytes AES-256 key, named after what
+// it encrypts in the errors.
+func parseKey(name, s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s key: %w", name, err)
+	}
+
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid %s key: %d bytes instead of 32", name, len(key))
+	}
+
+	return key, nil
+}
+
+// NewCredentialStore creates a CredentialStore encrypting with key, which must
+// be 32 bytes long.
+func NewCredentialStore(db *sql.DB, key []byte) (*CredentialStore, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid credentials key: %d bytes instead of 32", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CredentialStore{
+		db:    db,
+		aead:  aead,
+		cache: map[string]cachedCredentials{},
+	}, nil
+}
+
+// WithCredentialStore makes company jobs query the registers with the
+// credentials of their organization found in s.
+func WithCredentialStore(s *CredentialStore) ProviderOption {
+	return func(p *provider) {
+		p.credentials = s
+	}
+}
+
+// Put stores the credentials of organizationID, replacing the previous ones.
+func (s *CredentialStore) Put(ctx context.Context, organizationID string, c entreprise.Credentials) error {
+	data, err := s.encrypt(c)
+	if err != nil {
+		return err
+	}
+
+	const q = `INSERT INTO organization_credentials (organization_id, data, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (organization_id) DO UPDATE SET data = EXCLUDED.data, updated_at = NOW()`
+
+	if _, err := s.db.ExecContext(ctx, q, organizationID, data); err != nil {
+		return fmt.Errorf("failed to store credentials: %w", err)
+	}
+
+	s.forget(organizationID)
+
+	return nil
+}
+
+// Delete removes the credentials of organizationID, whose jobs then use the
+// global ones.
+func (s *CredentialStore) Delete(ctx context.Context, organizationID string) error {
+	const q = `DELETE FROM organization_credentials WHERE organization_id = $1`
+
+	if _, err := s.db.ExecContext(ctx, q, organizationID); err != nil {
+		return fmt.Errorf("failed to delete credentials: %w", err)
+	}
+
+	s.forget(organizationID)
+
+	return nil
+}
+
+// ResolveCredentials returns the credentials of organizationID, ok false when
+// it has none.
+func (s *CredentialStore) ResolveCredentials(ctx context.Context, organizationID string) (entreprise.Credentials, bool, error) {
+	s.mu.Lock()
+	cached, found := s.cache[organizationID]
+	s.mu.Unlock()
+
+	if found && time.Now().Before(cached.expires) {
+		return cached.creds, cached.ok, nil
+	}
+
+	const q = `SELECT data FROM organization_credentials WHERE organization_id = $1`
+
+	var (
+		creds entreprise.Credentials
+		ok    = true
+		data  []byte
+	)
+
+	err := s.db.QueryRowContext(ctx, q, organizationID).Scan(&data)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		ok = false
+	case err != nil:
+		return creds, false, fmt.Errorf("failed to read credentials: %w", err)
+	default:
+		creds, err = s.decrypt(data)
+		if err != nil {
+			return creds, false, fmt.Errorf("organization %s: %w", organizationID, err)
+		}
+	}
+
+	s.mu.Lock()
+	s.cache[organizationID] = cachedCredentials{
+		creds:   creds,
+		ok:      ok,
+		expires: time.Now().Add(credentialsCacheTTL),
+	}
+	s.mu.Unlock()
+
+	return creds, ok, nil
+}
+
+func (s *CredentialStore) forget(organizationID string) {
+	s.mu.Lock()
+	delete(s.cache, organizationID)
+	s.mu.Unlock()
+}
+
+// encrypt seals the JSON encoded c behind a random nonce.
+func (s *CredentialStore) encrypt(c entreprise.Credentials) ([]byte, error) {
+	plain, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return s.aead.Seal(nonce, nonce, plain, nil), nil
+}
+
+func (s *CredentialStore) decrypt(data []byte) (entreprise.Credentials, error) {
+	var c entreprise.Credentials
+
+	n := s.aead.NonceSize()
+	if len(data) < n {
+		return c, errors.New("credentials too short")
+	}
+
+	plain, err := s.aead.Open(nil, data[:n], data[n:], nil)
+	if err != nil {
+		return c, fmt.Errorf("failed to decrypt credentials: %w", err)
+	}
+
+	if err := json.Unmarshal(plain, &c); err != nil {
+		return c, fmt.Errorf("failed to decode credentials: %w", err)
+	}
+
+	return c, nil
+}
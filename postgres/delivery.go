@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Endpoints of the APIClient, as DeliveryError and WebhookDeliveries name
+// them.
+const (
+	EndpointRevalidation  = "revalidation"
+	EndpointJobCompletion = "job_completion"
+)
+
+const (
+	// deliveryAttempts is how many times a call is tried, waiting
+	// deliveryBackoff, then twice as long, between them.
+	deliveryAttempts = 3
+	deliveryBackoff  = time.Second
+)
+
+// DeliveryError tells why a call of the APIClient was dropped.
+type DeliveryError struct {
+	Endpoint string
+	// StatusCode is the status of the last answer, 0 when none came.
+	StatusCode int
+	Attempts   int
+	// Err is why the last attempt got no answer.
+	Err error
+}
+
+func (e *DeliveryError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s call dropped after %d attempts: %v", e.Endpoint, e.Attempts, e.Err)
+	}
+
+	return fmt.Sprintf("%s call dropped after %d attempts: answered %d %s", e.Endpoint, e.Attempts,
+		e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// DeliveryStats counts the calls made to an endpoint.
+type DeliveryStats struct {
+	Delivered int64 `json:"delivered"`
+	// Retried counts the calls delivered after failing at first, also
+	// counted in Delivered.
+	Retried int64 `json:"retried"`
+	Dropped int64 `json:"dropped"`
+}
+
+var deliveries = struct {
+	mu    sync.Mutex
+	stats map[string]DeliveryStats
+}{
+	stats: make(map[string]DeliveryStats),
+}
+
+// WebhookDeliveries returns the calls of the APIClients to each endpoint since
+// startup.
+func WebhookDeliveries() map[string]DeliveryStats {
+	deliveries.mu.Lock()
+	defer deliveries.mu.Unlock()
+
+	ans := make(map[string]DeliveryStats, len(deliveries.stats))
+	for k, v := range deliveries.stats {
+		ans[k] = v
+	}
+
+	return ans
+}
+
+// countDelivery counts a call to endpoint, delivered at the given attempt or
+// dropped when err is not nil.
+func countDelivery(endpoint string, attempt int, err error) {
+	deliveries.mu.Lock()
+	defer deliveries.mu.Unlock()
+
+	s := deliveries.stats[endpoint]
+
+	switch {
+	case err != nil:
+		s.Dropped++
+	case attempt > 1:
+		s.Delivered++
+		s.Retried++
+	default:
+		s.Delivered++
+	}
+
+	deliveries.stats[endpoint] = s
+}
+
+// deliver posts body to the url of endpoint. Calls getting no answer, a
+// server error or 429 are tried again; those answered another status than
+// 2xx are not, the API refused them. It returns a *DeliveryError when the
+// call is dropped.
+func (c *APIClient) deliver(ctx context.Context, endpoint, url string, body []byte) error {
+	backoff := deliveryBackoff
+
+	for attempt := 1; ; attempt++ {
+		status, err := c.post(ctx, url, body)
+		if err == nil && status >= 200 && status < 300 {
+			countDelivery(endpoint, attempt, nil)
+			return nil
+		}
+
+		retry := err != nil || status >= http.StatusInternalServerError || status == http.StatusTooManyRequests
+
+		if retry && attempt < deliveryAttempts {
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+			case <-time.After(backoff):
+				backoff *= 2
+				continue
+			}
+		}
+
+		derr := &DeliveryError{Endpoint: endpoint, StatusCode: status, Attempts: attempt, Err: err}
+		countDelivery(endpoint, attempt, derr)
+
+		return derr
+	}
+}
+
+// post posts body to url once and returns the status of the answer.
+func (c *APIClient) post(ctx context.Context, url string, body []byte) (int, error) {
+	req, err := c.newWebhookRequest(ctx, url, body)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// the connection is reused once the body is read
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	return resp.StatusCode, nil
+}
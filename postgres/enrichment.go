@@ -4,26 +4,49 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/gosom/google-maps-scraper/entreprise"
 	"github.com/gosom/google-maps-scraper/gmaps"
 	"github.com/gosom/scrapemate"
 )
 
-// pushEnrichmentJobs inserts enrichment jobs into the DB with parent_id = NULL.
-// It waits a short delay to let the batch result writer flush the place result first.
-func (p *provider) pushEnrichmentJobs(ctx context.Context, jobs []scrapemate.IJob) {
-	log := scrapemate.GetLoggerFromContext(ctx)
+// enrichmentDelay is how long enrichment jobs stay deferred, to let the
+// batch result writer flush the result row of their place first.
+const enrichmentDelay = 2 * time.Second
+
+// insertEnrichmentJobs inserts enrichment jobs with parent_id = NULL in tx,
+// the transaction marking the job creator that created them done, so that
+// they are never lost to a crash between the two. They are inserted deferred
+// and run once promoteDeferredJobs made them new. Their root_id is the root
+// job of creator, whose completion waits for them. A job that cannot be
+// encoded is left out rather than failing the transaction, and the job
+// creating it.
+func (p *provider) insertEnrichmentJobs(ctx context.Context, tx *sql.Tx, creator string, jobs []scrapemate.IJob) error {
+	const q = `INSERT INTO gmaps_jobs
+		(id, parent_id, priority, payload_type, payload, created_at, status, root_id)
+		VALUES
+		($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT DO NOTHING`
+
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	rootID, err := enrichmentRoot(ctx, tx, creator)
+	if err != nil {
+		return fmt.Errorf("failed to find the root job of enrichment jobs: %w", err)
+	}
 
-	time.Sleep(2 * time.Second)
+	log := scrapemate.GetLoggerFromContext(ctx)
 
 	for _, job := range jobs {
 		jsonJob, jobType, err := p.codecRegistry.EncodeJob(job)
 		if err != nil {
-			log.Error(fmt.Sprintf("pushEnrichmentJobs: failed to encode job: %v", err))
+			log.Error(fmt.Sprintf("insertEnrichmentJobs: skipping %T: %v", job, err))
 			continue
 		}
 
@@ -36,29 +59,64 @@ func (p *provider) pushEnrichmentJobs(ctx context.Context, jobs []scrapemate.IJo
 
 		payload, err := json.Marshal(jsonJob)
 		if err != nil {
-			log.Error(fmt.Sprintf("pushEnrichmentJobs: failed to marshal job: %v", err))
+			log.Error(fmt.Sprintf("insertEnrichmentJobs: skipping %T: %v", job, err))
 			continue
 		}
 
-		q := `INSERT INTO gmaps_jobs
-			(id, parent_id, priority, payload_type, payload, created_at, status)
-			VALUES
-			($1, $2, $3, $4, $5, $6, $7) ON CONFLICT DO NOTHING`
-
-		_, err = p.db.ExecContext(ctx, q,
+		_, err = tx.ExecContext(ctx, q,
 			jsonJob.ID,
 			nil, // no parent
 			jsonJob.Priority,
 			jobType,
 			payload,
 			time.Now().UTC(),
-			statusNew,
+			statusDeferred,
+			rootID,
 		)
 		if err != nil {
-			log.Error(fmt.Sprintf("pushEnrichmentJobs: failed to insert job: %v", err))
-			continue
+			return fmt.Errorf("failed to insert enrichment job: %w", err)
 		}
+	}
+
+	return nil
+}
 
+// enrichmentRoot returns the root job of the job id: its root_id when it is
+// an enrichment job itself, the top of its parents otherwise.
+func enrichmentRoot(ctx context.Context, tx *sql.Tx, id string) (string, error) {
+	const q = `SELECT parent_id, root_id FROM gmaps_jobs WHERE id = $1`
+
+	// a place job is at most two levels below its root, under the search
+	// of a search batch
+	for range 4 {
+		var parentID, rootID sql.NullString
+
+		if err := tx.QueryRowContext(ctx, q, id).Scan(&parentID, &rootID); err != nil {
+			return "", err
+		}
+
+		switch {
+		case rootID.Valid:
+			return rootID.String, nil
+		case !parentID.Valid:
+			return id, nil
+		}
+
+		id = parentID.String
+	}
+
+	return "", fmt.Errorf("job %s is too deep in its tree", id)
+}
+
+// promoteDeferredJobs makes the jobs deferred for longer than
+// enrichmentDelay new, whichever worker deferred them.
+func (p *provider) promoteDeferredJobs(ctx context.Context) {
+	const q = `UPDATE gmaps_jobs SET status = $1 WHERE status = $2 AND created_at < $3`
+
+	_, err := p.db.ExecContext(ctx, q, statusNew, statusDeferred, time.Now().UTC().Add(-enrichmentDelay))
+	if err != nil && ctx.Err() == nil {
+		log := scrapemate.GetLoggerFromContext(ctx)
+		log.Error(fmt.Sprintf("promoteDeferredJobs: %v", err))
 	}
 }
 
@@ -70,27 +128,34 @@ func (p *provider) updateResultEmails(ctx context.Context, result *gmaps.EmailEn
 		return
 	}
 
-	var q string
+	emails, err := p.cipher.sealAll(result.OrganizationID, result.Emails)
+	if err != nil {
+		log.Error(fmt.Sprintf("updateResultEmails: failed to encrypt: %v", err))
+		return
+	}
+
+	var idCond string
 	var args []interface{}
 
 	if result.OwnerID != "" && result.OrganizationID != "" {
-		q = `UPDATE results SET emails = $1, updated_at = NOW()
-			WHERE link = $2 AND (user_id = $3 OR organization_id = $4)
-			AND (emails IS NULL OR emails = '{}')`
-		args = []interface{}{result.Emails, result.PlaceLink, result.OwnerID, result.OrganizationID}
+		idCond = "(user_id = $4 OR organization_id = $5)"
+		args = []interface{}{emails, fieldSource("website", time.Time{}), result.PlaceLink, result.OwnerID, result.OrganizationID}
 	} else if result.OwnerID != "" {
-		q = `UPDATE results SET emails = $1, updated_at = NOW()
-			WHERE link = $2 AND user_id = $3
-			AND (emails IS NULL OR emails = '{}')`
-		args = []interface{}{result.Emails, result.PlaceLink, result.OwnerID}
+		idCond = "user_id = $4"
+		args = []interface{}{emails, fieldSource("website", time.Time{}), result.PlaceLink, result.OwnerID}
 	} else {
-		q = `UPDATE results SET emails = $1, updated_at = NOW()
-			WHERE link = $2 AND organization_id = $3
-			AND (emails IS NULL OR emails = '{}')`
-		args = []interface{}{result.Emails, result.PlaceLink, result.OrganizationID}
+		idCond = "organization_id = $4"
+		args = []interface{}{emails, fieldSource("website", time.Time{}), result.PlaceLink, result.OrganizationID}
 	}
 
-	_, err := p.db.ExecContext(ctx, q, args...)
+	q := fmt.Sprintf(`UPDATE results SET emails = $1, %s, updated_at = NOW()
+		WHERE link = $3 AND %s
+		AND (emails IS NULL OR emails = '{}')`,
+		setProvenance([]provenanceField{{column: "emails", source: 2}}),
+		idCond,
+	)
+
+	_, err = p.db.ExecContext(ctx, q, args...)
 	if err != nil {
 		log.Error(fmt.Sprintf("updateResultEmails: failed to update: %v", err))
 		return
@@ -99,11 +164,83 @@ func (p *provider) updateResultEmails(ctx context.Context, result *gmaps.EmailEn
 	p.apiClient.CallRevalidationAPI(ctx, result.OwnerID)
 }
 
+// updateResultTechnologies stores the products the website of a result runs
+// on. They replace the earlier ones, as sites change platforms.
+func (p *provider) updateResultTechnologies(ctx context.Context, result *gmaps.EmailEnrichmentResult) {
+	if len(result.Technologies) == 0 {
+		return
+	}
+
+	q := `UPDATE results SET technologies = $3, ` +
+		setProvenance([]provenanceField{{column: "technologies", source: 4}}) + `, updated_at = NOW()
+		WHERE link = $5 AND ` + ownerCond("user_id", "organization_id")
+
+	_, err := p.db.ExecContext(ctx, q, result.OwnerID, result.OrganizationID, result.Technologies,
+		fieldSource("website", time.Time{}), result.PlaceLink)
+	if err != nil {
+		log := scrapemate.GetLoggerFromContext(ctx)
+		log.Error(fmt.Sprintf("updateResultTechnologies: failed to update: %v", err))
+	}
+}
+
+// updateResultWhatsApp stores the number of the click-to-chat link of the
+// website of a result, unless the place gave one.
+func (p *provider) updateResultWhatsApp(ctx context.Context, result *gmaps.EmailEnrichmentResult) {
+	if result.WhatsApp == "" {
+		return
+	}
+
+	q := `UPDATE results SET whatsapp = $3, ` +
+		setProvenance([]provenanceField{{column: "whatsapp", source: 4}}) + `, updated_at = NOW()
+		WHERE link = $5 AND (whatsapp IS NULL OR whatsapp = '') AND ` + ownerCond("user_id", "organization_id")
+
+	_, err := p.db.ExecContext(ctx, q, result.OwnerID, result.OrganizationID, result.WhatsApp,
+		fieldSource("website", time.Time{}), result.PlaceLink)
+	if err != nil {
+		log := scrapemate.GetLoggerFromContext(ctx)
+		log.Error(fmt.Sprintf("updateResultWhatsApp: failed to update: %v", err))
+	}
+}
+
+// updateResultWebsiteHealth stores how the website of a result fared when
+// visited, replacing its earlier health.
+func (p *provider) updateResultWebsiteHealth(ctx context.Context, result *gmaps.EmailEnrichmentResult) {
+	if result.WebsiteHealth == nil {
+		return
+	}
+
+	raw, err := json.Marshal(result.WebsiteHealth)
+	if err != nil {
+		return
+	}
+
+	q := `UPDATE results SET website_health = $3::jsonb, ` +
+		setProvenance([]provenanceField{{column: "website_health", source: 4}}) + `, updated_at = NOW()
+		WHERE link = $5 AND ` + ownerCond("user_id", "organization_id")
+
+	_, err = p.db.ExecContext(ctx, q, result.OwnerID, result.OrganizationID, string(raw),
+		fieldSource("website", result.WebsiteHealth.CheckedAt), result.PlaceLink)
+	if err != nil {
+		log := scrapemate.GetLoggerFromContext(ctx)
+		log.Error(fmt.Sprintf("updateResultWebsiteHealth: failed to update: %v", err))
+	}
+}
+
 // updateResultCompanyData updates company/societe fields on an existing result row.
 func (p *provider) updateResultCompanyData(ctx context.Context, result *gmaps.CompanyEnrichmentResult) {
 	log := scrapemate.GetLoggerFromContext(ctx)
 
-	dirigeants := strings.Join(result.SocieteDirigeants, ",")
+	dirigeants, err := p.cipher.seal(result.OrganizationID, strings.Join(result.SocieteDirigeants, ","))
+	if err != nil {
+		log.Error(fmt.Sprintf("updateResultCompanyData: failed to encrypt: %v", err))
+		return
+	}
+
+	candidate, err := companyCandidate(p.cipher, result)
+	if err != nil {
+		log.Error(fmt.Sprintf("updateResultCompanyData: failed to encrypt: %v", err))
+		return
+	}
 
 	var idCond string
 	var args []interface{}
@@ -119,6 +256,11 @@ func (p *provider) updateResultCompanyData(ctx context.Context, result *gmaps.Co
 		args = []interface{}{result.PlaceLink, result.OrganizationID}
 	}
 
+	siren := entreprise.NormalizeSiren(result.SocieteSiren)
+	if siren != "" {
+		p.checkSirenMismatch(ctx, siren, idCond, args)
+	}
+
 	nextIdx := len(args) + 1
 
 	q := fmt.Sprintf(`UPDATE results SET
@@ -129,6 +271,11 @@ func (p *provider) updateResultCompanyData(ctx context.Context, result *gmaps.Co
 		societe_cloture = CASE WHEN (societe_cloture IS NULL OR societe_cloture = '') AND $%d <> '' THEN $%d ELSE societe_cloture END,
 		societe_link = CASE WHEN (societe_link IS NULL OR societe_link = '') AND $%d <> '' THEN $%d ELSE societe_link END,
 		societe_diffusion = CASE WHEN $%d IS NOT NULL AND (societe_diffusion IS NULL OR societe_diffusion = false) THEN $%d ELSE societe_diffusion END,
+		siren_resolution = CASE WHEN (societe_siren IS NULL OR societe_siren = '') AND $%d <> '' THEN $%d ELSE siren_resolution END,
+		naf_code = CASE WHEN (naf_code IS NULL OR naf_code = '') AND $%d <> '' THEN $%d ELSE naf_code END,
+		company_match = CASE WHEN $%d = '' OR ($%d = 'no_match' AND COALESCE(societe_siren, '') <> '') THEN company_match ELSE $%d END,
+		company_candidate = CASE WHEN $%d = 'matched' THEN NULL WHEN $%d = 'no_match' AND COALESCE(societe_siren, '') = '' THEN NULLIF($%d, '')::jsonb ELSE company_candidate END,
+		%s,
 		updated_at = NOW()
 		WHERE link = $1 AND %s`,
 		nextIdx, nextIdx,
@@ -138,20 +285,31 @@ func (p *provider) updateResultCompanyData(ctx context.Context, result *gmaps.Co
 		nextIdx+4, nextIdx+4,
 		nextIdx+5, nextIdx+5,
 		nextIdx+6, nextIdx+6,
+		nextIdx+7, nextIdx+7,
+		nextIdx+8, nextIdx+8,
+		nextIdx+11, nextIdx+11, nextIdx+11,
+		nextIdx+11, nextIdx+11, nextIdx+12,
+		setProvenance(companyProvenance(nextIdx, nextIdx+9, nextIdx+10)),
 		idCond,
 	)
 
 	args = append(args,
 		dirigeants,
-		result.SocieteSiren,
+		siren,
 		result.SocieteForme,
 		result.SocieteCreation,
 		result.SocieteCloture,
 		result.SocieteLink,
 		result.SocieteDiffusion,
+		result.SirenResolution,
+		result.NafCode,
+		fieldSource(result.Source, result.FetchedAt),
+		fieldSource(result.DirectorsSource, result.FetchedAt),
+		result.Outcome,
+		candidate,
 	)
 
-	_, err := p.db.ExecContext(ctx, q, args...)
+	_, err = p.db.ExecContext(ctx, q, args...)
 	if err != nil {
 		log.Error(fmt.Sprintf("updateResultCompanyData: failed to update: %v", err))
 		return
@@ -160,6 +318,88 @@ func (p *provider) updateResultCompanyData(ctx context.Context, result *gmaps.Co
 	p.apiClient.CallRevalidationAPI(ctx, result.OwnerID)
 }
 
+// CompanyCandidate is the company_candidate of a result whose lookup ended
+// in no_match: the company the registers matched best, kept for review.
+type CompanyCandidate struct {
+	Siren  string  `json:"siren"`
+	Name   string  `json:"name"`
+	Source string  `json:"source,omitempty"`
+	Score  float64 `json:"score"`
+}
+
+// companyCandidate returns the company_candidate JSON of result, empty when
+// it rejected no company. The name is sealed with c like the directors: that
+// of a sole proprietorship is the name of its owner.
+func companyCandidate(c *FieldCipher, result *gmaps.CompanyEnrichmentResult) (string, error) {
+	if result.Outcome != gmaps.MatchOutcomeNoMatch || result.Rejected == nil {
+		return "", nil
+	}
+
+	name, err := c.seal(result.OrganizationID, result.Rejected.SocieteNom)
+	if err != nil {
+		return "", err
+	}
+
+	raw, err := json.Marshal(CompanyCandidate{
+		Siren:  entreprise.NormalizeSiren(result.Rejected.SocieteSiren),
+		Name:   name,
+		Source: result.Rejected.Source,
+		Score:  result.Rejected.MatchScore,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(raw), nil
+}
+
+// companyProvenance returns the columns updateResultCompanyData fills, given
+// the index first of its dirigeants argument, followed by the other columns,
+// and the indexes of the company and directors sources.
+func companyProvenance(first, company, directors int) []provenanceField {
+	filled := func(column string, idx int) string {
+		return fmt.Sprintf("(%s IS NULL OR %s = '') AND $%d <> ''", column, column, idx)
+	}
+
+	return []provenanceField{
+		{column: "societe_dirigeants", cond: filled("societe_dirigeants", first), source: directors},
+		{column: "societe_siren", cond: filled("societe_siren", first+1), source: company},
+		{column: "societe_forme", cond: filled("societe_forme", first+2), source: company},
+		{column: "societe_creation", cond: filled("societe_creation", first+3), source: company},
+		{column: "societe_cloture", cond: filled("societe_cloture", first+4), source: company},
+		{column: "societe_link", cond: filled("societe_link", first+5), source: company},
+		{
+			column: "societe_diffusion",
+			cond:   fmt.Sprintf("$%d IS NOT NULL AND (societe_diffusion IS NULL OR societe_diffusion = false)", first+6),
+			source: company,
+		},
+		{column: "naf_code", cond: filled("naf_code", first+8), source: company},
+	}
+}
+
+// checkSirenMismatch flags the result of the place, selected by idCond and
+// args like in updateResultCompanyData, when it already holds another SIREN
+// than siren. The stored SIREN is kept.
+func (p *provider) checkSirenMismatch(ctx context.Context, siren, idCond string, args []interface{}) {
+	q := fmt.Sprintf(`SELECT societe_siren FROM results
+		WHERE link = $1 AND %s AND COALESCE(societe_siren, '') NOT IN ('', $%d)
+		LIMIT 1`, idCond, len(args)+1)
+
+	var stored string
+
+	err := p.db.QueryRowContext(ctx, q, append(args, siren)...).Scan(&stored)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log := scrapemate.GetLoggerFromContext(ctx)
+			log.Error(fmt.Sprintf("checkSirenMismatch: failed to query: %v", err))
+		}
+
+		return
+	}
+
+	entreprise.FlagSirenMismatch(args[0].(string), stored, siren, "stored result and company lookup")
+}
+
 // updateResultPappers updates director fields from Pappers scraping.
 func (p *provider) updateResultPappers(ctx context.Context, result *gmaps.PappersEnrichmentResult) {
 	log := scrapemate.GetLoggerFromContext(ctx)
@@ -168,35 +408,37 @@ func (p *provider) updateResultPappers(ctx context.Context, result *gmaps.Papper
 		return
 	}
 
-	dirigeants := strings.Join(result.SocieteDirigeants, ",")
+	dirigeants, err := p.cipher.seal(result.OrganizationID, strings.Join(result.SocieteDirigeants, ","))
+	if err != nil {
+		log.Error(fmt.Sprintf("updateResultPappers: failed to encrypt: %v", err))
+		return
+	}
 
-	var q string
+	var idCond string
 	var args []interface{}
 
 	if result.OwnerID != "" && result.OrganizationID != "" {
-		q = `UPDATE results SET
-			societe_dirigeants = $1,
-			updated_at = NOW()
-			WHERE link = $2 AND (user_id = $3 OR organization_id = $4)
-			AND (societe_dirigeants IS NULL OR societe_dirigeants = '')`
-		args = []interface{}{dirigeants, result.PlaceLink, result.OwnerID, result.OrganizationID}
+		idCond = "(user_id = $4 OR organization_id = $5)"
+		args = []interface{}{dirigeants, fieldSource("Pappers", time.Time{}), result.PlaceLink, result.OwnerID, result.OrganizationID}
 	} else if result.OwnerID != "" {
-		q = `UPDATE results SET
-			societe_dirigeants = $1,
-			updated_at = NOW()
-			WHERE link = $2 AND user_id = $3
-			AND (societe_dirigeants IS NULL OR societe_dirigeants = '')`
-		args = []interface{}{dirigeants, result.PlaceLink, result.OwnerID}
+		idCond = "user_id = $4"
+		args = []interface{}{dirigeants, fieldSource("Pappers", time.Time{}), result.PlaceLink, result.OwnerID}
 	} else {
-		q = `UPDATE results SET
-			societe_dirigeants = $1,
-			updated_at = NOW()
-			WHERE link = $2 AND organization_id = $3
-			AND (societe_dirigeants IS NULL OR societe_dirigeants = '')`
-		args = []interface{}{dirigeants, result.PlaceLink, result.OrganizationID}
+		idCond = "organization_id = $4"
+		args = []interface{}{dirigeants, fieldSource("Pappers", time.Time{}), result.PlaceLink, result.OrganizationID}
 	}
 
-	_, err := p.db.ExecContext(ctx, q, args...)
+	q := fmt.Sprintf(`UPDATE results SET
+		societe_dirigeants = $1,
+		%s,
+		updated_at = NOW()
+		WHERE link = $3 AND %s
+		AND (societe_dirigeants IS NULL OR societe_dirigeants = '')`,
+		setProvenance([]provenanceField{{column: "societe_dirigeants", source: 2}}),
+		idCond,
+	)
+
+	_, err = p.db.ExecContext(ctx, q, args...)
 	if err != nil {
 		log.Error(fmt.Sprintf("updateResultPappers: failed to update: %v", err))
 		return
@@ -205,14 +447,32 @@ func (p *provider) updateResultPappers(ctx context.Context, result *gmaps.Papper
 	p.apiClient.CallRevalidationAPI(ctx, result.OwnerID)
 }
 
-// isEnrichmentJob returns true if the job is an enrichment job (email, company, pappers).
+// copyProvenance records in entry the sources of the columns copied from the
+// stored result e: their source in e when it knows it.
+func (e *existingEnrichmentData) copyProvenance(entry *gmaps.Entry, columns ...string) {
+	if entry.Provenance == nil {
+		entry.Provenance = gmaps.Provenance{}
+	}
+
+	now := time.Now().UTC()
+
+	for _, column := range columns {
+		if src, ok := e.Provenance[column]; ok {
+			entry.Provenance[column] = src
+		} else {
+			entry.Provenance[column] = gmaps.FieldSource{Source: gmaps.SourceStoredResult, FetchedAt: now}
+		}
+	}
+}
+
+// isEnrichmentJob returns true if the job is an enrichment job (email, company, pappers, social).
 func isEnrichmentJob(job scrapemate.IJob) bool {
 	actualJob := job
 	if wrapper, ok := job.(*jobWrapper); ok {
 		actualJob = wrapper.IJob
 	}
 	switch actualJob.(type) {
-	case *gmaps.EmailExtractJob, *gmaps.CompanyJob, *gmaps.PappersJob:
+	case *gmaps.EmailExtractJob, *gmaps.CompanyJob, *gmaps.PappersJob, *gmaps.SocialJob:
 		return true
 	}
 	return false
@@ -245,6 +505,7 @@ type existingEnrichmentData struct {
 	SocieteCloture    string
 	SocieteLink       string
 	SocieteDiffusion  *bool
+	Provenance        gmaps.Provenance
 }
 
 // findExistingEnrichmentData looks up existing enrichment data by title+address
@@ -257,7 +518,7 @@ func (p *provider) findExistingEnrichmentData(ctx context.Context, title, addres
 	q := `SELECT
 		array_to_string(emails, ','),
 		societe_dirigeants, societe_siren, societe_forme,
-		societe_creation, societe_cloture, societe_link, societe_diffusion
+		societe_creation, societe_cloture, societe_link, societe_diffusion, provenance
 		FROM results
 		WHERE LOWER(TRIM(title)) = LOWER(TRIM($1))
 		AND LOWER(TRIM(address)) = LOWER(TRIM($2))
@@ -269,9 +530,10 @@ func (p *provider) findExistingEnrichmentData(ctx context.Context, title, addres
 
 	var emailsStr, dirigeants, siren, forme, creation, cloture, link sql.NullString
 	var diffusion sql.NullBool
+	var provenance []byte
 	err := p.db.QueryRowContext(ctx, q, title, address).Scan(
 		&emailsStr, &dirigeants, &siren, &forme,
-		&creation, &cloture, &link, &diffusion,
+		&creation, &cloture, &link, &diffusion, &provenance,
 	)
 	if err != nil {
 		return nil
@@ -281,11 +543,19 @@ func (p *provider) findExistingEnrichmentData(ctx context.Context, title, addres
 	hasData := false
 
 	if emailsStr.Valid && emailsStr.String != "" {
-		data.Emails = strings.Split(emailsStr.String, ",")
+		if data.Emails, err = p.cipher.openAll(strings.Split(emailsStr.String, ",")); err != nil {
+			scrapemate.GetLoggerFromContext(ctx).Error(fmt.Sprintf("findExistingEnrichmentData: %v", err))
+			return nil
+		}
 		hasData = true
 	}
 	if dirigeants.Valid && dirigeants.String != "" {
-		data.SocieteDirigeants = strings.Split(dirigeants.String, ",")
+		directors, err := p.cipher.open(dirigeants.String)
+		if err != nil {
+			scrapemate.GetLoggerFromContext(ctx).Error(fmt.Sprintf("findExistingEnrichmentData: %v", err))
+			return nil
+		}
+		data.SocieteDirigeants = strings.Split(directors, ",")
 		for i := range data.SocieteDirigeants {
 			data.SocieteDirigeants[i] = strings.TrimSpace(data.SocieteDirigeants[i])
 		}
@@ -311,6 +581,9 @@ func (p *provider) findExistingEnrichmentData(ctx context.Context, title, addres
 		v := diffusion.Bool
 		data.SocieteDiffusion = &v
 	}
+	if len(provenance) > 0 {
+		_ = json.Unmarshal(provenance, &data.Provenance)
+	}
 
 	if !hasData {
 		return nil
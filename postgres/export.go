@@ -0,0 +1,289 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gosom/scrapemate"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	// exportDelay leaves the result writer time to save its last batch,
+	// which it does at least every 10 seconds, before a finished job is
+	// exported.
+	exportDelay   = 30 * time.Second
+	exportTimeout = 5 * time.Minute
+	// maxSheetName is the longest sheet name Excel accepts.
+	maxSheetName = 31
+)
+
+var exportColumns = []string{
+	"Title", "Category", "Address", "Phones", "Emails", "Website", "Rating", "Reviews",
+	"SIREN", "Legal form", "Directors", "Employees", "Company created", "Company link",
+	"Lead status", "Tags", "Google Maps link",
+}
+
+// Exporter writes an Excel workbook with the results of each root job once it
+// is done, and stores where to download it in the export_path column of the
+// job.
+type Exporter struct {
+	db      *sql.DB
+	dir     string
+	baseURL string
+	cipher  *FieldCipher
+}
+
+// NewExporter creates an Exporter saving the workbooks in dir. When baseURL
+// is set, jobs get baseURL followed by the file name instead of the file path.
+// The results are decrypted with cipher.
+func NewExporter(db *sql.DB, dir, baseURL string, cipher *FieldCipher) *Exporter {
+	return &Exporter{
+		db:      db,
+		dir:     dir,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		cipher:  cipher,
+	}
+}
+
+// WithExporter exports root jobs with e when they are done.
+func WithExporter(e *Exporter) ProviderOption {
+	return func(p *provider) {
+		p.statusManager.onRootDone = append(p.statusManager.onRootDone, func(id string) {
+			p.goBackground(func() {
+				time.Sleep(exportDelay)
+
+				ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+				defer cancel()
+
+				log := scrapemate.GetLoggerFromContext(ctx)
+
+				location, err := e.Export(ctx, id)
+				if err != nil {
+					log.Error(fmt.Sprintf("failed to export job %s: %v", id, err))
+					return
+				}
+
+				log.Info(fmt.Sprintf("exported job %s to %s", id, location))
+			})
+		})
+	}
+}
+
+// Export writes the workbook of the root job id and returns where it can be
+// downloaded.
+func (e *Exporter) Export(ctx context.Context, id string) (string, error) {
+	name := id + ".xlsx"
+	if filepath.Base(name) != name {
+		return "", fmt.Errorf("invalid job id %q", id)
+	}
+
+	path := filepath.Join(e.dir, name)
+	tmp := path + ".tmp"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return "", err
+	}
+
+	defer os.Remove(tmp)
+
+	if err := ExportWorkbook(ctx, e.db, e.cipher, id, f); err != nil {
+		f.Close()
+		return "", err
+	}
+
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return "", err
+	}
+
+	location := path
+	if e.baseURL != "" {
+		location = e.baseURL + "/" + url.PathEscape(name)
+	}
+
+	_, err = e.db.ExecContext(ctx, `UPDATE gmaps_jobs SET export_path = $1 WHERE id = $2`, location, id)
+	if err != nil {
+		return "", fmt.Errorf("failed to store export path: %w", err)
+	}
+
+	return location, nil
+}
+
+// ExportWorkbook writes the results of the root job id to w as an Excel
+// workbook with one sheet per search query, decrypted with c.
+func ExportWorkbook(ctx context.Context, db *sql.DB, c *FieldCipher, id string, w io.Writer) error {
+	results, err := ListResults(ctx, db, c, NewResultsQuery(ResultFilter{JobID: id}).OrderBy(SortByID, false))
+	if err != nil {
+		return err
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	header, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Color: "FFFFFF"},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"1F4E78"}},
+	})
+	if err != nil {
+		return err
+	}
+
+	var (
+		sheets []string
+		rows   = map[string]int{}
+		names  = map[string]string{}
+	)
+
+	for i := range results {
+		r := &results[i]
+
+		sheet, ok := names[r.Query]
+		if !ok {
+			sheet = sheetName(r.Query, sheets)
+			names[r.Query] = sheet
+
+			if err := addSheet(f, sheet, len(sheets) == 0, header); err != nil {
+				return err
+			}
+
+			sheets = append(sheets, sheet)
+			rows[sheet] = 1
+		}
+
+		rows[sheet]++
+
+		cell, err := excelize.CoordinatesToCellName(1, rows[sheet])
+		if err != nil {
+			return err
+		}
+
+		if err := f.SetSheetRow(sheet, cell, exportRow(r)); err != nil {
+			return err
+		}
+	}
+
+	if len(sheets) == 0 {
+		if err := addSheet(f, "Results", true, header); err != nil {
+			return err
+		}
+	}
+
+	for sheet, n := range rows {
+		last, err := excelize.CoordinatesToCellName(len(exportColumns), n)
+		if err != nil {
+			return err
+		}
+
+		if err := f.AutoFilter(sheet, "A1:"+last, nil); err != nil {
+			return err
+		}
+	}
+
+	return f.Write(w)
+}
+
+// addSheet adds a sheet with the header row. The first sheet reuses the
+// default one of new workbooks.
+func addSheet(f *excelize.File, sheet string, first bool, headerStyle int) error {
+	if first {
+		if err := f.SetSheetName(f.GetSheetName(0), sheet); err != nil {
+			return err
+		}
+	} else if _, err := f.NewSheet(sheet); err != nil {
+		return err
+	}
+
+	if err := f.SetSheetRow(sheet, "A1", &exportColumns); err != nil {
+		return err
+	}
+
+	if err := f.SetRowStyle(sheet, 1, 1, headerStyle); err != nil {
+		return err
+	}
+
+	last, err := excelize.ColumnNumberToName(len(exportColumns))
+	if err != nil {
+		return err
+	}
+
+	if err := f.SetColWidth(sheet, "A", last, 22); err != nil {
+		return err
+	}
+
+	return f.SetPanes(sheet, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	})
+}
+
+func exportRow(r *Result) *[]any {
+	return &[]any{
+		r.Title, r.Category, r.Address,
+		strings.Join(r.Phones, ", "), strings.Join(r.Emails, ", "), r.Website,
+		r.ReviewRating, r.ReviewCount,
+		r.SocieteSiren, r.SocieteForme, strings.Join(r.SocieteDirigeants, ", "), r.SocieteEffectif,
+		r.SocieteCreation, r.SocieteLink,
+		string(r.LeadStatus), strings.Join(r.Tags, ", "), r.Link,
+	}
+}
+
+// sheetName turns a query into a sheet name Excel accepts that is not one of
+// taken.
+func sheetName(query string, taken []string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return ' '
+		}
+
+		return r
+	}, query)
+
+	name = strings.Trim(strings.TrimSpace(name), "'")
+	if name == "" {
+		name = "Results"
+	}
+
+	base := truncate(name, maxSheetName)
+	name = base
+
+	for i := 2; isTaken(name, taken); i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		name = truncate(base, maxSheetName-len(suffix)) + suffix
+	}
+
+	return name
+}
+
+func isTaken(name string, taken []string) bool {
+	for _, t := range taken {
+		// Excel compares sheet names ignoring case
+		if strings.EqualFold(t, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+
+	return string(r[:n])
+}
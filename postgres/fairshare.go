@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// tenantExpr is the tenant of a job: the organization of the job, or its
+// owner when it has none. The gmaps_jobs_tenant_idx index of the README is on
+// this very expression.
+const tenantExpr = `COALESCE(
+				NULLIF(payload::jsonb -> 'metadata' ->> 'organization_id', ''),
+				payload::jsonb -> 'metadata' ->> 'owner_id',
+				''
+			)`
+
+// fairFetchQuery queues the next jobs taking turns between tenants. Within a
+// priority, the first job of every tenant comes before the second job of any
+// of them, so an organization with a large backlog cannot hold every worker.
+//
+// A tenant with weight w gets w jobs per turn; tenants missing from the
+// weights ($4) get 1. Four times the batch size are ranked before locking,
+// so workers fetching at the same time skip the jobs another one locked
+// instead of coming back empty. No tenant has more of them, so only the
+// first ones of each tenant are ranked, read in order from the tenant index
+// instead of parsing and ranking every new job. Like fetchQuery, it takes
+// the conditions of the options in its %s verb.
+const fairFetchQuery = `
+	WITH tenants AS (
+		SELECT DISTINCT ` + tenantExpr + ` AS tenant
+		FROM gmaps_jobs
+		WHERE status = $2
+	), pending AS (
+		SELECT p.id, p.priority, p.created_at, t.tenant
+		FROM tenants t
+		CROSS JOIN LATERAL (
+			SELECT id, priority, created_at FROM gmaps_jobs
+			WHERE status = $2 AND ` + tenantExpr + ` = t.tenant%s
+			ORDER BY priority ASC, created_at ASC
+			LIMIT $3 * 4
+		) p
+	), ranked AS (
+		SELECT id, priority, created_at,
+			CEIL(
+				ROW_NUMBER() OVER (PARTITION BY tenant ORDER BY priority ASC, created_at ASC)
+				/ COALESCE(($4::jsonb ->> tenant)::numeric, 1)
+			) AS turn
+		FROM pending
+	), picked AS (
+		SELECT j.id FROM gmaps_jobs j
+		JOIN (
+			SELECT id, turn FROM ranked
+			ORDER BY priority ASC, turn ASC, created_at ASC
+			LIMIT $3 * 4
+		) c ON c.id = j.id
+		WHERE j.status = $2
+		ORDER BY j.priority ASC, c.turn ASC, j.created_at ASC
+		LIMIT $3
+		FOR UPDATE OF j SKIP LOCKED
+	), updated AS (
+		UPDATE gmaps_jobs
+		SET status = $1
+		WHERE id IN (SELECT id FROM picked)
+		RETURNING *
+	)
+	SELECT id, payload_type, payload from updated ORDER by priority ASC, created_at ASC
+	`
+
+// WithFairScheduling makes the provider take turns between tenants when it
+// fetches jobs instead of running the oldest first. weights gives some
+// tenants, keyed by organization id or owner id, more jobs per turn; it may
+// be nil.
+func WithFairScheduling(weights map[string]float64) ProviderOption {
+	return func(p *provider) {
+		if weights == nil {
+			weights = map[string]float64{}
+		}
+
+		// a map of float64 always encodes
+		raw, _ := json.Marshal(weights)
+
+		p.tenantWeights = string(raw)
+		p.fairScheduling = true
+	}
+}
+
+// fetchQuery returns the query queueing the next batchSize jobs and its
+// arguments.
+func (p *provider) fetchQuery(batchSize int) (string, []any) {
+	q := fetchQuery
+	args := []any{statusQueued, statusNew, batchSize}
+
+	if p.fairScheduling {
+		q = fairFetchQuery
+		args = append(args, p.tenantWeights)
+	}
+
+	var filter string
+
+	if p.pacer != nil {
+		filter = pacedFilter
+	}
+
+	if p.throttled() {
+		filter += pressureFilter
+	}
+
+	if gmaps.BlockBackoff(nil) > 0 {
+		filter += blockedFilter
+	}
+
+	return fmt.Sprintf(q, filter), args
+}
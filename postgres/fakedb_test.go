@@ -0,0 +1,265 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// answer is what the fake database returns for a statement.
+type answer struct {
+	columns  []string
+	rows     [][]driver.Value
+	affected int64
+	err      error
+}
+
+// fakeDB is a database answering each statement with the first handler
+// whose key the statement contains, so the SQL paths run without Postgres.
+// Statements without a handler affect no row. It records the statements run
+// and their arguments.
+type fakeDB struct {
+	mu       sync.Mutex
+	handlers []fakeHandler
+	execs    []fakeExec
+	// down makes every statement and ping fail with it.
+	down error
+	// commitErr makes every commit fail with it.
+	commitErr error
+}
+
+type fakeHandler struct {
+	key string
+	fn  func(args []driver.Value) answer
+}
+
+type fakeExec struct {
+	query string
+	args  []driver.Value
+}
+
+func newFakeDB(t *testing.T) (*fakeDB, *sql.DB) {
+	t.Helper()
+
+	f := &fakeDB{}
+	db := sql.OpenDB(f)
+
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	return f, db
+}
+
+// on answers the statements containing key with fn.
+func (f *fakeDB) on(key string, fn func(args []driver.Value) answer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.handlers = append(f.handlers, fakeHandler{key: key, fn: fn})
+}
+
+// setDown makes the database fail with err, or answer again when nil.
+func (f *fakeDB) setDown(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.down = err
+}
+
+// failCommits makes the commits fail with err, or succeed again when nil.
+func (f *fakeDB) failCommits(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.commitErr = err
+}
+
+// ran returns the arguments of the statements run containing key.
+func (f *fakeDB) ran(key string) [][]driver.Value {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var ans [][]driver.Value
+
+	for _, e := range f.execs {
+		if strings.Contains(e.query, key) {
+			ans = append(ans, e.args)
+		}
+	}
+
+	return ans
+}
+
+func (f *fakeDB) answer(query string, named []driver.NamedValue) answer {
+	args := make([]driver.Value, len(named))
+	for i, nv := range named {
+		args[i] = nv.Value
+	}
+
+	f.mu.Lock()
+
+	if f.down != nil {
+		err := f.down
+		f.mu.Unlock()
+
+		return answer{err: err}
+	}
+
+	f.execs = append(f.execs, fakeExec{query: query, args: args})
+
+	var fn func([]driver.Value) answer
+
+	for _, h := range f.handlers {
+		if strings.Contains(query, h.key) {
+			fn = h.fn
+			break
+		}
+	}
+
+	f.mu.Unlock()
+
+	if fn == nil {
+		return answer{}
+	}
+
+	return fn(args)
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) {
+	return fakeConn{f}, nil
+}
+
+func (f *fakeDB) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{db: c.db, query: query}, nil
+}
+
+func (c fakeConn) Close() error {
+	return nil
+}
+
+func (c fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{c.db}, nil
+}
+
+func (c fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	if c.db.down != nil {
+		return nil, c.db.down
+	}
+
+	return fakeTx{c.db}, nil
+}
+
+func (c fakeConn) Ping(context.Context) error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	return c.db.down
+}
+
+// CheckNamedValue takes every argument as is, arrays included.
+func (c fakeConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	a := c.db.answer(query, args)
+	if a.err != nil {
+		return nil, a.err
+	}
+
+	return driver.RowsAffected(a.affected), nil
+}
+
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	a := c.db.answer(query, args)
+	if a.err != nil {
+		return nil, a.err
+	}
+
+	return &fakeRows{columns: a.columns, rows: a.rows}, nil
+}
+
+type fakeTx struct {
+	db *fakeDB
+}
+
+func (t fakeTx) Commit() error {
+	t.db.mu.Lock()
+	defer t.db.mu.Unlock()
+
+	return t.db.commitErr
+}
+
+func (fakeTx) Rollback() error {
+	return nil
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s fakeStmt) Close() error {
+	return nil
+}
+
+func (s fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return fakeConn{s.db}.ExecContext(context.Background(), s.query, named(args))
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return fakeConn{s.db}.QueryContext(context.Background(), s.query, named(args))
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	ans := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		ans[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+
+	return ans
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+
+	return nil
+}
@@ -0,0 +1,201 @@
+package postgres
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// fieldPrefix starts the encrypted values of the results, followed by the
+// organization whose key encrypted them and the nonce and ciphertext, so
+// that any reader can decrypt them and the plaintext of the rows saved
+// before encryption is told apart.
+const fieldPrefix = "enc:v1:"
+
+// FieldCipher encrypts the emails and directors of the results with
+// AES-256-GCM, with a key per organization derived from a master key. The
+// organization is authenticated with the value, so a value moved to the row
+// of another organization does not decrypt.
+type FieldCipher struct {
+	master []byte
+
+	mu   sync.Mutex
+	keys map[string]cipher.AEAD
+}
+
+// ParseFieldKey decodes the base64 encoded 32 bytes master key of a
+// FieldCipher.
+func ParseFieldKey(s string) ([]byte, error) {
+	return parseKey("encryption", s)
+}
+
+// NewFieldCipher creates a FieldCipher deriving the keys of the
+// organizations from master, which must be 32 bytes long.
+func NewFieldCipher(master []byte) (*FieldCipher, error) {
+	if len(master) != 32 {
+		return nil, fmt.Errorf("invalid encryption key: %d bytes instead of 32", len(master))
+	}
+
+	return &FieldCipher{master: master, keys: map[string]cipher.AEAD{}}, nil
+}
+
+// ErrNoFieldCipher is returned when an encrypted value is read without the
+// FieldCipher that can decrypt it.
+var ErrNoFieldCipher = errors.New("encrypted value read without PII_ENCRYPTION_KEY")
+
+// Seal encrypts v with the key of organizationID.
+func (c *FieldCipher) Seal(organizationID, v string) (string, error) {
+	aead, err := c.aead(organizationID)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(v), []byte(organizationID))
+
+	return fieldPrefix + base64.RawURLEncoding.EncodeToString([]byte(organizationID)) + ":" +
+		base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts v, returning it as it is when it is not encrypted.
+func (c *FieldCipher) Open(v string) (string, error) {
+	rest, ok := strings.CutPrefix(v, fieldPrefix)
+	if !ok {
+		return v, nil
+	}
+
+	rawOrg, rawSealed, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", fmt.Errorf("invalid encrypted value")
+	}
+
+	org, err := base64.RawURLEncoding.DecodeString(rawOrg)
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %w", err)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(rawSealed)
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %w", err)
+	}
+
+	aead, err := c.aead(string(org))
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("invalid encrypted value: too short")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	plain, err := aead.Open(nil, nonce, ciphertext, org)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt a value of %q: %w", org, err)
+	}
+
+	return string(plain), nil
+}
+
+// aead returns the cipher of the organization, whose key is the HMAC of its
+// id under the master key.
+func (c *FieldCipher) aead(organizationID string) (cipher.AEAD, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if aead, ok := c.keys[organizationID]; ok {
+		return aead, nil
+	}
+
+	mac := hmac.New(sha256.New, c.master)
+	mac.Write([]byte("results:" + organizationID))
+
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	c.keys[organizationID] = aead
+
+	return aead, nil
+}
+
+// seal encrypts v for the organization, or returns it as it is when c is
+// nil. Empty values stay empty, so that the queries looking for missing
+// values still find them.
+func (c *FieldCipher) seal(organizationID, v string) (string, error) {
+	if c == nil || v == "" || strings.HasPrefix(v, fieldPrefix) {
+		return v, nil
+	}
+
+	return c.Seal(organizationID, v)
+}
+
+// sealAll encrypts each of values, keeping them an array whose length the
+// queries can count.
+func (c *FieldCipher) sealAll(organizationID string, values []string) ([]string, error) {
+	if c == nil || len(values) == 0 {
+		return values, nil
+	}
+
+	ans := make([]string, len(values))
+
+	for i, v := range values {
+		var err error
+		if ans[i], err = c.seal(organizationID, v); err != nil {
+			return nil, err
+		}
+	}
+
+	return ans, nil
+}
+
+// open decrypts v. Plaintext values are returned as they are, and so are
+// those that do not decrypt, which readers show rather than failing; but an
+// encrypted value read when c is nil fails with ErrNoFieldCipher, so that a
+// reader missing the key is not mistaken for one reading plaintext.
+func (c *FieldCipher) open(v string) (string, error) {
+	if !strings.HasPrefix(v, fieldPrefix) {
+		return v, nil
+	}
+
+	if c == nil {
+		return "", ErrNoFieldCipher
+	}
+
+	plain, err := c.Open(v)
+	if err != nil {
+		return v, nil
+	}
+
+	return plain, nil
+}
+
+// openAll decrypts each of values in place.
+func (c *FieldCipher) openAll(values []string) ([]string, error) {
+	for i, v := range values {
+		var err error
+		if values[i], err = c.open(v); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
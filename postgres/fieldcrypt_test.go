@@ -0,0 +1,44 @@
+package postgres_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+func Test_FieldCipher(t *testing.T) {
+	c, err := postgres.NewFieldCipher(bytes.Repeat([]byte{7}, 32))
+	require.NoError(t, err)
+
+	sealed, err := c.Seal("org-1", "jean.dupont@gmail.com")
+	require.NoError(t, err)
+	require.NotContains(t, sealed, "dupont")
+
+	again, err := c.Seal("org-1", "jean.dupont@gmail.com")
+	require.NoError(t, err)
+	require.NotEqual(t, sealed, again)
+
+	plain, err := c.Open(sealed)
+	require.NoError(t, err)
+	require.Equal(t, "jean.dupont@gmail.com", plain)
+
+	// rows saved before encryption are read as they are
+	plain, err = c.Open("contact@boulangerie.fr")
+	require.NoError(t, err)
+	require.Equal(t, "contact@boulangerie.fr", plain)
+
+	// the value of org-1 claimed by org-2 does not decrypt
+	other, err := c.Seal("org-2", "x")
+	require.NoError(t, err)
+
+	forged := other[:strings.LastIndex(other, ":")] + sealed[strings.LastIndex(sealed, ":"):]
+	_, err = c.Open(forged)
+	require.Error(t, err)
+
+	_, err = postgres.NewFieldCipher([]byte("short"))
+	require.Error(t, err)
+}
@@ -0,0 +1,263 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Owner selects the data of a user, of an organization, or of both.
+type Owner struct {
+	OwnerID        string
+	OrganizationID string
+}
+
+// DeleteOptions tells DeleteOwnerData where the exports are and whether to
+// only report what it would delete.
+type DeleteOptions struct {
+	// Dirs are the export and report directories, whose files named after
+	// the jobs of the owner are removed.
+	Dirs   []string
+	DryRun bool
+}
+
+// DeletionReport is what DeleteOwnerData deleted, or would delete in a dry
+// run.
+type DeletionReport struct {
+	DryRun bool
+	// Tables lists the tables in the order they were cleared, skipping those
+	// the database does not have.
+	Tables []TableCount
+	Files  []string
+}
+
+// TableCount is how many rows of a table belong to the owner.
+type TableCount struct {
+	Table string
+	Rows  int64
+}
+
+// ownerCond matches the rows of the owner ($1) or organization ($2), an empty
+// id matching nothing.
+func ownerCond(userCol, orgCol string) string {
+	return fmt.Sprintf("(($1 <> '' AND %s = $1) OR ($2 <> '' AND %s = $2))", userCol, orgCol)
+}
+
+// ownerJobsQuery creates the owner_jobs table holding the ids of the jobs of
+// the owner, queued or archived, as read from their payload metadata.
+const ownerJobsQuery = `CREATE TEMP TABLE owner_jobs ON COMMIT DROP AS
+	SELECT id::text AS id FROM %s
+	WHERE (($1 <> '' AND payload::jsonb -> 'metadata' ->> 'owner_id' = $1)
+		OR ($2 <> '' AND payload::jsonb -> 'metadata' ->> 'organization_id' = $2))`
+
+// ownerCacheKeys are the temporary tables holding the keys of the places and
+// companies in the results of the owner and of no one else: the copies of
+// the shared caches only the owner asked for.
+var ownerCacheKeys = []struct {
+	table  string
+	column string
+	expr   string
+}{
+	{"owner_places", "key", "substring(link FROM '!1s(0x[0-9a-fA-F]+:0x[0-9a-fA-F]+)')"},
+	{"owner_sirens", "siren", "NULLIF(societe_siren, '')"},
+}
+
+// ownerCacheKeysQuery creates one of ownerCacheKeys from the results.
+const ownerCacheKeysQuery = `CREATE TEMP TABLE %[1]s ON COMMIT DROP AS
+	SELECT %[3]s AS %[2]s FROM results WHERE %[4]s
+	EXCEPT SELECT %[3]s FROM results WHERE NOT COALESCE(%[4]s, false)`
+
+// ownerDeletes are the tables holding data of the owners, with the rows of
+// the owner and the ids they take, in an order that keeps the references of
+// each table to the next ones. The tables of optional features are skipped
+// when they do not exist.
+var ownerDeletes = []struct {
+	table string
+	where string
+	args  func(Owner) []any
+}{
+	{"results", ownerCond("user_id", "organization_id"), byOwner},
+	{"review_queue", ownerCond("user_id", "organization_id"), byOwner},
+	{"rankings", ownerCond("owner_id", "organization_id"), byOwner},
+	{"gmb_audits", ownerCond("owner_id", "organization_id"), byOwner},
+	{"organization_credentials", "$1 <> '' AND organization_id = $1", byOrganization},
+	{"notification_recipients", ownerCond("owner_id", "owner_id"), byOwner},
+	{"place_changes", "root_id IN (SELECT root_id FROM monitor_runs WHERE " + ownerCond("owner_id", "organization_id") + ")", byOwner},
+	{"monitor_runs", ownerCond("owner_id", "organization_id"), byOwner},
+	{"place_observations", "search_id::text IN (SELECT id FROM owner_jobs)", byTemp},
+	{"gmaps_job_events", "job_id IN (SELECT id FROM owner_jobs)", byTemp},
+	{"gmaps_jobs", "id::text IN (SELECT id FROM owner_jobs)", byTemp},
+	{"gmaps_jobs_archive", "id::text IN (SELECT id FROM owner_jobs)", byTemp},
+	{"place_cache", "key IN (SELECT key FROM owner_places)", byTemp},
+	{"place_archive", "key IN (SELECT key FROM owner_places)", byTemp},
+	{"company_cache", "siren IN (SELECT siren FROM owner_sirens)", byTemp},
+}
+
+func byOwner(o Owner) []any        { return []any{o.OwnerID, o.OrganizationID} }
+func byOrganization(o Owner) []any { return []any{o.OrganizationID} }
+
+// byTemp is the arguments of the rows selected by a temporary table, see
+// ownerJobsQuery and ownerCacheKeys.
+func byTemp(Owner) []any { return nil }
+
+// DeleteOwnerData deletes the results, jobs, job events, review items,
+// credentials, monitoring snapshots and exported files of the owner, all
+// the rows in one transaction. With DryRun nothing is deleted: the rows are
+// counted by deleting them in a transaction that is rolled back.
+//
+// The place cache, place archive and company cache are shared by every
+// owner: only the places and companies no other owner has in their results
+// are deleted from them.
+func DeleteOwnerData(ctx context.Context, db *sql.DB, owner Owner, opts DeleteOptions) (DeletionReport, error) {
+	report := DeletionReport{DryRun: opts.DryRun}
+
+	if owner.OwnerID == "" && owner.OrganizationID == "" {
+		return report, errors.New("an owner or organization id is required")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return report, err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	exists := make(map[string]bool, len(ownerDeletes))
+
+	for _, d := range ownerDeletes {
+		var ok bool
+		if err := tx.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, d.table).Scan(&ok); err != nil {
+			return report, err
+		}
+
+		exists[d.table] = ok
+	}
+
+	jobsFrom := "gmaps_jobs"
+	if exists["gmaps_jobs_archive"] {
+		jobsFrom = "(SELECT id, payload FROM gmaps_jobs UNION ALL SELECT id, payload FROM gmaps_jobs_archive) j"
+	}
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(ownerJobsQuery, jobsFrom), owner.OwnerID, owner.OrganizationID); err != nil {
+		return report, fmt.Errorf("select the jobs: %w", err)
+	}
+
+	for _, k := range ownerCacheKeys {
+		var err error
+
+		if exists["results"] {
+			_, err = tx.ExecContext(ctx, fmt.Sprintf(ownerCacheKeysQuery, k.table, k.column, k.expr,
+				ownerCond("user_id", "organization_id")), owner.OwnerID, owner.OrganizationID)
+		} else {
+			_, err = tx.ExecContext(ctx, fmt.Sprintf(`CREATE TEMP TABLE %s (%s text) ON COMMIT DROP`, k.table, k.column))
+		}
+
+		if err != nil {
+			return report, fmt.Errorf("select the %s: %w", k.table, err)
+		}
+	}
+
+	jobs, err := ownerJobIDs(ctx, tx)
+	if err != nil {
+		return report, err
+	}
+
+	for _, d := range ownerDeletes {
+		if !exists[d.table] {
+			continue
+		}
+
+		res, err := tx.ExecContext(ctx, `DELETE FROM `+d.table+` WHERE `+d.where, d.args(owner)...)
+		if err != nil {
+			return report, fmt.Errorf("delete from %s: %w", d.table, err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return report, err
+		}
+
+		report.Tables = append(report.Tables, TableCount{Table: d.table, Rows: n})
+	}
+
+	report.Files, err = ownerFiles(opts.Dirs, jobs)
+	if err != nil {
+		return report, err
+	}
+
+	if opts.DryRun {
+		return report, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return report, err
+	}
+
+	for _, path := range report.Files {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+func ownerJobIDs(ctx context.Context, tx *sql.Tx) (map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM owner_jobs`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := map[string]bool{}
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids[id] = true
+	}
+
+	return ids, rows.Err()
+}
+
+// ownerFiles returns the files of dirs named after one of the jobs, the
+// workbooks and reports of the root jobs whatever their format.
+func ownerFiles(dirs []string, jobs map[string]bool) ([]string, error) {
+	var files []string
+
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range entries {
+			name := e.Name()
+			if e.IsDir() || !jobs[strings.TrimSuffix(name, filepath.Ext(name))] {
+				continue
+			}
+
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,72 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+func Test_DeleteOwnerDataDryRunCounts(t *testing.T) {
+	fake, db := newFakeDB(t)
+
+	// the database has every table but the archive of the jobs
+	fake.on("to_regclass", func(args []driver.Value) answer {
+		return answer{columns: []string{"ok"}, rows: [][]driver.Value{{args[0] != "gmaps_jobs_archive"}}}
+	})
+
+	counts := map[string]int64{
+		"results":       12,
+		"gmaps_jobs":    3,
+		"place_cache":   4,
+		"place_archive": 2,
+		"company_cache": 1,
+	}
+
+	for table, n := range counts {
+		fake.on("DELETE FROM "+table+" WHERE", func([]driver.Value) answer {
+			return answer{affected: n}
+		})
+	}
+
+	// the deletes select the jobs too
+	fake.on("SELECT id FROM owner_jobs", func([]driver.Value) answer {
+		return answer{columns: []string{"id"}, rows: [][]driver.Value{{"job-1"}}}
+	})
+
+	dir := t.TempDir()
+	export := filepath.Join(dir, "job-1.xlsx")
+	require.NoError(t, os.WriteFile(export, []byte("x"), 0o600))
+
+	report, err := postgres.DeleteOwnerData(context.Background(), db, postgres.Owner{OrganizationID: "org-1"},
+		postgres.DeleteOptions{Dirs: []string{dir}, DryRun: true})
+	require.NoError(t, err)
+
+	require.True(t, report.DryRun)
+	require.Equal(t, []string{export}, report.Files)
+
+	got := map[string]int64{}
+	for _, c := range report.Tables {
+		got[c.Table] = c.Rows
+	}
+
+	require.NotContains(t, got, "gmaps_jobs_archive")
+	require.Equal(t, int64(12), got["results"])
+	require.Equal(t, int64(3), got["gmaps_jobs"])
+
+	// the shared caches are reported too, for the keys of the owner only
+	require.Equal(t, int64(4), got["place_cache"])
+	require.Equal(t, int64(2), got["place_archive"])
+	require.Equal(t, int64(1), got["company_cache"])
+	require.Len(t, fake.ran("CREATE TEMP TABLE owner_places ON COMMIT DROP AS"), 1)
+	require.Len(t, fake.ran("CREATE TEMP TABLE owner_sirens ON COMMIT DROP AS"), 1)
+
+	// nothing is deleted from the disk
+	_, err = os.Stat(export)
+	require.NoError(t, err)
+}
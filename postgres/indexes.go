@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Index is an index the queries of the scraper need to stay fast once the
+// tables hold millions of rows.
+type Index struct {
+	Table string
+	// Create is the statement creating it, without locking the table.
+	Create string
+	// probe is a query it serves, which must not scan Table sequentially.
+	probe string
+}
+
+// probeID is the id the probes look up, valid whether the ids are stored as
+// text or as uuid.
+const probeID = "00000000-0000-0000-0000-000000000000"
+
+// RecommendedIndexes are the indexes of the job fetch and status queries, of
+// the results lookups by link and owner, and of the job trees walked by the
+// status updates, reports and summaries.
+var RecommendedIndexes = []Index{
+	{
+		Table: "gmaps_jobs",
+		Create: `CREATE INDEX CONCURRENTLY IF NOT EXISTS gmaps_jobs_pending_idx
+	ON gmaps_jobs (status, priority, created_at)
+	WHERE status IN ('new', 'queued', 'processing', 'deferred')`,
+		probe: `SELECT id FROM gmaps_jobs WHERE status = 'new' ORDER BY priority ASC, created_at ASC LIMIT 1`,
+	},
+	{
+		Table:  "results",
+		Create: `CREATE INDEX CONCURRENTLY IF NOT EXISTS results_link_user_id_idx ON results (link, user_id)`,
+		probe:  `SELECT link FROM results WHERE link = '' AND user_id = '` + probeID + `'`,
+	},
+	{
+		Table:  "gmaps_jobs",
+		Create: `CREATE INDEX CONCURRENTLY IF NOT EXISTS gmaps_jobs_parent_id_idx ON gmaps_jobs (parent_id)`,
+		probe:  `SELECT id FROM gmaps_jobs WHERE parent_id = '` + probeID + `'`,
+	},
+}
+
+// MissingIndexes returns the recommended indexes whose queries no index of
+// the database serves. Each query is planned with EXPLAIN and sequential
+// scans disabled: the planner only scans the table anyway when it has no
+// index to use, so indexes created under other names count as well.
+func MissingIndexes(ctx context.Context, db *sql.DB) ([]Index, error) {
+	var missing []Index
+
+	for _, idx := range RecommendedIndexes {
+		plan, err := explain(ctx, db, idx.probe)
+		if err != nil {
+			return nil, fmt.Errorf("explain %s: %w", idx.Table, err)
+		}
+
+		if strings.Contains(plan, "Seq Scan on "+idx.Table) {
+			missing = append(missing, idx)
+		}
+	}
+
+	return missing, nil
+}
+
+// explain returns the plan of q with sequential scans disabled.
+func explain(ctx context.Context, db *sql.DB, q string) (string, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, `SET LOCAL enable_seqscan = off`); err != nil {
+		return "", err
+	}
+
+	rows, err := tx.QueryContext(ctx, "EXPLAIN "+q)
+	if err != nil {
+		return "", err
+	}
+
+	defer rows.Close()
+
+	var plan strings.Builder
+
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+
+		plan.WriteString(line)
+		plan.WriteByte('\n')
+	}
+
+	return plan.String(), rows.Err()
+}
@@ -3,6 +3,8 @@ package postgres
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/gosom/google-maps-scraper/gmaps"
 	"github.com/gosom/scrapemate"
@@ -18,24 +20,157 @@ type JobCodec interface {
 	Decode(jsonJob *JSONJob) (scrapemate.IJob, error)
 }
 
+// Payload types of the jobs stored in gmaps_jobs. Every one has a codec in
+// the registry, and jobTypeOf is the only place mapping job structs to them.
+// Company jobs keep the name of the BODACC lookups they replaced, so rows
+// queued before still decode. Search batches are only inserted by other
+// services, see searchBatchJob.
+const (
+	jobTypeSearch      = "search"
+	jobTypeFastSearch  = "fast_search"
+	jobTypePlace       = "place"
+	jobTypeEmail       = "email"
+	jobTypeCompany     = "bodacc"
+	jobTypePappers     = "pappers"
+	jobTypeSocial      = "social"
+	jobTypeBackfill    = "backfill"
+	jobTypeSearchBatch = "search_batch"
+)
+
+// codecs lists the codec of every payload type.
+var codecs = [...]JobCodec{
+	&GmapJobCodec{},
+	&SearchJobCodec{},
+	&PlaceJobCodec{},
+	&EmailJobCodec{},
+	&CompanyJobCodec{},
+	&PappersJobCodec{},
+	&SocialJobCodec{},
+	&BackfillJobCodec{},
+	&SearchBatchJobCodec{},
+}
+
 // CodecRegistry manages job codecs by type.
 type CodecRegistry struct {
-	codecs map[string]JobCodec
+	codecs  map[string]JobCodec
+	lenient bool
+}
+
+// CodecRegistryOption configures a CodecRegistry.
+type CodecRegistryOption func(*CodecRegistry)
+
+// LenientDecoding makes DecodeJob fill the metadata keys that payloads queued
+// by older versions lack with their default instead of failing. Each key
+// filled is counted in DecodeWarnings.
+func LenientDecoding() CodecRegistryOption {
+	return func(r *CodecRegistry) {
+		r.lenient = true
+	}
 }
 
 // NewCodecRegistry creates a new registry with all supported codecs.
-func NewCodecRegistry() *CodecRegistry {
+func NewCodecRegistry(opts ...CodecRegistryOption) *CodecRegistry {
 	r := &CodecRegistry{
 		codecs: make(map[string]JobCodec),
 	}
-	r.Register(&GmapJobCodec{})
-	r.Register(&PlaceJobCodec{})
-	r.Register(&EmailJobCodec{})
-	r.Register(&CompanyJobCodec{})
-	r.Register(&PappersJobCodec{})
+	for _, codec := range codecs {
+		r.Register(codec)
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
 	return r
 }
 
+// metadataDefaults are the values lenient decoding uses for the metadata keys
+// a payload lacks, per job type. Numbers are float64 like the ones read from
+// JSON. Keys without a sensible default, such as the company name of bodacc
+// jobs, are not listed and stay required.
+var metadataDefaults = map[string]map[string]any{
+	jobTypeSearch: {
+		"max_depth":       float64(10),
+		"lang_code":       "en",
+		"extract_email":   false,
+		"owner_id":        "",
+		"organization_id": "",
+	},
+	jobTypeFastSearch: {
+		"lang_code":       "en",
+		"owner_id":        "",
+		"organization_id": "",
+	},
+	jobTypePlace: {
+		"extract_email":   false,
+		"owner_id":        "",
+		"organization_id": "",
+	},
+	jobTypeEmail: {
+		"owner_id":        "",
+		"organization_id": "",
+	},
+	jobTypeCompany: {
+		"owner_id":        "",
+		"organization_id": "",
+	},
+	jobTypePappers: {
+		"owner_id":        "",
+		"organization_id": "",
+	},
+	jobTypeSocial: {
+		"owner_id":        "",
+		"organization_id": "",
+	},
+}
+
+var decodeWarnings = struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}{
+	counts: make(map[string]int64),
+}
+
+// DecodeWarnings returns how many times lenient decoding filled a missing
+// metadata key since startup, per job type and key (e.g.
+// "place.organization_id").
+func DecodeWarnings() map[string]int64 {
+	decodeWarnings.mu.Lock()
+	defer decodeWarnings.mu.Unlock()
+
+	ans := make(map[string]int64, len(decodeWarnings.counts))
+	for k, v := range decodeWarnings.counts {
+		ans[k] = v
+	}
+
+	return ans
+}
+
+// fillDefaults sets the missing or null metadata keys of jsonJob to their
+// default.
+func fillDefaults(payloadType string, jsonJob *JSONJob) {
+	defaults := metadataDefaults[payloadType]
+	if len(defaults) == 0 {
+		return
+	}
+
+	if jsonJob.Metadata == nil {
+		jsonJob.Metadata = make(map[string]interface{}, len(defaults))
+	}
+
+	decodeWarnings.mu.Lock()
+	defer decodeWarnings.mu.Unlock()
+
+	for key, value := range defaults {
+		if jsonJob.Metadata[key] != nil {
+			continue
+		}
+
+		jsonJob.Metadata[key] = value
+		decodeWarnings.counts[payloadType+"."+key]++
+	}
+}
+
 // Register adds a codec to the registry.
 func (r *CodecRegistry) Register(codec JobCodec) {
 	r.codecs[codec.JobType()] = codec
@@ -47,27 +182,47 @@ func (r *CodecRegistry) GetCodec(jobType string) (JobCodec, bool) {
 	return codec, ok
 }
 
-// EncodeJob encodes a job using the appropriate codec.
-func (r *CodecRegistry) EncodeJob(job scrapemate.IJob) (*JSONJob, string, error) {
-	// Unwrap if wrapped
-	actualJob := job
+// jobTypeOf returns the payload type of job, or "" for jobs without a
+// codec.
+func jobTypeOf(job scrapemate.IJob) string {
 	if wrapper, ok := job.(*jobWrapper); ok {
-		actualJob = wrapper.IJob
+		job = wrapper.IJob
 	}
 
-	var jobType string
-	switch actualJob.(type) {
+	switch job.(type) {
 	case *gmaps.GmapJob:
-		jobType = "search"
+		return jobTypeSearch
+	case *gmaps.SearchJob:
+		return jobTypeFastSearch
 	case *gmaps.PlaceJob:
-		jobType = "place"
+		return jobTypePlace
 	case *gmaps.EmailExtractJob:
-		jobType = "email"
+		return jobTypeEmail
 	case *gmaps.CompanyJob:
-		jobType = "bodacc"
+		return jobTypeCompany
 	case *gmaps.PappersJob:
-		jobType = "pappers"
-	default:
+		return jobTypePappers
+	case *gmaps.SocialJob:
+		return jobTypeSocial
+	case *gmaps.BackfillJob:
+		return jobTypeBackfill
+	case *searchBatchJob:
+		return jobTypeSearchBatch
+	}
+
+	return ""
+}
+
+// EncodeJob encodes a job using the appropriate codec.
+func (r *CodecRegistry) EncodeJob(job scrapemate.IJob) (*JSONJob, string, error) {
+	// Unwrap if wrapped
+	actualJob := job
+	if wrapper, ok := job.(*jobWrapper); ok {
+		actualJob = wrapper.IJob
+	}
+
+	jobType := jobTypeOf(actualJob)
+	if jobType == "" {
 		return nil, "", fmt.Errorf("unsupported job type: %T", actualJob)
 	}
 
@@ -102,13 +257,17 @@ func (r *CodecRegistry) DecodeJob(payloadType string, payload []byte) (scrapemat
 		return nil, fmt.Errorf("invalid payload type: %s", payloadType)
 	}
 
+	if r.lenient {
+		fillDefaults(payloadType, &jsonJob)
+	}
+
 	return codec.Decode(&jsonJob)
 }
 
 // GmapJobCodec handles GmapJob encoding/decoding.
 type GmapJobCodec struct{}
 
-func (c *GmapJobCodec) JobType() string { return "search" }
+func (c *GmapJobCodec) JobType() string { return jobTypeSearch }
 
 func (c *GmapJobCodec) Encode(job scrapemate.IJob) (*JSONJob, error) {
 	j, ok := job.(*gmaps.GmapJob)
@@ -122,7 +281,7 @@ func (c *GmapJobCodec) Encode(job scrapemate.IJob) (*JSONJob, error) {
 		URL:        j.GetURL(),
 		URLParams:  j.GetURLParams(),
 		MaxRetries: j.GetMaxRetries(),
-		JobType:    "search",
+		JobType:    jobTypeSearch,
 		Metadata: map[string]interface{}{
 			"max_depth":       j.MaxDepth,
 			"lang_code":       j.LangCode,
@@ -133,6 +292,39 @@ func (c *GmapJobCodec) Encode(job scrapemate.IJob) (*JSONJob, error) {
 		},
 	}
 
+	if j.Country != "" {
+		jsonJob.Metadata["country"] = j.Country
+	}
+
+	if j.Domain != "" {
+		jsonJob.Metadata["domain"] = j.Domain
+	}
+
+	if j.Track != "" {
+		jsonJob.Metadata["track"] = j.Track
+	}
+
+	if j.Audit != nil {
+		jsonJob.Metadata["audit"] = auditMetadata(j.Audit)
+	}
+
+	if j.GeoCoordinates != "" {
+		jsonJob.Metadata["geo"] = j.GeoCoordinates
+		jsonJob.Metadata["zoom"] = j.Zoom
+	}
+
+	if j.MaxResults > 0 {
+		jsonJob.Metadata["max_results"] = j.MaxResults
+	}
+
+	if j.RelaxEmpty {
+		jsonJob.Metadata["relax_empty"] = true
+	}
+
+	if len(j.Relaxations) > 0 {
+		jsonJob.Metadata["relaxations"] = j.Relaxations
+	}
+
 	if j.ParentID != "" {
 		jsonJob.ParentID = &j.ParentID
 	}
@@ -172,7 +364,7 @@ func (c *GmapJobCodec) Decode(jsonJob *JSONJob) (scrapemate.IJob, error) {
 		parentID = *jsonJob.ParentID
 	}
 
-	return &gmaps.GmapJob{
+	job := &gmaps.GmapJob{
 		Job: scrapemate.Job{
 			ID:         jsonJob.ID,
 			ParentID:   parentID,
@@ -187,13 +379,181 @@ func (c *GmapJobCodec) Decode(jsonJob *JSONJob) (scrapemate.IJob, error) {
 		ExtractBodacc:  extractBodacc,
 		OwnerID:        ownerID,
 		OrganizationID: organizationID,
-	}, nil
+	}
+
+	if err := applyTargeting(job, jsonJob.Metadata); err != nil {
+		return nil, err
+	}
+
+	// searches without a limit, relaxation or tracked business leave them out
+	job.MaxResults, _ = getIntFromMetadata(jsonJob.Metadata, "max_results")
+	job.RelaxEmpty, _ = jsonJob.Metadata["relax_empty"].(bool)
+	job.Track, _ = jsonJob.Metadata["track"].(string)
+	job.Audit = auditFromMetadata(jsonJob.Metadata)
+
+	if raw, ok := jsonJob.Metadata["relaxations"].([]interface{}); ok {
+		for _, s := range raw {
+			if s, ok := s.(string); ok {
+				job.Relaxations = append(job.Relaxations, s)
+			}
+		}
+	}
+
+	return job, nil
+}
+
+// applyTargeting applies the optional per-job country, Google domain and geo
+// override from the metadata. The domain and the override rewrite the search
+// URL, so jobs created by the frontend only need to set "domain", "geo" and
+// "zoom".
+func applyTargeting(job *gmaps.GmapJob, metadata map[string]interface{}) error {
+	if job.URLParams == nil {
+		job.URLParams = map[string]string{"hl": job.LangCode}
+	}
+
+	if country, _ := metadata["country"].(string); country != "" {
+		gmaps.WithCountry(country)(job)
+	}
+
+	if domain, _ := metadata["domain"].(string); domain != "" {
+		if _, err := gmaps.ParseDomain(domain); err != nil {
+			return err
+		}
+
+		gmaps.WithDomain(domain)(job)
+	}
+
+	geo, _ := metadata["geo"].(string)
+	if geo == "" {
+		return nil
+	}
+
+	zoom, err := getIntFromMetadata(metadata, "zoom")
+	if err != nil {
+		return fmt.Errorf("failed to get zoom: %w", err)
+	}
+
+	if zoom < 1 || zoom > 21 {
+		return fmt.Errorf("invalid zoom level: %d", zoom)
+	}
+
+	job.GeoCoordinates = strings.ReplaceAll(geo, " ", "")
+	job.Zoom = zoom
+	job.URL = gmaps.SearchURLWithGeo(job.URL, job.GeoCoordinates, zoom)
+
+	return nil
+}
+
+// SearchJobCodec handles the SearchJob of fast mode. The URL parameters are
+// built from the search, so only the search is stored.
+type SearchJobCodec struct{}
+
+func (c *SearchJobCodec) JobType() string { return jobTypeFastSearch }
+
+func (c *SearchJobCodec) Encode(job scrapemate.IJob) (*JSONJob, error) {
+	j, ok := job.(*gmaps.SearchJob)
+	if !ok {
+		return nil, fmt.Errorf("expected *gmaps.SearchJob, got %T", job)
+	}
+
+	params := j.Params()
+
+	jsonJob := &JSONJob{
+		ID:         j.GetID(),
+		Priority:   j.GetPriority(),
+		URL:        j.GetURL(),
+		MaxRetries: j.GetMaxRetries(),
+		JobType:    jobTypeFastSearch,
+		Metadata: map[string]interface{}{
+			"query":           params.Query,
+			"lang_code":       params.Hl,
+			"lat":             params.Location.Lat,
+			"lon":             params.Location.Lon,
+			"zoom":            params.Location.ZoomLvl,
+			"radius":          params.Location.Radius,
+			"extract_email":   j.ExtractEmail,
+			"extract_bodacc":  j.ExtractBodacc,
+			"owner_id":        j.OwnerID,
+			"organization_id": j.OrganizationID,
+		},
+	}
+
+	if j.Country != "" {
+		jsonJob.Metadata["country"] = j.Country
+	}
+
+	if j.ParentID != "" {
+		jsonJob.ParentID = &j.ParentID
+	}
+
+	return jsonJob, nil
+}
+
+func (c *SearchJobCodec) Decode(jsonJob *JSONJob) (scrapemate.IJob, error) {
+	query, ok := jsonJob.Metadata["query"].(string)
+	if !ok {
+		return nil, fmt.Errorf("query is missing or not a string")
+	}
+
+	langCode, ok := jsonJob.Metadata["lang_code"].(string)
+	if !ok {
+		return nil, fmt.Errorf("lang_code is missing or not a string")
+	}
+
+	var location gmaps.MapLocation
+
+	for key, dst := range map[string]*float64{
+		"lat":    &location.Lat,
+		"lon":    &location.Lon,
+		"zoom":   &location.ZoomLvl,
+		"radius": &location.Radius,
+	} {
+		v, ok := jsonJob.Metadata[key].(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s is missing or not a number", key)
+		}
+
+		*dst = v
+	}
+
+	ownerID, ok := jsonJob.Metadata["owner_id"].(string)
+	if !ok {
+		return nil, fmt.Errorf("owner_id is missing or not a string")
+	}
+
+	organizationID, ok := jsonJob.Metadata["organization_id"].(string)
+	if !ok {
+		return nil, fmt.Errorf("organization_id is not a string")
+	}
+
+	extractEmail, _ := jsonJob.Metadata["extract_email"].(bool)
+	extractBodacc, _ := jsonJob.Metadata["extract_bodacc"].(bool)
+	country, _ := jsonJob.Metadata["country"].(string)
+
+	job := gmaps.NewSearchJob(&gmaps.MapSearchParams{Location: location, Query: query, Hl: langCode},
+		gmaps.WithSearchJobOwner(ownerID, organizationID),
+		gmaps.WithSearchJobCountry(country),
+		gmaps.WithSearchJobEnrichment(extractEmail, extractBodacc),
+	)
+
+	if jsonJob.ID != "" {
+		job.Job.ID = jsonJob.ID
+	}
+
+	job.Job.MaxRetries = jsonJob.MaxRetries
+	job.Job.Priority = jsonJob.Priority
+
+	if jsonJob.ParentID != nil {
+		job.Job.ParentID = *jsonJob.ParentID
+	}
+
+	return job, nil
 }
 
 // PlaceJobCodec handles PlaceJob encoding/decoding.
 type PlaceJobCodec struct{}
 
-func (c *PlaceJobCodec) JobType() string { return "place" }
+func (c *PlaceJobCodec) JobType() string { return jobTypePlace }
 
 func (c *PlaceJobCodec) Encode(job scrapemate.IJob) (*JSONJob, error) {
 	j, ok := job.(*gmaps.PlaceJob)
@@ -207,7 +567,7 @@ func (c *PlaceJobCodec) Encode(job scrapemate.IJob) (*JSONJob, error) {
 		URL:        j.GetURL(),
 		URLParams:  j.GetURLParams(),
 		MaxRetries: j.GetMaxRetries(),
-		JobType:    "place",
+		JobType:    jobTypePlace,
 		Metadata: map[string]interface{}{
 			"extract_email":   j.ExtractEmail,
 			"extract_bodacc":  j.ExtractBodacc,
@@ -216,6 +576,18 @@ func (c *PlaceJobCodec) Encode(job scrapemate.IJob) (*JSONJob, error) {
 		},
 	}
 
+	if j.Country != "" {
+		jsonJob.Metadata["country"] = j.Country
+	}
+
+	if j.Refresh {
+		jsonJob.Metadata["refresh"] = true
+	}
+
+	if j.Audit != nil {
+		jsonJob.Metadata["audit"] = auditMetadata(j.Audit)
+	}
+
 	if j.ParentID != "" {
 		jsonJob.ParentID = &j.ParentID
 	}
@@ -245,6 +617,9 @@ func (c *PlaceJobCodec) Decode(jsonJob *JSONJob) (scrapemate.IJob, error) {
 		parentID = *jsonJob.ParentID
 	}
 
+	country, _ := jsonJob.Metadata["country"].(string)
+	refresh, _ := jsonJob.Metadata["refresh"].(bool)
+
 	return &gmaps.PlaceJob{
 		Job: scrapemate.Job{
 			ID:         jsonJob.ID,
@@ -258,13 +633,38 @@ func (c *PlaceJobCodec) Decode(jsonJob *JSONJob) (scrapemate.IJob, error) {
 		ExtractBodacc:  extractBodacc,
 		OwnerID:        ownerID,
 		OrganizationID: organizationID,
+		Country:        country,
+		Refresh:        refresh,
+		Audit:          auditFromMetadata(jsonJob.Metadata),
 	}, nil
 }
 
+// auditMetadata encodes the company whose listing a job audits.
+func auditMetadata(a *gmaps.Audit) map[string]interface{} {
+	return map[string]interface{}{"siren": a.Siren, "name": a.Name, "address": a.Address}
+}
+
+// auditFromMetadata decodes the company whose listing a job audits, nil for
+// the jobs that audit none.
+func auditFromMetadata(metadata map[string]interface{}) *gmaps.Audit {
+	md, ok := metadata["audit"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var a gmaps.Audit
+
+	a.Siren, _ = md["siren"].(string)
+	a.Name, _ = md["name"].(string)
+	a.Address, _ = md["address"].(string)
+
+	return &a
+}
+
 // EmailJobCodec handles EmailExtractJob encoding/decoding.
 type EmailJobCodec struct{}
 
-func (c *EmailJobCodec) JobType() string { return "email" }
+func (c *EmailJobCodec) JobType() string { return jobTypeEmail }
 
 func (c *EmailJobCodec) Encode(job scrapemate.IJob) (*JSONJob, error) {
 	j, ok := job.(*gmaps.EmailExtractJob)
@@ -278,7 +678,7 @@ func (c *EmailJobCodec) Encode(job scrapemate.IJob) (*JSONJob, error) {
 		URL:        j.GetURL(),
 		URLParams:  j.GetURLParams(),
 		MaxRetries: j.GetMaxRetries(),
-		JobType:    "email",
+		JobType:    jobTypeEmail,
 		Metadata: map[string]interface{}{
 			"place_link":      j.PlaceLink,
 			"parent_id":       j.Job.ParentID,
@@ -333,10 +733,12 @@ func (c *EmailJobCodec) Decode(jsonJob *JSONJob) (scrapemate.IJob, error) {
 	return job, nil
 }
 
-// CompanyJobCodec handles CompanyJob encoding/decoding.
+// CompanyJobCodec handles CompanyJob encoding/decoding. Company jobs are the
+// BODACC enrichment of places (-bodacc), stored with the "bodacc" payload
+// type.
 type CompanyJobCodec struct{}
 
-func (c *CompanyJobCodec) JobType() string { return "bodacc" }
+func (c *CompanyJobCodec) JobType() string { return jobTypeCompany }
 
 func (c *CompanyJobCodec) Encode(job scrapemate.IJob) (*JSONJob, error) {
 	j, ok := job.(*gmaps.CompanyJob)
@@ -350,7 +752,7 @@ func (c *CompanyJobCodec) Encode(job scrapemate.IJob) (*JSONJob, error) {
 		URL:        j.GetURL(),
 		URLParams:  j.GetURLParams(),
 		MaxRetries: j.GetMaxRetries(),
-		JobType:    "bodacc",
+		JobType:    jobTypeCompany,
 		Metadata: map[string]interface{}{
 			"company_name":    j.CompanyName,
 			"address":         j.Address,
@@ -423,7 +825,7 @@ func (c *CompanyJobCodec) Decode(jsonJob *JSONJob) (scrapemate.IJob, error) {
 // PappersJobCodec handles PappersJob encoding/decoding.
 type PappersJobCodec struct{}
 
-func (c *PappersJobCodec) JobType() string { return "pappers" }
+func (c *PappersJobCodec) JobType() string { return jobTypePappers }
 
 func (c *PappersJobCodec) Encode(job scrapemate.IJob) (*JSONJob, error) {
 	j, ok := job.(*gmaps.PappersJob)
@@ -437,7 +839,7 @@ func (c *PappersJobCodec) Encode(job scrapemate.IJob) (*JSONJob, error) {
 		URL:        j.GetURL(),
 		URLParams:  j.GetURLParams(),
 		MaxRetries: j.GetMaxRetries(),
-		JobType:    "pappers",
+		JobType:    jobTypePappers,
 		Metadata: map[string]interface{}{
 			"owner_id":        j.OwnerID,
 			"organization_id": j.OrganizationID,
@@ -493,6 +895,133 @@ func (c *PappersJobCodec) Decode(jsonJob *JSONJob) (scrapemate.IJob, error) {
 	}, nil
 }
 
+// SocialJobCodec handles SocialJob encoding/decoding.
+type SocialJobCodec struct{}
+
+func (c *SocialJobCodec) JobType() string { return jobTypeSocial }
+
+func (c *SocialJobCodec) Encode(job scrapemate.IJob) (*JSONJob, error) {
+	j, ok := job.(*gmaps.SocialJob)
+	if !ok {
+		return nil, fmt.Errorf("expected *gmaps.SocialJob, got %T", job)
+	}
+
+	jsonJob := &JSONJob{
+		ID:         j.GetID(),
+		Priority:   j.GetPriority(),
+		URL:        j.GetURL(),
+		URLParams:  j.GetURLParams(),
+		MaxRetries: j.GetMaxRetries(),
+		JobType:    jobTypeSocial,
+		Metadata: map[string]interface{}{
+			"owner_id":        j.OwnerID,
+			"organization_id": j.OrganizationID,
+			"place_link":      j.PlaceLink,
+			"network":         j.Network,
+		},
+	}
+
+	if j.ParentID != "" {
+		jsonJob.ParentID = &j.ParentID
+	}
+
+	return jsonJob, nil
+}
+
+func (c *SocialJobCodec) Decode(jsonJob *JSONJob) (scrapemate.IJob, error) {
+	ownerID, ok := jsonJob.Metadata["owner_id"].(string)
+	if !ok {
+		return nil, fmt.Errorf("owner_id is missing or not a string")
+	}
+
+	organizationID, ok := jsonJob.Metadata["organization_id"].(string)
+	if !ok {
+		return nil, fmt.Errorf("organization_id is missing or not a string")
+	}
+
+	network, ok := jsonJob.Metadata["network"].(string)
+	if !ok {
+		return nil, fmt.Errorf("network is missing or not a string")
+	}
+
+	placeLink, _ := jsonJob.Metadata["place_link"].(string)
+
+	var parentID string
+	if jsonJob.ParentID != nil {
+		parentID = *jsonJob.ParentID
+	}
+
+	return &gmaps.SocialJob{
+		Job: scrapemate.Job{
+			ID:         jsonJob.ID,
+			ParentID:   parentID,
+			URL:        jsonJob.URL,
+			URLParams:  jsonJob.URLParams,
+			MaxRetries: jsonJob.MaxRetries,
+			Priority:   jsonJob.Priority,
+		},
+		OwnerID:        ownerID,
+		OrganizationID: organizationID,
+		PlaceLink:      placeLink,
+		Network:        network,
+	}, nil
+}
+
+// BackfillJobCodec handles BackfillJob encoding/decoding.
+type BackfillJobCodec struct{}
+
+func (c *BackfillJobCodec) JobType() string { return jobTypeBackfill }
+
+func (c *BackfillJobCodec) Encode(job scrapemate.IJob) (*JSONJob, error) {
+	j, ok := job.(*gmaps.BackfillJob)
+	if !ok {
+		return nil, fmt.Errorf("expected *gmaps.BackfillJob, got %T", job)
+	}
+
+	jsonJob := &JSONJob{
+		ID:         j.GetID(),
+		Priority:   j.GetPriority(),
+		URL:        j.GetURL(),
+		URLParams:  j.GetURLParams(),
+		MaxRetries: j.GetMaxRetries(),
+		JobType:    jobTypeBackfill,
+		Metadata: map[string]interface{}{
+			"after_id":   j.AfterID,
+			"batch_size": j.BatchSize,
+		},
+	}
+
+	if j.ParentID != "" {
+		jsonJob.ParentID = &j.ParentID
+	}
+
+	return jsonJob, nil
+}
+
+func (c *BackfillJobCodec) Decode(jsonJob *JSONJob) (scrapemate.IJob, error) {
+	afterID, err := getIntFromMetadata(jsonJob.Metadata, "after_id")
+	if err != nil {
+		return nil, err
+	}
+
+	// a row inserted by hand may leave the batch size out
+	batchSize, _ := getIntFromMetadata(jsonJob.Metadata, "batch_size")
+
+	job := gmaps.NewBackfillJob(int64(afterID), batchSize)
+	if jsonJob.ID != "" {
+		job.Job.ID = jsonJob.ID
+	}
+
+	job.Job.MaxRetries = jsonJob.MaxRetries
+	job.Job.Priority = jsonJob.Priority
+
+	if jsonJob.ParentID != nil {
+		job.Job.ParentID = *jsonJob.ParentID
+	}
+
+	return job, nil
+}
+
 // getIntFromMetadata extracts an integer from metadata (stored as float64 in JSON).
 func getIntFromMetadata(metadata map[string]interface{}, key string) (int, error) {
 	value, ok := metadata[key]
@@ -0,0 +1,259 @@
+package postgres_test
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/gosom/scrapemate"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+// roundTrip encodes job the way the provider stores it, decodes the payload
+// and encodes the decoded job again. Both encodings must be equal, so nothing
+// is lost or changes type on the way through the database.
+func roundTrip(t *testing.T, job scrapemate.IJob) scrapemate.IJob {
+	t.Helper()
+
+	registry := postgres.NewCodecRegistry()
+
+	first, jobType, err := registry.EncodeJob(job)
+	require.NoError(t, err)
+
+	payload, err := json.Marshal(first)
+	require.NoError(t, err)
+
+	decoded, err := registry.DecodeJob(jobType, payload)
+	require.NoError(t, err)
+
+	second, secondType, err := registry.EncodeJob(decoded)
+	require.NoError(t, err)
+	require.Equal(t, jobType, secondType)
+
+	// compare the stored form: metadata numbers are ints when encoded and
+	// float64 once read back
+	want, err := json.Marshal(first)
+	require.NoError(t, err)
+
+	got, err := json.Marshal(second)
+	require.NoError(t, err)
+
+	require.JSONEq(t, string(want), string(got))
+
+	return decoded
+}
+
+func Test_CodecRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		job  scrapemate.IJob
+	}{
+		{
+			name: "search",
+			job:  gmaps.NewGmapJob("", "fr", "plombier paris", "owner-1", "org-1", 10, true, true, "", 0),
+		},
+		{
+			name: "search with targeting",
+			job: gmaps.NewGmapJob("", "fr", "plombier", "owner-1", "org-1", 5, false, true, "48.8566, 2.3522", 13,
+				gmaps.WithCountry("FR")),
+		},
+		{
+			name: "search on a regional domain",
+			job: gmaps.NewGmapJob("", "de", "klempner berlin", "owner-1", "org-1", 5, false, false, "52.52,13.405", 13,
+				gmaps.WithCountry("de"), gmaps.WithDomain("google.de")),
+		},
+		{
+			name: "search with max results",
+			job: gmaps.NewGmapJob("", "fr", "boulangerie lyon", "owner-1", "org-1", 10, false, false, "", 0,
+				gmaps.WithMaxResults(50)),
+		},
+		{
+			name: "search relaxing empty results",
+			job: gmaps.NewGmapJob("", "fr", "plombier paris", "owner-1", "org-1", 10, false, false, "48.8566,2.3522", 14,
+				gmaps.WithRelaxEmpty()),
+		},
+		{
+			name: "search without organization",
+			job:  gmaps.NewGmapJob("", "en", "bakery", "owner-1", "", 1, false, false, "", 0),
+		},
+		{
+			name: "fast search",
+			job: gmaps.NewSearchJob(&gmaps.MapSearchParams{
+				Location: gmaps.MapLocation{Lat: 48.8566, Lon: 2.3522, ZoomLvl: 14, Radius: 5000},
+				Query:    "plombier",
+				Hl:       "fr",
+			}, gmaps.WithSearchJobOwner("owner-1", "org-1"), gmaps.WithSearchJobCountry("fr"),
+				gmaps.WithSearchJobEnrichment(true, true)),
+		},
+		{
+			name: "place",
+			job: gmaps.NewPlaceJob("parent-1", "fr", "https://www.google.com/maps/place/x", "owner-1", "org-1", true, false,
+				gmaps.WithBodaccExtraction(), gmaps.WithPlaceJobCountry("fr")),
+		},
+		{
+			name: "email",
+			job:  gmaps.NewEmailJob("parent-1", "https://www.google.com/maps/place/x", "https://example.com", "owner-1", "org-1"),
+		},
+		{
+			name: "company",
+			job: gmaps.NewCompanyJob("Boulangerie Martin", "1 rue de la Paix, 75002 Paris", "owner-1", "org-1",
+				"https://www.google.com/maps/place/x", gmaps.WithCompanyJobParentID("parent-1")),
+		},
+		{
+			name: "pappers",
+			job: gmaps.NewPappersJob("https://www.pappers.fr/entreprise/martin-123456789", "https://www.google.com/maps/place/x",
+				"owner-1", "org-1", gmaps.WithPappersJobParentID("parent-1")),
+		},
+		{
+			name: "social",
+			job: gmaps.NewSocialJob("https://www.instagram.com/boulangerie.martin/", gmaps.SocialInstagram,
+				"https://www.google.com/maps/place/x", "owner-1", "org-1", gmaps.WithSocialJobParentID("parent-1")),
+		},
+		{
+			name: "backfill",
+			job:  gmaps.NewBackfillJob(1234, 50),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			decoded := roundTrip(t, tc.job)
+			require.IsType(t, tc.job, decoded)
+			require.Equal(t, tc.job.GetID(), decoded.GetID())
+		})
+	}
+}
+
+func FuzzCodecRoundTrip(f *testing.F) {
+	f.Add("plombier paris", "fr", "owner-1", "org-1", "fr", "48.8566,2.3522", 10, 13, true)
+	f.Add("café & co", "en", "", "", "", "", 1, 0, false)
+
+	f.Fuzz(func(t *testing.T, query, lang, ownerID, orgID, country, geo string, depth, zoom int, flag bool) {
+		for _, s := range []string{query, lang, ownerID, orgID, country, geo} {
+			// JSON replaces invalid UTF-8, the database never sees it
+			if !utf8.ValidString(s) {
+				t.Skip()
+			}
+		}
+
+		// the zoom levels Google accepts, as the runners validate them
+		if zoom < 1 || zoom > 21 {
+			zoom = 0
+		}
+
+		link := "https://www.google.com/maps/place/" + query
+
+		jobs := []scrapemate.IJob{
+			gmaps.NewGmapJob("", lang, query, ownerID, orgID, depth, flag, !flag, geo, zoom, gmaps.WithCountry(country)),
+			gmaps.NewPlaceJob("parent", lang, link, ownerID, orgID, flag, false, gmaps.WithPlaceJobCountry(country)),
+			gmaps.NewEmailJob("parent", link, "https://"+query, ownerID, orgID),
+			gmaps.NewCompanyJob(query, geo, ownerID, orgID, link),
+			gmaps.NewPappersJob("https://www.pappers.fr/entreprise/"+query, link, ownerID, orgID),
+		}
+
+		for _, job := range jobs {
+			roundTrip(t, job)
+		}
+	})
+}
+
+func FuzzDecodeJob(f *testing.F) {
+	f.Add("search", []byte(`{"id":"1","url":"https://www.google.com/maps/search/a","metadata":{"max_depth":10,"lang_code":"en","extract_email":false,"owner_id":"o","organization_id":""}}`))
+	f.Add("place", []byte(`{"id":"1","metadata":{"extract_email":true,"owner_id":"o","organization_id":"g"}}`))
+	f.Add("email", []byte(`"{\"id\":\"1\",\"metadata\":{\"owner_id\":\"o\",\"organization_id\":\"g\"}}"`))
+	f.Add("bodacc", []byte(`{"metadata":{"company_name":1}}`))
+	f.Add("pappers", []byte(`{"metadata":null}`))
+	f.Add("backfill", []byte(`{"id":"1","metadata":{"after_id":0}}`))
+
+	registry := postgres.NewCodecRegistry()
+
+	f.Fuzz(func(t *testing.T, payloadType string, payload []byte) {
+		// malformed payloads must fail with an error, and what decodes must
+		// be storable again
+		job, err := registry.DecodeJob(payloadType, payload)
+		if err != nil {
+			return
+		}
+
+		_, _, err = registry.EncodeJob(job)
+		require.NoError(t, err)
+	})
+}
+
+func Test_LenientDecoding(t *testing.T) {
+	// a place job queued before organizations and email extraction existed
+	payload := []byte(`{"id":"1","url":"https://www.google.com/maps/place/x","metadata":{"owner_id":"owner-1"}}`)
+
+	_, err := postgres.NewCodecRegistry().DecodeJob("place", payload)
+	require.Error(t, err)
+
+	before := postgres.DecodeWarnings()
+
+	job, err := postgres.NewCodecRegistry(postgres.LenientDecoding()).DecodeJob("place", payload)
+	require.NoError(t, err)
+
+	place, ok := job.(*gmaps.PlaceJob)
+	require.True(t, ok)
+	require.Equal(t, "owner-1", place.OwnerID)
+	require.Equal(t, "", place.OrganizationID)
+	require.False(t, place.ExtractEmail)
+
+	after := postgres.DecodeWarnings()
+	require.Equal(t, before["place.organization_id"]+1, after["place.organization_id"])
+	require.Equal(t, before["place.extract_email"]+1, after["place.extract_email"])
+	require.Equal(t, before["place.owner_id"], after["place.owner_id"])
+
+	// keys without a default stay required
+	_, err = postgres.NewCodecRegistry(postgres.LenientDecoding()).DecodeJob("bodacc", []byte(`{"metadata":{}}`))
+	require.Error(t, err)
+}
+
+// BenchmarkEnrichmentJobPayload compares the payloads of company jobs
+// holding the whole place, as they were queued by older versions, with the
+// link to its result row they hold now: go test ./postgres -bench
+// EnrichmentJobPayload -benchmem
+func BenchmarkEnrichmentJobPayload(b *testing.B) {
+	raw, err := os.ReadFile("../testdata/raw.json")
+	require.NoError(b, err)
+
+	entry, err := gmaps.EntryFromJSON(raw)
+	require.NoError(b, err)
+
+	registry := postgres.NewCodecRegistry()
+
+	job := gmaps.NewCompanyJob(entry.Title, entry.Address, "owner-1", "org-1", entry.Link)
+
+	jsonJob, jobType, err := registry.EncodeJob(job)
+	require.NoError(b, err)
+
+	link, err := json.Marshal(jsonJob)
+	require.NoError(b, err)
+
+	delete(jsonJob.Metadata, "place_link")
+	jsonJob.Metadata["entry"] = entry
+
+	legacy, err := json.Marshal(jsonJob)
+	require.NoError(b, err)
+
+	for _, bc := range []struct {
+		name    string
+		payload []byte
+	}{
+		{"entry", legacy},
+		{"place_link", link},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportMetric(float64(len(bc.payload)), "payload_bytes")
+
+			for range b.N {
+				if _, err := registry.DecodeJob(jobType, bc.payload); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
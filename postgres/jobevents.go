@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/apiauth"
+	"github.com/gosom/google-maps-scraper/httpjson"
+)
+
+// JobEvent is a status transition of a job, as recorded in gmaps_job_events
+// by the trigger of the README.
+type JobEvent struct {
+	Status string `json:"status"`
+	// PreviousStatus is empty for the insert of the job.
+	PreviousStatus string `json:"previous_status,omitempty"`
+	// Worker is the application_name of the connection that changed the
+	// status, the host and process id of the worker.
+	Worker string `json:"worker"`
+	// Reason is why the worker changed it, empty for the usual transitions.
+	Reason string    `json:"reason,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// setReason gives the reason of the status transitions tx makes to the
+// trigger recording them. It holds until tx ends.
+func setReason(ctx context.Context, tx *sql.Tx, reason string) error {
+	_, err := tx.ExecContext(ctx, `SELECT set_config('gmaps.reason', $1, true)`, failureReason(reason))
+
+	return err
+}
+
+// execWithReason runs the status update q in a transaction of its own,
+// giving reason to the trigger.
+func execWithReason(ctx context.Context, db *sql.DB, reason, q string, args ...any) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setReason(ctx, tx, reason); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, q, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetJobEvents returns the timeline of the job id, oldest first, and
+// ErrJobNotFound when nothing was recorded about it.
+func GetJobEvents(ctx context.Context, db *sql.DB, id string) ([]JobEvent, error) {
+	const q = `SELECT status, COALESCE(previous_status, ''), worker, reason, created_at
+		FROM gmaps_job_events WHERE job_id = $1 ORDER BY id`
+
+	rows, err := db.QueryContext(ctx, q, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var events []JobEvent
+
+	for rows.Next() {
+		var e JobEvent
+		if err := rows.Scan(&e.Status, &e.PreviousStatus, &e.Worker, &e.Reason, &e.At); err != nil {
+			return nil, err
+		}
+
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(events) == 0 {
+		return nil, ErrJobNotFound
+	}
+
+	return events, nil
+}
+
+// JobEventsHandler serves the timeline of a job at GET /jobs/{id}/events.
+func JobEventsHandler(db *sql.DB) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /jobs/{id}/events", func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		var events []JobEvent
+
+		err := CheckJobOrganization(r.Context(), db, id, apiauth.GetOrganizationFromContext(r.Context()))
+		if err == nil {
+			events, err = GetJobEvents(r.Context(), db, id)
+		}
+
+		switch {
+		case errors.Is(err, ErrJobNotFound):
+			httpjson.Write(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		case err != nil:
+			httpjson.Write(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		default:
+			httpjson.Write(w, http.StatusOK, events)
+		}
+	})
+
+	return mux
+}
@@ -0,0 +1,53 @@
+package postgres_test
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+func Test_JobEventsHandler(t *testing.T) {
+	fake, db := newFakeDB(t)
+
+	at := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	fake.on("FROM gmaps_job_events", func(args []driver.Value) answer {
+		ans := answer{columns: []string{"status", "previous_status", "worker", "reason", "created_at"}}
+
+		if args[0] == "job-1" {
+			ans.rows = [][]driver.Value{
+				{"new", "", "worker-a:12", "", at},
+				{"failed", "queued", "worker-b:34", "browser crashed", at.Add(time.Minute)},
+			}
+		}
+
+		return ans
+	})
+
+	h := postgres.JobEventsHandler(db)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/job-1/events", http.NoBody))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var events []postgres.JobEvent
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &events))
+
+	require.Equal(t, []postgres.JobEvent{
+		{Status: "new", Worker: "worker-a:12", At: at},
+		{Status: "failed", PreviousStatus: "queued", Worker: "worker-b:34", Reason: "browser crashed", At: at.Add(time.Minute)},
+	}, events)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/job-2/events", http.NoBody))
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
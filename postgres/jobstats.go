@@ -0,0 +1,175 @@
+package postgres
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// maxRecentErrors is how many of the last job errors JobStats keeps.
+const maxRecentErrors = 20
+
+// JobError is a job of this worker that failed.
+type JobError struct {
+	JobID  string    `json:"job_id"`
+	Type   string    `json:"type"`
+	Reason string    `json:"reason"`
+	At     time.Time `json:"at"`
+}
+
+// HitRate counts the enrichment jobs of a type that found what they looked
+// for, and those that did not.
+type HitRate struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// Rate is the share of the jobs that found something, from 0 to 1.
+func (h HitRate) Rate() float64 {
+	if h.Hits+h.Misses == 0 {
+		return 0
+	}
+
+	return float64(h.Hits) / float64(h.Hits+h.Misses)
+}
+
+// JobActivity is what the jobs of this worker did since startup, by payload
+// type.
+type JobActivity struct {
+	// Running counts the jobs running their browser actions.
+	Running map[string]int64 `json:"running"`
+	// Done and Failed count the jobs processed.
+	Done   map[string]int64 `json:"done"`
+	Failed map[string]int64 `json:"failed"`
+	// Enrichment is the hit rate of the email, bodacc, pappers and social
+	// jobs.
+	Enrichment map[string]HitRate `json:"enrichment"`
+	// RecentErrors are the last job errors, the newest first.
+	RecentErrors []JobError `json:"recent_errors"`
+}
+
+var jobStats = struct {
+	mu         sync.Mutex
+	running    map[string]int64
+	done       map[string]int64
+	failed     map[string]int64
+	enrichment map[string]HitRate
+	recent     []JobError
+}{
+	running:    make(map[string]int64),
+	done:       make(map[string]int64),
+	failed:     make(map[string]int64),
+	enrichment: make(map[string]HitRate),
+}
+
+// JobStats returns what the jobs of this worker did since startup.
+func JobStats() JobActivity {
+	jobStats.mu.Lock()
+	defer jobStats.mu.Unlock()
+
+	ans := JobActivity{
+		Running:      copyCounts(jobStats.running),
+		Done:         copyCounts(jobStats.done),
+		Failed:       copyCounts(jobStats.failed),
+		Enrichment:   make(map[string]HitRate, len(jobStats.enrichment)),
+		RecentErrors: make([]JobError, 0, len(jobStats.recent)),
+	}
+
+	for k, v := range jobStats.enrichment {
+		ans.Enrichment[k] = v
+	}
+
+	for i := len(jobStats.recent) - 1; i >= 0; i-- {
+		ans.RecentErrors = append(ans.RecentErrors, jobStats.recent[i])
+	}
+
+	return ans
+}
+
+func copyCounts(m map[string]int64) map[string]int64 {
+	ans := make(map[string]int64, len(m))
+	for k, v := range m {
+		ans[k] = v
+	}
+
+	return ans
+}
+
+// jobStarted counts job as running, and returns the function counting it
+// done with its browser actions.
+func jobStarted(job scrapemate.IJob) func() {
+	jobType := jobTypeOf(job)
+
+	jobStats.mu.Lock()
+	jobStats.running[jobType]++
+	jobStats.mu.Unlock()
+
+	return func() {
+		jobStats.mu.Lock()
+		defer jobStats.mu.Unlock()
+
+		jobStats.running[jobType]--
+		if jobStats.running[jobType] <= 0 {
+			delete(jobStats.running, jobType)
+		}
+	}
+}
+
+// jobProcessed counts job as done, or failed for reason when it is not
+// empty, and the hit of its enrichment data.
+func jobProcessed(job scrapemate.IJob, data any, reason string) {
+	jobType := jobTypeOf(job)
+
+	jobStats.mu.Lock()
+	defer jobStats.mu.Unlock()
+
+	if reason != "" {
+		jobStats.failed[jobType]++
+
+		jobStats.recent = append(jobStats.recent, JobError{
+			JobID:  job.GetID(),
+			Type:   jobType,
+			Reason: failureReason(reason),
+			At:     time.Now().UTC(),
+		})
+
+		if len(jobStats.recent) > maxRecentErrors {
+			jobStats.recent = jobStats.recent[len(jobStats.recent)-maxRecentErrors:]
+		}
+
+		return
+	}
+
+	jobStats.done[jobType]++
+
+	if hit, ok := enrichmentHit(data); ok {
+		h := jobStats.enrichment[jobType]
+		if hit {
+			h.Hits++
+		} else {
+			h.Misses++
+		}
+
+		jobStats.enrichment[jobType] = h
+	}
+}
+
+// enrichmentHit tells whether the result of an enrichment job found data,
+// and false for ok when data is no such result.
+func enrichmentHit(data any) (hit, ok bool) {
+	switch result := data.(type) {
+	case *gmaps.EmailEnrichmentResult:
+		return result != nil && len(result.Emails) > 0, true
+	case *gmaps.CompanyEnrichmentResult:
+		return result != nil && result.SocieteSiren != "", true
+	case *gmaps.PappersEnrichmentResult:
+		return result != nil && len(result.SocieteDirigeants) > 0, true
+	case *gmaps.SocialEnrichmentResult:
+		return result != nil && result.Profile != nil, true
+	}
+
+	return false, false
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"sync"
 
 	"github.com/gosom/scrapemate"
 )
@@ -11,24 +12,70 @@ import (
 type StatusManager struct {
 	db        *sql.DB
 	apiClient *APIClient
+	// onRootDone are called with the id of each root job that is done.
+	onRootDone []func(id string)
+	// background runs the completion of root jobs.
+	background func(fn func())
+	// monitorChanges compares root jobs with the previous run of their
+	// queries when they are done.
+	monitorChanges bool
+	// notifiers are told when root jobs complete or fail.
+	notifiers []JobNotifier
+	// summaries holds the root jobs done until the result writer saved
+	// their results, nil to complete them right away.
+	summaries *RootSummaries
+	// pending are the functions the default background runs.
+	pending sync.WaitGroup
 }
 
 // NewStatusManager creates a new StatusManager.
 func NewStatusManager(db *sql.DB, apiClient *APIClient) *StatusManager {
-	return &StatusManager{
+	s := &StatusManager{
 		db:        db,
 		apiClient: apiClient,
 	}
+
+	s.background = func(fn func()) {
+		s.pending.Add(1)
+
+		go func() {
+			defer s.pending.Done()
+			fn()
+		}()
+	}
+
+	return s
+}
+
+// Wait waits for the completion of the root jobs s finished, their summary
+// and job completion call, for callers exiting right after a status change.
+// The provider waits for those of its own status manager in Drain.
+func (s *StatusManager) Wait() {
+	s.pending.Wait()
 }
 
 // MarkDone marks a job as done and handles parent-child tracking.
 func (s *StatusManager) MarkDone(ctx context.Context, job scrapemate.IJob, childJobsCreated int) error {
+	return s.MarkDoneWith(ctx, job, childJobsCreated, nil)
+}
+
+// MarkDoneWith is MarkDone also running inTx, when not nil, in its
+// transaction: what inTx writes is committed with the status or not at all.
+func (s *StatusManager) MarkDoneWith(ctx context.Context, job scrapemate.IJob, childJobsCreated int, inTx func(tx *sql.Tx) error) error {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	var done []doneRoot
+
+	if inTx != nil {
+		if err := inTx(tx); err != nil {
+			return err
+		}
+	}
+
 	if childJobsCreated == 0 {
 		q := `UPDATE gmaps_jobs SET status = $1 WHERE id = $2`
 		_, err = tx.ExecContext(ctx, q, statusDone, job.GetID())
@@ -42,11 +89,11 @@ func (s *StatusManager) MarkDone(ctx context.Context, job scrapemate.IJob, child
 			var payload []byte
 			err = tx.QueryRowContext(ctx, `SELECT payload FROM gmaps_jobs WHERE id = $1`, job.GetID()).Scan(&payload)
 			if err == nil {
-				s.apiClient.CallJobCompletionAPIAsync(ctx, job.GetID(), payload)
+				done = append(done, doneRoot{id: job.GetID(), payload: payload})
 			}
 		}
 
-		if err := s.checkAndMarkParentDone(ctx, tx, job.GetID()); err != nil {
+		if err := s.checkAndMarkParentDone(ctx, tx, job.GetID(), &done); err != nil {
 			return err
 		}
 	} else {
@@ -57,17 +104,27 @@ func (s *StatusManager) MarkDone(ctx context.Context, job scrapemate.IJob, child
 		}
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.rootsDone(done)
+
+	return nil
 }
 
-// MarkFailed marks a job as failed and updates parent tracking.
-func (s *StatusManager) MarkFailed(ctx context.Context, job scrapemate.IJob) error {
+// MarkFailed marks a job as failed for reason and updates parent tracking.
+func (s *StatusManager) MarkFailed(ctx context.Context, job scrapemate.IJob, reason string) error {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	if err := setReason(ctx, tx, reason); err != nil {
+		return err
+	}
+
 	q := `UPDATE gmaps_jobs SET status = $1 WHERE id = $2`
 	_, err = tx.ExecContext(ctx, q, statusFailed, job.GetID())
 	if err != nil {
@@ -78,11 +135,28 @@ func (s *StatusManager) MarkFailed(ctx context.Context, job scrapemate.IJob) err
 		return err
 	}
 
-	if err := s.checkAndMarkParentDone(ctx, tx, job.GetID()); err != nil {
+	var done []doneRoot
+
+	if err := s.checkAndMarkParentDone(ctx, tx, job.GetID(), &done); err != nil {
 		return err
 	}
 
-	return tx.Commit()
+	var parentID sql.NullString
+	if err := tx.QueryRowContext(ctx, `SELECT parent_id FROM gmaps_jobs WHERE id = $1`, job.GetID()).Scan(&parentID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.rootsDone(done)
+
+	if !parentID.Valid {
+		s.rootFailed(job.GetID(), reason)
+	}
+
+	return nil
 }
 
 // incrementParentFailedCounter increments the failed counter on the parent job.
@@ -101,16 +175,37 @@ func (s *StatusManager) incrementParentFailedCounter(ctx context.Context, tx *sq
 	return nil
 }
 
-// MarkEnrichmentDone marks an enrichment job as done without any parent tracking.
-func (s *StatusManager) MarkEnrichmentDone(ctx context.Context, job scrapemate.IJob) error {
-	_, err := s.db.ExecContext(ctx,
-		`UPDATE gmaps_jobs SET status = $1 WHERE id = $2`,
-		statusDone, job.GetID())
-	return err
+// MarkEnrichmentDone marks an enrichment job as done without any parent
+// tracking, running inTx, when not nil, in the same transaction.
+func (s *StatusManager) MarkEnrichmentDone(ctx context.Context, job scrapemate.IJob, inTx func(tx *sql.Tx) error) error {
+	if inTx == nil {
+		_, err := s.db.ExecContext(ctx,
+			`UPDATE gmaps_jobs SET status = $1 WHERE id = $2`,
+			statusDone, job.GetID())
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := inTx(tx); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE gmaps_jobs SET status = $1 WHERE id = $2`, statusDone, job.GetID()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // checkAndMarkParentDone checks if all child jobs are done and marks the parent as done.
-func (s *StatusManager) checkAndMarkParentDone(ctx context.Context, tx *sql.Tx, jobID string) error {
+// The root jobs it marks done are appended to done, to be reported once tx
+// commits.
+func (s *StatusManager) checkAndMarkParentDone(ctx context.Context, tx *sql.Tx, jobID string, done *[]doneRoot) error {
 	var parentID sql.NullString
 	err := tx.QueryRowContext(ctx, `SELECT parent_id FROM gmaps_jobs WHERE id = $1`, jobID).Scan(&parentID)
 	if err != nil || !parentID.Valid {
@@ -156,13 +251,136 @@ func (s *StatusManager) checkAndMarkParentDone(ctx context.Context, tx *sql.Tx,
 				var payload []byte
 				err = tx.QueryRowContext(ctx, `SELECT payload FROM gmaps_jobs WHERE id = $1`, parentID.String).Scan(&payload)
 				if err == nil {
-					s.apiClient.CallJobCompletionAPIAsync(ctx, parentID.String, payload)
+					*done = append(*done, doneRoot{id: parentID.String, payload: payload})
 				}
 			}
 
-			return s.checkAndMarkParentDone(ctx, tx, parentID.String)
+			return s.checkAndMarkParentDone(ctx, tx, parentID.String, done)
 		}
 	}
 
 	return nil
 }
+
+// completeParent marks the parent id done for reason when it is still
+// waiting for its children although its counters say they all ran, and
+// propagates it to its own parent as the last child would have. It tells
+// whether it changed the status.
+func (s *StatusManager) completeParent(ctx context.Context, id, reason string) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if err := setReason(ctx, tx, reason); err != nil {
+		return false, err
+	}
+
+	const q = `UPDATE gmaps_jobs SET status = $1
+		WHERE id = $2 AND status = $3 AND child_jobs_count > 0
+			AND child_jobs_completed + child_jobs_failed >= child_jobs_count`
+
+	result, err := tx.ExecContext(ctx, q, statusDone, id, statusProcessing)
+	if err != nil {
+		return false, err
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	var parentID sql.NullString
+	if err := tx.QueryRowContext(ctx, `SELECT parent_id FROM gmaps_jobs WHERE id = $1`, id).Scan(&parentID); err != nil {
+		return false, err
+	}
+
+	var done []doneRoot
+
+	if !parentID.Valid {
+		var payload []byte
+		if err := tx.QueryRowContext(ctx, `SELECT payload FROM gmaps_jobs WHERE id = $1`, id).Scan(&payload); err != nil {
+			return false, err
+		}
+
+		done = append(done, doneRoot{id: id, payload: payload})
+	}
+
+	if err := s.checkAndMarkParentDone(ctx, tx, id, &done); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	s.rootsDone(done)
+
+	return true, nil
+}
+
+// doneRoot is a root job a transaction marked done.
+type doneRoot struct {
+	id      string
+	payload []byte
+}
+
+// rootsDone reports the root jobs of done, once the transaction that marked
+// them done committed: their summary waits for their results with the
+// RootSummaries of the status manager, or is computed right away without.
+func (s *StatusManager) rootsDone(done []doneRoot) {
+	for _, r := range done {
+		if s.summaries != nil {
+			s.summaries.add(r.id, r.payload)
+		} else {
+			s.background(func() {
+				s.completeRoot(r.id, r.payload)
+			})
+		}
+
+		for _, fn := range s.onRootDone {
+			fn(r.id)
+		}
+	}
+}
+
+// PendingJobs returns how many jobs are waiting to run or for their children.
+func PendingJobs(ctx context.Context, db *sql.DB) (int, error) {
+	const q = `SELECT COUNT(*) FROM gmaps_jobs WHERE status IN ($1, $2, $3, $4)`
+
+	var n int
+
+	err := db.QueryRowContext(ctx, q, statusNew, statusQueued, statusProcessing, statusDeferred).Scan(&n)
+
+	return n, err
+}
+
+// PendingRootJob returns how many jobs of the root job id are still to run:
+// the root itself until it is done or failed, and the enrichment jobs
+// detached from its tree, which are only counted by their root_id. It
+// returns ErrJobNotFound when the root job does not exist.
+func PendingRootJob(ctx context.Context, db *sql.DB, id string) (int, error) {
+	const q = `SELECT
+		(SELECT status FROM gmaps_jobs WHERE id = $1),
+		(SELECT COUNT(*) FROM gmaps_jobs WHERE root_id = $1 AND status IN ($2, $3, $4, $5))`
+
+	var (
+		status   sql.NullString
+		detached int
+	)
+
+	err := db.QueryRowContext(ctx, q, id, statusNew, statusQueued, statusProcessing, statusDeferred).Scan(&status, &detached)
+	if err != nil {
+		return 0, err
+	}
+
+	if !status.Valid {
+		return 0, ErrJobNotFound
+	}
+
+	if status.String == statusDone || status.String == statusFailed {
+		return detached, nil
+	}
+
+	return detached + 1, nil
+}
@@ -0,0 +1,85 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gosom/scrapemate"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+func Test_PendingRootJobCountsTheDetachedEnrichmentJobs(t *testing.T) {
+	fake, db := newFakeDB(t)
+
+	roots := map[string][]driver.Value{
+		"running":  {"processing", int64(0)},
+		"enriched": {"done", int64(2)},
+		"finished": {"done", int64(0)},
+		"missing":  {nil, int64(0)},
+	}
+
+	fake.on("SELECT status FROM gmaps_jobs WHERE id = $1", func(args []driver.Value) answer {
+		return answer{columns: []string{"status", "count"}, rows: [][]driver.Value{roots[args[0].(string)]}}
+	})
+
+	for _, tc := range []struct {
+		id   string
+		want int
+	}{
+		{"running", 1},
+		{"enriched", 2},
+		{"finished", 0},
+	} {
+		n, err := postgres.PendingRootJob(context.Background(), db, tc.id)
+		require.NoError(t, err)
+		require.Equal(t, tc.want, n, tc.id)
+	}
+
+	_, err := postgres.PendingRootJob(context.Background(), db, "missing")
+	require.ErrorIs(t, err, postgres.ErrJobNotFound)
+
+	// the enrichment jobs still to run are counted by their root
+	require.Equal(t, []driver.Value{"enriched", "new", "queued", "processing", "deferred"},
+		fake.ran("SELECT status FROM gmaps_jobs WHERE id = $1")[1])
+}
+
+func Test_MarkDoneCompletesTheRootJobOnceCommitted(t *testing.T) {
+	var calls atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	fake, db := newFakeDB(t)
+
+	fake.on("SELECT parent_id FROM gmaps_jobs", func([]driver.Value) answer {
+		return answer{columns: []string{"parent_id"}, rows: [][]driver.Value{{nil}}}
+	})
+	fake.on("SELECT payload FROM gmaps_jobs", func([]driver.Value) answer {
+		return answer{columns: []string{"payload"}, rows: [][]driver.Value{{[]byte(`{"metadata":{"owner_id":"user-1"}}`)}}}
+	})
+
+	sm := postgres.NewStatusManager(db, postgres.NewAPIClient("", srv.URL))
+	job := &scrapemate.Job{ID: "root-1"}
+
+	// a root job whose status is rolled back is not reported done
+	fake.failCommits(errors.New("connection reset"))
+
+	require.Error(t, sm.MarkDone(context.Background(), job, 0))
+	sm.Wait()
+	require.Equal(t, int32(0), calls.Load())
+
+	fake.failCommits(nil)
+
+	require.NoError(t, sm.MarkDone(context.Background(), job, 0))
+	sm.Wait()
+	require.Equal(t, int32(1), calls.Load())
+}
@@ -0,0 +1,364 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gosom/scrapemate"
+)
+
+const (
+	memoryCheckInterval = 5 * time.Second
+	// memoryResumeRatio is the share of the limit the memory must fall under
+	// before the watchdog lifts the pressure, so it does not flap.
+	memoryResumeRatio = 0.9
+	// pressureBatchDivisor divides the fetch batch size under pressure.
+	pressureBatchDivisor = 10
+)
+
+// pressureFilter leaves out place jobs while the provider is throttled; the
+// jobs already running and the enrichment jobs still drain.
+const pressureFilter = `
+			AND payload_type <> '` + jobTypePlace + `'`
+
+// MemoryStats is the state of a MemoryWatchdog.
+type MemoryStats struct {
+	// RSS is the memory of the worker and of the browsers it starts.
+	RSS      uint64 `json:"rss_bytes"`
+	Limit    uint64 `json:"limit_bytes"`
+	Pressure bool   `json:"pressure"`
+	// Episodes counts how many times the memory went over the limit.
+	Episodes int64 `json:"episodes"`
+}
+
+// MemoryWatchdog samples the memory of the worker, with the browsers it
+// starts, and applies backpressure while it is above the limit: the provider fetches smaller
+// batches and no new place jobs, and the result writer saves its buffer
+// right away. Campaigns of tens of thousands of places then slow down
+// instead of getting the worker OOM killed.
+type MemoryWatchdog struct {
+	limit    uint64
+	root     string
+	rss      atomic.Uint64
+	pressure atomic.Bool
+	episodes atomic.Int64
+
+	mu      sync.Mutex
+	flushes []chan struct{}
+}
+
+// MemoryWatchdogOption configures a MemoryWatchdog.
+type MemoryWatchdogOption func(*MemoryWatchdog)
+
+// WithMemoryRoot makes the watchdog read /proc and /sys/fs/cgroup under root,
+// for a worker seeing the ones of its container mounted elsewhere.
+func WithMemoryRoot(root string) MemoryWatchdogOption {
+	return func(m *MemoryWatchdog) {
+		m.root = root
+	}
+}
+
+// NewMemoryWatchdog creates a MemoryWatchdog for a limit in bytes. It does
+// nothing until Run is called.
+func NewMemoryWatchdog(limit uint64, opts ...MemoryWatchdogOption) *MemoryWatchdog {
+	m := &MemoryWatchdog{limit: limit, root: "/"}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// WithMemoryWatchdog makes the provider fetch fewer jobs and no place jobs
+// while m reports pressure.
+func WithMemoryWatchdog(m *MemoryWatchdog) ProviderOption {
+	return func(p *provider) {
+		p.memory = m
+	}
+}
+
+// WithWriterMemoryWatchdog makes the result writer save its buffer when m
+// reports pressure.
+func WithWriterMemoryWatchdog(m *MemoryWatchdog) ResultWriterOption {
+	return func(r *resultWriter) {
+		r.flush = m.subscribe()
+	}
+}
+
+// Run samples the memory until ctx is done.
+func (m *MemoryWatchdog) Run(ctx context.Context) {
+	ticker := time.NewTicker(memoryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		m.check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// UnderPressure tells whether the memory went over the limit and has not
+// fallen back under memoryResumeRatio of it yet.
+func (m *MemoryWatchdog) UnderPressure() bool {
+	return m != nil && m.pressure.Load()
+}
+
+// Stats returns the last sample.
+func (m *MemoryWatchdog) Stats() MemoryStats {
+	return MemoryStats{
+		RSS:      m.rss.Load(),
+		Limit:    m.limit,
+		Pressure: m.pressure.Load(),
+		Episodes: m.episodes.Load(),
+	}
+}
+
+func (m *MemoryWatchdog) check(ctx context.Context) {
+	rss := m.usage()
+	m.rss.Store(rss)
+
+	log := scrapemate.GetLoggerFromContext(ctx)
+
+	switch {
+	case rss > m.limit:
+		if !m.pressure.Swap(true) {
+			m.episodes.Add(1)
+			log.Info(fmt.Sprintf("memory watchdog: %d MB over the %d MB limit, applying backpressure", rss>>20, m.limit>>20))
+		}
+
+		m.notifyFlush()
+		debug.FreeOSMemory()
+	case float64(rss) < float64(m.limit)*memoryResumeRatio:
+		if m.pressure.Swap(false) {
+			log.Info(fmt.Sprintf("memory watchdog: back to %d MB, resuming", rss>>20))
+		}
+	}
+}
+
+func (m *MemoryWatchdog) subscribe() <-chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := make(chan struct{}, 1)
+	m.flushes = append(m.flushes, c)
+
+	return c
+}
+
+// notifyFlush asks every subscribed writer to save its buffer, without
+// waiting for writers that were already asked.
+func (m *MemoryWatchdog) notifyFlush() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range m.flushes {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// usage returns the memory of the worker and of the browsers it starts: the
+// working set of its cgroup when it is that of its container, or else the
+// RSS of the process and of its descendants, or else the memory the Go
+// runtime holds from the OS where /proc is not available.
+func (m *MemoryWatchdog) usage() uint64 {
+	if n, ok := cgroupMemory(m.root); ok {
+		return n
+	}
+
+	if n, ok := processTreeMemory(m.root); ok {
+		return n
+	}
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	return ms.Sys - ms.HeapReleased
+}
+
+// cgroupMemory returns the working set of the memory cgroup of the process,
+// as the OOM killer sees it: its usage without the inactive page cache the
+// kernel reclaims first. Only the cgroup of a container is read: the root of
+// the cgroup namespace of the process, or, without a namespace, the cgroup
+// mounted at the root when the path of the cgroup of the process is not
+// mounted, as a container only sees its own. The cgroup of a process of the
+// host, a slice or a session, is shared with other processes and not read.
+func cgroupMemory(root string) (uint64, bool) {
+	data, err := os.ReadFile(filepath.Join(root, "proc/self/cgroup"))
+	if err != nil {
+		return 0, false
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		var mount, usage, inactive string
+
+		switch {
+		case parts[0] == "0" && parts[1] == "":
+			mount, usage, inactive = "sys/fs/cgroup", "memory.current", "inactive_file"
+		case slices.Contains(strings.Split(parts[1], ","), "memory"):
+			mount, usage, inactive = "sys/fs/cgroup/memory", "memory.usage_in_bytes", "total_inactive_file"
+		default:
+			continue
+		}
+
+		dir := filepath.Join(root, mount)
+
+		if parts[2] != "/" {
+			if _, err := os.Stat(filepath.Join(dir, parts[2])); err == nil {
+				return 0, false
+			}
+		}
+
+		n, err := readUint(filepath.Join(dir, usage))
+		if err != nil {
+			continue
+		}
+
+		if cache, ok := memoryStat(filepath.Join(dir, "memory.stat"), inactive); ok && cache < n {
+			n -= cache
+		}
+
+		return n, true
+	}
+
+	return 0, false
+}
+
+// memoryStat returns the value of key in the memory.stat file at path.
+func memoryStat(path, key string) (uint64, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		name, value, ok := strings.Cut(line, " ")
+		if !ok || name != key {
+			continue
+		}
+
+		n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+
+		return n, err == nil
+	}
+
+	return 0, false
+}
+
+func readUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(string(bytes.TrimSpace(data)), 10, 64)
+}
+
+// processTreeMemory sums the RSS of the process and of its descendants, the
+// browsers and their renderers, from /proc.
+func processTreeMemory(root string) (uint64, bool) {
+	self, err := os.Readlink(filepath.Join(root, "proc/self"))
+	if err != nil {
+		return 0, false
+	}
+
+	entries, err := os.ReadDir(filepath.Join(root, "proc"))
+	if err != nil {
+		return 0, false
+	}
+
+	var (
+		children = map[string][]string{}
+		rss      = map[string]uint64{}
+	)
+
+	for _, e := range entries {
+		pid := e.Name()
+		if _, err := strconv.Atoi(pid); err != nil {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(root, "proc", pid, "stat"))
+		if err != nil {
+			continue
+		}
+
+		// the fields follow the command name, which may hold spaces
+		i := bytes.LastIndexByte(data, ')')
+		if i < 0 {
+			continue
+		}
+
+		fields := bytes.Fields(data[i+1:])
+		if len(fields) < 22 {
+			continue
+		}
+
+		ppid := string(fields[1])
+		children[ppid] = append(children[ppid], pid)
+
+		if pages, err := strconv.ParseUint(string(fields[21]), 10, 64); err == nil {
+			rss[pid] = pages * uint64(os.Getpagesize())
+		}
+	}
+
+	if _, ok := rss[self]; !ok {
+		return 0, false
+	}
+
+	var (
+		total uint64
+		queue = []string{self}
+	)
+
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+
+		total += rss[pid]
+		queue = append(queue, children[pid]...)
+	}
+
+	return total, true
+}
+
+// fetchBatchSize is the number of jobs fetchJobs queues per query, reduced
+// while the provider is throttled.
+func (p *provider) fetchBatchSize() int {
+	size := p.tuning.batchSize()
+
+	if p.throttled() {
+		size = max(1, size/pressureBatchDivisor)
+	}
+
+	return size
+}
+
+// throttled tells whether the memory watchdog reports pressure or the result
+// writer is behind.
+func (p *provider) throttled() bool {
+	return p.memory.UnderPressure() || p.results.Behind()
+}
@@ -0,0 +1,111 @@
+package postgres_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+// writeFiles creates the files under root.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+}
+
+// sample runs the watchdog until it took a sample and returns it.
+func sample(t *testing.T, m *postgres.MemoryWatchdog) postgres.MemoryStats {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		m.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool { return m.Stats().RSS > 0 }, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+
+	return m.Stats()
+}
+
+func Test_MemoryWatchdogReadsTheCgroup(t *testing.T) {
+	root := t.TempDir()
+
+	writeFiles(t, root, map[string]string{
+		"proc/self/cgroup":             "0::/\n",
+		"sys/fs/cgroup/memory.current": "3221225472\n",
+		"sys/fs/cgroup/memory.stat":    "anon 2147483648\nfile 1073741824\ninactive_file 1073741824\n",
+	})
+
+	// the browsers count with the worker, the page cache the kernel drops
+	// first does not
+	stats := sample(t, postgres.NewMemoryWatchdog(1<<30, postgres.WithMemoryRoot(root)))
+	require.Equal(t, uint64(2<<30), stats.RSS)
+	require.True(t, stats.Pressure)
+
+	// without a cgroup namespace a container only sees its own cgroup
+	root = t.TempDir()
+
+	writeFiles(t, root, map[string]string{
+		"proc/self/cgroup":                           "12:cpu,cpuacct:/docker/abc\n4:memory:/docker/abc\n",
+		"sys/fs/cgroup/memory/memory.usage_in_bytes": "1073741824\n",
+	})
+
+	stats = sample(t, postgres.NewMemoryWatchdog(1<<40, postgres.WithMemoryRoot(root)))
+	require.Equal(t, uint64(1<<30), stats.RSS)
+}
+
+func Test_MemoryWatchdogSkipsSharedCgroups(t *testing.T) {
+	root := t.TempDir()
+
+	writeFiles(t, root, map[string]string{
+		// the worker runs in the session of a user of the host, with the
+		// other processes of the user
+		"proc/100/cgroup": "0::/user.slice/user-1000.slice/session-2.scope\n",
+		"sys/fs/cgroup/user.slice/user-1000.slice/session-2.scope/memory.current": "8589934592\n",
+		"proc/100/stat": fmt.Sprintf("100 (google-maps-scraper) S 1 %s256 0 0\n", strings.Repeat("0 ", 19)),
+	})
+	require.NoError(t, os.Symlink("100", filepath.Join(root, "proc/self")))
+
+	stats := sample(t, postgres.NewMemoryWatchdog(1<<40, postgres.WithMemoryRoot(root)))
+	require.Equal(t, uint64(256*os.Getpagesize()), stats.RSS)
+}
+
+func Test_MemoryWatchdogSumsTheProcessTree(t *testing.T) {
+	root := t.TempDir()
+
+	stat := func(pid, ppid, pages int) string {
+		return fmt.Sprintf("%d (chrome renderer) S %d %s%d 0 0\n", pid, ppid, strings.Repeat("0 ", 19), pages)
+	}
+
+	writeFiles(t, root, map[string]string{
+		// the worker, its browser, a renderer of the browser and another
+		// process
+		"proc/100/stat": stat(100, 1, 256),
+		"proc/101/stat": stat(101, 100, 512),
+		"proc/102/stat": stat(102, 101, 256),
+		"proc/200/stat": stat(200, 1, 4096),
+	})
+	require.NoError(t, os.Symlink("100", filepath.Join(root, "proc/self")))
+
+	stats := sample(t, postgres.NewMemoryWatchdog(1<<40, postgres.WithMemoryRoot(root)))
+	require.Equal(t, uint64(1024*os.Getpagesize()), stats.RSS)
+	require.False(t, stats.Pressure)
+}
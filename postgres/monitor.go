@@ -0,0 +1,364 @@
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// maxWebhookChanges is how many changes the job completion call lists; the
+// counts always cover all of them.
+const maxWebhookChanges = 200
+
+// Kinds of PlaceChange.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// PlaceSnapshot is what a run saw of a place.
+type PlaceSnapshot struct {
+	Key         string
+	Link        string
+	Title       string
+	Phone       string
+	Website     string
+	Rating      float64
+	ReviewCount int
+	Status      string
+}
+
+// PlaceChange is a difference between two runs of the same queries: a place
+// that appeared or disappeared, or a field of a place that changed.
+type PlaceChange struct {
+	Kind     string `json:"kind"`
+	Link     string `json:"link"`
+	Title    string `json:"title"`
+	Field    string `json:"field,omitempty"`
+	OldValue string `json:"oldValue,omitempty"`
+	NewValue string `json:"newValue,omitempty"`
+}
+
+// ChangeSet is the difference between a run and the previous run of the same
+// queries by the same owner. Changes holds the first maxWebhookChanges.
+type ChangeSet struct {
+	PreviousJobID string        `json:"previousJobId"`
+	Added         int           `json:"added"`
+	Removed       int           `json:"removed"`
+	Changed       int           `json:"changed"`
+	Changes       []PlaceChange `json:"changes"`
+	Truncated     bool          `json:"truncated,omitempty"`
+}
+
+// WithChangeMonitoring records what every place job sees and, when a root
+// job is done, compares it with the previous run of the same queries by the
+// same owner or organization. The changes are stored in place_changes and
+// sent with the job completion call.
+func WithChangeMonitoring() ProviderOption {
+	return func(p *provider) {
+		p.monitorChanges = true
+		p.statusManager.monitorChanges = true
+	}
+}
+
+// DiffRuns compares the places of two runs. Places are matched by key;
+// changed places get a change per field, for the title, phone, website,
+// rating and status (e.g. "Permanently closed"). The changes are sorted by
+// kind, title and field.
+func DiffRuns(previous, current []PlaceSnapshot) []PlaceChange {
+	before := make(map[string]PlaceSnapshot, len(previous))
+	for _, p := range previous {
+		before[p.Key] = p
+	}
+
+	var changes []PlaceChange
+
+	seen := make(map[string]bool, len(current))
+
+	for _, cur := range current {
+		seen[cur.Key] = true
+
+		prev, ok := before[cur.Key]
+		if !ok {
+			changes = append(changes, PlaceChange{Kind: ChangeAdded, Link: cur.Link, Title: cur.Title})
+			continue
+		}
+
+		fields := []struct {
+			name     string
+			old, new string
+		}{
+			{"title", prev.Title, cur.Title},
+			{"phone", prev.Phone, cur.Phone},
+			{"website", prev.Website, cur.Website},
+			{"rating", formatRating(prev.Rating), formatRating(cur.Rating)},
+			{"status", prev.Status, cur.Status},
+		}
+
+		for _, f := range fields {
+			if f.old != f.new {
+				changes = append(changes, PlaceChange{
+					Kind:     ChangeChanged,
+					Link:     cur.Link,
+					Title:    cur.Title,
+					Field:    f.name,
+					OldValue: f.old,
+					NewValue: f.new,
+				})
+			}
+		}
+	}
+
+	for _, prev := range previous {
+		if !seen[prev.Key] {
+			changes = append(changes, PlaceChange{Kind: ChangeRemoved, Link: prev.Link, Title: prev.Title})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+
+		if a.Title != b.Title {
+			return a.Title < b.Title
+		}
+
+		return a.Field < b.Field
+	})
+
+	return changes
+}
+
+func formatRating(r float64) string {
+	if r == 0 {
+		return ""
+	}
+
+	return strconv.FormatFloat(r, 'f', 1, 64)
+}
+
+// observePlace records what the place job saw of entry for the run it
+// belongs to, before duplicates are dropped.
+func (w *jobWrapper) observePlace(job *gmaps.PlaceJob, entry *gmaps.Entry) {
+	if !w.provider.monitorChanges {
+		return
+	}
+
+	searchID := job.ParentID
+	if searchID == "" {
+		searchID = job.GetID()
+	}
+
+	snapshot := PlaceSnapshot{
+		Key:         PlaceKey(entry.Link),
+		Link:        entry.Link,
+		Title:       entry.Title,
+		Phone:       entry.Phone,
+		Website:     entry.WebSite,
+		Rating:      entry.ReviewRating,
+		ReviewCount: entry.ReviewCount,
+		Status:      entry.Status,
+	}
+
+	w.provider.goBackground(func() {
+		const q = `INSERT INTO place_observations
+			(search_id, place_key, link, title, phone, website, review_rating, review_count, status, observed_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			ON CONFLICT (search_id, place_key) DO UPDATE SET
+				link = EXCLUDED.link, title = EXCLUDED.title, phone = EXCLUDED.phone,
+				website = EXCLUDED.website, review_rating = EXCLUDED.review_rating,
+				review_count = EXCLUDED.review_count, status = EXCLUDED.status,
+				observed_at = EXCLUDED.observed_at`
+
+		_, err := w.provider.db.ExecContext(context.Background(), q, searchID, snapshot.Key, snapshot.Link,
+			snapshot.Title, snapshot.Phone, snapshot.Website, snapshot.Rating, snapshot.ReviewCount,
+			snapshot.Status, time.Now().UTC())
+		if err != nil {
+			log := scrapemate.GetLoggerFromContext(context.Background())
+			log.Error(fmt.Sprintf("jobWrapper.observePlace: %v", err))
+		}
+	})
+}
+
+// compareRuns records the root job id as a run of its queries and compares
+// it with the previous run. It returns nil without a previous run.
+func (s *StatusManager) compareRuns(ctx context.Context, id string, payload []byte) (*ChangeSet, error) {
+	ownerID, organizationID, querySet, err := runIdentity(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	const previousQ = `SELECT root_id FROM monitor_runs
+		WHERE owner_id = $1 AND organization_id = $2 AND query_set = $3 AND root_id <> $4
+		ORDER BY completed_at DESC LIMIT 1`
+
+	var previousID string
+
+	err = s.db.QueryRowContext(ctx, previousQ, ownerID, organizationID, querySet, id).Scan(&previousID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to find the previous run: %w", err)
+	}
+
+	const runQ = `INSERT INTO monitor_runs (root_id, owner_id, organization_id, query_set, completed_at)
+		VALUES ($1, $2, $3, $4, $5) ON CONFLICT (root_id) DO NOTHING`
+
+	if _, err := s.db.ExecContext(ctx, runQ, id, ownerID, organizationID, querySet, time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("failed to record the run: %w", err)
+	}
+
+	if previousID == "" {
+		return nil, nil
+	}
+
+	previous, err := s.runSnapshots(ctx, previousID)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.runSnapshots(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := DiffRuns(previous, current)
+
+	if err := s.storeChanges(ctx, id, previousID, changes); err != nil {
+		return nil, err
+	}
+
+	set := ChangeSet{PreviousJobID: previousID, Changes: changes}
+
+	for _, c := range changes {
+		switch c.Kind {
+		case ChangeAdded:
+			set.Added++
+		case ChangeRemoved:
+			set.Removed++
+		case ChangeChanged:
+			set.Changed++
+		}
+	}
+
+	if len(set.Changes) > maxWebhookChanges {
+		set.Changes = set.Changes[:maxWebhookChanges]
+		set.Truncated = true
+	}
+
+	return &set, nil
+}
+
+// runSnapshots returns the places observed by the searches of the root job
+// id, relaxed searches included.
+func (s *StatusManager) runSnapshots(ctx context.Context, id string) ([]PlaceSnapshot, error) {
+	const q = `WITH RECURSIVE tree AS (
+			SELECT id FROM gmaps_jobs WHERE id = $1
+			UNION ALL
+			SELECT j.id FROM gmaps_jobs j JOIN tree t ON j.parent_id = t.id
+			WHERE j.payload_type <> $2
+		)
+		SELECT DISTINCT ON (place_key) place_key, link, COALESCE(title, ''), COALESCE(phone, ''),
+			COALESCE(website, ''), COALESCE(review_rating, 0), COALESCE(review_count, 0), COALESCE(status, '')
+		FROM place_observations
+		WHERE search_id IN (SELECT id FROM tree)
+		ORDER BY place_key, observed_at DESC`
+
+	rows, err := s.db.QueryContext(ctx, q, id, jobTypePlace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read observations of %s: %w", id, err)
+	}
+	defer rows.Close()
+
+	var ans []PlaceSnapshot
+
+	for rows.Next() {
+		var p PlaceSnapshot
+		if err := rows.Scan(&p.Key, &p.Link, &p.Title, &p.Phone, &p.Website, &p.Rating, &p.ReviewCount, &p.Status); err != nil {
+			return nil, fmt.Errorf("failed to scan observation: %w", err)
+		}
+
+		ans = append(ans, p)
+	}
+
+	return ans, rows.Err()
+}
+
+func (s *StatusManager) storeChanges(ctx context.Context, id, previousID string, changes []PlaceChange) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	const q = `INSERT INTO place_changes
+		(root_id, previous_root_id, place_key, link, title, kind, field, old_value, new_value, detected_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), $10)`
+
+	now := time.Now().UTC()
+
+	for _, c := range changes {
+		if _, err := tx.ExecContext(ctx, q, id, previousID, PlaceKey(c.Link), c.Link, c.Title,
+			c.Kind, c.Field, c.OldValue, c.NewValue, now); err != nil {
+			return fmt.Errorf("failed to store change: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// runIdentity returns who a root job ran for and a hash of what it searched:
+// its queries, sorted and lower-cased, and its position, so runs of the same
+// campaign compare whatever the order of the queries.
+func runIdentity(payload []byte) (ownerID, organizationID, querySet string, err error) {
+	var rawJSON string
+	if err := json.Unmarshal(payload, &rawJSON); err == nil {
+		payload = []byte(rawJSON)
+	}
+
+	var jsonJob JSONJob
+	if err := json.Unmarshal(payload, &jsonJob); err != nil {
+		return "", "", "", fmt.Errorf("failed to unmarshal root job: %w", err)
+	}
+
+	md := jsonJob.Metadata
+
+	ownerID, _ = md["owner_id"].(string)
+	organizationID, _ = md["organization_id"].(string)
+
+	var queries []string
+
+	if raw, ok := md["queries"].([]interface{}); ok {
+		for _, q := range raw {
+			if q, ok := q.(string); ok {
+				queries = append(queries, q)
+			}
+		}
+	} else {
+		queries = append(queries, gmaps.SearchQuery(jsonJob.URL))
+	}
+
+	for i, q := range queries {
+		queries[i] = strings.ToLower(strings.TrimSpace(q))
+	}
+
+	sort.Strings(queries)
+
+	geo, _ := md["geo"].(string)
+
+	sum := sha256.Sum256([]byte(geo + "\n" + strings.Join(queries, "\n")))
+
+	return ownerID, organizationID, hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,33 @@
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+func Test_DiffRuns(t *testing.T) {
+	previous := []postgres.PlaceSnapshot{
+		{Key: "a", Link: "https://maps/a", Title: "Boulangerie Martin", Phone: "01 02", Rating: 4.5},
+		{Key: "b", Link: "https://maps/b", Title: "Pizzeria Roma", Rating: 4.1},
+		{Key: "c", Link: "https://maps/c", Title: "Café du Port", Rating: 3.9},
+	}
+
+	current := []postgres.PlaceSnapshot{
+		{Key: "a", Link: "https://maps/a", Title: "Boulangerie Martin", Phone: "01 03", Rating: 4.5},
+		{Key: "b", Link: "https://maps/b", Title: "Pizzeria Roma", Rating: 4.3, Status: "Permanently closed"},
+		{Key: "d", Link: "https://maps/d", Title: "Le Bistrot", Rating: 4.8},
+	}
+
+	require.Equal(t, []postgres.PlaceChange{
+		{Kind: postgres.ChangeAdded, Link: "https://maps/d", Title: "Le Bistrot"},
+		{Kind: postgres.ChangeChanged, Link: "https://maps/a", Title: "Boulangerie Martin", Field: "phone", OldValue: "01 02", NewValue: "01 03"},
+		{Kind: postgres.ChangeChanged, Link: "https://maps/b", Title: "Pizzeria Roma", Field: "rating", OldValue: "4.1", NewValue: "4.3"},
+		{Kind: postgres.ChangeChanged, Link: "https://maps/b", Title: "Pizzeria Roma", Field: "status", NewValue: "Permanently closed"},
+		{Kind: postgres.ChangeRemoved, Link: "https://maps/c", Title: "Café du Port"},
+	}, postgres.DiffRuns(previous, current))
+
+	require.Empty(t, postgres.DiffRuns(current, current))
+}
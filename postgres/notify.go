@@ -0,0 +1,349 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// notifyTimeout bounds notifying the start or the end of a root job.
+const notifyTimeout = time.Minute
+
+// JobStarted is the status of the notification of a root job a worker
+// picked up.
+const JobStarted = "started"
+
+// JobNotification tells that a root job started, or how it ended.
+type JobNotification struct {
+	JobID          string
+	OwnerID        string
+	OrganizationID string
+	// Status is started, done or failed.
+	Status string
+	// Reason is why the job failed.
+	Reason  string
+	Queries []string
+	// Summary is nil for started and failed jobs, and when it could not be
+	// computed.
+	Summary *ResultsSummary
+	// ChatWebhook is the Slack or Discord webhook the job metadata gives in
+	// notify_webhook, for the jobs of organizations with their own channel.
+	ChatWebhook string
+	// FinishedAt is when the notification was made, also for started jobs.
+	FinishedAt time.Time
+}
+
+// Failed tells whether the job failed.
+func (n *JobNotification) Failed() bool {
+	return n.Status == statusFailed
+}
+
+// Name names the job after its first query, or its id without one.
+func (n *JobNotification) Name() string {
+	switch len(n.Queries) {
+	case 0:
+		return n.JobID
+	case 1:
+		return n.Queries[0]
+	default:
+		return fmt.Sprintf("%s (+%d)", n.Queries[0], len(n.Queries)-1)
+	}
+}
+
+// JobNotifier is told when a root job starts, completes or fails, besides the
+// job completion call of the APIClient.
+type JobNotifier interface {
+	NotifyJob(ctx context.Context, n *JobNotification) error
+}
+
+// WithJobNotifier tells n when root jobs start, complete or fail.
+func WithJobNotifier(n JobNotifier) ProviderOption {
+	return func(p *provider) {
+		p.statusManager.notifiers = append(p.statusManager.notifiers, n)
+	}
+}
+
+// newJobNotification describes the root job id of payload, and returns nil
+// for jobs with a parent.
+func newJobNotification(id string, payload []byte, status string) (*JobNotification, error) {
+	var rawJSON string
+	if err := json.Unmarshal(payload, &rawJSON); err == nil {
+		payload = []byte(rawJSON)
+	}
+
+	var jsonJob JSONJob
+	if err := json.Unmarshal(payload, &jsonJob); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal root job: %w", err)
+	}
+
+	if jsonJob.ParentID != nil {
+		return nil, nil
+	}
+
+	n := &JobNotification{
+		JobID:      id,
+		Status:     status,
+		FinishedAt: time.Now().UTC(),
+	}
+
+	md := jsonJob.Metadata
+
+	n.OwnerID, _ = md["owner_id"].(string)
+	n.OrganizationID, _ = md["organization_id"].(string)
+	n.ChatWebhook, _ = md["notify_webhook"].(string)
+
+	if raw, ok := md["queries"].([]interface{}); ok {
+		for _, q := range raw {
+			if q, ok := q.(string); ok {
+				n.Queries = append(n.Queries, q)
+			}
+		}
+	} else if jsonJob.URL != "" {
+		n.Queries = append(n.Queries, gmaps.SearchQuery(jsonJob.URL))
+	}
+
+	return n, nil
+}
+
+// notify tells the notifiers about n.
+func (s *StatusManager) notify(ctx context.Context, n *JobNotification) {
+	log := scrapemate.GetLoggerFromContext(ctx)
+
+	for _, notifier := range s.notifiers {
+		if err := notifier.NotifyJob(ctx, n); err != nil {
+			log.Error(fmt.Sprintf("failed to notify job %s: %v", n.JobID, err))
+		}
+	}
+}
+
+// rootStarted notifies that a worker picked up the job id of payload, when it
+// is a root job.
+func (s *StatusManager) rootStarted(id string, payload []byte) {
+	if len(s.notifiers) == 0 {
+		return
+	}
+
+	n, err := newJobNotification(id, payload, JobStarted)
+	if err != nil || n == nil {
+		return
+	}
+
+	s.background(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+
+		s.notify(ctx, n)
+	})
+}
+
+// rootFailed notifies that the root job id failed for reason.
+func (s *StatusManager) rootFailed(id, reason string) {
+	if len(s.notifiers) == 0 {
+		return
+	}
+
+	s.background(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+
+		log := scrapemate.GetLoggerFromContext(ctx)
+
+		var payload []byte
+		if err := s.db.QueryRowContext(ctx, `SELECT payload FROM gmaps_jobs WHERE id = $1`, id).Scan(&payload); err != nil {
+			log.Error(fmt.Sprintf("job %s: failed to read payload: %v", id, err))
+			return
+		}
+
+		n, err := newJobNotification(id, payload, statusFailed)
+		if err != nil || n == nil {
+			if err != nil {
+				log.Error(fmt.Sprintf("job %s: %v", id, err))
+			}
+
+			return
+		}
+
+		n.Reason = failureReason(reason)
+
+		s.notify(ctx, n)
+	})
+}
+
+// SMTPConfig is the server EmailNotifier sends through.
+type SMTPConfig struct {
+	// Addr is the host:port of the server. Servers offering STARTTLS are
+	// talked to over TLS.
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+// defaultNotifyTemplate is the email sent without -notify-template. Its
+// "subject" template is the subject, the rest the body.
+const defaultNotifyTemplate = `{{define "subject"}}{{if .Failed}}Scrape failed{{else}}Scrape completed{{end}}: {{.Name}}{{end}}
+{{- if .Failed -}}
+Your scrape "{{.Name}}" failed: {{.Reason}}
+{{- else -}}
+Your scrape "{{.Name}}" is done.
+{{- end}}
+
+Job: {{.JobID}}
+{{- with .Queries}}
+Queries:
+{{- range .}}
+  - {{.}}
+{{- end}}
+{{- end}}
+{{- with .Summary}}
+
+Places: {{.Places}}
+With a website: {{.WithWebsitePct}}%
+With an email: {{.WithEmailPct}}%
+With a SIREN: {{.WithSirenPct}}%
+Average rating: {{.AverageRating}}
+{{- end}}
+`
+
+// ParseNotifyTemplate parses the email template of the file path, or the
+// default template when path is empty. The template is executed with a
+// *JobNotification; the template it defines named "subject" is the subject
+// and the rest the body.
+func ParseNotifyTemplate(path string) (*template.Template, error) {
+	text := defaultNotifyTemplate
+
+	if path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		text = string(raw)
+	}
+
+	tmpl, err := template.New("body").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification template: %w", err)
+	}
+
+	if tmpl.Lookup("subject") == nil {
+		return nil, errors.New(`invalid notification template: it defines no "subject" template`)
+	}
+
+	return tmpl, nil
+}
+
+// EmailNotifier emails the owner of each root job when it completes or
+// fails, not when it starts. Recipients are read from the notification_recipients table, by
+// organization id or else by owner id; jobs of owners without one send
+// nothing.
+type EmailNotifier struct {
+	db   *sql.DB
+	smtp SMTPConfig
+	tmpl *template.Template
+}
+
+// NewEmailNotifier creates an EmailNotifier sending the emails tmpl renders
+// through the server of cfg.
+func NewEmailNotifier(db *sql.DB, cfg SMTPConfig, tmpl *template.Template) *EmailNotifier {
+	return &EmailNotifier{
+		db:   db,
+		smtp: cfg,
+		tmpl: tmpl,
+	}
+}
+
+// NotifyJob emails n to the recipient of its owner.
+func (e *EmailNotifier) NotifyJob(ctx context.Context, n *JobNotification) error {
+	if n.Status == JobStarted {
+		return nil
+	}
+
+	to, err := e.recipient(ctx, n.OrganizationID, n.OwnerID)
+	if err != nil || to == "" {
+		return err
+	}
+
+	msg, err := e.Message(n, to)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+
+	if e.smtp.Username != "" {
+		host, _, _ := net.SplitHostPort(e.smtp.Addr)
+		auth = smtp.PlainAuth("", e.smtp.Username, e.smtp.Password, host)
+	}
+
+	if err := smtp.SendMail(e.smtp.Addr, auth, e.smtp.From, []string{to}, msg); err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", to, err)
+	}
+
+	return nil
+}
+
+// recipient returns the address notifications of the organization, or else
+// of the owner, go to, or "" without one.
+func (e *EmailNotifier) recipient(ctx context.Context, organizationID, ownerID string) (string, error) {
+	for _, id := range []string{organizationID, ownerID} {
+		if id == "" {
+			continue
+		}
+
+		var email string
+
+		err := e.db.QueryRowContext(ctx, `SELECT email FROM notification_recipients WHERE owner_id = $1`, id).Scan(&email)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+
+		if err != nil {
+			return "", fmt.Errorf("failed to read recipient: %w", err)
+		}
+
+		return email, nil
+	}
+
+	return "", nil
+}
+
+// Message renders the email telling to about n, headers included.
+func (e *EmailNotifier) Message(n *JobNotification, to string) ([]byte, error) {
+	var subject, body bytes.Buffer
+
+	if err := e.tmpl.ExecuteTemplate(&subject, "subject", n); err != nil {
+		return nil, fmt.Errorf("failed to render subject: %w", err)
+	}
+
+	if err := e.tmpl.Execute(&body, n); err != nil {
+		return nil, fmt.Errorf("failed to render body: %w", err)
+	}
+
+	var msg bytes.Buffer
+
+	fmt.Fprintf(&msg, "From: %s\r\n", e.smtp.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject.String())))
+	fmt.Fprintf(&msg, "Date: %s\r\n", n.FinishedAt.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(strings.TrimSpace(body.String()), "\n", "\r\n"))
+	msg.WriteString("\r\n")
+
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,41 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+func Test_EmailNotifierMessage(t *testing.T) {
+	tmpl, err := postgres.ParseNotifyTemplate("")
+	require.NoError(t, err)
+
+	notifier := postgres.NewEmailNotifier(nil, postgres.SMTPConfig{From: "scraper@example.com"}, tmpl)
+
+	n := &postgres.JobNotification{
+		JobID:      "job-1",
+		Status:     "done",
+		Queries:    []string{"plombier paris", "plombier lyon"},
+		Summary:    &postgres.ResultsSummary{Places: 12, WithWebsitePct: 75, AverageRating: 4.4},
+		FinishedAt: time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC),
+	}
+
+	msg, err := notifier.Message(n, "owner@example.com")
+	require.NoError(t, err)
+	require.Contains(t, string(msg), "To: owner@example.com\r\n")
+	require.Contains(t, string(msg), "Subject: Scrape completed: plombier paris (+1)\r\n")
+	require.Contains(t, string(msg), "\r\n\r\nYour scrape \"plombier paris (+1)\" is done.\r\n")
+	require.Contains(t, string(msg), "  - plombier lyon\r\n")
+	require.Contains(t, string(msg), "Places: 12\r\nWith a website: 75%\r\n")
+
+	n.Status, n.Reason, n.Summary = "failed", "search batch has no queries", nil
+
+	msg, err = notifier.Message(n, "owner@example.com")
+	require.NoError(t, err)
+	require.Contains(t, string(msg), "Subject: Scrape failed: plombier paris (+1)\r\n")
+	require.Contains(t, string(msg), `Your scrape "plombier paris (+1)" failed: search batch has no queries`)
+	require.NotContains(t, string(msg), "Places:")
+}
@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// pacedFilter leaves out the place jobs of the owners whose next place job
+// slot has not come yet, whichever worker booked it.
+const pacedFilter = `
+			AND NOT (
+				payload_type = '` + jobTypePlace + `'
+				AND payload::jsonb -> 'metadata' ->> 'owner_id' IN (SELECT owner_id FROM owner_pacing WHERE next_at > NOW())
+			)`
+
+// reserveSlotQuery books the place job slot of the owner $1 and the next one
+// $2 seconds later, unless the slot has not come yet. It returns no row then.
+const reserveSlotQuery = `
+	INSERT INTO owner_pacing AS p (owner_id, next_at)
+	VALUES ($1, NOW() + make_interval(secs => $2))
+	ON CONFLICT (owner_id) DO UPDATE SET next_at = EXCLUDED.next_at
+	WHERE p.next_at <= NOW()
+	RETURNING owner_id`
+
+// Pacer spreads the place jobs of each owner evenly over time, so a campaign
+// queueing thousands of places at once loads Google pages at a steady rate
+// instead of in a burst. The slots are booked in the owner_pacing table, so
+// the limit holds across every worker.
+type Pacer struct {
+	db       *sql.DB
+	interval time.Duration
+}
+
+// NewPacer creates a Pacer dispatching at most perHour place jobs per owner
+// every hour.
+func NewPacer(db *sql.DB, perHour int) *Pacer {
+	return &Pacer{
+		db:       db,
+		interval: time.Hour / time.Duration(perHour),
+	}
+}
+
+// WithPacer stops fetching the place jobs of owners above the rate of pc
+// until their turn comes.
+func WithPacer(pc *Pacer) ProviderOption {
+	return func(p *provider) {
+		p.pacer = pc
+	}
+}
+
+// reserve books the next place job slot of owner. It returns false when the
+// slot has not come yet.
+func (pc *Pacer) reserve(ctx context.Context, owner string) (bool, error) {
+	var booked string
+
+	err := pc.db.QueryRowContext(ctx, reserveSlotQuery, owner, pc.interval.Seconds()).Scan(&booked)
+
+	switch {
+	case err == sql.ErrNoRows:
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("failed to reserve a place job slot: %w", err)
+	}
+
+	return true, nil
+}
+
+// pace returns the jobs of a fetched batch to run now. Only one place job of
+// each owner runs per slot: the others, fetched before the slot was booked,
+// go back to the queue as new for any worker to fetch in their turn.
+func (p *provider) pace(ctx context.Context, jobs []scrapemate.IJob) ([]scrapemate.IJob, error) {
+	if p.pacer == nil {
+		return jobs, nil
+	}
+
+	var (
+		ans  = make([]scrapemate.IJob, 0, len(jobs))
+		held []string
+		// free tells whether the slot of an owner is still free in this
+		// batch, once booked
+		free = map[string]bool{}
+	)
+
+	for _, job := range jobs {
+		place, ok := job.(*gmaps.PlaceJob)
+		if !ok {
+			ans = append(ans, job)
+			continue
+		}
+
+		ok, seen := free[place.OwnerID]
+		if !seen {
+			var err error
+
+			if ok, err = p.pacer.reserve(ctx, place.OwnerID); err != nil {
+				return nil, err
+			}
+		}
+
+		free[place.OwnerID] = false
+
+		if ok {
+			ans = append(ans, job)
+		} else {
+			held = append(held, job.GetID())
+		}
+	}
+
+	const q = `UPDATE gmaps_jobs SET status = $1 WHERE id = $2 AND status = $3`
+
+	for _, id := range held {
+		if _, err := p.db.ExecContext(ctx, q, statusNew, id, statusQueued); err != nil {
+			return nil, fmt.Errorf("failed to put paced job back: %w", err)
+		}
+	}
+
+	return ans, nil
+}
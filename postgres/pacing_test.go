@@ -0,0 +1,87 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+func Test_PacerPutsBackJobsOutOfTurn(t *testing.T) {
+	fake, db := newFakeDB(t)
+
+	registry := postgres.NewCodecRegistry()
+
+	var (
+		rows [][]driver.Value
+		ids  []string
+	)
+
+	for _, owner := range []string{"owner-1", "owner-1", "owner-2"} {
+		job := gmaps.NewPlaceJob("parent", "en", "https://www.google.com/maps/place/x", owner, "", false, false)
+
+		encoded, typ, err := registry.EncodeJob(job)
+		require.NoError(t, err)
+
+		payload, err := json.Marshal(encoded)
+		require.NoError(t, err)
+
+		rows = append(rows, []driver.Value{job.ID, typ, payload})
+		ids = append(ids, job.ID)
+	}
+
+	fake.on("WITH updated AS", func([]driver.Value) answer {
+		batch := rows
+		rows = nil
+
+		return answer{columns: []string{"id", "payload_type", "payload"}, rows: batch}
+	})
+
+	// owner-2 got its place job from another worker a moment ago
+	fake.on("INSERT INTO owner_pacing", func(args []driver.Value) answer {
+		if args[0] == "owner-2" {
+			return answer{columns: []string{"owner_id"}}
+		}
+
+		return answer{columns: []string{"owner_id"}, rows: [][]driver.Value{{args[0]}}}
+	})
+
+	p := postgres.NewProvider(db, "", "", postgres.WithPacer(postgres.NewPacer(db, 3600)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	jobc, _ := p.Jobs(ctx)
+
+	select {
+	case job := <-jobc:
+		require.Equal(t, ids[0], job.GetID())
+	case <-time.After(time.Second):
+		t.Fatal("no job dispatched")
+	}
+
+	select {
+	case job := <-jobc:
+		t.Fatalf("job %s dispatched out of turn", job.GetID())
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// one slot asked per owner, the next one a second later
+	reserved := fake.ran("INSERT INTO owner_pacing")
+	require.Len(t, reserved, 2)
+	require.InDelta(t, 1.0, reserved[0][1], 0.001)
+
+	var putBack []driver.Value
+	for _, args := range fake.ran("WHERE id = $2 AND status = $3") {
+		putBack = append(putBack, args[1])
+	}
+
+	require.Equal(t, []driver.Value{ids[1], ids[2]}, putBack)
+	require.NotEmpty(t, fake.ran("SELECT owner_id FROM owner_pacing WHERE next_at > NOW()"))
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// PlaceArchive keeps the raw APP_INITIALIZATION_STATE data of every place
+// scraped, gzipped, so later versions of the parser can be run over it
+// without scraping the places again.
+type PlaceArchive interface {
+	// Put stores the gzipped raw data of the place key, see PlaceKey,
+	// replacing an older copy.
+	Put(ctx context.Context, key string, gz []byte) error
+}
+
+// TablePlaceArchive keeps the archive in the place_archive table.
+type TablePlaceArchive struct {
+	db *sql.DB
+}
+
+// NewTablePlaceArchive creates a TablePlaceArchive.
+func NewTablePlaceArchive(db *sql.DB) *TablePlaceArchive {
+	return &TablePlaceArchive{db: db}
+}
+
+// WithPlaceArchive stores the raw data of the places scraped in a.
+func WithPlaceArchive(a PlaceArchive) ProviderOption {
+	return func(p *provider) {
+		p.placeArchive = a
+	}
+}
+
+func (a *TablePlaceArchive) Put(ctx context.Context, key string, gz []byte) error {
+	const q = `INSERT INTO place_archive (key, data, archived_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET data = EXCLUDED.data, archived_at = EXCLUDED.archived_at`
+
+	if _, err := a.db.ExecContext(ctx, q, key, gz, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to write place archive: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns the raw data archived for the place at u, uncompressed.
+func (a *TablePlaceArchive) Get(ctx context.Context, u string) ([]byte, bool, error) {
+	const q = `SELECT data FROM place_archive WHERE key = $1`
+
+	var gz []byte
+
+	err := a.db.QueryRowContext(ctx, q, PlaceKey(u)).Scan(&gz)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read place archive: %w", err)
+	}
+
+	raw, err := Gunzip(gz)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return raw, true, nil
+}
+
+// Gzip compresses raw place data for a PlaceArchive.
+func Gzip(raw []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+
+	if _, err := zw.Write(raw); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Gunzip uncompresses place data read from a PlaceArchive.
+func Gunzip(gz []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		return nil, fmt.Errorf("invalid archived place data: %w", err)
+	}
+	defer zr.Close()
+
+	return io.ReadAll(zr)
+}
+
+// archivePlace stores the place data of resp, unless it came from the place
+// cache and is no newer than the copy archived when it was scraped.
+func (w *jobWrapper) archivePlace(resp *scrapemate.Response) {
+	if w.provider.placeArchive == nil {
+		return
+	}
+
+	if _, hit := resp.Meta[gmaps.CachedMetaKey]; hit {
+		return
+	}
+
+	raw, ok := resp.Meta["json"].([]byte)
+	if !ok || len(raw) == 0 {
+		return
+	}
+
+	key := PlaceKey(w.GetURL())
+
+	w.provider.goBackground(func() {
+		log := scrapemate.GetLoggerFromContext(context.Background())
+
+		gz, err := Gzip(raw)
+		if err != nil {
+			log.Error(fmt.Sprintf("jobWrapper.archivePlace: %v", err))
+			return
+		}
+
+		if err := w.provider.placeArchive.Put(context.Background(), key, gz); err != nil {
+			log.Error(fmt.Sprintf("jobWrapper.archivePlace: %v", err))
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// placeIDRe matches the feature id Google puts in the data part of place
+// URLs, e.g. !1s0x47e66e2964e34e2d:0x8ddca9ee380ef7e0. It identifies the
+// place whatever the search that led to it.
+var placeIDRe = regexp.MustCompile(`!1s(0x[0-9a-fA-F]+:0x[0-9a-fA-F]+)`)
+
+// PlaceCache keeps the raw data of every place scraped, whoever asked for it,
+// so a place scraped recently by any organization is not scraped again. Only
+// the Google data is shared: a cached place still goes through the place job
+// of the owner asking for it, lands in their results and gets their tags and
+// lead status.
+type PlaceCache struct {
+	db  *sql.DB
+	ttl time.Duration
+}
+
+// NewPlaceCache creates a PlaceCache serving places scraped less than ttl ago.
+func NewPlaceCache(db *sql.DB, ttl time.Duration) *PlaceCache {
+	return &PlaceCache{
+		db:  db,
+		ttl: ttl,
+	}
+}
+
+// WithPlaceCache gives the place jobs the fresh copies of c as they are
+// fetched, so they use them instead of loading the place page, and fills c
+// with the places scraped.
+func WithPlaceCache(c *PlaceCache) ProviderOption {
+	return func(p *provider) {
+		p.placeCache = c
+	}
+}
+
+// PlaceKey returns the key a place URL is cached under: the place id when the
+// URL has one, the URL without its query string otherwise.
+func PlaceKey(u string) string {
+	if m := placeIDRe.FindStringSubmatch(u); m != nil {
+		return m[1]
+	}
+
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+
+	parsed.RawQuery = ""
+	parsed.Fragment = ""
+
+	return parsed.String()
+}
+
+// Get returns the raw data of the place at u if it was scraped less than the
+// TTL ago.
+func (c *PlaceCache) Get(ctx context.Context, u string) ([]byte, bool, error) {
+	const q = `SELECT data FROM place_cache WHERE key = $1 AND scraped_at > $2`
+
+	var raw []byte
+
+	err := c.db.QueryRowContext(ctx, q, PlaceKey(u), time.Now().UTC().Add(-c.ttl)).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read place cache: %w", err)
+	}
+
+	return raw, true, nil
+}
+
+// Put stores the raw data of the place at u, replacing an older copy.
+func (c *PlaceCache) Put(ctx context.Context, u string, raw []byte) error {
+	const q = `INSERT INTO place_cache (key, data, scraped_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET data = EXCLUDED.data, scraped_at = EXCLUDED.scraped_at`
+
+	if _, err := c.db.ExecContext(ctx, q, PlaceKey(u), raw, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to write place cache: %w", err)
+	}
+
+	return nil
+}
+
+// useCachedPlaces gives the place jobs of a fetched batch the data the cache
+// has of their place, before they are dispatched, so that they run without
+// waiting for a browser slot. Replayed fixtures are not mixed with it.
+func (p *provider) useCachedPlaces(ctx context.Context, jobs []scrapemate.IJob) {
+	if p.placeCache == nil || p.fixtures != nil {
+		return
+	}
+
+	for _, job := range jobs {
+		place, ok := job.(*gmaps.PlaceJob)
+		if !ok || place.ExtractExtraReviews {
+			continue
+		}
+
+		raw, hit, err := p.placeCache.Get(ctx, place.GetURL())
+		if err != nil {
+			log := scrapemate.GetLoggerFromContext(ctx)
+			log.Info(fmt.Sprintf("useCachedPlaces: %s: %v", place.GetURL(), err))
+
+			continue
+		}
+
+		if hit {
+			place.UseCachedData(raw)
+		}
+	}
+}
+
+// cachePlace stores the place data of resp, unless it came from the cache.
+func (w *jobWrapper) cachePlace(resp *scrapemate.Response) {
+	if w.provider.placeCache == nil {
+		return
+	}
+
+	if _, hit := resp.Meta[gmaps.CachedMetaKey]; hit {
+		return
+	}
+
+	raw, ok := resp.Meta["json"].([]byte)
+	if !ok || len(raw) == 0 {
+		return
+	}
+
+	u := w.GetURL()
+
+	w.provider.goBackground(func() {
+		if err := w.provider.placeCache.Put(context.Background(), u, raw); err != nil {
+			log := scrapemate.GetLoggerFromContext(context.Background())
+			log.Error(fmt.Sprintf("jobWrapper.cachePlace: %v", err))
+		}
+	})
+}
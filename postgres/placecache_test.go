@@ -0,0 +1,60 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/gosom/scrapemate"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+func Test_PlaceKey(t *testing.T) {
+	const id = "0x47e66e2964e34e2d:0x8ddca9ee380ef7e0"
+
+	// the same place reached from two searches
+	a := postgres.PlaceKey("https://www.google.com/maps/place/Boulangerie/data=!4m7!3m6!1s" + id + "!8m2!3d48.85!4d2.35?authuser=0&hl=fr&rclk=1")
+	b := postgres.PlaceKey("https://www.google.com/maps/place/Boulangerie+Martin/data=!4m10!1m2!2m1!1spain!3m6!1s" + id + "!8m2?hl=en")
+
+	require.Equal(t, id, a)
+	require.Equal(t, a, b)
+
+	require.Equal(t, "https://www.google.com/maps/place/Boulangerie", postgres.PlaceKey("https://www.google.com/maps/place/Boulangerie?hl=fr"))
+}
+
+func Test_ProviderServesCachedPlacesBeforeDispatch(t *testing.T) {
+	fake, db := newFakeDB(t)
+
+	raw := []byte(`["cached"]`)
+
+	fake.on("FROM place_cache", func(args []driver.Value) answer {
+		if args[0] != "https://www.google.com/maps/place/cached" {
+			return answer{columns: []string{"data"}}
+		}
+
+		return answer{columns: []string{"data"}, rows: [][]driver.Value{{raw}}}
+	})
+
+	cached := gmaps.NewPlaceJob("", "en", "https://www.google.com/maps/place/cached", "owner-1", "", false, false)
+	missed := gmaps.NewPlaceJob("", "en", "https://www.google.com/maps/place/other", "owner-1", "", false, false)
+	queueJobs(t, fake, cached, missed)
+
+	p := postgres.NewProvider(db, "", "", postgres.WithPlaceCache(postgres.NewPlaceCache(db, time.Hour)))
+	jobs := fetchJobs(t, p, 2)
+
+	// the cache was read when the jobs were fetched, and the cached place
+	// needs no page
+	resp := jobs[0].BrowserActions(context.Background(), nil)
+	require.NoError(t, resp.Error)
+	require.Equal(t, raw, resp.Meta["json"])
+	require.Equal(t, true, resp.Meta[gmaps.CachedMetaKey])
+
+	// the other place loads its page
+	_, ok := jobs[1].(interface{ Unwrap() scrapemate.IJob }).Unwrap().(*gmaps.PlaceJob).CachedResponse()
+	require.False(t, ok)
+	require.Len(t, fake.ran("FROM place_cache"), 2)
+}
@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/apiauth"
+	"github.com/gosom/google-maps-scraper/httpjson"
+)
+
+const (
+	// DefaultPreviewSize is how many results a preview holds unless told.
+	DefaultPreviewSize = 20
+	// MaxPreviewSize and MaxPreviewWindow bound the results of a preview
+	// and the latest results they are sampled from.
+	MaxPreviewSize   = 100
+	MaxPreviewWindow = 1000
+)
+
+// ResultPreview is a result with the few fields a live preview shows. The
+// emails are not decrypted, only told to be there.
+type ResultPreview struct {
+	ID           int64     `json:"id"`
+	Title        string    `json:"title"`
+	Category     string    `json:"category"`
+	Address      string    `json:"address"`
+	Website      string    `json:"website"`
+	Phone        string    `json:"phone"`
+	HasEmail     bool      `json:"has_email"`
+	ReviewRating float64   `json:"review_rating"`
+	ReviewCount  int       `json:"review_count"`
+	Latitude     float64   `json:"latitude"`
+	Longitude    float64   `json:"longitude"`
+	QualityScore int       `json:"quality_score"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Preview is a sample of the latest results of a root job while it runs.
+type Preview struct {
+	JobID    string      `json:"job_id"`
+	Progress JobProgress `json:"progress"`
+	// Total counts the results of the job so far.
+	Total   int             `json:"total"`
+	Results []ResultPreview `json:"results"`
+}
+
+// previewQuery picks every $3rd of the latest $2 results of the root job $1,
+// the newest first, up to $4 of them.
+const previewQuery = `
+	SELECT id, title, category, address, website, phone, has_email, review_rating, review_count,
+		latitude, longitude, quality_score, created_at
+	FROM (
+		SELECT id, COALESCE(title, '') AS title, COALESCE(category, '') AS category,
+			COALESCE(address, '') AS address, COALESCE(website, '') AS website,
+			COALESCE(phones[1], '') AS phone, COALESCE(CARDINALITY(emails), 0) > 0 AS has_email,
+			COALESCE(review_rating, 0) AS review_rating, COALESCE(review_count, 0) AS review_count,
+			COALESCE(latitude, 0) AS latitude, COALESCE(longitude, 0) AS longitude,
+			COALESCE(quality_score, 0) AS quality_score, created_at,
+			ROW_NUMBER() OVER (ORDER BY id DESC) - 1 AS n
+		FROM (SELECT * FROM results WHERE parent_id = $1 ORDER BY id DESC LIMIT $2) latest
+	) sampled
+	WHERE n % $3 = 0
+	ORDER BY id DESC
+	LIMIT $4`
+
+// PreviewResults returns size results of the root job id spread evenly over
+// its latest window results, the newest first: the latest size results when
+// window is not larger than size. It returns ErrJobNotFound for unknown jobs.
+func PreviewResults(ctx context.Context, db *sql.DB, id string, size, window int) (*Preview, error) {
+	window = max(window, size)
+
+	progress, err := GetJobProgress(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ans := Preview{
+		JobID:    id,
+		Progress: progress,
+		Results:  []ResultPreview{},
+	}
+
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM results WHERE parent_id = $1`, id).Scan(&ans.Total); err != nil {
+		return nil, fmt.Errorf("failed to count results: %w", err)
+	}
+
+	rows, err := db.QueryContext(ctx, previewQuery, id, window, window/size, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to preview results: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r ResultPreview
+
+		err := rows.Scan(&r.ID, &r.Title, &r.Category, &r.Address, &r.Website, &r.Phone, &r.HasEmail,
+			&r.ReviewRating, &r.ReviewCount, &r.Latitude, &r.Longitude, &r.QualityScore, &r.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		ans.Results = append(ans.Results, r)
+	}
+
+	return &ans, rows.Err()
+}
+
+// PreviewHandler serves GET /jobs/{id}/preview, the preview of the results
+// of a root job. The limit query parameter sets its size and window the
+// latest results it is sampled from, by default as many.
+func PreviewHandler(db *sql.DB) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /jobs/{id}/preview", func(w http.ResponseWriter, r *http.Request) {
+		size, err := previewParam(r, "limit", DefaultPreviewSize, MaxPreviewSize)
+		if err != nil {
+			httpjson.Write(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		window, err := previewParam(r, "window", size, MaxPreviewWindow)
+		if err != nil {
+			httpjson.Write(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		id := r.PathValue("id")
+
+		var preview *Preview
+
+		err = CheckJobOrganization(r.Context(), db, id, apiauth.GetOrganizationFromContext(r.Context()))
+		if err == nil {
+			preview, err = PreviewResults(r.Context(), db, id, size, window)
+		}
+
+		switch {
+		case errors.Is(err, ErrJobNotFound):
+			httpjson.Write(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		case err != nil:
+			httpjson.Write(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		default:
+			httpjson.Write(w, http.StatusOK, preview)
+		}
+	})
+
+	return mux
+}
+
+// previewParam reads the query parameter name of r, between 1 and maxValue.
+func previewParam(r *http.Request, name string, def, maxValue int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > maxValue {
+		return 0, fmt.Errorf("%s must be between 1 and %d", name, maxValue)
+	}
+
+	return n, nil
+}
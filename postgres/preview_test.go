@@ -0,0 +1,31 @@
+package postgres_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+func Test_PreviewHandler_InvalidParams(t *testing.T) {
+	// the parameters are checked before the database is read
+	h := postgres.PreviewHandler(nil)
+
+	tests := map[string]string{
+		"/jobs/abc/preview?limit=0":          "limit must be between 1 and 100",
+		"/jobs/abc/preview?limit=101":        "limit must be between 1 and 100",
+		"/jobs/abc/preview?window=5000":      "window must be between 1 and 1000",
+		"/jobs/abc/preview?limit=x&window=2": "limit must be between 1 and 100",
+	}
+
+	for path, want := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		require.Equal(t, http.StatusBadRequest, rec.Code, path)
+		require.Contains(t, rec.Body.String(), want, path)
+	}
+}
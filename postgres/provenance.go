@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// provenanceField is an enriched column whose source an UPDATE records.
+type provenanceField struct {
+	column string
+	// cond is the SQL condition under which the UPDATE fills the column; the
+	// source is always recorded when empty.
+	cond string
+	// source is the index of the argument holding the gmaps.FieldSource.
+	source int
+}
+
+// fieldSource returns the JSON of the gmaps.FieldSource of source fetched at.
+func fieldSource(source string, at time.Time) string {
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+
+	raw, _ := json.Marshal(gmaps.FieldSource{Source: source, FetchedAt: at})
+
+	return string(raw)
+}
+
+// setProvenance returns the SET clause merging the sources of fields into
+// the provenance column of a result, for the fields the UPDATE fills only.
+func setProvenance(fields []provenanceField) string {
+	pairs := make([]string, 0, len(fields))
+
+	for _, f := range fields {
+		value := fmt.Sprintf("$%d::jsonb", f.source)
+		if f.cond != "" {
+			value = fmt.Sprintf("CASE WHEN %s THEN %s END", f.cond, value)
+		}
+
+		pairs = append(pairs, fmt.Sprintf("'%s', %s", f.column, value))
+	}
+
+	return fmt.Sprintf("provenance = COALESCE(provenance, '{}'::jsonb) || jsonb_strip_nulls(jsonb_build_object(%s))",
+		strings.Join(pairs, ", "))
+}
+
+// provenanceJSON returns the JSON of p, or "" when it is empty.
+func provenanceJSON(p gmaps.Provenance) string {
+	if len(p) == 0 {
+		return ""
+	}
+
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return ""
+	}
+
+	return string(raw)
+}
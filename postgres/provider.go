@@ -5,15 +5,20 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gosom/scrapemate"
 
 	"github.com/google/uuid"
+	"github.com/gosom/google-maps-scraper/browserpool"
 	"github.com/gosom/google-maps-scraper/entreprise"
+	"github.com/gosom/google-maps-scraper/exiter"
 	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/privacy"
 )
 
 const (
@@ -22,6 +27,9 @@ const (
 	statusProcessing = "processing"
 	statusDone       = "done"
 	statusFailed     = "failed"
+	// statusDeferred jobs were inserted with the job that created them and
+	// wait for promoteDeferredJobs to make them new.
+	statusDeferred = "deferred"
 )
 
 var _ scrapemate.JobProvider = (*provider)(nil)
@@ -47,14 +55,80 @@ type provider struct {
 	apiClient     *APIClient
 	statusManager *StatusManager
 	codecRegistry *CodecRegistry
+	blockReporter gmaps.BlockReporter
+	browserPool   *browserpool.Pool
+	resources     *gmaps.ResourceBlocking
+	placeClient   *http.Client
+	fixtures      *gmaps.Fixtures
+	placeCache    *PlaceCache
+	placeArchive  PlaceArchive
+	memory        *MemoryWatchdog
+	results       *ResultBuffer
+	geocoder      gmaps.ReverseGeocoder
+	categories    gmaps.CategoryMapper
+	companyCache  *CompanyCache
+	tuning        *Tuning
+	// fairScheduling makes fetchJobs take turns between tenants, with the
+	// JSON encoded tenantWeights.
+	fairScheduling bool
+	tenantWeights  string
+	pacer          *Pacer
+	// privacy redacts the personal data of the results before they are
+	// saved.
+	privacy *privacy.Policies
+	// recordFailures stores why jobs fail, for the reports and the dashboard.
+	recordFailures bool
+	// monitorChanges records what place jobs see, for DiffRuns.
+	monitorChanges bool
+	// socialProfiles keeps the jobs fetching the social pages of places.
+	socialProfiles bool
+	activity       *exiter.InactivityMonitor
+	draining       atomic.Bool
+	background     sync.WaitGroup
+	// credentials holds the register accounts of the organizations that
+	// brought their own.
+	credentials *CredentialStore
+	// registers are the services company jobs query the registers with.
+	registers *entreprise.Services
+	// skipServiceArea skips the address based enrichment of service-area
+	// businesses.
+	skipServiceArea bool
+	// cipher encrypts the emails and directors the jobs find.
+	cipher *FieldCipher
+	// review queues the doubtful company matches.
+	review *ReviewQueue
 }
 
 type providerKey struct{}
 
+// ProviderOption configures the postgres provider.
+type ProviderOption func(*provider)
+
+// WithBlockReporter sets the reporter notified when Google blocks a page,
+// typically the proxy forwarder so it can rotate the upstream.
+func WithBlockReporter(r gmaps.BlockReporter) ProviderOption {
+	return func(p *provider) {
+		p.blockReporter = r
+	}
+}
+
 var _ gmaps.CompanyDataChecker = (*provider)(nil)
 
-// CheckCompanyDataExists checks if company data exists in the database.
+// CheckCompanyDataExists checks if company data exists in the database: in
+// the company cache when there is one, then in the results of the owner.
 func (p *provider) CheckCompanyDataExists(ctx context.Context, title, address, ownerID, organizationID string) (*entreprise.CompanyInfo, bool, error) {
+	if p.companyCache != nil {
+		info, ok, err := p.companyCache.Get(ctx, title, address)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if ok {
+			info.Source = "company cache"
+			return info, true, nil
+		}
+	}
+
 	query := NewCompanyDataQuery(title, address, ownerID, organizationID)
 	q, args, ok := query.Build()
 	if !ok {
@@ -76,7 +150,11 @@ func (p *provider) CheckCompanyDataExists(ctx context.Context, title, address, o
 
 	data := &entreprise.CompanyInfo{}
 	if societeDirigeants.Valid && societeDirigeants.String != "" {
-		data.SocieteDirigeants = strings.Split(societeDirigeants.String, ",")
+		directors, err := p.cipher.open(societeDirigeants.String)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read BODACC data: %w", err)
+		}
+		data.SocieteDirigeants = strings.Split(directors, ",")
 		for i := range data.SocieteDirigeants {
 			data.SocieteDirigeants[i] = strings.TrimSpace(data.SocieteDirigeants[i])
 		}
@@ -104,8 +182,162 @@ func (p *provider) CheckCompanyDataExists(ctx context.Context, title, address, o
 	return data, true, nil
 }
 
+// WithBrowserPool sets the pool that warms up and fingerprints every browser
+// context before a job runs in it.
+func WithBrowserPool(bp *browserpool.Pool) ProviderOption {
+	return func(p *provider) {
+		p.browserPool = bp
+	}
+}
+
+// WithResourceBlocking sets the requests search and place jobs abort.
+func WithResourceBlocking(rb *gmaps.ResourceBlocking) ProviderOption {
+	return func(p *provider) {
+		p.resources = rb
+	}
+}
+
+// WithPlaceHTTPClient sets the client the jobs of fast mode are loaded with,
+// see gmaps.FetchWithoutBrowser.
+func WithPlaceHTTPClient(c *http.Client) ProviderOption {
+	return func(p *provider) {
+		p.placeClient = c
+	}
+}
+
+// WithFixtures makes search and place jobs replay the pages of f, or record
+// them when f.Record is set.
+func WithFixtures(f *gmaps.Fixtures) ProviderOption {
+	return func(p *provider) {
+		p.fixtures = f
+	}
+}
+
+// WithPrivacy redacts the personal data of the places and enrichment
+// results with the policy of their organization before they are saved.
+func WithPrivacy(policies *privacy.Policies) ProviderOption {
+	return func(p *provider) {
+		p.privacy = policies
+	}
+}
+
+// WithReverseGeocoder sets the commune codes of French places with g.
+func WithReverseGeocoder(g gmaps.ReverseGeocoder) ProviderOption {
+	return func(p *provider) {
+		p.geocoder = g
+	}
+}
+
+// WithRegisterServices makes company jobs query the registers with the
+// services of s, instead of those created without options.
+func WithRegisterServices(s *entreprise.Services) ProviderOption {
+	return func(p *provider) {
+		p.registers = s
+	}
+}
+
+// WithSkipServiceAreaEnrichment makes place jobs neither reverse geocode
+// service-area businesses nor look up their company, as only their service
+// area is known.
+func WithSkipServiceAreaEnrichment(enabled bool) ProviderOption {
+	return func(p *provider) {
+		p.skipServiceArea = enabled
+	}
+}
+
+// WithFieldCipher makes the jobs store the emails and directors they find
+// encrypted with c, and read the stored ones with it.
+func WithFieldCipher(c *FieldCipher) ProviderOption {
+	return func(p *provider) {
+		p.cipher = c
+	}
+}
+
+// WithCategoryMapper sets the canonical category id of places with m.
+func WithCategoryMapper(m gmaps.CategoryMapper) ProviderOption {
+	return func(p *provider) {
+		p.categories = m
+	}
+}
+
+// WithInactivityMonitor reports fetched and running jobs to m.
+func WithInactivityMonitor(m *exiter.InactivityMonitor) ProviderOption {
+	return func(p *provider) {
+		p.activity = m
+	}
+}
+
+// WithLenientDecoding decodes the queued jobs with LenientDecoding, so jobs
+// queued by older versions run with defaults instead of failing the fetch.
+func WithLenientDecoding() ProviderOption {
+	return func(p *provider) {
+		p.codecRegistry = NewCodecRegistry(LenientDecoding())
+	}
+}
+
+// WithCompletionMonitor tells m about each root job that is done.
+func WithCompletionMonitor(m *exiter.CompletionMonitor) ProviderOption {
+	return func(p *provider) {
+		p.statusManager.onRootDone = append(p.statusManager.onRootDone, m.RootDone)
+	}
+}
+
+// Drainer is implemented by the provider returned by NewProvider.
+type Drainer interface {
+	// Drain stops fetching jobs and waits for the database updates that run
+	// in the background (enrichment results and jobs) to finish, then makes
+	// the revalidation calls waiting for their interval.
+	Drain(ctx context.Context) error
+}
+
+var _ Drainer = (*provider)(nil)
+
+func (p *provider) Drain(ctx context.Context) error {
+	p.draining.Store(true)
+
+	// the root jobs waiting for the writer are completed with the results
+	// saved so far
+	if p.statusManager.summaries != nil {
+		p.statusManager.summaries.completeAll()
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		p.background.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return p.apiClient.FlushRevalidations(ctx)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WithAPIClient makes the revalidation and job completion calls with c
+// instead of a client of its own, so that they are coalesced with those of
+// the other users of c.
+func WithAPIClient(c *APIClient) ProviderOption {
+	return func(p *provider) {
+		p.apiClient = c
+		p.statusManager.apiClient = c
+	}
+}
+
+// goBackground runs fn in a goroutine Drain waits for.
+func (p *provider) goBackground(fn func()) {
+	p.background.Add(1)
+
+	go func() {
+		defer p.background.Done()
+		fn()
+	}()
+}
+
 // NewProvider creates a new JobProvider backed by PostgreSQL.
-func NewProvider(db *sql.DB, revalidationAPIURL, jobCompletionAPIURL string) scrapemate.JobProvider {
+func NewProvider(db *sql.DB, revalidationAPIURL, jobCompletionAPIURL string, opts ...ProviderOption) scrapemate.JobProvider {
 	apiClient := NewAPIClient(revalidationAPIURL, jobCompletionAPIURL)
 	codecRegistry := NewCodecRegistry()
 
@@ -119,6 +351,13 @@ func NewProvider(db *sql.DB, revalidationAPIURL, jobCompletionAPIURL string) scr
 		codecRegistry: codecRegistry,
 	}
 
+	// Drain waits for the summaries and completion calls of root jobs
+	prov.statusManager.background = prov.goBackground
+
+	for _, opt := range opts {
+		opt(&prov)
+	}
+
 	return &prov
 }
 
@@ -181,36 +420,6 @@ func (p *provider) Push(ctx context.Context, job scrapemate.IJob) error {
 		return fmt.Errorf("invalid job type: %w", err)
 	}
 
-	// Extract parentID from the job
-	var parentID *string
-	actualJob := job
-	if wrapper, ok := job.(*jobWrapper); ok {
-		actualJob = wrapper.IJob
-	}
-
-	switch j := actualJob.(type) {
-	case *gmaps.GmapJob:
-		if j.ParentID != "" {
-			parentID = &j.ParentID
-		}
-	case *gmaps.PlaceJob:
-		if j.ParentID != "" {
-			parentID = &j.ParentID
-		}
-	case *gmaps.EmailExtractJob:
-		if j.ParentID != "" {
-			parentID = &j.ParentID
-		}
-	case *gmaps.CompanyJob:
-		if j.ParentID != "" {
-			parentID = &j.ParentID
-		}
-	case *gmaps.PappersJob:
-		if j.ParentID != "" {
-			parentID = &j.ParentID
-		}
-	}
-
 	if jsonJob.ID == "" {
 		jsonJob.ID = uuid.New().String()
 	}
@@ -222,7 +431,7 @@ func (p *provider) Push(ctx context.Context, job scrapemate.IJob) error {
 
 	_, err = p.db.ExecContext(ctx, q,
 		jsonJob.ID,
-		parentID,
+		jsonJob.ParentID,
 		jsonJob.Priority,
 		jobType,
 		payload,
@@ -233,32 +442,34 @@ func (p *provider) Push(ctx context.Context, job scrapemate.IJob) error {
 	return err
 }
 
-// fetchJobs fetches jobs from the database and sends them to the job channel.
-func (p *provider) fetchJobs(ctx context.Context) {
-	defer close(p.jobc)
-	defer close(p.errc)
-
-	q := `
+// fetchQuery queues the next jobs, the oldest of the highest priority first.
+// The %s verb receives the conditions of the options, see (*provider).fetchQuery.
+const fetchQuery = `
 	WITH updated AS (
 		UPDATE gmaps_jobs
 		SET status = $1
 		WHERE id IN (
 			SELECT id from gmaps_jobs
-			WHERE status = $2
+			WHERE status = $2%s
 			ORDER BY priority ASC, created_at ASC FOR UPDATE SKIP LOCKED
-		LIMIT 50
+		LIMIT $3
 		)
 		RETURNING *
 	)
-	SELECT payload_type, payload from updated ORDER by priority ASC, created_at ASC
+	SELECT id, payload_type, payload from updated ORDER by priority ASC, created_at ASC
 	`
 
+// fetchJobs fetches jobs from the database and sends them to the job channel.
+func (p *provider) fetchJobs(ctx context.Context) {
+	defer close(p.jobc)
+	defer close(p.errc)
+
 	baseDelay := time.Second
 	maxDelay := time.Minute
 	factor := 2
 	currentDelay := baseDelay
 
-	jobs := make([]scrapemate.IJob, 0, 50)
+	jobs := make([]scrapemate.IJob, 0, defaultFetchBatchSize)
 
 	for {
 		select {
@@ -267,7 +478,15 @@ func (p *provider) fetchJobs(ctx context.Context) {
 		default:
 		}
 
-		rows, err := p.db.QueryContext(ctx, q, statusQueued, statusNew)
+		if p.draining.Load() {
+			return
+		}
+
+		p.promoteDeferredJobs(ctx)
+
+		q, args := p.fetchQuery(p.fetchBatchSize())
+
+		rows, err := p.db.QueryContext(ctx, q, args...)
 		if err != nil {
 			p.errc <- err
 			return
@@ -275,15 +494,18 @@ func (p *provider) fetchJobs(ctx context.Context) {
 
 		for rows.Next() {
 			var (
+				id          string
 				payloadType string
 				payload     []byte
 			)
 
-			if err := rows.Scan(&payloadType, &payload); err != nil {
+			if err := rows.Scan(&id, &payloadType, &payload); err != nil {
 				p.errc <- err
 				return
 			}
 
+			p.statusManager.rootStarted(id, payload)
+
 			job, err := p.codecRegistry.DecodeJob(payloadType, payload)
 			if err != nil {
 				p.errc <- err
@@ -304,7 +526,19 @@ func (p *provider) fetchJobs(ctx context.Context) {
 		}
 
 		if len(jobs) > 0 {
-			for _, job := range jobs {
+			if p.activity != nil {
+				p.activity.Touch()
+			}
+
+			paced, err := p.pace(ctx, jobs)
+			if err != nil {
+				p.errc <- err
+				return
+			}
+
+			p.useCachedPlaces(ctx, paced)
+
+			for _, job := range paced {
 				select {
 				case p.jobc <- job:
 				case <-ctx.Done():
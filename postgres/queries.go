@@ -1,5 +1,12 @@
 package postgres
 
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
 // CompanyDataQuery builds a query for checking existing company data.
 type CompanyDataQuery struct {
 	title          string
@@ -101,3 +108,269 @@ func (q *DuplicateURLQuery) Build() (string, []interface{}, bool) {
 
 	return "", nil, false
 }
+
+// ResultFilter selects results. Unset fields do not filter.
+type ResultFilter struct {
+	// OwnerID and OrganizationID match either column when both are set.
+	OwnerID        string
+	OrganizationID string
+	// ScopeOrganizationID restricts the results to those of an
+	// organization, whatever OwnerID and OrganizationID select.
+	ScopeOrganizationID string
+	// JobID is the root job the results belong to.
+	JobID    string
+	AfterID  int64
+	Category string
+	// CategoryID is a canonical category id, whatever the language of the
+	// category.
+	CategoryID string
+	// City is matched against the town following the postal code of the
+	// address, ignoring case.
+	City string
+	// Department is a French department code, e.g. 75 or 2A, matched against
+	// the department of the result, or the first postal code of the address
+	// of results saved without one.
+	Department string
+	// Region is a French region, e.g. Occitanie, ignoring case.
+	Region       string
+	SocieteForme string
+	HasEmail     *bool
+	HasSiren     *bool
+	MinRating    *float64
+	MaxRating    *float64
+	MinReviews   *int
+	MaxReviews   *int
+	// MinQuality keeps the results with at least this quality score.
+	MinQuality    *int
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// Tag keeps the results carrying it.
+	Tag string
+	// Technology keeps the results whose website runs on it, e.g. Shopify.
+	Technology string
+	LeadStatus LeadStatus
+}
+
+// ResultSort is a column results can be sorted by.
+type ResultSort string
+
+const (
+	SortByID          ResultSort = "id"
+	SortByCreatedAt   ResultSort = "created_at"
+	SortByTitle       ResultSort = "title"
+	SortByCategory    ResultSort = "category"
+	SortByRating      ResultSort = "review_rating"
+	SortByReviewCount ResultSort = "review_count"
+	SortByQuality     ResultSort = "quality_score"
+)
+
+// resultSorts are the sorts OrderBy accepts: their names are written in the
+// query as is.
+var resultSorts = map[ResultSort]bool{
+	SortByID:          true,
+	SortByCreatedAt:   true,
+	SortByTitle:       true,
+	SortByCategory:    true,
+	SortByRating:      true,
+	SortByReviewCount: true,
+	SortByQuality:     true,
+}
+
+// ResultsQuery builds queries listing and counting results. Without OrderBy
+// the newest results come first.
+type ResultsQuery struct {
+	filter ResultFilter
+	sort   ResultSort
+	desc   bool
+	limit  int
+	offset int
+}
+
+// NewResultsQuery creates a new ResultsQuery builder.
+func NewResultsQuery(filter ResultFilter) *ResultsQuery {
+	return &ResultsQuery{
+		filter: filter,
+		sort:   SortByID,
+		desc:   true,
+	}
+}
+
+// OrderBy sorts the results by sort, then by id in the same direction. A
+// sort that is not one of resultSorts sorts by id.
+func (q *ResultsQuery) OrderBy(sort ResultSort, desc bool) *ResultsQuery {
+	if !resultSorts[sort] {
+		sort = SortByID
+	}
+
+	q.sort = sort
+	q.desc = desc
+
+	return q
+}
+
+// Page returns at most limit results after skipping offset ones. A zero
+// limit returns every result.
+func (q *ResultsQuery) Page(limit, offset int) *ResultsQuery {
+	q.limit = limit
+	q.offset = offset
+
+	return q
+}
+
+// Build returns the SQL query string and arguments listing the results.
+func (q *ResultsQuery) Build() (string, []interface{}) {
+	where, args := q.where()
+
+	dir := "ASC"
+	if q.desc {
+		dir = "DESC"
+	}
+
+	// sort only holds the names of resultSorts
+	query := `SELECT ` + resultColumns + ` FROM results` + where +
+		` ORDER BY ` + string(q.sort) + ` ` + dir + ` NULLS LAST`
+
+	if q.sort != SortByID {
+		query += `, id ` + dir
+	}
+
+	if q.limit > 0 {
+		args = append(args, q.limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	if q.offset > 0 {
+		args = append(args, q.offset)
+		query += fmt.Sprintf(` OFFSET $%d`, len(args))
+	}
+
+	return query, args
+}
+
+// BuildCount returns the SQL query string and arguments counting the
+// results matching the filter.
+func (q *ResultsQuery) BuildCount() (string, []interface{}) {
+	where, args := q.where()
+
+	return `SELECT COUNT(*) FROM results` + where, args
+}
+
+func (q *ResultsQuery) where() (string, []interface{}) {
+	var (
+		f     = &q.filter
+		conds []string
+		args  []interface{}
+	)
+
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	switch {
+	case f.OwnerID != "" && f.OrganizationID != "":
+		conds = append(conds, "(user_id = "+arg(f.OwnerID)+" OR organization_id = "+arg(f.OrganizationID)+")")
+	case f.OwnerID != "":
+		conds = append(conds, "user_id = "+arg(f.OwnerID))
+	case f.OrganizationID != "":
+		conds = append(conds, "organization_id = "+arg(f.OrganizationID))
+	}
+
+	if f.ScopeOrganizationID != "" {
+		conds = append(conds, "organization_id = "+arg(f.ScopeOrganizationID))
+	}
+
+	if f.JobID != "" {
+		conds = append(conds, "parent_id = "+arg(f.JobID))
+	}
+
+	if f.AfterID > 0 {
+		conds = append(conds, "id > "+arg(f.AfterID))
+	}
+
+	if f.Category != "" {
+		conds = append(conds, "LOWER(category) = LOWER("+arg(f.Category)+")")
+	}
+
+	if f.CategoryID != "" {
+		conds = append(conds, "category_id = "+arg(f.CategoryID))
+	}
+
+	if f.City != "" {
+		conds = append(conds, `address ~* ('\d{5}\s+' || `+arg(regexp.QuoteMeta(f.City))+` || '\M')`)
+	}
+
+	if f.Department != "" {
+		conds = append(conds, `COALESCE(department_code, LEFT(SUBSTRING(address FROM '\d{5}'), 2)) = `+arg(f.Department))
+	}
+
+	if f.Region != "" {
+		conds = append(conds, "LOWER(region) = LOWER("+arg(f.Region)+")")
+	}
+
+	if f.SocieteForme != "" {
+		conds = append(conds, "LOWER(societe_forme) = LOWER("+arg(f.SocieteForme)+")")
+	}
+
+	if f.HasEmail != nil {
+		if *f.HasEmail {
+			conds = append(conds, "COALESCE(CARDINALITY(emails), 0) > 0")
+		} else {
+			conds = append(conds, "COALESCE(CARDINALITY(emails), 0) = 0")
+		}
+	}
+
+	if f.HasSiren != nil {
+		if *f.HasSiren {
+			conds = append(conds, "COALESCE(societe_siren, '') <> ''")
+		} else {
+			conds = append(conds, "COALESCE(societe_siren, '') = ''")
+		}
+	}
+
+	if f.MinRating != nil {
+		conds = append(conds, "review_rating >= "+arg(*f.MinRating))
+	}
+
+	if f.MaxRating != nil {
+		conds = append(conds, "review_rating <= "+arg(*f.MaxRating))
+	}
+
+	if f.MinReviews != nil {
+		conds = append(conds, "review_count >= "+arg(*f.MinReviews))
+	}
+
+	if f.MaxReviews != nil {
+		conds = append(conds, "review_count <= "+arg(*f.MaxReviews))
+	}
+
+	if f.MinQuality != nil {
+		conds = append(conds, "quality_score >= "+arg(*f.MinQuality))
+	}
+
+	if f.Tag != "" {
+		conds = append(conds, arg(f.Tag)+" = ANY(tags)")
+	}
+
+	if f.Technology != "" {
+		conds = append(conds, arg(f.Technology)+" = ANY(technologies)")
+	}
+
+	if f.LeadStatus != "" {
+		conds = append(conds, "COALESCE(lead_status, 'new') = "+arg(string(f.LeadStatus)))
+	}
+
+	if f.CreatedAfter != nil {
+		conds = append(conds, "created_at >= "+arg(*f.CreatedAfter))
+	}
+
+	if f.CreatedBefore != nil {
+		conds = append(conds, "created_at < "+arg(*f.CreatedBefore))
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
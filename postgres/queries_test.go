@@ -0,0 +1,55 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+func Test_ResultsQuery(t *testing.T) {
+	hasEmail := true
+	after := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	q := postgres.NewResultsQuery(postgres.ResultFilter{
+		OwnerID:      "user-1",
+		City:         "Saint-Denis (Réunion)",
+		Department:   "97",
+		HasEmail:     &hasEmail,
+		CreatedAfter: &after,
+	}).OrderBy(postgres.SortByRating, true).Page(20, 40)
+
+	query, args := q.Build()
+	require.Contains(t, query, ` WHERE user_id = $1 AND address ~* ('\d{5}\s+' || $2 || '\M')`+
+		` AND COALESCE(department_code, LEFT(SUBSTRING(address FROM '\d{5}'), 2)) = $3 AND COALESCE(CARDINALITY(emails), 0) > 0`+
+		` AND created_at >= $4 ORDER BY review_rating DESC NULLS LAST, id DESC LIMIT $5 OFFSET $6`)
+	require.Equal(t, []interface{}{"user-1", `Saint-Denis \(Réunion\)`, "97", after, 20, 40}, args)
+
+	count, countArgs := q.BuildCount()
+	require.Equal(t, `SELECT COUNT(*) FROM results WHERE user_id = $1 AND address ~* ('\d{5}\s+' || $2 || '\M')`+
+		` AND COALESCE(department_code, LEFT(SUBSTRING(address FROM '\d{5}'), 2)) = $3 AND COALESCE(CARDINALITY(emails), 0) > 0`+
+		` AND created_at >= $4`, count)
+	require.Equal(t, args[:4], countArgs)
+
+	query, args = postgres.NewResultsQuery(postgres.ResultFilter{}).Build()
+	require.Contains(t, query, ` FROM results ORDER BY id DESC NULLS LAST`)
+	require.NotContains(t, query, "LIMIT")
+	require.Empty(t, args)
+
+	// unknown sorts never reach the query
+	query, _ = postgres.NewResultsQuery(postgres.ResultFilter{}).
+		OrderBy(postgres.ResultSort("id; DROP TABLE results"), false).Build()
+	require.Contains(t, query, ` FROM results ORDER BY id ASC NULLS LAST`)
+	require.NotContains(t, query, "DROP")
+
+	// the organization of the key holds whatever the owner matches
+	count, countArgs = postgres.NewResultsQuery(postgres.ResultFilter{
+		OwnerID:             "user-2",
+		OrganizationID:      "org-1",
+		ScopeOrganizationID: "org-1",
+	}).BuildCount()
+	require.Equal(t, `SELECT COUNT(*) FROM results WHERE (user_id = $1 OR organization_id = $2) AND organization_id = $3`, count)
+	require.Equal(t, []interface{}{"user-2", "org-1", "org-1"}, countArgs)
+}
@@ -0,0 +1,320 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+const (
+	// retryReason and cancelReason are the reasons of the status changes the
+	// jobs subcommand makes, in gmaps_job_events.
+	retryReason  = "retried by an operator"
+	cancelReason = "cancelled by an operator"
+)
+
+// ErrJobStatus is returned when cancelling a job that is already done or
+// failed, or retrying one that did not fail.
+var ErrJobStatus = errors.New("invalid job status")
+
+// JobInfo is a row of gmaps_jobs as the jobs subcommand and the dashboard
+// list it.
+type JobInfo struct {
+	ID       string `json:"id"`
+	ParentID string `json:"parent_id,omitempty"`
+	Type     string `json:"type"`
+	Status   string `json:"status"`
+	Priority int    `json:"priority"`
+	// Name is the first query of the job, or else its URL.
+	Name               string    `json:"name"`
+	CreatedAt          time.Time `json:"created_at"`
+	OwnerID            string    `json:"owner_id"`
+	OrganizationID     string    `json:"organization_id"`
+	ChildJobsCount     int       `json:"child_jobs_count"`
+	ChildJobsCompleted int       `json:"child_jobs_completed"`
+	ChildJobsFailed    int       `json:"child_jobs_failed"`
+	// FailureReason is why the job failed, when it was recorded.
+	FailureReason string `json:"failure_reason,omitempty"`
+}
+
+// JobListFilter selects the jobs ListJobs returns. Only root jobs are listed
+// unless All or ParentID is set.
+type JobListFilter struct {
+	Status         string
+	OwnerID        string
+	OrganizationID string
+	// ParentID lists the children of a job.
+	ParentID string
+	All      bool
+	Limit    int
+}
+
+// ListJobs returns the jobs matching f, the newest first.
+func ListJobs(ctx context.Context, db *sql.DB, f JobListFilter) ([]JobInfo, error) {
+	var (
+		conds []string
+		args  []any
+	)
+
+	switch {
+	case f.ParentID != "":
+		args = append(args, f.ParentID)
+		conds = append(conds, fmt.Sprintf("parent_id = $%d", len(args)))
+	case !f.All:
+		conds = append(conds, "parent_id IS NULL")
+	}
+
+	if f.Status != "" {
+		args = append(args, f.Status)
+		conds = append(conds, fmt.Sprintf("status = $%d", len(args)))
+	}
+
+	if f.OwnerID != "" {
+		args = append(args, f.OwnerID)
+		conds = append(conds, fmt.Sprintf("payload::jsonb -> 'metadata' ->> 'owner_id' = $%d", len(args)))
+	}
+
+	if f.OrganizationID != "" {
+		args = append(args, f.OrganizationID)
+		conds = append(conds, fmt.Sprintf("payload::jsonb -> 'metadata' ->> 'organization_id' = $%d", len(args)))
+	}
+
+	q := `SELECT id, COALESCE(parent_id::text, ''), payload_type, status, priority,
+			COALESCE(payload::jsonb -> 'metadata' -> 'queries' ->> 0, payload::jsonb ->> 'url', ''), created_at,
+			COALESCE(payload::jsonb -> 'metadata' ->> 'owner_id', ''),
+			COALESCE(payload::jsonb -> 'metadata' ->> 'organization_id', ''),
+			child_jobs_count, child_jobs_completed, child_jobs_failed, COALESCE(failure_reason, '')
+		FROM gmaps_jobs`
+
+	if len(conds) > 0 {
+		q += " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	q += " ORDER BY created_at DESC"
+
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		q += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var ans []JobInfo
+
+	for rows.Next() {
+		var j JobInfo
+
+		err := rows.Scan(&j.ID, &j.ParentID, &j.Type, &j.Status, &j.Priority, &j.Name, &j.CreatedAt,
+			&j.OwnerID, &j.OrganizationID, &j.ChildJobsCount, &j.ChildJobsCompleted, &j.ChildJobsFailed, &j.FailureReason)
+		if err != nil {
+			return nil, err
+		}
+
+		// search jobs queued without their queries are named after their URL
+		j.Name = gmaps.SearchQuery(j.Name)
+
+		ans = append(ans, j)
+	}
+
+	return ans, rows.Err()
+}
+
+// QueueStat counts the jobs of a type in a status.
+type QueueStat struct {
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	Jobs   int    `json:"jobs"`
+}
+
+// GetQueueStats counts the jobs of gmaps_jobs by type and status.
+func GetQueueStats(ctx context.Context, db *sql.DB) ([]QueueStat, error) {
+	return queueStats(ctx, db, `SELECT payload_type, status, COUNT(*) FROM gmaps_jobs GROUP BY 1, 2 ORDER BY 1, 2`)
+}
+
+// GetQueueBacklog counts the jobs of gmaps_jobs that did not finish yet by
+// type and status, without reading the done and failed ones.
+func GetQueueBacklog(ctx context.Context, db *sql.DB) ([]QueueStat, error) {
+	const q = `SELECT payload_type, status, COUNT(*) FROM gmaps_jobs
+		WHERE status IN ($1, $2, $3, $4) GROUP BY 1, 2 ORDER BY 1, 2`
+
+	return queueStats(ctx, db, q, statusDeferred, statusNew, statusQueued, statusProcessing)
+}
+
+func queueStats(ctx context.Context, db *sql.DB, q string, args ...any) ([]QueueStat, error) {
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var ans []QueueStat
+
+	for rows.Next() {
+		var s QueueStat
+		if err := rows.Scan(&s.Type, &s.Status, &s.Jobs); err != nil {
+			return nil, err
+		}
+
+		ans = append(ans, s)
+	}
+
+	return ans, rows.Err()
+}
+
+// reopenQuery takes back a finished child from the counters of the parent
+// $1, the completed one when $2 is true and the failed one otherwise, and
+// makes a done parent wait for its children again. It returns the previous
+// status of the parent and its own parent.
+const reopenQuery = `
+	UPDATE gmaps_jobs j SET
+		child_jobs_completed = GREATEST(j.child_jobs_completed - CASE WHEN $2 THEN 1 ELSE 0 END, 0),
+		child_jobs_failed = GREATEST(j.child_jobs_failed - CASE WHEN $2 THEN 0 ELSE 1 END, 0),
+		status = CASE WHEN old.status = $3 THEN $4 ELSE old.status END
+	FROM (SELECT id, status FROM gmaps_jobs WHERE id = $1 FOR UPDATE) old
+	WHERE j.id = old.id
+	RETURNING old.status, j.parent_id`
+
+// RetryJob queues the failed job id again. Its parents done meanwhile wait
+// for it again, so the completion of its root job is reported once more when
+// it finishes.
+func (s *StatusManager) RetryJob(ctx context.Context, id string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setReason(ctx, tx, retryReason); err != nil {
+		return err
+	}
+
+	var (
+		status   string
+		parentID sql.NullString
+	)
+
+	err = tx.QueryRowContext(ctx, `SELECT status, parent_id FROM gmaps_jobs WHERE id = $1 FOR UPDATE`, id).Scan(&status, &parentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrJobNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if status != statusFailed {
+		return fmt.Errorf("%w: job %s is %s", ErrJobStatus, id, status)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE gmaps_jobs SET status = $1 WHERE id = $2`, statusNew, id); err != nil {
+		return err
+	}
+
+	// the job counted as failed in its parent, and each parent it made done
+	// as completed in the next one
+	completed := false
+
+	for parentID.Valid {
+		var previous string
+
+		err := tx.QueryRowContext(ctx, reopenQuery, parentID.String, completed, statusDone, statusProcessing).
+			Scan(&previous, &parentID)
+		if err != nil {
+			return err
+		}
+
+		if previous != statusDone {
+			break
+		}
+
+		completed = true
+	}
+
+	return tx.Commit()
+}
+
+// cancelTreeQuery fails the descendants of the job $1 that did not run yet
+// or wait for their children.
+const cancelTreeQuery = `
+	WITH RECURSIVE tree AS (
+		SELECT id FROM gmaps_jobs WHERE parent_id = $1
+		UNION ALL
+		SELECT j.id FROM gmaps_jobs j JOIN tree t ON j.parent_id = t.id
+	)
+	UPDATE gmaps_jobs SET status = $2
+	WHERE id IN (SELECT id FROM tree) AND status IN ($3, $4, $5, $6)`
+
+// CancelJob fails the job id and those of its descendants that did not run
+// yet, and returns how many jobs it failed. The parent of the job counts it
+// as failed, as if it ran. Jobs a worker already fetched still run.
+func (s *StatusManager) CancelJob(ctx context.Context, id string) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := setReason(ctx, tx, cancelReason); err != nil {
+		return 0, err
+	}
+
+	var (
+		status   string
+		parentID sql.NullString
+	)
+
+	err = tx.QueryRowContext(ctx, `SELECT status, parent_id FROM gmaps_jobs WHERE id = $1 FOR UPDATE`, id).Scan(&status, &parentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrJobNotFound
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if status == statusDone || status == statusFailed {
+		return 0, fmt.Errorf("%w: job %s is already %s", ErrJobStatus, id, status)
+	}
+
+	res, err := tx.ExecContext(ctx, cancelTreeQuery, id, statusFailed, statusNew, statusQueued, statusDeferred, statusProcessing)
+	if err != nil {
+		return 0, err
+	}
+
+	n, _ := res.RowsAffected()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE gmaps_jobs SET status = $1 WHERE id = $2`, statusFailed, id); err != nil {
+		return 0, err
+	}
+
+	if err := s.incrementParentFailedCounter(ctx, tx, id); err != nil {
+		return 0, err
+	}
+
+	var done []doneRoot
+
+	if err := s.checkAndMarkParentDone(ctx, tx, id, &done); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	s.rootsDone(done)
+
+	if !parentID.Valid {
+		s.rootFailed(id, cancelReason)
+	}
+
+	return int(n) + 1, nil
+}
@@ -0,0 +1,30 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// saveRanking stores where a search tracking a business listed it. A retried
+// search replaces the ranking of its earlier attempt.
+func (p *provider) saveRanking(ctx context.Context, r *gmaps.Ranking) error {
+	const q = `INSERT INTO rankings
+		(search_id, parent_id, owner_id, organization_id, query, target, geo, zoom, domain,
+		 position, title, link, listed, checked_at)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, 0), NULLIF($9, ''),
+		 NULLIF($10, 0), NULLIF($11, ''), NULLIF($12, ''), $13, $14)
+		ON CONFLICT (search_id) DO UPDATE SET
+			position = EXCLUDED.position, title = EXCLUDED.title, link = EXCLUDED.link,
+			listed = EXCLUDED.listed, checked_at = EXCLUDED.checked_at`
+
+	_, err := p.db.ExecContext(ctx, q, r.SearchID, r.ParentID, r.OwnerID, r.OrganizationID, r.Query,
+		r.Target, r.Geo, r.Zoom, r.Domain, r.Position, r.Title, r.Link, r.Listed, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save the ranking: %w", err)
+	}
+
+	return nil
+}
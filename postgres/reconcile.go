@@ -0,0 +1,189 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/gosom/scrapemate"
+)
+
+// reconcileGrace is how old a parent must be before its counters are
+// recomputed, so that the increments of its running children are not
+// overwritten while they commit.
+const reconcileGrace = 10 * time.Minute
+
+// reconcileCountersQuery sets the counters of the parents waiting for their
+// children ($3), created before $4, to the counts of their child rows, and
+// returns those it changed. The counters drift when an increment is lost to
+// a crash, or when child_jobs_count counts a child whose insert conflicted.
+const reconcileCountersQuery = `
+	WITH counts AS (
+		SELECT p.id,
+			COUNT(*) AS total,
+			COUNT(*) FILTER (WHERE c.status = $1) AS completed,
+			COUNT(*) FILTER (WHERE c.status = $2) AS failed
+		FROM gmaps_jobs p
+		JOIN gmaps_jobs c ON c.parent_id = p.id
+		WHERE p.status = $3 AND p.created_at < $4
+		GROUP BY p.id
+	)
+	UPDATE gmaps_jobs j
+	SET child_jobs_count = c.total, child_jobs_completed = c.completed, child_jobs_failed = c.failed
+	FROM counts c
+	WHERE j.id = c.id AND (j.child_jobs_count, j.child_jobs_completed, j.child_jobs_failed)
+		IS DISTINCT FROM (c.total, c.completed, c.failed)
+	RETURNING j.id`
+
+// stuckParentsQuery returns the parents still waiting for their children
+// ($1) although the counters say they all ran.
+const stuckParentsQuery = `
+	SELECT id FROM gmaps_jobs
+	WHERE status = $1 AND child_jobs_count > 0
+		AND child_jobs_completed + child_jobs_failed >= child_jobs_count`
+
+// ReconcileStats counts the corrections of a Reconciler since startup.
+type ReconcileStats struct {
+	Runs int64 `json:"runs"`
+	// Counters counts the parents whose counters were recomputed.
+	Counters int64 `json:"counters"`
+	// Completed counts the parents marked done that were stuck waiting.
+	Completed int64 `json:"completed"`
+}
+
+// Reconciler recomputes the child counters of the parent jobs from their
+// child rows and marks done the parents whose children all ran, which lost
+// increments would otherwise leave processing forever.
+type Reconciler struct {
+	db       *sql.DB
+	interval time.Duration
+	status   *StatusManager
+
+	runs      atomic.Int64
+	counters  atomic.Int64
+	completed atomic.Int64
+}
+
+// NewReconciler creates a Reconciler running every interval. It must be
+// given to the provider with WithReconciler, whose status manager completes
+// the parents, and does nothing until Run or Reconcile is called.
+func NewReconciler(db *sql.DB, interval time.Duration) *Reconciler {
+	return &Reconciler{db: db, interval: interval}
+}
+
+// WithReconciler makes r complete the parents as the provider does, calling
+// the job completion API and the completion hooks of their root jobs.
+func WithReconciler(r *Reconciler) ProviderOption {
+	return func(p *provider) {
+		r.status = p.statusManager
+	}
+}
+
+// Run reconciles every interval until ctx is done.
+func (r *Reconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	log := scrapemate.GetLoggerFromContext(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		counters, completed, err := r.Reconcile(ctx)
+
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Error(fmt.Sprintf("reconciler: %v", err))
+		case counters > 0 || completed > 0:
+			log.Info(fmt.Sprintf("reconciler: %d parents recounted, %d completed", counters, completed))
+		}
+	}
+}
+
+// Reconcile recomputes the counters that drifted, then completes the
+// parents whose children all ran, and returns how many of each it fixed.
+func (r *Reconciler) Reconcile(ctx context.Context) (counters, completed int, err error) {
+	r.runs.Add(1)
+
+	counters, err = r.recount(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	r.counters.Add(int64(counters))
+
+	ids, err := r.stuckParents(ctx)
+	if err != nil {
+		return counters, 0, err
+	}
+
+	for _, id := range ids {
+		done, err := r.status.completeParent(ctx, id, "children all ran, found by the reconciler")
+		if err != nil {
+			return counters, completed, fmt.Errorf("complete %s: %w", id, err)
+		}
+
+		if done {
+			completed++
+			r.completed.Add(1)
+		}
+	}
+
+	return counters, completed, nil
+}
+
+// Stats returns the corrections made since startup.
+func (r *Reconciler) Stats() ReconcileStats {
+	return ReconcileStats{
+		Runs:      r.runs.Load(),
+		Counters:  r.counters.Load(),
+		Completed: r.completed.Load(),
+	}
+}
+
+func (r *Reconciler) recount(ctx context.Context) (int, error) {
+	cutoff := time.Now().UTC().Add(-reconcileGrace)
+
+	rows, err := r.db.QueryContext(ctx, reconcileCountersQuery, statusDone, statusFailed, statusProcessing, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	defer rows.Close()
+
+	var n int
+
+	for rows.Next() {
+		n++
+	}
+
+	return n, rows.Err()
+}
+
+func (r *Reconciler) stuckParents(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, stuckParentsQuery, statusProcessing)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ids []string
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
@@ -0,0 +1,61 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+func Test_ReconcilerCompletesStuckParents(t *testing.T) {
+	fake, db := newFakeDB(t)
+
+	// two parents had their counters recomputed
+	fake.on("WITH counts AS", func([]driver.Value) answer {
+		return answer{columns: []string{"id"}, rows: [][]driver.Value{{"parent-1"}, {"parent-2"}}}
+	})
+	fake.on("SELECT id FROM gmaps_jobs", func([]driver.Value) answer {
+		return answer{columns: []string{"id"}, rows: [][]driver.Value{{"parent-1"}, {"parent-2"}}}
+	})
+	// another worker completed parent-2 meanwhile
+	fake.on("AND status = $3 AND child_jobs_count > 0", func(args []driver.Value) answer {
+		if args[1] == "parent-2" {
+			return answer{}
+		}
+
+		return answer{affected: 1}
+	})
+	fake.on("SELECT parent_id FROM gmaps_jobs", func([]driver.Value) answer {
+		return answer{columns: []string{"parent_id"}, rows: [][]driver.Value{{"root"}}}
+	})
+	fake.on("SELECT status FROM gmaps_jobs", func([]driver.Value) answer {
+		return answer{columns: []string{"status"}, rows: [][]driver.Value{{"done"}}}
+	})
+	fake.on("SELECT child_jobs_count, child_jobs_completed, child_jobs_failed", func([]driver.Value) answer {
+		return answer{
+			columns: []string{"child_jobs_count", "child_jobs_completed", "child_jobs_failed"},
+			rows:    [][]driver.Value{{int64(3), int64(1), int64(0)}},
+		}
+	})
+
+	r := postgres.NewReconciler(db, 0)
+	postgres.NewProvider(db, "", "", postgres.WithReconciler(r))
+
+	counters, completed, err := r.Reconcile(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, counters)
+	require.Equal(t, 1, completed)
+
+	// only the parents waiting for their children are recounted
+	recount := fake.ran("WITH counts AS")
+	require.Len(t, recount, 1)
+	require.Equal(t, []driver.Value{"done", "failed", "processing"}, recount[0][:3])
+
+	// the completed parent counts for its own parent
+	require.Equal(t, [][]driver.Value{{"root"}}, fake.ran("child_jobs_completed = child_jobs_completed + 1"))
+
+	require.Equal(t, postgres.ReconcileStats{Runs: 1, Counters: 2, Completed: 1}, r.Stats())
+}
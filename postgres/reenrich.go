@@ -0,0 +1,302 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/entreprise"
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// reenrichBatch is how many results Reenrich reads per query.
+const reenrichBatch = 100
+
+// ReenrichFilter selects the stored results Reenrich looks up again: those of
+// the owner or organization missing a SIREN or directors, but the ones a
+// previous lookup found no match for, and those last updated more than
+// OlderThan ago when it is set.
+type ReenrichFilter struct {
+	OwnerID        string
+	OrganizationID string
+	OlderThan      time.Duration
+}
+
+// ReenrichStats counts what Reenrich did.
+type ReenrichStats struct {
+	Checked int
+	Updated int
+	// NotFound results matched no company.
+	NotFound int
+	// Mismatches results hold another SIREN than the one found now; they
+	// are left alone and flagged like other SIREN mismatches.
+	Mismatches int
+}
+
+// Reenricher runs the company lookups of stored results again and updates
+// them in place, without scraping Google Maps.
+type Reenricher struct {
+	db        *sql.DB
+	apiClient *APIClient
+	review    *ReviewQueue
+	cipher    *FieldCipher
+}
+
+// NewReenricher creates a Reenricher. The frontend cache of each updated
+// owner is revalidated through apiClient, doubtful matches are queued in
+// review unless it is nil, and the directors found are encrypted with
+// cipher.
+func NewReenricher(db *sql.DB, apiClient *APIClient, review *ReviewQueue, cipher *FieldCipher) *Reenricher {
+	return &Reenricher{
+		db:        db,
+		apiClient: apiClient,
+		review:    review,
+		cipher:    cipher,
+	}
+}
+
+// reenrichRow is a stored result to look up again.
+type reenrichRow struct {
+	id                   int64
+	link, title, address string
+	userID, organization string
+	siren                string
+}
+
+// Reenrich looks up the companies of the results matching f again, in id
+// order, and updates their company fields.
+func (r *Reenricher) Reenrich(ctx context.Context, f ReenrichFilter) (ReenrichStats, error) {
+	var (
+		stats   ReenrichStats
+		afterID int64
+		owners  = map[string]bool{}
+	)
+
+	log := scrapemate.GetLoggerFromContext(ctx)
+
+	for {
+		rows, err := r.next(ctx, f, afterID)
+		if err != nil {
+			return stats, err
+		}
+
+		if len(rows) == 0 {
+			break
+		}
+
+		for _, row := range rows {
+			if err := ctx.Err(); err != nil {
+				return stats, err
+			}
+
+			afterID = row.id
+			stats.Checked++
+
+			updated, err := r.reenrich(ctx, row, &stats)
+			if err != nil {
+				log.Error(fmt.Sprintf("reenrich: result %d: %v", row.id, err))
+				continue
+			}
+
+			if updated {
+				stats.Updated++
+				owners[row.userID] = true
+			}
+		}
+
+		log.Info(fmt.Sprintf("reenrich: %d results checked, %d updated", stats.Checked, stats.Updated))
+	}
+
+	for owner := range owners {
+		r.apiClient.CallRevalidationAPI(ctx, owner)
+	}
+
+	if err := r.apiClient.FlushRevalidations(ctx); err != nil {
+		return stats, err
+	}
+
+	return stats, nil
+}
+
+func (r *Reenricher) next(ctx context.Context, f ReenrichFilter, afterID int64) ([]reenrichRow, error) {
+	var (
+		conds []string
+		args  []interface{}
+	)
+
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	switch {
+	case f.OwnerID != "" && f.OrganizationID != "":
+		conds = append(conds, "(user_id = "+arg(f.OwnerID)+" OR organization_id = "+arg(f.OrganizationID)+")")
+	case f.OwnerID != "":
+		conds = append(conds, "user_id = "+arg(f.OwnerID))
+	case f.OrganizationID != "":
+		conds = append(conds, "organization_id = "+arg(f.OrganizationID))
+	}
+
+	conds = append(conds, "id > "+arg(afterID))
+
+	stale := `COALESCE(company_match, '') <> 'no_match'
+		AND (COALESCE(societe_siren, '') = '' OR COALESCE(societe_dirigeants, '') = '')`
+	if f.OlderThan > 0 {
+		stale += " OR updated_at < " + arg(time.Now().UTC().Add(-f.OlderThan))
+	}
+
+	conds = append(conds, "("+stale+")")
+
+	q := `SELECT id, link, COALESCE(title, ''), COALESCE(address, ''),
+		COALESCE(user_id, ''), COALESCE(organization_id, ''), COALESCE(societe_siren, '')
+		FROM results
+		WHERE ` + strings.Join(conds, " AND ") + `
+		ORDER BY id
+		LIMIT ` + arg(reenrichBatch)
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select results to reenrich: %w", err)
+	}
+	defer rows.Close()
+
+	var ans []reenrichRow
+
+	for rows.Next() {
+		var row reenrichRow
+		if err := rows.Scan(&row.id, &row.link, &row.title, &row.address,
+			&row.userID, &row.organization, &row.siren); err != nil {
+			return nil, fmt.Errorf("failed to scan result to reenrich: %w", err)
+		}
+
+		ans = append(ans, row)
+	}
+
+	return ans, rows.Err()
+}
+
+// reenrich runs the company job of row and tells whether it updated it.
+func (r *Reenricher) reenrich(ctx context.Context, row reenrichRow, stats *ReenrichStats) (bool, error) {
+	if row.title == "" {
+		stats.NotFound++
+		return false, nil
+	}
+
+	job := gmaps.NewCompanyJob(row.title, row.address, row.userID, row.organization, row.link)
+
+	data, _, err := job.Process(ctx, &scrapemate.Response{})
+	if err != nil {
+		return false, err
+	}
+
+	result, ok := data.(*gmaps.CompanyEnrichmentResult)
+	if !ok {
+		return false, fmt.Errorf("unexpected company job result %T", data)
+	}
+
+	if r.review != nil {
+		if err := r.review.Enqueue(ctx, row.title, row.address, result); err != nil {
+			return false, err
+		}
+	}
+
+	siren := entreprise.NormalizeSiren(result.SocieteSiren)
+	if siren == "" {
+		stats.NotFound++
+
+		if result.Outcome == gmaps.MatchOutcomeNoMatch && row.siren == "" {
+			return false, r.markNoMatch(ctx, row, result)
+		}
+
+		return false, nil
+	}
+
+	if row.siren != "" && row.siren != siren {
+		stats.Mismatches++
+		entreprise.FlagSirenMismatch(row.link, row.siren, siren, "stored result and "+result.Source)
+
+		return false, nil
+	}
+
+	company := fieldSource(result.Source, result.FetchedAt)
+	directors := fieldSource(result.DirectorsSource, result.FetchedAt)
+
+	dirigeants, err := r.cipher.seal(row.organization, strings.Join(result.SocieteDirigeants, ","))
+	if err != nil {
+		return false, err
+	}
+
+	q := fmt.Sprintf(`UPDATE results SET
+		societe_dirigeants = COALESCE(NULLIF($2, ''), societe_dirigeants),
+		societe_siren = $3,
+		societe_forme = COALESCE(NULLIF($4, ''), societe_forme),
+		societe_creation = COALESCE(NULLIF($5, ''), societe_creation),
+		societe_cloture = COALESCE(NULLIF($6, ''), societe_cloture),
+		societe_link = COALESCE(NULLIF($7, ''), societe_link),
+		societe_diffusion = COALESCE($8, societe_diffusion),
+		siren_resolution = NULLIF($9, ''),
+		naf_code = COALESCE(NULLIF($12, ''), naf_code),
+		company_match = 'matched',
+		company_candidate = NULL,
+		%s,
+		updated_at = NOW()
+		WHERE id = $1`,
+		setProvenance([]provenanceField{
+			{column: "societe_dirigeants", cond: "$2 <> ''", source: 11},
+			{column: "societe_siren", source: 10},
+			{column: "societe_forme", cond: "$4 <> ''", source: 10},
+			{column: "societe_creation", cond: "$5 <> ''", source: 10},
+			{column: "societe_cloture", cond: "$6 <> ''", source: 10},
+			{column: "societe_link", cond: "$7 <> ''", source: 10},
+			{column: "societe_diffusion", cond: "$8 IS NOT NULL", source: 10},
+			{column: "naf_code", cond: "$12 <> ''", source: 10},
+		}),
+	)
+
+	_, err = r.db.ExecContext(ctx, q,
+		row.id,
+		dirigeants,
+		siren,
+		result.SocieteForme,
+		result.SocieteCreation,
+		result.SocieteCloture,
+		result.SocieteLink,
+		result.SocieteDiffusion,
+		result.SirenResolution,
+		company,
+		directors,
+		result.NafCode,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update: %w", err)
+	}
+
+	return true, nil
+}
+
+// markNoMatch records on row that no register matched its company, with the
+// candidate they rejected, so that the next runs skip it.
+func (r *Reenricher) markNoMatch(ctx context.Context, row reenrichRow, result *gmaps.CompanyEnrichmentResult) error {
+	candidate, err := companyCandidate(r.cipher, result)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `UPDATE results SET
+		company_match = 'no_match',
+		company_candidate = NULLIF($2, '')::jsonb,
+		updated_at = NOW()
+		WHERE id = $1`,
+		row.id, candidate,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark no match: %w", err)
+	}
+
+	return nil
+}
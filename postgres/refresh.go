@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// refreshResult updates the results the owner has of the place of entry,
+// matched by link or place id, with what Google Maps shows of it now. Their
+// enrichment, lead status and notes are kept. It tells whether the owner had
+// the place.
+func (r *resultWriter) refreshResult(ctx context.Context, entry *gmaps.Entry, userID, organizationID string) (bool, error) {
+	q := `UPDATE results SET
+			title = $3, category = $4, category_id = COALESCE(NULLIF($5, ''), category_id),
+			address = $6, website = $7, phones = $8, latitude = $9, longitude = $10,
+			review_rating = $11, review_count = $12,
+			review_stats = COALESCE(NULLIF($13, '')::jsonb, review_stats),
+			place_id = COALESCE(NULLIF($14, ''), place_id),
+			service_area_business = $15, service_area = NULLIF($16, ''),
+			whatsapp = COALESCE(NULLIF($18, ''), whatsapp),
+			updated_at = NOW()
+		WHERE (link = $17 OR (NULLIF($14, '') IS NOT NULL AND place_id = $14))
+			AND ` + ownerCond("user_id", "organization_id")
+
+	res, err := r.db.ExecContext(ctx, q, userID, organizationID,
+		entry.Title, entry.Category, entry.CategoryID,
+		entry.Address, entry.WebSite, phoneToPhones(entry.Phone, entry.CompleteAddress.Country),
+		entry.Latitude, entry.Longtitude, entry.ReviewRating, entry.ReviewCount,
+		reviewStatsJSON(entry.ReviewStats), placeID(entry),
+		entry.ServiceAreaBusiness, entry.ServiceArea, entry.Link, entry.WhatsApp)
+	if err != nil {
+		return false, fmt.Errorf("failed to refresh the result of %s: %w", entry.Link, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
@@ -0,0 +1,475 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+const (
+	// reportSamples is how many results a report shows, the most reviewed
+	// first.
+	reportSamples = 10
+	// maxFailureReason is the longest failure reason stored, in runes.
+	maxFailureReason = 120
+)
+
+// ReportFormat is the file format of scrape reports.
+type ReportFormat string
+
+const (
+	ReportMarkdown ReportFormat = "markdown"
+	ReportHTML     ReportFormat = "html"
+)
+
+// ParseReportFormat parses a report format name.
+func ParseReportFormat(s string) (ReportFormat, error) {
+	switch f := ReportFormat(strings.ToLower(s)); f {
+	case ReportMarkdown, ReportHTML:
+		return f, nil
+	default:
+		return "", fmt.Errorf("invalid report format %q: must be markdown or html", s)
+	}
+}
+
+func (f ReportFormat) extension() string {
+	if f == ReportHTML {
+		return ".html"
+	}
+
+	return ".md"
+}
+
+// Report describes how a root job went, for agencies to attach to what they
+// deliver to their clients.
+type Report struct {
+	JobID       string         `json:"job_id"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	Progress    JobProgress    `json:"progress"`
+	Summary     ResultsSummary `json:"summary"`
+	// Queries are the searches of the job with the places each one found.
+	Queries  []QueryCount   `json:"queries"`
+	Failures []FailureCount `json:"failures"`
+	// Samples are the most reviewed places.
+	Samples []Result `json:"samples"`
+}
+
+type QueryCount struct {
+	Query  string `json:"query"`
+	Places int    `json:"places"`
+}
+
+type FailureCount struct {
+	Reason string `json:"reason"`
+	Jobs   int    `json:"jobs"`
+}
+
+// Reporter writes a report of each root job once it is done, and stores
+// where to download it in the report_path column of the job.
+type Reporter struct {
+	db      *sql.DB
+	dir     string
+	baseURL string
+	format  ReportFormat
+	cipher  *FieldCipher
+}
+
+// NewReporter creates a Reporter saving the reports in dir. When baseURL is
+// set, jobs get baseURL followed by the file name instead of the file path.
+// The sample results are decrypted with cipher.
+func NewReporter(db *sql.DB, dir, baseURL string, format ReportFormat, cipher *FieldCipher) *Reporter {
+	return &Reporter{
+		db:      db,
+		dir:     dir,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		format:  format,
+		cipher:  cipher,
+	}
+}
+
+// WithReporter writes the report of root jobs with r when they are done, and
+// records why jobs fail so reports can group the failures.
+func WithReporter(r *Reporter) ProviderOption {
+	return func(p *provider) {
+		WithFailureReasons()(p)
+
+		p.statusManager.onRootDone = append(p.statusManager.onRootDone, func(id string) {
+			p.goBackground(func() {
+				time.Sleep(exportDelay)
+
+				ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+				defer cancel()
+
+				log := scrapemate.GetLoggerFromContext(ctx)
+
+				location, err := r.Write(ctx, id)
+				if err != nil {
+					log.Error(fmt.Sprintf("failed to write report of job %s: %v", id, err))
+					return
+				}
+
+				log.Info(fmt.Sprintf("wrote report of job %s to %s", id, location))
+			})
+		})
+	}
+}
+
+// Write writes the report of the root job id and returns where it can be
+// downloaded.
+func (r *Reporter) Write(ctx context.Context, id string) (string, error) {
+	name := id + r.format.extension()
+	if filepath.Base(name) != name {
+		return "", fmt.Errorf("invalid job id %q", id)
+	}
+
+	report, err := BuildReport(ctx, r.db, r.cipher, id)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(r.dir, name)
+	tmp := path + ".tmp"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return "", err
+	}
+
+	defer os.Remove(tmp)
+
+	if err := RenderReport(f, report, r.format); err != nil {
+		f.Close()
+		return "", err
+	}
+
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return "", err
+	}
+
+	location := path
+	if r.baseURL != "" {
+		location = r.baseURL + "/" + url.PathEscape(name)
+	}
+
+	_, err = r.db.ExecContext(ctx, `UPDATE gmaps_jobs SET report_path = $1 WHERE id = $2`, location, id)
+	if err != nil {
+		return "", fmt.Errorf("failed to store report path: %w", err)
+	}
+
+	return location, nil
+}
+
+// BuildReport gathers the report of the root job id, its sample results
+// decrypted with c.
+func BuildReport(ctx context.Context, db *sql.DB, c *FieldCipher, id string) (*Report, error) {
+	progress, err := GetJobProgress(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := SummarizeResults(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	queries, err := reportQueries(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	failures, err := reportFailures(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	samples, err := ListResults(ctx, db, c,
+		NewResultsQuery(ResultFilter{JobID: id}).OrderBy(SortByReviewCount, true).Page(reportSamples, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Report{
+		JobID:       id,
+		GeneratedAt: time.Now().UTC(),
+		Progress:    progress,
+		Summary:     summary,
+		Queries:     queries,
+		Failures:    failures,
+		Samples:     samples,
+	}, nil
+}
+
+// reportQueries returns the queries of the search jobs of the root job id,
+// with the places saved for each one. Searches that found nothing are listed
+// too.
+func reportQueries(ctx context.Context, db *sql.DB, id string) ([]QueryCount, error) {
+	counts := map[string]int{}
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT COALESCE(query, ''), COUNT(*) FROM results WHERE parent_id = $1 GROUP BY 1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count results per query: %w", err)
+	}
+
+	for rows.Next() {
+		var (
+			query string
+			n     int
+		)
+
+		if err := rows.Scan(&query, &n); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		counts[query] = n
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.QueryContext(ctx,
+		`SELECT payload FROM gmaps_jobs WHERE payload_type = $1 AND (id = $2 OR parent_id = $2)`, jobTypeSearch, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list search jobs: %w", err)
+	}
+	defer rows.Close()
+
+	registry := NewCodecRegistry()
+
+	for rows.Next() {
+		var payload []byte
+		if err := rows.Scan(&payload); err != nil {
+			return nil, err
+		}
+
+		job, err := registry.DecodeJob(jobTypeSearch, payload)
+		if err != nil {
+			// the queries of the results are still listed
+			continue
+		}
+
+		q := gmaps.SearchQuery(job.GetURL())
+		if _, ok := counts[q]; !ok && q != "" {
+			counts[q] = 0
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ans := make([]QueryCount, 0, len(counts))
+	for q, n := range counts {
+		ans = append(ans, QueryCount{Query: q, Places: n})
+	}
+
+	sort.Slice(ans, func(i, j int) bool {
+		if ans[i].Places != ans[j].Places {
+			return ans[i].Places > ans[j].Places
+		}
+
+		return ans[i].Query < ans[j].Query
+	})
+
+	return ans, nil
+}
+
+// reportFailures counts the failed jobs under the root job id by reason. Jobs
+// that failed before reasons were recorded are counted by type.
+func reportFailures(ctx context.Context, db *sql.DB, id string) ([]FailureCount, error) {
+	const q = `WITH RECURSIVE tree AS (
+			SELECT id FROM gmaps_jobs WHERE id = $1
+			UNION ALL
+			SELECT j.id FROM gmaps_jobs j JOIN tree t ON j.parent_id = t.id
+		)
+		SELECT COALESCE(NULLIF(failure_reason, ''), payload_type || ' job failed'), COUNT(*)
+		FROM gmaps_jobs
+		WHERE id IN (SELECT id FROM tree) AND status = $2
+		GROUP BY 1
+		ORDER BY 2 DESC, 1`
+
+	rows, err := db.QueryContext(ctx, q, id, statusFailed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count failures: %w", err)
+	}
+	defer rows.Close()
+
+	var ans []FailureCount
+
+	for rows.Next() {
+		var f FailureCount
+		if err := rows.Scan(&f.Reason, &f.Jobs); err != nil {
+			return nil, err
+		}
+
+		ans = append(ans, f)
+	}
+
+	return ans, rows.Err()
+}
+
+// WithFailureReasons records why jobs fail in their failure_reason column,
+// which reports group and the dashboard shows.
+func WithFailureReasons() ProviderOption {
+	return func(p *provider) {
+		p.recordFailures = true
+	}
+}
+
+// recordFailure stores why job failed, for the reports and the dashboard.
+func (p *provider) recordFailure(job scrapemate.IJob, reason string) {
+	if !p.recordFailures {
+		return
+	}
+
+	id := job.GetID()
+	reason = failureReason(reason)
+
+	p.goBackground(func() {
+		ctx := context.Background()
+
+		_, err := p.db.ExecContext(ctx, `UPDATE gmaps_jobs SET failure_reason = $1 WHERE id = $2`, reason, id)
+		if err != nil {
+			log := scrapemate.GetLoggerFromContext(ctx)
+			log.Error(fmt.Sprintf("failed to record failure of job %s: %v", id, err))
+		}
+	})
+}
+
+// failureReason keeps the first line of an error message, short enough to
+// group the failures sharing a cause.
+func failureReason(msg string) string {
+	msg, _, _ = strings.Cut(strings.TrimSpace(msg), "\n")
+
+	return truncate(msg, maxFailureReason)
+}
+
+// RenderReport writes report to w in format.
+func RenderReport(w io.Writer, report *Report, format ReportFormat) error {
+	if format == ReportHTML {
+		return htmlReport.Execute(w, report)
+	}
+
+	return markdownReport.Execute(w, report)
+}
+
+var reportFuncs = map[string]any{
+	"join": strings.Join,
+	// md escapes the characters breaking a Markdown table cell
+	"md": func(s string) string {
+		return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+	},
+	"date": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04 MST")
+	},
+}
+
+var markdownReport = template.Must(template.New("report").Funcs(reportFuncs).Parse(
+	`# Scrape report
+
+Job ` + "`{{.JobID}}`" + `, generated on {{date .GeneratedAt}}.
+
+## Overview
+
+| | |
+|---|---|
+| Status | {{.Progress.Status}} |
+| Places found | {{.Summary.Places}} |
+| With website | {{.Summary.WithWebsitePct}}% |
+| With email | {{.Summary.WithEmailPct}}% |
+| With SIREN | {{.Summary.WithSirenPct}}% |
+| Average rating | {{.Summary.AverageRating}} |
+| Jobs completed | {{.Progress.ChildJobsCompleted}} of {{.Progress.ChildJobsCount}} |
+| Jobs failed | {{.Progress.ChildJobsFailed}} |
+
+## Queries
+
+| Query | Places |
+|---|---|
+{{range .Queries}}| {{md .Query}} | {{.Places}} |
+{{end}}
+## Failures
+{{if .Failures}}
+| Reason | Jobs |
+|---|---|
+{{range .Failures}}| {{md .Reason}} | {{.Jobs}} |
+{{end}}{{else}}
+No job failed.
+{{end}}
+## Sample results
+
+| Name | Category | Address | Phone | Website | Rating | Reviews |
+|---|---|---|---|---|---|---|
+{{range .Samples}}| {{md .Title}} | {{md .Category}} | {{md .Address}} | {{md (join .Phones ", ")}} | {{md .Website}} | {{.ReviewRating}} | {{.ReviewCount}} |
+{{end}}`))
+
+var htmlReport = htmltemplate.Must(htmltemplate.New("report").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Scrape report {{.JobID}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { background: #1f4e78; color: #fff; }
+</style>
+</head>
+<body>
+<h1>Scrape report</h1>
+<p>Job <code>{{.JobID}}</code>, generated on {{date .GeneratedAt}}.</p>
+
+<h2>Overview</h2>
+<table>
+<tr><th>Status</th><td>{{.Progress.Status}}</td></tr>
+<tr><th>Places found</th><td>{{.Summary.Places}}</td></tr>
+<tr><th>With website</th><td>{{.Summary.WithWebsitePct}}%</td></tr>
+<tr><th>With email</th><td>{{.Summary.WithEmailPct}}%</td></tr>
+<tr><th>With SIREN</th><td>{{.Summary.WithSirenPct}}%</td></tr>
+<tr><th>Average rating</th><td>{{.Summary.AverageRating}}</td></tr>
+<tr><th>Jobs completed</th><td>{{.Progress.ChildJobsCompleted}} of {{.Progress.ChildJobsCount}}</td></tr>
+<tr><th>Jobs failed</th><td>{{.Progress.ChildJobsFailed}}</td></tr>
+</table>
+
+<h2>Queries</h2>
+<table>
+<tr><th>Query</th><th>Places</th></tr>
+{{range .Queries}}<tr><td>{{.Query}}</td><td>{{.Places}}</td></tr>
+{{end}}</table>
+
+<h2>Failures</h2>
+{{if .Failures}}<table>
+<tr><th>Reason</th><th>Jobs</th></tr>
+{{range .Failures}}<tr><td>{{.Reason}}</td><td>{{.Jobs}}</td></tr>
+{{end}}</table>
+{{else}}<p>No job failed.</p>
+{{end}}
+<h2>Sample results</h2>
+<table>
+<tr><th>Name</th><th>Category</th><th>Address</th><th>Phone</th><th>Website</th><th>Rating</th><th>Reviews</th></tr>
+{{range .Samples}}<tr><td>{{.Title}}</td><td>{{.Category}}</td><td>{{.Address}}</td><td>{{join .Phones ", "}}</td><td>{{.Website}}</td><td>{{.ReviewRating}}</td><td>{{.ReviewCount}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
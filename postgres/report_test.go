@@ -0,0 +1,37 @@
+package postgres_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+func Test_RenderReport(t *testing.T) {
+	report := &postgres.Report{
+		JobID:       "job-1",
+		GeneratedAt: time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC),
+		Progress:    postgres.JobProgress{Status: "done", ChildJobsCount: 3, ChildJobsCompleted: 2, ChildJobsFailed: 1},
+		Summary:     postgres.ResultsSummary{Places: 2, WithWebsitePct: 50, AverageRating: 4.5},
+		Queries:     []postgres.QueryCount{{Query: "plombier | paris", Places: 2}},
+		Failures:    []postgres.FailureCount{{Reason: "place already saved for this owner", Jobs: 1}},
+		Samples: []postgres.Result{
+			{Title: "<b>Martin</b>", Phones: []string{"01 23 45 67 89", "06 00 00 00 00"}, ReviewRating: 4.5, ReviewCount: 12},
+		},
+	}
+
+	var md bytes.Buffer
+	require.NoError(t, postgres.RenderReport(&md, report, postgres.ReportMarkdown))
+	require.Contains(t, md.String(), `| plombier \| paris | 2 |`)
+	require.Contains(t, md.String(), "| place already saved for this owner | 1 |")
+	require.Contains(t, md.String(), "| 01 23 45 67 89, 06 00 00 00 00 |")
+
+	var html bytes.Buffer
+	require.NoError(t, postgres.RenderReport(&html, report, postgres.ReportHTML))
+	require.Contains(t, html.String(), "&lt;b&gt;Martin&lt;/b&gt;")
+	require.NotContains(t, html.String(), "<b>Martin")
+	require.Contains(t, html.String(), "<td>plombier | paris</td><td>2</td>")
+}
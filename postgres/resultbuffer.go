@@ -0,0 +1,176 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/gosom/scrapemate"
+)
+
+const (
+	// DefaultResultBuffer is how many results the crawler hands over to the
+	// result writer before it waits for it.
+	DefaultResultBuffer = 500
+	// behindRatio is the share of the buffer the results waiting for the
+	// writer must reach for it to count as behind, and resumeRatio the share
+	// they must fall under again, so that the throttling does not flap.
+	behindRatio = 0.8
+	resumeRatio = 0.5
+)
+
+// ResultBufferStats is the state of a ResultBuffer.
+type ResultBufferStats struct {
+	// Length is how many results wait for the writer, out of Capacity.
+	Length   int `json:"length"`
+	Capacity int `json:"capacity"`
+	// HighWater is the most results that waited at once.
+	HighWater int  `json:"high_water"`
+	Behind    bool `json:"behind"`
+	// Episodes counts how many times the writer fell behind.
+	Episodes int64 `json:"episodes"`
+	// BlockedSeconds is how long the crawler waited on a full buffer.
+	BlockedSeconds float64 `json:"blocked_seconds"`
+	Results        int64   `json:"results"`
+}
+
+// ResultBuffer runs a result writer behind a buffer of results. Unlike a
+// sink it never drops them: when the buffer is full the crawler waits for
+// the writer. A writer holding most of the buffer, as when the database
+// slows down, is behind, and the provider then fetches fewer jobs and no
+// place jobs until it catches up instead of piling up results.
+type ResultBuffer struct {
+	w   scrapemate.ResultWriter
+	buf chan scrapemate.Result
+
+	highWater atomic.Int64
+	behind    atomic.Bool
+	episodes  atomic.Int64
+	blocked   atomic.Int64
+	results   atomic.Int64
+}
+
+// NewResultBuffer creates a ResultBuffer running w with a buffer of size
+// results.
+func NewResultBuffer(w scrapemate.ResultWriter, size int) *ResultBuffer {
+	if size < 1 {
+		size = DefaultResultBuffer
+	}
+
+	return &ResultBuffer{w: w, buf: make(chan scrapemate.Result, size)}
+}
+
+// WithResultBuffer makes the provider fetch fewer jobs and no place jobs
+// while the writer of b is behind.
+func WithResultBuffer(b *ResultBuffer) ProviderOption {
+	return func(p *provider) {
+		p.results = b
+	}
+}
+
+// Run feeds the writer until in is closed, then waits for it to finish. It
+// goes on once ctx is done: scrapemate closes in once its workers stopped,
+// and the writer saves the results handed over until then. It returns early
+// with the error of the writer when it fails.
+func (b *ResultBuffer) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	done := make(chan error, 1)
+
+	go func() {
+		done <- b.w.Run(ctx, b.buf)
+	}()
+
+	log := scrapemate.GetLoggerFromContext(ctx)
+
+	for {
+		select {
+		case result, ok := <-in:
+			if !ok {
+				close(b.buf)
+				return <-done
+			}
+
+			if err := b.push(result, done); err != nil {
+				return err
+			}
+
+			if b.update() {
+				log.Info(fmt.Sprintf("result writer behind with %d of %d results buffered, throttling job fetching",
+					len(b.buf), cap(b.buf)))
+			}
+		case err := <-done:
+			return err
+		}
+	}
+}
+
+// push hands result over to the writer, waiting while the buffer is full.
+func (b *ResultBuffer) push(result scrapemate.Result, done <-chan error) error {
+	b.results.Add(1)
+
+	select {
+	case b.buf <- result:
+		return nil
+	default:
+	}
+
+	start := time.Now()
+	defer func() {
+		b.blocked.Add(int64(time.Since(start)))
+	}()
+
+	select {
+	case b.buf <- result:
+		return nil
+	case err := <-done:
+		return err
+	}
+}
+
+// update records the length of the buffer and tells whether the writer just
+// fell behind.
+func (b *ResultBuffer) update() bool {
+	n := int64(len(b.buf))
+
+	for {
+		hw := b.highWater.Load()
+		if n <= hw || b.highWater.CompareAndSwap(hw, n) {
+			break
+		}
+	}
+
+	if float64(n) >= float64(cap(b.buf))*behindRatio && !b.behind.Swap(true) {
+		b.episodes.Add(1)
+		return true
+	}
+
+	return false
+}
+
+// Behind tells whether the writer filled behindRatio of the buffer and has
+// not brought it back under resumeRatio yet.
+func (b *ResultBuffer) Behind() bool {
+	if b == nil || !b.behind.Load() {
+		return false
+	}
+
+	if float64(len(b.buf)) < float64(cap(b.buf))*resumeRatio {
+		b.behind.Store(false)
+		return false
+	}
+
+	return true
+}
+
+// Stats returns the state of the buffer.
+func (b *ResultBuffer) Stats() ResultBufferStats {
+	return ResultBufferStats{
+		Length:         len(b.buf),
+		Capacity:       cap(b.buf),
+		HighWater:      int(b.highWater.Load()),
+		Behind:         b.Behind(),
+		Episodes:       b.episodes.Load(),
+		BlockedSeconds: time.Duration(b.blocked.Load()).Seconds(),
+		Results:        b.results.Load(),
+	}
+}
@@ -0,0 +1,110 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gosom/scrapemate"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+// gatedWriter takes no result until its gate is opened.
+type gatedWriter struct {
+	gate chan struct{}
+}
+
+func (w *gatedWriter) Run(_ context.Context, in <-chan scrapemate.Result) error {
+	<-w.gate
+
+	for range in {
+	}
+
+	return nil
+}
+
+func Test_ResultBufferBehind(t *testing.T) {
+	w := &gatedWriter{gate: make(chan struct{})}
+	b := postgres.NewResultBuffer(w, 10)
+
+	in := make(chan scrapemate.Result)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- b.Run(context.Background(), in)
+	}()
+
+	for i := 0; i < 8; i++ {
+		in <- scrapemate.Result{}
+	}
+
+	require.Eventually(t, b.Behind, time.Second, 10*time.Millisecond)
+
+	stats := b.Stats()
+	require.Equal(t, 10, stats.Capacity)
+	require.Equal(t, 8, stats.HighWater)
+	require.Equal(t, int64(1), stats.Episodes)
+
+	close(w.gate)
+
+	require.Eventually(t, func() bool { return !b.Behind() }, time.Second, 10*time.Millisecond)
+
+	close(in)
+	require.NoError(t, <-done)
+	require.Equal(t, int64(8), b.Stats().Results)
+}
+
+func Test_ResultWriterSavesHandedOverResultsOnShutdown(t *testing.T) {
+	fake, db := newFakeDB(t)
+
+	fake.on("SELECT COUNT(*) FROM results", func([]driver.Value) answer {
+		return answer{columns: []string{"count"}, rows: [][]driver.Value{{int64(0)}}}
+	})
+
+	b := postgres.NewResultBuffer(postgres.NewResultWriter(db, ""), 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan scrapemate.Result)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- b.Run(ctx, in)
+	}()
+
+	var links []driver.Value
+
+	send := func(n int) {
+		for range n {
+			link := fmt.Sprintf("https://www.google.com/maps/place/%d", len(links))
+			links = append(links, link)
+
+			job := gmaps.NewPlaceJob("", "en", link, "owner-1", "", false, false)
+			in <- scrapemate.Result{Job: job, Data: &gmaps.Entry{Link: link, Title: "place"}}
+		}
+	}
+
+	send(3)
+	cancel()
+	// scrapemate hands over the results of the jobs still running, then
+	// closes the channel
+	send(2)
+	close(in)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the writer did not stop")
+	}
+
+	var saved []driver.Value
+	for _, args := range fake.ran("INSERT INTO results") {
+		saved = append(saved, args[3])
+	}
+
+	require.Equal(t, links, saved)
+}
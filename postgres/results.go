@@ -0,0 +1,497 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/gosom/google-maps-scraper/apiauth"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/httpjson"
+)
+
+var (
+	// ErrJobNotFound is returned when a job id does not exist.
+	ErrJobNotFound = errors.New("job not found")
+	// ErrResultNotFound is returned when a result id does not exist.
+	ErrResultNotFound = errors.New("result not found")
+)
+
+// LeadStatus tracks where a result is in the sales pipeline.
+type LeadStatus string
+
+const (
+	LeadStatusNew       LeadStatus = "new"
+	LeadStatusContacted LeadStatus = "contacted"
+	LeadStatusQualified LeadStatus = "qualified"
+	LeadStatusRejected  LeadStatus = "rejected"
+)
+
+// ParseLeadStatus returns the LeadStatus named s.
+func ParseLeadStatus(s string) (LeadStatus, error) {
+	switch LeadStatus(s) {
+	case LeadStatusNew, LeadStatusContacted, LeadStatusQualified, LeadStatusRejected:
+		return LeadStatus(s), nil
+	default:
+		return "", fmt.Errorf("invalid lead status %q: must be new, contacted, qualified or rejected", s)
+	}
+}
+
+// JobProgress is the state of a job and of the jobs it spawned.
+type JobProgress struct {
+	Status             string `json:"status"`
+	ChildJobsCount     int    `json:"child_jobs_count"`
+	ChildJobsCompleted int    `json:"child_jobs_completed"`
+	ChildJobsFailed    int    `json:"child_jobs_failed"`
+	// OrganizationID is the organization that submitted the job.
+	OrganizationID string `json:"-"`
+}
+
+// Finished reports whether the job reached a final status.
+func (p JobProgress) Finished() bool {
+	return p.Status == statusDone || p.Status == statusFailed
+}
+
+// GetJobProgress returns the progress of the job id.
+func GetJobProgress(ctx context.Context, db *sql.DB, id string) (JobProgress, error) {
+	var p JobProgress
+
+	err := db.QueryRowContext(ctx,
+		`SELECT status, child_jobs_count, child_jobs_completed, child_jobs_failed,
+			COALESCE(payload::jsonb -> 'metadata' ->> 'organization_id', '')
+		FROM gmaps_jobs WHERE id = $1`,
+		id,
+	).Scan(&p.Status, &p.ChildJobsCount, &p.ChildJobsCompleted, &p.ChildJobsFailed, &p.OrganizationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, ErrJobNotFound
+	}
+
+	return p, err
+}
+
+// CheckJobOrganization returns ErrJobNotFound when the job id was not
+// submitted by organizationID, unless it is empty.
+func CheckJobOrganization(ctx context.Context, db *sql.DB, id, organizationID string) error {
+	if organizationID == "" {
+		return nil
+	}
+
+	p, err := GetJobProgress(ctx, db, id)
+	if err != nil {
+		return err
+	}
+
+	if p.OrganizationID != organizationID {
+		return ErrJobNotFound
+	}
+
+	return nil
+}
+
+// Result is a row of the results table.
+type Result struct {
+	ID             int64  `json:"id"`
+	ParentID       string `json:"job_id"`
+	UserID         string `json:"owner_id"`
+	OrganizationID string `json:"organization_id"`
+	Link           string `json:"link"`
+	Query          string `json:"query"`
+	Title          string `json:"title"`
+	Category       string `json:"category"`
+	CategoryID     string `json:"category_id"`
+	// QualityScore rates from 0 to 100 how complete the lead is. The
+	// database computes it, see the README.
+	QualityScore      int        `json:"quality_score"`
+	Address           string     `json:"address"`
+	Website           string     `json:"website"`
+	Phones            []string   `json:"phones"`
+	Emails            []string   `json:"emails"`
+	Latitude          float64    `json:"latitude"`
+	Longitude         float64    `json:"longitude"`
+	ReviewRating      float64    `json:"review_rating"`
+	ReviewCount       int        `json:"review_count"`
+	SocieteDirigeants []string   `json:"societe_dirigeants"`
+	SocieteSiren      string     `json:"societe_siren"`
+	SocieteForme      string     `json:"societe_forme"`
+	SocieteEffectif   string     `json:"societe_effectif"`
+	SocieteCreation   string     `json:"societe_creation"`
+	SocieteCloture    string     `json:"societe_cloture"`
+	SocieteLink       string     `json:"societe_link"`
+	SocieteDiffusion  *bool      `json:"societe_diffusion"`
+	SirenResolution   string     `json:"siren_resolution"`
+	CodeCommune       string     `json:"code_commune"`
+	CodePostal        string     `json:"code_postal"`
+	DepartmentCode    string     `json:"department_code"`
+	DepartmentName    string     `json:"department_name"`
+	Region            string     `json:"region"`
+	Tags              []string   `json:"tags"`
+	LeadStatus        LeadStatus `json:"lead_status"`
+	CreatedAt         time.Time  `json:"created_at"`
+	// Provenance tells which source supplied each enriched column and when.
+	Provenance gmaps.Provenance `json:"provenance"`
+	// PlaceID is the Google feature id of the place, e.g.
+	// 0x47e66e2964e34e2d:0x8ddca9ee380ef7e0.
+	PlaceID string `json:"place_id"`
+	NafCode string `json:"naf_code"`
+	// ServiceAreaBusiness is set for listings with a hidden address, whose
+	// ServiceArea is the locality Google shows instead.
+	ServiceAreaBusiness bool   `json:"service_area_business"`
+	ServiceArea         string `json:"service_area,omitempty"`
+	// ReviewStats sums up the reviews of the place when extra reviews were
+	// fetched.
+	ReviewStats *gmaps.ReviewStats `json:"review_stats,omitempty"`
+	// CompanyMatch is matched or no_match after a company lookup, empty
+	// before. CompanyCandidate is the company rejected on a no_match.
+	CompanyMatch     string            `json:"company_match,omitempty"`
+	CompanyCandidate *CompanyCandidate `json:"company_candidate,omitempty"`
+	// Technologies are the products the website runs on, e.g. WordPress or
+	// Shopify, found while extracting emails.
+	Technologies []string `json:"technologies"`
+	// WebsiteHealth is how the website fared when visited.
+	WebsiteHealth *gmaps.WebsiteHealth `json:"website_health,omitempty"`
+	// SocialProfiles are the social pages of the place, by network.
+	SocialProfiles map[string]gmaps.SocialProfile `json:"social_profiles,omitempty"`
+	// WhatsApp is the number of the click-to-chat link of the place or its
+	// website, e.g. +33612345678.
+	WhatsApp string `json:"whatsapp,omitempty"`
+}
+
+const resultColumns = `id, parent_id, user_id, organization_id, link, query, title, category, address, website,
+	phones, emails, latitude, longitude, review_rating, review_count, societe_dirigeants, societe_siren, societe_forme,
+	societe_effectif, societe_creation, societe_cloture, societe_link, societe_diffusion, siren_resolution, provenance, code_commune, code_postal,
+	department_code, department_name, region, category_id, quality_score, tags, lead_status, created_at, place_id, naf_code,
+	service_area_business, service_area, review_stats, company_match, company_candidate, technologies,
+	website_health, social_profiles, whatsapp`
+
+// ResultsAfter returns up to limit results of the root job parentID with an
+// id greater than afterID, in id order, decrypted with c.
+func ResultsAfter(ctx context.Context, db *sql.DB, c *FieldCipher, parentID string, afterID int64, limit int) ([]Result, error) {
+	q := NewResultsQuery(ResultFilter{JobID: parentID, AfterID: afterID}).
+		OrderBy(SortByID, false).
+		Page(limit, 0)
+
+	return ListResults(ctx, db, c, q)
+}
+
+// ListResults returns the results selected by q, their emails and directors
+// decrypted with c. Reading an encrypted one without c fails with
+// ErrNoFieldCipher.
+func ListResults(ctx context.Context, db *sql.DB, c *FieldCipher, q *ResultsQuery) ([]Result, error) {
+	query, args := q.Build()
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query results: %w", err)
+	}
+	defer rows.Close()
+
+	var ans []Result
+
+	types := pgtype.NewMap()
+
+	for rows.Next() {
+		r, err := scanResult(rows, types, c)
+		if err != nil {
+			return nil, err
+		}
+
+		ans = append(ans, r)
+	}
+
+	return ans, rows.Err()
+}
+
+// CountResults returns how many results match the filters of q, ignoring
+// its pagination.
+func CountResults(ctx context.Context, db *sql.DB, q *ResultsQuery) (int, error) {
+	query, args := q.BuildCount()
+
+	var n int
+	if err := db.QueryRowContext(ctx, query, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count results: %w", err)
+	}
+
+	return n, nil
+}
+
+// ResultUpdate changes the fields it sets and leaves the others alone.
+type ResultUpdate struct {
+	// Tags replace the current ones. They are trimmed and deduplicated.
+	Tags       *[]string   `json:"tags"`
+	LeadStatus *LeadStatus `json:"lead_status"`
+}
+
+// Validate reports whether u is valid and changes something.
+func (u *ResultUpdate) Validate() error {
+	if u.Tags == nil && u.LeadStatus == nil {
+		return errors.New("nothing to update: set tags or lead_status")
+	}
+
+	if u.LeadStatus != nil {
+		if _, err := ParseLeadStatus(string(*u.LeadStatus)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateResult applies u to the result id of organizationID, of any
+// organization when it is empty, and returns the updated result, decrypted
+// with c.
+func UpdateResult(ctx context.Context, db *sql.DB, c *FieldCipher, id int64, organizationID string, u ResultUpdate) (Result, error) {
+	if err := u.Validate(); err != nil {
+		return Result{}, err
+	}
+
+	var (
+		sets []string
+		args []any
+	)
+
+	if u.Tags != nil {
+		args = append(args, normalizeTags(*u.Tags))
+		sets = append(sets, fmt.Sprintf("tags = $%d", len(args)))
+	}
+
+	if u.LeadStatus != nil {
+		args = append(args, string(*u.LeadStatus))
+		sets = append(sets, fmt.Sprintf("lead_status = $%d", len(args)))
+	}
+
+	args = append(args, id, organizationID)
+
+	rows, err := db.QueryContext(ctx,
+		fmt.Sprintf(`UPDATE results SET %s WHERE id = $%d AND ($%d = '' OR organization_id = $%d) RETURNING `+resultColumns,
+			strings.Join(sets, ", "), len(args)-1, len(args), len(args)),
+		args...,
+	)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to update result: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return Result{}, fmt.Errorf("failed to update result: %w", err)
+		}
+
+		return Result{}, ErrResultNotFound
+	}
+
+	return scanResult(rows, pgtype.NewMap(), c)
+}
+
+func normalizeTags(tags []string) []string {
+	ans := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" && !slices.Contains(ans, tag) {
+			ans = append(ans, tag)
+		}
+	}
+
+	return ans
+}
+
+// ResultsHandler lets clients update results over HTTP:
+//
+//	PATCH /results/{id}    change the tags or lead status: {"tags": ["vip"], "lead_status": "contacted"}
+//
+// The results returned are decrypted with c.
+func ResultsHandler(db *sql.DB, c *FieldCipher) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/results/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			w.Header().Set("Allow", "PATCH")
+			httpjson.Write(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+
+			return
+		}
+
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			httpjson.Write(w, http.StatusBadRequest, map[string]string{"error": "invalid result id"})
+			return
+		}
+
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+
+		var u ResultUpdate
+		if err := dec.Decode(&u); err != nil {
+			httpjson.Write(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		if err := u.Validate(); err != nil {
+			httpjson.Write(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		result, err := UpdateResult(r.Context(), db, c, id, apiauth.GetOrganizationFromContext(r.Context()), u)
+
+		switch {
+		case errors.Is(err, ErrResultNotFound):
+			httpjson.Write(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		case err != nil:
+			httpjson.Write(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		default:
+			httpjson.Write(w, http.StatusOK, result)
+		}
+	})
+
+	return mux
+}
+
+func scanResult(rows *sql.Rows, types *pgtype.Map, c *FieldCipher) (Result, error) {
+	var (
+		r                                  Result
+		parentID, userID, organizationID   sql.NullString
+		query                              sql.NullString
+		title, category, address, website  sql.NullString
+		latitude, longitude, rating        sql.NullFloat64
+		reviews                            sql.NullInt64
+		dirigeants, siren, forme           sql.NullString
+		effectif, creation, cloture        sql.NullString
+		societeLink                        sql.NullString
+		diffusion                          sql.NullBool
+		resolution                         sql.NullString
+		provenance                         []byte
+		codeCommune, codePostal            sql.NullString
+		department, departmentName, region sql.NullString
+		categoryID                         sql.NullString
+		quality                            sql.NullInt64
+		leadStatus                         sql.NullString
+		createdAt                          sql.NullTime
+		placeID, naf                       sql.NullString
+		serviceAreaBusiness                sql.NullBool
+		serviceArea                        sql.NullString
+		reviewStats, websiteHealth         []byte
+		socialProfiles                     []byte
+		whatsApp                           sql.NullString
+		companyMatch                       sql.NullString
+		candidate                          []byte
+	)
+
+	err := rows.Scan(&r.ID, &parentID, &userID, &organizationID, &r.Link, &query, &title, &category, &address, &website,
+		types.SQLScanner(&r.Phones), types.SQLScanner(&r.Emails), &latitude, &longitude, &rating, &reviews, &dirigeants, &siren, &forme,
+		&effectif, &creation, &cloture, &societeLink, &diffusion, &resolution, &provenance, &codeCommune, &codePostal,
+		&department, &departmentName, &region, &categoryID, &quality,
+		types.SQLScanner(&r.Tags), &leadStatus, &createdAt, &placeID, &naf,
+		&serviceAreaBusiness, &serviceArea, &reviewStats, &companyMatch, &candidate, types.SQLScanner(&r.Technologies),
+		&websiteHealth, &socialProfiles, &whatsApp)
+	if err != nil {
+		return r, fmt.Errorf("failed to scan result: %w", err)
+	}
+
+	r.ParentID = parentID.String
+	r.UserID = userID.String
+	r.OrganizationID = organizationID.String
+	r.Query = query.String
+	r.Title = title.String
+	r.Category = category.String
+	r.CategoryID = categoryID.String
+	r.QualityScore = int(quality.Int64)
+	r.Address = address.String
+	r.Website = website.String
+	r.Latitude = latitude.Float64
+	r.Longitude = longitude.Float64
+	r.ReviewRating = rating.Float64
+	r.ReviewCount = int(reviews.Int64)
+	r.SocieteSiren = siren.String
+	r.SocieteForme = forme.String
+	r.SocieteEffectif = effectif.String
+	r.SocieteCreation = creation.String
+	r.SocieteCloture = cloture.String
+	r.SocieteLink = societeLink.String
+	r.SirenResolution = resolution.String
+	r.CodeCommune = codeCommune.String
+	r.CodePostal = codePostal.String
+	r.DepartmentCode = department.String
+	r.DepartmentName = departmentName.String
+	r.Region = region.String
+	r.LeadStatus = LeadStatus(leadStatus.String)
+	r.CreatedAt = createdAt.Time
+	r.PlaceID = placeID.String
+	r.NafCode = naf.String
+	r.ServiceAreaBusiness = serviceAreaBusiness.Bool
+	r.ServiceArea = serviceArea.String
+	r.WhatsApp = whatsApp.String
+	r.CompanyMatch = companyMatch.String
+
+	if r.LeadStatus == "" {
+		r.LeadStatus = LeadStatusNew
+	}
+
+	emails, err := c.openAll(r.Emails)
+	if err != nil {
+		return r, fmt.Errorf("failed to read the emails of result %d: %w", r.ID, err)
+	}
+
+	r.Emails = emails
+
+	directors, err := c.open(dirigeants.String)
+	if err != nil {
+		return r, fmt.Errorf("failed to read the directors of result %d: %w", r.ID, err)
+	}
+
+	for _, d := range strings.Split(directors, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			r.SocieteDirigeants = append(r.SocieteDirigeants, d)
+		}
+	}
+
+	if diffusion.Valid {
+		v := diffusion.Bool
+		r.SocieteDiffusion = &v
+	}
+
+	if len(provenance) > 0 {
+		if err := json.Unmarshal(provenance, &r.Provenance); err != nil {
+			return r, fmt.Errorf("invalid provenance of result %d: %w", r.ID, err)
+		}
+	}
+
+	if len(reviewStats) > 0 {
+		if err := json.Unmarshal(reviewStats, &r.ReviewStats); err != nil {
+			return r, fmt.Errorf("invalid review stats of result %d: %w", r.ID, err)
+		}
+	}
+
+	if len(websiteHealth) > 0 {
+		if err := json.Unmarshal(websiteHealth, &r.WebsiteHealth); err != nil {
+			return r, fmt.Errorf("invalid website health of result %d: %w", r.ID, err)
+		}
+	}
+
+	if len(socialProfiles) > 0 {
+		if err := json.Unmarshal(socialProfiles, &r.SocialProfiles); err != nil {
+			return r, fmt.Errorf("invalid social profiles of result %d: %w", r.ID, err)
+		}
+	}
+
+	if len(candidate) > 0 {
+		if err := json.Unmarshal(candidate, &r.CompanyCandidate); err != nil {
+			return r, fmt.Errorf("invalid company candidate of result %d: %w", r.ID, err)
+		}
+
+		if r.CompanyCandidate != nil {
+			if r.CompanyCandidate.Name, err = c.open(r.CompanyCandidate.Name); err != nil {
+				return r, fmt.Errorf("failed to read the company candidate of result %d: %w", r.ID, err)
+			}
+		}
+	}
+
+	return r, nil
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -10,6 +11,8 @@ import (
 	"github.com/gosom/scrapemate"
 	"github.com/nyaruka/phonenumbers"
 
+	"github.com/gosom/google-maps-scraper/entreprise"
+	"github.com/gosom/google-maps-scraper/exiter"
 	"github.com/gosom/google-maps-scraper/gmaps"
 )
 
@@ -19,14 +22,18 @@ type dbEntry struct {
 	ParentID          string
 	Link              string
 	PayloadType       string
+	Query             string
 	Title             string
 	Category          string
+	CategoryID        string
 	Address           string
 	Website           string
 	Phones            []string
 	Emails            []string
 	Latitude          float64
 	Longitude         float64
+	ReviewRating      float64
+	ReviewCount       int
 	SocieteDirigeants string
 	SocieteSiren      string
 	SocieteForme      string
@@ -35,6 +42,20 @@ type dbEntry struct {
 	SocieteCloture    string
 	SocieteLink       string
 	SocieteDiffusion  *bool
+	CodeCommune       string
+	CodePostal        string
+	DepartmentCode    string
+	DepartmentName    string
+	Region            string
+	Provenance        string
+	PlaceID           string
+	// ServiceAreaBusiness and ServiceArea are set for listings with a
+	// hidden address.
+	ServiceAreaBusiness bool
+	ServiceArea         string
+	// ReviewStats is the JSON of the review stats of the place.
+	ReviewStats string
+	WhatsApp    string
 }
 
 // countryNameToCode maps common country names (as returned by Google Maps) to ISO 3166-1 alpha-2 codes.
@@ -70,6 +91,20 @@ var countryNameToCode = map[string]string{
 	"égypte": "EG",
 }
 
+// placeID returns the Google feature id of the place of entry, taken from its
+// link when the data has none.
+func placeID(entry *gmaps.Entry) string {
+	if entry.DataID != "" {
+		return entry.DataID
+	}
+
+	if m := placeIDRe.FindStringSubmatch(entry.Link); m != nil {
+		return m[1]
+	}
+
+	return ""
+}
+
 // phoneToPhones normalizes a phone string to E.164 using the place's country for context.
 // Google Maps often returns local-format numbers (e.g. "01 23 45 67 89" for France).
 func phoneToPhones(phone, country string) []string {
@@ -117,19 +152,68 @@ func phoneToPhones(phone, country string) []string {
 	return []string{}
 }
 
+// ResultWriterOption configures the postgres result writer.
+type ResultWriterOption func(*resultWriter)
+
+// WithWriterInactivityMonitor reports written results to m.
+func WithWriterInactivityMonitor(m *exiter.InactivityMonitor) ResultWriterOption {
+	return func(r *resultWriter) {
+		r.activity = m
+	}
+}
+
+// WithWriterAPIClient makes the revalidation calls with c instead of a client
+// of its own, so that they are coalesced with those of the provider.
+func WithWriterAPIClient(c *APIClient) ResultWriterOption {
+	return func(r *resultWriter) {
+		r.apiClient = c
+	}
+}
+
+// WithWriterFieldCipher stores the emails and directors of the results
+// encrypted with c.
+func WithWriterFieldCipher(c *FieldCipher) ResultWriterOption {
+	return func(r *resultWriter) {
+		r.cipher = c
+	}
+}
+
 // NewResultWriter creates a new ResultWriter backed by PostgreSQL.
-func NewResultWriter(db *sql.DB, revalidationAPIURL string) scrapemate.ResultWriter {
-	return &resultWriter{
+func NewResultWriter(db *sql.DB, revalidationAPIURL string, opts ...ResultWriterOption) scrapemate.ResultWriter {
+	w := &resultWriter{
 		db:            db,
 		apiClient:     NewAPIClient(revalidationAPIURL, ""),
 		inMemoryIndex: make(map[string]int),
+		codecs:        NewCodecRegistry(),
+		queries:       make(map[string]string),
 	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
 }
 
 type resultWriter struct {
 	db            *sql.DB
 	apiClient     *APIClient
 	inMemoryIndex map[string]int
+	activity      *exiter.InactivityMonitor
+	codecs        *CodecRegistry
+	queries       map[string]string
+	// flush receives a value when the memory watchdog wants the buffer
+	// saved; it is nil without one.
+	flush <-chan struct{}
+	// spool keeps the batches the database fails to take, nil to fail
+	// instead.
+	spool *Spool
+	// summaries are completed once the results of their root jobs are
+	// saved, nil without.
+	summaries *RootSummaries
+	// cipher encrypts the emails and directors, nil to store them in
+	// plaintext.
+	cipher *FieldCipher
 }
 
 func (r *resultWriter) checkDuplicateURL(ctx context.Context, url, userID, organizationID string) (bool, error) {
@@ -190,6 +274,48 @@ func (r *resultWriter) getRootParentJobID(ctx context.Context, jobID string) (st
 	}
 }
 
+// maxCachedQueries bounds the cache of search queries by job id.
+const maxCachedQueries = 1000
+
+// searchQuery returns the query of the search job id. Every place found by a
+// search is written with it, so the answer is cached.
+func (r *resultWriter) searchQuery(ctx context.Context, id string) (string, error) {
+	if q, ok := r.queries[id]; ok {
+		return q, nil
+	}
+
+	var (
+		payloadType string
+		payload     []byte
+	)
+
+	err := r.db.QueryRowContext(ctx, `SELECT payload_type, payload FROM gmaps_jobs WHERE id = $1`, id).
+		Scan(&payloadType, &payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to get search job: %w", err)
+	}
+
+	job, err := r.codecs.DecodeJob(payloadType, payload)
+	if err != nil {
+		return "", err
+	}
+
+	q := gmaps.SearchQuery(job.GetURL())
+
+	// the searches of fast mode keep their query out of the URL
+	if search, ok := job.(*gmaps.SearchJob); ok {
+		q = search.Params().Query
+	}
+
+	if len(r.queries) >= maxCachedQueries {
+		clear(r.queries)
+	}
+
+	r.queries[id] = q
+
+	return q, nil
+}
+
 func (r *resultWriter) notifyRevalidation(ctx context.Context, entries []dbEntry) {
 	if r.apiClient.GetRevalidationURL() == "" {
 		return
@@ -205,14 +331,23 @@ func (r *resultWriter) notifyRevalidation(ctx context.Context, entries []dbEntry
 
 	// Call revalidation API for each unique user ID
 	for userID := range userIDs {
-		go r.apiClient.CallRevalidationAPI(ctx, userID)
+		r.apiClient.CallRevalidationAPI(ctx, userID)
 	}
 }
 
-func (r *resultWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
-	const maxBatchSize = 50
+const (
+	// maxBatchSize is how many results the result writer saves at once.
+	maxBatchSize = 50
+	// drainTimeout bounds how long the result writer keeps saving the
+	// results handed over once it is stopped.
+	drainTimeout = 30 * time.Second
+)
 
+func (r *resultWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
 	log := scrapemate.GetLoggerFromContext(ctx)
+	// the results are saved with work, which the shutdown does not cancel:
+	// those handed over belong to jobs already marked done
+	work := context.WithoutCancel(ctx)
 	buff := make([]dbEntry, 0, 50)
 	lastSave := time.Now().UTC()
 	ticker := time.NewTicker(time.Second * 10)
@@ -222,136 +357,266 @@ func (r *resultWriter) Run(ctx context.Context, in <-chan scrapemate.Result) err
 		case result, ok := <-in:
 			if !ok {
 				if len(buff) > 0 {
-					if err := r.batchSave(ctx, buff); err != nil {
+					if err := r.save(work, buff); err != nil {
 						return err
 					}
 				}
 				return nil
 			}
 
-			entry, ok := result.Data.(*gmaps.Entry)
-			if !ok || entry == nil {
-				continue
-			}
+			buff = r.add(work, buff, result)
 
-			payloadType := "place"
-
-			if result.Job != nil {
-				switch result.Job.(type) {
-				case *gmaps.GmapJob:
-					payloadType = "search"
-				case *gmaps.PlaceJob:
-					payloadType = "place"
-				}
-			}
-
-			var userID string
-			var organizationID string
-			var parentJobID string
-			var actualJob scrapemate.IJob = result.Job
-
-			if wrapper, ok := result.Job.(*jobWrapper); ok {
-				actualJob = wrapper.IJob
-			}
-
-			// keep base place results; enrichment happens via merge/update
-
-			if job, ok := actualJob.(*gmaps.GmapJob); ok {
-				userID = job.OwnerID
-				organizationID = job.OrganizationID
-
-				rootParentID, err := r.getRootParentJobID(ctx, job.GetID())
-				if err != nil {
-					log.Error(fmt.Sprintf("Error getting root parent job ID: %v", err))
-					parentJobID = job.GetID()
-				} else {
-					parentJobID = rootParentID
-				}
-			} else if job, ok := actualJob.(*gmaps.PlaceJob); ok {
-				userID = job.OwnerID
-				organizationID = job.OrganizationID
-
-				rootParentID, err := r.getRootParentJobID(ctx, job.GetID())
+			if len(buff) >= maxBatchSize {
+				err := r.save(work, buff)
 				if err != nil {
-					log.Error(fmt.Sprintf("Error getting root parent job ID: %v", err))
-					parentJobID = job.ParentID
-				} else {
-					parentJobID = rootParentID
+					return err
 				}
-			}
-
-			isDuplicate, err := r.checkDuplicateURL(ctx, entry.Link, userID, organizationID)
-			if err != nil {
-				log.Error(fmt.Sprintf("Error checking duplicate URL: %v", err))
-				continue
-			}
-
-			if isDuplicate {
-				continue
-			}
-
-			dbEntry := dbEntry{
-				UserID:            userID,
-				OrganizationID:    organizationID,
-				ParentID:          parentJobID,
-				Link:              entry.Link,
-				PayloadType:       payloadType,
-				Title:             entry.Title,
-				Category:          entry.Category,
-				Address:           entry.Address,
-				Website:           entry.WebSite,
-				Phones:            phoneToPhones(entry.Phone, entry.CompleteAddress.Country),
-				Emails:            entry.Emails,
-				Latitude:          entry.Latitude,
-				Longitude:         entry.Longtitude,
-				SocieteDirigeants: strings.Join(entry.SocieteDirigeants, ","),
-				SocieteSiren:      entry.SocieteSiren,
-				SocieteForme:      entry.SocieteForme,
-				SocieteEffectif:   "",
-				SocieteCreation:   entry.SocieteCreation,
-				SocieteCloture:    entry.SocieteCloture,
-				SocieteLink:       entry.SocieteLink,
-				SocieteDiffusion:  entry.SocieteDiffusion,
-			}
 
-			key := userID + "|" + organizationID + "|" + entry.Link
-			if _, ok := r.inMemoryIndex[key]; ok {
-				// Duplicate within the same batch - skip silently
-				continue
+				buff = buff[:0]
+				r.inMemoryIndex = make(map[string]int)
+				lastSave = time.Now().UTC()
 			}
-			r.inMemoryIndex[key] = len(buff)
-			buff = append(buff, dbEntry)
-
-			if len(buff) >= maxBatchSize {
-				err := r.batchSave(ctx, buff)
-				if err != nil {
+		case <-r.flush:
+			if len(buff) > 0 {
+				if err := r.save(work, buff); err != nil {
 					return err
 				}
-
 				buff = buff[:0]
 				r.inMemoryIndex = make(map[string]int)
 				lastSave = time.Now().UTC()
 			}
 		case <-ticker.C:
+			at, idle := time.Now(), len(in) == 0
+
 			if len(buff) > 0 && time.Since(lastSave) >= time.Second*5 {
-				if err := r.batchSave(ctx, buff); err != nil {
+				if err := r.save(work, buff); err != nil {
 					return err
 				}
 				buff = buff[:0]
 				r.inMemoryIndex = make(map[string]int)
 				lastSave = time.Now().UTC()
+			} else if r.spool != nil && r.spool.pending.Load() > 0 {
+				if err := r.replay(work); err != nil {
+					log.Info(fmt.Sprintf("spooled results not saved back yet: %v", err))
+				}
 			}
-		case <-ctx.Done():
-			if len(buff) > 0 {
-				saveCtx, cancel := context.WithTimeout(context.Background(), time.Second*5)
-				defer cancel()
-				_ = r.batchSave(saveCtx, buff)
+
+			if idle && len(buff) == 0 {
+				r.summarize(work, at)
 			}
+		case <-ctx.Done():
+			r.drain(ctx, in, buff)
 			return ctx.Err()
 		}
 	}
 }
 
+// summarize completes the root jobs done by at, when every result handed
+// over by then is saved: none was left in the buffer, and none is spooled.
+func (r *resultWriter) summarize(ctx context.Context, at time.Time) {
+	if r.summaries == nil || (r.spool != nil && r.spool.pending.Load() > 0) {
+		return
+	}
+
+	r.summaries.saved(ctx, r.db, at)
+}
+
+// drain saves buff and the results still handed over once ctx is done, until
+// in is closed or drainTimeout passes: their jobs are already marked done, so
+// they would be lost for good.
+func (r *resultWriter) drain(ctx context.Context, in <-chan scrapemate.Result, buff []dbEntry) {
+	log := scrapemate.GetLoggerFromContext(ctx)
+
+	// the last batch gets a few seconds to be saved after the deadline
+	deadline := time.After(drainTimeout)
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), drainTimeout+5*time.Second)
+	defer cancel()
+
+	save := func() {
+		if len(buff) == 0 {
+			return
+		}
+
+		if err := r.save(ctx, buff); err != nil {
+			log.Error(fmt.Sprintf("Error saving results on shutdown: %v", err))
+		}
+
+		buff = buff[:0]
+		r.inMemoryIndex = make(map[string]int)
+	}
+
+	defer save()
+
+	for {
+		select {
+		case result, ok := <-in:
+			if !ok {
+				return
+			}
+
+			buff = r.add(ctx, buff, result)
+
+			if len(buff) >= maxBatchSize {
+				save()
+			}
+		case <-deadline:
+			log.Error("Stopped saving results on shutdown after " + drainTimeout.String())
+			return
+		}
+	}
+}
+
+// add appends the entry of result to buff, unless it has none to save.
+func (r *resultWriter) add(ctx context.Context, buff []dbEntry, result scrapemate.Result) []dbEntry {
+	log := scrapemate.GetLoggerFromContext(ctx)
+
+	entry, ok := result.Data.(*gmaps.Entry)
+	if !ok || entry == nil {
+		return buff
+	}
+
+	var userID string
+	var organizationID string
+	var parentJobID string
+	var actualJob scrapemate.IJob = result.Job
+
+	if wrapper, ok := result.Job.(*jobWrapper); ok {
+		actualJob = wrapper.IJob
+	}
+
+	payloadType := jobTypePlace
+	if _, ok := actualJob.(*gmaps.GmapJob); ok {
+		payloadType = jobTypeSearch
+	}
+
+	// keep base place results; enrichment happens via merge/update
+
+	var (
+		query   string
+		refresh bool
+	)
+
+	if job, ok := actualJob.(*gmaps.GmapJob); ok {
+		query = gmaps.SearchQuery(job.GetURL())
+		userID = job.OwnerID
+		organizationID = job.OrganizationID
+
+		rootParentID, err := r.getRootParentJobID(ctx, job.GetID())
+		if err != nil {
+			log.Error(fmt.Sprintf("Error getting root parent job ID: %v", err))
+			parentJobID = job.GetID()
+		} else {
+			parentJobID = rootParentID
+		}
+	} else if job, ok := actualJob.(*gmaps.PlaceJob); ok {
+		userID = job.OwnerID
+		organizationID = job.OrganizationID
+		refresh = job.Refresh
+
+		rootParentID, err := r.getRootParentJobID(ctx, job.GetID())
+		if err != nil {
+			log.Error(fmt.Sprintf("Error getting root parent job ID: %v", err))
+			parentJobID = job.ParentID
+		} else {
+			parentJobID = rootParentID
+		}
+
+		if job.ParentID != "" {
+			if query, err = r.searchQuery(ctx, job.ParentID); err != nil {
+				log.Error(fmt.Sprintf("Error getting search query: %v", err))
+			}
+		}
+	}
+
+	if refresh {
+		updated, err := r.refreshResult(ctx, entry, userID, organizationID)
+		if err != nil {
+			log.Error(fmt.Sprintf("Error refreshing result: %v", err))
+			return buff
+		}
+
+		// places the owner does not have yet are saved as new
+		if updated {
+			return buff
+		}
+	}
+
+	isDuplicate, err := r.checkDuplicateURL(ctx, entry.Link, userID, organizationID)
+	if err != nil {
+		log.Error(fmt.Sprintf("Error checking duplicate URL: %v", err))
+
+		// the spool checks again when saving it back
+		if r.spool == nil {
+			return buff
+		}
+	}
+
+	if isDuplicate {
+		return buff
+	}
+
+	emails, err := r.cipher.sealAll(organizationID, entry.Emails)
+	if err != nil {
+		log.Error(fmt.Sprintf("Error encrypting emails: %v", err))
+		return buff
+	}
+
+	dirigeants, err := r.cipher.seal(organizationID, strings.Join(entry.SocieteDirigeants, ","))
+	if err != nil {
+		log.Error(fmt.Sprintf("Error encrypting directors: %v", err))
+		return buff
+	}
+
+	dbEntry := dbEntry{
+		UserID:              userID,
+		OrganizationID:      organizationID,
+		ParentID:            parentJobID,
+		Link:                entry.Link,
+		PayloadType:         payloadType,
+		Query:               query,
+		Title:               entry.Title,
+		Category:            entry.Category,
+		CategoryID:          entry.CategoryID,
+		Address:             entry.Address,
+		Website:             entry.WebSite,
+		Phones:              phoneToPhones(entry.Phone, entry.CompleteAddress.Country),
+		Emails:              emails,
+		Latitude:            entry.Latitude,
+		Longitude:           entry.Longtitude,
+		ReviewRating:        entry.ReviewRating,
+		ReviewCount:         entry.ReviewCount,
+		SocieteDirigeants:   dirigeants,
+		SocieteSiren:        entreprise.NormalizeSiren(entry.SocieteSiren),
+		SocieteForme:        entry.SocieteForme,
+		SocieteEffectif:     "",
+		SocieteCreation:     entry.SocieteCreation,
+		SocieteCloture:      entry.SocieteCloture,
+		SocieteLink:         entry.SocieteLink,
+		SocieteDiffusion:    entry.SocieteDiffusion,
+		CodeCommune:         entry.CodeCommune,
+		CodePostal:          entry.CodePostal,
+		DepartmentCode:      entry.DepartmentCode,
+		DepartmentName:      entry.DepartmentName,
+		Region:              entry.Region,
+		Provenance:          provenanceJSON(entry.Provenance),
+		PlaceID:             placeID(entry),
+		ServiceAreaBusiness: entry.ServiceAreaBusiness,
+		ServiceArea:         entry.ServiceArea,
+		ReviewStats:         reviewStatsJSON(entry.ReviewStats),
+		WhatsApp:            entry.WhatsApp,
+	}
+
+	key := userID + "|" + organizationID + "|" + entry.Link
+	if _, ok := r.inMemoryIndex[key]; ok {
+		// Duplicate within the same batch - skip silently
+		return buff
+	}
+	r.inMemoryIndex[key] = len(buff)
+
+	return append(buff, dbEntry)
+}
+
 func (r *resultWriter) batchSave(ctx context.Context, entries []dbEntry) error {
 	if len(entries) == 0 {
 		return nil
@@ -365,13 +630,18 @@ func (r *resultWriter) batchSave(ctx context.Context, entries []dbEntry) error {
 
 	stmt, err := tx.PrepareContext(ctx, `
 		INSERT INTO results (
-			parent_id, user_id, organization_id, link, payload_type,
+			parent_id, user_id, organization_id, link, payload_type, query,
 			title, category, address, website, phones, emails, latitude, longitude,
-			societe_dirigeants, societe_siren, societe_forme,
-			societe_effectif, societe_creation, societe_cloture, societe_link, societe_diffusion
+			review_rating, review_count, societe_dirigeants, societe_siren, societe_forme,
+			societe_effectif, societe_creation, societe_cloture, societe_link, societe_diffusion,
+			code_commune, code_postal, department_code, department_name, region,
+			category_id, provenance, place_id, service_area_business, service_area, review_stats, whatsapp
 		) VALUES (
 			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12,
-			$13, $14, $15, $16, $17, $18, $19, $20, $21
+			$13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24,
+			NULLIF($25, ''), NULLIF($26, ''), NULLIF($27, ''), NULLIF($28, ''), NULLIF($29, ''),
+			NULLIF($30, ''), NULLIF($31, '')::jsonb, NULLIF($32, ''), $33, NULLIF($34, ''),
+			NULLIF($35, '')::jsonb, NULLIF($36, '')
 		)`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
@@ -380,10 +650,13 @@ func (r *resultWriter) batchSave(ctx context.Context, entries []dbEntry) error {
 
 	for _, entry := range entries {
 		_, err := stmt.ExecContext(ctx,
-			entry.ParentID, entry.UserID, entry.OrganizationID, entry.Link, entry.PayloadType,
+			entry.ParentID, entry.UserID, entry.OrganizationID, entry.Link, entry.PayloadType, entry.Query,
 			entry.Title, entry.Category, entry.Address, entry.Website, entry.Phones, entry.Emails,
-			entry.Latitude, entry.Longitude, entry.SocieteDirigeants, entry.SocieteSiren, entry.SocieteForme,
+			entry.Latitude, entry.Longitude, entry.ReviewRating, entry.ReviewCount, entry.SocieteDirigeants, entry.SocieteSiren, entry.SocieteForme,
 			entry.SocieteEffectif, entry.SocieteCreation, entry.SocieteCloture, entry.SocieteLink, entry.SocieteDiffusion,
+			entry.CodeCommune, entry.CodePostal, entry.DepartmentCode, entry.DepartmentName, entry.Region,
+			entry.CategoryID, entry.Provenance, entry.PlaceID, entry.ServiceAreaBusiness, entry.ServiceArea,
+			entry.ReviewStats, entry.WhatsApp,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to insert entry: %w", err)
@@ -394,8 +667,27 @@ func (r *resultWriter) batchSave(ctx context.Context, entries []dbEntry) error {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	if r.activity != nil {
+		r.activity.Touch()
+	}
+
 	// Call revalidation API for unique user IDs
 	r.notifyRevalidation(ctx, entries)
 
 	return nil
 }
+
+// reviewStatsJSON encodes stats for the review_stats column, empty when the
+// place has none.
+func reviewStatsJSON(stats *gmaps.ReviewStats) string {
+	if stats == nil {
+		return ""
+	}
+
+	raw, err := json.Marshal(stats)
+	if err != nil {
+		return ""
+	}
+
+	return string(raw)
+}
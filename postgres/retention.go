@@ -0,0 +1,193 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gosom/scrapemate"
+)
+
+const (
+	retentionInterval = time.Hour
+	// retentionBatchSize is how many results a statement deletes, so that
+	// the locks it takes stay short.
+	retentionBatchSize = 1000
+)
+
+// purgeQuery deletes at most $2 results created before $1 matching the
+// condition of its %s verb, and counts them by organization.
+const purgeQuery = `
+	WITH purged AS (
+		DELETE FROM results WHERE id IN (
+			SELECT id FROM results WHERE %s AND created_at < $1 LIMIT $2
+		)
+		RETURNING COALESCE(organization_id, '') AS organization_id
+	)
+	SELECT organization_id, COUNT(*) FROM purged GROUP BY organization_id`
+
+// Retention is how long results are kept: Default for every organization
+// but those of Organizations, which have their own. Results are kept forever
+// when it is 0.
+type Retention struct {
+	Default       time.Duration
+	Organizations map[string]time.Duration
+}
+
+// RetentionStats counts the results a Janitor purged since startup, by
+// organization.
+type RetentionStats struct {
+	Runs          int64            `json:"runs"`
+	Purged        int64            `json:"purged"`
+	Organizations map[string]int64 `json:"organizations"`
+}
+
+// Janitor deletes the results older than the retention of their
+// organization.
+type Janitor struct {
+	db        *sql.DB
+	retention Retention
+
+	runs   atomic.Int64
+	purged atomic.Int64
+
+	mu   sync.Mutex
+	orgs map[string]int64
+}
+
+// NewJanitor creates a Janitor enforcing retention. It does nothing until
+// Run or Purge is called.
+func NewJanitor(db *sql.DB, retention Retention) *Janitor {
+	return &Janitor{db: db, retention: retention, orgs: map[string]int64{}}
+}
+
+// Run purges once an hour until ctx is done.
+func (j *Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+
+	log := scrapemate.GetLoggerFromContext(ctx)
+
+	for {
+		n, err := j.Purge(ctx)
+
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Error(fmt.Sprintf("janitor: %v", err))
+		case n > 0:
+			log.Info(fmt.Sprintf("janitor: purged %d results past their retention", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge deletes the results past the retention of their organization, a
+// batch at a time, and returns how many it deleted.
+func (j *Janitor) Purge(ctx context.Context) (int64, error) {
+	j.runs.Add(1)
+
+	now := time.Now().UTC()
+
+	var total int64
+
+	for org, d := range j.retention.Organizations {
+		if d <= 0 {
+			continue
+		}
+
+		n, err := j.purge(ctx, "organization_id = $3", now.Add(-d), org)
+
+		total += n
+
+		if err != nil {
+			return total, fmt.Errorf("purge %s: %w", org, err)
+		}
+	}
+
+	if j.retention.Default > 0 {
+		// the organizations with a retention of their own, 0 included, are
+		// left to it
+		own, err := json.Marshal(j.retention.Organizations)
+		if err != nil {
+			return total, err
+		}
+
+		n, err := j.purge(ctx, "NOT ($3::jsonb ? COALESCE(organization_id, ''))", now.Add(-j.retention.Default), string(own))
+
+		total += n
+
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+// Stats returns the results purged since startup.
+func (j *Janitor) Stats() RetentionStats {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return RetentionStats{
+		Runs:          j.runs.Load(),
+		Purged:        j.purged.Load(),
+		Organizations: maps.Clone(j.orgs),
+	}
+}
+
+func (j *Janitor) purge(ctx context.Context, cond string, cutoff time.Time, arg any) (int64, error) {
+	q := fmt.Sprintf(purgeQuery, cond)
+
+	var total int64
+
+	for {
+		n, err := j.purgeBatch(ctx, q, cutoff, arg)
+		total += n
+
+		if err != nil || n == 0 {
+			return total, err
+		}
+	}
+}
+
+func (j *Janitor) purgeBatch(ctx context.Context, q string, cutoff time.Time, arg any) (int64, error) {
+	rows, err := j.db.QueryContext(ctx, q, cutoff, retentionBatchSize, arg)
+	if err != nil {
+		return 0, err
+	}
+
+	defer rows.Close()
+
+	var total int64
+
+	for rows.Next() {
+		var (
+			org string
+			n   int64
+		)
+
+		if err := rows.Scan(&org, &n); err != nil {
+			return total, err
+		}
+
+		total += n
+		j.purged.Add(n)
+
+		j.mu.Lock()
+		j.orgs[org] += n
+		j.mu.Unlock()
+	}
+
+	return total, rows.Err()
+}
@@ -0,0 +1,468 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/apiauth"
+	"github.com/gosom/google-maps-scraper/entreprise"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/httpjson"
+)
+
+// SourceReview is the provenance source of the SIRENs an operator approved
+// in the review queue.
+const SourceReview = "review"
+
+var (
+	// ErrReviewItemNotFound is returned when a review item id does not exist.
+	ErrReviewItemNotFound = errors.New("review item not found")
+	// ErrAlreadyReviewed is returned when approving or rejecting an item
+	// that is no longer pending.
+	ErrAlreadyReviewed = errors.New("review item already reviewed")
+)
+
+// Review statuses.
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+// ReviewItem is a company match waiting for an operator, or decided by one.
+type ReviewItem struct {
+	ID             int64  `json:"id"`
+	ResultLink     string `json:"result_link"`
+	OwnerID        string `json:"owner_id"`
+	OrganizationID string `json:"organization_id"`
+	Title          string `json:"title"`
+	Address        string `json:"address"`
+	// Outcome is matched when the lookup kept Candidate, no_match when it
+	// rejected it.
+	Outcome    string           `json:"outcome"`
+	Candidate  CompanyCandidate `json:"candidate"`
+	Status     string           `json:"status"`
+	CreatedAt  time.Time        `json:"created_at"`
+	ReviewedAt *time.Time       `json:"reviewed_at,omitempty"`
+}
+
+// ReviewFilter selects the items List returns. An empty Status selects the
+// pending ones.
+type ReviewFilter struct {
+	Status         string
+	OwnerID        string
+	OrganizationID string
+	Limit          int
+	Offset         int
+}
+
+// ReviewQueue keeps the company matches whose score is too close to the
+// threshold of the registers to be trusted either way, kept or rejected, so
+// that an operator confirms them. Approved SIRENs are written to the
+// results, rejected ones removed from them.
+type ReviewQueue struct {
+	db       *sql.DB
+	minScore float64
+	maxScore float64
+	cipher   *FieldCipher
+}
+
+// NewReviewQueue creates a ReviewQueue of the matches scoring between
+// minScore and maxScore included. Nothing is queued when maxScore is 0, but
+// the queued items can still be reviewed. The names of the candidates are
+// encrypted with cipher.
+func NewReviewQueue(db *sql.DB, minScore, maxScore float64, cipher *FieldCipher) *ReviewQueue {
+	return &ReviewQueue{
+		db:       db,
+		minScore: minScore,
+		maxScore: maxScore,
+		cipher:   cipher,
+	}
+}
+
+// WithReviewQueue makes company jobs queue their doubtful matches in q.
+func WithReviewQueue(q *ReviewQueue) ProviderOption {
+	return func(p *provider) {
+		p.review = q
+	}
+}
+
+// Enqueue queues the company result kept or rejected for the business title
+// at address when its score is within the bounds of q. A company already
+// queued for the same result is not queued again, decided or not.
+func (q *ReviewQueue) Enqueue(ctx context.Context, title, address string, result *gmaps.CompanyEnrichmentResult) error {
+	candidate, ok := reviewCandidate(result)
+	if !ok || q.maxScore <= 0 || candidate.Score < q.minScore || candidate.Score > q.maxScore {
+		return nil
+	}
+
+	// the name of a sole proprietorship is the name of its owner
+	name, err := q.cipher.seal(result.OrganizationID, candidate.Name)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt match for review: %w", err)
+	}
+
+	const stmt = `INSERT INTO review_queue
+		(result_link, user_id, organization_id, title, address, outcome, siren, name, source, score)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (result_link, user_id, organization_id, siren) DO NOTHING`
+
+	_, err = q.db.ExecContext(ctx, stmt,
+		result.PlaceLink, result.OwnerID, result.OrganizationID, title, address, result.Outcome,
+		candidate.Siren, name, candidate.Source, candidate.Score,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to queue match for review: %w", err)
+	}
+
+	return nil
+}
+
+// queueReview queues the company match of the wrapped company job for review
+// when its score calls for it.
+func (w *jobWrapper) queueReview(result *gmaps.CompanyEnrichmentResult) {
+	job, ok := w.IJob.(*gmaps.CompanyJob)
+	if !ok || w.provider.review == nil {
+		return
+	}
+
+	w.provider.goBackground(func() {
+		if err := w.provider.review.Enqueue(context.Background(), job.CompanyName, job.Address, result); err != nil {
+			log := scrapemate.GetLoggerFromContext(context.Background())
+			log.Error(fmt.Sprintf("jobWrapper.queueReview: %v", err))
+		}
+	})
+}
+
+// reviewCandidate returns the company result kept, or rejected on a
+// no_match, false when there is none to review.
+func reviewCandidate(result *gmaps.CompanyEnrichmentResult) (CompanyCandidate, bool) {
+	var ans CompanyCandidate
+
+	switch {
+	case result.Existing:
+		return ans, false
+	case result.Outcome == gmaps.MatchOutcomeMatched:
+		ans = CompanyCandidate{
+			Siren:  entreprise.NormalizeSiren(result.SocieteSiren),
+			Name:   result.SocieteNom,
+			Source: result.Source,
+			Score:  result.MatchScore,
+		}
+	case result.Outcome == gmaps.MatchOutcomeNoMatch && result.Rejected != nil:
+		ans = CompanyCandidate{
+			Siren:  entreprise.NormalizeSiren(result.Rejected.SocieteSiren),
+			Name:   result.Rejected.SocieteNom,
+			Source: result.Rejected.Source,
+			Score:  result.Rejected.MatchScore,
+		}
+	}
+
+	return ans, ans.Siren != ""
+}
+
+const reviewColumns = `id, result_link, user_id, organization_id, COALESCE(title, ''), COALESCE(address, ''),
+	outcome, siren, COALESCE(name, ''), COALESCE(source, ''), score, status, created_at, reviewed_at`
+
+// List returns the items selected by f, oldest first.
+func (q *ReviewQueue) List(ctx context.Context, f ReviewFilter) ([]ReviewItem, error) {
+	const (
+		defaultLimit = 50
+		maxLimit     = 500
+	)
+
+	status := f.Status
+	if status == "" {
+		status = ReviewPending
+	}
+
+	limit := f.Limit
+	if limit <= 0 || limit > maxLimit {
+		limit = defaultLimit
+	}
+
+	query := `SELECT ` + reviewColumns + ` FROM review_queue
+		WHERE status = $1
+		AND ($2 = '' OR user_id = $2)
+		AND ($3 = '' OR organization_id = $3)
+		ORDER BY id
+		LIMIT $4 OFFSET $5`
+
+	rows, err := q.db.QueryContext(ctx, query, status, f.OwnerID, f.OrganizationID, limit, max(f.Offset, 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list review items: %w", err)
+	}
+	defer rows.Close()
+
+	ans := []ReviewItem{}
+
+	for rows.Next() {
+		item, err := q.scanReviewItem(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		ans = append(ans, item)
+	}
+
+	return ans, rows.Err()
+}
+
+func (q *ReviewQueue) scanReviewItem(rows interface{ Scan(...any) error }) (ReviewItem, error) {
+	var (
+		item       ReviewItem
+		reviewedAt sql.NullTime
+	)
+
+	err := rows.Scan(&item.ID, &item.ResultLink, &item.OwnerID, &item.OrganizationID, &item.Title, &item.Address,
+		&item.Outcome, &item.Candidate.Siren, &item.Candidate.Name, &item.Candidate.Source, &item.Candidate.Score,
+		&item.Status, &item.CreatedAt, &reviewedAt)
+	if err != nil {
+		return item, fmt.Errorf("failed to scan review item: %w", err)
+	}
+
+	item.Candidate.Name, err = q.cipher.open(item.Candidate.Name)
+	if err != nil {
+		return item, fmt.Errorf("failed to read review item %d: %w", item.ID, err)
+	}
+
+	if reviewedAt.Valid {
+		item.ReviewedAt = &reviewedAt.Time
+	}
+
+	return item, nil
+}
+
+// companyColumns are the result columns describing its company, cleared
+// when its SIREN changes in a review.
+var companyColumns = []string{
+	"societe_dirigeants", "societe_forme", "societe_creation", "societe_cloture",
+	"societe_link", "societe_diffusion", "siren_resolution", "naf_code",
+}
+
+// Approve confirms the SIREN of item id: it becomes the SIREN of the result,
+// whose other company columns are cleared when it held another one, so that
+// -reenrich fills them again. Only the items of organizationID are found,
+// unless it is empty.
+func (q *ReviewQueue) Approve(ctx context.Context, id int64, organizationID string) (ReviewItem, error) {
+	return q.decide(ctx, id, organizationID, ReviewApproved, func(tx *sql.Tx, item ReviewItem) error {
+		// $1 is the link, then the owner and organization, see resultOwnerCond
+		cond, args := resultOwnerCond(item)
+		siren := len(args) + 1
+		source := len(args) + 2
+
+		changed := fmt.Sprintf("COALESCE(societe_siren, '') NOT IN ('', $%d)", siren)
+
+		sets := ""
+		for _, column := range companyColumns {
+			sets += fmt.Sprintf("%s = CASE WHEN %s THEN NULL ELSE %s END,\n", column, changed, column)
+		}
+
+		stmt := fmt.Sprintf(`UPDATE results SET
+			%s
+			societe_siren = $%d,
+			company_match = 'matched',
+			company_candidate = NULL,
+			provenance = (COALESCE(provenance, '{}'::jsonb) - CASE WHEN %s THEN $%d::text[] ELSE '{}'::text[] END)
+				|| jsonb_build_object('societe_siren', $%d::jsonb),
+			updated_at = NOW()
+			WHERE %s`,
+			sets, siren, changed, source+1, source, cond)
+
+		args = append(args, item.Candidate.Siren, fieldSource(SourceReview, time.Now().UTC()), companyColumns)
+
+		_, err := tx.ExecContext(ctx, stmt, args...)
+
+		return err
+	})
+}
+
+// Reject records that the SIREN of item id is not the company of the
+// result: a result holding it loses its company columns and is marked
+// no_match, with the SIREN as its rejected candidate. Only the items of
+// organizationID are found, unless it is empty.
+func (q *ReviewQueue) Reject(ctx context.Context, id int64, organizationID string) (ReviewItem, error) {
+	return q.decide(ctx, id, organizationID, ReviewRejected, func(tx *sql.Tx, item ReviewItem) error {
+		cond, args := resultOwnerCond(item)
+		siren := len(args) + 1
+
+		sets := ""
+		for _, column := range companyColumns {
+			sets += column + " = NULL,\n"
+		}
+
+		var err error
+
+		stored := item.Candidate
+
+		stored.Name, err = q.cipher.seal(item.OrganizationID, stored.Name)
+		if err != nil {
+			return err
+		}
+
+		candidate, err := json.Marshal(stored)
+		if err != nil {
+			return err
+		}
+
+		stmt := fmt.Sprintf(`UPDATE results SET
+			%s
+			societe_siren = NULL,
+			company_match = 'no_match',
+			company_candidate = $%d::jsonb,
+			provenance = COALESCE(provenance, '{}'::jsonb) - $%d::text[],
+			updated_at = NOW()
+			WHERE %s AND societe_siren = $%d`,
+			sets, siren+1, siren+2, cond, siren)
+
+		args = append(args, item.Candidate.Siren, string(candidate), append([]string{"societe_siren"}, companyColumns...))
+
+		_, err = tx.ExecContext(ctx, stmt, args...)
+
+		return err
+	})
+}
+
+// decide sets the status of the pending item id of organizationID and
+// applies it to the results with apply, in one transaction.
+func (q *ReviewQueue) decide(ctx context.Context, id int64, organizationID, status string, apply func(*sql.Tx, ReviewItem) error) (ReviewItem, error) {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ReviewItem{}, fmt.Errorf("failed to begin review: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	row := tx.QueryRowContext(ctx, `UPDATE review_queue SET status = $2, reviewed_at = NOW()
+		WHERE id = $1 AND status = 'pending' AND ($3 = '' OR organization_id = $3)
+		RETURNING `+reviewColumns, id, status, organizationID)
+
+	item, err := q.scanReviewItem(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM review_queue WHERE id = $1 AND ($2 = '' OR organization_id = $2))`,
+			id, organizationID).Scan(&exists); err != nil {
+			return ReviewItem{}, fmt.Errorf("failed to read review item: %w", err)
+		}
+
+		if exists {
+			return ReviewItem{}, ErrAlreadyReviewed
+		}
+
+		return ReviewItem{}, ErrReviewItemNotFound
+	}
+
+	if err != nil {
+		return ReviewItem{}, err
+	}
+
+	if err := apply(tx, item); err != nil {
+		return ReviewItem{}, fmt.Errorf("failed to update the result of review item %d: %w", id, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ReviewItem{}, fmt.Errorf("failed to commit review: %w", err)
+	}
+
+	return item, nil
+}
+
+// resultOwnerCond returns the condition selecting the results of item, the
+// link as $1, and its arguments.
+func resultOwnerCond(item ReviewItem) (string, []any) {
+	switch {
+	case item.OwnerID != "" && item.OrganizationID != "":
+		return "link = $1 AND (user_id = $2 OR organization_id = $3)", []any{item.ResultLink, item.OwnerID, item.OrganizationID}
+	case item.OwnerID != "":
+		return "link = $1 AND user_id = $2", []any{item.ResultLink, item.OwnerID}
+	default:
+		return "link = $1 AND organization_id = $2", []any{item.ResultLink, item.OrganizationID}
+	}
+}
+
+// ReviewHandler lets operators work through the review queue over HTTP:
+//
+//	GET  /review                 list the items: ?status=pending&owner_id=&organization_id=&limit=50&offset=0
+//	POST /review/{id}/approve    write the SIREN of the item to its result
+//	POST /review/{id}/reject     remove the SIREN of the item from its result
+func ReviewHandler(q *ReviewQueue) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /review", func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+
+		organization, err := apiauth.RestrictOrganization(r.Context(), params.Get("organization_id"))
+		if err != nil {
+			httpjson.Write(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+			return
+		}
+
+		f := ReviewFilter{
+			Status:         params.Get("status"),
+			OwnerID:        params.Get("owner_id"),
+			OrganizationID: organization,
+		}
+
+		switch f.Status {
+		case "", ReviewPending, ReviewApproved, ReviewRejected:
+		default:
+			httpjson.Write(w, http.StatusBadRequest, map[string]string{"error": "invalid status " + strconv.Quote(f.Status)})
+			return
+		}
+
+		for name, dst := range map[string]*int{"limit": &f.Limit, "offset": &f.Offset} {
+			if v := params.Get(name); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 0 {
+					httpjson.Write(w, http.StatusBadRequest, map[string]string{"error": "invalid " + name})
+					return
+				}
+
+				*dst = n
+			}
+		}
+
+		items, err := q.List(r.Context(), f)
+		if err != nil {
+			httpjson.Write(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+
+		httpjson.Write(w, http.StatusOK, items)
+	})
+
+	decide := func(fn func(context.Context, int64, string) (ReviewItem, error)) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+			if err != nil {
+				httpjson.Write(w, http.StatusBadRequest, map[string]string{"error": "invalid review item id"})
+				return
+			}
+
+			item, err := fn(r.Context(), id, apiauth.GetOrganizationFromContext(r.Context()))
+
+			switch {
+			case errors.Is(err, ErrReviewItemNotFound):
+				httpjson.Write(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			case errors.Is(err, ErrAlreadyReviewed):
+				httpjson.Write(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			case err != nil:
+				httpjson.Write(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			default:
+				httpjson.Write(w, http.StatusOK, item)
+			}
+		}
+	}
+
+	mux.HandleFunc("POST /review/{id}/approve", decide(q.Approve))
+	mux.HandleFunc("POST /review/{id}/reject", decide(q.Reject))
+
+	return mux
+}
@@ -0,0 +1,184 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gosom/scrapemate"
+	"github.com/playwright-community/playwright-go"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// searchBatchJob is a seed job holding several queries that share the same
+// metadata. It loads nothing: processing it yields one search job per
+// query, which the wrapper pushes as its children, so the batch is the root
+// of the whole campaign and completes when every query has been scraped.
+type searchBatchJob struct {
+	scrapemate.Job
+	Metadata map[string]interface{}
+}
+
+func (j *searchBatchJob) Process(_ context.Context, resp *scrapemate.Response) (any, []scrapemate.IJob, error) {
+	defer func() {
+		resp.Document = nil
+		resp.Body = nil
+		resp.Meta = nil
+	}()
+
+	jobs, err := j.searches()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(jobs) == 0 {
+		return nil, nil, fmt.Errorf("search batch %s has no queries", j.ID)
+	}
+
+	return nil, jobs, nil
+}
+
+func (j *searchBatchJob) UseInResults() bool {
+	return false
+}
+
+func (j *searchBatchJob) BrowserActions(ctx context.Context, _ playwright.Page) scrapemate.Response {
+	return j.FetchWithoutBrowser(ctx)
+}
+
+// FetchWithoutBrowser returns the response the batch is expanded on.
+func (j *searchBatchJob) FetchWithoutBrowser(context.Context) scrapemate.Response {
+	var resp scrapemate.Response
+	resp.URL = j.URL
+	resp.StatusCode = 200
+
+	return resp
+}
+
+// SearchBatchJobCodec handles the encoding/decoding of search batches. The
+// metadata is kept as is and only read when the batch is processed, so a
+// batch with invalid settings fails alone.
+type SearchBatchJobCodec struct{}
+
+func (c *SearchBatchJobCodec) JobType() string { return jobTypeSearchBatch }
+
+func (c *SearchBatchJobCodec) Encode(job scrapemate.IJob) (*JSONJob, error) {
+	j, ok := job.(*searchBatchJob)
+	if !ok {
+		return nil, fmt.Errorf("expected *searchBatchJob, got %T", job)
+	}
+
+	jsonJob := &JSONJob{
+		ID:         j.GetID(),
+		Priority:   j.GetPriority(),
+		URL:        j.GetURL(),
+		URLParams:  j.GetURLParams(),
+		MaxRetries: j.GetMaxRetries(),
+		JobType:    jobTypeSearchBatch,
+		Metadata:   j.Metadata,
+	}
+
+	if j.ParentID != "" {
+		jsonJob.ParentID = &j.ParentID
+	}
+
+	return jsonJob, nil
+}
+
+func (c *SearchBatchJobCodec) Decode(jsonJob *JSONJob) (scrapemate.IJob, error) {
+	var parentID string
+	if jsonJob.ParentID != nil {
+		parentID = *jsonJob.ParentID
+	}
+
+	return &searchBatchJob{
+		Job: scrapemate.Job{
+			ID:         jsonJob.ID,
+			ParentID:   parentID,
+			URL:        jsonJob.URL,
+			URLParams:  jsonJob.URLParams,
+			MaxRetries: jsonJob.MaxRetries,
+			Priority:   jsonJob.Priority,
+		},
+		Metadata: jsonJob.Metadata,
+	}, nil
+}
+
+// searches returns the search jobs of the batch, one per query.
+func (j *searchBatchJob) searches() ([]scrapemate.IJob, error) {
+	md := j.Metadata
+
+	rawQueries, ok := md["queries"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("queries is missing or not a list")
+	}
+
+	maxDepth, err := getIntFromMetadata(md, "max_depth")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get max_depth: %w", err)
+	}
+
+	langCode, ok := md["lang_code"].(string)
+	if !ok {
+		return nil, fmt.Errorf("lang_code is missing or not a string")
+	}
+
+	extractEmail, _ := md["extract_email"].(bool)
+	extractBodacc, _ := md["extract_bodacc"].(bool)
+
+	ownerID, ok := md["owner_id"].(string)
+	if !ok {
+		return nil, fmt.Errorf("owner_id is missing or not a string")
+	}
+
+	organizationID, _ := md["organization_id"].(string)
+	country, _ := md["country"].(string)
+	geo, _ := md["geo"].(string)
+
+	domain, _ := md["domain"].(string)
+	if _, err := gmaps.ParseDomain(domain); err != nil {
+		return nil, err
+	}
+
+	// batches without a limit, relaxation or tracked business leave them out
+	maxResults, _ := getIntFromMetadata(md, "max_results")
+	relaxEmpty, _ := md["relax_empty"].(bool)
+	track, _ := md["track"].(string)
+
+	var zoom int
+	if geo != "" {
+		if zoom, err = getIntFromMetadata(md, "zoom"); err != nil {
+			return nil, fmt.Errorf("failed to get zoom: %w", err)
+		}
+	}
+
+	jobs := make([]scrapemate.IJob, 0, len(rawQueries))
+
+	for _, rq := range rawQueries {
+		query, _ := rq.(string)
+		if query = strings.TrimSpace(query); query == "" {
+			continue
+		}
+
+		job := gmaps.NewGmapJob("", langCode, query, ownerID, organizationID, maxDepth,
+			extractEmail, extractBodacc, geo, zoom, gmaps.WithCountry(country), gmaps.WithDomain(domain),
+			gmaps.WithMaxResults(maxResults), gmaps.WithTrack(track))
+
+		if relaxEmpty {
+			gmaps.WithRelaxEmpty()(job)
+		}
+
+		if j.Priority != 0 {
+			job.Priority = j.Priority
+		}
+
+		if j.MaxRetries != 0 {
+			job.MaxRetries = j.MaxRetries
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
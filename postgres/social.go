@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// WithSocialProfiles makes the provider fetch the public data of the
+// Facebook and Instagram pages of places, linked from their website or being
+// it. The jobs doing so are dropped otherwise.
+func WithSocialProfiles() ProviderOption {
+	return func(p *provider) {
+		p.socialProfiles = true
+	}
+}
+
+// filterSocial drops the social profile jobs of jobs unless the provider
+// fetches social profiles.
+func (p *provider) filterSocial(jobs []scrapemate.IJob) []scrapemate.IJob {
+	if p.socialProfiles {
+		return jobs
+	}
+
+	ans := jobs[:0]
+
+	for _, job := range jobs {
+		if _, ok := job.(*gmaps.SocialJob); !ok {
+			ans = append(ans, job)
+		}
+	}
+
+	return ans
+}
+
+// updateResultSocialProfile stores the social page of a result under its
+// network, replacing the one fetched before.
+func (p *provider) updateResultSocialProfile(ctx context.Context, result *gmaps.SocialEnrichmentResult) {
+	if result.Profile == nil {
+		return
+	}
+
+	raw, err := json.Marshal(result.Profile)
+	if err != nil {
+		return
+	}
+
+	q := `UPDATE results SET
+			social_profiles = COALESCE(social_profiles, '{}'::jsonb) || jsonb_build_object($3::text, $4::jsonb),
+			updated_at = NOW()
+		WHERE link = $5 AND ` + ownerCond("user_id", "organization_id")
+
+	_, err = p.db.ExecContext(ctx, q, result.OwnerID, result.OrganizationID, result.Profile.Network, string(raw), result.PlaceLink)
+	if err != nil {
+		log := scrapemate.GetLoggerFromContext(ctx)
+		log.Error(fmt.Sprintf("updateResultSocialProfile: failed to update: %v", err))
+	}
+}
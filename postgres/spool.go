@@ -0,0 +1,318 @@
+package postgres
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gosom/scrapemate"
+)
+
+const (
+	// saveAttempts is how many times the result writer tries to save a batch,
+	// waiting saveBackoff, then twice as long, between them, before it
+	// spools it.
+	saveAttempts = 3
+	saveBackoff  = time.Second
+	// spoolExt is the extension of the spooled batches; those that cannot be
+	// read or saved are renamed with badSpoolExt and left aside.
+	spoolExt    = ".jsonl"
+	badSpoolExt = ".bad"
+)
+
+// SpoolStats is the state of a Spool.
+type SpoolStats struct {
+	// Pending counts the batches waiting on disk.
+	Pending int64 `json:"pending_batches"`
+	// Spooled and Replayed count the results written to disk and those
+	// saved from it since startup.
+	Spooled  int64 `json:"spooled"`
+	Replayed int64 `json:"replayed"`
+}
+
+// Spool keeps on disk, one JSONL file per batch, the results the result
+// writer could not save, so that an outage of the database neither loses
+// them nor stops the worker. The writer saves them back, the oldest first,
+// once the database answers again, including the batches left by a previous
+// run.
+type Spool struct {
+	dir string
+
+	mu  sync.Mutex
+	seq int64
+
+	pending  atomic.Int64
+	spooled  atomic.Int64
+	replayed atomic.Int64
+}
+
+// NewSpool creates a Spool in dir, creating the directory when needed.
+func NewSpool(dir string) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	s := &Spool{dir: dir}
+
+	paths, err := s.batches()
+	if err != nil {
+		return nil, err
+	}
+
+	s.pending.Store(int64(len(paths)))
+
+	return s, nil
+}
+
+// WithWriterSpool makes the result writer spool the batches it fails to save
+// to s and save them back when the database recovers.
+func WithWriterSpool(s *Spool) ResultWriterOption {
+	return func(r *resultWriter) {
+		r.spool = s
+	}
+}
+
+// Stats returns the state of the spool.
+func (s *Spool) Stats() SpoolStats {
+	return SpoolStats{
+		Pending:  s.pending.Load(),
+		Spooled:  s.spooled.Load(),
+		Replayed: s.replayed.Load(),
+	}
+}
+
+// write stores entries as a new batch. The file only gets its name once
+// complete, so that a crash never leaves half a batch to replay.
+func (s *Spool) write(entries []dbEntry) error {
+	s.mu.Lock()
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), s.seq, spoolExt)
+	s.mu.Unlock()
+
+	path := filepath.Join(s.dir, name)
+	tmp := path + ".tmp"
+
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to spool results: %w", err)
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+
+	for i := range entries {
+		if err := enc.Encode(&entries[i]); err != nil {
+			f.Close()
+			os.Remove(tmp)
+
+			return fmt.Errorf("failed to spool results: %w", err)
+		}
+	}
+
+	if err := w.Flush(); err == nil {
+		err = f.Sync()
+	}
+
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to spool results: %w", err)
+	}
+
+	s.pending.Add(1)
+	s.spooled.Add(int64(len(entries)))
+
+	return nil
+}
+
+// batches returns the paths of the spooled batches, the oldest first.
+func (s *Spool) batches() ([]string, error) {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list spooled results: %w", err)
+	}
+
+	var ans []string
+
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), spoolExt) {
+			ans = append(ans, filepath.Join(s.dir, f.Name()))
+		}
+	}
+
+	sort.Strings(ans)
+
+	return ans, nil
+}
+
+// read returns the entries of the batch at path.
+func (s *Spool) read(path string) ([]dbEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ans []dbEntry
+
+	dec := json.NewDecoder(f)
+
+	for dec.More() {
+		var entry dbEntry
+		if err := dec.Decode(&entry); err != nil {
+			return nil, err
+		}
+
+		ans = append(ans, entry)
+	}
+
+	return ans, nil
+}
+
+// done removes the batch at path, saved with n results.
+func (s *Spool) done(path string, n int) error {
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+
+	s.pending.Add(-1)
+	s.replayed.Add(int64(n))
+
+	return nil
+}
+
+// discard sets aside the batch at path, which cannot be read or saved.
+func (s *Spool) discard(path string) error {
+	if err := os.Rename(path, path+badSpoolExt); err != nil {
+		return err
+	}
+
+	s.pending.Add(-1)
+
+	return nil
+}
+
+// save saves entries, spooling them when the database fails to take them
+// saveAttempts times. Once batches are spooled the next ones follow them
+// until the spooled ones could be saved back.
+func (r *resultWriter) save(ctx context.Context, entries []dbEntry) error {
+	if r.spool == nil {
+		return r.batchSave(ctx, entries)
+	}
+
+	if r.spool.pending.Load() > 0 {
+		if err := r.replay(ctx); err != nil {
+			return r.spoolBatch(ctx, entries, err)
+		}
+	}
+
+	backoff := saveBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := r.batchSave(ctx, entries)
+		if err == nil {
+			return nil
+		}
+
+		if attempt == saveAttempts {
+			return r.spoolBatch(ctx, entries, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return r.spoolBatch(ctx, entries, err)
+		case <-time.After(backoff):
+			backoff *= 2
+		}
+	}
+}
+
+// spoolBatch writes entries to the spool, which failed to be saved with
+// cause. It only fails when the spool does.
+func (r *resultWriter) spoolBatch(ctx context.Context, entries []dbEntry, cause error) error {
+	if err := r.spool.write(entries); err != nil {
+		return fmt.Errorf("%w (saving the results failed: %v)", err, cause)
+	}
+
+	log := scrapemate.GetLoggerFromContext(ctx)
+	log.Info(fmt.Sprintf("spooled %d results to disk, the database failed: %v", len(entries), cause))
+
+	return nil
+}
+
+// replay saves the spooled batches back, the oldest first, and stops at the
+// first the database fails to take while it is down. The entries are checked for duplicates
+// again: those spooled when the check itself failed were not.
+func (r *resultWriter) replay(ctx context.Context) error {
+	log := scrapemate.GetLoggerFromContext(ctx)
+
+	paths, err := r.spool.batches()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		entries, err := r.spool.read(path)
+		if err != nil {
+			log.Error(fmt.Sprintf("cannot read spooled results %s, setting them aside: %v", path, err))
+
+			if err := r.spool.discard(path); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		fresh := entries[:0]
+
+		for _, entry := range entries {
+			dup, err := r.checkDuplicateURL(ctx, entry.Link, entry.UserID, entry.OrganizationID)
+			if err != nil {
+				return err
+			}
+
+			if !dup {
+				fresh = append(fresh, entry)
+			}
+		}
+
+		if err := r.batchSave(ctx, fresh); err != nil {
+			// a database that answers refuses the batch itself, which
+			// must not hold the next ones
+			if r.db.PingContext(ctx) != nil {
+				return err
+			}
+
+			log.Error(fmt.Sprintf("database refused spooled results %s, setting them aside: %v", path, err))
+
+			if err := r.spool.discard(path); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := r.spool.done(path, len(fresh)); err != nil {
+			return err
+		}
+
+		log.Info(fmt.Sprintf("saved %d spooled results back to the database", len(fresh)))
+	}
+
+	return nil
+}
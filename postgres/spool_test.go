@@ -0,0 +1,99 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gosom/scrapemate"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+func Test_NewSpoolPendingBatches(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "spool")
+
+	s, err := postgres.NewSpool(dir)
+	require.NoError(t, err)
+	require.Equal(t, postgres.SpoolStats{}, s.Stats())
+
+	// batches left by a previous run, and files that are none
+	for _, name := range []string{"1-000001.jsonl", "2-000002.jsonl", "3-000003.jsonl.tmp", "0-000001.jsonl.bad"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("{}\n"), 0o600))
+	}
+
+	s, err = postgres.NewSpool(dir)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), s.Stats().Pending)
+}
+
+func Test_ResultWriterReplaysSpool(t *testing.T) {
+	fake, db := newFakeDB(t)
+
+	// the owner already has the place of one spooled result
+	fake.on("SELECT COUNT(*) FROM results", func(args []driver.Value) answer {
+		var n int64
+		if args[0] == "https://www.google.com/maps/place/dup" {
+			n = 1
+		}
+
+		return answer{columns: []string{"count"}, rows: [][]driver.Value{{n}}}
+	})
+
+	dir := t.TempDir()
+
+	// batches left by a previous run, one of them unreadable
+	batches := map[string]string{
+		"1-000001.jsonl": `{"UserID": "owner-1", "Link": "https://www.google.com/maps/place/a", "Title": "a"}` + "\n" +
+			`{"UserID": "owner-1", "Link": "https://www.google.com/maps/place/dup", "Title": "dup"}` + "\n",
+		"2-000002.jsonl": `{"UserID": "owner-1", "Link": "https://www.google.com/maps/place/b", "Title": "b"}` + "\n",
+		"3-000003.jsonl": "not json\n",
+	}
+
+	for name, content := range batches {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+
+	s, err := postgres.NewSpool(dir)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), s.Stats().Pending)
+
+	w := postgres.NewResultWriter(db, "", postgres.WithWriterSpool(s))
+
+	in := make(chan scrapemate.Result, 1)
+
+	link := "https://www.google.com/maps/place/c"
+	in <- scrapemate.Result{
+		Job:  gmaps.NewPlaceJob("", "en", link, "owner-1", "", false, false),
+		Data: &gmaps.Entry{Link: link, Title: "c"},
+	}
+
+	close(in)
+
+	require.NoError(t, w.Run(context.Background(), in))
+
+	// the spooled results are saved first, the oldest first, without the
+	// one the owner already has
+	var saved []driver.Value
+	for _, args := range fake.ran("INSERT INTO results") {
+		saved = append(saved, args[3])
+	}
+
+	require.Equal(t, []driver.Value{
+		"https://www.google.com/maps/place/a",
+		"https://www.google.com/maps/place/b",
+		link,
+	}, saved)
+
+	// the batches saved are removed, the unreadable one is set aside
+	require.Equal(t, postgres.SpoolStats{Replayed: 2}, s.Stats())
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, "3-000003.jsonl.bad", files[0].Name())
+}
@@ -0,0 +1,305 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gosom/scrapemate"
+)
+
+const (
+	// summaryTimeout bounds computing and storing the summary of a root job.
+	summaryTimeout = time.Minute
+	// summaryWait bounds how long a root job waits for its enrichment jobs,
+	// which one stuck queued on a dead worker would make endless.
+	summaryWait = time.Hour
+)
+
+// ResultsSummary describes the results of a root job. It is stored in the
+// summary column of the job and sent with the job completion webhook.
+type ResultsSummary struct {
+	Places         int     `json:"places"`
+	WithWebsitePct float64 `json:"withWebsitePct"`
+	WithEmailPct   float64 `json:"withEmailPct"`
+	WithSirenPct   float64 `json:"withSirenPct"`
+	// AverageRating leaves out the places without reviews.
+	AverageRating float64 `json:"averageRating"`
+	// PlacesFound counts the places the searches collected, duplicates of
+	// places the owner already had included.
+	PlacesFound int `json:"placesFound"`
+	// MaxResults is the number of places each search stopped at, when the
+	// job set one.
+	MaxResults int `json:"maxResults,omitempty"`
+	// FailedWebhooks counts the revalidation calls of the owner dropped
+	// while the job ran, and the job completion call when it was dropped
+	// too.
+	FailedWebhooks int `json:"failedWebhooks,omitempty"`
+}
+
+// SummarizeResults computes the summary of the results of the root job id.
+func SummarizeResults(ctx context.Context, db *sql.DB, id string) (ResultsSummary, error) {
+	const q = `SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE COALESCE(website, '') <> ''),
+			COUNT(*) FILTER (WHERE COALESCE(CARDINALITY(emails), 0) > 0),
+			COUNT(*) FILTER (WHERE COALESCE(societe_siren, '') <> ''),
+			COALESCE(AVG(review_rating) FILTER (WHERE review_rating > 0), 0)
+		FROM results WHERE parent_id = $1`
+
+	var (
+		s                                 ResultsSummary
+		withWebsite, withEmail, withSiren int
+	)
+
+	err := db.QueryRowContext(ctx, q, id).Scan(&s.Places, &withWebsite, &withEmail, &withSiren, &s.AverageRating)
+	if err != nil {
+		return s, fmt.Errorf("failed to summarize results: %w", err)
+	}
+
+	s.WithWebsitePct = percent(withWebsite, s.Places)
+	s.WithEmailPct = percent(withEmail, s.Places)
+	s.WithSirenPct = percent(withSiren, s.Places)
+	s.AverageRating = math.Round(s.AverageRating*100) / 100
+
+	// place jobs are children of the root search or of the searches of a
+	// root search batch
+	const found = `SELECT
+			(SELECT COUNT(*) FROM gmaps_jobs
+				WHERE payload_type = $2
+				AND (parent_id = $1 OR parent_id IN (SELECT id FROM gmaps_jobs WHERE parent_id = $1))),
+			COALESCE((SELECT (payload::jsonb -> 'metadata' ->> 'max_results')::int FROM gmaps_jobs WHERE id = $1), 0)`
+
+	if err := db.QueryRowContext(ctx, found, id, jobTypePlace).Scan(&s.PlacesFound, &s.MaxResults); err != nil {
+		return s, fmt.Errorf("failed to count places found: %w", err)
+	}
+
+	return s, nil
+}
+
+// StoreResultsSummary computes the summary of the root job id and stores it
+// on the job.
+func StoreResultsSummary(ctx context.Context, db *sql.DB, id string) (ResultsSummary, error) {
+	s, err := SummarizeResults(ctx, db, id)
+	if err != nil {
+		return s, err
+	}
+
+	return s, storeSummary(ctx, db, id, &s)
+}
+
+func storeSummary(ctx context.Context, db *sql.DB, id string, s *ResultsSummary) error {
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, `UPDATE gmaps_jobs SET summary = $1 WHERE id = $2`, raw, id); err != nil {
+		return fmt.Errorf("failed to store summary: %w", err)
+	}
+
+	return nil
+}
+
+// completeRoot stores the summary of the root job id and, when changes are
+// monitored, compares it with the previous run, then calls the job completion
+// API with both and tells the notifiers. The summary counts the webhook
+// calls dropped, the job completion one included.
+func (s *StatusManager) completeRoot(id string, payload []byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), summaryTimeout)
+	defer cancel()
+
+	log := scrapemate.GetLoggerFromContext(ctx)
+
+	var (
+		summary *ResultsSummary
+		changes *ChangeSet
+	)
+
+	if computed, err := SummarizeResults(ctx, s.db, id); err != nil {
+		log.Error(fmt.Sprintf("job %s: %v", id, err))
+	} else {
+		summary = &computed
+
+		if ownerID, _, err := rootOwner(payload); err == nil {
+			summary.FailedWebhooks = s.apiClient.takeDropped(ownerID)
+		}
+
+		if err := storeSummary(ctx, s.db, id, summary); err != nil {
+			log.Error(fmt.Sprintf("job %s: %v", id, err))
+		}
+	}
+
+	if s.monitorChanges {
+		var err error
+		if changes, err = s.compareRuns(ctx, id, payload); err != nil {
+			log.Error(fmt.Sprintf("job %s: %v", id, err))
+		}
+	}
+
+	if err := s.apiClient.CallJobCompletionAPI(ctx, id, payload, summary, changes); err != nil {
+		log.Error(fmt.Sprintf("job %s: %v", id, err))
+
+		var derr *DeliveryError
+		if summary != nil && errors.As(err, &derr) {
+			summary.FailedWebhooks++
+
+			if err := storeSummary(ctx, s.db, id, summary); err != nil {
+				log.Error(fmt.Sprintf("job %s: %v", id, err))
+			}
+		}
+	}
+
+	if len(s.notifiers) > 0 {
+		n, err := newJobNotification(id, payload, statusDone)
+		if err != nil {
+			log.Error(fmt.Sprintf("job %s: %v", id, err))
+			return
+		}
+
+		if n != nil {
+			n.Summary = summary
+			s.notify(ctx, n)
+		}
+	}
+}
+
+// RootSummaries holds the root jobs that are done until their results are
+// saved. The result writer tells it each time it saved what it was handed
+// over, and the root jobs done before whose detached enrichment jobs all ran
+// are completed then, so neither the summary nor the job completion call
+// waits longer than the writer.
+type RootSummaries struct {
+	mu    sync.Mutex
+	roots map[string]awaitingRoot
+	// complete completes a root job, set by WithRootSummaries.
+	complete func(id string, payload []byte)
+}
+
+type awaitingRoot struct {
+	payload []byte
+	since   time.Time
+}
+
+// NewRootSummaries returns an empty RootSummaries, for WithRootSummaries and
+// WithWriterSummaries.
+func NewRootSummaries() *RootSummaries {
+	return &RootSummaries{roots: make(map[string]awaitingRoot)}
+}
+
+// WithRootSummaries makes the root jobs done wait in s for the result writer
+// to save their results before they are completed.
+func WithRootSummaries(s *RootSummaries) ProviderOption {
+	return func(p *provider) {
+		sm := p.statusManager
+
+		sm.summaries = s
+		s.complete = func(id string, payload []byte) {
+			sm.background(func() {
+				sm.completeRoot(id, payload)
+			})
+		}
+	}
+}
+
+// WithWriterSummaries makes the writer complete the root jobs of s once it
+// saved their results.
+func WithWriterSummaries(s *RootSummaries) ResultWriterOption {
+	return func(r *resultWriter) {
+		r.summaries = s
+	}
+}
+
+// add holds the root job id, done now, until its results are saved.
+func (s *RootSummaries) add(id string, payload []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.roots[id] = awaitingRoot{payload: payload, since: time.Now()}
+}
+
+// Len returns how many root jobs wait for their results.
+func (s *RootSummaries) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.roots)
+}
+
+// saved completes the root jobs done before at, when the writer saved every
+// result it was handed over by then, whose detached enrichment jobs all ran
+// or that waited summaryWait for them.
+func (s *RootSummaries) saved(ctx context.Context, db *sql.DB, at time.Time) {
+	const q = `SELECT COUNT(*) FROM gmaps_jobs WHERE root_id = $1 AND status IN ($2, $3, $4, $5)`
+
+	s.mu.Lock()
+
+	ids := make([]string, 0, len(s.roots))
+	for id, r := range s.roots {
+		if r.since.Before(at) {
+			ids = append(ids, id)
+		}
+	}
+
+	s.mu.Unlock()
+
+	log := scrapemate.GetLoggerFromContext(ctx)
+
+	for _, id := range ids {
+		var pending int
+
+		err := db.QueryRowContext(ctx, q, id, statusDeferred, statusNew, statusQueued, statusProcessing).Scan(&pending)
+		if err != nil {
+			log.Error(fmt.Sprintf("job %s: failed to check its pending enrichment: %v", id, err))
+			continue
+		}
+
+		s.mu.Lock()
+
+		r, ok := s.roots[id]
+
+		ready := ok && (pending == 0 || time.Since(r.since) >= summaryWait)
+		if ready {
+			delete(s.roots, id)
+		}
+
+		s.mu.Unlock()
+
+		if !ready {
+			continue
+		}
+
+		if pending > 0 {
+			log.Error(fmt.Sprintf("job %s: summarized after waiting %s for its enrichment", id, summaryWait))
+		}
+
+		s.complete(id, r.payload)
+	}
+}
+
+// completeAll completes the root jobs still waiting, with the results saved
+// so far, when the worker stops.
+func (s *RootSummaries) completeAll() {
+	s.mu.Lock()
+	roots := s.roots
+	s.roots = make(map[string]awaitingRoot)
+	s.mu.Unlock()
+
+	for id, r := range roots {
+		s.complete(id, r.payload)
+	}
+}
+
+// percent returns n out of total as a percentage with one decimal.
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return math.Round(float64(n)*1000/float64(total)) / 10
+}
@@ -0,0 +1,232 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/httpjson"
+)
+
+const (
+	defaultFetchBatchSize = 50
+	maxFetchBatchSize     = 1000
+)
+
+// TuningSettings is the current state of a Tuning.
+type TuningSettings struct {
+	Concurrency    int  `json:"concurrency"`
+	MaxConcurrency int  `json:"max_concurrency"`
+	Active         int  `json:"active"`
+	FetchBatchSize int  `json:"fetch_batch_size"`
+	Email          bool `json:"email"`
+	Bodacc         bool `json:"bodacc"`
+}
+
+// TuningUpdate changes the settings it sets and leaves the others alone.
+type TuningUpdate struct {
+	Concurrency    *int  `json:"concurrency"`
+	FetchBatchSize *int  `json:"fetch_batch_size"`
+	Email          *bool `json:"email"`
+	Bodacc         *bool `json:"bodacc"`
+}
+
+// Tuning holds the settings operators can change while workers run: how many
+// jobs run at once (up to the concurrency the workers were started with), how
+// many jobs are fetched per query, and whether email and BODACC enrichment
+// jobs are created. Disabling an enrichment only drops the jobs of places
+// scraped from then on.
+type Tuning struct {
+	mu             sync.Mutex
+	wake           chan struct{}
+	concurrency    int
+	maxConcurrency int
+	active         int
+	fetchBatchSize int
+	email          bool
+	bodacc         bool
+}
+
+// NewTuning creates a Tuning allowing up to maxConcurrency jobs at once.
+func NewTuning(maxConcurrency int) *Tuning {
+	return &Tuning{
+		wake:           make(chan struct{}),
+		concurrency:    maxConcurrency,
+		maxConcurrency: maxConcurrency,
+		fetchBatchSize: defaultFetchBatchSize,
+		email:          true,
+		bodacc:         true,
+	}
+}
+
+// WithTuning makes the provider follow the runtime settings of t.
+func WithTuning(t *Tuning) ProviderOption {
+	return func(p *provider) {
+		p.tuning = t
+	}
+}
+
+// Settings returns the current settings.
+func (t *Tuning) Settings() TuningSettings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return TuningSettings{
+		Concurrency:    t.concurrency,
+		MaxConcurrency: t.maxConcurrency,
+		Active:         t.active,
+		FetchBatchSize: t.fetchBatchSize,
+		Email:          t.email,
+		Bodacc:         t.bodacc,
+	}
+}
+
+// Update validates and applies u. Nothing is changed when a value is invalid.
+func (t *Tuning) Update(u TuningUpdate) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if u.Concurrency != nil && (*u.Concurrency < 1 || *u.Concurrency > t.maxConcurrency) {
+		return fmt.Errorf("concurrency must be between 1 and %d", t.maxConcurrency)
+	}
+
+	if u.FetchBatchSize != nil && (*u.FetchBatchSize < 1 || *u.FetchBatchSize > maxFetchBatchSize) {
+		return fmt.Errorf("fetch_batch_size must be between 1 and %d", maxFetchBatchSize)
+	}
+
+	if u.Concurrency != nil {
+		t.concurrency = *u.Concurrency
+		t.broadcast()
+	}
+
+	if u.FetchBatchSize != nil {
+		t.fetchBatchSize = *u.FetchBatchSize
+	}
+
+	if u.Email != nil {
+		t.email = *u.Email
+	}
+
+	if u.Bodacc != nil {
+		t.bodacc = *u.Bodacc
+	}
+
+	return nil
+}
+
+// acquire waits until fewer jobs than the current concurrency are running.
+func (t *Tuning) acquire(ctx context.Context) error {
+	for {
+		t.mu.Lock()
+
+		if t.active < t.concurrency {
+			t.active++
+			t.mu.Unlock()
+
+			return nil
+		}
+
+		wake := t.wake
+		t.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (t *Tuning) release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.active--
+	t.broadcast()
+}
+
+// broadcast wakes up every waiter. It must be called with mu held.
+func (t *Tuning) broadcast() {
+	close(t.wake)
+	t.wake = make(chan struct{})
+}
+
+func (t *Tuning) batchSize() int {
+	if t == nil {
+		return defaultFetchBatchSize
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.fetchBatchSize
+}
+
+// filterEnrichment drops the enrichment jobs that are currently disabled.
+func (t *Tuning) filterEnrichment(jobs []scrapemate.IJob) []scrapemate.IJob {
+	if t == nil {
+		return jobs
+	}
+
+	s := t.Settings()
+
+	ans := jobs[:0]
+
+	for _, job := range jobs {
+		switch job.(type) {
+		case *gmaps.EmailExtractJob:
+			if !s.Email {
+				continue
+			}
+		case *gmaps.CompanyJob:
+			if !s.Bodacc {
+				continue
+			}
+		}
+
+		ans = append(ans, job)
+	}
+
+	return ans
+}
+
+// TuningHandler exposes t over HTTP:
+//
+//	GET   /tuning    current settings
+//	PATCH /tuning    change some settings: {"concurrency": 2, "email": false}
+func TuningHandler(t *Tuning) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/tuning", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			httpjson.Write(w, http.StatusOK, t.Settings())
+		case http.MethodPatch, http.MethodPut:
+			dec := json.NewDecoder(r.Body)
+			dec.DisallowUnknownFields()
+
+			var u TuningUpdate
+			if err := dec.Decode(&u); err != nil {
+				httpjson.Write(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+
+			if err := t.Update(u); err != nil {
+				httpjson.Write(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+
+			httpjson.Write(w, http.StatusOK, t.Settings())
+		default:
+			w.Header().Set("Allow", "GET, PATCH, PUT")
+			httpjson.Write(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		}
+	})
+
+	return mux
+}
@@ -5,61 +5,299 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/gosom/google-maps-scraper/browserpool"
 	"github.com/gosom/google-maps-scraper/gmaps"
 	"github.com/gosom/scrapemate"
+	"github.com/playwright-community/playwright-go"
 )
 
 // jobWrapper wraps jobs to handle marking them as done after processing.
 type jobWrapper struct {
 	scrapemate.IJob
 	provider *provider
+	// requeued is set when the browser crashed under the job, or Google
+	// blocks pages, and it was put back in the queue.
+	requeued bool
+}
+
+// Unwrap returns the job the provider fetched, for result writers reading its
+// owner or type.
+func (w *jobWrapper) Unwrap() scrapemate.IJob {
+	return w.IJob
+}
+
+// BrowserActions runs the wrapped job's browser actions in a context of the
+// browser pool speaking the language and country of the job, with the block
+// reporter in the context, besides what fetch sets up. With a block reporter,
+// the pages go through the proxy session of their browser context, which is
+// the scope of their backoff after a blocked page. The session cookies are
+// captured after successful jobs, and jobs failing because the browser
+// crashed are requeued in a restarted one. Place jobs given the data of the
+// place cache return it before any of this.
+func (w *jobWrapper) BrowserActions(ctx context.Context, page playwright.Page) scrapemate.Response {
+	if resp, ok := w.cachedResponse(); ok {
+		return resp
+	}
+
+	if w.provider.browserPool != nil {
+		params := w.GetURLParams()
+		target := browserpool.Target{Lang: params["hl"], Country: params["gl"]}
+
+		pooled, err := w.provider.browserPool.Acquire(page, target)
+		if err != nil {
+			log := scrapemate.GetLoggerFromContext(ctx)
+			log.Error(fmt.Sprintf("jobWrapper.BrowserActions: failed to acquire browser context: %v", err))
+		} else {
+			page = pooled
+		}
+	}
+
+	if w.provider.blockReporter != nil {
+		ctx = gmaps.WithBlockScope(ctx, page.Context())
+	}
+
+	resp := w.fetch(ctx, func(ctx context.Context) scrapemate.Response {
+		if w.provider.blockReporter != nil {
+			ctx = context.WithValue(ctx, gmaps.BlockReporterKey{}, w.provider.blockReporter)
+		}
+
+		resp := w.IJob.BrowserActions(ctx, page)
+
+		if w.provider.browserPool != nil {
+			if kind, crashed := browserpool.Crashed(page, resp.Error); crashed {
+				resp = w.recoverCrash(ctx, page, kind, resp)
+			} else {
+				crashRequeues.limiter.Done(w.GetID())
+			}
+		}
+
+		if w.provider.browserPool != nil && resp.Error == nil && !w.requeued {
+			if err := w.provider.browserPool.Persist(page); err != nil {
+				log := scrapemate.GetLoggerFromContext(ctx)
+				log.Error(fmt.Sprintf("jobWrapper.BrowserActions: failed to persist session: %v", err))
+			}
+		}
+
+		return resp
+	})
+
+	if w.provider.browserPool != nil {
+		w.provider.browserPool.Release(page, resp.Error == nil)
+	}
+
+	return resp
+}
+
+// FetchWithoutBrowser loads the data of the wrapped job with
+// gmaps.FetchWithoutBrowser, for the fetcher of fast mode, once fetch set it
+// up. Place jobs given the data of the place cache return it first.
+func (w *jobWrapper) FetchWithoutBrowser(ctx context.Context) scrapemate.Response {
+	if resp, ok := w.cachedResponse(); ok {
+		return resp
+	}
+
+	return w.fetch(ctx, func(ctx context.Context) scrapemate.Response {
+		return gmaps.FetchWithoutBrowser(ctx, w.IJob)
+	})
+}
+
+// cachedResponse returns the response of a place job given the data of the
+// place cache.
+func (w *jobWrapper) cachedResponse() (scrapemate.Response, bool) {
+	if place, ok := w.IJob.(*gmaps.PlaceJob); ok {
+		return place.CachedResponse()
+	}
+
+	return scrapemate.Response{}, false
+}
+
+// fetch runs load, which loads the data of the job, with the resource
+// blocking, the place fast path client and the fixtures in the context. With
+// a Tuning set, the job first waits for one of the slots it allows. The job
+// counts as running in JobStats meanwhile. Search and place jobs go back to
+// the queue untouched while Google blocks the pages of the scope of ctx.
+func (w *jobWrapper) fetch(ctx context.Context, load func(context.Context) scrapemate.Response) scrapemate.Response {
+	if w.holdBlocked(ctx) {
+		return scrapemate.Response{StatusCode: http.StatusOK}
+	}
+
+	defer jobStarted(w.IJob)()
+
+	if w.provider.activity != nil {
+		w.provider.activity.Begin()
+		defer w.provider.activity.End()
+	}
+
+	if w.provider.tuning != nil {
+		if err := w.provider.tuning.acquire(ctx); err != nil {
+			return scrapemate.Response{Error: err}
+		}
+
+		defer w.provider.tuning.release()
+	}
+
+	if w.provider.resources != nil {
+		ctx = context.WithValue(ctx, gmaps.ResourceBlockingKey{}, w.provider.resources)
+	}
+
+	if w.provider.placeClient != nil {
+		ctx = context.WithValue(ctx, gmaps.PlaceHTTPClientKey{}, w.provider.placeClient)
+	}
+
+	if w.provider.fixtures != nil {
+		ctx = context.WithValue(ctx, gmaps.FixturesKey{}, w.provider.fixtures)
+	}
+
+	return load(ctx)
 }
 
-// Process handles job processing and child job management.
 func (w *jobWrapper) Process(ctx context.Context, resp *scrapemate.Response) (any, []scrapemate.IJob, error) {
+	if w.provider.activity != nil {
+		defer w.provider.activity.Touch()
+	}
+
+	// the job runs again from the queue
+	if w.requeued {
+		return nil, nil, nil
+	}
+
+	data, next, err := w.process(ctx, resp)
+
+	reason := ""
+	if err != nil {
+		reason = err.Error()
+	}
+
+	jobProcessed(w.IJob, data, reason)
+
+	return data, next, err
+}
+
+// process runs the wrapped job's Process and stores its status, its children
+// and its enrichment data.
+func (w *jobWrapper) process(ctx context.Context, resp *scrapemate.Response) (any, []scrapemate.IJob, error) {
 	ctx = context.WithValue(ctx, providerKey{}, w.provider)
 	ctx = context.WithValue(ctx, gmaps.CompanyDataCheckerKey{}, w.provider)
+	ctx = context.WithValue(ctx, gmaps.BackfillerKey{}, w.provider)
+
+	if w.provider.geocoder != nil {
+		ctx = context.WithValue(ctx, gmaps.ReverseGeocoderKey{}, w.provider.geocoder)
+	}
+
+	if w.provider.categories != nil {
+		ctx = context.WithValue(ctx, gmaps.CategoryMapperKey{}, w.provider.categories)
+	}
+
+	if w.provider.skipServiceArea {
+		ctx = context.WithValue(ctx, gmaps.SkipServiceAreaEnrichmentKey{}, true)
+	}
+
+	if w.provider.credentials != nil {
+		ctx = context.WithValue(ctx, gmaps.CredentialResolverKey{}, w.provider.credentials)
+	}
+
+	if w.provider.registers != nil {
+		ctx = context.WithValue(ctx, gmaps.RegisterServicesKey{}, w.provider.registers)
+	}
+
+	// the job clears the response once processed
+	cacheable := *resp
 
 	data, nextJobs, err := w.IJob.Process(ctx, resp)
 
 	if err != nil {
-		_ = w.provider.statusManager.MarkFailed(ctx, w.IJob)
+		_ = w.provider.statusManager.MarkFailed(ctx, w.IJob, err.Error())
+		w.provider.recordFailure(w.IJob, err.Error())
+
 		return data, nil, err
 	}
 
-	// Handle enrichment jobs (email, company, pappers) - fire-and-forget
+	w.redact(data)
+
+	// Handle enrichment jobs (email, company, pappers, social) - fire-and-forget
 	if isEnrichmentJob(w.IJob) {
-		_ = w.provider.statusManager.MarkEnrichmentDone(ctx, w.IJob)
+		var (
+			inTx     func(tx *sql.Tx) error
+			children []scrapemate.IJob
+		)
+
+		// CompanyJob produces PappersJob(s) and EmailExtractJob SocialJob(s),
+		// pushed with its status
+		switch job := w.IJob.(type) {
+		case *gmaps.CompanyJob:
+			children = job.EnrichmentJobs
+		case *gmaps.EmailExtractJob:
+			children = w.provider.filterSocial(job.EnrichmentJobs)
+		}
+
+		if len(children) > 0 {
+			inTx = func(tx *sql.Tx) error {
+				return w.provider.insertEnrichmentJobs(ctx, tx, w.GetID(), children)
+			}
+		}
+
+		if err := w.provider.statusManager.MarkEnrichmentDone(ctx, w.IJob, inTx); err != nil {
+			log := scrapemate.GetLoggerFromContext(ctx)
+			log.Error(fmt.Sprintf("jobWrapper.Process: marking enrichment job done: %v", err))
+
+			// the transaction rolled back: the job would stay processing
+			// forever without its children
+			reason := fmt.Sprintf("enrichment jobs not saved: %v", err)
+			_ = w.provider.statusManager.MarkFailed(ctx, w.IJob, reason)
+			w.provider.recordFailure(w.IJob, reason)
+		}
 
 		// Direct UPDATE on results table based on result type
 		switch result := data.(type) {
 		case *gmaps.EmailEnrichmentResult:
-			go w.provider.updateResultEmails(context.Background(), result)
+			w.provider.goBackground(func() {
+				w.provider.updateResultEmails(context.Background(), result)
+				w.provider.updateResultTechnologies(context.Background(), result)
+				w.provider.updateResultWebsiteHealth(context.Background(), result)
+				w.provider.updateResultWhatsApp(context.Background(), result)
+			})
 		case *gmaps.CompanyEnrichmentResult:
-			go w.provider.updateResultCompanyData(context.Background(), result)
-			// If CompanyJob produced PappersJob(s), push them
-			if companyJob, ok := w.IJob.(*gmaps.CompanyJob); ok && len(companyJob.EnrichmentJobs) > 0 {
-				go w.provider.pushEnrichmentJobs(context.Background(), companyJob.EnrichmentJobs)
-			}
+			w.cacheCompany(result)
+			w.queueReview(result)
+			w.provider.goBackground(func() { w.provider.updateResultCompanyData(context.Background(), result) })
 		case *gmaps.PappersEnrichmentResult:
-			go w.provider.updateResultPappers(context.Background(), result)
+			w.cacheDirectors(result)
+			w.provider.goBackground(func() { w.provider.updateResultPappers(context.Background(), result) })
+		case *gmaps.SocialEnrichmentResult:
+			w.provider.goBackground(func() { w.provider.updateResultSocialProfile(context.Background(), result) })
 		}
 
 		return data, nil, nil
 	}
 
+	// an audit yields its result instead of an entry, from the search when the
+	// company is not listed and from its place otherwise
+	if audit, ok := data.(*gmaps.AuditResult); ok && audit != nil {
+		if err := w.provider.saveAudit(ctx, audit); err != nil {
+			return data, nil, err
+		}
+	}
+
 	// Handle PlaceJob: check duplicate, copy enrichment data, push enrichment jobs
 	if placeJob, ok := w.IJob.(*gmaps.PlaceJob); ok {
 		entry, isEntry := data.(*gmaps.Entry)
 
 		// Check if this place already exists for this user/org
 		if isEntry && entry != nil {
-			isDup := w.provider.checkDuplicatePlace(ctx, entry.Link, placeJob.OwnerID, placeJob.OrganizationID)
+			w.cachePlace(&cacheable)
+			w.archivePlace(&cacheable)
+			w.observePlace(placeJob, entry)
+
+			// a refreshed place updates the result the owner has
+			isDup := !placeJob.Refresh && w.provider.checkDuplicatePlace(ctx, entry.Link, placeJob.OwnerID, placeJob.OrganizationID)
 			if isDup {
-				_ = w.provider.statusManager.MarkFailed(ctx, w.IJob)
+				_ = w.provider.statusManager.MarkFailed(ctx, w.IJob, "place already saved for this owner")
+				w.provider.recordFailure(w.IJob, "place already saved for this owner")
+
 				return nil, nil, nil
 			}
 
@@ -69,6 +307,7 @@ func (w *jobWrapper) Process(ctx context.Context, resp *scrapemate.Response) (an
 				if existing != nil {
 					if len(existing.Emails) > 0 && len(entry.Emails) == 0 {
 						entry.Emails = existing.Emails
+						existing.copyProvenance(entry, "emails")
 					}
 					if existing.SocieteSiren != "" && entry.SocieteSiren == "" {
 						entry.SocieteDirigeants = existing.SocieteDirigeants
@@ -78,6 +317,8 @@ func (w *jobWrapper) Process(ctx context.Context, resp *scrapemate.Response) (an
 						entry.SocieteCloture = existing.SocieteCloture
 						entry.SocieteLink = existing.SocieteLink
 						entry.SocieteDiffusion = existing.SocieteDiffusion
+						existing.copyProvenance(entry, "societe_dirigeants", "societe_siren", "societe_forme",
+							"societe_creation", "societe_cloture", "societe_link", "societe_diffusion")
 					}
 					// Skip enrichment jobs since we already have the data
 					placeJob.EnrichmentJobs = nil
@@ -85,26 +326,33 @@ func (w *jobWrapper) Process(ctx context.Context, resp *scrapemate.Response) (an
 			}
 		}
 
-		if err := w.provider.statusManager.MarkDone(ctx, w.IJob, 0); err != nil {
+		// the data copied from the results of others follows the policy of
+		// this organization too
+		w.redact(data)
+
+		placeJob.EnrichmentJobs = w.provider.filterSocial(w.provider.tuning.filterEnrichment(placeJob.EnrichmentJobs))
+
+		// the enrichment jobs are committed with the status, so a crash
+		// loses both or neither
+		err := w.provider.statusManager.MarkDoneWith(ctx, w.IJob, 0, func(tx *sql.Tx) error {
+			return w.provider.insertEnrichmentJobs(ctx, tx, w.GetID(), placeJob.EnrichmentJobs)
+		})
+		if err != nil {
 			return data, nil, err
 		}
-		if len(placeJob.EnrichmentJobs) > 0 {
-			go w.provider.pushEnrichmentJobs(context.Background(), placeJob.EnrichmentJobs)
-		}
 		return data, nil, nil
 	}
 
-	log := scrapemate.GetLoggerFromContext(ctx)
-
 	// Handle GmapJob (search): push PlaceJobs to DB, don't return them to scrapemate
 	if gmapJob, ok := w.IJob.(*gmaps.GmapJob); ok {
-		if len(nextJobs) > 0 {
-			if err := w.provider.pushChildJobs(ctx, w.IJob, nextJobs); err != nil {
-				log.Error(fmt.Sprintf("jobWrapper.Process: Error pushing child jobs: %v", err))
-				return data, nil, fmt.Errorf("while pushing jobs: %w", err)
+		// a rank check has no children, only its ranking
+		if ranking, ok := data.(*gmaps.Ranking); ok && ranking != nil {
+			if err := w.provider.saveRanking(ctx, ranking); err != nil {
+				return data, nil, err
 			}
 		}
-		if err := w.provider.statusManager.MarkDone(ctx, w.IJob, len(nextJobs)); err != nil {
+
+		if err := w.pushChildren(ctx, nextJobs); err != nil {
 			return data, nil, err
 		}
 		w.provider.apiClient.CallRevalidationAPI(ctx, gmapJob.OwnerID)
@@ -112,19 +360,62 @@ func (w *jobWrapper) Process(ctx context.Context, resp *scrapemate.Response) (an
 	}
 
 	// Default: any other job type
-	if len(nextJobs) > 0 {
-		if err := w.provider.pushChildJobs(ctx, w.IJob, nextJobs); err != nil {
-			log.Error(fmt.Sprintf("jobWrapper.Process: Error pushing child jobs: %v", err))
-			return data, nil, fmt.Errorf("while pushing jobs: %w", err)
-		}
-	}
-	if err := w.provider.statusManager.MarkDone(ctx, w.IJob, len(nextJobs)); err != nil {
+	if err := w.pushChildren(ctx, nextJobs); err != nil {
 		return data, nil, err
 	}
 
 	return data, nil, nil
 }
 
+// redact applies the privacy policy of the organization of the job to data
+// before it is saved anywhere.
+func (w *jobWrapper) redact(data any) {
+	if w.provider.privacy == nil {
+		return
+	}
+
+	var orgID string
+
+	switch job := w.IJob.(type) {
+	case *gmaps.PlaceJob:
+		orgID = job.OrganizationID
+	case *gmaps.EmailExtractJob:
+		orgID = job.OrganizationID
+	case *gmaps.CompanyJob:
+		orgID = job.OrganizationID
+	case *gmaps.PappersJob:
+		orgID = job.OrganizationID
+	case *gmaps.SocialJob:
+		orgID = job.OrganizationID
+	}
+
+	w.provider.privacy.Apply(orgID, data)
+}
+
+// pushChildren pushes nextJobs as children of the job and marks it done, or
+// processing until they ran. The children an earlier attempt of the job
+// pushed are not counted again, and when they all ran already the job is
+// done right away.
+func (w *jobWrapper) pushChildren(ctx context.Context, nextJobs []scrapemate.IJob) error {
+	inserted, err := w.provider.pushChildJobs(ctx, w.IJob, nextJobs)
+	if err != nil {
+		log := scrapemate.GetLoggerFromContext(ctx)
+		log.Error(fmt.Sprintf("jobWrapper.Process: Error pushing child jobs: %v", err))
+
+		return fmt.Errorf("while pushing jobs: %w", err)
+	}
+
+	if err := w.provider.statusManager.MarkDone(ctx, w.IJob, len(nextJobs)); err != nil {
+		return err
+	}
+
+	if inserted < len(nextJobs) {
+		_, err = w.provider.statusManager.completeParent(ctx, w.GetID(), "children pushed by an earlier attempt all ran")
+	}
+
+	return err
+}
+
 // ChildJobManager handles pushing child jobs to the database.
 type ChildJobManager struct {
 	db            *sql.DB
@@ -139,35 +430,52 @@ func NewChildJobManager(db *sql.DB, codecRegistry *CodecRegistry) *ChildJobManag
 	}
 }
 
-// pushChildJobs pushes child jobs synchronously within a transaction.
-func (p *provider) pushChildJobs(ctx context.Context, parentJob scrapemate.IJob, childJobs []scrapemate.IJob) error {
+// pushChildJobs pushes child jobs synchronously within a transaction and
+// returns how many were inserted. A child that exists already, pushed by an
+// earlier attempt of the parent or twice by this one, is neither inserted
+// nor counted again.
+func (p *provider) pushChildJobs(ctx context.Context, parentJob scrapemate.IJob, childJobs []scrapemate.IJob) (int, error) {
 	if len(childJobs) == 0 {
-		return nil
+		return 0, nil
 	}
 
 	tx, err := p.db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer tx.Rollback()
 
-	updateParentQuery := `UPDATE gmaps_jobs SET child_jobs_count = child_jobs_count + $1 WHERE id = $2`
-	_, err = tx.ExecContext(ctx, updateParentQuery, len(childJobs), parentJob.GetID())
-	if err != nil {
-		return err
-	}
+	var inserted int
 
 	for _, childJob := range childJobs {
-		if err := p.pushJobWithParent(ctx, tx, childJob, parentJob.GetID()); err != nil {
-			return err
+		ok, err := p.pushJobWithParent(ctx, tx, childJob, parentJob.GetID())
+		if err != nil {
+			return 0, err
+		}
+
+		if ok {
+			inserted++
 		}
 	}
 
-	return tx.Commit()
+	updateParentQuery := `UPDATE gmaps_jobs SET child_jobs_count = child_jobs_count + $1 WHERE id = $2`
+	_, err = tx.ExecContext(ctx, updateParentQuery, inserted, parentJob.GetID())
+	if err != nil {
+		return 0, err
+	}
+
+	return inserted, tx.Commit()
+}
+
+// childJobID returns the id of the child of parentID loading fullURL, the
+// same on every attempt of the parent so that pushing it again conflicts.
+func childJobID(parentID, fullURL string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(parentID+" "+fullURL)).String()
 }
 
-// pushJobWithParent inserts a job with a parent reference.
-func (p *provider) pushJobWithParent(ctx context.Context, tx *sql.Tx, job scrapemate.IJob, parentID string) error {
+// pushJobWithParent inserts a job with a parent reference and tells whether
+// it did not exist yet.
+func (p *provider) pushJobWithParent(ctx context.Context, tx *sql.Tx, job scrapemate.IJob, parentID string) (bool, error) {
 	q := `INSERT INTO gmaps_jobs
 		(id, parent_id, priority, payload_type, payload, created_at, status)
 		VALUES
@@ -180,21 +488,18 @@ func (p *provider) pushJobWithParent(ctx context.Context, tx *sql.Tx, job scrape
 
 	jsonJob, jobType, err := p.codecRegistry.EncodeJob(actualJob)
 	if err != nil {
-		return fmt.Errorf("invalid job type in pushJobWithParent: %w", err)
+		return false, fmt.Errorf("invalid job type in pushJobWithParent: %w", err)
 	}
 
 	jsonJob.ParentID = &parentID
-
-	if jsonJob.ID == "" {
-		jsonJob.ID = uuid.New().String()
-	}
+	jsonJob.ID = childJobID(parentID, actualJob.GetFullURL())
 
 	payload, err := json.Marshal(jsonJob)
 	if err != nil {
-		return fmt.Errorf("failed to marshal job: %w", err)
+		return false, fmt.Errorf("failed to marshal job: %w", err)
 	}
 
-	_, err = tx.ExecContext(ctx, q,
+	result, err := tx.ExecContext(ctx, q,
 		jsonJob.ID,
 		parentID,
 		jsonJob.Priority,
@@ -205,8 +510,10 @@ func (p *provider) pushJobWithParent(ctx context.Context, tx *sql.Tx, job scrape
 	)
 
 	if err != nil {
-		return fmt.Errorf("failed to insert job: %w", err)
+		return false, fmt.Errorf("failed to insert job: %w", err)
 	}
 
-	return nil
+	n, err := result.RowsAffected()
+
+	return n > 0, err
 }
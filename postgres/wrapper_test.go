@@ -0,0 +1,204 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gosom/scrapemate"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+// queueJobs makes the fake database hand out jobs on the next fetch.
+func queueJobs(t *testing.T, fake *fakeDB, jobs ...scrapemate.IJob) {
+	t.Helper()
+
+	registry := postgres.NewCodecRegistry()
+
+	var rows [][]driver.Value
+
+	for _, job := range jobs {
+		encoded, typ, err := registry.EncodeJob(job)
+		require.NoError(t, err)
+
+		payload, err := json.Marshal(encoded)
+		require.NoError(t, err)
+
+		rows = append(rows, []driver.Value{job.GetID(), typ, payload})
+	}
+
+	fake.on("WITH updated AS", func([]driver.Value) answer {
+		batch := rows
+		rows = nil
+
+		return answer{columns: []string{"id", "payload_type", "payload"}, rows: batch}
+	})
+}
+
+// fetchJobs returns the n next jobs the provider dispatches.
+func fetchJobs(t *testing.T, p scrapemate.JobProvider, n int) []scrapemate.IJob {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	jobc, _ := p.Jobs(ctx)
+
+	var ans []scrapemate.IJob
+
+	for range n {
+		select {
+		case job := <-jobc:
+			ans = append(ans, job)
+		case <-time.After(time.Second):
+			t.Fatal("no job dispatched")
+		}
+	}
+
+	return ans
+}
+
+func document(t *testing.T, html string) *goquery.Document {
+	t.Helper()
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	require.NoError(t, err)
+
+	return doc
+}
+
+func Test_EnrichmentJobFailsWhenItsChildrenAreNotSaved(t *testing.T) {
+	fake, db := newFakeDB(t)
+
+	fake.on("INSERT INTO gmaps_jobs", func([]driver.Value) answer {
+		return answer{err: errors.New("disk full")}
+	})
+	fake.on("SELECT parent_id FROM gmaps_jobs", func([]driver.Value) answer {
+		return answer{columns: []string{"parent_id"}, rows: [][]driver.Value{{nil}}}
+	})
+	fake.on("SELECT parent_id, root_id FROM gmaps_jobs", func([]driver.Value) answer {
+		return answer{columns: []string{"parent_id", "root_id"}, rows: [][]driver.Value{{nil, "root"}}}
+	})
+
+	job := gmaps.NewEmailJob("", "https://www.google.com/maps/place/acme", "http://127.0.0.1:1", "owner-1", "")
+	queueJobs(t, fake, job)
+
+	p := postgres.NewProvider(db, "", "", postgres.WithSocialProfiles())
+	wrapped := fetchJobs(t, p, 1)[0]
+
+	resp := &scrapemate.Response{Document: document(t, `<a href="https://www.facebook.com/acme">Facebook</a>`)}
+
+	_, _, err := wrapped.Process(context.Background(), resp)
+	require.NoError(t, err)
+
+	// the social job was tried in the transaction marking the job done,
+	// under the root of the email job
+	inserts := fake.ran("INSERT INTO gmaps_jobs")
+	require.Len(t, inserts, 1)
+	require.Equal(t, "root", inserts[0][7])
+
+	var statuses []driver.Value
+	for _, args := range fake.ran("UPDATE gmaps_jobs SET status = $1 WHERE id = $2") {
+		statuses = append(statuses, args[0])
+	}
+
+	// the job is not left processing with its children lost
+	require.Equal(t, []driver.Value{"failed"}, statuses)
+}
+
+func Test_RetriedParentPushesTheSameChildren(t *testing.T) {
+	fake, db := newFakeDB(t)
+
+	// the first attempt inserted the child, the second conflicts with it
+	var attempts int
+
+	fake.on("INSERT INTO gmaps_jobs", func([]driver.Value) answer {
+		attempts++
+		if attempts > 1 {
+			return answer{}
+		}
+
+		return answer{affected: 1}
+	})
+	fake.on("SELECT parent_id FROM gmaps_jobs", func([]driver.Value) answer {
+		return answer{columns: []string{"parent_id"}, rows: [][]driver.Value{{nil}}}
+	})
+
+	job := gmaps.NewGmapJob("", "en", "acme", "owner-1", "", 1, false, false, "", 0)
+	queueJobs(t, fake, job, job)
+
+	p := postgres.NewProvider(db, "", "")
+
+	for _, wrapped := range fetchJobs(t, p, 2) {
+		resp := &scrapemate.Response{
+			URL:      "https://www.google.com/maps/place/acme",
+			Document: document(t, "<html></html>"),
+		}
+
+		_, _, err := wrapped.Process(context.Background(), resp)
+		require.NoError(t, err)
+	}
+
+	inserts := fake.ran("INSERT INTO gmaps_jobs")
+	require.Len(t, inserts, 2)
+	require.Equal(t, inserts[0][0], inserts[1][0])
+
+	// the conflicting child is not counted again
+	var counted []driver.Value
+	for _, args := range fake.ran("child_jobs_count = child_jobs_count + $1") {
+		counted = append(counted, args[0])
+	}
+
+	require.Equal(t, []driver.Value{1, 0}, counted)
+}
+
+func Test_SearchBatchPushesItsSearches(t *testing.T) {
+	fake, db := newFakeDB(t)
+
+	// search batches are inserted by other services only
+	rows := [][]driver.Value{{"batch-1", "search_batch", []byte(`{"id": "batch-1", "max_retries": 3,
+		"job_type": "search_batch", "metadata": {"queries": ["plombier", " ", "serrurier"], "lang_code": "fr",
+		"max_depth": 10, "owner_id": "owner-1", "organization_id": "org-1"}}`)}}
+
+	fake.on("WITH updated AS", func([]driver.Value) answer {
+		batch := rows
+		rows = nil
+
+		return answer{columns: []string{"id", "payload_type", "payload"}, rows: batch}
+	})
+	fake.on("INSERT INTO gmaps_jobs", func([]driver.Value) answer {
+		return answer{affected: 1}
+	})
+
+	p := postgres.NewProvider(db, "", "")
+
+	// the batch is dispatched like any job, and loads nothing
+	wrapped := fetchJobs(t, p, 1)[0]
+	require.Equal(t, "batch-1", wrapped.GetID())
+
+	resp := wrapped.(gmaps.BrowserlessJob).FetchWithoutBrowser(context.Background())
+	require.NoError(t, resp.Error)
+
+	_, next, err := wrapped.Process(context.Background(), &resp)
+	require.NoError(t, err)
+	require.Empty(t, next)
+
+	// one search per query, children of the batch, which waits for them
+	inserts := fake.ran("INSERT INTO gmaps_jobs")
+	require.Len(t, inserts, 2)
+
+	for _, args := range inserts {
+		require.Equal(t, "batch-1", args[1])
+		require.Equal(t, "search", args[3])
+	}
+
+	require.Equal(t, [][]driver.Value{{2, "batch-1"}}, fake.ran("child_jobs_count = child_jobs_count + $1"))
+}
@@ -0,0 +1,245 @@
+// Package privacy minimizes the personal data of the places before they are
+// saved: the personal emails and the names of the directors can be kept,
+// hashed or dropped, for every organization or for some of them.
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// Action is what a policy does with a kind of personal data.
+type Action string
+
+const (
+	Keep Action = "keep"
+	Hash Action = "hash"
+	Drop Action = "drop"
+)
+
+// hashPrefix marks the hashed values, which are not hashed again. Hashed
+// emails have no @ and are no longer personal.
+const hashPrefix = "sha256:"
+
+// Policy is what is done with the personal data of an organization.
+type Policy struct {
+	// Emails applies to the personal emails, those of webmail domains;
+	// the addresses of the business domain are always kept.
+	Emails Action
+	// Directors applies to the names of the directors.
+	Directors Action
+}
+
+// Policies holds the policy of every organization: the default one and
+// those of the organizations that have their own.
+type Policies struct {
+	def  Policy
+	orgs map[string]Policy
+	key  []byte
+}
+
+// ParsePolicies parses -pii-policy entries in the [org/]field=action format,
+// field being emails or directors and action keep, hash or drop. Entries
+// without an organization set the default, which the organizations inherit
+// for the fields they do not set. Hashes are keyed with PII_HASH_KEY when it
+// is set, so that they cannot be matched against hashes of known emails.
+func ParsePolicies(entries []string) (*Policies, error) {
+	p := &Policies{
+		def:  Policy{Emails: Keep, Directors: Keep},
+		orgs: map[string]Policy{},
+		key:  []byte(os.Getenv("PII_HASH_KEY")),
+	}
+
+	type rule struct {
+		org, field string
+		action     Action
+	}
+
+	var rules []rule
+
+	for _, entry := range entries {
+		target, action, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid entry %q (expected '[org/]field=action')", entry)
+		}
+
+		org, field, ok := strings.Cut(strings.TrimSpace(target), "/")
+		if !ok {
+			org, field = "", org
+		}
+
+		r := rule{org: org, field: field, action: Action(strings.TrimSpace(action))}
+
+		switch r.action {
+		case Keep, Hash, Drop:
+		default:
+			return nil, fmt.Errorf("action of %q must be keep, hash or drop, got %q", target, action)
+		}
+
+		switch r.field {
+		case "emails", "directors":
+		default:
+			return nil, fmt.Errorf("field of %q must be emails or directors, got %q", target, field)
+		}
+
+		if ok && org == "" {
+			return nil, fmt.Errorf("invalid entry %q: empty organization", entry)
+		}
+
+		rules = append(rules, r)
+	}
+
+	// the defaults first, so that the organizations inherit them whatever
+	// the order of the entries
+	for _, r := range rules {
+		if r.org == "" {
+			p.def.set(r.field, r.action)
+		}
+	}
+
+	for _, r := range rules {
+		if r.org == "" {
+			continue
+		}
+
+		pol, ok := p.orgs[r.org]
+		if !ok {
+			pol = p.def
+		}
+
+		pol.set(r.field, r.action)
+		p.orgs[r.org] = pol
+	}
+
+	return p, nil
+}
+
+func (pol *Policy) set(field string, action Action) {
+	if field == "emails" {
+		pol.Emails = action
+	} else {
+		pol.Directors = action
+	}
+}
+
+// For returns the policy of the organization orgID.
+func (p *Policies) For(orgID string) Policy {
+	if pol, ok := p.orgs[orgID]; ok {
+		return pol
+	}
+
+	return p.def
+}
+
+// Apply redacts in place the personal data of data, a place or an
+// enrichment result of the organization orgID. Values already hashed are
+// left as they are, so applying it twice changes nothing.
+func (p *Policies) Apply(orgID string, data any) {
+	if p == nil {
+		return
+	}
+
+	pol := p.For(orgID)
+
+	switch v := data.(type) {
+	case *gmaps.Entry:
+		if v != nil {
+			v.Emails = p.emails(pol.Emails, v.Emails)
+			v.SocieteDirigeants = p.directors(pol.Directors, v.SocieteDirigeants)
+		}
+	case *gmaps.EmailEnrichmentResult:
+		if v != nil {
+			v.Emails = p.emails(pol.Emails, v.Emails)
+		}
+	case *gmaps.CompanyEnrichmentResult:
+		if v != nil {
+			v.SocieteDirigeants = p.directors(pol.Directors, v.SocieteDirigeants)
+		}
+	case *gmaps.PappersEnrichmentResult:
+		if v != nil {
+			v.SocieteDirigeants = p.directors(pol.Directors, v.SocieteDirigeants)
+		}
+	}
+}
+
+func (p *Policies) emails(action Action, emails []string) []string {
+	if action == Keep {
+		return emails
+	}
+
+	ans := make([]string, 0, len(emails))
+
+	for _, email := range emails {
+		switch {
+		case !Personal(email):
+			ans = append(ans, email)
+		case action == Hash:
+			ans = append(ans, p.hash(strings.ToLower(strings.TrimSpace(email))))
+		}
+	}
+
+	return ans
+}
+
+func (p *Policies) directors(action Action, names []string) []string {
+	switch action {
+	case Drop:
+		if names == nil {
+			return nil
+		}
+
+		return []string{}
+	case Hash:
+		ans := make([]string, len(names))
+		for i, name := range names {
+			ans[i] = name
+
+			if !strings.HasPrefix(name, hashPrefix) {
+				ans[i] = p.hash(strings.ToUpper(strings.TrimSpace(name)))
+			}
+		}
+
+		return ans
+	default:
+		return names
+	}
+}
+
+func (p *Policies) hash(s string) string {
+	var sum []byte
+
+	if len(p.key) > 0 {
+		mac := hmac.New(sha256.New, p.key)
+		mac.Write([]byte(s))
+		sum = mac.Sum(nil)
+	} else {
+		digest := sha256.Sum256([]byte(s))
+		sum = digest[:]
+	}
+
+	return hashPrefix + hex.EncodeToString(sum)
+}
+
+// webmailDomains are the domains of the mailboxes of individuals, whose
+// addresses are personal data.
+var webmailDomains = []string{
+	"gmail.com", "googlemail.com", "hotmail.com", "hotmail.fr", "outlook.com", "outlook.fr",
+	"live.com", "live.fr", "msn.com", "yahoo.com", "yahoo.fr", "icloud.com", "me.com",
+	"orange.fr", "wanadoo.fr", "free.fr", "sfr.fr", "neuf.fr", "laposte.net", "bbox.fr",
+	"aol.com", "gmx.fr", "gmx.com", "protonmail.com", "proton.me",
+}
+
+// Personal tells whether email is the address of an individual, at a
+// webmail domain, rather than of a business.
+func Personal(email string) bool {
+	_, domain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+
+	return ok && slices.Contains(webmailDomains, domain)
+}
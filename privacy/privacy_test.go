@@ -0,0 +1,45 @@
+package privacy_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/privacy"
+)
+
+func Test_PoliciesApply(t *testing.T) {
+	policies, err := privacy.ParsePolicies([]string{"org-1/directors=drop", "emails=hash", "org-2/emails=keep"})
+	require.NoError(t, err)
+
+	require.Equal(t, privacy.Policy{Emails: privacy.Hash, Directors: privacy.Drop}, policies.For("org-1"))
+	require.Equal(t, privacy.Policy{Emails: privacy.Keep, Directors: privacy.Keep}, policies.For("org-2"))
+	require.Equal(t, privacy.Policy{Emails: privacy.Hash, Directors: privacy.Keep}, policies.For(""))
+
+	entry := &gmaps.Entry{
+		Emails:            []string{"contact@boulangerie-martin.fr", "Paul.Martin@Gmail.com"},
+		SocieteDirigeants: []string{"Paul Martin"},
+	}
+
+	policies.Apply("org-1", entry)
+
+	require.Equal(t, "contact@boulangerie-martin.fr", entry.Emails[0])
+	require.True(t, strings.HasPrefix(entry.Emails[1], "sha256:"))
+	require.Empty(t, entry.SocieteDirigeants)
+
+	// hashing again changes nothing, and the hash does not depend on the case
+	hashed := entry.Emails[1]
+	policies.Apply("org-1", entry)
+	require.Equal(t, hashed, entry.Emails[1])
+
+	result := &gmaps.EmailEnrichmentResult{Emails: []string{"paul.martin@gmail.com"}}
+	policies.Apply("", result)
+	require.Equal(t, []string{hashed}, result.Emails)
+
+	for _, entries := range [][]string{{"emails"}, {"phones=drop"}, {"emails=encrypt"}, {"/emails=drop"}} {
+		_, err := privacy.ParsePolicies(entries)
+		require.Error(t, err, entries)
+	}
+}
@@ -0,0 +1,54 @@
+package proxypool
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gosom/google-maps-scraper/httpjson"
+)
+
+type addProxyRequest struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// AdminHandler exposes the pool over HTTP:
+//
+//	GET    /proxies            list proxies and their health
+//	POST   /proxies            add a proxy: {"url": "...", "weight": 1}
+//	DELETE /proxies?url=...    remove a proxy
+func AdminHandler(pool *Pool) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/proxies", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			httpjson.Write(w, http.StatusOK, pool.Snapshot())
+		case http.MethodPost:
+			var req addProxyRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				httpjson.Write(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+
+			if err := pool.Add(req.URL, req.Weight); err != nil {
+				httpjson.Write(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+
+			httpjson.Write(w, http.StatusCreated, pool.Snapshot())
+		case http.MethodDelete:
+			if !pool.Remove(r.URL.Query().Get("url")) {
+				httpjson.Write(w, http.StatusNotFound, map[string]string{"error": "proxy not found"})
+				return
+			}
+
+			httpjson.Write(w, http.StatusOK, pool.Snapshot())
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			httpjson.Write(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		}
+	})
+
+	return mux
+}
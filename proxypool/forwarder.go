@@ -0,0 +1,361 @@
+package proxypool
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+const maxUpstreamAttempts = 3
+
+// SessionHost is answered by the forwarder itself, with the session of the
+// request, so that a browser context can tell which session its proxy
+// credentials stand for.
+const SessionHost = "proxypool.session"
+
+// Forwarder is a local HTTP proxy that forwards each connection through a
+// proxy picked from the pool. Scrapemate is pointed at the forwarder so the
+// pool can rotate, ban and add/remove upstreams while the scraper is running.
+type Forwarder struct {
+	pool   *Pool
+	server *http.Server
+	ln     net.Listener
+	wg     sync.WaitGroup
+
+	mu sync.Mutex
+	// lastUsed is the upstream of the last tunnel of each session to each
+	// host.
+	lastUsed sessions
+}
+
+// NewForwarder creates a forwarder backed by pool.
+func NewForwarder(pool *Pool) *Forwarder {
+	return &Forwarder{
+		pool:     pool,
+		lastUsed: make(sessions),
+	}
+}
+
+// ReportBlocked bans the upstream that served the blocked page. The page asks
+// the forwarder for its session through the proxy of its browser context,
+// then Block bans the upstream of that session.
+func (f *Forwarder) ReportBlocked(page playwright.Page) {
+	session, err := pageSession(page)
+	if err != nil {
+		log.Printf("proxypool: no session for the blocked page %s: %v", page.URL(), err)
+		return
+	}
+
+	f.Block(session, blockedHost(page.URL()))
+}
+
+// Block bans the upstream through which session last opened a tunnel to
+// host. The browser keeps its tunnel to Google open across navigations, so
+// this is the upstream that served the blocked page of the session; the
+// tunnels of the other sessions are left alone.
+func (f *Forwarder) Block(session, host string) {
+	key := upstreamKey(session, host)
+
+	f.mu.Lock()
+	upstream, ok := f.lastUsed.take(key, time.Now())
+	f.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	log.Printf("proxypool: %s was blocked by %s for session %s", upstream, host, session)
+
+	f.pool.Ban(upstream)
+}
+
+func (f *Forwarder) recordUpstream(session, host string, upstream *url.URL) {
+	f.mu.Lock()
+	f.lastUsed.set(upstreamKey(session, host), redact(upstream), time.Now())
+	f.mu.Unlock()
+}
+
+func upstreamKey(session, host string) string {
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "443")
+	}
+
+	return session + " " + host
+}
+
+// blockedHost is the host whose tunnel served the blocked page at pageURL:
+// the consent wall is served for www.google.com.
+func blockedHost(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Host == "" || strings.Contains(u.Host, "consent.") {
+		return "www.google.com"
+	}
+
+	return u.Host
+}
+
+// pageSession asks the forwarder for the session of the browser context of
+// page.
+func pageSession(page playwright.Page) (string, error) {
+	resp, err := page.Context().Request().Get("http://" + SessionHost + "/")
+	if err != nil {
+		return "", err
+	}
+
+	defer func() { _ = resp.Dispose() }()
+
+	if resp.Status() != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.Status())
+	}
+
+	body, err := resp.Text()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(body), nil
+}
+
+// Start listens on addr (use "127.0.0.1:0" for a random port) and serves in the background.
+func (f *Forwarder) Start(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	f.ln = ln
+	f.server = &http.Server{
+		Handler:           f,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	f.wg.Add(1)
+
+	go func() {
+		defer f.wg.Done()
+
+		if err := f.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("proxypool: forwarder stopped: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// URL returns the proxy URL of the forwarder, without a session.
+func (f *Forwarder) URL() string {
+	return "http://" + f.ln.Addr().String()
+}
+
+// SessionURLs returns n proxy URLs of the forwarder, each with the
+// credentials of its own session. Scrapemate gives every browser it starts
+// the next one, so the sticky strategy and the blocked pages are keyed per
+// browser instead of per client address, which is always the loopback one.
+func (f *Forwarder) SessionURLs(n int) []string {
+	ans := make([]string, n)
+
+	for i := range ans {
+		u := url.URL{
+			Scheme: "http",
+			User:   url.UserPassword(fmt.Sprintf("session-%d", i+1), "proxypool"),
+			Host:   f.ln.Addr().String(),
+		}
+
+		ans[i] = u.String()
+	}
+
+	return ans
+}
+
+// Close stops the forwarder.
+func (f *Forwarder) Close() error {
+	if f.server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := f.server.Shutdown(ctx)
+	f.wg.Wait()
+
+	return err
+}
+
+// ServeHTTP implements http.Handler.
+func (f *Forwarder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		f.handleConnect(w, r)
+		return
+	}
+
+	f.handlePlain(w, r)
+}
+
+// sessionKey identifies the session of the client: the proxy username it
+// sent, as with the URLs of SessionURLs, or else its address.
+func sessionKey(r *http.Request) string {
+	if auth := r.Header.Get("Proxy-Authorization"); strings.HasPrefix(auth, "Basic ") {
+		if raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(auth, "Basic ")); err == nil {
+			if user, _, _ := strings.Cut(string(raw), ":"); user != "" {
+				return user
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func (f *Forwarder) handleConnect(w http.ResponseWriter, r *http.Request) {
+	key := sessionKey(r)
+
+	var (
+		upstreamConn net.Conn
+		lastErr      error
+	)
+
+	for range maxUpstreamAttempts {
+		upstream, err := f.pool.Next(key)
+		if err != nil {
+			lastErr = err
+			break
+		}
+
+		upstreamConn, err = dialThrough(r.Context(), upstream, r.Host)
+		if err == nil {
+			f.pool.ReportSuccess(upstream.String())
+			f.recordUpstream(key, r.Host, upstream)
+
+			break
+		}
+
+		lastErr = err
+
+		// the content of a tunnel is encrypted: only a refused tunnel tells
+		// of a ban here, the blocked pages are reported by the browser
+		var refused *connectError
+		if errors.As(err, &refused) && IsBanResponse(refused.code, "", nil) {
+			f.pool.Ban(upstream.String())
+			continue
+		}
+
+		f.pool.ReportFailure(upstream.String())
+	}
+
+	if upstreamConn == nil {
+		http.Error(w, "proxypool: "+lastErr.Error(), http.StatusBadGateway)
+		return
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		_ = upstreamConn.Close()
+		http.Error(w, "proxypool: hijacking not supported", http.StatusInternalServerError)
+
+		return
+	}
+
+	clientConn, _, err := hj.Hijack()
+	if err != nil {
+		_ = upstreamConn.Close()
+		return
+	}
+
+	if _, err := clientConn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		_ = clientConn.Close()
+		_ = upstreamConn.Close()
+
+		return
+	}
+
+	go pipe(clientConn, upstreamConn)
+	pipe(upstreamConn, clientConn)
+}
+
+func pipe(dst, src net.Conn) {
+	defer dst.Close()
+	defer src.Close()
+
+	_, _ = io.Copy(dst, src)
+}
+
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+func (f *Forwarder) handlePlain(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Hostname() == SessionHost {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = io.WriteString(w, sessionKey(r))
+
+		return
+	}
+
+	upstream, err := f.pool.Next(sessionKey(r))
+	if err != nil {
+		http.Error(w, "proxypool: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	outReq := r.Clone(r.Context())
+	outReq.RequestURI = ""
+
+	for _, h := range hopHeaders {
+		outReq.Header.Del(h)
+	}
+
+	tr := transportFor(upstream)
+	defer tr.CloseIdleConnections()
+
+	resp, err := tr.RoundTrip(outReq)
+	if err != nil {
+		f.pool.ReportFailure(upstream.String())
+		http.Error(w, "proxypool: "+err.Error(), http.StatusBadGateway)
+
+		return
+	}
+	defer resp.Body.Close()
+
+	if IsBanResponse(resp.StatusCode, resp.Header.Get("Location"), nil) {
+		f.pool.Ban(upstream.String())
+	} else {
+		f.pool.ReportSuccess(upstream.String())
+	}
+
+	for _, h := range hopHeaders {
+		resp.Header.Del(h)
+	}
+
+	for k, vv := range resp.Header {
+		for _, v := range vv {
+			w.Header().Add(k, v)
+		}
+	}
+
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
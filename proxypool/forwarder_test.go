@@ -0,0 +1,195 @@
+package proxypool_test
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/proxypool"
+)
+
+// connectProxy is an upstream proxy accepting every tunnel.
+func connectProxy(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return refusingProxy(t, http.StatusOK)
+}
+
+// refusingProxy is an upstream proxy answering every tunnel with code.
+func refusingProxy(t *testing.T, code int) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if code != http.StatusOK {
+			w.WriteHeader(code)
+			return
+		}
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		_, _ = io.WriteString(conn, "HTTP/1.1 200 Connection Established\r\n\r\n")
+		_, _ = io.Copy(io.Discard, conn)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func startForwarder(t *testing.T, pool *proxypool.Pool) *proxypool.Forwarder {
+	t.Helper()
+
+	f := proxypool.NewForwarder(pool)
+	require.NoError(t, f.Start("127.0.0.1:0"))
+	t.Cleanup(func() { _ = f.Close() })
+
+	return f
+}
+
+// tunnel opens a tunnel to host through the forwarder for session and
+// returns the status of the answer.
+func tunnel(t *testing.T, f *proxypool.Forwarder, session, host string) int {
+	t.Helper()
+
+	u, err := url.Parse(f.URL())
+	require.NoError(t, err)
+
+	conn, err := net.Dial("tcp", u.Host)
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	auth := base64.StdEncoding.EncodeToString([]byte(session + ":proxypool"))
+	_, err = io.WriteString(conn, "CONNECT "+host+" HTTP/1.1\r\nHost: "+host+"\r\nProxy-Authorization: Basic "+auth+"\r\n\r\n")
+	require.NoError(t, err)
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: http.MethodConnect})
+	require.NoError(t, err)
+
+	return resp.StatusCode
+}
+
+// banned returns the upstreams of pool banned now.
+func banned(pool *proxypool.Pool) []string {
+	var ans []string
+
+	for _, st := range pool.Snapshot() {
+		if st.Banned {
+			ans = append(ans, st.URL)
+		}
+	}
+
+	return ans
+}
+
+func Test_ForwarderBlockBansTheUpstreamOfTheSession(t *testing.T) {
+	a, b := connectProxy(t), connectProxy(t)
+
+	pool, err := proxypool.New([]string{a.URL, b.URL})
+	require.NoError(t, err)
+
+	f := startForwarder(t, pool)
+
+	// round robin: the first session tunnels through a, the second through b
+	require.Equal(t, http.StatusOK, tunnel(t, f, "session-1", "www.google.com:443"))
+	require.Equal(t, http.StatusOK, tunnel(t, f, "session-2", "www.google.com:443"))
+
+	f.Block("session-2", "www.google.com")
+	require.Equal(t, []string{b.URL}, banned(pool))
+
+	// the tunnel of the session was already blamed
+	f.Block("session-2", "www.google.com")
+	// and the session never reached that host
+	f.Block("session-1", "maps.google.com")
+
+	require.Len(t, banned(pool), 1)
+}
+
+func Test_ForwarderAnswersTheSession(t *testing.T) {
+	pool, err := proxypool.New([]string{connectProxy(t).URL})
+	require.NoError(t, err)
+
+	f := startForwarder(t, pool)
+
+	urls := f.SessionURLs(2)
+	require.Len(t, urls, 2)
+
+	for i, raw := range urls {
+		u, err := url.Parse(raw)
+		require.NoError(t, err)
+
+		client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(u)}}
+
+		resp, err := client.Get("http://" + proxypool.SessionHost + "/")
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"session-1", "session-2"}[i], string(body))
+	}
+}
+
+func Test_ForwarderBansUpstreamsRefusingTunnels(t *testing.T) {
+	limited, ok := refusingProxy(t, http.StatusTooManyRequests), connectProxy(t)
+
+	pool, err := proxypool.New([]string{limited.URL, ok.URL})
+	require.NoError(t, err)
+
+	f := startForwarder(t, pool)
+
+	// the rate limited upstream is banned and the tunnel opened through the
+	// next one
+	require.Equal(t, http.StatusOK, tunnel(t, f, "session-1", "www.google.com:443"))
+	require.Equal(t, []string{limited.URL}, banned(pool))
+	require.Equal(t, 0, status(t, pool, limited.URL).Failures)
+}
+
+func Test_ForwarderStickySessionMovesAfterABlock(t *testing.T) {
+	a, b := connectProxy(t), connectProxy(t)
+
+	pool, err := proxypool.New([]string{a.URL, b.URL}, proxypool.WithStrategy(proxypool.StrategySticky))
+	require.NoError(t, err)
+
+	f := startForwarder(t, pool)
+
+	require.Equal(t, http.StatusOK, tunnel(t, f, "session-1", "www.google.com:443"))
+
+	f.Block("session-1", "www.google.com")
+
+	blocked := banned(pool)
+	require.Len(t, blocked, 1)
+
+	// the next tunnel of the session goes through the other upstream
+	require.Equal(t, http.StatusOK, tunnel(t, f, "session-1", "www.google.com:443"))
+
+	served := map[string]int64{}
+	for _, st := range pool.Snapshot() {
+		served[st.URL] = st.Served
+	}
+
+	require.Equal(t, int64(1), served[blocked[0]])
+
+	for u, n := range served {
+		if u != blocked[0] {
+			require.Equal(t, int64(1), n)
+		}
+	}
+}
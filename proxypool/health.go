@@ -0,0 +1,237 @@
+package proxypool
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	xproxy "golang.org/x/net/proxy"
+)
+
+const (
+	// DefaultCheckURL is requested through every proxy during health checks.
+	DefaultCheckURL = "https://www.google.com/generate_204"
+
+	dialTimeout  = 15 * time.Second
+	checkTimeout = 20 * time.Second
+)
+
+// IsBanResponse reports whether an upstream response means Google has
+// flagged the proxy: rate limiting, a redirect to the /sorry/ interstitial,
+// or a reCAPTCHA challenge in the body.
+func IsBanResponse(statusCode int, location string, body []byte) bool {
+	if statusCode == http.StatusTooManyRequests {
+		return true
+	}
+
+	if strings.Contains(location, "/sorry/") {
+		return true
+	}
+
+	if len(body) == 0 {
+		return false
+	}
+
+	lower := strings.ToLower(string(body))
+
+	return strings.Contains(lower, "g-recaptcha") ||
+		strings.Contains(lower, "unusual traffic from your computer network")
+}
+
+// connectError is returned when an upstream proxy refuses a tunnel.
+type connectError struct {
+	code   int
+	status string
+}
+
+func (e *connectError) Error() string {
+	return "upstream proxy CONNECT failed: " + e.status
+}
+
+// dialThrough opens a tunnel to addr through the given upstream proxy.
+func dialThrough(ctx context.Context, upstream *url.URL, addr string) (net.Conn, error) {
+	if isSocks(upstream) {
+		d, err := xproxy.FromURL(upstream, &net.Dialer{Timeout: dialTimeout})
+		if err != nil {
+			return nil, err
+		}
+
+		if cd, ok := d.(xproxy.ContextDialer); ok {
+			return cd.DialContext(ctx, "tcp", addr)
+		}
+
+		return d.Dial("tcp", addr)
+	}
+
+	dialer := &net.Dialer{Timeout: dialTimeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", upstream.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	if upstream.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: upstream.Hostname(), MinVersion: tls.VersionTLS12})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+
+	if auth := proxyAuthorization(upstream); auth != "" {
+		req.Header.Set("Proxy-Authorization", auth)
+	}
+
+	if err := req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_ = conn.Close()
+		return nil, &connectError{code: resp.StatusCode, status: resp.Status}
+	}
+
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+
+	return conn, nil
+}
+
+func isSocks(u *url.URL) bool {
+	return u.Scheme == "socks5" || u.Scheme == "socks5h"
+}
+
+func proxyAuthorization(u *url.URL) string {
+	if u.User == nil {
+		return ""
+	}
+
+	pass, _ := u.User.Password()
+	creds := u.User.Username() + ":" + pass
+
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(creds))
+}
+
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// transportFor returns an http.Transport that routes every request through upstream.
+func transportFor(upstream *url.URL) *http.Transport {
+	tr := &http.Transport{
+		TLSHandshakeTimeout:   dialTimeout,
+		ResponseHeaderTimeout: checkTimeout,
+	}
+
+	if isSocks(upstream) {
+		tr.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return dialThrough(ctx, upstream, addr)
+		}
+	} else {
+		tr.Proxy = http.ProxyURL(upstream)
+	}
+
+	return tr
+}
+
+// CheckHealth requests checkURL through every proxy once and updates the pool.
+func (p *Pool) CheckHealth(ctx context.Context, checkURL string) {
+	for _, u := range p.urls() {
+		p.checkOne(ctx, u, checkURL)
+	}
+}
+
+func (p *Pool) checkOne(ctx context.Context, upstream *url.URL, checkURL string) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	tr := transportFor(upstream)
+	defer tr.CloseIdleConnections()
+
+	client := &http.Client{
+		Transport: tr,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, checkURL, http.NoBody)
+	if err != nil {
+		return
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("proxypool: health check failed for %s: %v", redact(upstream), err)
+		p.ReportFailure(upstream.String())
+
+		return
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	if IsBanResponse(resp.StatusCode, resp.Header.Get("Location"), body) {
+		log.Printf("proxypool: %s is banned by the target, backing off", redact(upstream))
+		p.Ban(upstream.String())
+
+		return
+	}
+
+	p.ReportSuccess(upstream.String())
+}
+
+// RunHealthChecks checks every proxy at the given interval until ctx is done.
+func (p *Pool) RunHealthChecks(ctx context.Context, interval time.Duration, checkURL string) {
+	if checkURL == "" {
+		checkURL = DefaultCheckURL
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	p.CheckHealth(ctx, checkURL)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.CheckHealth(ctx, checkURL)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package proxypool_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/proxypool"
+)
+
+// status returns the status of the proxy raw in pool.
+func status(t *testing.T, pool *proxypool.Pool, raw string) proxypool.Status {
+	t.Helper()
+
+	for _, st := range pool.Snapshot() {
+		if st.URL == raw {
+			return st
+		}
+	}
+
+	t.Fatalf("proxy %s not in the pool", raw)
+
+	return proxypool.Status{}
+}
+
+// answering is a plain HTTP proxy answering every request with code.
+func answering(t *testing.T, code int) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func Test_PoolCheckHealth(t *testing.T) {
+	ok := answering(t, http.StatusNoContent)
+	limited := answering(t, http.StatusTooManyRequests)
+
+	down := answering(t, http.StatusNoContent)
+	down.Close()
+
+	pool, err := proxypool.New([]string{ok.URL, limited.URL, down.URL})
+	require.NoError(t, err)
+
+	// the check URL is plain HTTP, so the proxies answer it themselves
+	const checkURL = "http://www.google.com/generate_204"
+
+	for range 3 {
+		pool.CheckHealth(context.Background(), checkURL)
+	}
+
+	require.True(t, status(t, pool, ok.URL).Healthy)
+	require.False(t, status(t, pool, ok.URL).Banned)
+
+	// a proxy Google rate limits is banned, not unhealthy
+	require.True(t, status(t, pool, limited.URL).Healthy)
+	require.True(t, status(t, pool, limited.URL).Banned)
+
+	// an unreachable one is unhealthy after three checks
+	require.False(t, status(t, pool, down.URL).Healthy)
+	require.Equal(t, 3, status(t, pool, down.URL).Failures)
+
+	u, err := pool.Next("")
+	require.NoError(t, err)
+	require.Equal(t, ok.URL, u.String())
+}
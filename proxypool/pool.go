@@ -0,0 +1,487 @@
+package proxypool
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Strategy selects which proxy serves the next connection.
+type Strategy string
+
+const (
+	StrategyRoundRobin Strategy = "round-robin"
+	StrategySticky     Strategy = "sticky"
+	StrategyWeighted   Strategy = "weighted"
+)
+
+const (
+	defaultWeight      = 1
+	defaultBanDuration = 10 * time.Minute
+	maxFailures        = 3
+	maxBanDoublings    = 4
+)
+
+var (
+	ErrNoProxies        = errors.New("no proxies configured")
+	ErrNoHealthyProxies = errors.New("no healthy proxies available")
+	ErrInvalidStrategy  = errors.New("invalid proxy strategy")
+)
+
+// ParseStrategy converts a flag value into a Strategy.
+func ParseStrategy(s string) (Strategy, error) {
+	switch Strategy(strings.ToLower(strings.TrimSpace(s))) {
+	case "", StrategyRoundRobin:
+		return StrategyRoundRobin, nil
+	case StrategySticky:
+		return StrategySticky, nil
+	case StrategyWeighted:
+		return StrategyWeighted, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidStrategy, s)
+	}
+}
+
+type proxy struct {
+	raw         string
+	u           *url.URL
+	weight      int
+	healthy     bool
+	failures    int
+	bannedUntil time.Time
+	bans        int
+	strikes     int
+	served      int64
+	lastChecked time.Time
+}
+
+func (p *proxy) available(now time.Time) bool {
+	return p.healthy && now.After(p.bannedUntil)
+}
+
+// is reports whether s identifies this proxy, either as configured, as
+// returned by Next, or in the redacted form returned by Snapshot.
+func (p *proxy) is(s string) bool {
+	return s == p.raw || s == p.u.String() || s == redact(p.u)
+}
+
+// Status is a point-in-time view of a proxy, used by the admin endpoint.
+type Status struct {
+	URL         string    `json:"url"`
+	Weight      int       `json:"weight"`
+	Healthy     bool      `json:"healthy"`
+	Banned      bool      `json:"banned"`
+	BannedUntil time.Time `json:"banned_until,omitempty"`
+	Bans        int       `json:"bans"`
+	Failures    int       `json:"failures"`
+	Served      int64     `json:"served"`
+	LastChecked time.Time `json:"last_checked,omitempty"`
+}
+
+// Option configures a Pool.
+type Option func(*Pool)
+
+// WithStrategy sets the rotation strategy.
+func WithStrategy(s Strategy) Option {
+	return func(p *Pool) {
+		p.strategy = s
+	}
+}
+
+// WithBanDuration sets how long a proxy is skipped after a ban is detected.
+func WithBanDuration(d time.Duration) Option {
+	return func(p *Pool) {
+		if d > 0 {
+			p.banDuration = d
+		}
+	}
+}
+
+// Pool holds the upstream proxies and hands them out according to a Strategy.
+type Pool struct {
+	mu          sync.Mutex
+	proxies     []*proxy
+	strategy    Strategy
+	banDuration time.Duration
+	next        int
+	sticky      sessions
+}
+
+// New creates a pool from entries in the form "scheme://[user:pass@]host:port[|weight]".
+func New(entries []string, opts ...Option) (*Pool, error) {
+	p := &Pool{
+		strategy:    StrategyRoundRobin,
+		banDuration: defaultBanDuration,
+		sticky:      make(sessions),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	for _, e := range entries {
+		if strings.TrimSpace(e) == "" {
+			continue
+		}
+
+		raw, weight, err := parseEntry(e)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.Add(raw, weight); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+func parseEntry(entry string) (string, int, error) {
+	raw, weightStr, hasWeight := strings.Cut(strings.TrimSpace(entry), "|")
+	if !hasWeight {
+		return raw, defaultWeight, nil
+	}
+
+	weight, err := strconv.Atoi(weightStr)
+	if err != nil || weight < 1 {
+		return "", 0, fmt.Errorf("invalid proxy weight in %q", entry)
+	}
+
+	return raw, weight, nil
+}
+
+// ValidateEntry checks an entry in the form accepted by New without creating
+// a pool.
+func ValidateEntry(entry string) error {
+	raw, _, err := parseEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	_, err = parseURL(raw)
+
+	return err
+}
+
+func parseURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url %q: %w", raw, err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy url %q has no host", raw)
+	}
+
+	return u, nil
+}
+
+// Add registers a new upstream proxy. Adding an existing proxy updates its weight.
+func (p *Pool) Add(raw string, weight int) error {
+	u, err := parseURL(raw)
+	if err != nil {
+		return err
+	}
+
+	if weight < 1 {
+		weight = defaultWeight
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, px := range p.proxies {
+		if px.raw == raw {
+			px.weight = weight
+			return nil
+		}
+	}
+
+	p.proxies = append(p.proxies, &proxy{
+		raw:     raw,
+		u:       u,
+		weight:  weight,
+		healthy: true,
+	})
+
+	return nil
+}
+
+// Remove deletes a proxy from the pool. The proxy can be identified either by
+// its full URL or by the redacted form returned by Snapshot.
+// It reports whether the proxy existed.
+func (p *Pool) Remove(raw string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, px := range p.proxies {
+		if !px.is(raw) {
+			continue
+		}
+
+		p.proxies = append(p.proxies[:i], p.proxies[i+1:]...)
+		p.sticky.drop(px.raw)
+
+		return true
+	}
+
+	return false
+}
+
+// Sessions returns the number of sessions the sticky strategy remembers.
+func (p *Pool) Sessions() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.sticky)
+}
+
+// Len returns the number of proxies in the pool.
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.proxies)
+}
+
+// Next returns the proxy that should serve a connection for the given key.
+// The key is only used by the sticky strategy.
+func (p *Pool) Next(key string) (*url.URL, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.proxies) == 0 {
+		return nil, ErrNoProxies
+	}
+
+	now := time.Now()
+
+	var px *proxy
+
+	switch p.strategy {
+	case StrategySticky:
+		px = p.pickSticky(key, now)
+	case StrategyWeighted:
+		px = p.pickWeighted(now)
+	default:
+		px = p.pickRoundRobin(now)
+	}
+
+	if px == nil {
+		return nil, ErrNoHealthyProxies
+	}
+
+	px.served++
+
+	u := *px.u
+
+	return &u, nil
+}
+
+func (p *Pool) pickRoundRobin(now time.Time) *proxy {
+	for range p.proxies {
+		px := p.proxies[p.next%len(p.proxies)]
+		p.next++
+
+		if px.available(now) {
+			return px
+		}
+	}
+
+	return nil
+}
+
+func (p *Pool) pickWeighted(now time.Time) *proxy {
+	total := 0
+
+	for _, px := range p.proxies {
+		if px.available(now) {
+			total += px.weight
+		}
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	//nolint:gosec // proxy selection does not need a cryptographic source
+	n := rand.IntN(total)
+
+	for _, px := range p.proxies {
+		if !px.available(now) {
+			continue
+		}
+
+		if n < px.weight {
+			return px
+		}
+
+		n -= px.weight
+	}
+
+	return nil
+}
+
+func (p *Pool) pickSticky(key string, now time.Time) *proxy {
+	if raw, ok := p.sticky.get(key, now); ok {
+		for _, px := range p.proxies {
+			if px.raw == raw && px.available(now) {
+				return px
+			}
+		}
+	}
+
+	available := make([]*proxy, 0, len(p.proxies))
+
+	for _, px := range p.proxies {
+		if px.available(now) {
+			available = append(available, px)
+		}
+	}
+
+	if len(available) == 0 {
+		return nil
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+
+	px := available[int(h.Sum32()%uint32(len(available)))]
+	p.sticky.set(key, px.raw, now)
+
+	return px
+}
+
+// Ban takes a proxy out of rotation. The ban duration doubles with every
+// consecutive ban (up to 2^maxBanDoublings times the configured duration)
+// and sticky sessions pinned to the proxy are moved to another one.
+func (p *Pool) Ban(raw string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, px := range p.proxies {
+		if !px.is(raw) {
+			continue
+		}
+
+		px.bannedUntil = time.Now().Add(p.banDuration << min(px.strikes, maxBanDoublings))
+		px.strikes++
+		px.bans++
+
+		p.sticky.drop(px.raw)
+	}
+}
+
+// ReportSuccess marks a proxy as healthy again.
+func (p *Pool) ReportSuccess(raw string) {
+	p.setHealth(raw, true)
+}
+
+// ReportFailure records a failed request; after maxFailures in a row the proxy is marked unhealthy.
+func (p *Pool) ReportFailure(raw string) {
+	p.setHealth(raw, false)
+}
+
+func (p *Pool) setHealth(raw string, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, px := range p.proxies {
+		if !px.is(raw) {
+			continue
+		}
+
+		px.lastChecked = time.Now()
+
+		if ok {
+			px.failures = 0
+			px.healthy = true
+
+			// a proxy that stayed clean for a full ban period starts over
+			if px.strikes > 0 && time.Since(px.bannedUntil) > p.banDuration {
+				px.strikes = 0
+			}
+
+			continue
+		}
+
+		px.failures++
+		if px.failures >= maxFailures {
+			px.healthy = false
+		}
+	}
+}
+
+// Snapshot returns the status of every proxy, sorted by URL.
+func (p *Pool) Snapshot() []Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	ans := make([]Status, 0, len(p.proxies))
+
+	for _, px := range p.proxies {
+		st := Status{
+			URL:         redact(px.u),
+			Weight:      px.weight,
+			Healthy:     px.healthy,
+			Banned:      now.Before(px.bannedUntil),
+			Bans:        px.bans,
+			Failures:    px.failures,
+			Served:      px.served,
+			LastChecked: px.lastChecked,
+		}
+
+		if st.Banned {
+			st.BannedUntil = px.bannedUntil
+		}
+
+		ans = append(ans, st)
+	}
+
+	sort.Slice(ans, func(i, j int) bool {
+		return ans[i].URL < ans[j].URL
+	})
+
+	return ans
+}
+
+func (p *Pool) urls() []*url.URL {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ans := make([]*url.URL, len(p.proxies))
+
+	for i, px := range p.proxies {
+		u := *px.u
+		ans[i] = &u
+	}
+
+	return ans
+}
+
+// redact hides proxy credentials so they never leak through the admin endpoint.
+func redact(u *url.URL) string {
+	if u.User == nil {
+		return u.String()
+	}
+
+	c := *u
+	c.User = url.User(u.User.Username())
+
+	return c.String()
+}
@@ -0,0 +1,92 @@
+package proxypool_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/proxypool"
+)
+
+// next returns the hosts of the n next proxies handed out for key.
+func next(t *testing.T, pool *proxypool.Pool, key string, n int) []string {
+	t.Helper()
+
+	var ans []string
+
+	for range n {
+		u, err := pool.Next(key)
+		require.NoError(t, err)
+
+		ans = append(ans, u.Host)
+	}
+
+	return ans
+}
+
+func Test_PoolRoundRobin(t *testing.T) {
+	pool, err := proxypool.New([]string{"http://a:1", "http://b:1", "http://c:1"})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"a:1", "b:1", "c:1", "a:1"}, next(t, pool, "", 4))
+
+	// a banned proxy is skipped until its ban is over
+	pool.Ban("http://b:1")
+	require.Equal(t, []string{"c:1", "a:1", "c:1"}, next(t, pool, "", 3))
+
+	pool.Ban("http://a:1")
+	pool.Ban("http://c:1")
+
+	_, err = pool.Next("")
+	require.ErrorIs(t, err, proxypool.ErrNoHealthyProxies)
+}
+
+func Test_PoolWeighted(t *testing.T) {
+	pool, err := proxypool.New([]string{"http://a:1|5", "http://b:1"}, proxypool.WithStrategy(proxypool.StrategyWeighted))
+	require.NoError(t, err)
+
+	pool.Ban("http://a:1")
+
+	for _, host := range next(t, pool, "", 20) {
+		require.Equal(t, "b:1", host)
+	}
+}
+
+func Test_PoolStickyKeepsTheProxyOfASession(t *testing.T) {
+	pool, err := proxypool.New([]string{"http://a:1", "http://b:1", "http://c:1"}, proxypool.WithStrategy(proxypool.StrategySticky))
+	require.NoError(t, err)
+
+	hosts := next(t, pool, "job-1", 5)
+	for _, host := range hosts {
+		require.Equal(t, hosts[0], host)
+	}
+
+	// a ban moves the session to another proxy, where it stays
+	pool.Ban("http://" + hosts[0])
+
+	moved := next(t, pool, "job-1", 5)
+	require.NotEqual(t, hosts[0], moved[0])
+
+	for _, host := range moved {
+		require.Equal(t, moved[0], host)
+	}
+
+	require.Equal(t, 1, pool.Sessions())
+}
+
+func Test_PoolForgetsTheOldestSessions(t *testing.T) {
+	pool, err := proxypool.New([]string{"http://a:1", "http://b:1"}, proxypool.WithStrategy(proxypool.StrategySticky))
+	require.NoError(t, err)
+
+	for i := range proxypool.MaxSessions + 100 {
+		_, err := pool.Next(fmt.Sprintf("job-%d", i))
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, proxypool.MaxSessions, pool.Sessions())
+
+	// removing a proxy forgets the sessions pinned to it
+	require.True(t, pool.Remove("http://a:1"))
+	require.Less(t, pool.Sessions(), proxypool.MaxSessions)
+}
@@ -0,0 +1,90 @@
+package proxypool
+
+import "time"
+
+const (
+	// MaxSessions is how many sessions the pool and the forwarder remember
+	// at most; the least recently used are forgotten first.
+	MaxSessions = 4096
+	// sessionTTL is how long a session is remembered after its last use.
+	sessionTTL = 30 * time.Minute
+)
+
+type session struct {
+	raw  string
+	used time.Time
+}
+
+// sessions maps session keys to the proxy they use. It forgets the sessions
+// unused for sessionTTL and the least recently used beyond MaxSessions, so
+// that the keys of jobs long gone do not pile up. It is not safe for
+// concurrent use.
+type sessions map[string]session
+
+// get returns the proxy of key and refreshes it.
+func (s sessions) get(key string, now time.Time) (string, bool) {
+	e, ok := s[key]
+	if !ok {
+		return "", false
+	}
+
+	if now.Sub(e.used) > sessionTTL {
+		delete(s, key)
+		return "", false
+	}
+
+	e.used = now
+	s[key] = e
+
+	return e.raw, true
+}
+
+// take returns the proxy of key and forgets it.
+func (s sessions) take(key string, now time.Time) (string, bool) {
+	raw, ok := s.get(key, now)
+	delete(s, key)
+
+	return raw, ok
+}
+
+func (s sessions) set(key, raw string, now time.Time) {
+	s[key] = session{raw: raw, used: now}
+
+	if len(s) > MaxSessions {
+		s.prune(now)
+	}
+}
+
+// prune forgets the expired sessions, then the least recently used one while
+// there are too many.
+func (s sessions) prune(now time.Time) {
+	for k, e := range s {
+		if now.Sub(e.used) > sessionTTL {
+			delete(s, k)
+		}
+	}
+
+	for len(s) > MaxSessions {
+		var (
+			oldest string
+			at     time.Time
+		)
+
+		for k, e := range s {
+			if oldest == "" || e.used.Before(at) {
+				oldest, at = k, e.used
+			}
+		}
+
+		delete(s, oldest)
+	}
+}
+
+// drop forgets the sessions using the proxy raw.
+func (s sessions) drop(raw string) {
+	for k, e := range s {
+		if e.raw == raw {
+			delete(s, k)
+		}
+	}
+}
@@ -0,0 +1,532 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix is prepended to the upper-cased flag name to get the environment
+// variable overriding it, e.g. GMAPS_PROXY_STRATEGY for -proxy-strategy.
+const envPrefix = "GMAPS_"
+
+// envAliases names the environment variable of flags whose name is too short
+// to be meaningful on its own.
+var envAliases = map[string]string{
+	"c": "concurrency",
+}
+
+// FileConfig is the schema of the file given with -config. Every value is
+// optional: a missing key keeps the flag default.
+//
+// Values are applied in this order, each one overriding the previous:
+// flag defaults, the config file, environment variables, command line flags.
+type FileConfig struct {
+	Dsn                string              `yaml:"dsn" toml:"dsn"`
+	Concurrency        *int                `yaml:"concurrency" toml:"concurrency"`
+	FetchBatchSize     *int                `yaml:"fetch_batch_size" toml:"fetch_batch_size"`
+	Depth              *int                `yaml:"depth" toml:"depth"`
+	MaxResults         *int                `yaml:"max_results" toml:"max_results"`
+	RelaxEmptySearches *bool               `yaml:"relax_empty_searches" toml:"relax_empty_searches"`
+	Track              string              `yaml:"track" toml:"track"`
+	Input              string              `yaml:"input" toml:"input"`
+	Lang               string              `yaml:"lang" toml:"lang"`
+	Country            string              `yaml:"country" toml:"country"`
+	GoogleDomain       string              `yaml:"google_domain" toml:"google_domain"`
+	OwnerID            string              `yaml:"owner_id" toml:"owner_id"`
+	OrganizationID     string              `yaml:"organization_id" toml:"organization_id"`
+	Produce            *bool               `yaml:"produce" toml:"produce"`
+	DryRun             *bool               `yaml:"dry_run" toml:"dry_run"`
+	Reenrich           *bool               `yaml:"reenrich" toml:"reenrich"`
+	Backfill           *bool               `yaml:"backfill" toml:"backfill"`
+	Since              string              `yaml:"since" toml:"since"`
+	Debug              *bool               `yaml:"debug" toml:"debug"`
+	ExitOnInactivity   string              `yaml:"exit_on_inactivity" toml:"exit_on_inactivity"`
+	ExitOnComplete     *bool               `yaml:"exit_on_complete" toml:"exit_on_complete"`
+	ExitOnJob          string              `yaml:"exit_on_job" toml:"exit_on_job"`
+	Email              *bool               `yaml:"email" toml:"email"`
+	Bodacc             *bool               `yaml:"bodacc" toml:"bodacc"`
+	ExtraReviews       *bool               `yaml:"extra_reviews" toml:"extra_reviews"`
+	Geo                string              `yaml:"geo" toml:"geo"`
+	Zoom               *int                `yaml:"zoom" toml:"zoom"`
+	Radius             *float64            `yaml:"radius" toml:"radius"`
+	FastMode           *bool               `yaml:"fast_mode" toml:"fast_mode"`
+	PlaceCacheTTL      string              `yaml:"place_cache_ttl" toml:"place_cache_ttl"`
+	PlaceArchive       string              `yaml:"place_archive" toml:"place_archive"`
+	ArchiveJobsAfter   string              `yaml:"archive_jobs_after" toml:"archive_jobs_after"`
+	ResultSinks        []string            `yaml:"result_sinks" toml:"result_sinks"`
+	SinkBuffer         *int                `yaml:"sink_buffer" toml:"sink_buffer"`
+	Retention          RetentionFileConfig `yaml:"retention" toml:"retention"`
+	ReconcileInterval  string              `yaml:"reconcile_interval" toml:"reconcile_interval"`
+	CompanyCacheTTL    string              `yaml:"company_cache_ttl" toml:"company_cache_ttl"`
+	LenientDecode      *bool               `yaml:"lenient_decode" toml:"lenient_decode"`
+	MemoryLimitMB      *int                `yaml:"memory_limit_mb" toml:"memory_limit_mb"`
+	ResultBuffer       *int                `yaml:"result_buffer" toml:"result_buffer"`
+	SpoolDir           string              `yaml:"spool_dir" toml:"spool_dir"`
+	ReverseGeocode     *bool               `yaml:"reverse_geocode" toml:"reverse_geocode"`
+	BANAddressParsing  *bool               `yaml:"ban_address_parsing" toml:"ban_address_parsing"`
+	MapCategories      *bool               `yaml:"map_categories" toml:"map_categories"`
+	CategoryTaxonomy   string              `yaml:"category_taxonomy" toml:"category_taxonomy"`
+	ReconcileCompanies *bool               `yaml:"reconcile_companies" toml:"reconcile_companies"`
+	MonitorChanges     *bool               `yaml:"monitor_changes" toml:"monitor_changes"`
+	SocialProfiles     *bool               `yaml:"social_profiles" toml:"social_profiles"`
+	SkipSABEnrichment  *bool               `yaml:"skip_sab_enrichment" toml:"skip_sab_enrichment"`
+	RegisterRetries    *int                `yaml:"register_retries" toml:"register_retries"`
+	RegisterRetryDelay string              `yaml:"register_retry_delay" toml:"register_retry_delay"`
+	AdminAddr          string              `yaml:"admin_addr" toml:"admin_addr"`
+	TUI                *bool               `yaml:"tui" toml:"tui"`
+	GRPCAddr           string              `yaml:"grpc_addr" toml:"grpc_addr"`
+	APIAddr            string              `yaml:"api_addr" toml:"api_addr"`
+	APIInsecure        *bool               `yaml:"api_insecure" toml:"api_insecure"`
+	SecretsRefresh     string              `yaml:"secrets_refresh" toml:"secrets_refresh"`
+	ReviewMinScore     *float64            `yaml:"review_min_score" toml:"review_min_score"`
+	ReviewMaxScore     *float64            `yaml:"review_max_score" toml:"review_max_score"`
+
+	Browser    BrowserFileConfig    `yaml:"browser" toml:"browser"`
+	Proxy      ProxyFileConfig      `yaml:"proxy" toml:"proxy"`
+	API        APIFileConfig        `yaml:"api" toml:"api"`
+	Insee      InseeFileConfig      `yaml:"insee" toml:"insee"`
+	Inpi       InpiFileConfig       `yaml:"inpi" toml:"inpi"`
+	Enrichment EnrichmentFileConfig `yaml:"enrichment" toml:"enrichment"`
+	Export     ExportFileConfig     `yaml:"export" toml:"export"`
+	Report     ReportFileConfig     `yaml:"report" toml:"report"`
+	Webhook    WebhookFileConfig    `yaml:"webhook" toml:"webhook"`
+	Notify     NotifyFileConfig     `yaml:"notify" toml:"notify"`
+	Scheduling SchedulingFileConfig `yaml:"scheduling" toml:"scheduling"`
+	Privacy    PrivacyFileConfig    `yaml:"privacy" toml:"privacy"`
+}
+
+// EnrichmentFileConfig switches enrichment jobs off for the whole worker,
+// whatever the scraped jobs asked for. It is re-read on SIGHUP.
+type EnrichmentFileConfig struct {
+	Email  *bool `yaml:"email" toml:"email"`
+	Bodacc *bool `yaml:"bodacc" toml:"bodacc"`
+}
+
+type ExportFileConfig struct {
+	Dir     string `yaml:"dir" toml:"dir"`
+	BaseURL string `yaml:"base_url" toml:"base_url"`
+}
+
+type ReportFileConfig struct {
+	Dir     string `yaml:"dir" toml:"dir"`
+	BaseURL string `yaml:"base_url" toml:"base_url"`
+	Format  string `yaml:"format" toml:"format"`
+}
+
+type WebhookFileConfig struct {
+	URL       string `yaml:"url" toml:"url"`
+	BatchSize *int   `yaml:"batch_size" toml:"batch_size"`
+}
+
+// NotifyFileConfig emails the owners of root jobs when they complete or fail,
+// and posts their lifecycle to a chat.
+type NotifyFileConfig struct {
+	SMTPAddr     string `yaml:"smtp_addr" toml:"smtp_addr"`
+	SMTPUsername string `yaml:"smtp_username" toml:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password" toml:"smtp_password"`
+	From         string `yaml:"from" toml:"from"`
+	Template     string `yaml:"template" toml:"template"`
+	// ChatWebhookURL is the Slack or Discord webhook of jobs whose metadata
+	// gives none.
+	ChatWebhookURL string `yaml:"chat_webhook_url" toml:"chat_webhook_url"`
+}
+
+// SchedulingFileConfig sets how jobs are shared between tenants. Weights are
+// keyed by organization id, or owner id for jobs without an organization.
+type SchedulingFileConfig struct {
+	Fair               *bool              `yaml:"fair" toml:"fair"`
+	TenantWeights      map[string]float64 `yaml:"tenant_weights" toml:"tenant_weights"`
+	OwnerPlacesPerHour *int               `yaml:"owner_places_per_hour" toml:"owner_places_per_hour"`
+}
+
+// PrivacyFileConfig redacts the personal data of the results: emails and
+// directors take keep, hash or drop, for every organization and, under
+// organizations, for some of them.
+type PrivacyFileConfig struct {
+	Emails        string                       `yaml:"emails" toml:"emails"`
+	Directors     string                       `yaml:"directors" toml:"directors"`
+	Organizations map[string]PrivacyFileConfig `yaml:"organizations" toml:"organizations"`
+}
+
+// RetentionFileConfig sets how long results are kept, e.g. 365d: Default
+// for every organization and Organizations for those with their own.
+type RetentionFileConfig struct {
+	Default       string            `yaml:"default" toml:"default"`
+	Organizations map[string]string `yaml:"organizations" toml:"organizations"`
+}
+
+type BrowserFileConfig struct {
+	DisablePageReuse   *bool    `yaml:"disable_page_reuse" toml:"disable_page_reuse"`
+	DisableFingerprint *bool    `yaml:"disable_fingerprint" toml:"disable_fingerprint"`
+	SessionFile        string   `yaml:"session_file" toml:"session_file"`
+	BlockResources     []string `yaml:"block_resources" toml:"block_resources"`
+	BlockDomains       []string `yaml:"block_domains" toml:"block_domains"`
+	FixturesDir        string   `yaml:"fixtures_dir" toml:"fixtures_dir"`
+	RecordFixtures     *bool    `yaml:"record_fixtures" toml:"record_fixtures"`
+}
+
+type ProxyFileConfig struct {
+	List           []string `yaml:"list" toml:"list"`
+	Strategy       string   `yaml:"strategy" toml:"strategy"`
+	HealthInterval string   `yaml:"health_interval" toml:"health_interval"`
+	CheckURL       string   `yaml:"check_url" toml:"check_url"`
+}
+
+type APIFileConfig struct {
+	RevalidationURL  string `yaml:"revalidation_url" toml:"revalidation_url"`
+	JobCompletionURL string `yaml:"job_completion_url" toml:"job_completion_url"`
+	// RevalidationInterval is the -revalidation-interval duration, e.g. 30s.
+	RevalidationInterval string `yaml:"revalidation_interval" toml:"revalidation_interval"`
+	WebhookSecret        string `yaml:"webhook_secret" toml:"webhook_secret"`
+	// Keys are the role:organization:key entries of API_KEYS, and
+	// JWTSecret the API_JWT_SECRET the tokens are signed with.
+	Keys      []string `yaml:"keys" toml:"keys"`
+	JWTSecret string   `yaml:"jwt_secret" toml:"jwt_secret"`
+}
+
+type InseeFileConfig struct {
+	APIKey string `yaml:"api_key" toml:"api_key"`
+	// ConsumerKey and ConsumerSecret authenticate accounts without an API
+	// key.
+	ConsumerKey    string `yaml:"consumer_key" toml:"consumer_key"`
+	ConsumerSecret string `yaml:"consumer_secret" toml:"consumer_secret"`
+}
+
+type InpiFileConfig struct {
+	Username string `yaml:"username" toml:"username"`
+	Password string `yaml:"password" toml:"password"`
+	UseDemo  *bool  `yaml:"use_demo" toml:"use_demo"`
+}
+
+// LoadConfigFile reads a YAML (.yaml, .yml) or TOML (.toml) config file.
+// Unknown keys are rejected so typos do not go unnoticed.
+func LoadConfigFile(path string) (*FileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fc FileConfig
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+
+		if err := dec.Decode(&fc); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), &fc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("invalid config file %s: unknown key %s", path, undecoded[0])
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file format: %s", path)
+	}
+
+	return &fc, nil
+}
+
+// FlagValues returns the values set in the file, keyed by flag name.
+func (fc *FileConfig) FlagValues() map[string]string {
+	ans := map[string]string{}
+
+	setString := func(name, v string) {
+		if v != "" {
+			ans[name] = v
+		}
+	}
+
+	setBool := func(name string, v *bool) {
+		if v != nil {
+			ans[name] = strconv.FormatBool(*v)
+		}
+	}
+
+	setInt := func(name string, v *int) {
+		if v != nil {
+			ans[name] = strconv.Itoa(*v)
+		}
+	}
+
+	setFloat := func(name string, v *float64) {
+		if v != nil {
+			ans[name] = strconv.FormatFloat(*v, 'f', -1, 64)
+		}
+	}
+
+	// a list present but empty is meaningful: it disables the defaults
+	setList := func(name string, v []string) {
+		if v != nil {
+			ans[name] = strings.Join(v, ",")
+		}
+	}
+
+	setString("dsn", fc.Dsn)
+	setInt("c", fc.Concurrency)
+	setInt("fetch-batch-size", fc.FetchBatchSize)
+	setInt("depth", fc.Depth)
+	setInt("max-results", fc.MaxResults)
+	setBool("relax-empty-searches", fc.RelaxEmptySearches)
+	setString("track", fc.Track)
+	setString("input", fc.Input)
+	setString("lang", fc.Lang)
+	setString("country", fc.Country)
+	setString("google-domain", fc.GoogleDomain)
+	setString("owner-id", fc.OwnerID)
+	setString("organization-id", fc.OrganizationID)
+	setBool("produce", fc.Produce)
+	setBool("dry-run", fc.DryRun)
+	setBool("reenrich", fc.Reenrich)
+	setBool("backfill", fc.Backfill)
+	setString("since", fc.Since)
+	setBool("debug", fc.Debug)
+	setString("exit-on-inactivity", fc.ExitOnInactivity)
+	setBool("exit-on-complete", fc.ExitOnComplete)
+	setString("exit-on-job", fc.ExitOnJob)
+	setBool("email", fc.Email)
+	setBool("bodacc", fc.Bodacc)
+	setBool("extra-reviews", fc.ExtraReviews)
+	setString("geo", fc.Geo)
+	setInt("zoom", fc.Zoom)
+	setBool("fast-mode", fc.FastMode)
+	setString("place-cache-ttl", fc.PlaceCacheTTL)
+	setString("place-archive", fc.PlaceArchive)
+	setString("archive-jobs-after", fc.ArchiveJobsAfter)
+	setString("reconcile-interval", fc.ReconcileInterval)
+	setString("company-cache-ttl", fc.CompanyCacheTTL)
+	setBool("lenient-decode", fc.LenientDecode)
+	setInt("memory-limit-mb", fc.MemoryLimitMB)
+	setInt("result-buffer", fc.ResultBuffer)
+	setString("spool-dir", fc.SpoolDir)
+	setBool("reverse-geocode", fc.ReverseGeocode)
+	setBool("ban-address-parsing", fc.BANAddressParsing)
+	setBool("map-categories", fc.MapCategories)
+	setString("category-taxonomy", fc.CategoryTaxonomy)
+	setBool("reconcile-companies", fc.ReconcileCompanies)
+	setBool("monitor-changes", fc.MonitorChanges)
+	setBool("social-profiles", fc.SocialProfiles)
+	setBool("skip-sab-enrichment", fc.SkipSABEnrichment)
+	setInt("register-retries", fc.RegisterRetries)
+	setString("register-retry-delay", fc.RegisterRetryDelay)
+	setString("admin-addr", fc.AdminAddr)
+	setBool("tui", fc.TUI)
+	setString("grpc-addr", fc.GRPCAddr)
+	setString("api-addr", fc.APIAddr)
+	setBool("api-insecure", fc.APIInsecure)
+	setString("secrets-refresh", fc.SecretsRefresh)
+
+	setFloat("radius", fc.Radius)
+	setFloat("review-min-score", fc.ReviewMinScore)
+	setFloat("review-max-score", fc.ReviewMaxScore)
+
+	setBool("disable-page-reuse", fc.Browser.DisablePageReuse)
+	setBool("disable-fingerprint", fc.Browser.DisableFingerprint)
+	setString("session-file", fc.Browser.SessionFile)
+	setList("block-resources", fc.Browser.BlockResources)
+	setList("block-domains", fc.Browser.BlockDomains)
+	setString("fixtures-dir", fc.Browser.FixturesDir)
+	setBool("record-fixtures", fc.Browser.RecordFixtures)
+
+	setList("proxies", fc.Proxy.List)
+	setString("proxy-strategy", fc.Proxy.Strategy)
+	setString("proxy-health-interval", fc.Proxy.HealthInterval)
+	setString("proxy-check-url", fc.Proxy.CheckURL)
+
+	setString("revalidation-api", fc.API.RevalidationURL)
+	setString("job-completion-api", fc.API.JobCompletionURL)
+	setString("revalidation-interval", fc.API.RevalidationInterval)
+
+	setString("export-dir", fc.Export.Dir)
+	setString("export-base-url", fc.Export.BaseURL)
+
+	setString("report-dir", fc.Report.Dir)
+	setString("report-base-url", fc.Report.BaseURL)
+	setString("report-format", fc.Report.Format)
+
+	setString("webhook-url", fc.Webhook.URL)
+	setInt("webhook-batch-size", fc.Webhook.BatchSize)
+
+	setList("result-sinks", fc.ResultSinks)
+	setInt("sink-buffer", fc.SinkBuffer)
+
+	setString("smtp-addr", fc.Notify.SMTPAddr)
+	setString("smtp-username", fc.Notify.SMTPUsername)
+	setString("smtp-from", fc.Notify.From)
+	setString("notify-template", fc.Notify.Template)
+	setString("chat-webhook-url", fc.Notify.ChatWebhookURL)
+
+	setBool("fair-scheduling", fc.Scheduling.Fair)
+	setInt("owner-places-per-hour", fc.Scheduling.OwnerPlacesPerHour)
+
+	if fc.Scheduling.TenantWeights != nil {
+		weights := make([]string, 0, len(fc.Scheduling.TenantWeights))
+		for id, w := range fc.Scheduling.TenantWeights {
+			weights = append(weights, id+"="+strconv.FormatFloat(w, 'f', -1, 64))
+		}
+
+		sort.Strings(weights)
+		ans["tenant-weights"] = strings.Join(weights, ",")
+	}
+
+	if fc.Retention.Default != "" || fc.Retention.Organizations != nil {
+		var ages []string
+		if fc.Retention.Default != "" {
+			ages = append(ages, fc.Retention.Default)
+		}
+
+		orgs := make([]string, 0, len(fc.Retention.Organizations))
+		for id, age := range fc.Retention.Organizations {
+			orgs = append(orgs, id+"="+age)
+		}
+
+		sort.Strings(orgs)
+		ans["retention"] = strings.Join(append(ages, orgs...), ",")
+	}
+
+	if rules := fc.Privacy.rules(""); len(rules) > 0 {
+		ans["pii-policy"] = strings.Join(rules, ",")
+	}
+
+	return ans
+}
+
+// rules returns the -pii-policy entries of c for the organization prefix,
+// those of the organizations sorted.
+func (c PrivacyFileConfig) rules(prefix string) []string {
+	var rules []string
+
+	if c.Emails != "" {
+		rules = append(rules, prefix+"emails="+c.Emails)
+	}
+
+	if c.Directors != "" {
+		rules = append(rules, prefix+"directors="+c.Directors)
+	}
+
+	orgs := make([]string, 0, len(c.Organizations))
+	for id := range c.Organizations {
+		orgs = append(orgs, id)
+	}
+
+	sort.Strings(orgs)
+
+	for _, id := range orgs {
+		rules = append(rules, c.Organizations[id].rules(id+"/")...)
+	}
+
+	return rules
+}
+
+// exportCredentials makes the credentials of the file visible to the
+// packages reading them from the environment. Variables already set win.
+func (fc *FileConfig) exportCredentials() error {
+	vars := map[string]string{
+		"INSEE_API_KEY":         fc.Insee.APIKey,
+		"INSEE_CONSUMER_KEY":    fc.Insee.ConsumerKey,
+		"INSEE_CONSUMER_SECRET": fc.Insee.ConsumerSecret,
+		"INPI_USERNAME":         fc.Inpi.Username,
+		"INPI_PASSWORD":         fc.Inpi.Password,
+		"WEBHOOK_SECRET":        fc.API.WebhookSecret,
+		"SMTP_PASSWORD":         fc.Notify.SMTPPassword,
+		"API_KEYS":              strings.Join(fc.API.Keys, ","),
+		"API_JWT_SECRET":        fc.API.JWTSecret,
+	}
+
+	if fc.Inpi.UseDemo != nil {
+		vars["INPI_USE_DEMO"] = strconv.FormatBool(*fc.Inpi.UseDemo)
+	}
+
+	for k, v := range vars {
+		if v == "" {
+			continue
+		}
+
+		if _, ok := os.LookupEnv(k); ok {
+			continue
+		}
+
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// envName returns the environment variable overriding the flag name.
+func envName(name string) string {
+	if alias, ok := envAliases[name]; ok {
+		name = alias
+	}
+
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyConfigSources sets the flags that were not given on the command line
+// from the environment, then from the config file at path (if any). It
+// returns the flags the config file could not set: those given on the
+// command line or in the environment.
+func applyConfigSources(fs *flag.FlagSet, path string) (map[string]bool, error) {
+	explicit := map[string]bool{}
+
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	var fileValues map[string]string
+
+	if path != "" {
+		fc, err := LoadConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := fc.exportCredentials(); err != nil {
+			return nil, err
+		}
+
+		fileValues = fc.FlagValues()
+	}
+
+	var errs []error
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if explicit[f.Name] || f.Name == "config" || f.Name == "secrets" {
+			return
+		}
+
+		v, ok := os.LookupEnv(envName(f.Name))
+		if ok {
+			explicit[f.Name] = true
+		} else {
+			v, ok = fileValues[f.Name]
+		}
+
+		if !ok {
+			return
+		}
+
+		if err := fs.Set(f.Name, v); err != nil {
+			errs = append(errs, fmt.Errorf("-%s: invalid value %q: %w", f.Name, v, err))
+		}
+	})
+
+	return explicit, errors.Join(errs...)
+}
@@ -3,78 +3,515 @@ package databaserunner
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
-	// postgres driver
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 
+	"github.com/gosom/google-maps-scraper/apiauth"
+	"github.com/gosom/google-maps-scraper/browserpool"
+	"github.com/gosom/google-maps-scraper/categories"
+	"github.com/gosom/google-maps-scraper/dashboard"
+	"github.com/gosom/google-maps-scraper/entreprise"
+	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/graphqlapi"
+	"github.com/gosom/google-maps-scraper/grpcapi"
 	"github.com/gosom/google-maps-scraper/postgres"
+	"github.com/gosom/google-maps-scraper/privacy"
+	"github.com/gosom/google-maps-scraper/proxypool"
 	"github.com/gosom/google-maps-scraper/runner"
+	"github.com/gosom/google-maps-scraper/s3archive"
+	"github.com/gosom/google-maps-scraper/sinks"
+	"github.com/gosom/google-maps-scraper/webhook"
 	"github.com/gosom/scrapemate"
+	"github.com/gosom/scrapemate/adapters/fetchers/jshttp"
+	"github.com/gosom/scrapemate/adapters/proxy"
 	"github.com/gosom/scrapemate/scrapemateapp"
+	"google.golang.org/grpc"
+)
+
+const (
+	// drainTimeout bounds the wait for background database updates when the
+	// runner exits on its own.
+	drainTimeout = time.Minute
+	// completionInterval is how often the pending jobs are counted with
+	// -exit-on-complete and -exit-on-job, besides when a root job is done.
+	completionInterval = 30 * time.Second
 )
 
 type dbrunner struct {
-	cfg      *runner.Config
-	provider scrapemate.JobProvider
-	produce  bool
-	app      *scrapemateapp.ScrapemateApp
-	conn     *sql.DB
+	cfg         *runner.Config
+	provider    scrapemate.JobProvider
+	produce     bool
+	reenricher  *postgres.Reenricher
+	credentials *postgres.CredentialStore
+	registers   *entreprise.Services
+	cipher      *postgres.FieldCipher
+	app         app
+	conn        *sql.DB
+	proxyPool   *proxypool.Pool
+	browserPool *browserpool.Pool
+	tuning      *postgres.Tuning
+	activity    *exiter.InactivityMonitor
+	memory      *postgres.MemoryWatchdog
+	results     *postgres.ResultBuffer
+	spool       *postgres.Spool
+	archiver    *postgres.Archiver
+	reconciler  *postgres.Reconciler
+	janitor     *postgres.Janitor
+	sinks       []*sinks.Sink
+	auth        *apiauth.Authenticator
+	completion  *exiter.CompletionMonitor
+	forwarder   *proxypool.Forwarder
+	admin       *http.ServeMux
+	adminSrv    *http.Server
+	apiSrv      *http.Server
+	grpcSrv     *grpc.Server
 }
 
 func New(cfg *runner.Config) (runner.Runner, error) {
-	if cfg.RunMode != runner.RunModeDatabase && cfg.RunMode != runner.RunModeDatabaseProduce {
+	if cfg.RunMode != runner.RunModeDatabase && cfg.RunMode != runner.RunModeDatabaseProduce &&
+		cfg.RunMode != runner.RunModeDatabaseReenrich && cfg.RunMode != runner.RunModeDatabaseCredentials &&
+		cfg.RunMode != runner.RunModeDatabaseForget && cfg.RunMode != runner.RunModeDatabaseAdmin {
 		return nil, fmt.Errorf("%w: %d", runner.ErrInvalidRunMode, cfg.RunMode)
 	}
 
-	conn, err := openPsqlConn(cfg.Dsn)
+	conn, err := openPsqlConn(cfg)
 	if err != nil {
 		return nil, err
 	}
 
 	ans := dbrunner{
-		cfg:      cfg,
-		provider: postgres.NewProvider(conn, cfg.RevalidationAPIURL, cfg.JobCompletionAPIURL),
-		produce:  cfg.ProduceOnly,
-		conn:     conn,
+		cfg:     cfg,
+		produce: cfg.RunMode == runner.RunModeDatabaseProduce,
+		conn:    conn,
 	}
 
 	if ans.produce {
+		ans.provider = postgres.NewProvider(conn, cfg.RevalidationAPIURL, cfg.JobCompletionAPIURL)
+
+		return &ans, nil
+	}
+
+	if cfg.RunMode == runner.RunModeDatabaseForget {
+		return &ans, nil
+	}
+
+	if cfg.RunMode == runner.RunModeDatabaseAdmin {
+		// the results are decrypted for the export
+		ans.cipher, err = newFieldCipher()
+		if err != nil {
+			return nil, err
+		}
+
+		return &ans, nil
+	}
+
+	ans.credentials, err = newCredentialStore(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	ans.cipher, err = newFieldCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	ans.auth, err = apiauth.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	if ans.auth == nil && (cfg.APIAddr != "" || cfg.GRPCAddr != "" || cfg.AdminAddr != "") {
+		if !cfg.APIInsecure {
+			return nil, errors.New("API_KEYS or API_JWT_SECRET is required to serve the APIs, or -api-insecure to serve them without authentication")
+		}
+
+		log.Printf("warning: the APIs are served without authentication (-api-insecure)")
+	}
+
+	if cfg.RunMode == runner.RunModeDatabaseCredentials {
+		if ans.credentials == nil {
+			return nil, errors.New("credentials: CREDENTIALS_KEY is not set")
+		}
+
+		return &ans, nil
+	}
+
+	registerOpts := cfg.RegisterOptions()
+
+	ans.registers = entreprise.NewServices(registerOpts...)
+
+	// the provider, the results writer and the re-enrichment share it, so
+	// that their revalidation calls are coalesced
+	apiClient := postgres.NewAPIClient(cfg.RevalidationAPIURL, cfg.JobCompletionAPIURL,
+		postgres.WithRevalidationInterval(cfg.RevalidationInterval))
+
+	if cfg.RunMode == runner.RunModeDatabaseReenrich {
+		ans.reenricher = postgres.NewReenricher(conn, apiClient, ans.reviewQueue(), ans.cipher)
+
 		return &ans, nil
 	}
 
-	psqlWriter := postgres.NewResultWriter(conn, cfg.RevalidationAPIURL)
+	var (
+		proxyURL     string
+		providerOpts = []postgres.ProviderOption{postgres.WithAPIClient(apiClient), postgres.WithFieldCipher(ans.cipher)}
+	)
+
+	if len(cfg.Proxies) > 0 {
+		proxyURL, err = ans.setupProxyPool()
+		if err != nil {
+			return nil, err
+		}
+
+		providerOpts = append(providerOpts, postgres.WithBlockReporter(ans.forwarder))
+	}
+
+	poolOpts := []browserpool.Option{
+		browserpool.WithReuse(!cfg.DisablePageReuse),
+		browserpool.WithFingerprints(!cfg.DisableFingerprint),
+		browserpool.WithSessionFile(cfg.SessionFile),
+	}
+
+	// every context of the pool gets a session of its own, so that a
+	// blocked page bans the upstream of its context only
+	if ans.forwarder != nil {
+		poolOpts = append(poolOpts,
+			browserpool.WithProxies(ans.forwarder.SessionURLs(cfg.Concurrency*browserpool.ContextsPerBrowser)))
+	}
+
+	ans.browserPool = browserpool.New(poolOpts...)
+
+	providerOpts = append(providerOpts, postgres.WithBrowserPool(ans.browserPool))
+
+	ans.tuning = postgres.NewTuning(cfg.Concurrency)
+
+	// the enrichment switches only exist in the config file; the other
+	// settings come from the merged configuration
+	if cfg.ConfigFile != "" {
+		if err := ans.applyTuning(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ans.tuning.Update(postgres.TuningUpdate{
+		Concurrency:    &cfg.Concurrency,
+		FetchBatchSize: &cfg.FetchBatchSize,
+	}); err != nil {
+		return nil, err
+	}
+
+	providerOpts = append(providerOpts, postgres.WithTuning(ans.tuning))
+
+	if len(cfg.BlockResourceTypes) > 0 || len(cfg.BlockDomains) > 0 {
+		providerOpts = append(providerOpts, postgres.WithResourceBlocking(&gmaps.ResourceBlocking{
+			ResourceTypes: cfg.BlockResourceTypes,
+			Domains:       cfg.BlockDomains,
+		}))
+	}
+
+	var placeClient *http.Client
+
+	if cfg.FastMode {
+		placeClient, err = gmaps.NewPlaceHTTPClient(proxyURL)
+		if err != nil {
+			return nil, err
+		}
+
+		providerOpts = append(providerOpts, postgres.WithPlaceHTTPClient(placeClient))
+	}
+
+	if cfg.FixturesDir != "" {
+		providerOpts = append(providerOpts, postgres.WithFixtures(&gmaps.Fixtures{
+			Dir:    cfg.FixturesDir,
+			Record: cfg.RecordFixtures,
+		}))
+	}
+
+	if cfg.PlaceCacheTTL > 0 {
+		providerOpts = append(providerOpts, postgres.WithPlaceCache(postgres.NewPlaceCache(conn, cfg.PlaceCacheTTL)))
+	}
+
+	switch {
+	case cfg.PlaceArchive == "postgres":
+		providerOpts = append(providerOpts, postgres.WithPlaceArchive(postgres.NewTablePlaceArchive(conn)))
+	case cfg.PlaceArchive != "":
+		archive, err := s3archive.New(context.Background(), cfg.PlaceArchive)
+		if err != nil {
+			return nil, err
+		}
+
+		providerOpts = append(providerOpts, postgres.WithPlaceArchive(archive))
+	}
+
+	if cfg.FairScheduling {
+		// validated with the config
+		weights, _ := runner.ParseTenantWeights(cfg.TenantWeights)
+		providerOpts = append(providerOpts, postgres.WithFairScheduling(weights))
+	}
+
+	if cfg.OwnerPlacesPerHour > 0 {
+		providerOpts = append(providerOpts, postgres.WithPacer(postgres.NewPacer(conn, cfg.OwnerPlacesPerHour)))
+	}
+
+	if cfg.ReverseGeocode {
+		providerOpts = append(providerOpts, postgres.WithReverseGeocoder(entreprise.NewBANService(registerOpts...)))
+	}
+
+	if cfg.MapCategories || cfg.CategoryTaxonomy != "" {
+		taxonomy := categories.Default()
+
+		if cfg.CategoryTaxonomy != "" {
+			t, err := categories.Load(cfg.CategoryTaxonomy)
+			if err != nil {
+				return nil, err
+			}
+
+			taxonomy = t
+		}
+
+		providerOpts = append(providerOpts, postgres.WithCategoryMapper(taxonomy))
+	}
+
+	if cfg.LenientDecode {
+		providerOpts = append(providerOpts, postgres.WithLenientDecoding())
+	}
+
+	if cfg.MonitorChanges {
+		providerOpts = append(providerOpts, postgres.WithChangeMonitoring())
+	}
+
+	if cfg.SocialProfiles {
+		providerOpts = append(providerOpts, postgres.WithSocialProfiles())
+	}
+
+	if ans.credentials != nil {
+		providerOpts = append(providerOpts, postgres.WithCredentialStore(ans.credentials))
+	}
+
+	providerOpts = append(providerOpts, postgres.WithRegisterServices(ans.registers),
+		postgres.WithSkipServiceAreaEnrichment(cfg.SkipSABEnrichment))
+
+	if cfg.ReviewMaxScore > 0 {
+		providerOpts = append(providerOpts, postgres.WithReviewQueue(ans.reviewQueue()))
+	}
+
+	if cfg.CompanyCacheTTL > 0 {
+		providerOpts = append(providerOpts, postgres.WithCompanyCache(postgres.NewCompanyCache(conn, cfg.CompanyCacheTTL, ans.cipher)))
+	}
+
+	if cfg.ExportDir != "" {
+		providerOpts = append(providerOpts, postgres.WithExporter(
+			postgres.NewExporter(conn, cfg.ExportDir, cfg.ExportBaseURL, ans.cipher),
+		))
+	}
+
+	if cfg.ReportDir != "" {
+		// validated with the config
+		format, _ := postgres.ParseReportFormat(cfg.ReportFormat)
+		providerOpts = append(providerOpts, postgres.WithReporter(
+			postgres.NewReporter(conn, cfg.ReportDir, cfg.ReportBaseURL, format, ans.cipher),
+		))
+	}
+
+	// the dashboard of the API server shows why jobs failed
+	if cfg.APIAddr != "" {
+		providerOpts = append(providerOpts, postgres.WithFailureReasons())
+	}
+
+	// jobs may give their own webhook without a global one
+	providerOpts = append(providerOpts, postgres.WithJobNotifier(postgres.NewChatNotifier(cfg.ChatWebhookURL)))
+
+	if cfg.SMTPAddr != "" {
+		tmpl, err := postgres.ParseNotifyTemplate(cfg.NotifyTemplate)
+		if err != nil {
+			return nil, err
+		}
+
+		providerOpts = append(providerOpts, postgres.WithJobNotifier(postgres.NewEmailNotifier(conn, postgres.SMTPConfig{
+			Addr:     cfg.SMTPAddr,
+			Username: cfg.SMTPUsername,
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     cfg.SMTPFrom,
+		}, tmpl)))
+	}
+
+	if cfg.ExitOnComplete || cfg.ExitOnJob != "" {
+		pending := func(ctx context.Context) (int, error) {
+			return postgres.PendingJobs(ctx, conn)
+		}
+
+		if cfg.ExitOnJob != "" {
+			pending = func(ctx context.Context) (int, error) {
+				n, err := postgres.PendingRootJob(ctx, conn, cfg.ExitOnJob)
+				if errors.Is(err, postgres.ErrJobNotFound) {
+					return 0, exiter.Permanent(fmt.Errorf("job %s: %w", cfg.ExitOnJob, err))
+				}
+
+				return n, err
+			}
+		}
+
+		ans.completion = exiter.NewCompletionMonitor(pending, completionInterval)
+
+		providerOpts = append(providerOpts, postgres.WithCompletionMonitor(ans.completion))
+	}
+
+	// root jobs are completed once the writer saved their results
+	summaries := postgres.NewRootSummaries()
+
+	writerOpts := []postgres.ResultWriterOption{
+		postgres.WithWriterAPIClient(apiClient),
+		postgres.WithWriterSummaries(summaries),
+		postgres.WithWriterFieldCipher(ans.cipher),
+	}
+
+	providerOpts = append(providerOpts, postgres.WithRootSummaries(summaries))
+
+	if cfg.ExitOnInactivityDuration > 0 {
+		ans.activity = exiter.NewInactivityMonitor(cfg.ExitOnInactivityDuration)
+
+		providerOpts = append(providerOpts, postgres.WithInactivityMonitor(ans.activity))
+		writerOpts = append(writerOpts, postgres.WithWriterInactivityMonitor(ans.activity))
+	}
+
+	if cfg.MemoryLimitMB > 0 {
+		ans.memory = postgres.NewMemoryWatchdog(uint64(cfg.MemoryLimitMB) << 20)
+
+		providerOpts = append(providerOpts, postgres.WithMemoryWatchdog(ans.memory))
+		writerOpts = append(writerOpts, postgres.WithWriterMemoryWatchdog(ans.memory))
+	}
+
+	if cfg.SpoolDir != "" {
+		ans.spool, err = postgres.NewSpool(cfg.SpoolDir)
+		if err != nil {
+			return nil, err
+		}
+
+		writerOpts = append(writerOpts, postgres.WithWriterSpool(ans.spool))
+	}
+
+	if cfg.ArchiveJobsAfter > 0 {
+		ans.archiver = postgres.NewArchiver(conn, cfg.ArchiveJobsAfter)
+	}
+
+	if len(cfg.Retention) > 0 {
+		retention, err := runner.ParseRetention(cfg.Retention)
+		if err != nil {
+			return nil, err
+		}
+
+		ans.janitor = postgres.NewJanitor(conn, retention)
+	}
+
+	if len(cfg.PIIPolicy) > 0 {
+		policies, err := privacy.ParsePolicies(cfg.PIIPolicy)
+		if err != nil {
+			return nil, err
+		}
+
+		providerOpts = append(providerOpts, postgres.WithPrivacy(policies))
+	}
+
+	if cfg.ReconcileInterval > 0 {
+		ans.reconciler = postgres.NewReconciler(conn, cfg.ReconcileInterval)
+
+		providerOpts = append(providerOpts, postgres.WithReconciler(ans.reconciler))
+	}
+
+	// the results table is written behind a buffer whose filling throttles
+	// the provider
+	psqlWriter := postgres.NewResultWriter(conn, cfg.RevalidationAPIURL, writerOpts...)
+	ans.results = postgres.NewResultBuffer(psqlWriter, cfg.ResultBuffer)
+
+	providerOpts = append(providerOpts, postgres.WithResultBuffer(ans.results))
+
+	ans.provider = postgres.NewProvider(conn, cfg.RevalidationAPIURL, cfg.JobCompletionAPIURL, providerOpts...)
+
+	ans.adminMux().HandleFunc("/metrics", ans.handleMetrics)
+	ans.adminMux().Handle("/tuning", postgres.TuningHandler(ans.tuning))
 
 	writers := []scrapemate.ResultWriter{
-		psqlWriter,
+		ans.results,
+	}
+
+	if cfg.WebhookURL != "" {
+		w := webhook.NewWriter(cfg.WebhookURL, webhook.WithBatchSize(cfg.WebhookBatchSize))
+		ans.sinks = append(ans.sinks, sinks.New("webhook-url", w, cfg.SinkBuffer))
+	}
+
+	for _, s := range cfg.ResultSinks {
+		spec, err := sinks.ParseSpec(s)
+		if err != nil {
+			return nil, err
+		}
+
+		ans.sinks = append(ans.sinks, sinks.New(spec.Name(), newSinkWriter(spec, cfg), cfg.SinkBuffer))
+	}
+
+	// the sinks are behind buffers of their own too, dropping what does not
+	// fit, so that none of them holds the results table or the others
+	for _, s := range ans.sinks {
+		writers = append(writers, s)
+	}
+
+	// fast mode loads the jobs over plain HTTP, with the client of the
+	// place fast path, and the jobs it cannot load in a browser started
+	// when the first one comes
+	if cfg.FastMode {
+		fetcherOpts := []gmaps.HTTPFetcherOption{
+			gmaps.WithBrowserFallback(&lazyFetcher{start: ans.browserFetcher}),
+		}
+
+		if proxyURL != "" {
+			fetcherOpts = append(fetcherOpts, gmaps.WithProxySessions())
+		}
+
+		ans.app = &fastApp{
+			fetcher:     gmaps.NewHTTPFetcher(placeClient, fetcherOpts...),
+			provider:    ans.provider,
+			writers:     writers,
+			concurrency: cfg.Concurrency,
+		}
+
+		return &ans, nil
 	}
 
 	opts := []func(*scrapemateapp.Config) error{
 		// scrapemateapp.WithCache("leveldb", "cache"),
 		scrapemateapp.WithConcurrency(cfg.Concurrency),
 		scrapemateapp.WithProvider(ans.provider),
-		scrapemateapp.WithExitOnInactivity(cfg.ExitOnInactivityDuration),
 	}
 
-	if len(cfg.Proxies) > 0 {
+	// every browser gets a session of its own from the forwarder, so that
+	// a blocked page bans the upstream of its browser only
+	if proxyURL != "" {
 		opts = append(opts,
-			scrapemateapp.WithProxies(cfg.Proxies),
+			scrapemateapp.WithProxies(ans.forwarder.SessionURLs(cfg.Concurrency)),
 		)
 	}
 
-	if !cfg.FastMode {
-		if cfg.Debug {
-			opts = append(opts, scrapemateapp.WithJS(
-				scrapemateapp.Headfull(),
-				scrapemateapp.DisableImages(),
-			))
-		} else {
-			opts = append(opts, scrapemateapp.WithJS(scrapemateapp.DisableImages()))
-		}
+	if cfg.Debug {
+		opts = append(opts, scrapemateapp.WithJS(
+			scrapemateapp.Headfull(),
+			scrapemateapp.DisableImages(),
+		))
 	} else {
-		opts = append(opts, scrapemateapp.WithStealth("firefox"))
+		opts = append(opts, scrapemateapp.WithJS(scrapemateapp.DisableImages()))
 	}
 
 	if !cfg.DisablePageReuse {
@@ -99,15 +536,486 @@ func New(cfg *runner.Config) (runner.Runner, error) {
 	return &ans, nil
 }
 
+// browserFetcher starts the browsers of the browser fallback of fast mode,
+// set up like the ones scrapemateapp starts without it.
+func (d *dbrunner) browserFetcher() (scrapemate.HTTPFetcher, error) {
+	params := jshttp.JSFetcherOptions{
+		Headless:      !d.cfg.Debug,
+		DisableImages: true,
+		PoolSize:      d.cfg.Concurrency,
+	}
+
+	if !d.cfg.DisablePageReuse {
+		params.PageReuseLimit = 200
+	}
+
+	if d.forwarder != nil {
+		params.Rotator = proxy.New(d.forwarder.SessionURLs(d.cfg.Concurrency))
+	}
+
+	return jshttp.New(params)
+}
+
+// newSinkWriter returns the result writer of spec.
+func newSinkWriter(spec sinks.Spec, cfg *runner.Config) scrapemate.ResultWriter {
+	switch spec.Kind {
+	case "csv":
+		return sinks.NewCSVWriter(spec.Target)
+	case "jsonl":
+		return sinks.NewJSONLWriter(spec.Target)
+	default:
+		return webhook.NewWriter(spec.Target, webhook.WithBatchSize(cfg.WebhookBatchSize))
+	}
+}
+
+// setupProxyPool starts a local forwarder in front of the configured proxies
+// and returns its URL.
+func (d *dbrunner) setupProxyPool() (string, error) {
+	strategy, err := proxypool.ParseStrategy(d.cfg.ProxyStrategy)
+	if err != nil {
+		return "", err
+	}
+
+	d.proxyPool, err = proxypool.New(d.cfg.Proxies, proxypool.WithStrategy(strategy))
+	if err != nil {
+		return "", err
+	}
+
+	d.forwarder = proxypool.NewForwarder(d.proxyPool)
+	if err := d.forwarder.Start("127.0.0.1:0"); err != nil {
+		return "", fmt.Errorf("failed to start proxy forwarder: %w", err)
+	}
+
+	d.adminMux().Handle("/proxies", proxypool.AdminHandler(d.proxyPool))
+
+	return d.forwarder.URL(), nil
+}
+
+func (d *dbrunner) adminMux() *http.ServeMux {
+	if d.admin == nil {
+		d.admin = http.NewServeMux()
+	}
+
+	return d.admin
+}
+
+// handleMetrics serves the metrics of the worker as JSON.
+func (d *dbrunner) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(d.metrics(r.Context()))
+}
+
+// metrics reports blocked page, aborted request, decode warning, SIREN check,
+// empty search and webhook delivery counts, the activity of the jobs of this
+// worker and the jobs waiting in the queue and, when configured, the state of the proxy and
+// browser pools, browser crashes included, and of the memory watchdog.
+func (d *dbrunner) metrics(ctx context.Context) map[string]any {
+	metrics := map[string]any{
+		"blocked":          gmaps.BlockedCounts(),
+		"aborted_requests": gmaps.AbortedRequests(),
+		"decode_warnings":  postgres.DecodeWarnings(),
+		"siren":            entreprise.SirenStats(),
+		"empty_searches":   gmaps.EmptySearchCounts(),
+		"jobs":             postgres.JobStats(),
+		"webhooks":         postgres.WebhookDeliveries(),
+	}
+
+	if backlog, err := postgres.GetQueueBacklog(ctx, d.conn); err == nil {
+		metrics["queues"] = backlog
+	} else {
+		log.Printf("metrics: %v", err)
+	}
+
+	if d.proxyPool != nil {
+		metrics["proxies"] = d.proxyPool.Snapshot()
+	}
+
+	if d.browserPool != nil {
+		metrics["browser_contexts"] = d.browserPool.Len()
+		metrics["browser_crashes"] = browserpool.CrashCounts()
+		metrics["crash_requeues"] = postgres.CrashRequeues()
+	}
+
+	if d.memory != nil {
+		metrics["memory"] = d.memory.Stats()
+	}
+
+	if d.results != nil {
+		metrics["results_buffer"] = d.results.Stats()
+	}
+
+	if d.spool != nil {
+		metrics["spool"] = d.spool.Stats()
+	}
+
+	if d.reconciler != nil {
+		metrics["reconciler"] = d.reconciler.Stats()
+	}
+
+	if d.janitor != nil {
+		metrics["retention"] = d.janitor.Stats()
+	}
+
+	if len(d.sinks) > 0 {
+		stats := make(map[string]sinks.Stats, len(d.sinks))
+		for _, s := range d.sinks {
+			stats[s.Name()] = s.Stats()
+		}
+
+		metrics["sinks"] = stats
+	}
+
+	return metrics
+}
+
+func (d *dbrunner) startAdmin() {
+	if d.cfg.AdminAddr == "" || d.admin == nil {
+		return
+	}
+
+	d.adminSrv = &http.Server{
+		Addr:              d.cfg.AdminAddr,
+		Handler:           d.auth.RequireGlobal(apiauth.Admin, d.admin),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := d.adminSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("admin server stopped: %v", err)
+		}
+	}()
+}
+
+// startAPI serves the GraphQL API over the results table, the endpoint
+// updating their tags and lead status, the exported workbooks and the
+// reports.
+func (d *dbrunner) startAPI() {
+	if d.cfg.APIAddr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	// GraphQL only reads, whatever the method
+	mux.Handle("/graphql", d.auth.Require(apiauth.Reader, graphqlapi.Handler(d.conn, d.cipher)))
+	mux.Handle("/results/", d.auth.ByMethod(postgres.ResultsHandler(d.conn, d.cipher)))
+	mux.Handle("/jobs/", d.auth.ByMethod(postgres.JobEventsHandler(d.conn)))
+	mux.Handle("GET /jobs/{id}/preview", d.auth.Require(apiauth.Reader, postgres.PreviewHandler(d.conn)))
+
+	review := d.auth.ByMethod(postgres.ReviewHandler(d.reviewQueue()))
+	mux.Handle("/review", review)
+	mux.Handle("/review/", review)
+
+	// the page holds no data, the browser reads it with the key its user
+	// enters
+	mux.Handle("/dashboard/", dashboard.Page())
+	mux.Handle("/dashboard/api/", d.auth.Require(apiauth.Reader, dashboard.API(d.conn, d.cipher)))
+
+	if d.cfg.ExportDir != "" {
+		mux.Handle("/exports/", d.auth.Require(apiauth.Reader,
+			http.StripPrefix("/exports/", d.jobFiles(http.FileServer(http.Dir(d.cfg.ExportDir))))))
+	}
+
+	if d.cfg.ReportDir != "" {
+		mux.Handle("/reports/", d.auth.Require(apiauth.Reader,
+			http.StripPrefix("/reports/", d.jobFiles(http.FileServer(http.Dir(d.cfg.ReportDir))))))
+	}
+
+	d.apiSrv = &http.Server{
+		Addr:              d.cfg.APIAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := d.apiSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("API server stopped: %v", err)
+		}
+	}()
+}
+
+// checkIndexes warns about the recommended indexes the database lacks; the
+// worker runs without them, only slower as the tables grow.
+func (d *dbrunner) checkIndexes(ctx context.Context) {
+	missing, err := postgres.MissingIndexes(ctx, d.conn)
+	if err != nil {
+		log.Printf("index check failed: %v", err)
+		return
+	}
+
+	for _, idx := range missing {
+		log.Printf("warning: the queries on %s scan the whole table, create the index with:\n%s;", idx.Table, idx.Create)
+	}
+}
+
+// reviewQueue returns the queue of the doubtful company matches, bounded by
+// -review-min-score and -review-max-score.
+func (d *dbrunner) reviewQueue() *postgres.ReviewQueue {
+	return postgres.NewReviewQueue(d.conn, d.cfg.ReviewMinScore, d.cfg.ReviewMaxScore, d.cipher)
+}
+
+// jobFiles serves the files named after the job they were written for, the
+// workbooks and reports, with h. It answers 404 instead of listing a
+// directory, so they can only be downloaded by name, and to a key bound to
+// another organization than that of the job.
+func (d *dbrunner) jobFiles(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "" || strings.Contains(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		id, _, _ := strings.Cut(r.URL.Path, ".")
+
+		err := postgres.CheckJobOrganization(r.Context(), d.conn, id, apiauth.GetOrganizationFromContext(r.Context()))
+		if errors.Is(err, postgres.ErrJobNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (d *dbrunner) startGRPC() error {
+	if d.cfg.GRPCAddr == "" {
+		return nil
+	}
+
+	lis, err := net.Listen("tcp", d.cfg.GRPCAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for gRPC: %w", err)
+	}
+
+	d.grpcSrv = grpcapi.NewServer(d.conn, d.provider, grpcapi.WithFieldCipher(d.cipher)).Register(d.auth.ServerOptions(grpcapi.Roles)...)
+
+	go func() {
+		if err := d.grpcSrv.Serve(lis); err != nil {
+			log.Printf("gRPC server stopped: %v", err)
+		}
+	}()
+
+	return nil
+}
+
 func (d *dbrunner) Run(ctx context.Context) error {
 	if d.produce {
 		return d.produceSeedJobs(ctx)
 	}
 
-	return d.app.Start(ctx)
+	if d.cfg.Credentials {
+		return d.storeCredentials(ctx)
+	}
+
+	if d.cfg.Forget {
+		return d.forget(ctx)
+	}
+
+	if d.cfg.AdminCommand != "" {
+		return d.runQueueCommand(ctx)
+	}
+
+	if d.reenricher != nil {
+		return d.reenrich(ctx)
+	}
+
+	if d.proxyPool != nil && d.cfg.ProxyHealthInterval > 0 {
+		go d.proxyPool.RunHealthChecks(ctx, d.cfg.ProxyHealthInterval, d.cfg.ProxyCheckURL)
+	}
+
+	d.checkIndexes(ctx)
+
+	d.startAdmin()
+	d.startAPI()
+
+	if err := d.startGRPC(); err != nil {
+		return err
+	}
+
+	go d.reloadOnHangup(ctx)
+
+	if d.memory != nil {
+		go d.memory.Run(ctx)
+	}
+
+	if d.archiver != nil {
+		go d.archiver.Run(ctx)
+	}
+
+	if d.reconciler != nil {
+		go d.reconciler.Run(ctx)
+	}
+
+	if d.janitor != nil {
+		go d.janitor.Run(ctx)
+	}
+
+	if d.cfg.TUI {
+		stop := d.startDashboard(ctx)
+		defer stop()
+	}
+
+	if d.activity == nil && d.completion == nil {
+		return d.app.Start(ctx)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		once     sync.Once
+		finished atomic.Bool
+		// failure is the error the run exits with, set before finished
+		failure error
+	)
+
+	exit := func(reason string, err error) {
+		once.Do(func() {
+			log.Printf("%s, draining before exit", reason)
+
+			d.drain()
+			failure = err
+			finished.Store(true)
+			cancel()
+		})
+	}
+
+	if d.activity != nil {
+		go d.activity.Run(ctx, func() {
+			exit(fmt.Sprintf("no activity for %s", d.cfg.ExitOnInactivityDuration), nil)
+		})
+	}
+
+	if d.completion != nil {
+		go func() {
+			err := d.completion.Run(ctx, func() {
+				exit("all jobs complete", nil)
+			})
+			if err != nil {
+				exit(fmt.Sprintf("cannot wait for the jobs: %v", err), err)
+			}
+		}()
+	}
+
+	err := d.app.Start(ctx)
+	if finished.Load() {
+		return failure
+	}
+
+	return err
+}
+
+// drain stops fetching jobs and waits for the background database updates.
+func (d *dbrunner) drain() {
+	drainer, ok := d.provider.(postgres.Drainer)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	if err := drainer.Drain(ctx); err != nil {
+		log.Printf("drain incomplete: %v", err)
+	}
+}
+
+// reloadOnHangup re-reads the config file on SIGHUP and applies the settings
+// that can change at runtime.
+func (d *dbrunner) reloadOnHangup(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+
+		if d.cfg.ConfigFile == "" {
+			log.Printf("SIGHUP ignored: no config file")
+			continue
+		}
+
+		if err := d.applyTuning(); err != nil {
+			log.Printf("config reload failed: %v", err)
+			continue
+		}
+
+		log.Printf("config reloaded: %+v", d.tuning.Settings())
+	}
+}
+
+// applyTuning applies the runtime settings of the config file: concurrency
+// (capped to the value the workers were started with), fetch batch size and
+// the enrichment switches. Concurrency and fetch batch size given as flags
+// are applied instead of the file values.
+func (d *dbrunner) applyTuning() error {
+	fc, err := runner.LoadConfigFile(d.cfg.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	u := postgres.TuningUpdate{
+		FetchBatchSize: fc.FetchBatchSize,
+		Email:          fc.Enrichment.Email,
+		Bodacc:         fc.Enrichment.Bodacc,
+	}
+
+	if fc.Concurrency != nil {
+		c := min(*fc.Concurrency, d.cfg.Concurrency)
+		u.Concurrency = &c
+	}
+
+	// the flags given on the command line or in the environment keep
+	// overriding the file
+	if d.cfg.Overridden["c"] {
+		u.Concurrency = &d.cfg.Concurrency
+	}
+
+	if d.cfg.Overridden["fetch-batch-size"] {
+		u.FetchBatchSize = &d.cfg.FetchBatchSize
+	}
+
+	return d.tuning.Update(u)
 }
 
-func (d *dbrunner) Close(context.Context) error {
+func (d *dbrunner) Close(ctx context.Context) error {
+	if d.adminSrv != nil {
+		_ = d.adminSrv.Shutdown(ctx)
+	}
+
+	if d.apiSrv != nil {
+		_ = d.apiSrv.Shutdown(ctx)
+	}
+
+	if d.grpcSrv != nil {
+		stopped := make(chan struct{})
+
+		go func() {
+			d.grpcSrv.GracefulStop()
+			close(stopped)
+		}()
+
+		// following streams only end with their job
+		select {
+		case <-stopped:
+		case <-time.After(10 * time.Second):
+			d.grpcSrv.Stop()
+		}
+	}
+
+	if d.forwarder != nil {
+		_ = d.forwarder.Close()
+	}
+
 	if d.app != nil {
 		return d.app.Close()
 	}
@@ -119,7 +1027,131 @@ func (d *dbrunner) Close(context.Context) error {
 	return nil
 }
 
+// reenrich looks up the companies of the stored results again and prints
+// what was done.
+func (d *dbrunner) reenrich(ctx context.Context) error {
+	// validated with the config
+	var olderThan time.Duration
+	if d.cfg.Since != "" {
+		olderThan, _ = runner.ParseAge(d.cfg.Since)
+	}
+
+	if d.credentials != nil {
+		ctx = context.WithValue(ctx, gmaps.CredentialResolverKey{}, d.credentials)
+	}
+
+	ctx = context.WithValue(ctx, gmaps.RegisterServicesKey{}, d.registers)
+
+	stats, err := d.reenricher.Reenrich(ctx, postgres.ReenrichFilter{
+		OwnerID:        d.cfg.OwnerID,
+		OrganizationID: d.cfg.OrganizationID,
+		OlderThan:      olderThan,
+	})
+
+	fmt.Fprintf(os.Stdout, "%d results checked: %d updated, %d without a company, %d SIREN mismatches\n",
+		stats.Checked, stats.Updated, stats.NotFound, stats.Mismatches)
+
+	return err
+}
+
+// newCredentialStore returns the store of the credentials of the
+// organizations, encrypted with the key of CREDENTIALS_KEY, or nil when it is
+// not set.
+func newCredentialStore(conn *sql.DB) (*postgres.CredentialStore, error) {
+	v := os.Getenv("CREDENTIALS_KEY")
+	if v == "" {
+		return nil, nil
+	}
+
+	key, err := postgres.ParseCredentialsKey(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return postgres.NewCredentialStore(conn, key)
+}
+
+// newFieldCipher returns the cipher of the emails and directors of the
+// results, with the key of PII_ENCRYPTION_KEY, or nil when it is not set.
+func newFieldCipher() (*postgres.FieldCipher, error) {
+	v := os.Getenv("PII_ENCRYPTION_KEY")
+	if v == "" {
+		return nil, nil
+	}
+
+	key, err := postgres.ParseFieldKey(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return postgres.NewFieldCipher(key)
+}
+
+// storeCredentials stores the JSON encoded credentials of stdin for the
+// organization, or deletes its credentials.
+func (d *dbrunner) storeCredentials(ctx context.Context) error {
+	if d.cfg.CredentialsDelete {
+		if err := d.credentials.Delete(ctx, d.cfg.OrganizationID); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stdout, "credentials of %s deleted\n", d.cfg.OrganizationID)
+
+		return nil
+	}
+
+	var creds entreprise.Credentials
+
+	dec := json.NewDecoder(os.Stdin)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&creds); err != nil {
+		return fmt.Errorf("credentials: invalid JSON on stdin: %w", err)
+	}
+
+	if err := d.credentials.Put(ctx, d.cfg.OrganizationID, creds); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "credentials of %s stored\n", d.cfg.OrganizationID)
+
+	return nil
+}
+
+// forget deletes the data of the owner or organization and prints what was
+// deleted, or what would be with -dry-run.
+func (d *dbrunner) forget(ctx context.Context) error {
+	owner := postgres.Owner{OwnerID: d.cfg.OwnerID, OrganizationID: d.cfg.OrganizationID}
+
+	report, err := postgres.DeleteOwnerData(ctx, d.conn, owner, postgres.DeleteOptions{
+		Dirs:   []string{d.cfg.ExportDir, d.cfg.ReportDir},
+		DryRun: d.cfg.ForgetDryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	verb := "deleted"
+	if report.DryRun {
+		verb = "to delete"
+	}
+
+	for _, t := range report.Tables {
+		fmt.Fprintf(os.Stdout, "%s: %d rows %s\n", t.Table, t.Rows, verb)
+	}
+
+	for _, path := range report.Files {
+		fmt.Fprintf(os.Stdout, "%s: file %s\n", path, verb)
+	}
+
+	return nil
+}
+
 func (d *dbrunner) produceSeedJobs(ctx context.Context) error {
+	if d.cfg.Backfill {
+		return d.provider.Push(ctx, gmaps.NewBackfillJob(0, 0))
+	}
+
 	var input io.Reader
 
 	switch d.cfg.InputFile {
@@ -136,9 +1168,15 @@ func (d *dbrunner) produceSeedJobs(ctx context.Context) error {
 		input = f
 	}
 
+	gouv := entreprise.NewGOUVService(d.cfg.RegisterOptions()...)
+
 	jobs, err := runner.CreateSeedJobs(
 		d.cfg.FastMode,
 		d.cfg.LangCode,
+		d.cfg.Country,
+		d.cfg.GoogleDomain,
+		d.cfg.OwnerID,
+		d.cfg.OrganizationID,
 		input,
 		d.cfg.MaxDepth,
 		d.cfg.Email,
@@ -149,6 +1187,12 @@ func (d *dbrunner) produceSeedJobs(ctx context.Context) error {
 		nil,
 		nil,
 		d.cfg.ExtraReviews,
+		d.cfg.MaxResults,
+		d.cfg.RelaxEmptySearches,
+		d.cfg.Track,
+		func(siren string) (*entreprise.CompanyInfo, error) {
+			return gouv.GetBySiren(ctx, siren)
+		},
 	)
 	if err != nil {
 		return err
@@ -163,12 +1207,38 @@ func (d *dbrunner) produceSeedJobs(ctx context.Context) error {
 	return nil
 }
 
-func openPsqlConn(dsn string) (conn *sql.DB, err error) {
-	conn, err = sql.Open("pgx", dsn)
+// openPsqlConn connects to the database of cfg. New connections log in with
+// the latest credentials of the secret the DSN came from, so that they keep
+// working once it rotates.
+func openPsqlConn(cfg *runner.Config) (conn *sql.DB, err error) {
+	connCfg, err := pgx.ParseConfig(cfg.Dsn)
 	if err != nil {
 		return
 	}
 
+	// the job events record which worker changed a status by the
+	// application_name of its connection
+	if _, ok := connCfg.RuntimeParams["application_name"]; !ok {
+		connCfg.RuntimeParams["application_name"] = workerName()
+	}
+
+	conn = stdlib.OpenDB(*connCfg, stdlib.OptionBeforeConnect(func(_ context.Context, c *pgx.ConnConfig) error {
+		dsn := cfg.CurrentDsn()
+		if dsn == cfg.Dsn {
+			return nil
+		}
+
+		latest, err := pgx.ParseConfig(dsn)
+		if err != nil {
+			return err
+		}
+
+		c.User = latest.User
+		c.Password = latest.Password
+
+		return nil
+	}))
+
 	err = conn.Ping()
 	if err != nil {
 		return
@@ -178,3 +1248,13 @@ func openPsqlConn(dsn string) (conn *sql.DB, err error) {
 
 	return
 }
+
+// workerName identifies the worker in the job events, as host:pid.
+func workerName() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
@@ -0,0 +1,116 @@
+package databaserunner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gosom/scrapemate"
+	"github.com/gosom/scrapemate/adapters/parsers/goqueryparser"
+	"golang.org/x/sync/errgroup"
+)
+
+// app runs the crawler: a scrapemateapp with a browser, or fastApp.
+type app interface {
+	Start(ctx context.Context, seedJobs ...scrapemate.IJob) error
+	Close() error
+}
+
+// fastApp runs the crawler of fast mode. scrapemateapp only builds the
+// fetchers of scrapemate, so it runs scrapemate with the fetcher of
+// gmaps.NewHTTPFetcher itself, whose browser fallback is a lazyFetcher.
+type fastApp struct {
+	fetcher     scrapemate.HTTPFetcher
+	provider    scrapemate.JobProvider
+	writers     []scrapemate.ResultWriter
+	concurrency int
+}
+
+// Start runs the crawler and the writers of its results until ctx is done or
+// one of them fails. The seed jobs are in the queue already.
+func (a *fastApp) Start(ctx context.Context, _ ...scrapemate.IJob) error {
+	g, ctx := errgroup.WithContext(ctx)
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	defer cancel(errors.New("closing app"))
+
+	mate, err := scrapemate.New(
+		scrapemate.WithContext(ctx, cancel),
+		scrapemate.WithJobProvider(a.provider),
+		scrapemate.WithHTTPFetcher(a.fetcher),
+		scrapemate.WithHTMLParser(goqueryparser.New()),
+		scrapemate.WithConcurrency(a.concurrency),
+	)
+	if err != nil {
+		return err
+	}
+
+	defer mate.Close()
+
+	for _, w := range a.writers {
+		g.Go(func() error {
+			if err := w.Run(ctx, mate.Results()); err != nil {
+				cancel(err)
+				return err
+			}
+
+			return nil
+		})
+	}
+
+	g.Go(mate.Start)
+
+	return g.Wait()
+}
+
+func (a *fastApp) Close() error {
+	return a.fetcher.Close()
+}
+
+// lazyFetcher starts the fetcher of start on the first job it fetches, so
+// that fast mode runs a browser only once a job needs one.
+type lazyFetcher struct {
+	start func() (scrapemate.HTTPFetcher, error)
+
+	mu      sync.Mutex
+	fetcher scrapemate.HTTPFetcher
+}
+
+func (f *lazyFetcher) Fetch(ctx context.Context, job scrapemate.IJob) scrapemate.Response {
+	fetcher, err := f.get()
+	if err != nil {
+		return scrapemate.Response{Error: err}
+	}
+
+	return fetcher.Fetch(ctx, job)
+}
+
+// get returns the fetcher, starting it unless it runs already. A fetcher
+// failing to start is tried again on the next job.
+func (f *lazyFetcher) get() (scrapemate.HTTPFetcher, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.fetcher == nil {
+		fetcher, err := f.start()
+		if err != nil {
+			return nil, fmt.Errorf("failed to start browser: %w", err)
+		}
+
+		f.fetcher = fetcher
+	}
+
+	return f.fetcher, nil
+}
+
+func (f *lazyFetcher) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.fetcher == nil {
+		return nil
+	}
+
+	return f.fetcher.Close()
+}
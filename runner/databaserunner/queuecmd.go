@@ -0,0 +1,155 @@
+package databaserunner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+// exportPage is how many results the export reads at a time.
+const exportPage = 1000
+
+// runQueueCommand runs the jobs or results subcommand of the configuration.
+func (d *dbrunner) runQueueCommand(ctx context.Context) error {
+	status := postgres.NewStatusManager(d.conn, postgres.NewAPIClient(d.cfg.RevalidationAPIURL, d.cfg.JobCompletionAPIURL))
+
+	switch d.cfg.AdminCommand {
+	case "jobs ls":
+		return d.listJobs(ctx)
+	case "jobs stats":
+		return d.queueStats(ctx)
+	case "jobs retry":
+		for _, id := range d.cfg.AdminJobIDs {
+			if err := status.RetryJob(ctx, id); err != nil {
+				return fmt.Errorf("job %s: %w", id, err)
+			}
+
+			fmt.Fprintf(os.Stdout, "%s: queued again\n", id)
+		}
+	case "jobs cancel":
+		for _, id := range d.cfg.AdminJobIDs {
+			n, err := status.CancelJob(ctx, id)
+			if err != nil {
+				return fmt.Errorf("job %s: %w", id, err)
+			}
+
+			fmt.Fprintf(os.Stdout, "%s: %d jobs cancelled\n", id, n)
+		}
+
+		// parents the cancelled jobs finished report their completion
+		status.Wait()
+	case "results export":
+		return d.exportResults(ctx)
+	}
+
+	return nil
+}
+
+func (d *dbrunner) listJobs(ctx context.Context) error {
+	jobs, err := postgres.ListJobs(ctx, d.conn, postgres.JobListFilter{
+		Status:         d.cfg.AdminStatus,
+		OwnerID:        d.cfg.OwnerID,
+		OrganizationID: d.cfg.OrganizationID,
+		All:            d.cfg.AdminAll,
+		Limit:          d.cfg.AdminLimit,
+	})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "ID\tTYPE\tSTATUS\tCHILDREN\tOWNER\tCREATED")
+
+	for i := range jobs {
+		j := &jobs[i]
+
+		owner := j.OrganizationID
+		if owner == "" {
+			owner = j.OwnerID
+		}
+
+		children := "-"
+		if j.ChildJobsCount > 0 {
+			children = fmt.Sprintf("%d/%d (%d failed)", j.ChildJobsCompleted+j.ChildJobsFailed, j.ChildJobsCount, j.ChildJobsFailed)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", j.ID, j.Type, j.Status, children, owner,
+			j.CreatedAt.Local().Format(time.DateTime))
+	}
+
+	return w.Flush()
+}
+
+func (d *dbrunner) queueStats(ctx context.Context) error {
+	stats, err := postgres.GetQueueStats(ctx, d.conn)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "TYPE\tSTATUS\tJOBS")
+
+	for _, s := range stats {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", s.Type, s.Status, s.Jobs)
+	}
+
+	return w.Flush()
+}
+
+// exportResults writes the results of the job, owner or organization of the
+// configuration to the output file.
+func (d *dbrunner) exportResults(ctx context.Context) error {
+	var out io.Writer = os.Stdout
+
+	if d.cfg.AdminOutput != "stdout" {
+		f, err := os.Create(d.cfg.AdminOutput)
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		out = f
+	}
+
+	if d.cfg.AdminFormat == "xlsx" {
+		return postgres.ExportWorkbook(ctx, d.conn, d.cipher, d.cfg.AdminJobID, out)
+	}
+
+	enc := json.NewEncoder(out)
+
+	filter := postgres.ResultFilter{
+		JobID:          d.cfg.AdminJobID,
+		OwnerID:        d.cfg.OwnerID,
+		OrganizationID: d.cfg.OrganizationID,
+	}
+
+	for {
+		q := postgres.NewResultsQuery(filter).OrderBy(postgres.SortByID, false).Page(exportPage, 0)
+
+		results, err := postgres.ListResults(ctx, d.conn, d.cipher, q)
+		if err != nil {
+			return err
+		}
+
+		for i := range results {
+			if err := enc.Encode(&results[i]); err != nil {
+				return err
+			}
+		}
+
+		if len(results) < exportPage {
+			return nil
+		}
+
+		filter.AfterID = results[len(results)-1].ID
+	}
+}
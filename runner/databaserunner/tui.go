@@ -0,0 +1,186 @@
+package databaserunner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+const (
+	// dashboardInterval is how often the -tui dashboard is redrawn.
+	dashboardInterval = 2 * time.Second
+	// dashboardErrors is how many of the recent job errors it shows.
+	dashboardErrors = 8
+	// dashboardReason is how much of the reason of each error it shows.
+	dashboardReason = 80
+)
+
+// ANSI sequences switching to the alternate screen and back, and clearing it.
+const (
+	enterAltScreen = "\x1b[?1049h\x1b[?25l"
+	leaveAltScreen = "\x1b[?25h\x1b[?1049l"
+	clearScreen    = "\x1b[H\x1b[2J"
+)
+
+// tui draws the metrics of the worker in the terminal.
+type tui struct {
+	concurrency int
+	started     time.Time
+	// last and processed are when the dashboard was last drawn and how many
+	// jobs were processed then, for the jobs per second.
+	last      time.Time
+	processed int64
+}
+
+// startDashboard draws the metrics of the worker on stdout until the returned
+// function is called, which gives the terminal back.
+func (d *dbrunner) startDashboard(ctx context.Context) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	now := time.Now()
+	dash := &tui{
+		concurrency: d.cfg.Concurrency,
+		started:     now,
+		last:        now,
+	}
+
+	go func() {
+		defer close(done)
+
+		fmt.Fprint(os.Stdout, enterAltScreen)
+		defer fmt.Fprint(os.Stdout, leaveAltScreen)
+
+		ticker := time.NewTicker(dashboardInterval)
+		defer ticker.Stop()
+
+		for {
+			var buf bytes.Buffer
+
+			buf.WriteString(clearScreen)
+			dash.render(&buf, d.metrics(ctx), time.Now())
+
+			_, _ = os.Stdout.Write(buf.Bytes())
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// render writes the dashboard of metrics, as the admin /metrics endpoint
+// serves them, at now.
+func (dash *tui) render(w io.Writer, metrics map[string]any, now time.Time) {
+	jobs, _ := metrics["jobs"].(postgres.JobActivity)
+	queues, _ := metrics["queues"].([]postgres.QueueStat)
+
+	var running, processed int64
+
+	for _, n := range jobs.Running {
+		running += n
+	}
+
+	for _, counts := range []map[string]int64{jobs.Done, jobs.Failed} {
+		for _, n := range counts {
+			processed += n
+		}
+	}
+
+	var rate float64
+	if elapsed := now.Sub(dash.last).Seconds(); elapsed > 0 && processed >= dash.processed {
+		rate = float64(processed-dash.processed) / elapsed
+	}
+
+	var average float64
+	if elapsed := now.Sub(dash.started).Seconds(); elapsed > 0 {
+		average = float64(processed) / elapsed
+	}
+
+	dash.last, dash.processed = now, processed
+
+	fmt.Fprintf(w, "google-maps-scraper worker  %s  up %s\n\n", now.Local().Format(time.DateTime),
+		now.Sub(dash.started).Round(time.Second))
+	fmt.Fprintf(w, "Workers: %d/%d running   Jobs/sec: %.2f (%.2f average)   Processed: %d\n\n",
+		running, dash.concurrency, rate, average, processed)
+
+	// the types of the jobs this worker ran or the queue holds
+	pending := make(map[string]map[string]int)
+	types := make(map[string]bool)
+
+	for _, q := range queues {
+		if pending[q.Type] == nil {
+			pending[q.Type] = make(map[string]int)
+		}
+
+		pending[q.Type][q.Status] = q.Jobs
+		types[q.Type] = true
+	}
+
+	for _, counts := range []map[string]int64{jobs.Running, jobs.Done, jobs.Failed} {
+		for t := range counts {
+			types[t] = true
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "TYPE\tRUNNING\tDONE\tFAILED\tDEFERRED\tNEW\tQUEUED\tPROCESSING")
+
+	for _, t := range slices.Sorted(maps.Keys(types)) {
+		p := pending[t]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\n", t, jobs.Running[t], jobs.Done[t], jobs.Failed[t],
+			p["deferred"], p["new"], p["queued"], p["processing"])
+	}
+
+	_ = tw.Flush()
+
+	if len(jobs.Enrichment) > 0 {
+		fmt.Fprintln(w)
+
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+		fmt.Fprintln(tw, "ENRICHMENT\tHITS\tMISSES\tHIT RATE")
+
+		for _, t := range slices.Sorted(maps.Keys(jobs.Enrichment)) {
+			h := jobs.Enrichment[t]
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f%%\n", t, h.Hits, h.Misses, 100*h.Rate())
+		}
+
+		_ = tw.Flush()
+	}
+
+	fmt.Fprintln(w, "\nRECENT ERRORS")
+
+	if len(jobs.RecentErrors) == 0 {
+		fmt.Fprintln(w, "none")
+	}
+
+	for i, e := range jobs.RecentErrors {
+		if i == dashboardErrors {
+			break
+		}
+
+		reason := e.Reason
+		if r := []rune(reason); len(r) > dashboardReason {
+			reason = string(r[:dashboardReason-1]) + "…"
+		}
+
+		fmt.Fprintf(w, "%s  %-8s %s  %s\n", e.At.Local().Format(time.TimeOnly), e.Type, e.JobID, reason)
+	}
+}
@@ -0,0 +1,222 @@
+package dryrunner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/gosom/google-maps-scraper/entreprise"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/runner"
+	"github.com/gosom/scrapemate"
+)
+
+const (
+	// resultsPerScroll is roughly how many places each scroll of the results
+	// feed loads.
+	resultsPerScroll = 10
+	// maxSearchResults is the most places Google Maps lists for one search.
+	maxSearchResults = 120
+	// maxFastSearchResults is the most places the search endpoint of fast
+	// mode returns.
+	maxFastSearchResults = 20
+)
+
+type dryrunner struct {
+	cfg *runner.Config
+	out io.Writer
+}
+
+// New creates a runner that prints the jobs the input file would produce,
+// with an estimate of the requests they lead to, without touching the
+// database.
+func New(cfg *runner.Config) (runner.Runner, error) {
+	if cfg.RunMode != runner.RunModeDryRun {
+		return nil, fmt.Errorf("%w: %d", runner.ErrInvalidRunMode, cfg.RunMode)
+	}
+
+	return &dryrunner{cfg: cfg, out: os.Stdout}, nil
+}
+
+func (d *dryrunner) Run(ctx context.Context) error {
+	var input io.Reader
+
+	switch d.cfg.InputFile {
+	case "stdin":
+		input = os.Stdin
+	default:
+		f, err := os.Open(d.cfg.InputFile)
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		input = f
+	}
+
+	gouv := entreprise.NewGOUVService(d.cfg.RegisterOptions()...)
+
+	// same arguments as the database runner uses when producing
+	jobs, err := runner.CreateSeedJobs(
+		d.cfg.FastMode,
+		d.cfg.LangCode,
+		d.cfg.Country,
+		d.cfg.GoogleDomain,
+		d.cfg.OwnerID,
+		d.cfg.OrganizationID,
+		input,
+		d.cfg.MaxDepth,
+		d.cfg.Email,
+		d.cfg.Bodacc,
+		d.cfg.GeoCoordinates,
+		d.cfg.Zoom,
+		d.cfg.Radius,
+		nil,
+		nil,
+		d.cfg.ExtraReviews,
+		d.cfg.MaxResults,
+		d.cfg.RelaxEmptySearches,
+		d.cfg.Track,
+		func(siren string) (*entreprise.CompanyInfo, error) {
+			return gouv.GetBySiren(ctx, siren)
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return d.print(jobs)
+}
+
+func (d *dryrunner) Close(context.Context) error {
+	return nil
+}
+
+// estimate holds upper bounds of the requests a set of jobs leads to.
+type estimate struct {
+	searches  int
+	places    int
+	emails    int
+	companies int
+}
+
+func (e estimate) total() int {
+	return e.searches + e.places + e.emails + e.companies
+}
+
+func (d *dryrunner) print(jobs []scrapemate.IJob) error {
+	w := tabwriter.NewWriter(d.out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "TYPE\tQUERY\tLANG\tCOUNTRY\tGEO\tDEPTH\tEMAIL\tBODACC\tOWNER\tORGANIZATION")
+
+	counts := map[string]int{}
+
+	var est estimate
+
+	for _, job := range jobs {
+		switch j := job.(type) {
+		case *gmaps.GmapJob:
+			counts["search"]++
+
+			fmt.Fprintf(w, "search\t%s\t%s\t%s\t%s\t%d\t%t\t%t\t%s\t%s\n",
+				gmaps.SearchQuery(j.URL), j.LangCode, orDash(j.Country), orDash(geoString(j)),
+				j.MaxDepth, j.ExtractEmail, j.ExtractBodacc, orDash(j.OwnerID), orDash(j.OrganizationID))
+
+			places := min(j.MaxDepth*resultsPerScroll, maxSearchResults)
+
+			est.searches++
+			est.places += places
+
+			if j.ExtractEmail {
+				est.emails += places
+			}
+
+			if j.ExtractBodacc {
+				est.companies += places
+			}
+		case *gmaps.SearchJob:
+			counts["fast_search"]++
+
+			params := j.Params()
+
+			fmt.Fprintf(w, "fast_search\t%s\t%s\t%s\t%.4f,%.4f@%.0fz\t-\t%t\t%t\t%s\t%s\n",
+				params.Query, params.Hl, orDash(j.Country), params.Location.Lat, params.Location.Lon,
+				params.Location.ZoomLvl, j.ExtractEmail, j.ExtractBodacc, orDash(j.OwnerID), orDash(j.OrganizationID))
+
+			est.searches++
+			est.places += maxFastSearchResults
+
+			if j.ExtractEmail {
+				est.emails += maxFastSearchResults
+			}
+
+			if j.ExtractBodacc {
+				est.companies += maxFastSearchResults
+			}
+		case *gmaps.PlaceJob:
+			counts["place"]++
+
+			fmt.Fprintf(w, "place\t%s\t%s\t%s\t-\t-\t%t\t%t\t%s\t%s\n",
+				j.GetURL(), j.URLParams["hl"], orDash(j.Country), j.ExtractEmail, j.ExtractBodacc,
+				orDash(j.OwnerID), orDash(j.OrganizationID))
+
+			est.places++
+
+			if j.ExtractEmail {
+				est.emails++
+			}
+
+			if j.ExtractBodacc {
+				est.companies++
+			}
+		default:
+			counts[fmt.Sprintf("%T", job)]++
+
+			fmt.Fprintf(w, "%T\t%s\t\t\t\t\t\t\t\t\n", job, job.GetURL())
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(d.out, "\n%d jobs would be enqueued:", len(jobs))
+
+	for _, kind := range slices.Sorted(maps.Keys(counts)) {
+		fmt.Fprintf(d.out, " %s=%d", kind, counts[kind])
+	}
+
+	fmt.Fprintf(d.out, "\n\nestimated requests (upper bound, %d places per scroll, at most %d per search):\n",
+		resultsPerScroll, maxSearchResults)
+
+	w = tabwriter.NewWriter(d.out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "  search pages\t%d\n", est.searches)
+	fmt.Fprintf(w, "  place pages\t%d\n", est.places)
+	fmt.Fprintf(w, "  website fetches (email)\t%d\n", est.emails)
+	fmt.Fprintf(w, "  company lookups (bodacc)\t%d\n", est.companies)
+	fmt.Fprintf(w, "  total\t%d\n", est.total())
+
+	return w.Flush()
+}
+
+func geoString(j *gmaps.GmapJob) string {
+	if j.GeoCoordinates == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%s@%dz", j.GeoCoordinates, j.Zoom)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
@@ -0,0 +1,206 @@
+package enrichrunner
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/entreprise"
+)
+
+// batchHeader are the columns written for each company of the input.
+var batchHeader = []string{
+	"name", "address", "siren", "directors", "forme", "creation", "naf_code", "source", "error",
+}
+
+// company is a line of the input CSV file and what was found for it.
+type company struct {
+	name, address string
+	info          *entreprise.CompanyInfo
+	err           error
+}
+
+// runBatch looks up the companies of the input CSV file and writes them to
+// the output one in the same order.
+func (e *enrichrunner) runBatch(ctx context.Context) error {
+	in, err := os.Open(e.cfg.EnrichInput)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	companies, err := readCompanies(in)
+	if err != nil {
+		return fmt.Errorf("%s: %w", e.cfg.EnrichInput, err)
+	}
+
+	out := e.out
+
+	if e.cfg.EnrichOutput != "stdout" {
+		f, err := os.Create(e.cfg.EnrichOutput)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		out = f
+	}
+
+	e.lookup(ctx, companies)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return writeCompanies(out, companies)
+}
+
+// lookup enriches companies with cfg.EnrichWorkers workers, which together
+// start at most cfg.EnrichRate lookups per second.
+func (e *enrichrunner) lookup(ctx context.Context, companies []company) {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / e.cfg.EnrichRate))
+	defer ticker.Stop()
+
+	next := make(chan *company)
+
+	var wg sync.WaitGroup
+
+	for range e.cfg.EnrichWorkers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for c := range next {
+				c.info, c.err = entreprise.Enrich(ctx, c.name, c.address, entreprise.WithService(e.service))
+			}
+		}()
+	}
+
+	start := time.Now()
+
+feed:
+	for i := range companies {
+		select {
+		case <-ctx.Done():
+			break feed
+		case <-ticker.C:
+		}
+
+		select {
+		case <-ctx.Done():
+			break feed
+		case next <- &companies[i]:
+		}
+
+		if (i+1)%100 == 0 {
+			log.Printf("enrich: %d/%d companies looked up in %s", i+1, len(companies), time.Since(start).Round(time.Second))
+		}
+	}
+
+	close(next)
+	wg.Wait()
+}
+
+// readCompanies reads a CSV file whose header names a name column and
+// optionally an address column, in any case and order.
+func readCompanies(r io.Reader) ([]company, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the header: %w", err)
+	}
+
+	nameCol, addressCol := -1, -1
+
+	for i, col := range header {
+		switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(col, "\ufeff"))) {
+		case "name":
+			nameCol = i
+		case "address":
+			addressCol = i
+		}
+	}
+
+	if nameCol < 0 {
+		return nil, errors.New("the header has no name column")
+	}
+
+	var ans []company
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		c := company{name: strings.TrimSpace(field(record, nameCol))}
+		if c.name == "" {
+			continue
+		}
+
+		if addressCol >= 0 {
+			c.address = strings.TrimSpace(field(record, addressCol))
+		}
+
+		ans = append(ans, c)
+	}
+
+	return ans, nil
+}
+
+func field(record []string, i int) string {
+	if i < len(record) {
+		return record[i]
+	}
+
+	return ""
+}
+
+// writeCompanies writes companies as CSV, with empty columns for those no
+// register knows and the error of those the lookup failed for.
+func writeCompanies(w io.Writer, companies []company) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(batchHeader); err != nil {
+		return err
+	}
+
+	for _, c := range companies {
+		row := make([]string, len(batchHeader))
+		row[0], row[1] = c.name, c.address
+
+		switch {
+		case c.info != nil:
+			row[2] = c.info.SocieteSiren
+			row[3] = strings.Join(c.info.SocieteDirigeants, ", ")
+			row[4] = c.info.SocieteForme
+			row[5] = c.info.SocieteCreation
+			row[6] = c.info.NafCode
+			row[7] = c.info.Source
+		case c.err != nil && !errors.Is(c.err, entreprise.ErrNotFound):
+			row[8] = c.err.Error()
+		}
+
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
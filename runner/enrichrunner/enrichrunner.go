@@ -0,0 +1,71 @@
+package enrichrunner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gosom/google-maps-scraper/entreprise"
+	"github.com/gosom/google-maps-scraper/runner"
+)
+
+type enrichrunner struct {
+	cfg *runner.Config
+	out io.Writer
+	// service queries the registers with the credentials of the
+	// environment.
+	service *entreprise.Service
+}
+
+// output is what the enrich subcommand prints: the address as the registers
+// are queried with it, and the company found, null when there is none.
+type output struct {
+	Name          string                   `json:"name"`
+	Address       string                   `json:"address"`
+	ParsedAddress entreprise.ParsedAddress `json:"parsed_address"`
+	Company       *entreprise.CompanyInfo  `json:"company"`
+}
+
+// New creates a runner that looks up a single company in the registers, the
+// way place jobs do, and prints what it found as JSON, or those of a CSV file
+// and writes them as CSV.
+func New(cfg *runner.Config) (runner.Runner, error) {
+	if cfg.RunMode != runner.RunModeEnrich {
+		return nil, fmt.Errorf("%w: %d", runner.ErrInvalidRunMode, cfg.RunMode)
+	}
+
+	service := entreprise.NewServiceWithCredentials(entreprise.CredentialsFromEnv(), cfg.RegisterOptions()...)
+
+	return &enrichrunner{cfg: cfg, out: os.Stdout, service: service}, nil
+}
+
+func (e *enrichrunner) Run(ctx context.Context) error {
+	if e.cfg.EnrichInput != "" {
+		return e.runBatch(ctx)
+	}
+
+	ans := output{
+		Name:          e.cfg.EnrichName,
+		Address:       e.cfg.EnrichAddress,
+		ParsedAddress: e.service.ParseAddress(e.cfg.EnrichAddress),
+	}
+
+	company, err := entreprise.Enrich(ctx, e.cfg.EnrichName, e.cfg.EnrichAddress, entreprise.WithService(e.service))
+	if err != nil && !errors.Is(err, entreprise.ErrNotFound) && !errors.Is(err, entreprise.ErrLowConfidence) {
+		return err
+	}
+
+	ans.Company = company
+
+	enc := json.NewEncoder(e.out)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(ans)
+}
+
+func (e *enrichrunner) Close(context.Context) error {
+	return nil
+}
@@ -2,20 +2,194 @@ package runner
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"io"
 	"strconv"
 	"strings"
 
 	"github.com/gosom/google-maps-scraper/deduper"
+	"github.com/gosom/google-maps-scraper/entreprise"
 	"github.com/gosom/google-maps-scraper/exiter"
 	"github.com/gosom/google-maps-scraper/gmaps"
 	"github.com/gosom/scrapemate"
 )
 
+const maxSeedLineSize = 1024 * 1024
+
+// Seed is one line of the input file. A line is either a plain query, a query
+// followed by an id and optionally an organization ("query #!# id #!# org"),
+// or a JSON object. Fields left empty take their value from the command line
+// flags. A place URL, id or CID in place of the query is scraped directly,
+// without a search, and a SIREN audits the listing of its company.
+type Seed struct {
+	ID             string   `json:"id"`
+	Query          string   `json:"query"`
+	Place          string   `json:"place"`
+	Siren          string   `json:"siren"`
+	Lang           string   `json:"lang"`
+	Country        string   `json:"country"`
+	Domain         string   `json:"domain"`
+	Geo            string   `json:"geo"`
+	Zoom           *int     `json:"zoom"`
+	Radius         *float64 `json:"radius"`
+	Depth          *int     `json:"depth"`
+	MaxResults     *int     `json:"max_results"`
+	Email          *bool    `json:"email"`
+	Bodacc         *bool    `json:"bodacc"`
+	ExtraReviews   *bool    `json:"extra_reviews"`
+	RelaxEmpty     *bool    `json:"relax_empty"`
+	Track          string   `json:"track"`
+	OwnerID        string   `json:"owner_id"`
+	OrganizationID string   `json:"organization_id"`
+}
+
+// ParseSeed parses one input line.
+func ParseSeed(line string) (Seed, error) {
+	line = strings.TrimSpace(line)
+
+	if strings.HasPrefix(line, "{") {
+		var s Seed
+
+		dec := json.NewDecoder(strings.NewReader(line))
+		dec.DisallowUnknownFields()
+
+		if err := dec.Decode(&s); err != nil {
+			return Seed{}, fmt.Errorf("invalid json seed: %w", err)
+		}
+
+		s.Query = strings.TrimSpace(s.Query)
+		s.Place = strings.TrimSpace(s.Place)
+
+		if s.Siren != "" {
+			siren := entreprise.NormalizeSiren(s.Siren)
+			if siren == "" {
+				return Seed{}, fmt.Errorf("invalid siren %q", s.Siren)
+			}
+
+			s.Siren = siren
+		}
+
+		targets := 0
+
+		for _, v := range []string{s.Query, s.Place, s.Siren} {
+			if v != "" {
+				targets++
+			}
+		}
+
+		switch {
+		case targets == 0:
+			return Seed{}, fmt.Errorf("json seed without query, place or siren")
+		case targets > 1:
+			return Seed{}, fmt.Errorf("json seed with more than one of a query, a place and a siren")
+		}
+
+		return s, nil
+	}
+
+	parts := strings.SplitN(line, "#!#", 3)
+
+	s := Seed{Query: strings.TrimSpace(parts[0])}
+
+	if _, ok := gmaps.PlaceURL(s.Query, ""); ok {
+		s.Query, s.Place = "", s.Query
+	} else if siren := entreprise.NormalizeSiren(s.Query); siren != "" {
+		s.Query, s.Siren = "", siren
+	}
+
+	if len(parts) > 1 {
+		s.ID = strings.TrimSpace(parts[1])
+	}
+
+	if len(parts) > 2 {
+		s.OrganizationID = strings.TrimSpace(parts[2])
+	}
+
+	return s, nil
+}
+
+// withDefaults fills the fields the line did not set from the flag values.
+func (s Seed) withDefaults(d *Seed) Seed {
+	if s.Lang == "" {
+		s.Lang = d.Lang
+	}
+
+	if s.Country == "" {
+		s.Country = d.Country
+	}
+
+	if s.Domain == "" {
+		s.Domain = d.Domain
+	}
+
+	if s.Geo == "" {
+		s.Geo = d.Geo
+	}
+
+	if s.Zoom == nil {
+		s.Zoom = d.Zoom
+	}
+
+	if s.Radius == nil {
+		s.Radius = d.Radius
+	}
+
+	if s.Depth == nil {
+		s.Depth = d.Depth
+	}
+
+	if s.MaxResults == nil {
+		s.MaxResults = d.MaxResults
+	}
+
+	if s.Email == nil {
+		s.Email = d.Email
+	}
+
+	if s.Bodacc == nil {
+		s.Bodacc = d.Bodacc
+	}
+
+	if s.ExtraReviews == nil {
+		s.ExtraReviews = d.ExtraReviews
+	}
+
+	if s.RelaxEmpty == nil {
+		s.RelaxEmpty = d.RelaxEmpty
+	}
+
+	if s.Track == "" {
+		s.Track = d.Track
+	}
+
+	// the id has always doubled as the owner of the results
+	if s.OwnerID == "" {
+		s.OwnerID = s.ID
+	}
+
+	if s.OwnerID == "" {
+		s.OwnerID = d.OwnerID
+	}
+
+	if s.OrganizationID == "" {
+		s.OrganizationID = d.OrganizationID
+	}
+
+	return s
+}
+
+// CompanyLookup returns the company registered under siren, or nil when the
+// register does not know it.
+type CompanyLookup func(siren string) (*entreprise.CompanyInfo, error)
+
 func CreateSeedJobs(
 	fastmode bool,
 	langCode string,
+	country string,
+	domain string,
+	ownerID string,
+	organizationID string,
 	r io.Reader,
 	maxDepth int,
 	email bool,
@@ -26,110 +200,275 @@ func CreateSeedJobs(
 	dedup deduper.Deduper,
 	exitMonitor exiter.Exiter,
 	extraReviews bool,
+	maxResults int,
+	relaxEmpty bool,
+	track string,
+	lookup CompanyLookup,
 ) (jobs []scrapemate.IJob, err error) {
-	var lat, lon float64
+	defaults := Seed{
+		Lang:           langCode,
+		Country:        country,
+		Domain:         domain,
+		OwnerID:        ownerID,
+		OrganizationID: organizationID,
+		Geo:            geoCoordinates,
+		Zoom:           &zoom,
+		Radius:         &radius,
+		Depth:          &maxDepth,
+		MaxResults:     &maxResults,
+		Email:          &email,
+		Bodacc:         &bodacc,
+		ExtraReviews:   &extraReviews,
+		RelaxEmpty:     &relaxEmpty,
+		Track:          track,
+	}
 
-	if fastmode {
-		if geoCoordinates == "" {
-			return nil, fmt.Errorf("geo coordinates are required in fast mode")
-		}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSeedLineSize)
 
-		parts := strings.Split(geoCoordinates, ",")
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid geo coordinates: %s", geoCoordinates)
-		}
+	lineNo := 0
 
-		lat, err = strconv.ParseFloat(parts[0], 64)
-		if err != nil {
-			return nil, fmt.Errorf("invalid latitude: %w", err)
+	for scanner.Scan() {
+		lineNo++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
 		}
 
-		lon, err = strconv.ParseFloat(parts[1], 64)
+		seed, err := ParseSeed(line)
 		if err != nil {
-			return nil, fmt.Errorf("invalid longitude: %w", err)
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
 		}
 
-		if lat < -90 || lat > 90 {
-			return nil, fmt.Errorf("invalid latitude: %f", lat)
-		}
+		seed = seed.withDefaults(&defaults)
 
-		if lon < -180 || lon > 180 {
-			return nil, fmt.Errorf("invalid longitude: %f", lon)
+		if _, err := gmaps.ParseDomain(seed.Domain); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
 		}
 
-		if zoom < 1 || zoom > 21 {
-			return nil, fmt.Errorf("invalid zoom level: %d", zoom)
-		}
+		var job scrapemate.IJob
 
-		if radius < 0 {
-			return nil, fmt.Errorf("invalid radius: %f", radius)
+		switch {
+		case seed.Place != "":
+			job, err = newPlaceSeedJob(&seed, exitMonitor)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+		case seed.Siren != "":
+			job, err = newAuditSeedJob(&seed, lookup, exitMonitor)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+		case !fastmode:
+			job = newGmapSeedJob(&seed, dedup, exitMonitor)
+		default:
+			job, err = newFastSeedJob(&seed, exitMonitor)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
 		}
+
+		jobs = append(jobs, job)
 	}
 
-	scanner := bufio.NewScanner(r)
+	return jobs, scanner.Err()
+}
 
-	for scanner.Scan() {
-		query := strings.TrimSpace(scanner.Text())
-		if query == "" {
-			continue
-		}
+func newGmapSeedJob(seed *Seed, dedup deduper.Deduper, exitMonitor exiter.Exiter) scrapemate.IJob {
+	opts := []gmaps.GmapJobOptions{}
 
-		var id string
+	if dedup != nil {
+		opts = append(opts, gmaps.WithDeduper(dedup))
+	}
 
-		if before, after, ok := strings.Cut(query, "#!#"); ok {
-			query = strings.TrimSpace(before)
-			id = strings.TrimSpace(after)
-		}
+	if exitMonitor != nil {
+		opts = append(opts, gmaps.WithExitMonitor(exitMonitor))
+	}
 
-		var job scrapemate.IJob
+	if *seed.ExtraReviews {
+		opts = append(opts, gmaps.WithExtraReviews())
+	}
 
-		if !fastmode {
-			opts := []gmaps.GmapJobOptions{}
+	if seed.Country != "" {
+		opts = append(opts, gmaps.WithCountry(seed.Country))
+	}
 
-			if dedup != nil {
-				opts = append(opts, gmaps.WithDeduper(dedup))
-			}
+	if seed.Domain != "" {
+		opts = append(opts, gmaps.WithDomain(seed.Domain))
+	}
 
-			if exitMonitor != nil {
-				opts = append(opts, gmaps.WithExitMonitor(exitMonitor))
-			}
+	if *seed.MaxResults > 0 {
+		opts = append(opts, gmaps.WithMaxResults(*seed.MaxResults))
+	}
 
-			if extraReviews {
-				opts = append(opts, gmaps.WithExtraReviews())
-			}
+	if *seed.RelaxEmpty {
+		opts = append(opts, gmaps.WithRelaxEmpty())
+	}
 
-			var ownerID string
-			var organizationID string
-			if id != "" {
-				ownerID = id
-			}
+	if seed.Track != "" {
+		opts = append(opts, gmaps.WithTrack(seed.Track))
+	}
 
-			job = gmaps.NewGmapJob(id, langCode, query, ownerID, organizationID, maxDepth, email, bodacc, geoCoordinates, zoom, opts...)
-		} else {
-			jparams := gmaps.MapSearchParams{
-				Location: gmaps.MapLocation{
-					Lat:     lat,
-					Lon:     lon,
-					ZoomLvl: float64(zoom),
-					Radius:  radius,
-				},
-				Query:     query,
-				ViewportW: 1920,
-				ViewportH: 450,
-				Hl:        langCode,
-			}
+	return gmaps.NewGmapJob(seed.ID, seed.Lang, seed.Query, seed.OwnerID, seed.OrganizationID,
+		*seed.Depth, *seed.Email, *seed.Bodacc, seed.Geo, *seed.Zoom, opts...)
+}
+
+// newPlaceSeedJob scrapes the place of the seed directly. It refreshes the
+// result the owner has of the place, if any.
+func newPlaceSeedJob(seed *Seed, exitMonitor exiter.Exiter) (scrapemate.IJob, error) {
+	u, ok := gmaps.PlaceURL(seed.Place, seed.Domain)
+	if !ok {
+		return nil, fmt.Errorf("invalid place %q (expected a place URL, a place id or a CID)", seed.Place)
+	}
 
-			opts := []gmaps.SearchJobOptions{}
+	opts := []gmaps.PlaceJobOptions{gmaps.WithRefresh(), gmaps.WithPlaceJobCountry(seed.Country)}
 
-			if exitMonitor != nil {
-				opts = append(opts, gmaps.WithSearchJobExitMonitor(exitMonitor))
-			}
+	if exitMonitor != nil {
+		opts = append(opts, gmaps.WithPlaceJobExitMonitor(exitMonitor))
+	}
 
-			job = gmaps.NewSearchJob(&jparams, opts...)
-		}
+	if *seed.Bodacc {
+		opts = append(opts, gmaps.WithBodaccExtraction())
+	}
 
-		jobs = append(jobs, job)
+	job := gmaps.NewPlaceJob("", seed.Lang, u, seed.OwnerID, seed.OrganizationID, *seed.Email, *seed.ExtraReviews, opts...)
+
+	if seed.ID != "" {
+		job.ID = seed.ID
 	}
 
-	return jobs, scanner.Err()
+	return job, nil
+}
+
+// newAuditSeedJob audits the Google Maps listing of the company of the seed,
+// searched by the name and address the register gives.
+func newAuditSeedJob(seed *Seed, lookup CompanyLookup, exitMonitor exiter.Exiter) (scrapemate.IJob, error) {
+	if lookup == nil {
+		return nil, fmt.Errorf("siren %s: no company register to look it up", seed.Siren)
+	}
+
+	company, err := lookup(seed.Siren)
+	if err != nil {
+		return nil, fmt.Errorf("siren %s: %w", seed.Siren, err)
+	}
+
+	if company == nil {
+		return nil, fmt.Errorf("siren %s: unknown company", seed.Siren)
+	}
+
+	a := gmaps.Audit{
+		Siren:   seed.Siren,
+		Name:    auditName(company.SocieteNom),
+		Address: company.Address,
+	}
+
+	if a.Address == "" {
+		a.Address = strings.TrimSpace(company.PostalCode + " " + company.City)
+	}
+
+	opts := []gmaps.GmapJobOptions{gmaps.WithAudit(a)}
+
+	if exitMonitor != nil {
+		opts = append(opts, gmaps.WithExitMonitor(exitMonitor))
+	}
+
+	if seed.Country != "" {
+		opts = append(opts, gmaps.WithCountry(seed.Country))
+	}
+
+	if seed.Domain != "" {
+		opts = append(opts, gmaps.WithDomain(seed.Domain))
+	}
+
+	return gmaps.NewGmapJob(seed.ID, seed.Lang, gmaps.AuditQuery(a), seed.OwnerID, seed.OrganizationID,
+		1, false, false, seed.Geo, *seed.Zoom, opts...), nil
+}
+
+// auditName drops the acronym the register appends to the name of some
+// companies, e.g. "BOULANGERIE MARTIN (BM)", which the listing rarely shows.
+func auditName(name string) string {
+	if i := strings.LastIndex(name, " ("); i > 0 && strings.HasSuffix(name, ")") {
+		return name[:i]
+	}
+
+	return name
+}
+
+func newFastSeedJob(seed *Seed, exitMonitor exiter.Exiter) (scrapemate.IJob, error) {
+	lat, lon, err := parseGeoCoordinates(seed.Geo)
+	if err != nil {
+		return nil, err
+	}
+
+	zoom, radius := *seed.Zoom, *seed.Radius
+
+	if zoom < 1 || zoom > 21 {
+		return nil, fmt.Errorf("invalid zoom level: %d", zoom)
+	}
+
+	if radius < 0 {
+		return nil, fmt.Errorf("invalid radius: %f", radius)
+	}
+
+	jparams := gmaps.MapSearchParams{
+		Location: gmaps.MapLocation{
+			Lat:     lat,
+			Lon:     lon,
+			ZoomLvl: float64(zoom),
+			Radius:  radius,
+		},
+		Query:     seed.Query,
+		ViewportW: 1920,
+		ViewportH: 450,
+		Hl:        seed.Lang,
+	}
+
+	opts := []gmaps.SearchJobOptions{
+		gmaps.WithSearchJobOwner(seed.OwnerID, seed.OrganizationID),
+		gmaps.WithSearchJobCountry(seed.Country),
+		gmaps.WithSearchJobEnrichment(*seed.Email, *seed.Bodacc),
+	}
+
+	if exitMonitor != nil {
+		opts = append(opts, gmaps.WithSearchJobExitMonitor(exitMonitor))
+	}
+
+	job := gmaps.NewSearchJob(&jparams, opts...)
+	if seed.ID != "" {
+		job.ID = seed.ID
+	}
+
+	return job, nil
+}
+
+func parseGeoCoordinates(geo string) (lat, lon float64, err error) {
+	if geo == "" {
+		return 0, 0, fmt.Errorf("geo coordinates are required in fast mode")
+	}
+
+	parts := strings.Split(geo, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid geo coordinates: %s", geo)
+	}
+
+	lat, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid latitude: %w", err)
+	}
+
+	lon, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid longitude: %w", err)
+	}
+
+	if lat < -90 || lat > 90 {
+		return 0, 0, fmt.Errorf("invalid latitude: %f", lat)
+	}
+
+	if lon < -180 || lon > 180 {
+		return 0, 0, fmt.Errorf("invalid longitude: %f", lon)
+	}
+
+	return lat, lon, nil
 }
@@ -0,0 +1,140 @@
+package runner_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/entreprise"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/runner"
+)
+
+func Test_ParseSeed(t *testing.T) {
+	seed, err := runner.ParseSeed("Matsuhisa Athens #!#MyIDentifier")
+	require.NoError(t, err)
+	require.Equal(t, "Matsuhisa Athens", seed.Query)
+	require.Equal(t, "MyIDentifier", seed.ID)
+
+	seed, err = runner.ParseSeed(`{"query": "plombier", "geo": "46.2044,6.1432", "zoom": 13, "email": true}`)
+	require.NoError(t, err)
+	require.Equal(t, "plombier", seed.Query)
+	require.Equal(t, "46.2044,6.1432", seed.Geo)
+	require.Equal(t, 13, *seed.Zoom)
+	require.True(t, *seed.Email)
+	require.Nil(t, seed.Bodacc)
+
+	seed, err = runner.ParseSeed("pizza #!# owner-1 #!# org-1")
+	require.NoError(t, err)
+	require.Equal(t, "pizza", seed.Query)
+	require.Equal(t, "owner-1", seed.ID)
+	require.Equal(t, "org-1", seed.OrganizationID)
+
+	_, err = runner.ParseSeed(`{"geo": "46.2044,6.1432"}`)
+	require.Error(t, err)
+
+	_, err = runner.ParseSeed(`{"query": "plombier", "unknown": 1}`)
+	require.Error(t, err)
+}
+
+func Test_CreateSeedJobs(t *testing.T) {
+	input := strings.Join([]string{
+		"boulangerie #!#owner-1",
+		"",
+		"pizza",
+		`{"query": "plombier", "depth": 3, "max_results": 40, "country": "ch", "owner_id": "u", "organization_id": "org"}`,
+		`{"query": "klempner berlin", "lang": "de", "domain": "www.google.de", "geo": "52.52,13.405", "zoom": 13}`,
+		"https://www.google.com/maps/place/Boulangerie+Martin/data=!4m7!3m6!1s0x47e671f8f1a7b2a3:0x5bd4a1c5e6f7a8b9 #!# owner-2",
+		`{"place": "ChIJo7Kn8fhx5kcRuaj35sWh1Fs", "domain": "google.fr", "email": true}`,
+		"cid:11234567890123456789",
+	}, "\n")
+
+	jobs, err := runner.CreateSeedJobs(false, "fr", "", "", "default-owner", "default-org", strings.NewReader(input), 10, false, true, "", 0, 0, nil, nil, false, 100, false, "", nil)
+	require.NoError(t, err)
+	require.Len(t, jobs, 7)
+
+	first, ok := jobs[0].(*gmaps.GmapJob)
+	require.True(t, ok)
+	require.Equal(t, "owner-1", first.OwnerID)
+	require.Equal(t, "default-org", first.OrganizationID)
+	require.Equal(t, 10, first.MaxDepth)
+	require.Equal(t, 100, first.MaxResults)
+	require.True(t, first.ExtractBodacc)
+
+	plain, ok := jobs[1].(*gmaps.GmapJob)
+	require.True(t, ok)
+	require.Equal(t, "default-owner", plain.OwnerID)
+	require.Equal(t, "default-org", plain.OrganizationID)
+
+	second, ok := jobs[2].(*gmaps.GmapJob)
+	require.True(t, ok)
+	require.Equal(t, 3, second.MaxDepth)
+	require.Equal(t, 40, second.MaxResults)
+	require.Equal(t, "ch", second.Country)
+	require.Equal(t, "u", second.OwnerID)
+	require.Equal(t, "org", second.OrganizationID)
+	require.Equal(t, "fr", second.LangCode)
+	require.Empty(t, second.Domain)
+
+	regional, ok := jobs[3].(*gmaps.GmapJob)
+	require.True(t, ok)
+	require.Equal(t, "google.de", regional.Domain)
+	require.Equal(t, "https://www.google.de/maps/search/klempner+berlin/@52.52,13.405,13z", regional.URL)
+	require.Equal(t, "klempner berlin", gmaps.SearchQuery(regional.URL))
+
+	byURL, ok := jobs[4].(*gmaps.PlaceJob)
+	require.True(t, ok)
+	require.True(t, byURL.Refresh)
+	require.Equal(t, "owner-2", byURL.OwnerID)
+	require.Equal(t, "default-org", byURL.OrganizationID)
+	require.Contains(t, byURL.URL, "/maps/place/Boulangerie+Martin/")
+
+	byPlaceID, ok := jobs[5].(*gmaps.PlaceJob)
+	require.True(t, ok)
+	require.Equal(t, "https://www.google.fr/maps/place/?q=place_id:ChIJo7Kn8fhx5kcRuaj35sWh1Fs", byPlaceID.URL)
+	require.True(t, byPlaceID.ExtractEmail)
+
+	byCID, ok := jobs[6].(*gmaps.PlaceJob)
+	require.True(t, ok)
+	require.Equal(t, "https://www.google.com/maps?cid=11234567890123456789", byCID.URL)
+
+	_, err = runner.CreateSeedJobs(false, "fr", "", "", "", "", strings.NewReader(`{"place": "plombier paris"}`), 10, false, false, "", 0, 0, nil, nil, false, 0, false, "", nil)
+	require.Error(t, err)
+
+	_, err = runner.CreateSeedJobs(false, "fr", "", "", "", "", strings.NewReader(`{"query": "pizza", "domain": "example.com"}`), 10, false, false, "", 0, 0, nil, nil, false, 0, false, "", nil)
+	require.Error(t, err)
+}
+
+func Test_CreateSeedJobs_Audit(t *testing.T) {
+	lookup := func(siren string) (*entreprise.CompanyInfo, error) {
+		if siren != "732829320" {
+			return nil, nil
+		}
+
+		return &entreprise.CompanyInfo{SocieteNom: "BOULANGERIE MARTIN (BM)", Address: "12 RUE DE LA PAIX 75002 PARIS"}, nil
+	}
+
+	input := "732 829 320 #!# owner-1\n" + `{"siren": "732829320", "domain": "google.fr"}`
+
+	jobs, err := runner.CreateSeedJobs(false, "fr", "", "", "", "", strings.NewReader(input), 10, true, true, "", 0, 0, nil, nil, false, 0, false, "", lookup)
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+
+	audit, ok := jobs[0].(*gmaps.GmapJob)
+	require.True(t, ok)
+	require.Equal(t, &gmaps.Audit{Siren: "732829320", Name: "BOULANGERIE MARTIN", Address: "12 RUE DE LA PAIX 75002 PARIS"}, audit.Audit)
+	require.Equal(t, "BOULANGERIE MARTIN 12 RUE DE LA PAIX 75002 PARIS", gmaps.SearchQuery(audit.URL))
+	require.Equal(t, "owner-1", audit.OwnerID)
+	require.False(t, audit.ExtractEmail)
+
+	regional, ok := jobs[1].(*gmaps.GmapJob)
+	require.True(t, ok)
+	require.Equal(t, "google.fr", regional.Domain)
+
+	_, err = runner.CreateSeedJobs(false, "fr", "", "", "", "", strings.NewReader("552100554"), 10, false, false, "", 0, 0, nil, nil, false, 0, false, "", lookup)
+	require.Error(t, err)
+
+	_, err = runner.ParseSeed(`{"siren": "123456789"}`)
+	require.Error(t, err)
+}
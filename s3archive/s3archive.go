@@ -0,0 +1,73 @@
+// Package s3archive stores the raw data of scraped places in an S3 bucket,
+// as the place archive of the database provider.
+package s3archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Archive writes each place to <prefix>/<key>.json.gz in its bucket.
+type Archive struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// New creates an Archive from an s3://bucket/prefix location. Credentials,
+// region and endpoint come from the usual AWS environment variables and
+// shared configuration.
+func New(ctx context.Context, location string) (*Archive, error) {
+	bucket, prefix, err := ParseLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return &Archive{
+		client: s3.NewFromConfig(cfg),
+		bucket: bucket,
+		prefix: prefix,
+	}, nil
+}
+
+// ParseLocation splits an s3://bucket/prefix location.
+func ParseLocation(location string) (bucket, prefix string, err error) {
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme != "s3" || u.Host == "" {
+		return "", "", fmt.Errorf("invalid S3 location %q, expected s3://bucket/prefix", location)
+	}
+
+	return u.Host, strings.Trim(u.Path, "/"), nil
+}
+
+func (a *Archive) Put(ctx context.Context, key string, gz []byte) error {
+	name := key + ".json.gz"
+	if a.prefix != "" {
+		name = a.prefix + "/" + name
+	}
+
+	_, err := a.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:          aws.String(a.bucket),
+		Key:             aws.String(name),
+		Body:            bytes.NewReader(gz),
+		ContentType:     aws.String("application/json"),
+		ContentEncoding: aws.String("gzip"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write %s to S3: %w", name, err)
+	}
+
+	return nil
+}
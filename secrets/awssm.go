@@ -0,0 +1,114 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// AWSSource reads a secret of AWS Secrets Manager whose string is a JSON
+// object. Credentials and region come from the usual AWS environment
+// variables and shared configuration; AWS_ENDPOINT_URL_SECRETS_MANAGER
+// overrides the endpoint.
+type AWSSource struct {
+	client   *http.Client
+	cfg      aws.Config
+	signer   *v4.Signer
+	endpoint string
+	secretID string
+}
+
+// NewAWSSource creates an AWSSource of the secret secretID, a name or an
+// ARN.
+func NewAWSSource(ctx context.Context, secretID string) (*AWSSource, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_SECRETS_MANAGER")
+	if endpoint == "" {
+		if cfg.Region == "" {
+			return nil, errors.New("secrets manager: no AWS region configured")
+		}
+
+		endpoint = "https://secretsmanager." + cfg.Region + ".amazonaws.com"
+	}
+
+	return &AWSSource{
+		client:   &http.Client{Timeout: 30 * time.Second},
+		cfg:      cfg,
+		signer:   v4.NewSigner(),
+		endpoint: endpoint,
+		secretID: secretID,
+	}, nil
+}
+
+func (s *AWSSource) Fetch(ctx context.Context) (map[string]string, error) {
+	payload, err := json.Marshal(map[string]string{"SecretId": s.secretID})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+
+	if s.cfg.Credentials == nil {
+		return nil, errors.New("secrets manager: no AWS credentials configured")
+	}
+
+	creds, err := s.cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("secrets manager: failed to retrieve AWS credentials: %w", err)
+	}
+
+	hash := sha256.Sum256(payload)
+
+	err = s.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(hash[:]), "secretsmanager", s.cfg.Region, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("secrets manager: failed to sign the request: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("secrets manager: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("secrets manager: status %d: %s", resp.StatusCode, msg)
+	}
+
+	var body struct {
+		SecretString string `json:"SecretString"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("secrets manager: failed to decode the response: %w", err)
+	}
+
+	var values map[string]any
+	if err := json.Unmarshal([]byte(body.SecretString), &values); err != nil {
+		return nil, fmt.Errorf("secrets manager: %s is not a JSON object: %w", s.secretID, err)
+	}
+
+	return stringValues(values), nil
+}
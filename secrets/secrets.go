@@ -0,0 +1,145 @@
+// Package secrets loads the credentials of the scraper from a secrets
+// manager, HashiCorp Vault or AWS Secrets Manager, instead of the environment
+// or a .env file.
+//
+// A secret holds key/value pairs named after the environment variables they
+// replace, e.g. INSEE_API_KEY, INPI_PASSWORD or GMAPS_DSN. They are exported
+// to the environment, where the packages reading their credentials find
+// them, and exported again whenever the secret rotates.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source reads the key/value pairs of a secret.
+type Source interface {
+	Fetch(ctx context.Context) (map[string]string, error)
+}
+
+// Open returns the Source of location: vault://<mount>/<path> for a Vault KV
+// version 2 secret, or awssm://<secret id> for an AWS Secrets Manager one.
+func Open(ctx context.Context, location string) (Source, error) {
+	scheme, name, _ := strings.Cut(location, "://")
+	name = strings.Trim(name, "/")
+
+	switch {
+	case name == "":
+	case scheme == "vault":
+		mount, path, _ := strings.Cut(name, "/")
+		return NewVaultSource(mount, path)
+	case scheme == "awssm":
+		// not parsed as a URL: ARNs hold colons
+		return NewAWSSource(ctx, name)
+	}
+
+	return nil, fmt.Errorf("invalid secrets location %q, expected vault://mount/path or awssm://secret-id", location)
+}
+
+// Loader exports the pairs of a secret to the environment. Variables that
+// were set before the first Load win over the secret and are never touched.
+type Loader struct {
+	source Source
+
+	mu       sync.Mutex
+	values   map[string]string
+	explicit map[string]bool
+}
+
+// NewLoader creates a Loader of source.
+func NewLoader(source Source) *Loader {
+	return &Loader{
+		source:   source,
+		values:   map[string]string{},
+		explicit: map[string]bool{},
+	}
+}
+
+// Load fetches the secret and exports its pairs, returning the keys whose
+// value changed since the previous Load.
+func (l *Loader) Load(ctx context.Context) ([]string, error) {
+	values, err := l.source.Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var changed []string
+
+	for k, v := range values {
+		if _, ok := l.values[k]; !ok {
+			if _, set := os.LookupEnv(k); set {
+				l.explicit[k] = true
+			}
+		}
+
+		if l.explicit[k] {
+			continue
+		}
+
+		if old, ok := l.values[k]; ok && old == v {
+			continue
+		}
+
+		if err := os.Setenv(k, v); err != nil {
+			return changed, err
+		}
+
+		changed = append(changed, k)
+	}
+
+	l.values = values
+
+	sort.Strings(changed)
+
+	return changed, nil
+}
+
+// Get returns the value key had in the last Load, false when the secret does
+// not hold it or the environment overrides it.
+func (l *Loader) Get(key string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.explicit[key] {
+		return "", false
+	}
+
+	v, ok := l.values[key]
+
+	return v, ok
+}
+
+// Watch loads the secret again every interval until ctx is done, so that
+// rotated credentials are picked up without a restart. Failures are logged
+// and the previous values kept.
+func (l *Loader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := l.Load(ctx)
+			if err != nil {
+				log.Printf("secrets: reload failed: %v", err)
+				continue
+			}
+
+			if len(changed) > 0 {
+				log.Printf("secrets: rotated %s", strings.Join(changed, ", "))
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package secrets_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/secrets"
+)
+
+type fakeSource map[string]string
+
+func (f fakeSource) Fetch(context.Context) (map[string]string, error) {
+	ans := make(map[string]string, len(f))
+	for k, v := range f {
+		ans[k] = v
+	}
+
+	return ans, nil
+}
+
+func Test_Loader(t *testing.T) {
+	t.Setenv("SECRETS_TEST_INSEE", "")
+	os.Unsetenv("SECRETS_TEST_INSEE")
+	t.Setenv("SECRETS_TEST_INPI", "from env")
+
+	source := fakeSource{
+		"SECRETS_TEST_INSEE": "v1",
+		"SECRETS_TEST_INPI":  "from secret",
+	}
+
+	loader := secrets.NewLoader(source)
+
+	changed, err := loader.Load(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"SECRETS_TEST_INSEE"}, changed)
+	require.Equal(t, "v1", os.Getenv("SECRETS_TEST_INSEE"))
+	require.Equal(t, "from env", os.Getenv("SECRETS_TEST_INPI"))
+
+	// the environment wins over the secret, rotations included
+	source["SECRETS_TEST_INSEE"] = "v2"
+	source["SECRETS_TEST_INPI"] = "rotated"
+
+	changed, err = loader.Load(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"SECRETS_TEST_INSEE"}, changed)
+	require.Equal(t, "v2", os.Getenv("SECRETS_TEST_INSEE"))
+	require.Equal(t, "from env", os.Getenv("SECRETS_TEST_INPI"))
+
+	v, ok := loader.Get("SECRETS_TEST_INSEE")
+	require.True(t, ok)
+	require.Equal(t, "v2", v)
+
+	_, ok = loader.Get("SECRETS_TEST_INPI")
+	require.False(t, ok)
+}
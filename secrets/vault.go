@@ -0,0 +1,100 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// VaultSource reads a secret of a Vault KV version 2 engine, with the
+// address, token and namespace of VAULT_ADDR, VAULT_TOKEN and
+// VAULT_NAMESPACE.
+type VaultSource struct {
+	client    *http.Client
+	url       string
+	token     string
+	namespace string
+}
+
+// NewVaultSource creates a VaultSource of the secret at path in the KV engine
+// mounted at mount.
+func NewVaultSource(mount, path string) (*VaultSource, error) {
+	addr := strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/")
+	if addr == "" {
+		return nil, errors.New("vault: VAULT_ADDR is not set")
+	}
+
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		return nil, errors.New("vault: VAULT_TOKEN is not set")
+	}
+
+	if path == "" {
+		return nil, fmt.Errorf("vault: no secret path after the %s mount", mount)
+	}
+
+	return &VaultSource{
+		client:    &http.Client{Timeout: 30 * time.Second},
+		url:       addr + "/v1/" + mount + "/data/" + path,
+		token:     token,
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+	}, nil
+}
+
+func (s *VaultSource) Fetch(ctx context.Context) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("X-Vault-Token", s.token)
+
+	if s.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.namespace)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault: status %d for %s", resp.StatusCode, s.url)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("vault: failed to decode the secret: %w", err)
+	}
+
+	return stringValues(body.Data.Data), nil
+}
+
+// stringValues keeps the values of m as strings, booleans and numbers
+// formatted the way they would be written in an environment variable.
+func stringValues(m map[string]any) map[string]string {
+	ans := make(map[string]string, len(m))
+
+	for k, v := range m {
+		switch v := v.(type) {
+		case string:
+			ans[k] = v
+		case nil:
+		default:
+			ans[k] = fmt.Sprint(v)
+		}
+	}
+
+	return ans
+}
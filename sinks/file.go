@@ -0,0 +1,84 @@
+package sinks
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// NewCSVWriter creates a result writer appending the places to the CSV file
+// at path, with the columns of the file runner. The header is written when
+// the file is empty.
+func NewCSVWriter(path string) scrapemate.ResultWriter {
+	return &fileWriter{path: path, csv: true}
+}
+
+// NewJSONLWriter creates a result writer appending the places to the file at
+// path, one JSON object per line.
+func NewJSONLWriter(path string) scrapemate.ResultWriter {
+	return &fileWriter{path: path}
+}
+
+type fileWriter struct {
+	path string
+	csv  bool
+}
+
+func (w *fileWriter) Run(_ context.Context, in <-chan scrapemate.Result) error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	header := w.csv && info.Size() == 0
+	cw := csv.NewWriter(f)
+	enc := json.NewEncoder(f)
+
+	for result := range in {
+		entry, ok := result.Data.(*gmaps.Entry)
+		if !ok || entry == nil {
+			continue
+		}
+
+		if !w.csv {
+			if err := enc.Encode(entry); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if header {
+			if err := cw.Write(entry.CsvHeaders()); err != nil {
+				return err
+			}
+
+			header = false
+		}
+
+		if err := cw.Write(entry.CsvRow()); err != nil {
+			return err
+		}
+
+		// a row is on disk as soon as it is written, whenever the worker stops
+		cw.Flush()
+
+		if err := cw.Error(); err != nil {
+			return err
+		}
+	}
+
+	return f.Close()
+}
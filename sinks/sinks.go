@@ -0,0 +1,182 @@
+// Package sinks runs the result writers a worker feeds besides the results
+// table, each with its own buffer, so that a slow or failing one neither
+// holds the scraping nor the other writers.
+package sinks
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gosom/scrapemate"
+)
+
+// DefaultBuffer is how many results a sink holds before it drops the next
+// ones.
+const DefaultBuffer = 1000
+
+// Spec is a sink as given to -result-sinks, kind:target.
+type Spec struct {
+	Kind   string
+	Target string
+}
+
+// ParseSpec parses a kind:target sink: csv:<path>, jsonl:<path> or
+// webhook:<url>.
+func ParseSpec(s string) (Spec, error) {
+	kind, target, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok || target == "" {
+		return Spec{}, fmt.Errorf("invalid sink %q (expected 'kind:target')", s)
+	}
+
+	spec := Spec{Kind: kind, Target: target}
+
+	switch kind {
+	case "csv", "jsonl":
+	case "webhook":
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return Spec{}, fmt.Errorf("webhook sink must be an http or https URL, got %q", target)
+		}
+	default:
+		return Spec{}, fmt.Errorf("unknown sink kind %q: must be csv, jsonl or webhook", kind)
+	}
+
+	return spec, nil
+}
+
+// Name identifies the sink in the logs and metrics, without the path and
+// query of webhook URLs, which may hold tokens.
+func (s Spec) Name() string {
+	if s.Kind == "webhook" {
+		if u, err := url.Parse(s.Target); err == nil {
+			return s.Kind + ":" + u.Host
+		}
+	}
+
+	return s.Kind + ":" + s.Target
+}
+
+// Stats is the state of a sink since startup.
+type Stats struct {
+	// Accepted counts the results given to the writer.
+	Accepted int64 `json:"accepted"`
+	// Dropped counts the results the sink had no room for, or received
+	// after it failed.
+	Dropped int64  `json:"dropped"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Sink runs a result writer behind a buffer of its own. It never blocks the
+// results it is given and never stops the worker: when the writer falls
+// behind the results that do not fit are dropped, and when it fails its
+// error is kept for the metrics and the next results dropped.
+type Sink struct {
+	name   string
+	w      scrapemate.ResultWriter
+	buffer int
+
+	accepted atomic.Int64
+	dropped  atomic.Int64
+
+	mu  sync.Mutex
+	err error
+}
+
+// New creates a Sink running w with a buffer of buffer results.
+func New(name string, w scrapemate.ResultWriter, buffer int) *Sink {
+	if buffer < 1 {
+		buffer = DefaultBuffer
+	}
+
+	return &Sink{name: name, w: w, buffer: buffer}
+}
+
+// Name returns the name of the sink.
+func (s *Sink) Name() string {
+	return s.name
+}
+
+// Run feeds the writer until in is closed or ctx is done, then waits for it
+// to finish.
+func (s *Sink) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	buf := make(chan scrapemate.Result, s.buffer)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- s.w.Run(ctx, buf)
+	}()
+
+	for {
+		select {
+		case result, ok := <-in:
+			if !ok {
+				close(buf)
+
+				if done != nil {
+					s.fail(ctx, <-done)
+				}
+
+				return nil
+			}
+
+			if done == nil {
+				s.dropped.Add(1)
+				continue
+			}
+
+			select {
+			case buf <- result:
+				s.accepted.Add(1)
+			default:
+				s.dropped.Add(1)
+			}
+		case err := <-done:
+			// the writer gave up early: keep taking the results so that the
+			// other writers go on
+			s.fail(ctx, err)
+			done = nil
+		case <-ctx.Done():
+			close(buf)
+
+			if done != nil {
+				<-done
+			}
+
+			return ctx.Err()
+		}
+	}
+}
+
+// Stats returns the state of the sink.
+func (s *Sink) Stats() Stats {
+	st := Stats{
+		Accepted: s.accepted.Load(),
+		Dropped:  s.dropped.Load(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		st.Error = s.err.Error()
+	}
+
+	return st
+}
+
+func (s *Sink) fail(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+
+	log := scrapemate.GetLoggerFromContext(ctx)
+	log.Error(fmt.Sprintf("sink %s stopped: %v", s.name, err))
+}
@@ -0,0 +1,85 @@
+package sinks_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gosom/scrapemate"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/sinks"
+)
+
+type writerFunc func(ctx context.Context, in <-chan scrapemate.Result) error
+
+func (f writerFunc) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	return f(ctx, in)
+}
+
+func Test_SinkIsolatesFailures(t *testing.T) {
+	failing := sinks.New("failing", writerFunc(func(context.Context, <-chan scrapemate.Result) error {
+		return errors.New("disk full")
+	}), 10)
+
+	release := make(chan struct{})
+	slow := sinks.New("slow", writerFunc(func(_ context.Context, in <-chan scrapemate.Result) error {
+		<-release
+
+		for range in {
+		}
+
+		return nil
+	}), 2)
+
+	for _, s := range []*sinks.Sink{failing, slow} {
+		in := make(chan scrapemate.Result)
+		errc := make(chan error, 1)
+
+		go func() {
+			errc <- s.Run(context.Background(), in)
+		}()
+
+		// the writers are not read from, the sinks take the results anyway
+		for range 5 {
+			select {
+			case in <- scrapemate.Result{}:
+			case <-time.After(time.Second):
+				t.Fatalf("sink %s blocked", s.Name())
+			}
+		}
+
+		close(in)
+
+		if s == slow {
+			close(release)
+		}
+
+		require.NoError(t, <-errc)
+	}
+
+	st := failing.Stats()
+	require.Equal(t, "disk full", st.Error)
+	require.Equal(t, int64(5), st.Accepted+st.Dropped)
+
+	st = slow.Stats()
+	require.Equal(t, int64(2), st.Accepted)
+	require.Equal(t, int64(3), st.Dropped)
+	require.Empty(t, st.Error)
+}
+
+func Test_ParseSpec(t *testing.T) {
+	spec, err := sinks.ParseSpec("webhook:https://hooks.example.com/places?token=secret")
+	require.NoError(t, err)
+	require.Equal(t, "webhook:hooks.example.com", spec.Name())
+
+	spec, err = sinks.ParseSpec("csv:/data/places.csv")
+	require.NoError(t, err)
+	require.Equal(t, "/data/places.csv", spec.Target)
+
+	for _, s := range []string{"csv", "s3:bucket", "webhook:ftp://example.com"} {
+		_, err := sinks.ParseSpec(s)
+		require.Error(t, err, s)
+	}
+}
@@ -0,0 +1,320 @@
+// Package webhook posts scraped places to an HTTP endpoint as flat JSON
+// objects, the shape no-code tools like Zapier or Make expect from a webhook.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/postgres"
+)
+
+const (
+	defaultBatchSize     = 1
+	defaultFlushInterval = 5 * time.Second
+	defaultMaxRetries    = 3
+	// maxFlattenDepth is how many levels of nested objects become fields;
+	// deeper values are sent as JSON text.
+	maxFlattenDepth = 2
+)
+
+type Option func(*writer)
+
+// WithBatchSize sets how many entries are sent per request. With 1 each
+// request holds a single object, otherwise a JSON array.
+func WithBatchSize(n int) Option {
+	return func(w *writer) {
+		w.batchSize = n
+	}
+}
+
+// WithFlushInterval sets how long entries wait for a batch to fill up.
+func WithFlushInterval(d time.Duration) Option {
+	return func(w *writer) {
+		w.flushInterval = d
+	}
+}
+
+// WithMaxRetries sets how many times a failed request is retried.
+func WithMaxRetries(n int) Option {
+	return func(w *writer) {
+		w.maxRetries = n
+	}
+}
+
+// WithHTTPClient sets the client requests are sent with.
+func WithHTTPClient(c *http.Client) Option {
+	return func(w *writer) {
+		w.client = c
+	}
+}
+
+// NewWriter creates a result writer posting entries to url. Requests are
+// signed like the other webhooks when WEBHOOK_SECRET is set.
+func NewWriter(url string, opts ...Option) scrapemate.ResultWriter {
+	w := &writer{
+		url:           url,
+		secret:        os.Getenv("WEBHOOK_SECRET"),
+		client:        &http.Client{Timeout: 30 * time.Second},
+		batchSize:     defaultBatchSize,
+		flushInterval: defaultFlushInterval,
+		maxRetries:    defaultMaxRetries,
+		backoff:       time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
+}
+
+type writer struct {
+	url           string
+	secret        string
+	client        *http.Client
+	batchSize     int
+	flushInterval time.Duration
+	maxRetries    int
+	backoff       time.Duration
+}
+
+// Run posts the entries it receives. A batch that still fails after the
+// retries is logged and dropped so the scraping goes on.
+func (w *writer) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	log := scrapemate.GetLoggerFromContext(ctx)
+
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]map[string]any, 0, w.batchSize)
+
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := w.send(ctx, batch); err != nil {
+			log.Error(fmt.Sprintf("webhook: dropping %d entries: %v", len(batch), err))
+		}
+
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case result, ok := <-in:
+			if !ok {
+				flush(ctx)
+				return nil
+			}
+
+			entry, ok := result.Data.(*gmaps.Entry)
+			if !ok || entry == nil {
+				continue
+			}
+
+			fields, err := Flatten(entry)
+			if err != nil {
+				log.Error(fmt.Sprintf("webhook: %v", err))
+				continue
+			}
+
+			addJobFields(fields, result.Job)
+
+			batch = append(batch, fields)
+
+			if len(batch) >= w.batchSize {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		case <-ctx.Done():
+			// give the last batch a chance to go out
+			sendCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			flush(sendCtx)
+			cancel()
+
+			return ctx.Err()
+		}
+	}
+}
+
+func (w *writer) send(ctx context.Context, batch []map[string]any) error {
+	var (
+		body []byte
+		err  error
+	)
+
+	if w.batchSize == 1 {
+		body, err = json.Marshal(batch[0])
+	} else {
+		body, err = json.Marshal(batch)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	backoff := w.backoff
+
+	for attempt := 0; ; attempt++ {
+		err = w.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= w.maxRetries || !retryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+// statusError is returned for responses outside the 2xx range.
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", e.code)
+}
+
+// retryable reports whether err may go away: network errors, rate limiting
+// and server errors.
+func retryable(err error) bool {
+	se, ok := err.(*statusError)
+	if !ok {
+		return true
+	}
+
+	return se.code == http.StatusTooManyRequests || se.code >= 500
+}
+
+func (w *writer) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if w.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(postgres.WebhookTimestampHeader, timestamp)
+		req.Header.Set(postgres.WebhookSignatureHeader, postgres.SignWebhookPayload(w.secret, timestamp, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &statusError{code: resp.StatusCode}
+	}
+
+	return nil
+}
+
+// addJobFields adds who the entry was scraped for.
+func addJobFields(fields map[string]any, job scrapemate.IJob) {
+	if u, ok := job.(interface{ Unwrap() scrapemate.IJob }); ok {
+		job = u.Unwrap()
+	}
+
+	switch j := job.(type) {
+	case *gmaps.PlaceJob:
+		fields["owner_id"] = j.OwnerID
+		fields["organization_id"] = j.OrganizationID
+	case *gmaps.GmapJob:
+		fields["owner_id"] = j.OwnerID
+		fields["organization_id"] = j.OrganizationID
+	}
+}
+
+// Flatten turns e into a single level object that no-code tools can map
+// field by field: nested objects become prefix_key fields, lists of values
+// are joined with ", " and lists of objects, like reviews, are kept as JSON
+// text.
+func Flatten(e *gmaps.Entry) (map[string]any, error) {
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode entry: %w", err)
+	}
+
+	var nested map[string]any
+	if err := json.Unmarshal(raw, &nested); err != nil {
+		return nil, fmt.Errorf("failed to decode entry: %w", err)
+	}
+
+	ans := make(map[string]any, len(nested))
+
+	flatten(ans, "", nested, 0)
+
+	return ans, nil
+}
+
+func flatten(dst map[string]any, prefix string, obj map[string]any, depth int) {
+	for k, v := range obj {
+		key := k
+		if prefix != "" {
+			key = prefix + "_" + k
+		}
+
+		switch val := v.(type) {
+		case map[string]any:
+			if depth+1 < maxFlattenDepth {
+				flatten(dst, key, val, depth+1)
+			} else {
+				dst[key] = jsonText(val)
+			}
+		case []any:
+			dst[key] = flattenList(val)
+		default:
+			dst[key] = val
+		}
+	}
+}
+
+func flattenList(list []any) any {
+	parts := make([]string, 0, len(list))
+
+	for _, item := range list {
+		switch v := item.(type) {
+		case map[string]any, []any:
+			return jsonText(list)
+		case string:
+			parts = append(parts, v)
+		default:
+			parts = append(parts, fmt.Sprint(v))
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func jsonText(v any) string {
+	b, _ := json.Marshal(v)
+
+	return string(b)
+}
@@ -0,0 +1,41 @@
+package webhook_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/webhook"
+)
+
+func Test_Flatten(t *testing.T) {
+	e := &gmaps.Entry{
+		Title:           "Boulangerie Martin",
+		Emails:          []string{"a@example.com", "b@example.com"},
+		CompleteAddress: gmaps.Address{City: "Lyon", PostalCode: "69001"},
+		UserReviews:     []gmaps.Review{{Name: "Paul", Rating: 5}},
+		ReviewCount:     12,
+	}
+
+	fields, err := webhook.Flatten(e)
+	require.NoError(t, err)
+
+	require.Equal(t, "Boulangerie Martin", fields["title"])
+	require.Equal(t, "a@example.com, b@example.com", fields["emails"])
+	require.Equal(t, "Lyon", fields["complete_address_city"])
+	require.Equal(t, "69001", fields["complete_address_postal_code"])
+	require.Equal(t, float64(12), fields["review_count"])
+	require.NotContains(t, fields, "complete_address")
+
+	reviews, ok := fields["user_reviews"].(string)
+	require.True(t, ok)
+	require.Contains(t, reviews, `"Paul"`)
+
+	for k, v := range fields {
+		switch v.(type) {
+		case map[string]any, []any:
+			t.Errorf("field %s is not flat: %T", k, v)
+		}
+	}
+}